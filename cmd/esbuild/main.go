@@ -34,17 +34,19 @@ var helpText = func(colors logger.Colors) string {
   --bundle              Bundle all dependencies into the output files
   --define:K=V          Substitute K with V while parsing
   --external:M          Exclude module M from the bundle (can use * wildcards)
-  --format=...          Output format (iife | cjs | esm, no default when not
-                        bundling, otherwise default is iife when platform
-                        is browser and cjs when platform is node)
+  --format=...          Output format (iife | cjs | esm | umd | system, no
+                        default when not bundling, otherwise default is iife
+                        when platform is browser, cjs when platform is node,
+                        and esm otherwise)
   --loader:X=L          Use loader L to load file extension X, where L is
                         one of: base64 | binary | copy | css | dataurl |
-                        empty | file | js | json | jsx | text | ts | tsx
+                        empty | file | flow | js | json | jsx | text |
+                        ts | tsx
   --minify              Minify the output (sets all --minify-* flags)
   --outdir=...          The output directory (for multiple entry points)
   --outfile=...         The output file (for one entry point)
   --packages=...        Set to "external" to avoid bundling any package
-  --platform=...        Platform target (browser | node | neutral,
+  --platform=...        Platform target (browser | node | neutral | deno,
                         default browser)
   --serve=...           Start a local HTTP server on this host:port for outputs
   --sourcemap           Emit a source map
@@ -61,21 +63,54 @@ var helpText = func(colors logger.Colors) string {
                             (default "[name]-[hash]")
   --banner:T=...            Text to be prepended to each output file of type T
                             where T is one of: css | js
+  --browserslist=...        Lower syntax for this browserslist query (e.g.
+                            "defaults" or "chrome >= 90, safari 14"), or for
+                            the one in a ".browserslistrc" or "package.json"
   --certfile=...            Certificate for serving HTTPS (see also "--keyfile")
   --charset=utf8            Do not escape UTF-8 code points
   --chunk-names=...         Path template to use for code splitting chunks
                             (default "[name]-[hash]")
   --color=...               Force use of color terminal escapes (true | false)
+  --comments=...            Which comments to keep (none | legal | all, default
+                            is legal comments and comments in expressions, all
+                            is also kept when minifying)
+  --comments-filter=...     Only keep non-legal comments matching this regular
+                            expression (these are also kept when minifying)
+  --dirname=...             What to do with "__dirname" and "__filename" when
+                            bundling (source | runtime | error)
   --drop:...                Remove certain constructs (console | debugger)
+  --drop-labels=...         Remove labeled statements with any of these label
+                            names
+  --env-file:F              Load variables from the ".env" file F and expose
+                            them as "process.env.X" and "import.meta.env.X"
+  --env-prefix:P            Only expose variables from env files whose names
+                            start with P (required when using "--env-file")
   --entry-names=...         Path template to use for entry point output paths
                             (default "[dir]/[name]", can also use "[hash]")
+  --expose:N=P              Expose the module at path P to other builds using
+                            the name N (requires "--format=esm")
+  --extract-licenses        Write the licenses of bundled packages from
+                            "node_modules" to a ".LICENSES.txt" file next to
+                            each output file
   --footer:T=...            Text to be appended to each output file of type T
                             where T is one of: css | js
-  --global-name=...         The name of the global for the IIFE format
+  --global-name=...         The name of the global for the IIFE and UMD formats
+  --global:M=N              Use the global variable N for imports of the
+                            external module M in UMD output
+  --html-inline-limit=...   Inline outputs referenced by HTML entry points
+                            that are smaller than this many bytes (default 0)
   --ignore-annotations      Enable this to work with packages that have
                             incorrect tree-shaking annotations
+  --import-map=...          Remap import paths using this import map file
+                            (can also be a "deno.json" file)
+  --import-meta-shim=...    An entity name or JSON object that provides the
+                            properties of "import.meta" in non-ESM output
+  --indent=...              Indentation for non-minified output (tab or a
+                            number of spaces, default 2)
   --inject:F                Import the file F into all input files and
                             automatically replace matching globals with imports
+  --inline-workers          Embed web workers in the output files instead of
+                            writing them to separate files
   --jsx-dev                 Use React's automatic runtime in development mode
   --jsx-factory=...         What to use for JSX instead of React.createElement
   --jsx-fragment=...        What to use for JSX instead of React.Fragment
@@ -104,20 +139,43 @@ var helpText = func(colors logger.Colors) string {
   --minify-whitespace       Remove whitespace in output files
   --minify-identifiers      Shorten identifiers in output files
   --minify-syntax           Use equivalent but shorter syntax in output files
+  --node-globals=...        Shim these node globals in non-node builds when
+                            they are used (Buffer | process)
+  --node-polyfills          Substitute browser polyfill packages for node
+                            built-ins (e.g. "path" becomes "path-browserify")
   --out-extension:.js=.mjs  Use a custom output extension instead of ".js"
   --outbase=...             The base path used to determine entry point output
                             paths (for multiple entry points)
   --preserve-symlinks       Disable symlink resolution for module lookup
+  --process-env:K=V         Replace "process.env.K" with the string V (requires
+                            "--platform=node")
   --public-path=...         Set the base URL for the "file" loader
   --pure:N                  Mark the name N as a pure function for tree shaking
+  --quote-style=single      Prefer single quotes for strings in the output
+  --remote:N=U              Import paths starting with N from the URL U at run
+                            time (e.g. "N/Button" becomes "U/Button.js")
+  --reserve-names=...       A comma-separated list of names that minification
+                            must not generate or rename (e.g. "$,jQuery")
   --reserve-props=...       Do not mangle these properties
   --resolve-extensions=...  A comma-separated list of implicit extensions
                             (default ".tsx,.ts,.jsx,.js,.css,.json")
+  --runtime-chunk           Put shared helper functions in a separate chunk
+                            (requires --splitting)
   --servedir=...            What to serve in addition to generated output files
   --source-root=...         Sets the "sourceRoot" field in generated source maps
   --sourcefile=...          Set the source file for the source map (for stdin)
   --sourcemap=external      Do not link to the source map with a comment
   --sourcemap=inline        Emit the source map with an inline data URL
+  --sourcemap-debug-id      Add a matching "debugId" to each output file and
+                            its source map
+  --sourcemap-ignore-list=...
+                            Hide source map sources matching this regular
+                            expression in debuggers (e.g. node_modules)
+  --sourcemap-lines-only    Only map the start of each line in source maps
+                            (smaller source maps without column information)
+  --sourcemap-path-prefix:A=B
+                            Replace the prefix A with B in the paths of
+                            source map sources
   --sources-content=false   Omit "sourcesContent" in generated source maps
   --supported:F=...         Consider syntax F to be supported (true | false)
   --tree-shaking=...        Force tree shaking on or off (false | true)
@@ -782,6 +782,10 @@ func resolveKindToString(kind api.ResolveKind) string {
 		return "dynamic-import"
 	case api.ResolveJSRequireResolve:
 		return "require-resolve"
+	case api.ResolveJSWorker:
+		return "worker"
+	case api.ResolveJSNewURL:
+		return "new-url"
 
 	// CSS
 	case api.ResolveCSSImportRule:
@@ -808,6 +812,10 @@ func stringToResolveKind(kind string) (api.ResolveKind, bool) {
 		return api.ResolveJSDynamicImport, true
 	case "require-resolve":
 		return api.ResolveJSRequireResolve, true
+	case "worker":
+		return api.ResolveJSWorker, true
+	case "new-url":
+		return api.ResolveJSNewURL, true
 
 	// CSS
 	case "import-rule":
@@ -1123,6 +1131,7 @@ func (service *serviceType) handleTransformRequest(id uint32, request map[string
 		return encodeErrorPacket(id, err)
 	}
 	options.MangleCache, _ = request["mangleCache"].(map[string]interface{})
+	options.AST, _ = request["ast"].(bool)
 
 	transformInput := input
 	if inputFS {
@@ -1181,6 +1190,10 @@ func (service *serviceType) handleTransformRequest(id uint32, request map[string
 		response["mangleCache"] = result.MangleCache
 	}
 
+	if result.AST != nil {
+		response["ast"] = string(result.AST)
+	}
+
 	return encodePacket(packet{
 		id:    id,
 		value: response,
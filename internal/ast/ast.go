@@ -35,6 +35,12 @@ const (
 
 	// A CSS "url(...)" token
 	ImportURL
+
+	// A "new Worker(new URL(...))" expression with a string argument
+	ImportWorker
+
+	// A "new URL(..., import.meta.url)" expression with a string argument
+	ImportNewURL
 )
 
 func (kind ImportKind) StringForMetafile() string {
@@ -51,6 +57,10 @@ func (kind ImportKind) StringForMetafile() string {
 		return "import-rule"
 	case ImportURL:
 		return "url-token"
+	case ImportWorker:
+		return "worker"
+	case ImportNewURL:
+		return "new-url"
 	case ImportEntryPoint:
 		return "entry-point"
 	default:
@@ -62,7 +72,7 @@ func (kind ImportKind) IsFromCSS() bool {
 	return kind == ImportAt || kind == ImportURL
 }
 
-type ImportRecordFlags uint16
+type ImportRecordFlags uint32
 
 const (
 	// Sometimes the parser creates an import record and decides it isn't needed.
@@ -122,6 +132,10 @@ const (
 
 	// CSS "@import" of an empty file should be removed
 	WasLoadedWithEmptyLoader
+
+	// If true, this web worker is embedded into the output file as a string
+	// instead of being written to a separate output file
+	InlineWorker
 )
 
 func (flags ImportRecordFlags) Has(flag ImportRecordFlags) bool {
@@ -145,19 +159,67 @@ type ImportRecord struct {
 	// because they are sort of like external imports, and are not bundled.
 	CopySourceIndex Index32
 
+	// This is present if this import record is for a "require.context()" call.
+	// In that case the path is the directory to search instead of a module.
+	RequireContext *RequireContext
+
+	// This is present if this import record is for an "import.meta.glob()"
+	// call. In that case the path is the first glob pattern.
+	ImportGlob *ImportGlob
+
 	Flags ImportRecordFlags
 	Kind  ImportKind
 }
 
+// This is webpack's "require.context(directory, useSubdirectories, regExp)"
+// feature. It's expanded at build time into a module that can require all
+// files in the directory whose relative paths match the regular expression.
+type RequireContext struct {
+	RegExp            string // Includes the slashes and flags (e.g. "/\.js$/i")
+	UseSubdirectories bool
+}
+
+// This is Vite's "import.meta.glob(patterns, options)" feature. It's expanded
+// at build time into an object that maps the relative path of each file that
+// matches the glob patterns to either the module or a function that imports it.
+type ImportGlob struct {
+	Patterns []string // Patterns starting with "!" exclude files
+	Import   string   // If present, only this export is imported (e.g. "default")
+	Eager    bool     // If true, modules are imported statically instead of lazily
+}
+
 type ImportAssertions struct {
 	Entries            []AssertEntry
-	AssertLoc          logger.Loc
+	Keyword            ImportAssertionsKeyword
+	AssertLoc          logger.Loc // The location of the "assert" or "with" keyword
 	InnerOpenBraceLoc  logger.Loc
 	InnerCloseBraceLoc logger.Loc
 	OuterOpenBraceLoc  logger.Loc
 	OuterCloseBraceLoc logger.Loc
 }
 
+type ImportAssertionsKeyword uint8
+
+const (
+	AssertKeyword ImportAssertionsKeyword = iota // "import 'foo' assert { type: 'json' }"
+	WithKeyword                                  // "import 'foo' with { type: 'json' }"
+)
+
+func (kw ImportAssertionsKeyword) String() string {
+	if kw == WithKeyword {
+		return "with"
+	}
+	return "assert"
+}
+
+// This is used in log messages
+func (kw ImportAssertionsKeyword) Description() string {
+	if kw == WithKeyword {
+		return "import attribute"
+	}
+	return "import assertion"
+}
+
 type AssertEntry struct {
 	Key             []uint16 // An identifier or a string
 	Value           []uint16 // Always a string
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -29,6 +30,8 @@ import (
 	"github.com/evanw/esbuild/internal/fs"
 	"github.com/evanw/esbuild/internal/graph"
 	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/html_ast"
+	"github.com/evanw/esbuild/internal/html_parser"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/js_lexer"
 	"github.com/evanw/esbuild/internal/js_parser"
@@ -76,6 +79,20 @@ type Bundle struct {
 	files       []scannerFile
 	entryPoints []graph.EntryPoint
 	options     config.Options
+
+	// HTML entry points aren't passed to the linker. Instead the files they
+	// reference are added as entry points, and then each HTML file is written
+	// out after linking with its references substituted for the output paths.
+	html htmlInfo
+}
+
+type htmlInfo struct {
+	entryPoints []graph.EntryPoint
+	outputFile  string
+
+	// These entry points were only added because an HTML file references them,
+	// so their output files can be omitted if they're inlined into the HTML
+	addedEntryPoints map[uint32]bool
 }
 
 type parseArgs struct {
@@ -118,6 +135,12 @@ func parseFile(args parseArgs) {
 		IdentifierName: js_ast.GenerateNonUniqueNameFromPath(args.keyPath.Text),
 	}
 
+	// Modules generated for "import.meta.glob()" calls are named after the
+	// directory of the importing file, which isn't a very helpful name
+	if args.keyPath.Namespace == "import-glob" {
+		source.IdentifierName = "glob"
+	}
+
 	var loader config.Loader
 	var absResolveDir string
 	var pluginName string
@@ -162,8 +185,13 @@ func parseFile(args parseArgs) {
 	_, base, ext := logger.PlatformIndependentPathDirBaseExt(source.KeyPath.Text)
 
 	// The special "default" loader determines the loader from the file path
+	// unless the import used "with { type: 'json' }"
 	if loader == config.LoaderDefault {
-		loader = loaderFromFileExtension(args.options.ExtensionToLoader, base+ext)
+		if (source.KeyPath.Flags & logger.PathWithTypeJSON) != 0 {
+			loader = config.LoaderJSON
+		} else {
+			loader = loaderFromFileExtension(args.options.ExtensionToLoader, base+ext)
+		}
 	}
 
 	if loader == config.LoaderEmpty {
@@ -229,6 +257,20 @@ func parseFile(args parseArgs) {
 		result.file.inputFile.Repr = &graph.JSRepr{AST: ast}
 		result.ok = ok
 
+	case config.LoaderFlow:
+		// Flow code is written as JSX with type annotations. Unlike TypeScript,
+		// Flow only removes imports that are explicitly marked as type-only.
+		args.options.TS.Parse = true
+		args.options.TS.Flow = true
+		args.options.JSX.Parse = true
+		args.options.UnusedImportFlagsTS = config.UnusedImportKeepValues
+		ast, ok := args.caches.JSCache.Parse(args.log, source, js_parser.OptionsFromConfig(&args.options))
+		if len(ast.Parts) <= 1 { // Ignore the implicitly-generated namespace export part
+			result.file.inputFile.SideEffects.Kind = graph.NoSideEffects_EmptyAST
+		}
+		result.file.inputFile.Repr = &graph.JSRepr{AST: ast}
+		result.ok = ok
+
 	case config.LoaderCSS:
 		ast := args.caches.CSSCache.Parse(args.log, source, css_parser.Options{
 			MinifySyntax:           args.options.MinifySyntax,
@@ -239,6 +281,11 @@ func parseFile(args parseArgs) {
 		result.file.inputFile.Repr = &graph.CSSRepr{AST: ast}
 		result.ok = true
 
+	case config.LoaderHTML:
+		ast := html_parser.Parse(args.log, source)
+		result.file.inputFile.Repr = &graph.HTMLRepr{AST: ast}
+		result.ok = true
+
 	case config.LoaderJSON:
 		expr, ok := args.caches.JSONCache.Parse(args.log, source, js_parser.JSONOptions{})
 		ast := js_parser.LazyExportAST(args.log, source, js_parser.OptionsFromConfig(&args.options), expr, "")
@@ -378,6 +425,32 @@ func parseFile(args parseArgs) {
 						continue
 					}
 
+					// Calls to "require.context()" reference a directory, not a module
+					if record.RequireContext != nil {
+						result.resolveResults[importRecordIndex] = resolveRequireContext(args.fs, args.log, &tracker, record, absResolveDir)
+						continue
+					}
+
+					// Calls to "import.meta.glob()" reference glob patterns, not a module
+					if record.ImportGlob != nil {
+						result.resolveResults[importRecordIndex] = resolveImportGlob(args.log, &tracker, record, absResolveDir)
+						continue
+					}
+
+					// Remote modules are loaded from another build at run time
+					if url, ok := resolveRemoteModule(args.options.RemoteModules, record.Path.Text); ok {
+						if record.Kind != ast.ImportStmt && record.Kind != ast.ImportDynamic {
+							args.log.AddError(&tracker, record.Range,
+								fmt.Sprintf("Remote module %q must be imported using an \"import\" statement or an \"import()\" expression", record.Path.Text))
+							continue
+						}
+						result.resolveResults[importRecordIndex] = &resolver.ResolveResult{
+							PathPair:   resolver.PathPair{Primary: logger.Path{Text: url}},
+							IsExternal: true,
+						}
+						continue
+					}
+
 					// Cache the path in case it's imported multiple times in this file
 					cache, ok := resolverCache[record.Kind]
 					if !ok {
@@ -424,7 +497,12 @@ func parseFile(args parseArgs) {
 						// external imports instead of causing errors. This matches a common
 						// code pattern for conditionally importing a module with a graceful
 						// fallback.
-						if !didLogError && !record.Flags.Has(ast.HandlesImportErrors) {
+						if !didLogError && isOptionalFileReference(record) {
+							// References using "new URL()" that can't be resolved are left
+							// alone since they may refer to a file that exists at run time
+							args.log.AddID(logger.MsgID_None, logger.Debug, &tracker, record.Range,
+								fmt.Sprintf("Leaving %q alone because it could not be resolved", record.Path.Text))
+						} else if !didLogError && !record.Flags.Has(ast.HandlesImportErrors) {
 							text, suggestion, notes := ResolveFailureErrorTextSuggestionNotes(args.res, record.Path.Text, record.Kind,
 								pluginName, args.fs, absResolveDir, args.options.Platform, source.PrettyPath, debug.ModifiedImportPath)
 							debug.LogErrorMsg(args.log, &source, record.Range, text, suggestion, notes)
@@ -437,6 +515,15 @@ func parseFile(args parseArgs) {
 						continue
 					}
 
+					// References using "new URL()" to files that esbuild doesn't know
+					// how to load are also left alone instead of causing errors
+					if isOptionalFileReference(record) && !resolveResult.IsExternal &&
+						!canLoadPath(&args.options, resolveResult.PathPair.Primary) {
+						args.log.AddID(logger.MsgID_None, logger.Debug, &tracker, record.Range,
+							fmt.Sprintf("Leaving %q alone because no loader is configured for it", record.Path.Text))
+						continue
+					}
+
 					result.resolveResults[importRecordIndex] = resolveResult
 				}
 			}
@@ -452,7 +539,16 @@ func parseFile(args parseArgs) {
 				sourceMapComment = repr.AST.SourceMapComment
 			}
 
-			if sourceMapComment.Text != "" {
+			if stdin := args.options.Stdin; stdin != nil && stdin.InputSourceMap != "" {
+				// A source map passed to the transform API takes precedence over a
+				// "//# sourceMappingURL=" comment in the code itself
+				path := logger.Path{Text: source.PrettyPath, IgnoredSuffix: "#inputSourcemap"}
+				result.file.inputFile.InputSourceMap = js_parser.ParseSourceMap(args.log, logger.Source{
+					KeyPath:    path,
+					PrettyPath: resolver.PrettyPath(args.fs, path),
+					Contents:   stdin.InputSourceMap,
+				})
+			} else if sourceMapComment.Text != "" {
 				tracker := logger.MakeLineColumnTracker(&source)
 
 				if path, contents := extractSourceMapFromComment(args.log, args.fs, &args.caches.FSCache,
@@ -550,7 +646,24 @@ func ResolveFailureErrorTextSuggestionNotes(
 	originatingFilePath string,
 	modifiedImportPath string,
 ) (text string, suggestion string, notes []logger.MsgData) {
-	if modifiedImportPath != "" {
+	isPolyfill := modifiedImportPath != "" && modifiedImportPath == resolver.BrowserPolyfillsForNodeModules[strings.TrimPrefix(path, "node:")]
+	if isPolyfill {
+		// Some polyfill packages have the same name as the built-in they replace
+		if modifiedImportPath == path {
+			text = fmt.Sprintf("Could not resolve %q", path)
+			notes = append(notes, logger.MsgData{Text: fmt.Sprintf(
+				"The node built-in %q is provided by the browser polyfill package of the same name, which couldn't be resolved. "+
+					"You may need to install the %q package in the current working directory.",
+				path, strings.Split(modifiedImportPath, "/")[0])})
+		} else {
+			text = fmt.Sprintf("Could not resolve %q (originally %q)", modifiedImportPath, path)
+			notes = append(notes, logger.MsgData{Text: fmt.Sprintf(
+				"The node built-in %q was substituted with the browser polyfill %q, which then couldn't be resolved. "+
+					"You may need to install the %q package in the current working directory.",
+				path, modifiedImportPath, strings.Split(modifiedImportPath, "/")[0])})
+		}
+		path = modifiedImportPath
+	} else if modifiedImportPath != "" {
 		text = fmt.Sprintf("Could not resolve %q (originally %q)", modifiedImportPath, path)
 		notes = append(notes, logger.MsgData{Text: fmt.Sprintf(
 			"The path %q was remapped to %q using the alias feature, which then couldn't be resolved. "+
@@ -579,7 +692,9 @@ func ResolveFailureErrorTextSuggestionNotes(
 		}
 	}
 
-	if platform != config.PlatformNode {
+	// Don't suggest bundling for node if the built-in was already remapped to a
+	// browser polyfill since that's what the user asked for
+	if platform != config.PlatformNode && !isPolyfill {
 		pkg := path
 		if strings.HasPrefix(pkg, "node:") {
 			pkg = pkg[5:]
@@ -1010,10 +1125,448 @@ func runOnLoadPlugins(
 		}
 	}
 
+	// Generate the module for a "require.context()" call from the directory
+	if source.KeyPath.Namespace == "require-context" {
+		if contents, err := generateRequireContext(fs, source.KeyPath); err != nil {
+			log.AddError(&tracker, importPathRange,
+				fmt.Sprintf("Cannot read directory %q: %s", resolver.PrettyPath(fs, logger.Path{Text: source.KeyPath.Text, Namespace: "file"}), err.Error()))
+			return loaderPluginResult{}, false
+		} else {
+			source.Contents = contents
+			return loaderPluginResult{
+				loader:        config.LoaderJS,
+				absResolveDir: source.KeyPath.Text,
+			}, true
+		}
+	}
+
+	// Generate the module for an "import.meta.glob()" call from the file system
+	if source.KeyPath.Namespace == "import-glob" {
+		if contents, err := generateImportGlob(fs, source.KeyPath); err != nil {
+			log.AddError(&tracker, importPathRange,
+				fmt.Sprintf("Cannot expand glob patterns in %q: %s", resolver.PrettyPath(fs, logger.Path{Text: source.KeyPath.Text, Namespace: "file"}), err.Error()))
+			return loaderPluginResult{}, false
+		} else {
+			source.Contents = contents
+			return loaderPluginResult{
+				loader:        config.LoaderJS,
+				absResolveDir: source.KeyPath.Text,
+			}, true
+		}
+	}
+
+	// Generate the shim for a node global such as "process" or "Buffer"
+	if source.KeyPath.Namespace == "node-global" {
+		if contents, ok := nodeGlobalShims[source.KeyPath.Text]; ok {
+			source.Contents = contents
+			return loaderPluginResult{
+				loader:        config.LoaderJS,
+				absResolveDir: fs.Cwd(),
+			}, true
+		}
+	}
+
 	// Otherwise, fail to load the path
 	return loaderPluginResult{loader: config.LoaderNone}, true
 }
 
+// These shims are injected for node globals when they are referenced by code
+// that isn't bundled for node. They are intentionally minimal. The "Buffer"
+// shim just forwards to the "buffer" package, which must be installed.
+var nodeGlobalShims = map[string]string{
+	"process": `
+		export var process = {
+			title: "browser",
+			browser: true,
+			env: {},
+			argv: [],
+			version: "",
+			versions: {},
+			platform: "browser",
+			cwd: function() { return "/" },
+			nextTick: function(fn) {
+				var args = Array.prototype.slice.call(arguments, 1)
+				Promise.resolve().then(function() { fn.apply(null, args) })
+			},
+		}
+	`,
+	"Buffer": `
+		export { Buffer } from "buffer"
+	`,
+}
+
+func IsNodeGlobal(name string) bool {
+	_, ok := nodeGlobalShims[name]
+	return ok
+}
+
+// Import paths that start with the name of a remote are mapped to the URL of
+// the corresponding exposed module in that remote. The remote name by itself
+// maps to the container for all exposed modules in that remote.
+func resolveRemoteModule(remotes map[string]string, importPath string) (string, bool) {
+	// Use the longest matching name in case one remote name is a prefix of another
+	var bestName string
+	for name := range remotes {
+		if len(name) > len(bestName) && (importPath == name || strings.HasPrefix(importPath, name+"/")) {
+			bestName = name
+		}
+	}
+	if bestName == "" {
+		return "", false
+	}
+	url := remotes[bestName]
+	if importPath == bestName {
+		return url + "/" + config.FederationContainerName + ".js", true
+	}
+	return url + importPath[len(bestName):] + ".js", true
+}
+
+// The module for a "require.context()" call uses the directory as the path
+// and a webpack-style description of the arguments as the suffix (e.g.
+// " sync recursive /\.js$/"). That way each unique combination of arguments
+// results in a separate module, and the arguments are visible in the path.
+func resolveRequireContext(
+	fs fs.FS,
+	log logger.Log,
+	tracker *logger.LineColumnTracker,
+	record *ast.ImportRecord,
+	absResolveDir string,
+) *resolver.ResolveResult {
+	dir := record.Path.Text
+	if !fs.IsAbs(dir) {
+		if dir != "." && dir != ".." && !strings.HasPrefix(dir, "./") && !strings.HasPrefix(dir, "../") {
+			log.AddError(tracker, record.Range,
+				fmt.Sprintf("The directory %q passed to \"require.context\" must be a relative path", dir))
+			return nil
+		}
+		dir = fs.Join(absResolveDir, dir)
+	}
+
+	if _, err := compileRequireContextRegExp(record.RequireContext.RegExp); err != nil {
+		log.AddError(tracker, record.Range,
+			fmt.Sprintf("The regular expression %s passed to \"require.context\" is not supported: %s", record.RequireContext.RegExp, err.Error()))
+		return nil
+	}
+
+	if _, err, _ := fs.ReadDirectory(dir); err != nil {
+		if !record.Flags.Has(ast.HandlesImportErrors) {
+			log.AddError(tracker, record.Range,
+				fmt.Sprintf("Could not find the directory %q passed to \"require.context\"", record.Path.Text))
+		}
+		return nil
+	}
+
+	suffix := " sync "
+	if record.RequireContext.UseSubdirectories {
+		suffix += "recursive "
+	}
+	suffix += record.RequireContext.RegExp
+
+	return &resolver.ResolveResult{PathPair: resolver.PathPair{Primary: logger.Path{
+		Text:          dir,
+		Namespace:     "require-context",
+		IgnoredSuffix: suffix,
+	}}}
+}
+
+// The module for an "import.meta.glob()" call uses the directory of the
+// importing file as the path and a description of the options and patterns as
+// the suffix (e.g. " eager [\"./pages/*.js\"]"). The patterns come last since
+// they may contain spaces.
+func resolveImportGlob(
+	log logger.Log,
+	tracker *logger.LineColumnTracker,
+	record *ast.ImportRecord,
+	absResolveDir string,
+) *resolver.ResolveResult {
+	sb := strings.Builder{}
+	if record.ImportGlob.Eager {
+		sb.WriteString(" eager")
+	}
+	if record.ImportGlob.Import != "" {
+		sb.WriteString(" import=")
+		sb.WriteString(record.ImportGlob.Import)
+	}
+	sb.WriteString(" [")
+
+	for i, pattern := range record.ImportGlob.Patterns {
+		// Patterns that exclude files don't need to be relative since they are
+		// only used to filter the matches (e.g. "!**/*.test.js")
+		if !strings.HasPrefix(pattern, "!") && !strings.HasPrefix(pattern, "./") && !strings.HasPrefix(pattern, "../") {
+			log.AddError(tracker, record.Range,
+				fmt.Sprintf("The glob pattern %q passed to \"import.meta.glob\" must be a relative path", pattern))
+			return nil
+		}
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.Write(helpers.QuoteForJSON(pattern, false))
+	}
+	sb.WriteByte(']')
+
+	return &resolver.ResolveResult{PathPair: resolver.PathPair{Primary: logger.Path{
+		Text:          absResolveDir,
+		Namespace:     "import-glob",
+		IgnoredSuffix: sb.String(),
+	}}}
+}
+
+// Convert a glob pattern into a regular expression that matches the whole path.
+// This supports "*", "**", "?", and "{a,b}" which covers typical usage.
+func compileGlobPattern(pattern string) (*regexp.Regexp, error) {
+	sb := strings.Builder{}
+	sb.WriteByte('^')
+	braceDepth := 0
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				// "**/" matches zero or more directories
+				sb.WriteString("(?:[^/]*/)*")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				sb.WriteString(".*")
+				i++
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '{':
+			sb.WriteString("(?:")
+			braceDepth++
+		case '}':
+			if braceDepth > 0 {
+				sb.WriteByte(')')
+				braceDepth--
+			} else {
+				sb.WriteString("\\}")
+			}
+		case ',':
+			if braceDepth > 0 {
+				sb.WriteByte('|')
+			} else {
+				sb.WriteByte(',')
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteByte('$')
+	return regexp.Compile(sb.String())
+}
+
+func generateImportGlob(fsys fs.FS, path logger.Path) (string, error) {
+	suffix := path.IgnoredSuffix
+	bracket := strings.IndexByte(suffix, '[')
+	eager := false
+	importName := ""
+	for _, flag := range strings.Fields(suffix[:bracket]) {
+		if flag == "eager" {
+			eager = true
+		} else if strings.HasPrefix(flag, "import=") {
+			importName = flag[len("import="):]
+		}
+	}
+
+	// Decode the patterns, which are stored as a JSON array
+	var include []string
+	var exclude []*regexp.Regexp
+	patterns, _ := js_parser.ParseJSON(logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil),
+		logger.Source{Contents: suffix[bracket:]}, js_parser.JSONOptions{})
+	for _, item := range patterns.Data.(*js_ast.EArray).Items {
+		pattern := helpers.UTF16ToString(item.Data.(*js_ast.EString).Value)
+		if strings.HasPrefix(pattern, "!") {
+			regExp, err := compileGlobPattern(pattern[1:])
+			if err != nil {
+				return "", err
+			}
+			exclude = append(exclude, regExp)
+		} else {
+			include = append(include, pattern)
+		}
+	}
+
+	// Find all files that match, in sorted order for determinism
+	matches := make(map[string]bool)
+	for _, pattern := range include {
+		regExp, err := compileGlobPattern(pattern)
+		if err != nil {
+			return "", err
+		}
+
+		// Only search the directory before the first wildcard
+		prefix := pattern
+		if i := strings.IndexAny(pattern, "*?{"); i != -1 {
+			prefix = pattern[:strings.LastIndexByte(pattern[:i], '/')+1]
+		}
+		includeNodeModules := strings.Contains(pattern, "node_modules")
+
+		var visit func(absDir string, relDir string) error
+		visit = func(absDir string, relDir string) error {
+			entries, err, _ := fsys.ReadDirectory(absDir)
+			if err != nil {
+				return err
+			}
+			for _, name := range entries.SortedKeys() {
+				entry, _ := entries.Get(name)
+				switch entry.Kind(fsys) {
+				case fs.DirEntry:
+					if name != "node_modules" || includeNodeModules {
+						if err := visit(fsys.Join(absDir, name), relDir+name+"/"); err != nil {
+							return err
+						}
+					}
+				case fs.FileEntry:
+					if key := relDir + name; regExp.MatchString(key) {
+						matches[key] = true
+					}
+				}
+			}
+			return nil
+		}
+		if err := visit(fsys.Join(path.Text, prefix), prefix); err != nil {
+			return "", err
+		}
+	}
+	keys := make([]string, 0, len(matches))
+outer:
+	for key := range matches {
+		for _, regExp := range exclude {
+			if regExp.MatchString(key) {
+				continue outer
+			}
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sb := strings.Builder{}
+	if eager {
+		for i, key := range keys {
+			if importName != "" {
+				sb.WriteString(fmt.Sprintf("import { %s as __glob_%d } from %s;\n", importName, i, helpers.QuoteForJSON(key, false)))
+			} else {
+				sb.WriteString(fmt.Sprintf("import * as __glob_%d from %s;\n", i, helpers.QuoteForJSON(key, false)))
+			}
+		}
+	}
+	sb.WriteString("export default {\n")
+	for i, key := range keys {
+		quoted := helpers.QuoteForJSON(key, false)
+		if eager {
+			sb.WriteString(fmt.Sprintf("  %s: __glob_%d,\n", quoted, i))
+		} else if importName != "" {
+			sb.WriteString(fmt.Sprintf("  %s: () => import(%s).then((m) => m.%s),\n", quoted, quoted, importName))
+		} else {
+			sb.WriteString(fmt.Sprintf("  %s: () => import(%s),\n", quoted, quoted))
+		}
+	}
+	sb.WriteString("};\n")
+	return sb.String(), nil
+}
+
+// Convert a JavaScript regular expression literal such as "/\.js$/i" into a
+// Go regular expression. Go's regular expression syntax is similar enough to
+// JavaScript's that this works for the simple patterns used to match paths.
+func compileRequireContextRegExp(value string) (*regexp.Regexp, error) {
+	slash := strings.LastIndexByte(value, '/')
+	pattern := value[1:slash]
+	for _, flag := range value[slash+1:] {
+		switch flag {
+		case 'i', 'm', 's':
+			pattern = fmt.Sprintf("(?%c)%s", flag, pattern)
+		}
+	}
+	return regexp.Compile(pattern)
+}
+
+func generateRequireContext(fsys fs.FS, path logger.Path) (string, error) {
+	useSubdirectories := strings.HasPrefix(path.IgnoredSuffix, " sync recursive ")
+	regExp, err := compileRequireContextRegExp(path.IgnoredSuffix[strings.IndexByte(path.IgnoredSuffix, '/'):])
+	if err != nil {
+		return "", err
+	}
+
+	// Find all files in the directory that match, in sorted order for determinism
+	var keys []string
+	var visit func(absDir string, relDir string) error
+	visit = func(absDir string, relDir string) error {
+		entries, err, _ := fsys.ReadDirectory(absDir)
+		if err != nil {
+			return err
+		}
+		for _, name := range entries.SortedKeys() {
+			entry, _ := entries.Get(name)
+			switch entry.Kind(fsys) {
+			case fs.DirEntry:
+				if useSubdirectories {
+					if err := visit(fsys.Join(absDir, name), relDir+name+"/"); err != nil {
+						return err
+					}
+				}
+			case fs.FileEntry:
+				if key := relDir + name; regExp.MatchString(key) {
+					keys = append(keys, key)
+				}
+			}
+		}
+		return nil
+	}
+	if err := visit(path.Text, "./"); err != nil {
+		return "", err
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("var map = {\n")
+	for _, key := range keys {
+		quoted := helpers.QuoteForJSON(key, false)
+		sb.WriteString(fmt.Sprintf("  %s: function() { return require(%s); },\n", quoted, quoted))
+	}
+	sb.WriteString(`};
+function check(key) {
+  if (!Object.prototype.hasOwnProperty.call(map, key)) {
+    var e = new Error("Cannot find module '" + key + "'");
+    e.code = "MODULE_NOT_FOUND";
+    throw e;
+  }
+  return key;
+}
+function context(key) {
+  return map[check(key)]();
+}
+context.keys = function() {
+  return Object.keys(map);
+};
+context.resolve = check;
+module.exports = context;
+`)
+	return sb.String(), nil
+}
+
+// File references using "new URL()" outside of an inline worker are optional.
+// If esbuild can't bundle the file, the reference is left as it was.
+func isOptionalFileReference(record *ast.ImportRecord) bool {
+	return record.Kind == ast.ImportNewURL || (record.Kind == ast.ImportWorker && !record.Flags.Has(ast.InlineWorker))
+}
+
+// This returns false if loading this path would fail because no loader is
+// configured for it. Paths that a plugin may load are assumed to be loadable.
+func canLoadPath(options *config.Options, path logger.Path) bool {
+	if path.Namespace != "file" {
+		return true
+	}
+	for _, plugin := range options.Plugins {
+		for _, onLoad := range plugin.OnLoad {
+			if config.PluginAppliesToPath(path, onLoad.Filter, onLoad.Namespace) {
+				return true
+			}
+		}
+	}
+	_, base, ext := logger.PlatformIndependentPathDirBaseExt(path.Text)
+	return loaderFromFileExtension(options.ExtensionToLoader, base+ext) != config.LoaderNone
+}
+
 func loaderFromFileExtension(extensionToLoader map[string]config.Loader, base string) config.Loader {
 	// Pick the loader with the longest matching extension. So if there's an
 	// extension for ".css" and for ".module.css", we want to match the one for
@@ -1229,11 +1782,14 @@ func ScanBundle(
 		return Bundle{options: options}
 	}
 
+	entryPointMeta, html := s.addEntryPointsFromHTML(files, entryPointMeta)
+
 	return Bundle{
 		fs:              fs,
 		res:             s.res,
 		files:           files,
 		entryPoints:     entryPointMeta,
+		html:            html,
 		uniqueKeyPrefix: uniqueKeyPrefix,
 		options:         s.options,
 	}
@@ -1298,6 +1854,9 @@ func (s *scanner) maybeParseFile(
 	if resolveResult.UseDefineForClassFieldsTS != config.Unspecified {
 		optionsClone.UseDefineForClassFields = resolveResult.UseDefineForClassFieldsTS
 	}
+	if resolveResult.EmitDecoratorMetadataTS {
+		optionsClone.EmitDecoratorMetadata = true
+	}
 	if resolveResult.UnusedImportFlagsTS != 0 {
 		optionsClone.UnusedImportFlagsTS = resolveResult.UnusedImportFlagsTS
 	}
@@ -1533,6 +2092,24 @@ func (s *scanner) preprocessInjectedFiles() {
 	injectWaitGroup.Wait()
 	injectedFiles = append(injectedFiles, results[:j]...)
 
+	// Add shims for node globals last so that user-specified injected files
+	// take precedence over them. These are virtual modules that are generated
+	// by "runOnLoadPlugins" and are only included if they are referenced.
+	if s.options.Platform != config.PlatformNode && len(s.options.NodeGlobals) > 0 {
+		channels := make([]chan config.InjectedFile, len(s.options.NodeGlobals))
+		for i, name := range s.options.NodeGlobals {
+			path := logger.Path{Text: name, Namespace: "node-global"}
+			channels[i] = make(chan config.InjectedFile, 1)
+			s.maybeParseFile(resolver.ResolveResult{PathPair: resolver.PathPair{Primary: path}},
+				resolver.PrettyPath(s.fs, path), nil, logger.Range{}, nil, inputKindNormal, channels[i])
+		}
+		for _, channel := range channels {
+			file := <-channel
+			file.IsNodeGlobalShim = true
+			injectedFiles = append(injectedFiles, file)
+		}
+	}
+
 	// It's safe to mutate the options object to add the injected files here
 	// because there aren't any concurrent "parseFile" goroutines at this point.
 	// The only ones that were created by this point are the ones we created
@@ -1849,8 +2426,23 @@ func (s *scanner) scanAllDependencies() {
 
 				path := resolveResult.PathPair.Primary
 				if !resolveResult.IsExternal {
+					prettyPath := resolver.PrettyPath(s.fs, path)
+
+					// Use the "type" import attribute to select the loader. Files that
+					// wouldn't otherwise be loaded as JSON become a separate module. The
+					// copy loader is left alone since it's sort of like being external.
+					if record.Flags.Has(ast.AssertTypeJSON) {
+						_, base, ext := logger.PlatformIndependentPathDirBaseExt(path.Text)
+						if loader := loaderFromFileExtension(s.options.ExtensionToLoader, base+ext); loader != config.LoaderJSON && loader != config.LoaderCopy {
+							clone := *resolveResult
+							clone.PathPair.Primary.Flags |= logger.PathWithTypeJSON
+							resolveResult = &clone
+							prettyPath += " with { type: 'json' }"
+						}
+					}
+
 					// Handle a path within the bundle
-					sourceIndex := s.maybeParseFile(*resolveResult, resolver.PrettyPath(s.fs, path),
+					sourceIndex := s.maybeParseFile(*resolveResult, prettyPath,
 						&result.file.inputFile.Source, record.Range, resolveResult.PluginData, inputKindNormal, nil)
 					record.SourceIndex = ast.MakeIndex32(sourceIndex)
 				} else {
@@ -1899,6 +2491,11 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 			continue
 		}
 
+		// Skip over stubs for web workers, which are generated below
+		if repr, ok := result.file.inputFile.Repr.(*graph.CopyRepr); ok && repr.WorkerSourceIndex.IsValid() {
+			continue
+		}
+
 		sb := strings.Builder{}
 		isFirstImport := true
 
@@ -1968,19 +2565,31 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 						helpers.QuoteForJSON(record.Path.Text, s.options.ASCIIOnly)))
 				}
 
-				// Validate that imports with "assert { type: 'json' }" were imported
+				// Validate that imports with "with { type: 'json' }" were imported
 				// with the JSON loader. This is done to match the behavior of these
-				// import assertions in a real JavaScript runtime. In addition, we also
-				// allow the copy loader since this is sort of like marking the path
-				// as external (the import assertions are kept and the real JavaScript
-				// runtime evaluates them, not us).
+				// import attributes in a real JavaScript runtime. This can only fail
+				// if a plugin picked the loader. In addition, we also allow the copy
+				// loader since this is sort of like marking the path as external (the
+				// import attributes are kept and the real JavaScript runtime
+				// evaluates them, not us).
 				if record.Flags.Has(ast.AssertTypeJSON) && otherResult.ok && otherFile.inputFile.Loader != config.LoaderJSON && otherFile.inputFile.Loader != config.LoaderCopy {
+					description := record.Assertions.Keyword.Description()
 					s.log.AddErrorWithNotes(&tracker, record.Range,
 						fmt.Sprintf("The file %q was loaded with the %q loader", otherFile.inputFile.Source.PrettyPath, config.LoaderToString[otherFile.inputFile.Loader]),
 						[]logger.MsgData{
 							tracker.MsgData(js_lexer.RangeOfImportAssertion(result.file.inputFile.Source, *ast.FindAssertion(record.Assertions.Entries, "type")),
-								"This import assertion requires the loader to be \"json\" instead:"),
-							{Text: "You need to either reconfigure esbuild to ensure that the loader for this file is \"json\" or you need to remove this import assertion."}})
+								fmt.Sprintf("This %s requires the loader to be \"json\" instead:", description)),
+							{Text: fmt.Sprintf("You need to either reconfigure esbuild to ensure that the loader for this file is \"json\" or you need to remove this %s.", description)}})
+				}
+
+				// HTML files can only be entry points, so they can't be imported
+				if _, ok := otherFile.inputFile.Repr.(*graph.HTMLRepr); ok && record.Kind != ast.ImportEntryPoint {
+					s.log.AddErrorWithNotes(&tracker, record.Range,
+						fmt.Sprintf("Cannot import %q", otherFile.inputFile.Source.PrettyPath),
+						[]logger.MsgData{{Text: fmt.Sprintf(
+							"HTML files can only be used as entry points, and %q was loaded with the %q loader.",
+							otherFile.inputFile.Source.PrettyPath, config.LoaderToString[otherFile.inputFile.Loader])}})
+					continue
 				}
 
 				switch record.Kind {
@@ -2016,6 +2625,25 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 									otherFile.inputFile.Source.PrettyPath, config.LoaderToString[otherFile.inputFile.Loader])}})
 						}
 					}
+
+				case ast.ImportNewURL:
+					// Only files with a loader that provides a URL can be referenced
+					// using "new URL()" (the "copy" loader is handled below). Other
+					// references are left alone instead of causing errors.
+					providesURL := true
+					switch otherRepr := otherFile.inputFile.Repr.(type) {
+					case *graph.CSSRepr:
+						providesURL = false
+					case *graph.JSRepr:
+						providesURL = otherRepr.AST.URLForCSS != "" || otherFile.inputFile.Loader == config.LoaderEmpty
+					}
+					if !providesURL {
+						s.log.AddID(logger.MsgID_None, logger.Debug, &tracker, record.Range,
+							fmt.Sprintf("Leaving %q alone because it was loaded with the %q loader, which doesn't provide a URL",
+								record.Path.Text, config.LoaderToString[otherFile.inputFile.Loader]))
+						record.SourceIndex = ast.Index32{}
+						continue
+					}
 				}
 
 				// If the imported file uses the "copy" loader, then move it from
@@ -2026,6 +2654,67 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 					continue
 				}
 
+				// If this is a web worker, then redirect the import record to a stub
+				// that stands in for the worker. The worker is bundled separately as
+				// its own entry point during the compile phase. Using the stub as a
+				// "CopySourceIndex" means the worker itself isn't bundled here.
+				if record.Kind == ast.ImportWorker {
+					if !otherFile.inputFile.Loader.IsJavaScriptLike() && isOptionalFileReference(record) {
+						s.log.AddID(logger.MsgID_None, logger.Debug, &tracker, record.Range,
+							fmt.Sprintf("Leaving %q alone because it was loaded with the %q loader, which isn't JavaScript",
+								record.Path.Text, config.LoaderToString[otherFile.inputFile.Loader]))
+						record.SourceIndex = ast.Index32{}
+						continue
+					}
+					if !otherFile.inputFile.Loader.IsJavaScriptLike() {
+						s.log.AddErrorWithNotes(&tracker, record.Range,
+							fmt.Sprintf("Cannot use %q as a worker", otherFile.inputFile.Source.PrettyPath),
+							[]logger.MsgData{{Text: fmt.Sprintf(
+								"Web workers must be JavaScript or TypeScript files, and %q was loaded with the %q loader.",
+								otherFile.inputFile.Source.PrettyPath, config.LoaderToString[otherFile.inputFile.Loader])}})
+						continue
+					}
+					isInline := record.Flags.Has(ast.InlineWorker)
+					if s.options.WriteToStdout && !isInline {
+						s.log.AddError(&tracker, record.Range,
+							fmt.Sprintf("Cannot use %q as a worker without an output path configured", otherFile.inputFile.Source.PrettyPath))
+						continue
+					}
+					stubKey := otherFile.inputFile.Source.KeyPath
+					if stubKey.Namespace == "file" {
+						stubKey.Text = canonicalFileSystemPathForWindows(stubKey.Text)
+					}
+					kind := cache.SourceIndexWorkerStub
+					if isInline {
+						kind = cache.SourceIndexInlineWorkerStub
+					}
+					stubIndex := s.allocateSourceIndex(stubKey, kind)
+					if !s.results[stubIndex].ok {
+						uniqueKey := fmt.Sprintf("%sA%08d", s.uniqueKeyPrefix, stubIndex)
+						s.results[stubIndex] = parseResult{
+							file: scannerFile{
+								inputFile: graph.InputFile{
+									Source: logger.Source{
+										Index:      stubIndex,
+										KeyPath:    otherFile.inputFile.Source.KeyPath,
+										PrettyPath: otherFile.inputFile.Source.PrettyPath,
+									},
+									Repr: &graph.CopyRepr{
+										URLForCode:        uniqueKey,
+										WorkerSourceIndex: record.SourceIndex,
+										IsInlineWorker:    isInline,
+									},
+									UniqueKeyForAdditionalFile: uniqueKey,
+								},
+							},
+							ok: true,
+						}
+					}
+					record.CopySourceIndex = ast.MakeIndex32(stubIndex)
+					record.SourceIndex = ast.Index32{}
+					continue
+				}
+
 				// If an import from a JavaScript file targets a CSS file, generate a
 				// JavaScript stub to ensure that JavaScript files only ever import
 				// other JavaScript files.
@@ -2116,16 +2805,20 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 			if !isFirstImport {
 				sb.WriteString("\n      ")
 			}
-			if repr, ok := result.file.inputFile.Repr.(*graph.JSRepr); ok &&
-				(repr.AST.ExportsKind == js_ast.ExportsCommonJS || repr.AST.ExportsKind == js_ast.ExportsESM) {
-				format := "cjs"
-				if repr.AST.ExportsKind == js_ast.ExportsESM {
-					format = "esm"
+			sb.WriteString("]")
+			if repr, ok := result.file.inputFile.Repr.(*graph.JSRepr); ok {
+				if len(repr.AST.Directives) > 0 {
+					sb.WriteString(fmt.Sprintf(",\n      \"directives\": %s", helpers.QuoteStringArrayForJSON(repr.AST.Directives, s.options.ASCIIOnly)))
+				}
+				if repr.AST.ExportsKind == js_ast.ExportsCommonJS || repr.AST.ExportsKind == js_ast.ExportsESM {
+					format := "cjs"
+					if repr.AST.ExportsKind == js_ast.ExportsESM {
+						format = "esm"
+					}
+					sb.WriteString(fmt.Sprintf(",\n      \"format\": %q", format))
 				}
-				sb.WriteString(fmt.Sprintf("],\n      \"format\": %q\n    }", format))
-			} else {
-				sb.WriteString("]\n    }")
 			}
+			sb.WriteString("\n    }")
 		}
 
 		result.file.jsonMetadataChunk = sb.String()
@@ -2227,6 +2920,102 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 	return files
 }
 
+// The files referenced by "<script>" and "<link>" tags in HTML entry points
+// are bundled as separate entry points. The HTML entry points themselves are
+// removed from the list of entry points and returned separately since they
+// are written out after linking instead of by the linker.
+func (s *scanner) addEntryPointsFromHTML(files []scannerFile, entryPointMeta []graph.EntryPoint) (newEntryPointMeta []graph.EntryPoint, html htmlInfo) {
+	isEntryPoint := make(map[uint32]bool)
+	for _, entryPoint := range entryPointMeta {
+		if _, ok := files[entryPoint.SourceIndex].inputFile.Repr.(*graph.HTMLRepr); !ok {
+			isEntryPoint[entryPoint.SourceIndex] = true
+		}
+	}
+
+	for _, entryPoint := range entryPointMeta {
+		file := &files[entryPoint.SourceIndex].inputFile
+		repr, ok := file.Repr.(*graph.HTMLRepr)
+		if !ok {
+			newEntryPointMeta = append(newEntryPointMeta, entryPoint)
+			continue
+		}
+		html.entryPoints = append(html.entryPoints, entryPoint)
+		tracker := logger.MakeLineColumnTracker(&file.Source)
+
+		for _, ref := range repr.AST.References {
+			record := &repr.AST.ImportRecords[ref.ImportRecordIndex]
+			otherIndex := record.SourceIndex
+			if record.CopySourceIndex.IsValid() {
+				otherIndex = record.CopySourceIndex
+			}
+			if !otherIndex.IsValid() {
+				continue
+			}
+			other := &files[otherIndex.GetIndex()].inputFile
+
+			// Scripts must be JavaScript and stylesheets must be CSS
+			switch ref.Kind {
+			case html_ast.ReferenceScript:
+				if _, ok := other.Repr.(*graph.JSRepr); !ok || !other.Loader.IsJavaScriptLike() {
+					s.log.AddErrorWithNotes(&tracker, record.Range,
+						fmt.Sprintf("Cannot use %q as a script", other.Source.PrettyPath),
+						[]logger.MsgData{{Text: fmt.Sprintf(
+							"A \"<script>\" tag can only reference a JavaScript or TypeScript file, and %q was loaded with the %q loader.",
+							other.Source.PrettyPath, config.LoaderToString[other.Loader])}})
+					continue
+				}
+
+			case html_ast.ReferenceStylesheet:
+				if _, ok := other.Repr.(*graph.CSSRepr); !ok {
+					s.log.AddErrorWithNotes(&tracker, record.Range,
+						fmt.Sprintf("Cannot use %q as a stylesheet", other.Source.PrettyPath),
+						[]logger.MsgData{{Text: fmt.Sprintf(
+							"A \"<link rel=\"stylesheet\">\" tag can only reference a CSS file, and %q was loaded with the %q loader.",
+							other.Source.PrettyPath, config.LoaderToString[other.Loader])}})
+					continue
+				}
+			}
+
+			// Each referenced file only needs to be bundled once
+			if isEntryPoint[otherIndex.GetIndex()] {
+				continue
+			}
+			isEntryPoint[otherIndex.GetIndex()] = true
+			if html.addedEntryPoints == nil {
+				html.addedEntryPoints = make(map[uint32]bool)
+			}
+			html.addedEntryPoints[otherIndex.GetIndex()] = true
+
+			// Derive the output path from the input path like other entry points
+			outputPath := other.Source.KeyPath.Text
+			if other.Source.KeyPath.Namespace == "file" {
+				if relPath, ok := s.fs.Rel(s.options.AbsOutputBase, outputPath); ok {
+					outputPath = relPath
+				}
+			} else {
+				outputPath = s.fs.Base(outputPath)
+			}
+			if last := strings.LastIndexAny(outputPath, "/.\\"); last != -1 && outputPath[last] == '.' {
+				outputPath = outputPath[:last]
+			}
+			newEntryPointMeta = append(newEntryPointMeta, graph.EntryPoint{
+				OutputPath:                 outputPath,
+				SourceIndex:                otherIndex.GetIndex(),
+				OutputPathWasAutoGenerated: true,
+			})
+		}
+	}
+
+	// An HTML entry point causes multiple output files to be generated, so an
+	// explicit output file can only be used for the HTML file itself. All other
+	// output files are written to the same directory instead.
+	if len(html.entryPoints) > 0 && s.options.AbsOutputFile != "" {
+		html.outputFile = s.options.AbsOutputFile
+		s.options.AbsOutputFile = ""
+	}
+	return
+}
+
 func (s *scanner) validateTLA(sourceIndex uint32) tlaCheck {
 	result := &s.results[sourceIndex]
 
@@ -2326,6 +3115,7 @@ func DefaultExtensionToLoaderMap() map[string]config.Loader {
 		".mts":  config.LoaderTSNoAmbiguousLessThan,
 		".tsx":  config.LoaderTSX,
 		".css":  config.LoaderCSS,
+		".html": config.LoaderHTML,
 		".json": config.LoaderJSON,
 		".txt":  config.LoaderText,
 	}
@@ -2396,6 +3186,19 @@ func fixInvalidUnsupportedJSFeatureOverrides(options *config.Options, implies co
 	}
 }
 
+// This returns the AST of the first entry point if it's a JavaScript-like
+// file. It's used by the transform API to return the AST for its input.
+func (b *Bundle) EntryPointJSAST() (js_ast.AST, bool) {
+	if len(b.entryPoints) == 0 {
+		return js_ast.AST{}, false
+	}
+	repr, ok := b.files[b.entryPoints[0].SourceIndex].inputFile.Repr.(*graph.JSRepr)
+	if !ok {
+		return js_ast.AST{}, false
+	}
+	return repr.AST, true
+}
+
 type Linker func(
 	options *config.Options,
 	timer *helpers.Timer,
@@ -2430,18 +3233,40 @@ func (b *Bundle) Compile(log logger.Log, timer *helpers.Timer, mangleCache map[s
 	}
 
 	// Get the base path from the options or choose the lowest common ancestor of all entry points
-	allReachableFiles := findReachableFiles(files, b.entryPoints)
+	mainReachableFiles := findReachableFiles(files, b.entryPoints)
+	allReachableFiles := mainReachableFiles
+
+	// Web workers are bundled separately, so they aren't reachable from the
+	// main entry points. But they still need source map data and metadata.
+	// The same goes for HTML entry points, which aren't linked at all.
+	workerStubs, ok := findWorkerStubs(log, files, b.entryPoints)
+	if !ok {
+		return nil, ""
+	}
+	if len(workerStubs) > 0 || len(b.html.entryPoints) > 0 {
+		allEntryPoints := append(append([]graph.EntryPoint{}, b.entryPoints...), b.html.entryPoints...)
+		for _, stubIndex := range workerStubs {
+			stub := files[stubIndex].Repr.(*graph.CopyRepr)
+			allEntryPoints = append(allEntryPoints, graph.EntryPoint{SourceIndex: stub.WorkerSourceIndex.GetIndex()})
+		}
+		allReachableFiles = findReachableFiles(files, allEntryPoints)
+	}
 
 	// Compute source map data in parallel with linking
 	timer.Begin("Spawn source map tasks")
 	dataForSourceMaps := b.computeDataForSourceMapsInParallel(&options, allReachableFiles)
 	timer.End("Spawn source map tasks")
 
+	// Link each web worker before linking anything that uses it
+	if len(workerStubs) > 0 && !b.linkWorkers(&options, timer, log, link, files, workerStubs, dataForSourceMaps) {
+		return nil, ""
+	}
+
 	var resultGroups [][]graph.OutputFile
 	if options.CodeSplitting || len(b.entryPoints) == 1 {
 		// If code splitting is enabled or if there's only one entry point, link all entry points together
 		resultGroups = [][]graph.OutputFile{link(&options, timer, log, b.fs, b.res,
-			files, b.entryPoints, b.uniqueKeyPrefix, allReachableFiles, dataForSourceMaps)}
+			files, b.entryPoints, b.uniqueKeyPrefix, mainReachableFiles, dataForSourceMaps)}
 	} else {
 		// Otherwise, link each entry point with the runtime file separately
 		waitGroup := sync.WaitGroup{}
@@ -2482,6 +3307,18 @@ func (b *Bundle) Compile(log logger.Log, timer *helpers.Timer, mangleCache map[s
 		outputFiles = append(outputFiles, group...)
 	}
 
+	// Generate the container for exposed modules now that their paths are known
+	if len(options.ExposedModules) > 0 {
+		outputFiles = append(outputFiles, b.generateFederationContainer(&options, outputFiles))
+	}
+
+	// Write out HTML entry points now that the paths of their references are known
+	if len(b.html.entryPoints) > 0 {
+		timer.Begin("Generate HTML files")
+		outputFiles = b.generateHTMLFiles(&options, files, outputFiles)
+		timer.End("Generate HTML files")
+	}
+
 	// Also generate the metadata file if necessary
 	var metafileJSON string
 	if options.NeedsMetafile {
@@ -2558,6 +3395,471 @@ func (b *Bundle) Compile(log logger.Log, timer *helpers.Timer, mangleCache map[s
 	return outputFiles, metafileJSON
 }
 
+// Find the stubs for all web workers that can be reached from the entry points
+// (including workers created by other workers). Workers are returned in an
+// order where each worker comes after all workers that it creates, since a
+// worker must be linked before the path or code of its output is known.
+func findWorkerStubs(log logger.Log, files []graph.InputFile, entryPoints []graph.EntryPoint) ([]uint32, bool) {
+	const (
+		notVisited uint8 = iota
+		inProgress
+		done
+	)
+	state := make(map[uint32]uint8)
+	var order []uint32
+	isValid := true
+	var visitFiles func([]graph.EntryPoint, bool)
+
+	visitStub := func(stubIndex uint32) {
+		switch state[stubIndex] {
+		case inProgress:
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot bundle the worker %q because it creates itself",
+				files[stubIndex].Source.PrettyPath))
+			isValid = false
+			return
+		case done:
+			return
+		}
+		state[stubIndex] = inProgress
+		stub := files[stubIndex].Repr.(*graph.CopyRepr)
+		visitFiles([]graph.EntryPoint{{SourceIndex: stub.WorkerSourceIndex.GetIndex()}}, true)
+		state[stubIndex] = done
+		order = append(order, stubIndex)
+	}
+
+	visitFiles = func(entryPoints []graph.EntryPoint, isInsideWorker bool) {
+		for _, sourceIndex := range findReachableFiles(files, entryPoints) {
+			if recordsPtr := files[sourceIndex].Repr.ImportRecords(); recordsPtr != nil {
+				for _, record := range *recordsPtr {
+					if record.CopySourceIndex.IsValid() {
+						if repr, ok := files[record.CopySourceIndex.GetIndex()].Repr.(*graph.CopyRepr); ok && repr.WorkerSourceIndex.IsValid() {
+							// Workers are bundled using the IIFE format where "import.meta.url"
+							// isn't available, and inline workers don't have a usable base URL
+							// either. So a worker can only create other workers that are inline.
+							if isInsideWorker && !repr.IsInlineWorker {
+								tracker := logger.MakeLineColumnTracker(&files[sourceIndex].Source)
+								log.AddErrorWithNotes(&tracker, record.Range,
+									fmt.Sprintf("Cannot create the worker %q from inside another worker", files[record.CopySourceIndex.GetIndex()].Source.PrettyPath),
+									[]logger.MsgData{{Text: "Workers created by other workers must be inline workers. " +
+										"You can append \"?inline\" to the path to embed this worker in the worker that creates it."}})
+								isValid = false
+								continue
+							}
+							visitStub(record.CopySourceIndex.GetIndex())
+						}
+					}
+				}
+			}
+		}
+	}
+
+	visitFiles(entryPoints, false)
+	return order, isValid
+}
+
+// Each web worker is linked by itself into a single self-contained file using
+// the IIFE format, which works with both classic and module workers. Then the
+// stub for each worker is updated with the result so that linking code that
+// creates the worker can reference it. This returns false if linking failed.
+func (b *Bundle) linkWorkers(
+	options *config.Options,
+	timer *helpers.Timer,
+	log logger.Log,
+	link Linker,
+	files []graph.InputFile,
+	workerStubs []uint32,
+	dataForSourceMaps func() []DataForSourceMap,
+) bool {
+	timer.Begin("Link web workers")
+	defer timer.End("Link web workers")
+
+	workerOptions := *options
+	workerOptions.OutputFormat = config.FormatIIFE
+	workerOptions.GlobalName = nil
+	workerOptions.CodeSplitting = false
+	workerOptions.AbsOutputFile = ""
+
+	// Workers aren't entry points that the user asked for, so name them like
+	// chunks. This avoids collisions with entry points that have the same name.
+	workerOptions.EntryPathTemplate = options.ChunkPathTemplate
+
+	for _, stubIndex := range workerStubs {
+		stub := files[stubIndex].Repr.(*graph.CopyRepr)
+		entryPoints := []graph.EntryPoint{{SourceIndex: stub.WorkerSourceIndex.GetIndex(), OutputPathWasAutoGenerated: true}}
+		outputFiles := link(&workerOptions, timer, log, b.fs, b.res, files, entryPoints,
+			b.uniqueKeyPrefix, findReachableFiles(files, entryPoints), dataForSourceMaps)
+
+		// The output file for the worker itself is the last JavaScript file since
+		// each chunk comes after its additional files, and JavaScript chunks come
+		// before CSS chunks. Move it to the front so it's the one that's referenced.
+		workerIndex := -1
+		for i, outputFile := range outputFiles {
+			if strings.HasSuffix(outputFile.AbsPath, workerOptions.OutputExtensionJS) {
+				workerIndex = i
+			}
+		}
+		if workerIndex == -1 {
+			// Linking failed, so an error has already been logged
+			return false
+		}
+		workerFile := outputFiles[workerIndex]
+		otherFiles := append(append([]graph.OutputFile{}, outputFiles[:workerIndex]...), outputFiles[workerIndex+1:]...)
+
+		// Don't mutate the original stub since it's shared with incremental builds
+		clone := *stub
+		file := &files[stubIndex]
+		file.Repr = &clone
+		if stub.IsInlineWorker {
+			clone.URLForCode = string(workerFile.Contents)
+			file.AdditionalFiles = otherFiles
+		} else {
+			file.AdditionalFiles = append([]graph.OutputFile{workerFile}, otherFiles...)
+		}
+	}
+	return true
+}
+
+// The container is an ES module that other builds can use to load the exposed
+// modules from this build by name at run time. Each exposed module is its own
+// entry point, so the container just imports the corresponding output file.
+func (b *Bundle) generateFederationContainer(options *config.Options, outputFiles []graph.OutputFile) graph.OutputFile {
+	// Find the output file for each exposed module. Exposed modules are the
+	// entry points that have been given their exposed name as an output path.
+	outputPaths := make(map[string]string)
+	for _, entryPoint := range b.entryPoints {
+		if entryPoint.OutputPathWasAutoGenerated {
+			continue
+		}
+		for _, outputFile := range outputFiles {
+			if outputFile.EntryPointSourceIndex.IsValid() && outputFile.EntryPointSourceIndex.GetIndex() == entryPoint.SourceIndex &&
+				strings.HasSuffix(outputFile.AbsPath, options.OutputExtensionJS) {
+				outputPaths[entryPoint.OutputPath] = outputFile.AbsPath
+			}
+		}
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("const modules = {\n")
+	for _, exposed := range options.ExposedModules {
+		absPath, ok := outputPaths[exposed.Name]
+		if !ok {
+			// The exposed module failed to build, so an error has already been logged
+			continue
+		}
+		relPath, ok := b.fs.Rel(options.AbsOutputDir, absPath)
+		if !ok {
+			continue
+		}
+		relPath = "./" + strings.ReplaceAll(relPath, "\\", "/")
+		sb.WriteString(fmt.Sprintf("  %s: () => import(%s),\n",
+			helpers.QuoteForJSON("./"+exposed.Name, options.ASCIIOnly),
+			helpers.QuoteForJSON(relPath, options.ASCIIOnly)))
+	}
+	sb.WriteString(`};
+function normalize(name) {
+  return name.startsWith("./") ? name : "./" + name;
+}
+export function has(name) {
+  return Object.prototype.hasOwnProperty.call(modules, normalize(name));
+}
+export function get(name) {
+  return has(name) ? modules[normalize(name)]() : Promise.reject(new Error("Module \"" + name + "\" is not exposed by this container"));
+}
+`)
+	contents := []byte(sb.String())
+
+	// Optionally add metadata about the file
+	var jsonMetadataChunk string
+	if options.NeedsMetafile {
+		jsonMetadataChunk = fmt.Sprintf(
+			"{\n      \"imports\": [],\n      \"exports\": [\n        \"get\",\n        \"has\"\n      ],\n      \"inputs\": {},\n      \"bytes\": %d\n    }",
+			len(contents))
+	}
+
+	return graph.OutputFile{
+		AbsPath:           b.fs.Join(options.AbsOutputDir, config.FederationContainerName+options.OutputExtensionJS),
+		Contents:          contents,
+		JSONMetadataChunk: jsonMetadataChunk,
+	}
+}
+
+// Each HTML entry point is written out mostly verbatim. The only changes are
+// that references to bundled files are replaced with the paths of the output
+// files for those entry points, and stylesheets generated for JavaScript entry
+// points are linked in so that they are loaded too. Small output files may
+// also be inlined into the HTML instead. This returns the new list of output
+// files, which includes the HTML files.
+func (b *Bundle) generateHTMLFiles(
+	options *config.Options,
+	files []graph.InputFile,
+	outputFiles []graph.OutputFile,
+) []graph.OutputFile {
+	// Find the output files for each entry point. JavaScript entry points that
+	// import CSS have two output files: one for the JS and one for the CSS.
+	jsOutputs := make(map[uint32]int)
+	cssOutputs := make(map[uint32]int)
+	for i, outputFile := range outputFiles {
+		if !outputFile.EntryPointSourceIndex.IsValid() {
+			continue
+		}
+		sourceIndex := outputFile.EntryPointSourceIndex.GetIndex()
+		if strings.HasSuffix(outputFile.AbsPath, options.OutputExtensionCSS) {
+			cssOutputs[sourceIndex] = i
+		} else {
+			jsOutputs[sourceIndex] = i
+		}
+	}
+
+	// Output files that are inlined everywhere are omitted if they only exist
+	// because of the HTML files, so track which ones are still referenced
+	inlined := make(map[int]bool)
+	referenced := make(map[int]bool)
+	var results []graph.OutputFile
+
+	for _, entryPoint := range b.html.entryPoints {
+		file := &files[entryPoint.SourceIndex]
+		repr := file.Repr.(*graph.HTMLRepr)
+		contents := file.Source.Contents
+
+		// Figure out the output path first since references are relative to it
+		var dir, base, ext string
+		if b.html.outputFile != "" {
+			// If the output path was configured explicitly, use it verbatim
+			dir = "/"
+			base = b.fs.Base(b.html.outputFile)
+			ext = b.fs.Ext(base)
+			base = base[:len(base)-len(ext)]
+		} else {
+			// Otherwise, derive the output path from the input path
+			_, _, ext = logger.PlatformIndependentPathDirBaseExt(file.Source.KeyPath.Text)
+			dir, base = PathRelativeToOutbase(
+				file,
+				options,
+				b.fs,
+				/* avoidIndex */ false,
+				entryPoint.OutputPath,
+			)
+		}
+		templateExt := strings.TrimPrefix(ext, ".")
+		relPathForHash := func(hash string) string {
+			return config.TemplateToString(config.SubstituteTemplate(options.EntryPathTemplate, config.PathPlaceholders{
+				Dir:  &dir,
+				Name: &base,
+				Hash: &hash,
+				Ext:  &templateExt,
+			})) + ext
+		}
+
+		// The hash isn't known yet, so this assumes it's not part of the directory
+		absDir := b.fs.Dir(b.fs.Join(options.AbsOutputDir, relPathForHash("")))
+		pathTo := func(outputIndex int) string {
+			absPath := outputFiles[outputIndex].AbsPath
+			referenced[outputIndex] = true
+			if options.PublicPath != "" {
+				if relPath, ok := b.fs.Rel(options.AbsOutputDir, absPath); ok {
+					return strings.TrimSuffix(options.PublicPath, "/") + "/" + strings.ReplaceAll(relPath, "\\", "/")
+				}
+			}
+			relPath, ok := b.fs.Rel(absDir, absPath)
+			if !ok {
+				return absPath
+			}
+			relPath = strings.ReplaceAll(relPath, "\\", "/")
+			if !strings.HasPrefix(relPath, "./") && !strings.HasPrefix(relPath, "../") {
+				relPath = "./" + relPath
+			}
+			return relPath
+		}
+
+		// Only output files in the same directory as the HTML file are inlined.
+		// Otherwise relative paths inside them (e.g. to other chunks, to assets,
+		// or to source maps) would be resolved relative to the wrong directory.
+		shouldInline := func(outputIndex int) bool {
+			outputFile := &outputFiles[outputIndex]
+			if len(outputFile.Contents) < options.HTMLInlineLimit && b.fs.Dir(outputFile.AbsPath) == absDir {
+				inlined[outputIndex] = true
+				return true
+			}
+			return false
+		}
+
+		// Stylesheets for JavaScript entry points are inserted before the end of
+		// the "<head>" tag, or before the first script that needs one if there's
+		// no "<head>" tag
+		var extraStylesheets []string
+		seenStylesheets := make(map[string]bool)
+		insertAt := repr.AST.HeadEnd
+		for _, ref := range repr.AST.References {
+			record := &repr.AST.ImportRecords[ref.ImportRecordIndex]
+			if ref.Kind == html_ast.ReferenceScript && record.SourceIndex.IsValid() {
+				if cssIndex, ok := cssOutputs[record.SourceIndex.GetIndex()]; ok {
+					var tag string
+					if shouldInline(cssIndex) {
+						tag = "<style>" + escapeForHTMLRawText(string(outputFiles[cssIndex].Contents), "style") + "</style>"
+					} else {
+						tag = "<link rel=\"stylesheet\" href=" + quoteForHTMLAttribute(pathTo(cssIndex)) + ">"
+					}
+					if !seenStylesheets[tag] {
+						seenStylesheets[tag] = true
+						extraStylesheets = append(extraStylesheets, tag)
+					}
+					if insertAt == -1 {
+						insertAt = ref.TagStart
+					}
+				}
+			}
+		}
+		var insertText string
+		if len(extraStylesheets) > 0 {
+			insertAt, insertText = htmlTextToInsert(contents, insertAt, insertAt == repr.AST.HeadEnd, extraStylesheets)
+		}
+
+		// Substitute each reference with the path to the corresponding output file
+		sb := strings.Builder{}
+		end := int32(0)
+		writeUntil := func(offset int32) {
+			if insertAt != -1 && insertAt >= end && insertAt <= offset {
+				sb.WriteString(contents[end:insertAt])
+				sb.WriteString(insertText)
+				end = insertAt
+				insertAt = -1
+			}
+			sb.WriteString(contents[end:offset])
+		}
+		for _, ref := range repr.AST.References {
+			record := &repr.AST.ImportRecords[ref.ImportRecordIndex]
+			if !record.SourceIndex.IsValid() {
+				continue
+			}
+			outputs := jsOutputs
+			if ref.Kind == html_ast.ReferenceStylesheet {
+				outputs = cssOutputs
+			}
+			outputIndex, ok := outputs[record.SourceIndex.GetIndex()]
+			if !ok {
+				continue
+			}
+
+			switch {
+			case !shouldInline(outputIndex):
+				writeUntil(ref.ValueRange.Loc.Start)
+				sb.WriteString(quoteForHTMLAttribute(pathTo(outputIndex)))
+				end = ref.ValueRange.End()
+
+			case ref.Kind == html_ast.ReferenceScript:
+				// Keep the other attributes (e.g. "type") but remove "src"
+				writeUntil(ref.AttributeRange.Loc.Start)
+				sb.WriteString(contents[ref.AttributeRange.End():ref.TagEnd])
+				sb.WriteString(escapeForHTMLRawText(string(outputFiles[outputIndex].Contents), "script"))
+				sb.WriteString("</script>")
+				end = ref.ElementEnd
+
+			default:
+				writeUntil(ref.TagStart)
+				sb.WriteString("<style>")
+				sb.WriteString(escapeForHTMLRawText(string(outputFiles[outputIndex].Contents), "style"))
+				sb.WriteString("</style>")
+				end = ref.TagEnd
+			}
+		}
+		writeUntil(int32(len(contents)))
+		bytes := []byte(sb.String())
+
+		// Add a hash to the file name to prevent multiple files with the same name
+		// but different contents from colliding
+		var hash string
+		if b.html.outputFile == "" && config.HasPlaceholder(options.EntryPathTemplate, config.HashPlaceholder) {
+			h := xxhash.New()
+			h.Write(bytes)
+			hash = HashForFileName(h.Sum(nil))
+		}
+
+		relPath := relPathForHash(hash)
+
+		// Optionally add metadata about the file
+		var jsonMetadataChunk string
+		if options.NeedsMetafile {
+			inputs := fmt.Sprintf("{\n        %s: {\n          \"bytesInOutput\": %d\n        }\n      }",
+				helpers.QuoteForJSON(file.Source.PrettyPath, options.ASCIIOnly),
+				len(contents),
+			)
+			jsonMetadataChunk = fmt.Sprintf(
+				"{\n      \"imports\": [],\n      \"exports\": [],\n      \"entryPoint\": %s,\n      \"inputs\": %s,\n      \"bytes\": %d\n    }",
+				helpers.QuoteForJSON(file.Source.PrettyPath, options.ASCIIOnly),
+				inputs,
+				len(bytes),
+			)
+		}
+
+		results = append(results, graph.OutputFile{
+			AbsPath:           b.fs.Join(options.AbsOutputDir, relPath),
+			Contents:          bytes,
+			JSONMetadataChunk: jsonMetadataChunk,
+		})
+	}
+
+	// Put the HTML files after the other output files
+	end := 0
+	for i, outputFile := range outputFiles {
+		if inlined[i] && !referenced[i] && b.html.addedEntryPoints[outputFile.EntryPointSourceIndex.GetIndex()] {
+			continue
+		}
+		outputFiles[end] = outputFile
+		end++
+	}
+	return append(outputFiles[:end], results...)
+}
+
+// The contents of raw text elements end at the first matching closing tag, so
+// any closing tags inside the contents must be escaped. The "\/" escape works
+// in JavaScript strings, regular expressions, and comments as well as in CSS.
+func escapeForHTMLRawText(text string, tag string) string {
+	var sb strings.Builder
+	start := 0
+	for i := 0; i+2+len(tag) <= len(text); i++ {
+		if text[i] == '<' && text[i+1] == '/' && strings.EqualFold(text[i+2:i+2+len(tag)], tag) {
+			sb.WriteString(text[start:i])
+			sb.WriteString("<\\/")
+			start = i + 2
+		}
+	}
+	if start == 0 {
+		return text
+	}
+	sb.WriteString(text[start:])
+	return sb.String()
+}
+
+// This tries to match the indentation of the surrounding HTML. Tags inserted
+// before a closing tag are indented like the line before it, and tags inserted
+// before an opening tag are indented like that tag.
+func htmlTextToInsert(contents string, insertAt int32, isBeforeClosingTag bool, tags []string) (int32, string) {
+	lineStart := strings.LastIndexByte(contents[:insertAt], '\n') + 1
+	indent := contents[lineStart:insertAt]
+	if strings.TrimLeft(indent, " \t") != "" {
+		// The tag isn't at the start of its line, so just put everything inline
+		return insertAt, strings.Join(tags, "")
+	}
+	if isBeforeClosingTag && lineStart > 0 {
+		prevLineStart := strings.LastIndexByte(contents[:lineStart-1], '\n') + 1
+		prevLine := contents[prevLineStart : lineStart-1]
+		indent = prevLine[:len(prevLine)-len(strings.TrimLeft(prevLine, " \t"))]
+	}
+	sb := strings.Builder{}
+	for _, tag := range tags {
+		sb.WriteString(indent)
+		sb.WriteString(tag)
+		sb.WriteByte('\n')
+	}
+	return int32(lineStart), sb.String()
+}
+
+func quoteForHTMLAttribute(text string) string {
+	text = strings.ReplaceAll(text, "&", "&amp;")
+	text = strings.ReplaceAll(text, "\"", "&quot;")
+	return "\"" + text + "\""
+}
+
 // Find all files reachable from all entry points. This order should be
 // deterministic given that the entry point order is deterministic, since the
 // returned order is the postorder of the graph traversal and import record
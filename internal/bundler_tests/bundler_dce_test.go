@@ -6,6 +6,8 @@ import (
 
 	"github.com/evanw/esbuild/internal/compat"
 	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
 )
 
 var dce_suite = suite{
@@ -1061,6 +1063,62 @@ func TestJSONLoaderRemoveUnused(t *testing.T) {
 	})
 }
 
+func TestJSONLoaderRemoveUnusedDefaultProperties(t *testing.T) {
+	dce_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import data from "./data.json"
+				import other from "./other.js"
+				console.log(data.used, data['alsoUsed'], other)
+			`,
+			"/other.js": `
+				import data from "./data.json"
+				import {named} from "./data.json"
+				export default [data.fromOtherFile, named]
+			`,
+			"/data.json": `{
+				"used": 1,
+				"alsoUsed": [2],
+				"fromOtherFile": { "x": 3 },
+				"named": 4,
+				"unused": "this should be removed"
+			}`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestJSONLoaderKeepDefaultPropertiesWhenCaptured(t *testing.T) {
+	dce_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import captured from "./captured.json"
+				import inherited from "./inherited.json"
+				import assigned from "./assigned.json"
+				import * as ns from "./namespace.json"
+				import viaNamespace from "./namespace.json"
+				console.log(captured.used, captured)
+				console.log(inherited.hasOwnProperty('unused'))
+				assigned.used = true
+				console.log(viaNamespace.used, ns)
+			`,
+			"/captured.json":  `{ "used": 1, "unused": 2 }`,
+			"/inherited.json": `{ "used": 1, "unused": 2 }`,
+			"/assigned.json":  `{ "used": 1, "unused": 2 }`,
+			"/namespace.json": `{ "used": 1, "unused": 2 }`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
 func TestTextLoaderRemoveUnused(t *testing.T) {
 	dce_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -1297,6 +1355,49 @@ func TestDisableTreeShaking(t *testing.T) {
 	})
 }
 
+func TestDCESwitchWithConstantTest(t *testing.T) {
+	defines := config.ProcessDefines(map[string]config.DefineData{
+		"process.env.NODE_ENV": {
+			DefineExpr: &config.DefineExpr{
+				Constant: &js_ast.EString{Value: helpers.StringToUTF16("production")},
+			},
+		},
+	})
+	dce_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { dev, prod, test, other } from './lib'
+				switch (process.env.NODE_ENV) {
+					case 'development':
+						dev()
+						break
+					case 'production':
+						prod()
+					case 'test':
+						test()
+						break
+					default:
+						var keepMe = other()
+				}
+				console.log(keepMe)
+			`,
+			"/lib.js": `
+				export function dev() { console.log('dev') }
+				export function prod() { console.log('prod') }
+				export function test() { console.log('test') }
+				export function other() { console.log('other') }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			MinifySyntax:  true,
+			Defines:       &defines,
+		},
+	})
+}
+
 func TestDeadCodeFollowingJump(t *testing.T) {
 	dce_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -2584,6 +2685,62 @@ func TestInlineEmptyFunctionCalls(t *testing.T) {
 	})
 }
 
+func TestInlineConstReturnFunctionCalls(t *testing.T) {
+	dce_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/const.js": `
+				function num() { return 123 }
+				function bool(a, b) { return false }
+				function nil() { return null }
+				console.log(num(), bool(foo(), bar()), nil(1))
+				console.log(bool(...args), num(1, foo()))
+				if (bool()) console.log(num())
+				num(foo())
+				nil()
+			`,
+
+			"/const-cross-module.js": `
+				import { isProd } from './const-cross-module-def'
+				console.log(isProd())
+				isProd()
+			`,
+
+			"/const-cross-module-def.js": `
+				export function isProd() { return true }
+			`,
+
+			"/keep-default-arg.js": `
+				function keep(a = foo()) { return 1 }
+				console.log(keep())
+			`,
+
+			"/keep-non-const.js": `
+				function keep() { return 'long string' }
+				function keep2() { foo(); return 1 }
+				console.log(keep(), keep2())
+			`,
+
+			"/keep-reassign.js": `
+				function keep() { return 1 }
+				console.log(keep())
+				keep = function() { return 2 }
+			`,
+		},
+		entryPaths: []string{
+			"/const.js",
+			"/const-cross-module.js",
+			"/keep-default-arg.js",
+			"/keep-non-const.js",
+			"/keep-reassign.js",
+		},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			MinifySyntax: true,
+		},
+	})
+}
+
 func TestInlineFunctionCallBehaviorChanges(t *testing.T) {
 	dce_suite.expectBundled(t, bundled{
 		files: map[string]string{
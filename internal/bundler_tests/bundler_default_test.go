@@ -240,6 +240,238 @@ func TestExportFormsIIFE(t *testing.T) {
 	})
 }
 
+func TestExportFormsUMD(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export default 123
+				export var v = 234
+				export let l = 234
+				export const c = 234
+				export {Class as C}
+				export function Fn() {}
+				export class Class {}
+				export * from './a'
+				export * as b from './b'
+			`,
+			"/a.js": "export const abc = undefined",
+			"/b.js": "export const xyz = null",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatUMD,
+			GlobalName:    []string{"globalName"},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestUMDExternalGlobals(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import React from 'react'
+				import { render } from 'react-dom'
+				const lodash = require('lodash')
+				export let app = render(React.createElement('div'), lodash)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatUMD,
+			GlobalName:   []string{"my", "lib"},
+			UMDGlobals: map[string][]string{
+				"react":     {"React"},
+				"react-dom": {"ReactDOM"},
+				"unused":    {"Unused"},
+			},
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"react":     true,
+					"react-dom": true,
+					"lodash":    true,
+					"unused":    true,
+				}},
+			},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestUMDCommonJSEntryPointMinified(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				module.exports = { foo: require('foo') }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:              config.ModeBundle,
+			OutputFormat:      config.FormatUMD,
+			MinifyWhitespace:  true,
+			MinifyIdentifiers: true,
+			UMDGlobals: map[string][]string{
+				"foo": {"Foo", "default"},
+			},
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"foo": true,
+				}},
+			},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestExportFormsSystem(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export default 123
+				export var v = 234
+				export let l = 234
+				export const c = 234
+				export {Class as C}
+				export function Fn() {}
+				export class Class {}
+				export * from './a'
+				export * as b from './b'
+			`,
+			"/a.js": "export const abc = undefined",
+			"/b.js": "export const xyz = null",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestSystemLiveBindings(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import React, { useState } from 'react'
+				import * as lodash from 'lodash'
+				export { count, count as total, increment } from './counter'
+				export let state = useState(React, lodash)
+				export function reset() {
+					state = null
+					state ||= {}
+				}
+			`,
+			"/counter.js": `
+				export let count = 0
+				export function increment() {
+					count++
+					return [count++, --count, count += 2]
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatSystem,
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"react":  true,
+					"lodash": true,
+				}},
+			},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestSystemLiveBindingsDestructuringAndLoops(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export let a, b, c, d
+				export var e
+				let local
+				export function destructure(v, o) {
+					[a] = [v];
+					({ b } = o);
+					[a = 1, { c: [d] = [] }, ...b] = v;
+					[local] = v;
+					return { a } = o
+				}
+				export function loops(arr, obj) {
+					for (a of arr) console.log(a)
+					for (b in obj) ;
+					for ([c, d = 2] of arr) {
+						console.log(c, d)
+					}
+					for (local of arr) ;
+				}
+				for (var e of [1, 2]) ;
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestSystemCommonJSEntryPointMinified(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				module.exports = { foo: require('foo') }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:              config.ModeBundle,
+			OutputFormat:      config.FormatSystem,
+			MinifyWhitespace:  true,
+			MinifyIdentifiers: true,
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"foo": true,
+				}},
+			},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestSystemIndentUnitAndSingleQuotes(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				'use strict'
+				import { x } from "foo"
+				export function f() {
+					if (x) return "y"
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:               config.ModeBundle,
+			OutputFormat:       config.FormatSystem,
+			IndentUnit:         "\t",
+			PreferSingleQuotes: true,
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"foo": true,
+				}},
+			},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
 func TestExportFormsWithMinifyIdentifiersAndNoBundle(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -1334,6 +1566,132 @@ func TestSourceMap(t *testing.T) {
 	})
 }
 
+func TestSourceMapDebugID(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import './style.css'
+				function foo() { throw new Error('test') }
+				foo()
+			`,
+			"/Users/user/project/src/style.css": `
+				a { color: red }
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			SourceMap:        config.SourceMapLinkedWithComment,
+			SourceMapDebugID: true,
+			AbsOutputDir:     "/Users/user/project/out",
+		},
+	})
+}
+
+func TestSourceMapIgnoreList(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import {foo} from 'pkg'
+				import {bar} from './bar'
+				foo(bar)
+			`,
+			"/Users/user/project/src/bar.js": `
+				export let bar = 123
+			`,
+			"/Users/user/project/node_modules/pkg/index.js": `
+				export function foo(x) { throw new Error(x) }
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			SourceMap:             config.SourceMapLinkedWithComment,
+			SourceMapIgnoreList:   regexp.MustCompile("node_modules"),
+			ExcludeSourcesContent: true,
+			AbsOutputFile:         "/Users/user/project/out.js",
+		},
+	})
+}
+
+func TestSourceMapPathPrefix(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import {foo} from 'pkg'
+				import {bar} from './bar'
+				foo(bar)
+			`,
+			"/Users/user/project/src/bar.js": `
+				export let bar = 123
+			`,
+			"/Users/user/project/node_modules/pkg/index.js": `
+				export function foo(x) { throw new Error(x) }
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:      config.ModeBundle,
+			SourceMap: config.SourceMapLinkedWithComment,
+			SourceMapPathPrefix: map[string]string{
+				"src/":              "webpack:///./src/",
+				"node_modules/":     "webpack:///./node_modules/",
+				"node_modules/pkg/": "webpack:///pkg/",
+			},
+			ExcludeSourcesContent: true,
+			AbsOutputFile:         "/Users/user/project/out.js",
+		},
+	})
+}
+
+func TestSourceMapLinesOnly(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import {bar} from './bar'
+				function foo(x) { throw new Error(x) }
+				foo(bar)
+			`,
+			"/Users/user/project/src/bar.js": `
+				export let bar = [
+					1,
+					2,
+				].map(x => x * 2)
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			SourceMap:             config.SourceMapLinkedWithComment,
+			SourceMapLinesOnly:    true,
+			ExcludeSourcesContent: true,
+			AbsOutputFile:         "/Users/user/project/out.js",
+		},
+	})
+}
+
+func TestSourceMapInputSections(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `let a = 1;
+console.log("a_marker", a);
+let b = "x";
+console.log("b_marker", b);
+//# sourceMappingURL=data:application/json,{"version":3,"sections":[` +
+				`{"offset":{"line":0,"column":0},"map":{"version":3,"sources":["a.ts"],"mappings":"AAAA,IAAI,IAAY;AAChB,QAAQ,IAAI,YAAY,CAAC;","names":[]}},` +
+				`{"offset":{"line":2,"column":0},"map":{"version":3,"sources":["b.ts"],"mappings":"AAEA,IAAI,IAAY;AAChB,QAAQ,IAAI,YAAY,CAAC;","names":[]}}]}
+`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			SourceMap:             config.SourceMapLinkedWithComment,
+			ExcludeSourcesContent: true,
+			AbsOutputFile:         "/Users/user/project/out.js",
+		},
+	})
+}
+
 // This test covers a bug where a "var" in a nested scope did not correctly
 // bind with references to that symbol in sibling scopes. Instead, the
 // references were incorrectly considered to be unbound even though the symbol
@@ -2979,28 +3337,107 @@ func TestUseStrictDirectiveBundleESMIssue2264(t *testing.T) {
 	})
 }
 
-func TestNoOverwriteInputFileError(t *testing.T) {
+func TestDirectivesBundleEntryPoint(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
 			"/entry.js": `
-				console.log(123)
+				'use client'
+				'use strict'
+				'use client'
+				import { foo } from './foo'
+				foo()
+			`,
+			"/foo.js": `
+				'use server'
+				export let foo = () => {}
 			`,
 		},
 		entryPaths: []string{"/entry.js"},
 		options: config.Options{
-			Mode:         config.ModeBundle,
-			AbsOutputDir: "/",
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			OutputFormat:  config.FormatESModule,
+			NeedsMetafile: true,
 		},
-		expectedCompileLog: `ERROR: Refusing to overwrite input file "entry.js" (use "AllowOverwrite: true" to allow this)
-`,
 	})
 }
 
-func TestDuplicateEntryPoint(t *testing.T) {
+func TestDirectivesBundleWrappedModule(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
 			"/entry.js": `
-				console.log(123)
+				console.log(require('./cjs'))
+			`,
+			"/cjs.js": `
+				'use client'
+				exports.foo = 123
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			OutputFormat:  config.FormatESModule,
+		},
+	})
+}
+
+func TestDirectivesCodeSplittingChunk(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { x } from './x'
+				import { y } from './y'
+				console.log(x, y)
+			`,
+			"/b.js": `
+				import { x } from './x'
+				import { y } from './y'
+				console.log(x, y)
+			`,
+			"/x.js": `
+				'use client'
+				export let x = 1
+			`,
+			"/y.js": `
+				'use strict'
+				'use client'
+				export let y = 2
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputDir:  "/out",
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			NeedsMetafile: true,
+		},
+	})
+}
+
+func TestNoOverwriteInputFileError(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(123)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/",
+		},
+		expectedCompileLog: `ERROR: Refusing to overwrite input file "entry.js" (use "AllowOverwrite: true" to allow this)
+`,
+	})
+}
+
+func TestDuplicateEntryPoint(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(123)
 			`,
 		},
 		entryPaths: []string{"/entry.js", "/entry.js"},
@@ -3198,14 +3635,150 @@ func TestImportMetaCommonJS(t *testing.T) {
 			OutputFormat:  config.FormatCommonJS,
 			AbsOutputFile: "/out.js",
 		},
-		expectedScanLog: `entry.js: WARNING: "import.meta" is not available with the "cjs" output format and will be empty
-NOTE: You need to set the output format to "esm" for "import.meta" to work correctly.
+		expectedScanLog: `entry.js: WARNING: "import.meta.path" is not available with the "cjs" output format and will be undefined
+NOTE: You can use the "import meta shim" or "define" features to provide a value for "import.meta.path", or you can set the output format to "esm" for "import.meta" to work correctly.
+`,
+	})
+}
+
+func TestImportMetaCommonJSNode(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(import.meta.url, import.meta.dirname, import.meta.path, import.meta)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatCommonJS,
+			Platform:      config.PlatformNode,
+			AbsOutputFile: "/out.js",
+			Defines: &config.ProcessedDefines{
+				DotDefines: map[string][]config.DotDefine{
+					"dirname": {{Parts: []string{"import", "meta", "dirname"}, Data: config.DefineData{
+						DefineExpr: &config.DefineExpr{Parts: []string{"__dirname"}},
+					}}},
+				},
+			},
+		},
+		expectedScanLog: `entry.js: WARNING: "import.meta.path" is not available with the "cjs" output format and will be undefined
+NOTE: You can use the "import meta shim" or "define" features to provide a value for "import.meta.path", or you can set the output format to "esm" for "import.meta" to work correctly.
 entry.js: WARNING: "import.meta" is not available with the "cjs" output format and will be empty
 NOTE: You need to set the output format to "esm" for "import.meta" to work correctly.
 `,
 	})
 }
 
+func TestImportMetaIIFENode(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(import.meta.url)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatIIFE,
+			Platform:      config.PlatformNode,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestImportMetaIIFEBrowser(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(import.meta.url)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatIIFE,
+			Platform:      config.PlatformBrowser,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestImportMetaIIFENeutral(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(import.meta.url)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatIIFE,
+			Platform:      config.PlatformNeutral,
+			AbsOutputFile: "/out.js",
+		},
+		expectedScanLog: `entry.js: WARNING: "import.meta.url" is not available with the "iife" output format and will be undefined
+NOTE: You can use the "import meta shim" or "define" features to provide a value for "import.meta.url", or you can set the output format to "esm" for "import.meta" to work correctly.
+`,
+	})
+}
+
+func TestImportMetaShimObject(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(import.meta.url, import.meta.env.MODE, import.meta.hot, import.meta)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			OutputFormat:   config.FormatCommonJS,
+			ImportMetaShim: `{"url": "https://example.com/entry.js", "env": {"MODE": "production"}}`,
+			AbsOutputFile:  "/out.js",
+		},
+		expectedScanLog: `entry.js: WARNING: "import.meta.hot" is not available with the "cjs" output format and will be undefined
+NOTE: You can use the "import meta shim" or "define" features to provide a value for "import.meta.hot", or you can set the output format to "esm" for "import.meta" to work correctly.
+`,
+	})
+}
+
+func TestImportMetaShimEntityName(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(import.meta.url, import.meta.env.MODE, import.meta)
+				import.meta.foo = 1
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			OutputFormat:   config.FormatIIFE,
+			ImportMetaShim: "globalThis.importMeta",
+			AbsOutputFile:  "/out.js",
+		},
+	})
+}
+
+func TestImportMetaShimESM(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(import.meta.url, import.meta.env)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			OutputFormat:   config.FormatESModule,
+			ImportMetaShim: "globalThis.importMeta",
+			AbsOutputFile:  "/out.js",
+		},
+	})
+}
+
 func TestImportMetaES6(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -3727,6 +4300,48 @@ func TestLegalCommentsManyLinked(t *testing.T) {
 	})
 }
 
+func TestExtractLicenses(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/project/entry.js": `
+				import 'pkg-a'
+				import 'pkg-b/sub'
+				import './local'
+			`,
+			"/project/local.js":                        `console.log('local') //! Local copyright notice`,
+			"/project/node_modules/pkg-a/package.json": `{ "name": "pkg-a", "version": "1.2.3", "license": "MIT" }`,
+			"/project/node_modules/pkg-a/LICENSE":      "\nCopyright (c) pkg-a authors\n\nPermission is hereby granted...\n",
+			"/project/node_modules/pkg-a/index.js": `
+				import 'pkg-c'
+				function a() {
+					/*! (c) pkg-a authors */
+					console.log('pkg-a')
+				}
+				a()
+			`,
+			"/project/node_modules/pkg-b/package.json":       `{ "name": "pkg-b", "version": "0.1.0", "licenses": [{ "type": "MIT" }, { "type": "Apache-2.0" }] }`,
+			"/project/node_modules/pkg-b/LICENSE-MIT.txt":    `MIT license text`,
+			"/project/node_modules/pkg-b/LICENSE-APACHE.txt": `Apache license text`,
+			"/project/node_modules/pkg-b/sub/package.json":   `{ "main": "main.js" }`,
+			"/project/node_modules/pkg-b/sub/main.js":        `console.log('pkg-b')`,
+			"/project/node_modules/pkg-c/package.json":       `{ "name": "pkg-c", "version": "2.0.0", "license": { "type": "ISC" } }`,
+			"/project/node_modules/pkg-c/index.js":           `console.log('pkg-c')`,
+
+			"/project/entry.css": `
+				@import 'pkg-a/style.css';
+				a { color: red }
+			`,
+			"/project/node_modules/pkg-a/style.css": `/*! (c) pkg-a styles */ b { color: blue }`,
+		},
+		entryPaths: []string{"/project/entry.js", "/project/entry.css"},
+		options: config.Options{
+			Mode:            config.ModeBundle,
+			AbsOutputDir:    "/out",
+			ExtractLicenses: true,
+		},
+	})
+}
+
 // The IIFE should not be an arrow function when targeting ES5
 func TestIIFE_ES5(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
@@ -6296,6 +6911,77 @@ func TestRequireShimSubstitution(t *testing.T) {
 	})
 }
 
+func TestNodeESMDefinesCommonJSGlobals(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { readConfig } from './cjs'
+				export function dirname() {}
+				console.log(readConfig(), dirname)
+			`,
+			"/cjs.js": `
+				const fs = require('fs')
+				exports.readConfig = () => fs.readFileSync(__dirname + '/config.json')
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			Platform:      config.PlatformNode,
+			AbsOutputFile: "/out.js",
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"fs": true,
+				}},
+			},
+		},
+	})
+}
+
+func TestNodeESMDefinesCommonJSGlobalsMinified(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(__filename, __dirname, typeof require)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			OutputFormat:     config.FormatESModule,
+			Platform:         config.PlatformNode,
+			MinifyWhitespace: true,
+			AbsOutputFile:    "/out.js",
+		},
+	})
+}
+
+func TestNodeESMDefinesCommonJSGlobalsUnused(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import fs from 'fs'
+				if (false) require('path')
+				console.log(fs)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			Platform:      config.PlatformNode,
+			AbsOutputFile: "/out.js",
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"fs":   true,
+					"path": true,
+				}},
+			},
+		},
+	})
+}
+
 // This guards against a bad interaction between the strict mode nested function
 // declarations, name keeping, and initialized variable inlining. See this issue
 // for full context: https://github.com/evanw/esbuild/issues/1552.
@@ -6854,37 +7540,147 @@ func TestReserveProps(t *testing.T) {
 	})
 }
 
-func TestManglePropsImportExport(t *testing.T) {
+func TestReserveNames(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
-			// These don't count as property names, and aren't mangled
-			"/esm.js": `
-				export let foo_ = 123
-				import { bar_ } from 'xyz'
+			"/entry.js": `
+				import { render } from './render'
+				let $ = window.jQuery
+				export function main(jQuery) {
+					let a = 1, b = 2, c = 3, d = 4
+					return render($, jQuery, a, b, c, d)
+				}
 			`,
-
-			// These do count as property names, and are mangled
-			"/cjs.js": `
-				exports.foo_ = 123
-				let bar_ = require('xyz').bar_
+			"/render.js": `
+				export function render(node, value, ...rest) {
+					let e = node, t = value, n = rest
+					return [e, t, n]
+				}
 			`,
 		},
-		entryPaths: []string{
-			"/esm.js",
-			"/cjs.js",
-		},
+		entryPaths: []string{"/entry.js"},
 		options: config.Options{
-			Mode:         config.ModePassThrough,
-			AbsOutputDir: "/out",
-			MangleProps:  regexp.MustCompile("_$"),
+			Mode:              config.ModeBundle,
+			OutputFormat:      config.FormatESModule,
+			AbsOutputFile:     "/out.js",
+			MinifyIdentifiers: true,
+			ReserveNames:      []string{"$", "jQuery", "e", "t", "n"},
 		},
 	})
 }
 
-func TestManglePropsImportExportBundled(t *testing.T) {
+func TestHoistRepeatedStrings(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
-			// Note: import and export syntax does not count as a property name. That
+			"/entry.js": `
+				const { translate } = require('./translate')
+				console.log(translate('greeting.hello'), translate('greeting.hello'))
+				console.log({ 'greeting.hello': 'greeting.hello' }, x['greeting.hello'])
+				with (x) console.log('greeting.hello')
+			`,
+			"/translate.js": `
+				export function translate(key) {
+					return key === 'greeting.hello' ? 'Hello, world' : key
+				}
+				export function unused() {
+					return ['greeting.goodbye', 'greeting.goodbye', 'greeting.goodbye']
+				}
+				function alsoUnused() {
+					return ['greeting.goodbye', 'greeting.goodbye', 'greeting.goodbye']
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:              config.ModeBundle,
+			OutputFormat:      config.FormatIIFE,
+			AbsOutputFile:     "/out.js",
+			MinifySyntax:      true,
+			MinifyIdentifiers: true,
+		},
+	})
+}
+
+func TestHoistRepeatedStringsKeys(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { 'greeting.hello' as imported } from './other'
+				let { 'greeting.hello': a, ['greeting.hello']: b } = x
+				console.log(a, b, imported, 'greeting.hello', 'greeting.hello', 'greeting.hello')
+				console.log({ 'greeting.hello': 1 }, { ['greeting.hello']: 2 })
+				class Foo { 'greeting.hello'() {} static ['greeting.hello'] = 3 }
+				export { Foo as 'greeting.hello' }
+			`,
+			"/other.js": `
+				let value = 4
+				export { value as 'greeting.hello' }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:              config.ModeBundle,
+			OutputFormat:      config.FormatESModule,
+			AbsOutputFile:     "/out.js",
+			MinifySyntax:      true,
+			MinifyIdentifiers: true,
+		},
+	})
+}
+
+func TestHoistRepeatedStringsESMImports(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { run } from './other'
+				export function label() {
+					return ['greeting.hello', 'greeting.hello', 'greeting.hello']
+				}
+				run()
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:              config.ModeConvertFormat,
+			OutputFormat:      config.FormatESModule,
+			AbsOutputFile:     "/out.js",
+			MinifySyntax:      true,
+			MinifyIdentifiers: true,
+		},
+	})
+}
+
+func TestManglePropsImportExport(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			// These don't count as property names, and aren't mangled
+			"/esm.js": `
+				export let foo_ = 123
+				import { bar_ } from 'xyz'
+			`,
+
+			// These do count as property names, and are mangled
+			"/cjs.js": `
+				exports.foo_ = 123
+				let bar_ = require('xyz').bar_
+			`,
+		},
+		entryPaths: []string{
+			"/esm.js",
+			"/cjs.js",
+		},
+		options: config.Options{
+			Mode:         config.ModePassThrough,
+			AbsOutputDir: "/out",
+			MangleProps:  regexp.MustCompile("_$"),
+		},
+	})
+}
+
+func TestManglePropsImportExportBundled(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			// Note: import and export syntax does not count as a property name. That
 			// means the following code is broken. This test just serves to document
 			// this behavior and to detect if something about this behavior changes.
 			"/entry-esm.js": `
@@ -7216,6 +8012,40 @@ func TestManglePropsSuperCall(t *testing.T) {
 	})
 }
 
+func TestManglePropsRemoveUnusedMethods(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { Bar } from './bar'
+				class Foo {
+					used_() {}
+					unused_() {}
+					get unusedGetter_() {}
+					set unusedSetter_(x) {}
+					static unusedStatic_() {}
+					unusedField_ = 1
+					usedByObject_() {}
+					usedInOtherFile_() {}
+					run() { this.used_() }
+				}
+				console.log(new Foo, new Bar, { usedByObject_: 1 })
+			`,
+			"/bar.js": `
+				export class Bar {
+					unused_() {}
+					run(foo) { foo.usedInOtherFile_() }
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			MangleProps:   regexp.MustCompile("_$"),
+		},
+	})
+}
+
 func TestMangleNoQuotedProps(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -7551,176 +8381,562 @@ func TestPackageAlias(t *testing.T) {
 	})
 }
 
-func TestErrorsForAssertTypeJSON(t *testing.T) {
+func TestNodePolyfills(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
-			"/js-entry.js": `
-				import all from './foo.json' assert { type: 'json' }
-				import { default as def } from './foo.json' assert { type: 'json' }
-				import { unused } from './foo.json' assert { type: 'json' }
-				import { used } from './foo.json' assert { type: 'json' }
-				import * as ns from './foo.json' assert { type: 'json' }
-				use(used, ns.prop)
-				export { exported } from './foo.json' assert { type: 'json' }
-				import text from './foo.text' assert { type: 'json' }
-				import file from './foo.file' assert { type: 'json' }
-				import copy from './foo.copy' assert { type: 'json' }
-			`,
-			"/ts-entry.ts": `
-				import all from './foo.json' assert { type: 'json' }
-				import { default as def } from './foo.json' assert { type: 'json' }
-				import { unused } from './foo.json' assert { type: 'json' }
-				import { used } from './foo.json' assert { type: 'json' }
-				import * as ns from './foo.json' assert { type: 'json' }
-				use(used, ns.prop)
-				export { exported } from './foo.json' assert { type: 'json' }
-				import text from './foo.text' assert { type: 'json' }
-				import file from './foo.file' assert { type: 'json' }
-				import copy from './foo.copy' assert { type: 'json' }
+			"/entry.js": `
+				import { join } from "path"
+				import { EventEmitter } from "node:events"
+				import "./nested"
+				console.log(join, EventEmitter)
 			`,
-			"/foo.json": `{}`,
-			"/foo.text": `{}`,
-			"/foo.file": `{}`,
-			"/foo.copy": `{}`,
+			"/nested/index.js": `import "path"`,
+			"/nested/node_modules/path-browserify/index.js": `test failure`,
+			"/node_modules/path-browserify/index.js":        `export function join() {}`,
+			"/node_modules/events/index.js":                 `export class EventEmitter {}`,
 		},
-		entryPaths: []string{
-			"/js-entry.js",
-			"/ts-entry.ts",
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			NodePolyfills: true,
+		},
+	})
+}
+
+func TestNodePolyfillsAliasOverride(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js":                              `import "path"`,
+			"/node_modules/path-browserify/index.js": `test failure`,
+			"/node_modules/my-path/index.js":         `console.log("my-path")`,
 		},
+		entryPaths: []string{"/entry.js"},
 		options: config.Options{
-			Mode:         config.ModeBundle,
-			AbsOutputDir: "/out",
-			ExtensionToLoader: map[string]config.Loader{
-				".js":   config.LoaderJS,
-				".ts":   config.LoaderTS,
-				".json": config.LoaderJSON,
-				".text": config.LoaderText,
-				".file": config.LoaderFile,
-				".copy": config.LoaderCopy,
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			NodePolyfills: true,
+			PackageAliases: map[string]string{
+				"path": "my-path",
 			},
 		},
-		expectedScanLog: `js-entry.js: ERROR: Cannot use non-default import "unused" with a standard JSON module
-js-entry.js: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
-NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "unused" import (which is non-standard behavior).
-js-entry.js: ERROR: Cannot use non-default import "used" with a standard JSON module
-js-entry.js: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
-NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "used" import (which is non-standard behavior).
-js-entry.js: WARNING: Non-default import "prop" is undefined with a standard JSON module
-js-entry.js: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
-NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "prop" import (which is non-standard behavior).
-js-entry.js: ERROR: Cannot use non-default import "exported" with a standard JSON module
-js-entry.js: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
-NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "exported" import (which is non-standard behavior).
-js-entry.js: ERROR: The file "foo.text" was loaded with the "text" loader
-js-entry.js: NOTE: This import assertion requires the loader to be "json" instead:
-NOTE: You need to either reconfigure esbuild to ensure that the loader for this file is "json" or you need to remove this import assertion.
-js-entry.js: ERROR: The file "foo.file" was loaded with the "file" loader
-js-entry.js: NOTE: This import assertion requires the loader to be "json" instead:
-NOTE: You need to either reconfigure esbuild to ensure that the loader for this file is "json" or you need to remove this import assertion.
-ts-entry.ts: ERROR: Cannot use non-default import "used" with a standard JSON module
-ts-entry.ts: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
-NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "used" import (which is non-standard behavior).
-ts-entry.ts: WARNING: Non-default import "prop" is undefined with a standard JSON module
-ts-entry.ts: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
-NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "prop" import (which is non-standard behavior).
-ts-entry.ts: ERROR: Cannot use non-default import "exported" with a standard JSON module
-ts-entry.ts: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
-NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "exported" import (which is non-standard behavior).
-`,
 	})
 }
 
-func TestOutputForAssertTypeJSON(t *testing.T) {
+func TestNodePolyfillsMissing(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
-			"/js-entry.js": `
-				import all from './foo.json' assert { type: 'json' }
-				import copy from './foo.copy' assert { type: 'json' }
-				import { default as def } from './foo.json' assert { type: 'json' }
-				import * as ns from './foo.json' assert { type: 'json' }
-				use(all, copy, def, ns.prop)
-				export { default } from './foo.json' assert { type: 'json' }
-			`,
-			"/ts-entry.ts": `
-				import all from './foo.json' assert { type: 'json' }
-				import copy from './foo.copy' assert { type: 'json' }
-				import { default as def } from './foo.json' assert { type: 'json' }
-				import { unused } from './foo.json' assert { type: 'json' }
-				import * as ns from './foo.json' assert { type: 'json' }
-				use(all, copy, def, ns.prop)
-				export { default } from './foo.json' assert { type: 'json' }
+			"/entry.js": `
+				import "node:stream"
+				import "events"
+				import "fs"
 			`,
-			"/foo.json": `{}`,
-			"/foo.copy": `{}`,
-		},
-		entryPaths: []string{
-			"/js-entry.js",
-			"/ts-entry.ts",
 		},
+		entryPaths: []string{"/entry.js"},
 		options: config.Options{
-			Mode:         config.ModeBundle,
-			AbsOutputDir: "/out",
-			ExtensionToLoader: map[string]config.Loader{
-				".js":   config.LoaderJS,
-				".ts":   config.LoaderTS,
-				".json": config.LoaderJSON,
-				".copy": config.LoaderCopy,
-			},
-		},
-		expectedScanLog: `js-entry.js: WARNING: Non-default import "prop" is undefined with a standard JSON module
-js-entry.js: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
-NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "prop" import (which is non-standard behavior).
-ts-entry.ts: WARNING: Non-default import "prop" is undefined with a standard JSON module
-ts-entry.ts: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
-NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "prop" import (which is non-standard behavior).
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			NodePolyfills: true,
+		},
+		expectedScanLog: `entry.js: ERROR: Could not resolve "stream-browserify" (originally "node:stream")
+NOTE: The node built-in "node:stream" was substituted with the browser polyfill "stream-browserify", which then couldn't be resolved. You may need to install the "stream-browserify" package in the current working directory.
+NOTE: 
+NOTE: You can mark the path "stream-browserify" as external to exclude it from the bundle, which will remove this error.
+entry.js: ERROR: Could not resolve "events"
+NOTE: The node built-in "events" is provided by the browser polyfill package of the same name, which couldn't be resolved. You may need to install the "events" package in the current working directory.
+NOTE: 
+NOTE: You can mark the path "events" as external to exclude it from the bundle, which will remove this error.
+entry.js: ERROR: Could not resolve "fs"
+NOTE: The package "fs" wasn't found on the file system but is built into node. Are you trying to bundle for node? You can use "Platform: api.PlatformNode" to do that, which will remove this error.
 `,
 	})
 }
 
-func TestExternalPackages(t *testing.T) {
+func TestNodeGlobals(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
-			"/project/entry.js": `
-				import 'pkg1'
-				import './file'
-				import './node_modules/pkg2/index.js'
-				import '#pkg3'
-			`,
-			"/project/package.json": `{
-				"imports": {
-					"#pkg3": "./libs/pkg3.js"
-				}
-			}`,
-			"/project/file.js": `
-				console.log('file')
-			`,
-			"/project/node_modules/pkg2/index.js": `
-				console.log('pkg2')
-			`,
-			"/project/libs/pkg3.js": `
-				console.log('pkg3')
+			"/entry.js": `
+				console.log(process.env.FOO, Buffer.from("x"))
 			`,
+			"/node_modules/buffer/index.js": `export class Buffer { static from() {} }`,
 		},
-		entryPaths: []string{"/project/entry.js"},
+		entryPaths: []string{"/entry.js"},
 		options: config.Options{
-			Mode:             config.ModeBundle,
-			AbsOutputFile:    "/out.js",
-			ExternalPackages: true,
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			NodeGlobals:   []string{"process", "Buffer"},
 		},
 	})
 }
 
-func TestMetafileVariousCases(t *testing.T) {
+func TestNodeGlobalsCommonJS(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
-			"/project/entry.js": `
-				import a from 'extern-esm'
-				import b from './esm'
-				import c from 'data:application/json,2'
-				import d from './file.file'
-				import e from './copy.copy'
-				console.log(
-					a,
+			"/entry.js": `
+				import "./other.js"
+				console.log(Buffer.from("x"))
+			`,
+			"/other.js":                     `console.log(typeof Buffer)`,
+			"/node_modules/buffer/index.js": `exports.Buffer = { from: x => x }`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			NodeGlobals:   []string{"Buffer"},
+		},
+	})
+}
+
+func TestNodeGlobalsUnused(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				let process = {}
+				console.log(process, typeof Buffer)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			NodeGlobals:   []string{"process"},
+		},
+	})
+}
+
+func TestNodeGlobalsOnlyWhereReferenced(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import "./uses-process.js"
+				console.log(require("./no-globals.js"), require("./lazy-process.js"), Buffer.isBuffer(1))
+			`,
+			"/uses-process.js": `console.log(process.env.NODE_ENV)`,
+			"/no-globals.js":   `module.exports = 123`,
+			"/lazy-process.js": `export let platform = process.platform`,
+			"/node_modules/buffer/index.js": `
+				export function Buffer() {}
+				Buffer.isBuffer = x => x instanceof Buffer
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			NodeGlobals:   []string{"process", "Buffer"},
+		},
+	})
+}
+
+func TestNodeGlobalsInjectOverride(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js":  `console.log(process.argv)`,
+			"/inject.js": `export let process = { argv: ["custom"] }`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			InjectPaths:   []string{"/inject.js"},
+			NodeGlobals:   []string{"process"},
+		},
+	})
+}
+
+func TestNodeGlobalsPlatformNode(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `console.log(process.argv, Buffer)`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			Platform:      config.PlatformNode,
+			NodeGlobals:   []string{"process", "Buffer"},
+		},
+	})
+}
+
+func TestDirnameSource(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import "./src/nested/file"
+				console.log(__dirname, __filename)
+			`,
+			"/src/nested/file.js": `
+				let __filename = "shadowed"
+				console.log(__dirname, __filename, typeof __dirname)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			Dirname:       config.DirnameSource,
+		},
+	})
+}
+
+func TestDirnameRuntimeESM(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `console.log(__dirname, __filename)`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			OutputFormat:  config.FormatESModule,
+			Dirname:       config.DirnameRuntime,
+		},
+	})
+}
+
+func TestDirnameRuntimeCommonJS(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `console.log(__dirname, __filename)`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			OutputFormat:  config.FormatCommonJS,
+			Dirname:       config.DirnameRuntime,
+		},
+	})
+}
+
+func TestDirnameRuntimeIIFE(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `console.log(__dirname, typeof __filename)`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			OutputFormat:  config.FormatIIFE,
+			Dirname:       config.DirnameRuntime,
+		},
+		expectedScanLog: `entry.js: ERROR: There is no run-time equivalent of "__dirname" for the "iife" output format
+`,
+	})
+}
+
+func TestDirnameError(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(__dirname)
+				if (typeof __filename !== "undefined") console.log(__filename)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			Dirname:       config.DirnameError,
+		},
+		expectedScanLog: `entry.js: ERROR: Cannot use "__dirname" in bundled code
+NOTE: The value of "__dirname" depends on where the original source file was located, which is usually not where the bundled code is run. References to it have been configured to be an error.
+entry.js: ERROR: Cannot use "__filename" in bundled code
+NOTE: The value of "__filename" depends on where the original source file was located, which is usually not where the bundled code is run. References to it have been configured to be an error.
+`,
+	})
+}
+
+func TestPlatformDeno(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { debounce } from "npm:lodash@4"
+				import { assert } from "jsr:@std/assert"
+				import { readFileSync } from "node:fs"
+				import { join } from "path"
+				import { serve } from "https://deno.land/std/http/server.ts"
+				import "pkg"
+				console.log(debounce, assert, readFileSync, join, serve)
+			`,
+			"/node_modules/pkg/package.json": `{ "exports": { "deno": "./deno.js", "default": "./default.js" } }`,
+			"/node_modules/pkg/deno.js":      `console.log("deno")`,
+			"/node_modules/pkg/default.js":   `console.log("default")`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			OutputFormat:  config.FormatESModule,
+			Platform:      config.PlatformDeno,
+		},
+	})
+}
+
+func TestImportMap(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { util } from "@/util"
+				import { debounce } from "lodash"
+				import { join } from "std/path/mod.ts"
+				import "./vendor/index.js"
+				console.log(util, debounce, join)
+			`,
+			"/src/util.js":      `export let util = 1`,
+			"/vendor/index.js":  `import { debounce } from "lodash"; console.log(debounce)`,
+			"/vendor/lodash.js": `export function debounce() {}`,
+			"/import_map.json": `{
+				// Comments are allowed for "deno.jsonc" files
+				"imports": {
+					"@/": "./src/",
+					"lodash": "npm:lodash@4",
+					"std/": "https://deno.land/std/",
+				},
+				"scopes": {
+					"./vendor/": {
+						"lodash": "./vendor/lodash.js"
+					}
+				}
+			}`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			AbsOutputFile:    "/out.js",
+			OutputFormat:     config.FormatESModule,
+			Platform:         config.PlatformDeno,
+			AbsImportMapPath: "/import_map.json",
+		},
+	})
+}
+
+func TestImportMapMissingFile(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js":        `import "@/missing"`,
+			"/import_map.json": `{ "imports": { "@/": "./src/" } }`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			AbsOutputFile:    "/out.js",
+			AbsImportMapPath: "/import_map.json",
+		},
+		expectedScanLog: `entry.js: ERROR: Could not resolve "@/missing"
+import_map.json: NOTE: The import path "@/missing" was remapped to "src/missing" by the import map here:
+NOTE: You can mark the path "@/missing" as external to exclude it from the bundle, which will remove this error.
+`,
+	})
+}
+func TestErrorsForAssertTypeJSON(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/js-entry.js": `
+				import all from './foo.json' assert { type: 'json' }
+				import { default as def } from './foo.json' assert { type: 'json' }
+				import { unused } from './foo.json' assert { type: 'json' }
+				import { used } from './foo.json' assert { type: 'json' }
+				import * as ns from './foo.json' assert { type: 'json' }
+				use(used, ns.prop)
+				export { exported } from './foo.json' assert { type: 'json' }
+				import text from './foo.text' assert { type: 'json' }
+				import file from './foo.file' assert { type: 'json' }
+				import copy from './foo.copy' assert { type: 'json' }
+			`,
+			"/ts-entry.ts": `
+				import all from './foo.json' assert { type: 'json' }
+				import { default as def } from './foo.json' assert { type: 'json' }
+				import { unused } from './foo.json' assert { type: 'json' }
+				import { used } from './foo.json' assert { type: 'json' }
+				import * as ns from './foo.json' assert { type: 'json' }
+				use(used, ns.prop)
+				export { exported } from './foo.json' assert { type: 'json' }
+				import text from './foo.text' assert { type: 'json' }
+				import file from './foo.file' assert { type: 'json' }
+				import copy from './foo.copy' assert { type: 'json' }
+			`,
+			"/foo.json": `{}`,
+			"/foo.text": `{}`,
+			"/foo.file": `{}`,
+			"/foo.copy": `{}`,
+		},
+		entryPaths: []string{
+			"/js-entry.js",
+			"/ts-entry.ts",
+		},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":   config.LoaderJS,
+				".ts":   config.LoaderTS,
+				".json": config.LoaderJSON,
+				".text": config.LoaderText,
+				".file": config.LoaderFile,
+				".copy": config.LoaderCopy,
+			},
+		},
+		expectedScanLog: `js-entry.js: ERROR: Cannot use non-default import "unused" with a standard JSON module
+js-entry.js: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
+NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "unused" import (which is non-standard behavior).
+js-entry.js: ERROR: Cannot use non-default import "used" with a standard JSON module
+js-entry.js: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
+NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "used" import (which is non-standard behavior).
+js-entry.js: WARNING: Non-default import "prop" is undefined with a standard JSON module
+js-entry.js: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
+NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "prop" import (which is non-standard behavior).
+js-entry.js: ERROR: Cannot use non-default import "exported" with a standard JSON module
+js-entry.js: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
+NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "exported" import (which is non-standard behavior).
+ts-entry.ts: ERROR: Cannot use non-default import "used" with a standard JSON module
+ts-entry.ts: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
+NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "used" import (which is non-standard behavior).
+ts-entry.ts: WARNING: Non-default import "prop" is undefined with a standard JSON module
+ts-entry.ts: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
+NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "prop" import (which is non-standard behavior).
+ts-entry.ts: ERROR: Cannot use non-default import "exported" with a standard JSON module
+ts-entry.ts: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
+NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "exported" import (which is non-standard behavior).
+`,
+	})
+}
+
+func TestOutputForAssertTypeJSON(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/js-entry.js": `
+				import all from './foo.json' assert { type: 'json' }
+				import copy from './foo.copy' assert { type: 'json' }
+				import { default as def } from './foo.json' assert { type: 'json' }
+				import * as ns from './foo.json' assert { type: 'json' }
+				use(all, copy, def, ns.prop)
+				export { default } from './foo.json' assert { type: 'json' }
+			`,
+			"/ts-entry.ts": `
+				import all from './foo.json' assert { type: 'json' }
+				import copy from './foo.copy' assert { type: 'json' }
+				import { default as def } from './foo.json' assert { type: 'json' }
+				import { unused } from './foo.json' assert { type: 'json' }
+				import * as ns from './foo.json' assert { type: 'json' }
+				use(all, copy, def, ns.prop)
+				export { default } from './foo.json' assert { type: 'json' }
+			`,
+			"/foo.json": `{}`,
+			"/foo.copy": `{}`,
+		},
+		entryPaths: []string{
+			"/js-entry.js",
+			"/ts-entry.ts",
+		},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":   config.LoaderJS,
+				".ts":   config.LoaderTS,
+				".json": config.LoaderJSON,
+				".copy": config.LoaderCopy,
+			},
+		},
+		expectedScanLog: `js-entry.js: WARNING: Non-default import "prop" is undefined with a standard JSON module
+js-entry.js: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
+NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "prop" import (which is non-standard behavior).
+ts-entry.ts: WARNING: Non-default import "prop" is undefined with a standard JSON module
+ts-entry.ts: NOTE: This is considered an import of a standard JSON module because of the import assertion here:
+NOTE: You can either keep the import assertion and only use the "default" import, or you can remove the import assertion and use the "prop" import (which is non-standard behavior).
+`,
+	})
+}
+
+func TestOutputForWithTypeJSON(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import all from './foo.json' with { type: 'json' }
+				import text from './foo.text' with { type: 'json' }
+				import sameText from './foo.text' with { type: 'json' }
+				import rawText from './foo.text'
+				import copy from './foo.copy' with { type: 'json' }
+				import * as ns from './foo.json' with { type: 'json' }
+				import 'external' with { type: 'json' }
+				use(all, text, sameText, rawText, copy, ns.prop)
+			`,
+			"/foo.json": `{ "a": 1 }`,
+			"/foo.text": `{ "b": 2 }`,
+			"/foo.copy": `{}`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			OutputFormat:  config.FormatESModule,
+			ExtensionToLoader: map[string]config.Loader{
+				".js":   config.LoaderJS,
+				".json": config.LoaderJSON,
+				".text": config.LoaderText,
+				".copy": config.LoaderCopy,
+			},
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"external": true,
+				}},
+			},
+		},
+		expectedScanLog: `entry.js: WARNING: Non-default import "prop" is undefined with a standard JSON module
+entry.js: NOTE: This is considered an import of a standard JSON module because of the import attribute here:
+NOTE: You can either keep the import attribute and only use the "default" import, or you can remove the import attribute and use the "prop" import (which is non-standard behavior).
+`,
+	})
+}
+
+func TestExternalPackages(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/project/entry.js": `
+				import 'pkg1'
+				import './file'
+				import './node_modules/pkg2/index.js'
+				import '#pkg3'
+			`,
+			"/project/package.json": `{
+				"imports": {
+					"#pkg3": "./libs/pkg3.js"
+				}
+			}`,
+			"/project/file.js": `
+				console.log('file')
+			`,
+			"/project/node_modules/pkg2/index.js": `
+				console.log('pkg2')
+			`,
+			"/project/libs/pkg3.js": `
+				console.log('pkg3')
+			`,
+		},
+		entryPaths: []string{"/project/entry.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			AbsOutputFile:    "/out.js",
+			ExternalPackages: true,
+		},
+	})
+}
+
+func TestMetafileVariousCases(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/project/entry.js": `
+				import a from 'extern-esm'
+				import b from './esm'
+				import c from 'data:application/json,2'
+				import d from './file.file'
+				import e from './copy.copy'
+				console.log(
+					a,
 					b,
 					c,
 					d,
@@ -8089,3 +9305,396 @@ func TestCommentPreservationPreserveJSX(t *testing.T) {
 		},
 	})
 }
+
+func TestWorkerNewURL(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				import { shared } from './shared'
+				new Worker(new URL('./worker.js', import.meta.url), { type: 'module' })
+				new SharedWorker(new URL('../src/worker.js', import.meta.url))
+				new Worker(new URL('./not-relative.js', 'https://example.com'))
+				console.log(shared)
+			`,
+			"/src/worker.js": `
+				import { shared } from './shared'
+				self.onmessage = e => postMessage(shared + e.data)
+			`,
+			"/src/shared.js": `export let shared = 'shared'`,
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestWorkerInline(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				new Worker(new URL('./worker.js?inline', import.meta.url))
+				new Worker(new URL('./other.js', import.meta.url))
+			`,
+			"/worker.js": `self.onmessage = e => postMessage("worker: " + e.data)`,
+			"/other.js":  `self.onmessage = e => postMessage("other: " + e.data)`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestWorkerInlineWorkersOption(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				new Worker(new URL('./worker.js', import.meta.url))
+			`,
+			"/worker.js": `
+				new Worker(new URL('./nested.js', import.meta.url))
+			`,
+			"/nested.js": `console.log('nested')`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/out.js",
+			InlineWorkers: true,
+		},
+	})
+}
+
+func TestWorkerNewURLFallback(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				new Worker(new URL('./missing.js', import.meta.url))
+				new Worker(new URL('./worker.wasm', import.meta.url))
+				new Worker(new URL('./style.css', import.meta.url))
+			`,
+			"/worker.wasm": `wasm`,
+			"/style.css":   `a { color: red }`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestWorkerInlineMissingError(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				new Worker(new URL('./missing.js?inline', import.meta.url))
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+		},
+		expectedScanLog: `entry.js: ERROR: Could not resolve "./missing.js"
+`,
+	})
+}
+
+func TestWorkerSelfReferenceError(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				new Worker(new URL('./worker.js', import.meta.url))
+			`,
+			"/worker.js": `
+				new Worker(new URL('./worker.js?inline', import.meta.url))
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+		},
+		expectedCompileLog: `ERROR: Cannot bundle the worker "worker.js" because it creates itself
+`,
+	})
+}
+
+func TestWorkerNestedNotInlineError(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				new Worker(new URL('./worker.js', import.meta.url))
+				new Worker(new URL('./inline.js?inline', import.meta.url))
+			`,
+			"/worker.js": `
+				new Worker(new URL('./nested.js', import.meta.url))
+				new Worker(new URL('./nested.js?inline', import.meta.url))
+			`,
+			"/inline.js": `
+				new Worker(new URL('./nested.js', import.meta.url))
+			`,
+			"/nested.js": `console.log('nested')`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+		},
+		expectedCompileLog: `inline.js: ERROR: Cannot create the worker "nested.js" from inside another worker
+NOTE: Workers created by other workers must be inline workers. You can append "?inline" to the path to embed this worker in the worker that creates it.
+worker.js: ERROR: Cannot create the worker "nested.js" from inside another worker
+NOTE: Workers created by other workers must be inline workers. You can append "?inline" to the path to embed this worker in the worker that creates it.
+`,
+	})
+}
+
+func TestWorkerSameNameAsEntryPoint(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				new Worker(new URL('./worker.js', import.meta.url))
+			`,
+			"/worker.js": `self.onmessage = e => postMessage(e.data)`,
+		},
+		entryPaths: []string{"/entry.js", "/worker.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatIIFE,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestRequireContext(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				const all = require.context('./pages')
+				const js = require.context('./pages', false, /\.js$/)
+				const sync = require.context('./pages', true, /^\.\/sub\//i, 'sync')
+				console.log(all.keys(), js.keys(), sync.keys())
+			`,
+			"/pages/a.js":     `export default 'a'`,
+			"/pages/b.json":   `{ "b": true }`,
+			"/pages/sub/c.js": `module.exports = 'c'`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestRequireContextUnsupported(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				require.context(dir)
+				require.context('./pages', recursive)
+				require.context('./pages', true, /\.js$/, 'lazy')
+			`,
+			"/pages/a.js": `export default 'a'`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestRequireContextErrors(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				require.context('pages')
+				require.context('./missing')
+				require.context('./pages', true, /(a)\1/)
+				try {
+					require.context('./also-missing')
+				} catch {
+				}
+			`,
+			"/pages/a.js": `export default 'a'`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+		expectedScanLog: `entry.js: ERROR: The directory "pages" passed to "require.context" must be a relative path
+entry.js: ERROR: Could not find the directory "./missing" passed to "require.context"
+entry.js: ERROR: The regular expression /(a)\1/ passed to "require.context" is not supported: error parsing regexp: invalid escape sequence: ` + "`\\1`" + `
+`,
+	})
+}
+
+func TestImportMetaGlob(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				const lazy = import.meta.glob('./modules/*.js')
+				const named = import.meta.glob('./modules/{a,b}.js', { import: 'setup' })
+				console.log(lazy, named)
+			`,
+			"/modules/a.js":          `export let setup = 'a'`,
+			"/modules/b.js":          `export let setup = 'b'`,
+			"/modules/c.json":        `{ "setup": "c" }`,
+			"/modules/nested/d.js":   `export let setup = 'd'`,
+			"/node_modules/pkg/e.js": `export let setup = 'e'`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestImportMetaGlobEager(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				const all = import.meta.glob(['./**/*.js', '../shared/*.js', '!./entry.js', '!**/skip-*'], { eager: true })
+				const setups = import.meta.glob('./modules/**/*.js', { eager: true, import: 'setup' })
+				console.log(all, setups)
+			`,
+			"/src/modules/a.js":        `export let setup = 'a'`,
+			"/src/modules/nested/b.js": `export let setup = 'b'`,
+			"/src/modules/skip-me.js":  `export let setup = 'skip'`,
+			"/shared/c.js":             `export let setup = 'c'`,
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestImportMetaGlobErrors(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import.meta.glob(pattern)
+				import.meta.glob(['./a/*.js', pattern])
+				import.meta.glob('./*.js', options)
+				import.meta.glob('./*.js', { eager: 1 })
+				import.meta.glob('./*.js', { import: 'not valid' })
+				import.meta.glob('./*.js', { query: '?raw' })
+				import.meta.glob('modules/*.js')
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/out.js",
+		},
+		expectedScanLog: `entry.js: ERROR: The first argument to "import.meta.glob" must be a string or an array of strings
+entry.js: ERROR: The first argument to "import.meta.glob" must be a string or an array of strings
+entry.js: ERROR: The second argument to "import.meta.glob" must be an object literal
+entry.js: ERROR: The "eager" option must be a boolean literal
+entry.js: ERROR: The "import" option must be a string literal containing an identifier
+entry.js: ERROR: The "query" option for "import.meta.glob" is not supported
+entry.js: ERROR: The glob pattern "modules/*.js" passed to "import.meta.glob" must be a relative path
+`,
+	})
+}
+
+func TestFederationExposes(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				import { format } from './shared.js'
+				console.log(format('entry'))
+			`,
+			"/src/button.js": `
+				import { format } from './shared.js'
+				export default function Button() { return format('button') }
+			`,
+			"/src/list/index.js": `
+				export const List = () => 'list'
+			`,
+			"/src/shared.js": `
+				export const format = x => '[' + x + ']'
+			`,
+		},
+		entryPathsAdvanced: []bundler.EntryPoint{
+			{InputPath: "/src/entry.js"},
+			{InputPath: "/src/button.js", OutputPath: "Button"},
+			{InputPath: "/src/list/index.js", OutputPath: "components/List"},
+		},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+			ExposedModules: []config.ExposedModule{
+				{Name: "Button", InputPath: "/src/button.js"},
+				{Name: "components/List", InputPath: "/src/list/index.js"},
+			},
+		},
+	})
+}
+
+func TestFederationRemotes(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import * as container from 'app'
+				import Button from 'app/Button'
+				import { List } from 'app/components/List'
+				import { other } from 'application/other'
+				console.log(container.get('./Button'), Button, List, other, import('app-two/lazy'))
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/out.js",
+			RemoteModules: map[string]string{
+				"app":         "https://example.com/app",
+				"app-two":     "/app-two",
+				"application": "https://example.com/application",
+			},
+		},
+	})
+}
+
+func TestFederationRemotesRequireError(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(require('app/Button'))
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/out.js",
+			RemoteModules: map[string]string{
+				"app": "https://example.com/app",
+			},
+		},
+		expectedScanLog: `entry.js: ERROR: Remote module "app/Button" must be imported using an "import" statement or an "import()" expression
+`,
+	})
+}
@@ -1,6 +1,7 @@
 package bundler_tests
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/evanw/esbuild/internal/bundler"
@@ -1276,8 +1277,19 @@ func TestAssertTypeJSONWrongLoader(t *testing.T) {
 			Mode: config.ModeBundle,
 			ExtensionToLoader: map[string]config.Loader{
 				".js":   config.LoaderJS,
-				".json": config.LoaderJS,
+				".json": config.LoaderJSON,
 			},
+			Plugins: []config.Plugin{{
+				OnLoad: []config.OnLoad{
+					{
+						Filter: regexp.MustCompile("\\.json$"),
+						Callback: func(args config.OnLoadArgs) config.OnLoadResult {
+							contents := "{}"
+							return config.OnLoadResult{Contents: &contents, Loader: config.LoaderJS}
+						},
+					},
+				},
+			}},
 		},
 		expectedScanLog: `entry.js: ERROR: The file "foo.json" was loaded with the "js" loader
 entry.js: NOTE: This import assertion requires the loader to be "json" instead:
@@ -1498,3 +1510,252 @@ func TestLoaderCopyStartsWithDotRelPath(t *testing.T) {
 		},
 	})
 }
+
+func TestLoaderFileNewURL(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				console.log(new URL('./image.png', import.meta.url).href)
+				console.log(new URL('../assets/data.txt', import.meta.url))
+				console.log(new URL('./image.png', 'https://example.com'))
+				console.log(new URL('image.png', import.meta.url))
+			`,
+			"/src/image.png":   `png`,
+			"/assets/data.txt": `txt`,
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputBase: "/",
+			AbsOutputDir:  "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".png": config.LoaderFile,
+				".txt": config.LoaderCopy,
+			},
+		},
+	})
+}
+
+func TestLoaderNewURLWithoutURLLoader(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(new URL('./other.js', import.meta.url))
+				console.log(new URL('./style.css', import.meta.url))
+			`,
+			"/other.js":  `console.log('other')`,
+			"/style.css": `a { color: red }`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestLoaderNewURLMissingFile(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(new URL('./missing.png', import.meta.url))
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestLoaderNewURLNoLoader(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				console.log(new URL('./module.wasm', import.meta.url))
+			`,
+			"/module.wasm": `wasm`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			OutputFormat: config.FormatESModule,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestLoaderHTML(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/index.html": `<!DOCTYPE html>
+<html>
+  <head>
+    <title>Example</title>
+    <link rel="stylesheet" href="/theme.css">
+    <link rel="icon" href="favicon.ico">
+    <script src="https://example.com/analytics.js"></script>
+    <!-- <script src="commented-out.js"></script> -->
+  </head>
+  <body>
+    <script>if (a < b) document.write("<script src='inline.js'></" + "script>")</script>
+    <script type="module" src=./pages/main.js></script>
+  </body>
+</html>
+`,
+			"/src/theme.css": `body { color: red }`,
+			"/src/pages/main.js": `
+				import './main.css'
+				import { render } from '../lib/render.js'
+				render()
+			`,
+			"/src/pages/main.css": `main { color: blue }`,
+			"/src/lib/render.js":  `export function render() { console.log('render') }`,
+		},
+		entryPaths: []string{"/src/index.html"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			EntryPathTemplate: []config.PathTemplate{
+				// "[dir]/[name]-[hash]"
+				{Data: "./", Placeholder: config.DirPlaceholder},
+				{Data: "/", Placeholder: config.NamePlaceholder},
+				{Data: "-", Placeholder: config.HashPlaceholder},
+			},
+		},
+	})
+}
+
+func TestLoaderHTMLWithoutHead(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/index.html": `<div id="root"></div>
+<script src='app.js'></script>
+<script src="app.js"></script>
+`,
+			"/app.js":  `import './app.css'; console.log('app')`,
+			"/app.css": `#root { color: red }`,
+		},
+		entryPaths: []string{"/index.html"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out/page.html",
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestLoaderHTMLErrors(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/index.html": `
+<link rel="stylesheet" href="./entry.js">
+<script src="./style.css"></script>
+<script src="./data.txt"></script>
+`,
+			"/entry.js":  `import page from './index.html'`,
+			"/style.css": `a { color: red }`,
+			"/data.txt":  `text`,
+		},
+		entryPaths: []string{"/index.html"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+		},
+		expectedScanLog: `entry.js: ERROR: Cannot import "index.html"
+NOTE: HTML files can only be used as entry points, and "index.html" was loaded with the "html" loader.
+index.html: ERROR: Cannot use "entry.js" as a stylesheet
+NOTE: A "<link rel="stylesheet">" tag can only reference a CSS file, and "entry.js" was loaded with the "js" loader.
+index.html: ERROR: Cannot use "style.css" as a script
+NOTE: A "<script>" tag can only reference a JavaScript or TypeScript file, and "style.css" was loaded with the "css" loader.
+index.html: ERROR: Cannot use "data.txt" as a script
+NOTE: A "<script>" tag can only reference a JavaScript or TypeScript file, and "data.txt" was loaded with the "text" loader.
+`,
+	})
+}
+
+func TestLoaderHTMLInlineLimit(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/index.html": `<html>
+  <head>
+    <link rel="stylesheet" media="all" href="small.css">
+    <link rel="stylesheet" href="big.css">
+  </head>
+  <body>
+    <script type="module" src="small.js"></script>
+    <script src="pages/big.js"></script>
+    <script src="shared.js"></script>
+  </body>
+</html>
+`,
+			"/small.css":    `a { color: red }`,
+			"/big.css":      `a { color: red } b { color: green } i { color: blue } u { color: yellow }`,
+			"/small.js":     `import './small-js.css'; console.log("</script>")`,
+			"/small-js.css": `b { content: "</style>" }`,
+			"/pages/big.js": `console.log('this is a bigger file that should not be inlined')`,
+			"/shared.js":    `console.log('this file is also an entry point')`,
+		},
+		entryPaths: []string{"/index.html", "/shared.js"},
+		options: config.Options{
+			Mode:            config.ModeBundle,
+			AbsOutputDir:    "/out",
+			HTMLInlineLimit: 64,
+		},
+	})
+}
+
+func TestLoaderFlow(t *testing.T) {
+	loader_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				// @flow
+				import type { Props } from './types'
+				import typeof React from 'react'
+				import { sideEffect, type Unused } from './side-effect'
+				import './side-effect'
+				import { render } from './render'
+
+				export type Node = {| tag: string, children: Array<Node> |}
+				opaque type ID: string = string
+
+				function App(props: Props, id?: ID): React.Node {
+					return <div id={(id: any)}>{props.children}</div>
+				}
+
+				render((App: any))
+			`,
+			"/types.js": `
+				// @flow
+				export type Props = { +children: ?mixed }
+			`,
+			"/side-effect.js": `
+				// @flow
+				export let sideEffect: number = console.log('side effect')
+			`,
+			"/render.js": `
+				// @flow
+				declare var __DEV__: boolean
+				export const render = <T>(value: T): T => value
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			ExtensionToLoader: map[string]config.Loader{
+				".js": config.LoaderFlow,
+			},
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"react": true,
+				}},
+			},
+		},
+	})
+}
@@ -909,14 +909,6 @@ func TestLowerAsyncES5(t *testing.T) {
 			UnsupportedJSFeatures: es(5),
 			AbsOutputFile:         "/out.js",
 		},
-		expectedScanLog: `arrow-1.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-arrow-2.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-export-def-1.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-export-def-2.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-fn-expr.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-fn-stmt.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-obj-method.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-`,
 	})
 }
 
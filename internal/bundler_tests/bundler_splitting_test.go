@@ -1,9 +1,16 @@
 package bundler_tests
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/evanw/esbuild/internal/bundler"
+	"github.com/evanw/esbuild/internal/cache"
 	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/linker"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/test"
 )
 
 var splitting_suite = suite{
@@ -597,3 +604,132 @@ func TestEdgeCaseIssue2793WithoutSplitting(t *testing.T) {
 		},
 	})
 }
+
+func TestSplittingRuntimeChunk(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import * as ns from "./a-cjs"
+				import { shared } from "./shared"
+				console.log(ns, shared)
+			`,
+			"/b.js": `
+				import * as ns from "./b-cjs"
+				import { shared } from "./shared"
+				console.log(ns, shared)
+			`,
+			"/c.js": `
+				console.log("no helpers")
+			`,
+			"/a-cjs.js":  `module.exports = 1`,
+			"/b-cjs.js":  `module.exports = 2`,
+			"/shared.js": `export let shared = 3`,
+		},
+		entryPaths: []string{"/a.js", "/b.js", "/c.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			RuntimeChunk:  true,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingRuntimeChunkUnused(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { shared } from "./shared"
+				console.log(shared)
+			`,
+			"/b.js": `
+				import { shared } from "./shared"
+				console.log(shared)
+			`,
+			"/shared.js": `export let shared = 1`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			RuntimeChunk:  true,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+// Output must not depend on map iteration order or on the order in which the
+// files are parsed, so build the same input many times and compare the results
+func TestSplittingDeterministicOutput(t *testing.T) {
+	files := map[string]string{
+		"/shared.js": `export * from "./reexport"`,
+		"/reexport.js": `
+			import { helper } from "./helper"
+			export let a = helper(1), b = helper(2), c = helper(3), d = helper(4)
+			export let e = helper(5), f = helper(6), g = helper(7), h = helper(8)
+		`,
+		"/helper.js": `export function helper(x) { return x * 2 }`,
+	}
+	var entryPaths []string
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("/entry%d.js", i)
+		files[name] = fmt.Sprintf(`
+			import { a, b, c, d, e, f, g, h } from "./shared"
+			import("./lazy%d").then(x => console.log(x))
+			console.log(%d, a, b, c, d, e, f, g, h)
+		`, i%3, i)
+		entryPaths = append(entryPaths, name)
+	}
+	for i := 0; i < 3; i++ {
+		files[fmt.Sprintf("/lazy%d.js", i)] = fmt.Sprintf(`
+			import { a, h } from "./shared"
+			import "./style%d.css"
+			export default a + h + %d
+		`, i, i)
+		files[fmt.Sprintf("/style%d.css", i)] = fmt.Sprintf(`.s%d { color: red }`, i)
+	}
+
+	build := func(fsKind fs.MockKind) string {
+		var entryPoints []bundler.EntryPoint
+		options := config.Options{
+			Mode:              config.ModeBundle,
+			OutputFormat:      config.FormatESModule,
+			CodeSplitting:     true,
+			TreeShaking:       true,
+			MinifyIdentifiers: true,
+			NeedsMetafile:     true,
+			AbsOutputDir:      "/out",
+			ExtensionOrder:    []string{".js", ".css"},
+		}
+		absWorkingDir := "/"
+		if fsKind == fs.MockWindows {
+			options.AbsOutputDir = unix2win(options.AbsOutputDir)
+			absWorkingDir = unix2win(absWorkingDir)
+		}
+		for _, entryPath := range entryPaths {
+			if fsKind == fs.MockWindows {
+				entryPath = unix2win(entryPath)
+			}
+			entryPoints = append(entryPoints, bundler.EntryPoint{InputPath: entryPath})
+		}
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		mockFS := fs.MockFS(files, fsKind, absWorkingDir)
+		bundle := bundler.ScanBundle(log, mockFS, cache.MakeCacheSet(), entryPoints, options, nil)
+		results, metafileJSON := bundle.Compile(log, nil, nil, linker.Link)
+		assertLog(t, log.Done(), "")
+		generated := ""
+		for _, result := range results {
+			generated += fmt.Sprintf("---------- %s ----------\n%s\n", win2unix(result.AbsPath), string(result.Contents))
+		}
+		return generated + metafileJSON
+	}
+
+	expected := build(fs.MockUnix)
+	for i := 0; i < 10; i++ {
+		for _, fsKind := range []fs.MockKind{fs.MockUnix, fs.MockWindows} {
+			test.AssertEqualWithDiff(t, build(fsKind), expected)
+		}
+	}
+}
@@ -1630,6 +1630,58 @@ func TestTSSiblingNamespace(t *testing.T) {
 	})
 }
 
+func TestTSScriptNamespaceAcrossFiles(t *testing.T) {
+	ts_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.ts": `
+				import './a'
+				import './b'
+				import './c'
+				import './module'
+			`,
+			"/a.ts": `
+				namespace NS { export let a = 1 }
+			`,
+			"/b.ts": `
+				namespace NS { export function b() { return NS.a } }
+			`,
+			"/c.ts": `
+				console.log(NS.a, NS.b())
+			`,
+			"/module.ts": `
+				namespace NS { export let a = 2 }
+				console.log(NS.a)
+				export {}
+			`,
+		},
+		entryPaths: []string{"/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestTSScriptNamespaceAcrossFilesCodeSplitting(t *testing.T) {
+	ts_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.ts": `
+				namespace NS { export let a = 1 }
+			`,
+			"/b.ts": `
+				namespace NS { export let b = 2 }
+			`,
+		},
+		entryPaths: []string{"/a.ts", "/b.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
 func TestTSSiblingEnum(t *testing.T) {
 	ts_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -1343,6 +1343,86 @@ func TestTsconfigPreserveValueImportsAndImportsNotUsedAsValuesPreserve(t *testin
 	})
 }
 
+func TestTsconfigVerbatimModuleSyntax(t *testing.T) {
+	tsconfig_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.ts": `
+				import {} from "a"
+				import {b1} from "b"
+				import {c1, type c2} from "c"
+				import {d1, d2, type d3} from "d"
+				import {type e1, type e2} from "e"
+				import f1, {} from "f"
+				import g1, {g2} from "g"
+				import h1, {type h2} from "h"
+				import * as i1 from "i"
+				import "j"
+			`,
+			"/Users/user/project/src/tsconfig.json": `{
+				"compilerOptions": {
+					"verbatimModuleSyntax": true
+				}
+			}`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeConvertFormat,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/Users/user/project/out.js",
+			ExternalSettings: config.ExternalSettings{
+				PostResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"/Users/user/project/src/foo": true,
+				}},
+			},
+		},
+	})
+}
+
+func TestTsconfigEmitDecoratorMetadata(t *testing.T) {
+	tsconfig_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.ts": `
+				import "./metadata/foo"
+				import "./no-experimental-decorators/foo"
+			`,
+			"/Users/user/project/src/metadata/foo.ts": `
+				import { Service } from "../service"
+				import type { Options } from "../service"
+				@dec export class Foo {
+					constructor(service: Service, options?: Options) {}
+					@dec method(x: number): string { return "" }
+				}
+			`,
+			"/Users/user/project/src/metadata/tsconfig.json": `{
+				"compilerOptions": {
+					"experimentalDecorators": true,
+					"emitDecoratorMetadata": true
+				}
+			}`,
+			"/Users/user/project/src/no-experimental-decorators/foo.ts": `
+				import { Service } from "../service"
+				@dec export class Foo {
+					constructor(service: Service) {}
+				}
+			`,
+			"/Users/user/project/src/no-experimental-decorators/tsconfig.json": `{
+				"compilerOptions": {
+					"emitDecoratorMetadata": true
+				}
+			}`,
+			"/Users/user/project/src/service.ts": `
+				export class Service {}
+				export interface Options {}
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.ts"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+	})
+}
+
 func TestTsconfigTarget(t *testing.T) {
 	tsconfig_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -70,6 +70,8 @@ type SourceIndexKind uint8
 const (
 	SourceIndexNormal SourceIndexKind = iota
 	SourceIndexJSStubForCSS
+	SourceIndexWorkerStub
+	SourceIndexInlineWorkerStub
 )
 
 type sourceIndexKey struct {
@@ -39,6 +39,10 @@ func ParseLoader(text string) (api.Loader, *ErrorWithNote) {
 		return api.LoaderEmpty, nil
 	case "file":
 		return api.LoaderFile, nil
+	case "flow":
+		return api.LoaderFlow, nil
+	case "html":
+		return api.LoaderHTML, nil
 	case "js":
 		return api.LoaderJS, nil
 	case "json":
@@ -54,7 +58,7 @@ func ParseLoader(text string) (api.Loader, *ErrorWithNote) {
 	default:
 		return api.LoaderNone, MakeErrorWithNote(
 			fmt.Sprintf("Invalid loader value: %q", text),
-			"Valid values are \"base64\", \"binary\", \"copy\", \"css\", \"dataurl\", \"empty\", \"file\", \"js\", \"json\", \"jsx\", \"text\", \"ts\", or \"tsx\".",
+			"Valid values are \"base64\", \"binary\", \"copy\", \"css\", \"dataurl\", \"empty\", \"file\", \"flow\", \"html\", \"js\", \"json\", \"jsx\", \"text\", \"ts\", or \"tsx\".",
 		)
 	}
 }
@@ -0,0 +1,655 @@
+package compat
+
+// This converts a browserslist query (https://github.com/browserslist/browserslist)
+// into the oldest version of each engine that the query includes. Explicit
+// browser versions such as "chrome 90", "safari 14.1", "ios_saf 14.0-14.4",
+// "firefox >= 88", and "op_mini all" can be combined with "or", "and", and
+// "not".
+//
+// Queries that depend on browser release or usage data such as "defaults",
+// "last 2 versions", "> 0.5%", "firefox esr", and "not dead" are resolved
+// using the small snapshot of that data in "browserslist_data.go". Queries
+// that need other data such as "cover 99%" or "> 1% in US" generate an error
+// that says how to convert them. The output of "npx browserslist" only
+// contains explicit versions, so it can always be used instead.
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+var browserslistEngines = map[string]Engine{
+	"and_chr":        Chrome,
+	"and_ff":         Firefox,
+	"chrome":         Chrome,
+	"chromeandroid":  Chrome,
+	"edge":           Edge,
+	"explorer":       IE,
+	"ff":             Firefox,
+	"firefox":        Firefox,
+	"firefoxandroid": Firefox,
+	"fx":             Firefox,
+	"ie":             IE,
+	"ios":            IOS,
+	"ios_saf":        IOS,
+	"node":           Node,
+	"opera":          Opera,
+	"safari":         Safari,
+}
+
+// These are browsers that browserslist knows about but that we don't have any
+// compatibility data for. They are ignored instead of generating an error so
+// that the output of "npx browserslist" can be passed to esbuild unmodified.
+var browserslistIgnoredBrowsers = map[string]bool{
+	"and_qq":         true,
+	"and_uc":         true,
+	"baidu":          true,
+	"bb":             true,
+	"blackberry":     true,
+	"explorermobile": true,
+	"ie_mob":         true,
+	"kaios":          true,
+	"op_mini":        true,
+	"op_mob":         true,
+	"operamini":      true,
+	"operamobile":    true,
+	"qqandroid":      true,
+	"samsung":        true,
+	"ucandroid":      true,
+}
+
+// The Android WebView follows Chrome's version numbers starting with version
+// 37. Older versions use a different engine that we don't have any data for.
+const browserslistFirstChromiumAndroid = 37
+
+var browserslistVersionQuery = regexp.MustCompile(`^(\w+)\s+(all|tp|[0-9.]+(?:\s*-\s*[0-9.]+)?)$`)
+var browserslistCompareQuery = regexp.MustCompile(`^(\w+)\s*(>=?|<=?)\s*([0-9.]+)$`)
+var browserslistSeparator = regexp.MustCompile(`(?i),|\n|\bor\b`)
+var browserslistAnd = regexp.MustCompile(`(?i)\band\b`)
+var browserslistNot = regexp.MustCompile(`(?i)^not\s+`)
+var browserslistLastQuery = regexp.MustCompile(`^last\s+(\d+)\s+(?:(\w+)\s+)??(major\s+)?versions$`)
+var browserslistUsageQuery = regexp.MustCompile(`^(>=?|<=?)\s*([0-9.]+)%$`)
+
+// Browserslist lets some browsers be referred to by more than one name. These
+// map the other names to the names used in "browserslistReleases".
+var browserslistAliases = map[string]string{
+	"chromeandroid":  "and_chr",
+	"explorer":       "ie",
+	"ff":             "firefox",
+	"firefoxandroid": "and_ff",
+	"fx":             "firefox",
+	"ios":            "ios_saf",
+}
+
+// These are the standard browserslist queries that need data that esbuild
+// doesn't have. They are detected so that they can generate a specific error.
+var browserslistDataQueries = []struct {
+	regex *regexp.Regexp
+	data  string
+}{
+	{regexp.MustCompile(`^[<>]=?\s*[0-9.]+%\s+in\s`), "regional browser usage data"},
+	{regexp.MustCompile(`^cover\s`), "usage data for every browser"},
+	{regexp.MustCompile(`^(last\s+\d+\s+years?|since\s)`), "browser release dates"},
+	{regexp.MustCompile(`^unreleased\s`), "data about unreleased browsers"},
+	{regexp.MustCompile(`^(last\s|current\s+node$)`), "node release data"},
+	{regexp.MustCompile(`^(fully\s+|partially\s+)?supports\s`), "browser feature data"},
+	{regexp.MustCompile(`^(extends\s|browserslist\s+config$)`), "a shared browserslist configuration"},
+}
+
+// Versions in a span are inclusive at the start and exclusive at the end
+type versionSpan struct {
+	start []int
+	end   []int // Use nil for "no end"
+}
+
+type versionSpans []versionSpan
+
+type browserslistSet map[Engine]versionSpans
+
+// Returns <0 if "a < b"
+// Returns 0 if "a == b"
+// Returns >0 if "a > b"
+func CompareVersionSlices(a []int, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// Returns the version right after all versions that start with "version". For
+// example, "14" becomes "15" and "14.1" becomes "14.2".
+func nextVersion(version []int) []int {
+	next := append([]int{}, version...)
+	next[len(next)-1]++
+	return next
+}
+
+// Returns true if "end" is after "start", where a nil "end" means "no end"
+func isBefore(start []int, end []int) bool {
+	return end == nil || CompareVersionSlices(start, end) < 0
+}
+
+func (spans versionSpans) union(other versionSpans) versionSpans {
+	all := append(append(versionSpans{}, spans...), other...)
+	sort.SliceStable(all, func(i int, j int) bool {
+		return CompareVersionSlices(all[i].start, all[j].start) < 0
+	})
+	var result versionSpans
+	for _, span := range all {
+		if n := len(result); n > 0 && !isBefore(result[n-1].end, span.start) {
+			// This span overlaps or touches the previous one, so merge them
+			if last := &result[n-1]; last.end != nil && (span.end == nil || CompareVersionSlices(span.end, last.end) > 0) {
+				last.end = span.end
+			}
+			continue
+		}
+		result = append(result, span)
+	}
+	return result
+}
+
+func (spans versionSpans) intersect(other versionSpans) versionSpans {
+	var result versionSpans
+	for _, a := range spans {
+		for _, b := range other {
+			start := a.start
+			if CompareVersionSlices(b.start, start) > 0 {
+				start = b.start
+			}
+			end := a.end
+			if end == nil || (b.end != nil && CompareVersionSlices(b.end, end) < 0) {
+				end = b.end
+			}
+			if isBefore(start, end) {
+				result = append(result, versionSpan{start: start, end: end})
+			}
+		}
+	}
+	return versionSpans{}.union(result)
+}
+
+func (spans versionSpans) subtract(other versionSpans) versionSpans {
+	var complement versionSpans
+	prev := []int{0}
+	for _, span := range other {
+		if CompareVersionSlices(prev, span.start) < 0 {
+			complement = append(complement, versionSpan{start: prev, end: span.start})
+		}
+		if prev = span.end; prev == nil {
+			break
+		}
+	}
+	if prev != nil {
+		complement = append(complement, versionSpan{start: prev})
+	}
+	return spans.intersect(complement)
+}
+
+func (set browserslistSet) union(other browserslistSet) browserslistSet {
+	result := make(browserslistSet)
+	for engine, spans := range set {
+		result[engine] = spans
+	}
+	for engine, spans := range other {
+		result[engine] = result[engine].union(spans)
+	}
+	return result
+}
+
+func (set browserslistSet) intersect(other browserslistSet) browserslistSet {
+	result := make(browserslistSet)
+	for engine, spans := range set {
+		if spans = spans.intersect(other[engine]); len(spans) > 0 {
+			result[engine] = spans
+		}
+	}
+	return result
+}
+
+func (set browserslistSet) subtract(other browserslistSet) browserslistSet {
+	result := make(browserslistSet)
+	for engine, spans := range set {
+		if spans = spans.subtract(other[engine]); len(spans) > 0 {
+			result[engine] = spans
+		}
+	}
+	return result
+}
+
+type browserslistParser struct {
+	log     logger.Log
+	source  logger.Source
+	tracker logger.LineColumnTracker
+	queries []string
+	ok      bool
+}
+
+// ParseBrowserslist returns the oldest version of each engine included by the
+// browserslist query in the source. Queries are separated by commas, newlines,
+// or "or", and everything after a "#" on a line is a comment.
+func ParseBrowserslist(log logger.Log, source logger.Source) (map[Engine][]int, bool) {
+	p := browserslistParser{
+		log:     log,
+		source:  source,
+		tracker: logger.MakeLineColumnTracker(&source),
+		ok:      true,
+	}
+	result := p.parseQueries(source.Contents)
+
+	if !p.ok {
+		return nil, false
+	}
+	versions := make(map[Engine][]int)
+	for engine, spans := range result {
+		if len(spans) > 0 {
+			versions[engine] = spans[0].start
+		}
+	}
+	return versions, true
+}
+
+func (p *browserslistParser) parseQueries(text string) browserslistSet {
+	// Blank out comments so that the locations of everything else are preserved
+	contents := []byte(text)
+	for i := 0; i < len(contents); i++ {
+		if contents[i] == '#' {
+			for i < len(contents) && contents[i] != '\n' {
+				contents[i] = ' '
+				i++
+			}
+		}
+	}
+	text = string(contents)
+
+	type queryWithLoc struct {
+		text string
+		loc  int
+	}
+	var queries []queryWithLoc
+	start := 0
+	for _, match := range append(browserslistSeparator.FindAllStringIndex(text, -1), []int{len(text), len(text)}) {
+		query, loc := trimWithLoc(text[start:match[0]], start)
+		start = match[1]
+		if query != "" {
+			queries = append(queries, queryWithLoc{text: query, loc: loc})
+			p.queries = append(p.queries, query)
+		}
+	}
+
+	var result browserslistSet
+	for _, q := range queries {
+		query, loc := q.text, q.loc
+
+		// A query starting with "not" removes browsers from the previous queries
+		if not := browserslistNot.FindString(query); not != "" {
+			if result == nil {
+				p.addError(loc, len(query), fmt.Sprintf("The browserslist query %q must come after another query", query))
+				continue
+			}
+			if set, ok := p.parseAndQuery(query[len(not):], loc+len(not)); ok {
+				result = result.subtract(set)
+			}
+			continue
+		}
+
+		if set, ok := p.parseAndQuery(query, loc); ok {
+			result = result.union(set)
+		} else if result == nil {
+			result = make(browserslistSet)
+		}
+	}
+	return result
+}
+
+func trimWithLoc(text string, loc int) (string, int) {
+	trimmed := strings.TrimLeft(text, " \t\r")
+	loc += len(text) - len(trimmed)
+	return strings.TrimRight(trimmed, " \t\r"), loc
+}
+
+// Handles queries that are joined with "and"
+func (p *browserslistParser) parseAndQuery(query string, loc int) (browserslistSet, bool) {
+	var result browserslistSet
+	ok := true
+	start := 0
+	for _, match := range append(browserslistAnd.FindAllStringIndex(query, -1), []int{len(query), len(query)}) {
+		term, termLoc := trimWithLoc(query[start:match[0]], loc+start)
+		start = match[1]
+
+		isNot := false
+		if not := browserslistNot.FindString(term); not != "" && result != nil {
+			term = term[len(not):]
+			termLoc += len(not)
+			isNot = true
+		}
+
+		set, termOK := p.parseTerm(term, termLoc)
+		switch {
+		case !termOK:
+			ok = false
+		case result == nil:
+			result = set
+		case isNot:
+			result = result.subtract(set)
+		default:
+			result = result.intersect(set)
+		}
+	}
+	return result, ok
+}
+
+// Handles a single query such as "chrome 90" or "safari >= 14"
+func (p *browserslistParser) parseTerm(term string, loc int) (browserslistSet, bool) {
+	lower := strings.ToLower(term)
+	var name string
+	var spans versionSpans
+
+	// Handle queries that are resolved using browser data first
+	if set, ok, isDataQuery := p.parseDataQuery(term, lower, loc); isDataQuery {
+		return set, ok
+	}
+
+	if match := browserslistVersionQuery.FindStringSubmatch(lower); match != nil {
+		name = match[1]
+		switch version := match[2]; version {
+		case "all":
+			spans = versionSpans{{start: []int{0}}}
+
+		case "tp":
+			// Safari Technology Preview is newer than every released version, so
+			// it can't make any other version the oldest one
+
+		default:
+			var ok bool
+			if spans, ok = parseBrowserslistVersionRange(version); !ok {
+				p.addError(loc, len(term), fmt.Sprintf("Invalid version in browserslist query %q", term))
+				return nil, false
+			}
+		}
+	} else if match := browserslistCompareQuery.FindStringSubmatch(lower); match != nil {
+		name = match[1]
+		version, ok := parseBrowserslistVersion(match[3])
+		if !ok {
+			p.addError(loc, len(term), fmt.Sprintf("Invalid version in browserslist query %q", term))
+			return nil, false
+		}
+		switch match[2] {
+		case ">=":
+			spans = versionSpans{{start: version}}
+		case ">":
+			spans = versionSpans{{start: nextVersion(version)}}
+		case "<=":
+			spans = versionSpans{{start: []int{0}, end: nextVersion(version)}}
+		case "<":
+			if isBefore([]int{0}, version) {
+				spans = versionSpans{{start: []int{0}, end: version}}
+			}
+		}
+	} else {
+		p.addUnsupportedQueryError(term, lower, loc)
+		return nil, false
+	}
+
+	if browserslistIgnoredBrowsers[name] {
+		p.log.AddID(logger.MsgID_None, logger.Debug, &p.tracker, p.rangeOf(loc, len(term)),
+			fmt.Sprintf("Ignoring the browserslist query %q because esbuild doesn't have data for this browser", term))
+		return browserslistSet{}, true
+	}
+
+	engine, ok := browserslistEngines[name]
+	if name == "android" {
+		// Only the Chromium-based versions of the Android WebView are supported
+		for _, span := range spans {
+			if CompareVersionSlices(span.start, []int{browserslistFirstChromiumAndroid}) < 0 {
+				p.addError(loc, len(term), fmt.Sprintf("The browserslist query %q includes Android versions before %d, which are not supported",
+					term, browserslistFirstChromiumAndroid))
+				return nil, false
+			}
+		}
+		engine, ok = Chrome, true
+	}
+	if !ok {
+		p.addUnsupportedBrowserError(name, term, loc)
+		return nil, false
+	}
+
+	set := make(browserslistSet)
+	if len(spans) > 0 {
+		set[engine] = spans
+	}
+	return set, true
+}
+
+// Handles queries that need browser release or usage data such as "defaults",
+// "last 2 versions", or "> 0.5%". The last return value is false if the term
+// isn't one of these queries.
+func (p *browserslistParser) parseDataQuery(term string, lower string, loc int) (browserslistSet, bool, bool) {
+	var set browserslistSet
+
+	if match := browserslistLastQuery.FindStringSubmatch(lower); match != nil {
+		// "last 2 versions"
+		// "last 2 major versions"
+		// "last 2 safari versions"
+		// "last 2 safari major versions"
+		count, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, false, false
+		}
+		name, isMajor := match[2], match[3] != ""
+		if alias, ok := browserslistAliases[name]; ok {
+			name = alias
+		}
+		if name != "" {
+			if browserslistIgnoredBrowsers[name] {
+				p.log.AddID(logger.MsgID_None, logger.Debug, &p.tracker, p.rangeOf(loc, len(term)),
+					fmt.Sprintf("Ignoring the browserslist query %q because esbuild doesn't have data for this browser", term))
+				return browserslistSet{}, true, true
+			}
+			if _, ok := browserslistReleases[name]; !ok {
+				if _, ok := browserslistEngines[name]; ok {
+					// There is no release data for node
+					return nil, false, false
+				}
+				p.addUnsupportedBrowserError(name, term, loc)
+				return nil, false, true
+			}
+		}
+		set = browserslistReleasesToSet(func(releaseName string, releases []browserslistRelease, i int) bool {
+			if name != "" && releaseName != name {
+				return false
+			}
+			if !isMajor {
+				return i >= len(releases)-count
+			}
+
+			// Count the number of distinct major versions from the end
+			majors := 0
+			for j := len(releases) - 1; j >= i; j-- {
+				if j == len(releases)-1 || browserslistMajorVersion(releases[j].version) != browserslistMajorVersion(releases[j+1].version) {
+					majors++
+				}
+			}
+			return majors <= count
+		})
+	} else if match := browserslistUsageQuery.FindStringSubmatch(lower); match != nil {
+		// "> 0.5%"
+		usage, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			p.addError(loc, len(term), fmt.Sprintf("Invalid usage in browserslist query %q", term))
+			return nil, false, true
+		}
+		op := match[1]
+		set = browserslistReleasesToSet(func(_ string, releases []browserslistRelease, i int) bool {
+			switch op {
+			case ">":
+				return releases[i].usage > usage
+			case ">=":
+				return releases[i].usage >= usage
+			case "<":
+				return releases[i].usage < usage
+			default:
+				return releases[i].usage <= usage
+			}
+		})
+
+		// Versions that are too old to be in the data have no usage
+		if op[0] == '<' {
+			set = set.union(browserslistReleasesToSet(nil))
+		}
+	} else {
+		// "defaults"
+		// "dead"
+		// "firefox esr"
+		// "maintained node versions"
+		words := strings.Fields(lower)
+		if len(words) == 0 {
+			return nil, false, false
+		}
+		if alias, ok := browserslistAliases[words[0]]; ok {
+			words[0] = alias
+		}
+		query, ok := browserslistNamedQueries[strings.Join(words, " ")]
+		if !ok {
+			return nil, false, false
+		}
+
+		// Named queries are defined in terms of other queries, and also include
+		// browsers that esbuild doesn't have data for. Those are ignored here.
+		nested := browserslistParser{log: logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil), ok: true}
+		set = nested.parseQueries(query)
+		if !nested.ok {
+			panic("Internal error")
+		}
+	}
+
+	p.log.AddID(logger.MsgID_None, logger.Debug, &p.tracker, p.rangeOf(loc, len(term)),
+		fmt.Sprintf("Resolved the browserslist query %q using esbuild's browser data from %s", term, browserslistDataDate))
+	return set, true, true
+}
+
+// This returns the set of all released versions that match the filter. If the
+// filter is nil, this instead returns the set of versions that are older than
+// every release in the data.
+func browserslistReleasesToSet(filter func(name string, releases []browserslistRelease, i int) bool) browserslistSet {
+	set := make(browserslistSet)
+	for name, releases := range browserslistReleases {
+		engine, ok := browserslistEngines[name]
+		if name == "android" {
+			engine, ok = Chrome, true
+		}
+		if !ok {
+			panic("Internal error")
+		}
+		var spans versionSpans
+		if filter == nil {
+			first, ok := parseBrowserslistVersionRange(releases[0].version)
+			if !ok {
+				panic("Internal error")
+			}
+			spans = versionSpans{{start: []int{0}, end: first[0].start}}
+		} else {
+			for i, release := range releases {
+				if filter(name, releases, i) {
+					span, ok := parseBrowserslistVersionRange(release.version)
+					if !ok {
+						panic("Internal error")
+					}
+					spans = spans.union(span)
+				}
+			}
+		}
+		if len(spans) > 0 {
+			set[engine] = set[engine].union(spans)
+		}
+	}
+	return set
+}
+
+func browserslistMajorVersion(version string) string {
+	if dot := strings.IndexAny(version, ".-"); dot != -1 {
+		return version[:dot]
+	}
+	return version
+}
+
+// Parses a single version such as "14.1" or a range such as "14.0-14.4"
+func parseBrowserslistVersionRange(version string) (versionSpans, bool) {
+	first, last := version, version
+	if dash := strings.IndexByte(version, '-'); dash != -1 {
+		first, last = strings.TrimSpace(version[:dash]), strings.TrimSpace(version[dash+1:])
+	}
+	start, ok1 := parseBrowserslistVersion(first)
+	end, ok2 := parseBrowserslistVersion(last)
+	if !ok1 || !ok2 {
+		return nil, false
+	}
+	if end = nextVersion(end); isBefore(start, end) {
+		return versionSpans{{start: start, end: end}}, true
+	}
+	return nil, true
+}
+
+func parseBrowserslistVersion(text string) ([]int, bool) {
+	var version []int
+	for _, part := range strings.Split(text, ".") {
+		value, err := strconv.Atoi(part)
+		if err != nil || value < 0 {
+			return nil, false
+		}
+		version = append(version, value)
+	}
+	return version, true
+}
+
+func (p *browserslistParser) rangeOf(loc int, length int) logger.Range {
+	return logger.Range{Loc: logger.Loc{Start: int32(loc)}, Len: int32(length)}
+}
+
+func (p *browserslistParser) addUnsupportedQueryError(term string, lower string, loc int) {
+	text := fmt.Sprintf("Unsupported browserslist query %q", term)
+	for _, query := range browserslistDataQueries {
+		if query.regex.MatchString(lower) {
+			text = fmt.Sprintf("The browserslist query %q needs %s, which esbuild doesn't have", term, query.data)
+			break
+		}
+	}
+	p.log.AddErrorWithNotes(&p.tracker, p.rangeOf(loc, len(term)), text, []logger.MsgData{
+		{Text: "Supported queries are explicit browser versions such as \"chrome >= 90\" or \"safari 14.1\", " +
+			"\"last 2 versions\", \"> 0.5%\", \"defaults\", \"dead\", \"firefox esr\", and \"maintained node versions\"."},
+		{Text: fmt.Sprintf("You can run \"npx browserslist '%s'\" to convert your query into a list of explicit browser versions.",
+			strings.Join(p.queries, ", "))},
+	})
+	p.ok = false
+}
+
+func (p *browserslistParser) addUnsupportedBrowserError(name string, term string, loc int) {
+	names := make([]string, 0, len(browserslistEngines)+1)
+	for name := range browserslistEngines {
+		names = append(names, fmt.Sprintf("%q", name))
+	}
+	names = append(names, "\"android\"")
+	sort.Strings(names)
+	p.log.AddErrorWithNotes(&p.tracker, p.rangeOf(loc, len(term)),
+		fmt.Sprintf("Unsupported browser %q in browserslist query %q", name, term),
+		[]logger.MsgData{{Text: fmt.Sprintf("Valid browser names are %s.", strings.Join(names, ", "))}})
+	p.ok = false
+}
+
+func (p *browserslistParser) addError(loc int, length int, text string) {
+	p.log.AddError(&p.tracker, p.rangeOf(loc, length), text)
+	p.ok = false
+}
@@ -0,0 +1,142 @@
+package compat
+
+// This is a small snapshot of the browser release and usage data from caniuse
+// (https://caniuse.com/) that browserslist uses for queries such as "defaults",
+// "last 2 versions", and "> 0.5%". It only contains the browsers that esbuild
+// has compatibility data for, and only their recent versions. Older versions
+// are left out because their usage is negligible. This snapshot isn't updated
+// automatically, so these queries may not exactly match the output of running
+// browserslist with up-to-date data.
+const browserslistDataDate = "December 2022"
+
+type browserslistRelease struct {
+	version string  // The version in browserslist syntax (e.g. "108" or "15.2-15.3")
+	usage   float64 // The global usage of this version as a percentage
+}
+
+// Releases are sorted from oldest to newest. The Android WebView only lists
+// the versions based on Chromium, which use Chrome's version numbers.
+var browserslistReleases = map[string][]browserslistRelease{
+	"and_chr": {
+		{"108", 38.82},
+	},
+	"and_ff": {
+		{"107", 0.27},
+	},
+	"android": {
+		{"107", 0},
+		{"108", 0.3},
+	},
+	"chrome": {
+		{"87", 0.05},
+		{"88", 0.04},
+		{"89", 0.05},
+		{"90", 0.04},
+		{"91", 0.06},
+		{"92", 0.05},
+		{"93", 0.04},
+		{"94", 0.04},
+		{"95", 0.05},
+		{"96", 0.05},
+		{"97", 0.05},
+		{"98", 0.07},
+		{"99", 0.08},
+		{"100", 0.12},
+		{"101", 0.08},
+		{"102", 0.17},
+		{"103", 0.6},
+		{"104", 0.32},
+		{"105", 0.41},
+		{"106", 0.78},
+		{"107", 5.41},
+		{"108", 13.12},
+	},
+	"edge": {
+		{"99", 0.02},
+		{"100", 0.03},
+		{"101", 0.02},
+		{"102", 0.03},
+		{"103", 0.04},
+		{"104", 0.04},
+		{"105", 0.05},
+		{"106", 0.08},
+		{"107", 0.9},
+		{"108", 3.2},
+	},
+	"firefox": {
+		{"91", 0.04},
+		{"96", 0.02},
+		{"97", 0.02},
+		{"98", 0.03},
+		{"99", 0.03},
+		{"100", 0.03},
+		{"101", 0.02},
+		{"102", 0.21},
+		{"103", 0.03},
+		{"104", 0.05},
+		{"105", 0.05},
+		{"106", 0.1},
+		{"107", 1.1},
+		{"108", 1.15},
+	},
+	"ie": {
+		{"5.5", 0},
+		{"6", 0.01},
+		{"7", 0.01},
+		{"8", 0.02},
+		{"9", 0.02},
+		{"10", 0.02},
+		{"11", 0.45},
+	},
+	"ios_saf": {
+		{"12.2-12.5", 0.61},
+		{"13.0-13.1", 0.03},
+		{"13.2", 0.02},
+		{"13.3", 0.05},
+		{"13.4-13.7", 0.15},
+		{"14.0-14.4", 0.3},
+		{"14.5-14.8", 0.6},
+		{"15.0-15.1", 0.2},
+		{"15.2-15.3", 0.3},
+		{"15.4", 0.6},
+		{"15.5", 0.8},
+		{"15.6", 4.5},
+		{"16.0", 1.3},
+		{"16.1", 4.9},
+		{"16.2", 1.1},
+	},
+	"opera": {
+		{"85", 0.01},
+		{"86", 0.01},
+		{"87", 0.02},
+		{"88", 0.01},
+		{"89", 0.02},
+		{"90", 0.02},
+		{"91", 0.03},
+		{"92", 0.3},
+		{"93", 0.9},
+	},
+	"safari": {
+		{"13.1", 0.05},
+		{"14", 0.04},
+		{"14.1", 0.18},
+		{"15", 0.03},
+		{"15.1", 0.06},
+		{"15.2-15.3", 0.05},
+		{"15.4", 0.1},
+		{"15.5", 0.15},
+		{"15.6", 0.72},
+		{"16.0", 0.15},
+		{"16.1", 0.62},
+		{"16.2", 0.18},
+	},
+}
+
+// These are the definitions that browserslist uses for its named queries,
+// written in terms of queries that can be resolved using the data above
+var browserslistNamedQueries = map[string]string{
+	"dead":                     "ie <= 11, ie_mob <= 11, bb <= 10, op_mob <= 12.1, samsung 4, baidu >= 0",
+	"defaults":                 "> 0.5%, last 2 versions, firefox esr, not dead",
+	"firefox esr":              "firefox 102",
+	"maintained node versions": "node >= 14",
+}
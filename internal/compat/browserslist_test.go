@@ -0,0 +1,145 @@
+package compat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func expectBrowserslist(t *testing.T, query string, expected string) {
+	t.Helper()
+	t.Run(query, func(t *testing.T) {
+		t.Helper()
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		versions, ok := ParseBrowserslist(log, test.SourceForTest(query))
+		msgs := log.Done()
+		text := ""
+		for _, msg := range msgs {
+			text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+		}
+		test.AssertEqualWithDiff(t, text, "")
+		test.AssertEqual(t, ok, true)
+		var targets []string
+		for engine, version := range versions {
+			parts := make([]string, len(version))
+			for i, part := range version {
+				parts[i] = fmt.Sprintf("%d", part)
+			}
+			targets = append(targets, engine.String()+strings.Join(parts, "."))
+		}
+		sort.Strings(targets)
+		test.AssertEqualWithDiff(t, strings.Join(targets, ","), expected)
+	})
+}
+
+func expectBrowserslistError(t *testing.T, query string, expected string) {
+	t.Helper()
+	t.Run(query, func(t *testing.T) {
+		t.Helper()
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		_, ok := ParseBrowserslist(log, test.SourceForTest(query))
+		msgs := log.Done()
+		text := ""
+		for _, msg := range msgs {
+			text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+		}
+		test.AssertEqualWithDiff(t, text, expected)
+		test.AssertEqual(t, ok, false)
+	})
+}
+
+func TestBrowserslist(t *testing.T) {
+	expectBrowserslist(t, "", "")
+	expectBrowserslist(t, "chrome 90", "chrome90")
+	expectBrowserslist(t, "Chrome 90", "chrome90")
+	expectBrowserslist(t, "chrome 90, chrome 80", "chrome80")
+	expectBrowserslist(t, "chrome 90 or firefox 88", "chrome90,firefox88")
+	expectBrowserslist(t, "chrome 90\nfirefox 88 # comment\n# chrome 10", "chrome90,firefox88")
+	expectBrowserslist(t, "safari 14.1, ios_saf 14.0-14.4", "ios14.0,safari14.1")
+	expectBrowserslist(t, "safari TP, safari 15", "safari15")
+	expectBrowserslist(t, "ie 11, edge 18, opera 70", "edge18,ie11,opera70")
+	expectBrowserslist(t, "and_chr 120, and_ff 119, android 120", "chrome120,firefox119")
+	expectBrowserslist(t, "ff 90, fx 91, explorer 9", "firefox90,ie9")
+	expectBrowserslist(t, "node 18.17.0", "node18.17.0")
+
+	expectBrowserslist(t, "chrome >= 90", "chrome90")
+	expectBrowserslist(t, "chrome>=90", "chrome90")
+	expectBrowserslist(t, "chrome > 90", "chrome91")
+	expectBrowserslist(t, "safari > 14.1", "safari14.2")
+	expectBrowserslist(t, "ie <= 11", "ie0")
+	expectBrowserslist(t, "ie < 0", "")
+
+	expectBrowserslist(t, "chrome >= 80, not chrome < 85", "chrome85")
+	expectBrowserslist(t, "chrome >= 80, not chrome 80", "chrome81")
+	expectBrowserslist(t, "ie >= 9, not ie >= 10", "ie9")
+	expectBrowserslist(t, "ie 9, ie 10, not ie 9", "ie10")
+	expectBrowserslist(t, "ie 9, not ie 9", "")
+	expectBrowserslist(t, "chrome >= 80 and chrome > 85", "chrome86")
+	expectBrowserslist(t, "chrome >= 80 and not chrome 80", "chrome81")
+	expectBrowserslist(t, "chrome >= 80 and firefox >= 80", "")
+
+	// Browsers without compatibility data are ignored
+	expectBrowserslist(t, "chrome 120, op_mini all, samsung 23, kaios 3.0-3.1", "chrome120")
+
+	// These queries are resolved using esbuild's built-in browser data
+	expectBrowserslist(t, "defaults", "chrome103,edge107,firefox102,ios12.2,opera92,safari15.6")
+	expectBrowserslist(t, "defaults, not IE 11", "chrome103,edge107,firefox102,ios12.2,opera92,safari15.6")
+	expectBrowserslist(t, "last 2 versions", "chrome107,edge107,firefox107,ie10,ios16.1,opera92,safari16.1")
+	expectBrowserslist(t, "last 2 versions, not dead", "chrome107,edge107,firefox107,ios16.1,opera92,safari16.1")
+	expectBrowserslist(t, "last 1 major versions", "chrome108,edge108,firefox107,ie11,ios16.0,opera93,safari16.0")
+	expectBrowserslist(t, "last 2 Safari versions", "safari16.1")
+	expectBrowserslist(t, "last 2 safari major versions", "safari15")
+	expectBrowserslist(t, "last 3 ios versions", "ios16.0")
+	expectBrowserslist(t, "last 2 op_mini versions", "")
+	expectBrowserslist(t, "> 1%", "chrome107,edge108,firefox107,ios15.6")
+	expectBrowserslist(t, ">= 0.5%", "chrome103,edge107,firefox107,ios12.2,opera93,safari15.6")
+	expectBrowserslist(t, "< 0.5%", "chrome0,edge0,firefox0,ie0,ios0,opera0,safari0")
+	expectBrowserslist(t, "> 1% and chrome > 0", "chrome107")
+	expectBrowserslist(t, "chrome 90, not dead", "chrome90")
+	expectBrowserslist(t, "ie 11, not dead", "")
+	expectBrowserslist(t, "dead", "ie0")
+	expectBrowserslist(t, "firefox esr", "firefox102")
+	expectBrowserslist(t, "Firefox ESR, fx esr", "firefox102")
+	expectBrowserslist(t, "maintained node versions", "node14")
+
+	expectBrowserslistError(t, "> 0.5% in US",
+		"<stdin>: ERROR: The browserslist query \"> 0.5% in US\" needs regional browser usage data, which esbuild doesn't have\n"+
+			"NOTE: Supported queries are explicit browser versions such as \"chrome >= 90\" or \"safari 14.1\", \"last 2 versions\", \"> 0.5%\", \"defaults\", \"dead\", \"firefox esr\", and \"maintained node versions\".\n"+
+			"NOTE: You can run \"npx browserslist '> 0.5% in US'\" to convert your query into a list of explicit browser versions.\n")
+	expectBrowserslistError(t, "chrome 90, cover 99.5%",
+		"<stdin>: ERROR: The browserslist query \"cover 99.5%\" needs usage data for every browser, which esbuild doesn't have\n"+
+			"NOTE: Supported queries are explicit browser versions such as \"chrome >= 90\" or \"safari 14.1\", \"last 2 versions\", \"> 0.5%\", \"defaults\", \"dead\", \"firefox esr\", and \"maintained node versions\".\n"+
+			"NOTE: You can run \"npx browserslist 'chrome 90, cover 99.5%'\" to convert your query into a list of explicit browser versions.\n")
+	expectBrowserslistError(t, "last 2 years",
+		"<stdin>: ERROR: The browserslist query \"last 2 years\" needs browser release dates, which esbuild doesn't have\n"+
+			"NOTE: Supported queries are explicit browser versions such as \"chrome >= 90\" or \"safari 14.1\", \"last 2 versions\", \"> 0.5%\", \"defaults\", \"dead\", \"firefox esr\", and \"maintained node versions\".\n"+
+			"NOTE: You can run \"npx browserslist 'last 2 years'\" to convert your query into a list of explicit browser versions.\n")
+	expectBrowserslistError(t, "last 2 node versions",
+		"<stdin>: ERROR: The browserslist query \"last 2 node versions\" needs node release data, which esbuild doesn't have\n"+
+			"NOTE: Supported queries are explicit browser versions such as \"chrome >= 90\" or \"safari 14.1\", \"last 2 versions\", \"> 0.5%\", \"defaults\", \"dead\", \"firefox esr\", and \"maintained node versions\".\n"+
+			"NOTE: You can run \"npx browserslist 'last 2 node versions'\" to convert your query into a list of explicit browser versions.\n")
+	expectBrowserslistError(t, "chrome",
+		"<stdin>: ERROR: Unsupported browserslist query \"chrome\"\n"+
+			"NOTE: Supported queries are explicit browser versions such as \"chrome >= 90\" or \"safari 14.1\", \"last 2 versions\", \"> 0.5%\", \"defaults\", \"dead\", \"firefox esr\", and \"maintained node versions\".\n"+
+			"NOTE: You can run \"npx browserslist 'chrome'\" to convert your query into a list of explicit browser versions.\n")
+	expectBrowserslistError(t, "not dead",
+		"<stdin>: ERROR: The browserslist query \"not dead\" must come after another query\n")
+	expectBrowserslistError(t, "not ie 11",
+		"<stdin>: ERROR: The browserslist query \"not ie 11\" must come after another query\n")
+	expectBrowserslistError(t, "chrome 9..0",
+		"<stdin>: ERROR: Invalid version in browserslist query \"chrome 9..0\"\n")
+	expectBrowserslistError(t, "android 4.4",
+		"<stdin>: ERROR: The browserslist query \"android 4.4\" includes Android versions before 37, which are not supported\n")
+	expectBrowserslistError(t, "last 2 netscape versions",
+		"<stdin>: ERROR: Unsupported browser \"netscape\" in browserslist query \"last 2 netscape versions\"\n"+
+			"NOTE: Valid browser names are \"and_chr\", \"and_ff\", \"android\", \"chrome\", \"chromeandroid\", \"edge\", \"explorer\", "+
+			"\"ff\", \"firefox\", \"firefoxandroid\", \"fx\", \"ie\", \"ios\", \"ios_saf\", \"node\", \"opera\", \"safari\".\n")
+	expectBrowserslistError(t, "netscape 4",
+		"<stdin>: ERROR: Unsupported browser \"netscape\" in browserslist query \"netscape 4\"\n"+
+			"NOTE: Valid browser names are \"and_chr\", \"and_ff\", \"android\", \"chrome\", \"chromeandroid\", \"edge\", \"explorer\", "+
+			"\"ff\", \"firefox\", \"firefoxandroid\", \"fx\", \"ie\", \"ios\", \"ios_saf\", \"node\", \"opera\", \"safari\".\n")
+}
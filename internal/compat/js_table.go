@@ -70,6 +70,7 @@ const (
 	ClassStaticBlocks
 	ClassStaticField
 	ConstAndLet
+	Decorators
 	DefaultArgument
 	Destructuring
 	DynamicImport
@@ -80,6 +81,7 @@ const (
 	Generator
 	Hashbang
 	ImportAssertions
+	ImportAttributes
 	ImportMeta
 	InlineScript
 	LogicalAssignment
@@ -105,6 +107,7 @@ const (
 	TopLevelAwait
 	TypeofExoticObjectIsObject
 	UnicodeEscapes
+	Using
 )
 
 var StringToJSFeature = map[string]JSFeature{
@@ -126,6 +129,7 @@ var StringToJSFeature = map[string]JSFeature{
 	"class-static-blocks":              ClassStaticBlocks,
 	"class-static-field":               ClassStaticField,
 	"const-and-let":                    ConstAndLet,
+	"decorators":                       Decorators,
 	"default-argument":                 DefaultArgument,
 	"destructuring":                    Destructuring,
 	"dynamic-import":                   DynamicImport,
@@ -136,6 +140,7 @@ var StringToJSFeature = map[string]JSFeature{
 	"generator":                        Generator,
 	"hashbang":                         Hashbang,
 	"import-assertions":                ImportAssertions,
+	"import-attributes":                ImportAttributes,
 	"import-meta":                      ImportMeta,
 	"inline-script":                    InlineScript,
 	"logical-assignment":               LogicalAssignment,
@@ -161,6 +166,7 @@ var StringToJSFeature = map[string]JSFeature{
 	"top-level-await":                  TopLevelAwait,
 	"typeof-exotic-object-is-object":   TypeofExoticObjectIsObject,
 	"unicode-escapes":                  UnicodeEscapes,
+	"using":                            Using,
 }
 
 func (features JSFeature) Has(feature JSFeature) bool {
@@ -365,6 +371,7 @@ var jsTable = map[JSFeature]map[Engine][]versionRange{
 		Opera:   {{start: v{36, 0, 0}}},
 		Safari:  {{start: v{11, 0, 0}}},
 	},
+	Decorators: {},
 	DefaultArgument: {
 		Chrome:  {{start: v{49, 0, 0}}},
 		Deno:    {{start: v{1, 0, 0}}},
@@ -467,6 +474,11 @@ var jsTable = map[JSFeature]map[Engine][]versionRange{
 		Chrome: {{start: v{91, 0, 0}}},
 		Node:   {{start: v{16, 14, 0}}},
 	},
+	ImportAttributes: {
+		Chrome: {{start: v{123, 0, 0}}},
+		Deno:   {{start: v{1, 37, 0}}},
+		Node:   {{start: v{18, 20, 0}, end: v{19, 0, 0}}, {start: v{20, 10, 0}}},
+	},
 	ImportMeta: {
 		Chrome:  {{start: v{64, 0, 0}}},
 		Edge:    {{start: v{79, 0, 0}}},
@@ -713,6 +725,7 @@ var jsTable = map[JSFeature]map[Engine][]versionRange{
 		Opera:   {{start: v{31, 0, 0}}},
 		Safari:  {{start: v{9, 0, 0}}},
 	},
+	Using: {},
 }
 
 // Return all features that are not available in at least one environment
@@ -53,6 +53,7 @@ func (jsxOptions *JSXOptions) SetOptionsFromTSJSX(tsx TSJSX) {
 type TSOptions struct {
 	Parse               bool
 	NoAmbiguousLessThan bool
+	Flow                bool // Parse Flow type syntax instead of TypeScript
 }
 
 type Platform uint8
@@ -61,6 +62,25 @@ const (
 	PlatformBrowser Platform = iota
 	PlatformNode
 	PlatformNeutral
+	PlatformDeno
+)
+
+// This controls what happens to references to the CommonJS "__dirname" and
+// "__filename" globals in bundled code
+type DirnameMode uint8
+
+const (
+	// Leave the references alone
+	DirnameDefault DirnameMode = iota
+
+	// Replace them with the path of the original source file as a string
+	DirnameSource
+
+	// Replace them with expressions that compute the path at run-time
+	DirnameRuntime
+
+	// Generate an error for each reference
+	DirnameError
 )
 
 type SourceMap uint8
@@ -87,6 +107,35 @@ func (lc LegalComments) HasExternalFile() bool {
 	return lc == LegalCommentsLinkedWithComment || lc == LegalCommentsExternalWithoutComment
 }
 
+type CommentsMode uint8
+
+const (
+	// Keep legal comments and comments inside of expressions
+	CommentsDefault CommentsMode = iota
+
+	// Don't keep any comments (legal comments are also disabled)
+	CommentsNone
+
+	// Only keep legal comments
+	CommentsLegalOnly
+
+	// Also keep comments between statements and before function parameters.
+	// Comments in other positions (e.g. "let /* a */ x") are still removed.
+	CommentsAll
+)
+
+type ExposedModule struct {
+	// This is the name that other builds use to import this module, and is
+	// also the output path of this module relative to the output directory
+	Name string
+
+	InputPath string
+}
+
+// This is the output path of the container for exposed modules relative to the
+// output directory (without the output extension)
+const FederationContainerName = "remoteEntry"
+
 type Loader uint8
 
 const (
@@ -99,6 +148,8 @@ const (
 	LoaderDefault
 	LoaderEmpty
 	LoaderFile
+	LoaderFlow
+	LoaderHTML
 	LoaderJS
 	LoaderJSON
 	LoaderJSX
@@ -118,6 +169,8 @@ var LoaderToString = []string{
 	"default",
 	"empty",
 	"file",
+	"flow",
+	"html",
 	"js",
 	"json",
 	"jsx",
@@ -136,9 +189,18 @@ func (loader Loader) IsTypeScript() bool {
 	}
 }
 
+func (loader Loader) IsJavaScriptLike() bool {
+	switch loader {
+	case LoaderFlow, LoaderJS, LoaderJSX, LoaderTS, LoaderTSNoAmbiguousLessThan, LoaderTSX:
+		return true
+	default:
+		return false
+	}
+}
+
 func (loader Loader) CanHaveSourceMap() bool {
 	switch loader {
-	case LoaderJS, LoaderJSX, LoaderTS, LoaderTSNoAmbiguousLessThan, LoaderTSX, LoaderCSS, LoaderJSON:
+	case LoaderFlow, LoaderJS, LoaderJSX, LoaderTS, LoaderTSNoAmbiguousLessThan, LoaderTSX, LoaderCSS, LoaderJSON:
 		return true
 	default:
 		return false
@@ -182,12 +244,47 @@ const (
 	//   export {...};
 	//
 	FormatESModule
+
+	// UMD stands for universal module definition. It's like the IIFE format
+	// except that the function is passed to AMD's "define()" or assigned to
+	// "module.exports" or a global variable depending on what's available:
+	//
+	//   (function(root, factory) {
+	//     if (typeof define === "function" && define.amd) define(["require", ...], factory);
+	//     else if (typeof module === "object" && module.exports) module.exports = factory(require);
+	//     else root.globalName = factory(...);
+	//   })(this, function(require) {
+	//     ... bundled code ...
+	//     return exports;
+	//   });
+	//
+	FormatUMD
+
+	// The SystemJS format looks like this:
+	//
+	//   System.register(["dep", ...], function(_export) {
+	//     ...
+	//     return {
+	//       setters: [...],
+	//       execute: function() {
+	//         ... bundled code ...
+	//         _export(exports);
+	//       }
+	//     };
+	//   });
+	//
+	FormatSystem
 )
 
 func (f Format) KeepESMImportExportSyntax() bool {
 	return f == FormatPreserve || f == FormatESModule
 }
 
+// These formats put all of the code inside a function
+func (f Format) IsWrappedInFunction() bool {
+	return f == FormatIIFE || f == FormatUMD || f == FormatSystem
+}
+
 func (f Format) String() string {
 	switch f {
 	case FormatIIFE:
@@ -196,15 +293,20 @@ func (f Format) String() string {
 		return "cjs"
 	case FormatESModule:
 		return "esm"
+	case FormatUMD:
+		return "umd"
+	case FormatSystem:
+		return "system"
 	}
 	return ""
 }
 
 type StdinInfo struct {
-	Contents      string
-	SourceFile    string
-	AbsResolveDir string
-	Loader        Loader
+	Contents       string
+	SourceFile     string
+	InputSourceMap string
+	AbsResolveDir  string
+	Loader         Loader
 }
 
 type WildcardPattern struct {
@@ -290,6 +392,26 @@ type Options struct {
 	ExternalSettings ExternalSettings
 	ExternalPackages bool
 	PackageAliases   map[string]string
+	NodePolyfills    bool
+
+	// These are the names of node globals (e.g. "process" and "Buffer") that are
+	// shimmed when they are referenced by code bundled for a non-node platform
+	NodeGlobals []string
+	Dirname     DirnameMode
+
+	// This is an entity name or a JSON object that provides the properties of
+	// "import.meta" when the output format doesn't support "import.meta"
+	ImportMetaShim string
+
+	// This is an import map file (or a "deno.json" file) that remaps import
+	// paths before they are resolved
+	AbsImportMapPath string
+
+	// Module federation: exposed modules are written out as separate entry
+	// points along with a container that loads them by name, and imports of
+	// remote modules become imports of the URL of the corresponding remote
+	ExposedModules []ExposedModule
+	RemoteModules  map[string]string
 
 	AbsOutputFile      string
 	AbsOutputDir       string
@@ -300,6 +422,10 @@ type Options struct {
 	TsConfigOverride   string
 	ExtensionToLoader  map[string]Loader
 
+	// This maps the import paths of external modules to the global variables
+	// that are used in their place when UMD output is loaded with a script tag
+	UMDGlobals map[string][]string
+
 	PublicPath      string
 	InjectPaths     []string
 	InjectedDefines []InjectedDefine
@@ -335,22 +461,47 @@ type Options struct {
 	MinifySyntax      bool
 	ProfilerNames     bool
 	CodeSplitting     bool
+	RuntimeChunk      bool
 	WatchMode         bool
 	AllowOverwrite    bool
+	InlineWorkers     bool
 	LegalComments     LegalComments
+	Comments          CommentsMode
+
+	// If present, non-legal comments are only kept if they match this
+	CommentsFilter *regexp.Regexp
+
+	// If true, each output file gets a ".LICENSES.txt" file listing the
+	// third-party packages in it along with their license information
+	ExtractLicenses bool
+
+	// Output files for the references in HTML entry points that are smaller
+	// than this many bytes are inlined into the HTML file. Zero disables this.
+	HTMLInlineLimit int
 
 	// If true, make sure to generate a single file that can be written to stdout
 	WriteToStdout bool
 
+	// This is the text used for one level of indentation in non-minified
+	// output. If empty, two spaces are used.
+	IndentUnit string
+
+	// If true, single quotes are used for strings in the output unless double
+	// quotes would result in fewer escapes
+	PreferSingleQuotes bool
+
 	OmitRuntimeForTests     bool
 	OmitJSXRuntimeForTests  bool
 	UnusedImportFlagsTS     UnusedImportFlagsTS
 	UseDefineForClassFields MaybeBool
+	EmitDecoratorMetadata   bool
 	ASCIIOnly               bool
 	KeepNames               bool
 	IgnoreDCEAnnotations    bool
 	TreeShaking             bool
 	DropDebugger            bool
+	DropLabels              []string
+	ReserveNames            []string
 	MangleQuoted            bool
 	Platform                Platform
 	TargetFromAPI           TargetFromAPI
@@ -358,6 +509,22 @@ type Options struct {
 	NeedsMetafile           bool
 	SourceMap               SourceMap
 	ExcludeSourcesContent   bool
+
+	// If true, each output file with a source map gets a "debugId" comment and
+	// the source map gets a matching "debugId" field
+	SourceMapDebugID bool
+
+	// Sources in generated source maps whose paths match this are listed in the
+	// "x_google_ignoreList" field, which debuggers use to hide library code
+	SourceMapIgnoreList *regexp.Regexp
+
+	// Each key is a prefix of the paths in the "sources" field of generated
+	// source maps that is replaced by the corresponding value
+	SourceMapPathPrefix map[string]string
+
+	// If true, generated source maps only have one mapping at the start of each
+	// line instead of one mapping for each token
+	SourceMapLinesOnly bool
 }
 
 type TargetFromAPI uint8
@@ -407,11 +574,13 @@ const (
 	UnusedImportKeepValues                                 // "preserveValueImports" == true
 )
 
-func UnusedImportFlagsFromTsconfigValues(preserveImportsNotUsedAsValues bool, preserveValueImports bool) (flags UnusedImportFlagsTS) {
-	if preserveValueImports {
+// Note that "verbatimModuleSyntax" is the replacement for both of the other
+// settings in TypeScript 5.0+ and behaves like having both of them enabled
+func UnusedImportFlagsFromTsconfigValues(preserveImportsNotUsedAsValues bool, preserveValueImports bool, verbatimModuleSyntax bool) (flags UnusedImportFlagsTS) {
+	if preserveValueImports || verbatimModuleSyntax {
 		flags |= UnusedImportKeepValues
 	}
-	if preserveImportsNotUsedAsValues {
+	if preserveImportsNotUsedAsValues || verbatimModuleSyntax {
 		flags |= UnusedImportKeepStmt
 	}
 	return
@@ -562,6 +731,10 @@ type InjectedFile struct {
 	Exports    []InjectableExport
 	DefineName string
 	Source     logger.Source
+
+	// Shims for node globals are only imported into files that reference the
+	// global. Other injected files are imported into every file.
+	IsNodeGlobalShim bool
 }
 
 type InjectableExport struct {
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+type EnvVar struct {
+	Key   string
+	Value string
+}
+
+type envParser struct {
+	log     logger.Log
+	source  logger.Source
+	tracker logger.LineColumnTracker
+	index   int
+	ok      bool
+}
+
+// ParseEnvFile returns the variables in a ".env" file in the order they appear.
+// Each line is either blank, a "#" comment, or an assignment of the form
+// "KEY=value" optionally preceded by "export". Values can be unquoted, or can
+// be quoted using single quotes, double quotes, or backticks. Quoted values
+// can span multiple lines, and escape sequences such as "\n" are only
+// supported inside double quotes. A "#" after whitespace in an unquoted value
+// starts a comment.
+func ParseEnvFile(log logger.Log, source logger.Source) ([]EnvVar, bool) {
+	p := envParser{
+		log:     log,
+		source:  source,
+		tracker: logger.MakeLineColumnTracker(&source),
+		ok:      true,
+	}
+	var vars []EnvVar
+
+	for {
+		p.skipSpaces()
+		if p.index == len(p.source.Contents) {
+			break
+		}
+
+		// Skip over blank lines and comments
+		if c := p.source.Contents[p.index]; c == '\r' || c == '\n' || c == '#' {
+			p.skipLine()
+			continue
+		}
+
+		if v, ok := p.parseAssignment(); ok {
+			vars = append(vars, v)
+		} else {
+			p.skipLine()
+		}
+	}
+
+	return vars, p.ok
+}
+
+func (p *envParser) parseAssignment() (EnvVar, bool) {
+	key := p.parseKey()
+
+	// Allow the shell syntax "export KEY=value"
+	if key == "export" {
+		p.skipSpaces()
+		if p.index < len(p.source.Contents) && isEnvKeyChar(p.source.Contents[p.index]) {
+			key = p.parseKey()
+		}
+	}
+
+	if key == "" {
+		p.addError(logger.Range{Loc: logger.Loc{Start: int32(p.index)}}, "Expected a variable name")
+		return EnvVar{}, false
+	}
+
+	p.skipSpaces()
+	if p.index == len(p.source.Contents) || p.source.Contents[p.index] != '=' {
+		p.addError(logger.Range{Loc: logger.Loc{Start: int32(p.index)}}, fmt.Sprintf("Expected \"=\" after %q", key))
+		return EnvVar{}, false
+	}
+	p.index++
+	p.skipSpaces()
+
+	value, ok := p.parseValue()
+	if !ok {
+		return EnvVar{}, false
+	}
+	return EnvVar{Key: key, Value: value}, true
+}
+
+func (p *envParser) parseKey() string {
+	start := p.index
+	for p.index < len(p.source.Contents) && isEnvKeyChar(p.source.Contents[p.index]) {
+		p.index++
+	}
+	return p.source.Contents[start:p.index]
+}
+
+func (p *envParser) parseValue() (string, bool) {
+	contents := p.source.Contents
+	if p.index == len(contents) {
+		return "", true
+	}
+
+	switch quote := contents[p.index]; quote {
+	case '\'', '"', '`':
+		start := p.index
+		p.index++
+		var sb strings.Builder
+
+		for {
+			if p.index == len(contents) {
+				p.addError(logger.Range{Loc: logger.Loc{Start: int32(start)}, Len: 1}, "Unterminated quoted value")
+				return "", false
+			}
+			c := contents[p.index]
+			p.index++
+			if c == quote {
+				break
+			}
+
+			// Only double-quoted values support escape sequences
+			if c == '\\' && quote == '"' && p.index < len(contents) {
+				c = contents[p.index]
+				p.index++
+				switch c {
+				case 'n':
+					sb.WriteByte('\n')
+				case 'r':
+					sb.WriteByte('\r')
+				case 't':
+					sb.WriteByte('\t')
+				case '\\', '"':
+					sb.WriteByte(c)
+				default:
+					sb.WriteByte('\\')
+					sb.WriteByte(c)
+				}
+				continue
+			}
+
+			sb.WriteByte(c)
+		}
+
+		// Only a comment is allowed after the closing quote
+		p.skipSpaces()
+		if p.index < len(contents) {
+			if c := contents[p.index]; c != '\r' && c != '\n' && c != '#' {
+				p.addError(logger.Range{Loc: logger.Loc{Start: int32(p.index)}}, "Expected end of line after quoted value")
+				return "", false
+			}
+		}
+		p.skipLine()
+		return sb.String(), true
+
+	default:
+		start := p.index
+		end := p.index
+		for p.index < len(contents) {
+			c := contents[p.index]
+			if c == '\r' || c == '\n' || (c == '#' && (p.index == start || contents[p.index-1] == ' ' || contents[p.index-1] == '\t')) {
+				break
+			}
+			p.index++
+			if c != ' ' && c != '\t' {
+				end = p.index
+			}
+		}
+		p.skipLine()
+		return contents[start:end], true
+	}
+}
+
+func (p *envParser) skipSpaces() {
+	for p.index < len(p.source.Contents) {
+		if c := p.source.Contents[p.index]; c != ' ' && c != '\t' {
+			break
+		}
+		p.index++
+	}
+}
+
+func (p *envParser) skipLine() {
+	for p.index < len(p.source.Contents) {
+		c := p.source.Contents[p.index]
+		p.index++
+		if c == '\n' {
+			break
+		}
+	}
+}
+
+func (p *envParser) addError(r logger.Range, text string) {
+	p.log.AddError(&p.tracker, r, text)
+	p.ok = false
+}
+
+func isEnvKeyChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '.' || c == '-'
+}
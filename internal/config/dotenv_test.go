@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func expectEnvFile(t *testing.T, contents string, expected string) {
+	t.Helper()
+	t.Run(contents, func(t *testing.T) {
+		t.Helper()
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		vars, ok := ParseEnvFile(log, test.SourceForTest(contents))
+		msgs := log.Done()
+		text := ""
+		for _, msg := range msgs {
+			text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+		}
+		test.AssertEqualWithDiff(t, text, "")
+		test.AssertEqual(t, ok, true)
+		var parts []string
+		for _, v := range vars {
+			parts = append(parts, fmt.Sprintf("%s=%q", v.Key, v.Value))
+		}
+		test.AssertEqualWithDiff(t, strings.Join(parts, ","), expected)
+	})
+}
+
+func expectEnvFileError(t *testing.T, contents string, expected string) {
+	t.Helper()
+	t.Run(contents, func(t *testing.T) {
+		t.Helper()
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		_, ok := ParseEnvFile(log, test.SourceForTest(contents))
+		msgs := log.Done()
+		text := ""
+		for _, msg := range msgs {
+			text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+		}
+		test.AssertEqualWithDiff(t, text, expected)
+		test.AssertEqual(t, ok, false)
+	})
+}
+
+func TestEnvFile(t *testing.T) {
+	expectEnvFile(t, "", "")
+	expectEnvFile(t, "\n\n# comment\n  # comment\n", "")
+	expectEnvFile(t, "A=1", "A=\"1\"")
+	expectEnvFile(t, "A=1\nB=2\r\nC=3", "A=\"1\",B=\"2\",C=\"3\"")
+	expectEnvFile(t, "  A  =  some value  \n", "A=\"some value\"")
+	expectEnvFile(t, "A=", "A=\"\"")
+	expectEnvFile(t, "A=\nB=2", "A=\"\",B=\"2\"")
+	expectEnvFile(t, "export A=1", "A=\"1\"")
+	expectEnvFile(t, "export=1", "export=\"1\"")
+	expectEnvFile(t, "A.B-C=1", "A.B-C=\"1\"")
+	expectEnvFile(t, "A=1\nA=2", "A=\"1\",A=\"2\"")
+
+	// Comments
+	expectEnvFile(t, "A=1 # comment", "A=\"1\"")
+	expectEnvFile(t, "A=#fff", "A=\"\"")
+	expectEnvFile(t, "A=a#b", "A=\"a#b\"")
+	expectEnvFile(t, "A='1' # comment", "A=\"1\"")
+
+	// Quotes
+	expectEnvFile(t, "A='a \"b\" #c'", "A=\"a \\\"b\\\" #c\"")
+	expectEnvFile(t, "A=\"a 'b' #c\"", "A=\"a 'b' #c\"")
+	expectEnvFile(t, "A=`a 'b' \"c\"`", "A=\"a 'b' \\\"c\\\"\"")
+	expectEnvFile(t, "A='a\\nb'", "A=\"a\\\\nb\"")
+	expectEnvFile(t, "A=\"a\\nb\\t\\\"c\\\"\\\\\\x\"", "A=\"a\\nb\\t\\\"c\\\"\\\\\\\\x\"")
+	expectEnvFile(t, "A=\"line 1\nline 2\"\nB=2", "A=\"line 1\\nline 2\",B=\"2\"")
+	expectEnvFile(t, "A=  'a'  ", "A=\"a\"")
+}
+
+func TestEnvFileErrors(t *testing.T) {
+	expectEnvFileError(t, "A", "<stdin>: ERROR: Expected \"=\" after \"A\"\n")
+	expectEnvFileError(t, "A B=1", "<stdin>: ERROR: Expected \"=\" after \"A\"\n")
+	expectEnvFileError(t, "=1", "<stdin>: ERROR: Expected a variable name\n")
+	expectEnvFileError(t, "A='1", "<stdin>: ERROR: Unterminated quoted value\n")
+	expectEnvFileError(t, "A='1' 2", "<stdin>: ERROR: Expected end of line after quoted value\n")
+	expectEnvFileError(t, "A\nB=1\nC", "<stdin>: ERROR: Expected \"=\" after \"A\"\n<stdin>: ERROR: Expected \"=\" after \"C\"\n")
+}
@@ -1,6 +1,8 @@
 package css_parser
 
 import (
+	"strings"
+
 	"github.com/evanw/esbuild/internal/compat"
 	"github.com/evanw/esbuild/internal/css_ast"
 	"github.com/evanw/esbuild/internal/css_lexer"
@@ -17,7 +19,8 @@ func (p *parser) commaToken() css_ast.Token {
 	return t
 }
 
-func expandTokenQuad(tokens []css_ast.Token, allowedIdent string) (result [4]css_ast.Token, ok bool) {
+// If "isAllowed" is nil, only numeric tokens are allowed
+func expandTokenQuad(tokens []css_ast.Token, isAllowed func(css_ast.Token) bool) (result [4]css_ast.Token, ok bool) {
 	n := len(tokens)
 	if n < 1 || n > 4 {
 		return
@@ -25,7 +28,7 @@ func expandTokenQuad(tokens []css_ast.Token, allowedIdent string) (result [4]css
 
 	// Don't do this if we encounter any unexpected tokens such as "var()"
 	for i := 0; i < n; i++ {
-		if t := tokens[i]; !t.Kind.IsNumeric() && (t.Kind != css_lexer.TIdent || allowedIdent == "" || t.Text != allowedIdent) {
+		if t := tokens[i]; (isAllowed == nil && !t.Kind.IsNumeric()) || (isAllowed != nil && !isAllowed(t)) {
 			return
 		}
 	}
@@ -77,10 +80,19 @@ func compactTokenQuad(a css_ast.Token, b css_ast.Token, c css_ast.Token, d css_a
 	return tokens
 }
 
+// Declaration keys are case-insensitive but unknown keys keep their original
+// case, so this also matches something like "BORDER" or "Border-Top"
+func isBorderKey(keyText string) bool {
+	return len(keyText) >= 6 && strings.EqualFold(keyText[:6], "border")
+}
+
 func (p *parser) processDeclarations(rules []css_ast.Rule) []css_ast.Rule {
-	margin := boxTracker{key: css_ast.DMargin, keyText: "margin", allowAuto: true}
-	padding := boxTracker{key: css_ast.DPadding, keyText: "padding", allowAuto: false}
-	inset := boxTracker{key: css_ast.DInset, keyText: "inset", allowAuto: true}
+	margin := boxTracker{key: css_ast.DMargin, keyText: "margin", isAllowed: isNumericOrAuto}
+	padding := boxTracker{key: css_ast.DPadding, keyText: "padding"}
+	inset := boxTracker{key: css_ast.DInset, keyText: "inset", isAllowed: isNumericOrAuto}
+	borderWidth := boxTracker{key: css_ast.DBorderWidth, keyText: "border-width", isAllowed: isBorderWidth}
+	borderStyle := boxTracker{key: css_ast.DBorderStyle, keyText: "border-style", isAllowed: isBorderStyle}
+	borderColor := boxTracker{key: css_ast.DBorderColor, keyText: "border-color", isAllowed: isBorderColor}
 	borderRadius := borderRadiusTracker{}
 
 	for i, rule := range rules {
@@ -121,6 +133,33 @@ func (p *parser) processDeclarations(rules []css_ast.Rule) []css_ast.Rule {
 						decl.Value[0] = p.mangleColor(t, hex)
 					}
 				}
+			} else if decl.Key == css_ast.DBorderColor && p.options.MinifySyntax {
+				// "border-color: #ff0000 rgb(0, 0, 255)" => "border-color: red #00f"
+				for j, t := range decl.Value {
+					if hex, ok := parseColor(t); ok {
+						decl.Value[j] = p.mangleColor(t, hex)
+					}
+				}
+			}
+
+			// Border color
+			if p.options.MinifySyntax {
+				switch decl.Key {
+				case css_ast.DBorderColor:
+					borderColor.mangleSides(rules, decl, i, p.options.MinifyWhitespace)
+				case css_ast.DBorderTopColor:
+					borderColor.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxTop)
+				case css_ast.DBorderRightColor:
+					borderColor.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxRight)
+				case css_ast.DBorderBottomColor:
+					borderColor.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxBottom)
+				case css_ast.DBorderLeftColor:
+					borderColor.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxLeft)
+				default:
+					if isBorderKey(decl.KeyText) {
+						borderColor.sides = [4]boxSide{}
+					}
+				}
 			}
 
 		case css_ast.DFont:
@@ -216,6 +255,50 @@ func (p *parser) processDeclarations(rules []css_ast.Rule) []css_ast.Rule {
 				inset.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxLeft)
 			}
 
+		// Border width
+		case css_ast.DBorderWidth:
+			if p.options.MinifySyntax {
+				borderWidth.mangleSides(rules, decl, i, p.options.MinifyWhitespace)
+			}
+		case css_ast.DBorderTopWidth:
+			if p.options.MinifySyntax {
+				borderWidth.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxTop)
+			}
+		case css_ast.DBorderRightWidth:
+			if p.options.MinifySyntax {
+				borderWidth.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxRight)
+			}
+		case css_ast.DBorderBottomWidth:
+			if p.options.MinifySyntax {
+				borderWidth.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxBottom)
+			}
+		case css_ast.DBorderLeftWidth:
+			if p.options.MinifySyntax {
+				borderWidth.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxLeft)
+			}
+
+		// Border style
+		case css_ast.DBorderStyle:
+			if p.options.MinifySyntax {
+				borderStyle.mangleSides(rules, decl, i, p.options.MinifyWhitespace)
+			}
+		case css_ast.DBorderTopStyle:
+			if p.options.MinifySyntax {
+				borderStyle.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxTop)
+			}
+		case css_ast.DBorderRightStyle:
+			if p.options.MinifySyntax {
+				borderStyle.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxRight)
+			}
+		case css_ast.DBorderBottomStyle:
+			if p.options.MinifySyntax {
+				borderStyle.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxBottom)
+			}
+		case css_ast.DBorderLeftStyle:
+			if p.options.MinifySyntax {
+				borderStyle.mangleSide(rules, decl, i, p.options.MinifyWhitespace, boxLeft)
+			}
+
 		// Border radius
 		case css_ast.DBorderRadius:
 			if p.options.MinifySyntax {
@@ -237,6 +320,15 @@ func (p *parser) processDeclarations(rules []css_ast.Rule) []css_ast.Rule {
 			if p.options.MinifySyntax {
 				borderRadius.mangleCorner(rules, decl, i, p.options.MinifyWhitespace, borderRadiusBottomLeft)
 			}
+
+		default:
+			// Other border properties such as "border" and "border-inline-start"
+			// also set the sides of the border, so they can't be moved across
+			if isBorderKey(decl.KeyText) {
+				borderWidth.sides = [4]boxSide{}
+				borderStyle.sides = [4]boxSide{}
+				borderColor.sides = [4]boxSide{}
+			}
 		}
 	}
 
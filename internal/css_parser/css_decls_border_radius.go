@@ -69,8 +69,8 @@ func (borderRadius *borderRadiusTracker) mangleCorners(rules []css_ast.Rule, dec
 		unitSafety.includeUnitOf(t)
 	}
 
-	firstRadii, firstRadiiOk := expandTokenQuad(tokens[:beforeSplit], "")
-	lastRadii, lastRadiiOk := expandTokenQuad(tokens[afterSplit:], "")
+	firstRadii, firstRadiiOk := expandTokenQuad(tokens[:beforeSplit], nil)
+	lastRadii, lastRadiiOk := expandTokenQuad(tokens[afterSplit:], nil)
 
 	// Stop now if the pattern wasn't matched
 	if !firstRadiiOk || (beforeSplit < afterSplit && !lastRadiiOk) {
@@ -1,6 +1,8 @@
 package css_parser
 
 import (
+	"strings"
+
 	"github.com/evanw/esbuild/internal/css_ast"
 	"github.com/evanw/esbuild/internal/css_lexer"
 	"github.com/evanw/esbuild/internal/logger"
@@ -23,9 +25,55 @@ type boxSide struct {
 type boxTracker struct {
 	keyText   string
 	sides     [4]boxSide
-	allowAuto bool // If true, allow the "auto" keyword
 	important bool // True if all active rules were flagged as "!important"
 	key       css_ast.D
+
+	// This determines which tokens are allowed as the value of a side. If this
+	// is nil, only numeric tokens are allowed.
+	isAllowed func(css_ast.Token) bool
+}
+
+func isNumericOrAuto(t css_ast.Token) bool {
+	return t.Kind.IsNumeric() || (t.Kind == css_lexer.TIdent && t.Text == "auto")
+}
+
+// "border-width: thin 0 2px medium"
+func isBorderWidth(t css_ast.Token) bool {
+	if t.Kind == css_lexer.TIdent {
+		switch strings.ToLower(t.Text) {
+		case "thin", "medium", "thick":
+			return true
+		}
+		return false
+	}
+	return t.Kind.IsNumeric()
+}
+
+// "border-style: solid none dashed double"
+func isBorderStyle(t css_ast.Token) bool {
+	if t.Kind == css_lexer.TIdent {
+		switch strings.ToLower(t.Text) {
+		case "none", "hidden", "dotted", "dashed", "solid", "double", "groove", "ridge", "inset", "outset":
+			return true
+		}
+	}
+	return false
+}
+
+// "border-color: red #000 rgb(1, 2, 3) currentcolor"
+func isBorderColor(t css_ast.Token) bool {
+	if t.Kind == css_lexer.TIdent && strings.EqualFold(t.Text, "currentcolor") {
+		return true
+	}
+	_, ok := parseColor(t)
+	return ok
+}
+
+func (box *boxTracker) isAllowedSide(t css_ast.Token) bool {
+	if box.isAllowed == nil {
+		return t.Kind.IsNumeric()
+	}
+	return box.isAllowed(t)
 }
 
 type unitSafetyStatus uint8
@@ -98,15 +146,11 @@ func (box *boxTracker) mangleSides(rules []css_ast.Rule, decl *css_ast.RDeclarat
 		box.important = decl.Important
 	}
 
-	allowedIdent := ""
-	if box.allowAuto {
-		allowedIdent = "auto"
-	}
-	if quad, ok := expandTokenQuad(decl.Value, allowedIdent); ok {
+	if quad, ok := expandTokenQuad(decl.Value, box.isAllowed); ok {
 		// Use a single tracker for the whole rule
 		unitSafety := unitSafetyTracker{}
 		for _, t := range quad {
-			if !box.allowAuto || t.Kind.IsNumeric() {
+			if t.Kind.IsNumeric() {
 				unitSafety.includeUnitOf(t)
 			}
 		}
@@ -134,9 +178,9 @@ func (box *boxTracker) mangleSide(rules []css_ast.Rule, decl *css_ast.RDeclarati
 	}
 
 	if tokens := decl.Value; len(tokens) == 1 {
-		if t := tokens[0]; t.Kind.IsNumeric() || (t.Kind == css_lexer.TIdent && box.allowAuto && t.Text == "auto") {
+		if t := tokens[0]; box.isAllowedSide(t) {
 			unitSafety := unitSafetyTracker{}
-			if !box.allowAuto || t.Kind.IsNumeric() {
+			if t.Kind.IsNumeric() {
 				unitSafety.includeUnitOf(t)
 			}
 			if unitSafety.status == unitSafe && t.TurnLengthIntoNumberIfZero() {
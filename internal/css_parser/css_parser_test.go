@@ -1298,6 +1298,47 @@ func TestMarginAndPaddingAndInset(t *testing.T) {
 	expectPrintedLowerMangle(t, "a { top: 0; right: 0; bottom: 0; left: 0; }", "a {\n  top: 0;\n  right: 0;\n  bottom: 0;\n  left: 0;\n}\n")
 }
 
+func TestBorderWidthAndStyleAndColor(t *testing.T) {
+	for _, it := range []struct{ x, a, b string }{
+		{"width", "1px", "2px"},
+		{"width", "thin", "thick"},
+		{"style", "solid", "dashed"},
+		{"color", "red", "#00f"},
+	} {
+		x := "border-" + it.x
+		xTop := "border-top-" + it.x
+		xRight := "border-right-" + it.x
+		xBottom := "border-bottom-" + it.x
+		xLeft := "border-left-" + it.x
+		a, b := it.a, it.b
+
+		expectPrinted(t, "a { "+x+": "+a+" "+a+" "+a+" "+a+" }", "a {\n  "+x+": "+a+" "+a+" "+a+" "+a+";\n}\n")
+		expectPrintedMangle(t, "a { "+x+": "+a+" "+a+" "+a+" "+a+" }", "a {\n  "+x+": "+a+";\n}\n")
+		expectPrintedMangle(t, "a { "+x+": "+a+" "+b+" "+a+" "+b+" }", "a {\n  "+x+": "+a+" "+b+";\n}\n")
+		expectPrintedMangle(t, "a { "+x+": "+a+"; "+xLeft+": "+b+" }", "a {\n  "+x+": "+a+" "+a+" "+a+" "+b+";\n}\n")
+		expectPrintedMangle(t, "a { "+xTop+": "+a+"; "+xRight+": "+b+"; "+xBottom+": "+a+"; "+xLeft+": "+b+" }", "a {\n  "+x+": "+a+" "+b+";\n}\n")
+		expectPrintedMangle(t, "a { "+xTop+": "+a+"; "+xRight+": "+b+"; "+xBottom+": "+a+" }",
+			"a {\n  "+xTop+": "+a+";\n  "+xRight+": "+b+";\n  "+xBottom+": "+a+";\n}\n")
+
+		// Other border properties also set these, so nothing can be moved across them
+		expectPrintedMangle(t, "a { "+x+": "+a+"; border: 0; "+xLeft+": "+b+" }", "a {\n  "+x+": "+a+";\n  border: 0;\n  "+xLeft+": "+b+";\n}\n")
+		expectPrintedMangle(t, "a { "+x+": "+a+"; BORDER: 0; "+xLeft+": "+b+" }", "a {\n  "+x+": "+a+";\n  BORDER: 0;\n  "+xLeft+": "+b+";\n}\n")
+		expectPrintedMangle(t, "a { "+x+": "+a+"; border-inline-start-"+it.x+": "+b+"; "+xLeft+": "+b+" }",
+			"a {\n  "+x+": "+a+";\n  border-inline-start-"+it.x+": "+b+";\n  "+xLeft+": "+b+";\n}\n")
+
+		// Keywords that aren't values for a single side must not be merged
+		expectPrintedMangle(t, "a { "+x+": inherit; "+xLeft+": "+b+" }", "a {\n  "+x+": inherit;\n  "+xLeft+": "+b+";\n}\n")
+		expectPrintedMangle(t, "a { "+x+": "+a+"; "+xLeft+": var(--x) }", "a {\n  "+x+": "+a+";\n  "+xLeft+": var(--x);\n}\n")
+	}
+
+	expectPrintedMangle(t, "a { border-width: 1px 0px }", "a {\n  border-width: 1px 0;\n}\n")
+	expectPrintedMangle(t, "a { border-width: 1vw; border-left-width: 1vh }", "a {\n  border-width: 1vw;\n  border-left-width: 1vh;\n}\n")
+	expectPrintedMangle(t, "a { border-style: solid; border-left-style: thin }", "a {\n  border-style: solid;\n  border-left-style: thin;\n}\n")
+	expectPrintedMangle(t, "a { border-color: #ff0000 rgb(0, 0, 255) #FF0000 rgb(0 0 255) }", "a {\n  border-color: red #00f;\n}\n")
+	expectPrintedMangle(t, "a { border-color: red; border-left-color: currentcolor }", "a {\n  border-color: red red red currentcolor;\n}\n")
+	expectPrintedMangle(t, "a { border-width: 1px !important; border-left-width: 2px }", "a {\n  border-width: 1px !important;\n  border-left-width: 2px;\n}\n")
+}
+
 func TestBorderRadius(t *testing.T) {
 	expectPrinted(t, "a { border-top-left-radius: 0 0 }", "a {\n  border-top-left-radius: 0 0;\n}\n")
 	expectPrintedMangle(t, "a { border-top-left-radius: 0 0 }", "a {\n  border-top-left-radius: 0;\n}\n")
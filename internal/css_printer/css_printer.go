@@ -40,8 +40,18 @@ type Options struct {
 	ASCIIOnly           bool
 	SourceMap           config.SourceMap
 	AddSourceMappings   bool
+	SourceMapLinesOnly  bool
 	LegalComments       config.LegalComments
 	NeedsMetafile       bool
+	PreferSingleQuotes  bool
+
+	// This is the text used for one level of indentation. If empty, two spaces
+	// are used.
+	IndentUnit string
+
+	// If true, legal comments are also returned in "ExtractedLegalComments"
+	// when they are printed inline or omitted from the output entirely
+	RecordLegalComments bool
 }
 
 type PrintResult struct {
@@ -59,7 +69,7 @@ func Print(tree css_ast.AST, options Options) PrintResult {
 	p := printer{
 		options:       options,
 		importRecords: tree.ImportRecords,
-		builder:       sourcemap.MakeChunkBuilder(options.InputSourceMap, options.LineOffsetTables, options.ASCIIOnly),
+		builder:       sourcemap.MakeChunkBuilder(options.InputSourceMap, options.LineOffsetTables, options.ASCIIOnly, options.SourceMapLinesOnly),
 	}
 	for _, rule := range tree.Rules {
 		p.printRule(rule, 0, false)
@@ -94,22 +104,26 @@ func (p *printer) recordImportPathForMetafile(importRecordIndex uint32) {
 
 func (p *printer) printRule(rule css_ast.Rule, indent int32, omitTrailingSemicolon bool) {
 	if r, ok := rule.Data.(*css_ast.RComment); ok {
+		isExtracted := false
 		switch p.options.LegalComments {
-		case config.LegalCommentsNone:
-			return
-
 		case config.LegalCommentsEndOfFile,
 			config.LegalCommentsLinkedWithComment,
 			config.LegalCommentsExternalWithoutComment:
+			isExtracted = true
+		}
 
-			// Don't record the same legal comment more than once per file
+		// Don't record the same legal comment more than once per file
+		if isExtracted || p.options.RecordLegalComments {
 			if p.hasLegalComment == nil {
 				p.hasLegalComment = make(map[string]struct{})
-			} else if _, ok := p.hasLegalComment[r.Text]; ok {
-				return
 			}
-			p.hasLegalComment[r.Text] = struct{}{}
-			p.extractedLegalComments = append(p.extractedLegalComments, r.Text)
+			if _, ok := p.hasLegalComment[r.Text]; !ok {
+				p.hasLegalComment[r.Text] = struct{}{}
+				p.extractedLegalComments = append(p.extractedLegalComments, r.Text)
+			}
+		}
+
+		if isExtracted || p.options.LegalComments == config.LegalCommentsNone {
 			return
 		}
 	}
@@ -494,7 +508,7 @@ func (p *printer) print(text string) {
 	p.css = append(p.css, text...)
 }
 
-func bestQuoteCharForString(text string, forURL bool) byte {
+func bestQuoteCharForString(text string, forURL bool, preferSingleQuotes bool) byte {
 	forURLCost := 0
 	singleCost := 2
 	doubleCost := 2
@@ -525,7 +539,8 @@ func bestQuoteCharForString(text string, forURL bool) byte {
 	}
 
 	// Prefer double quotes to single quotes if there is no cost difference
-	if singleCost < doubleCost {
+	// unless single quotes were requested
+	if singleCost < doubleCost || (singleCost == doubleCost && preferSingleQuotes) {
 		return '\''
 	}
 
@@ -533,7 +548,7 @@ func bestQuoteCharForString(text string, forURL bool) byte {
 }
 
 func (p *printer) printQuoted(text string) {
-	p.printQuotedWithQuote(text, bestQuoteCharForString(text, false))
+	p.printQuotedWithQuote(text, bestQuoteCharForString(text, false, p.options.PreferSingleQuotes))
 }
 
 type escapeKind uint8
@@ -733,8 +748,12 @@ func (p *printer) printIdent(text string, mode identMode, whitespace trailingWhi
 }
 
 func (p *printer) printIndent(indent int32) {
+	unit := p.options.IndentUnit
+	if unit == "" {
+		unit = "  "
+	}
 	for i, n := 0, int(indent); i < n; i++ {
-		p.css = append(p.css, "  "...)
+		p.css = append(p.css, unit...)
 	}
 }
 
@@ -810,7 +829,7 @@ func (p *printer) printTokens(tokens []css_ast.Token, opts printTokensOpts) bool
 		case css_lexer.TURL:
 			text := p.importRecords[t.ImportRecordIndex].Path.Text
 			p.print("url(")
-			p.printQuotedWithQuote(text, bestQuoteCharForString(text, true))
+			p.printQuotedWithQuote(text, bestQuoteCharForString(text, true, p.options.PreferSingleQuotes))
 			p.print(")")
 			p.recordImportPathForMetafile(t.ImportRecordIndex)
 
@@ -405,3 +405,17 @@ func TestASCII(t *testing.T) {
 	// This character should always be escaped
 	expectPrinted(t, ".\\FEFF:after { content: '\uFEFF' }", ".\\feff:after {\n  content: \"\\feff\";\n}\n")
 }
+
+func TestIndentUnit(t *testing.T) {
+	tabs := Options{IndentUnit: "\t"}
+	expectPrintedCommon(t, "tabs", "a { b: c; @media (x) { d: e } }", "a {\n\tb: c;\n\t@media (x) {\n\t\td: e;\n\t}\n}\n", tabs)
+	expectPrintedCommon(t, "four spaces", "a { b: c }", "a {\n    b: c;\n}\n", Options{IndentUnit: "    "})
+}
+
+func TestPreferSingleQuotes(t *testing.T) {
+	single := Options{PreferSingleQuotes: true}
+	expectPrintedCommon(t, "content [single]", "a { content: \"b\" }", "a {\n  content: 'b';\n}\n", single)
+	expectPrintedCommon(t, "quote [single]", "a { content: \"b'c\" }", "a {\n  content: \"b'c\";\n}\n", single)
+	expectPrintedCommon(t, "url [single]", "a { b: url(\"c(d)\") }", "a {\n  b: url('c(d)');\n}\n", single)
+	expectPrintedCommon(t, "import [single]", "@import \"a.css\";", "@import 'a.css';\n", single)
+}
@@ -108,6 +108,9 @@ type LinkerGraph struct {
 	// This is for cross-module inlining of detected inlinable constants
 	ConstValues map[js_ast.Ref]js_ast.ConstValue
 
+	// This is for cross-module inlining of calls to functions that return constants
+	ConstReturnValues map[js_ast.Ref]js_ast.ConstValue
+
 	// We should avoid traversing all files in the bundle, because the linker
 	// should be able to run a linking operation on a large bundle where only
 	// a few files are needed (e.g. an incremental compilation scenario). This
@@ -267,6 +270,7 @@ func CloneLinkerGraph(
 	// Do a final quick pass over all files
 	var tsEnums map[js_ast.Ref]map[string]js_ast.TSEnumValue
 	var constValues map[js_ast.Ref]js_ast.ConstValue
+	var constReturnValues map[js_ast.Ref]js_ast.ConstValue
 	bitCount := uint(len(entryPoints))
 	for _, sourceIndex := range reachableFiles {
 		file := &files[sourceIndex]
@@ -295,12 +299,23 @@ func CloneLinkerGraph(
 				constValues[ref] = value
 			}
 		}
+
+		// And merge the return values of functions that return a constant
+		if repr, ok := file.InputFile.Repr.(*JSRepr); ok && repr.AST.ConstReturnValues != nil {
+			if constReturnValues == nil {
+				constReturnValues = make(map[js_ast.Ref]js_ast.ConstValue)
+			}
+			for ref, value := range repr.AST.ConstReturnValues {
+				constReturnValues[ref] = value
+			}
+		}
 	}
 
 	return LinkerGraph{
 		Symbols:             symbols,
 		TSEnums:             tsEnums,
 		ConstValues:         constValues,
+		ConstReturnValues:   constReturnValues,
 		entryPoints:         entryPoints,
 		Files:               files,
 		ReachableFiles:      reachableFiles,
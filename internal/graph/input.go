@@ -11,6 +11,7 @@ import (
 	"github.com/evanw/esbuild/internal/ast"
 	"github.com/evanw/esbuild/internal/config"
 	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/html_ast"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/resolver"
@@ -23,7 +24,8 @@ type InputFile struct {
 
 	// If this file ends up being used in the bundle, these are additional files
 	// that must be written to the output directory. It's used by the "file" and
-	// "copy" loaders.
+	// "copy" loaders and by web workers. The first file is the one that paths
+	// containing "UniqueKeyForAdditionalFile" are substituted with.
 	AdditionalFiles            []OutputFile
 	UniqueKeyForAdditionalFile string
 
@@ -41,6 +43,11 @@ type OutputFile struct {
 	AbsPath      string
 	Contents     []byte
 	IsExecutable bool
+
+	// If this is the main output file for an entry point chunk, this is the
+	// source index of that entry point. This is used to substitute references
+	// in HTML entry points with the paths of the corresponding output files.
+	EntryPointSourceIndex ast.Index32
 }
 
 type SideEffects struct {
@@ -118,8 +125,23 @@ func (repr *CSSRepr) ImportRecords() *[]ast.ImportRecord {
 type CopyRepr struct {
 	// The URL that replaces the contents of any import record paths for this file
 	URLForCode string
+
+	// If present, this file is a stub for a web worker. The worker is bundled
+	// separately during the compile phase and the result is either written to
+	// the output directory (using "URLForCode" as the placeholder path) or, if
+	// "IsInlineWorker" is true, embedded directly as the value of "URLForCode".
+	WorkerSourceIndex ast.Index32
+	IsInlineWorker    bool
 }
 
 func (repr *CopyRepr) ImportRecords() *[]ast.ImportRecord {
 	return nil
 }
+
+type HTMLRepr struct {
+	AST html_ast.AST
+}
+
+func (repr *HTMLRepr) ImportRecords() *[]ast.ImportRecord {
+	return &repr.AST.ImportRecords
+}
@@ -106,6 +106,11 @@ type JSReprMeta struct {
 	// it directly.
 	TopLevelSymbolToPartsOverlay map[js_ast.Ref][]uint32
 
+	// If this is a JSON file and its default export is only ever used to read
+	// properties with static names, this holds the names of those properties.
+	// Other properties are omitted from the default export object.
+	UsedLazyDefaultExportPropertiesOrNil map[string]bool
+
 	// If this is an entry point, this array holds a reference to one free
 	// temporary symbol for each entry in "sortedAndFilteredExportAliases".
 	// These may be needed to store copies of CommonJS re-exports in ESM.
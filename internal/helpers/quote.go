@@ -25,6 +25,18 @@ func QuoteForJSON(text string, asciiOnly bool) []byte {
 	return internalQuote(text, asciiOnly, '"')
 }
 
+// This returns a single-line JSON array such as ["a", "b"]
+func QuoteStringArrayForJSON(texts []string, asciiOnly bool) []byte {
+	sb := []byte{'['}
+	for i, text := range texts {
+		if i > 0 {
+			sb = append(sb, ',', ' ')
+		}
+		sb = append(sb, QuoteForJSON(text, asciiOnly)...)
+	}
+	return append(sb, ']')
+}
+
 func internalQuote(text string, asciiOnly bool, quoteChar byte) []byte {
 	// Estimate the required length
 	lenEstimate := 2
@@ -0,0 +1,55 @@
+package html_ast
+
+import (
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// HTML files are never printed from an AST. Instead the original source text
+// is kept as-is and only the attribute values that reference other files are
+// substituted when the final output is generated. So this AST only records
+// the locations of these references along with a few other interesting
+// locations in the original file.
+
+type AST struct {
+	ImportRecords []ast.ImportRecord
+	References    []Reference
+
+	// This is the start of the "</head>" tag if there is one, or -1 otherwise.
+	// Stylesheets that are generated for JavaScript entry points are inserted
+	// here so that they are loaded before the page is rendered.
+	HeadEnd int32
+}
+
+type ReferenceKind uint8
+
+const (
+	// A "<script src>" tag
+	ReferenceScript ReferenceKind = iota
+
+	// A "<link rel=stylesheet href>" tag
+	ReferenceStylesheet
+)
+
+type Reference struct {
+	// This is the range of the attribute value in the original source text,
+	// including the surrounding quotes if the value was quoted
+	ValueRange logger.Range
+
+	// This is the range of the whole attribute including the name and any
+	// whitespace before it. It's removed when the reference is inlined.
+	AttributeRange logger.Range
+
+	// This is the start of the tag containing this reference. It's used as a
+	// fallback location for inserting additional tags when there is no
+	// "</head>" tag.
+	TagStart int32
+
+	// These are the ends of the opening tag and of the whole element (i.e. the
+	// end of the closing tag for "<script>" and the opening tag for "<link>")
+	TagEnd     int32
+	ElementEnd int32
+
+	ImportRecordIndex uint32
+	Kind              ReferenceKind
+}
@@ -0,0 +1,280 @@
+package html_parser
+
+import (
+	"strings"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/html_ast"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This is not a full HTML parser. It only understands enough of the syntax to
+// find the "<script>" and "<link>" tags that reference other files without
+// getting confused by comments, attribute values, or the contents of raw text
+// elements such as "<script>" and "<style>". Everything else is passed through
+// verbatim when the HTML file is written to the output directory.
+
+type parser struct {
+	log     logger.Log
+	source  logger.Source
+	tracker logger.LineColumnTracker
+	ast     html_ast.AST
+	text    string
+	i       int
+}
+
+type attribute struct {
+	name       string
+	value      string
+	valueRange logger.Range
+	fullRange  logger.Range
+	hasValue   bool
+}
+
+func Parse(log logger.Log, source logger.Source) html_ast.AST {
+	p := parser{
+		log:     log,
+		source:  source,
+		tracker: logger.MakeLineColumnTracker(&source),
+		ast:     html_ast.AST{HeadEnd: -1},
+		text:    source.Contents,
+	}
+	p.parse()
+	return p.ast
+}
+
+func (p *parser) parse() {
+	for {
+		next := strings.IndexByte(p.text[p.i:], '<')
+		if next == -1 {
+			return
+		}
+		p.i += next
+		start := p.i
+		rest := p.text[p.i:]
+
+		// Skip over comments
+		if strings.HasPrefix(rest, "<!--") {
+			if end := strings.Index(rest[4:], "-->"); end != -1 {
+				p.i += 4 + end + 3
+			} else {
+				p.i = len(p.text)
+			}
+			continue
+		}
+
+		// Skip over doctypes and processing instructions
+		if strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?") {
+			p.skipPast('>')
+			continue
+		}
+
+		// Handle closing tags
+		if strings.HasPrefix(rest, "</") {
+			p.i += 2
+			name := p.parseName()
+			if p.ast.HeadEnd == -1 && strings.EqualFold(name, "head") {
+				p.ast.HeadEnd = int32(start)
+			}
+			p.skipPast('>')
+			continue
+		}
+
+		// Handle opening tags
+		p.i++
+		name := strings.ToLower(p.parseName())
+		if name == "" {
+			continue
+		}
+		attrs := p.parseAttributes()
+		tagEnd := p.i
+
+		switch name {
+		case "script":
+			p.skipRawText("script")
+			elementEnd := p.i
+			if elementEnd < len(p.text) {
+				elementEnd += strings.IndexByte(p.text[elementEnd:], '>') + 1
+				if elementEnd == p.i {
+					elementEnd = len(p.text)
+				}
+			}
+			if src, ok := findAttribute(attrs, "src"); ok {
+				p.addReference(html_ast.ReferenceScript, src, start, tagEnd, elementEnd)
+			}
+
+		case "style":
+			p.skipRawText("style")
+
+		case "link":
+			if href, ok := findAttribute(attrs, "href"); ok {
+				if rel, ok := findAttribute(attrs, "rel"); ok && hasToken(rel.value, "stylesheet") {
+					p.addReference(html_ast.ReferenceStylesheet, href, start, tagEnd, tagEnd)
+				}
+			}
+		}
+	}
+}
+
+func (p *parser) addReference(kind html_ast.ReferenceKind, attr attribute, tagStart int, tagEnd int, elementEnd int) {
+	path := strings.TrimSpace(attr.value)
+
+	// Leave references to other websites and inline data alone. Those aren't
+	// part of the bundle.
+	if path == "" || strings.HasPrefix(path, "//") || hasURLScheme(path) {
+		return
+	}
+
+	// Attribute values are URLs, so treat paths as relative to the HTML file
+	// even if they don't start with "./" or "../". Paths that start with "/"
+	// are treated as relative to the directory containing the HTML file too,
+	// since that's usually what is being served as the root directory.
+	if strings.HasPrefix(path, "/") {
+		path = "." + path
+	} else if !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "../") {
+		path = "./" + path
+	}
+
+	p.ast.References = append(p.ast.References, html_ast.Reference{
+		Kind:              kind,
+		ValueRange:        attr.valueRange,
+		AttributeRange:    attr.fullRange,
+		TagStart:          int32(tagStart),
+		TagEnd:            int32(tagEnd),
+		ElementEnd:        int32(elementEnd),
+		ImportRecordIndex: uint32(len(p.ast.ImportRecords)),
+	})
+	p.ast.ImportRecords = append(p.ast.ImportRecords, ast.ImportRecord{
+		Kind:  ast.ImportEntryPoint,
+		Path:  logger.Path{Text: path},
+		Range: attr.valueRange,
+	})
+}
+
+func (p *parser) parseName() string {
+	start := p.i
+	for p.i < len(p.text) {
+		c := p.text[p.i]
+		if c == '>' || c == '/' || c == '=' || isWhitespace(c) {
+			break
+		}
+		p.i++
+	}
+	return p.text[start:p.i]
+}
+
+func (p *parser) parseAttributes() (attrs []attribute) {
+	for {
+		start := p.i
+		p.skipWhitespace()
+		if p.i >= len(p.text) {
+			return
+		}
+		switch p.text[p.i] {
+		case '>':
+			p.i++
+			return
+		case '/', '=':
+			p.i++
+			continue
+		}
+
+		attr := attribute{name: strings.ToLower(p.parseName())}
+		p.skipWhitespace()
+		if p.i < len(p.text) && p.text[p.i] == '=' {
+			p.i++
+			p.skipWhitespace()
+			attr.hasValue = true
+			valueStart := p.i
+			if p.i < len(p.text) && (p.text[p.i] == '"' || p.text[p.i] == '\'') {
+				quote := p.text[p.i]
+				if end := strings.IndexByte(p.text[p.i+1:], quote); end != -1 {
+					attr.value = p.text[p.i+1 : p.i+1+end]
+					p.i += end + 2
+				} else {
+					p.log.AddError(&p.tracker, logger.Range{Loc: logger.Loc{Start: int32(p.i)}, Len: 1}, "Unterminated attribute value")
+					attr.value = p.text[p.i+1:]
+					p.i = len(p.text)
+				}
+			} else {
+				for p.i < len(p.text) && p.text[p.i] != '>' && !isWhitespace(p.text[p.i]) {
+					p.i++
+				}
+				attr.value = p.text[valueStart:p.i]
+			}
+			attr.valueRange = logger.Range{Loc: logger.Loc{Start: int32(valueStart)}, Len: int32(p.i - valueStart)}
+		}
+		attr.fullRange = logger.Range{Loc: logger.Loc{Start: int32(start)}, Len: int32(p.i - start)}
+		attrs = append(attrs, attr)
+	}
+}
+
+// The contents of raw text elements are not HTML, so they must be skipped
+// entirely. Otherwise something like "a<b" in a script would be a problem.
+func (p *parser) skipRawText(name string) {
+	for {
+		next := strings.Index(p.text[p.i:], "</")
+		if next == -1 {
+			p.i = len(p.text)
+			return
+		}
+		p.i += next
+		end := p.i + 2 + len(name)
+		if end <= len(p.text) && strings.EqualFold(p.text[p.i+2:end], name) &&
+			(end == len(p.text) || p.text[end] == '>' || p.text[end] == '/' || isWhitespace(p.text[end])) {
+			return
+		}
+		p.i += 2
+	}
+}
+
+func (p *parser) skipPast(c byte) {
+	if end := strings.IndexByte(p.text[p.i:], c); end != -1 {
+		p.i += end + 1
+	} else {
+		p.i = len(p.text)
+	}
+}
+
+func (p *parser) skipWhitespace() {
+	for p.i < len(p.text) && isWhitespace(p.text[p.i]) {
+		p.i++
+	}
+}
+
+func findAttribute(attrs []attribute, name string) (attribute, bool) {
+	for _, attr := range attrs {
+		if attr.name == name && attr.hasValue {
+			return attr, true
+		}
+	}
+	return attribute{}, false
+}
+
+func hasToken(value string, token string) bool {
+	for _, part := range strings.Fields(value) {
+		if strings.EqualFold(part, token) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasURLScheme(path string) bool {
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == ':':
+			return i > 0
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case i > 0 && ((c >= '0' && c <= '9') || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
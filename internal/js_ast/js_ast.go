@@ -257,6 +257,10 @@ const (
 	PropertySpread
 	PropertyDeclare
 	PropertyClassStaticBlock
+
+	// This is a class field with the "accessor" keyword. It's stored like a
+	// field (using "InitializerOrNil") even though it acts like a getter/setter.
+	PropertyAutoAccessor
 )
 
 type ClassStaticBlock struct {
@@ -297,7 +301,11 @@ type Property struct {
 	//
 	InitializerOrNil Expr
 
-	TSDecorators []Expr
+	Decorators []Expr
+
+	// This is the type of a class field for TypeScript's "emitDecoratorMetadata"
+	// setting. It's only present if that setting is enabled.
+	TSMetadataTypeOrNil Expr
 
 	Loc             logger.Loc
 	CloseBracketLoc logger.Loc
@@ -319,7 +327,12 @@ type PropertyBinding struct {
 type Arg struct {
 	Binding      Binding
 	DefaultOrNil Expr
-	TSDecorators []Expr
+	Decorators   []Expr
+
+	// This is the type of a method argument for TypeScript's
+	// "emitDecoratorMetadata" setting. It's only present if that setting is
+	// enabled.
+	TSMetadataTypeOrNil Expr
 
 	// "constructor(public x: boolean) {}"
 	IsTypeScriptCtorField bool
@@ -332,6 +345,11 @@ type Fn struct {
 	ArgumentsRef Ref
 	OpenParenLoc logger.Loc
 
+	// This is the return type of a method for TypeScript's
+	// "emitDecoratorMetadata" setting. It's only present if that setting is
+	// enabled.
+	TSMetadataReturnTypeOrNil Expr
+
 	IsAsync     bool
 	IsGenerator bool
 	HasRestArg  bool
@@ -347,7 +365,7 @@ type FnBody struct {
 }
 
 type Class struct {
-	TSDecorators  []Expr
+	Decorators    []Expr
 	Name          *LocRef
 	ExtendsOrNil  Expr
 	Properties    []Property
@@ -439,6 +457,7 @@ func (*EIf) isExpr()                   {}
 func (*ERequireString) isExpr()        {}
 func (*ERequireResolveString) isExpr() {}
 func (*EImportString) isExpr()         {}
+func (*EImportPath) isExpr()           {}
 func (*EImportCall) isExpr()           {}
 
 type EArray struct {
@@ -793,6 +812,13 @@ type EImportString struct {
 	CloseParenLoc     logger.Loc
 }
 
+// This is a string literal containing the path of an import record. It's used
+// for expressions such as "new URL('./worker.js', import.meta.url)" where the
+// path must be rewritten to point to the corresponding output file.
+type EImportPath struct {
+	ImportRecordIndex uint32
+}
+
 type EImportCall struct {
 	Expr          Expr
 	OptionsOrNil  Expr
@@ -1067,8 +1093,14 @@ const (
 	LocalVar LocalKind = iota
 	LocalLet
 	LocalConst
+	LocalUsing
+	LocalAwaitUsing
 )
 
+func (kind LocalKind) IsUsing() bool {
+	return kind >= LocalUsing
+}
+
 type SLocal struct {
 	Decls    []Decl
 	Kind     LocalKind
@@ -1369,6 +1401,10 @@ const (
 	// This means the symbol is a normal function that takes a single argument
 	// and returns that argument.
 	IsIdentityFunction
+
+	// This means the symbol is a normal function whose body returns a constant
+	// and does nothing else. The constant is stored in "ConstReturnValues".
+	IsConstReturnFunction
 )
 
 func (flags SymbolFlags) Has(flag SymbolFlags) bool {
@@ -1822,9 +1858,9 @@ type AST struct {
 	// This is internal-only data used for the implementation of Yarn PnP
 	ManifestForYarnPnP Expr
 
-	Hashbang  string
-	Directive string
-	URLForCSS string
+	Hashbang   string
+	Directives []string
+	URLForCSS  string
 
 	// Note: If you're in the linker, do not use this map directly. This map is
 	// filled in by the parser and is considered immutable. For performance reasons,
@@ -1841,10 +1877,20 @@ type AST struct {
 	// to enable cross-module inlining of these constants.
 	ConstValues map[Ref]ConstValue
 
+	// This contains the return values of all functions that were detected to
+	// always return the same constant. It exists to enable cross-module
+	// inlining of calls to these functions.
+	ConstReturnValues map[Ref]ConstValue
+
 	// Properties in here are represented as symbols instead of strings, which
 	// allows them to be renamed to smaller names.
 	MangledProps map[string]Ref
 
+	// This counts how many times each mangled property is used as the name of
+	// a class method. A method can be removed if all uses of the property
+	// across the whole bundle are method definitions.
+	MangledPropMethodCounts map[Ref]uint32
+
 	// Properties in here are existing non-mangled properties in the source code
 	// and must not be used when generating mangled names to avoid a collision.
 	ReservedProps map[string]bool
@@ -1876,6 +1922,12 @@ type AST struct {
 	NestedScopeSlotCounts SlotCounts
 	HasLazyExport         bool
 
+	// This is true for TypeScript files without any import or export syntax.
+	// TypeScript considers these files to be global scripts instead of modules,
+	// so top-level namespaces with the same name in different script files are
+	// merged together when bundling.
+	IsTypeScriptScript bool
+
 	// This is a list of CommonJS features. When a file uses CommonJS features,
 	// it's not a candidate for "flat bundling" and must be wrapped in its own
 	// closure. Note that this also includes top-level "return" but these aren't
@@ -2079,6 +2131,12 @@ type NamedImport struct {
 	// It's useful to flag exported imports because if they are in a TypeScript
 	// file, we can't tell if they are a type or a value.
 	IsExported bool
+
+	// This is only set for default imports that are never used for anything
+	// other than reading properties with static names (e.g. "data.foo"). It
+	// holds the names of those properties. This lets the linker omit unused
+	// properties from the default export of a JSON file.
+	PropertyReadsOrNil map[string]bool
 }
 
 type NamedExport struct {
@@ -2111,6 +2169,11 @@ type Part struct {
 	// function calls within this part.
 	SymbolCallUses map[Ref]SymbolCallUse
 
+	// An estimate of the number of times each string literal is used within
+	// this part. This is only computed when minifying and is used to decide
+	// which repeated string literals to hoist into shared variables.
+	StringLiteralUses map[string]uint32
+
 	// This tracks property accesses off of imported symbols. We don't know
 	// during parsing if an imported symbol is going to be an inlined enum
 	// value or not. This is only known during linking. So we defer adding
@@ -1367,7 +1367,612 @@ func FoldStringAddition(left Expr, right Expr) Expr {
 	return Expr{}
 }
 
+// "Number('3')" => "3"
+// "parseInt('10px')" => "10"
+//
+// This folds calls to certain global functions with constant arguments. The
+// caller is responsible for checking that the name refers to the global.
+//
+// This function intentionally avoids mutating the input AST so it can be
+// called after the AST has been frozen (i.e. after parsing ends).
+func FoldKnownGlobalCall(loc logger.Loc, name string, args []Expr) Expr {
+	switch name {
+	case "Number":
+		if len(args) == 0 {
+			return Expr{Loc: loc, Data: &ENumber{Value: 0}}
+		}
+		if len(args) == 1 {
+			if str, ok := args[0].Data.(*EString); ok {
+				if value, ok := stringToNumber(str.Value); ok {
+					return Expr{Loc: loc, Data: &ENumber{Value: value}}
+				}
+			} else if value, ok := ToNumberWithoutSideEffects(args[0].Data); ok {
+				return Expr{Loc: loc, Data: &ENumber{Value: value}}
+			}
+		}
+
+	case "String":
+		if len(args) == 0 {
+			return Expr{Loc: loc, Data: &EString{}}
+		}
+		if len(args) == 1 {
+			if text, ok := toStringWithoutSideEffects(args[0].Data); ok {
+				return Expr{Loc: loc, Data: &EString{Value: helpers.StringToUTF16(text)}}
+			}
+			if str, ok := args[0].Data.(*EString); ok {
+				return Expr{Loc: loc, Data: &EString{Value: str.Value}}
+			}
+		}
+
+	case "Boolean":
+		if len(args) == 0 {
+			return Expr{Loc: loc, Data: &EBoolean{Value: false}}
+		}
+		if len(args) == 1 {
+			if boolean, sideEffects, ok := ToBooleanWithSideEffects(args[0].Data); ok && sideEffects == NoSideEffects {
+				return Expr{Loc: loc, Data: &EBoolean{Value: boolean}}
+			}
+		}
+
+	case "parseInt":
+		if len(args) == 1 || len(args) == 2 {
+			if str, ok := args[0].Data.(*EString); ok {
+				radix := 0.0
+				if len(args) == 2 {
+					if value, ok := extractNumericValue(args[1].Data); ok {
+						radix = value
+					} else if _, ok := args[1].Data.(*EUndefined); !ok {
+						break
+					}
+				}
+				if value, ok := parseIntFromString(str.Value, radix); ok {
+					return Expr{Loc: loc, Data: &ENumber{Value: value}}
+				}
+			}
+		}
+
+	case "parseFloat":
+		if len(args) == 1 {
+			if str, ok := args[0].Data.(*EString); ok {
+				if value, ok := parseFloatFromString(str.Value); ok {
+					return Expr{Loc: loc, Data: &ENumber{Value: value}}
+				}
+			}
+		}
+	}
+
+	return Expr{}
+}
+
+// "String.fromCharCode(65)" => "'A'"
+// "Math.pow(2, 8)" => "256"
+// "Number.isNaN(1)" => "false"
+//
+// This folds calls to certain methods on well-known globals with constant
+// arguments. The caller is responsible for checking that the object name
+// refers to the global. Only operations that are guaranteed to give the same
+// result in every JavaScript engine are folded, so functions such as
+// "Math.sin" whose precision is implementation-defined are left alone.
+//
+// This function intentionally avoids mutating the input AST so it can be
+// called after the AST has been frozen (i.e. after parsing ends).
+func FoldKnownGlobalMethodCall(loc logger.Loc, object string, name string, args []Expr) Expr {
+	switch object {
+	case "String":
+		if name == "fromCharCode" {
+			text := make([]uint16, 0, len(args))
+			for _, arg := range args {
+				value, ok := ToNumberWithoutSideEffects(arg.Data)
+				if !ok {
+					return Expr{}
+				}
+				if math.IsNaN(value) || math.IsInf(value, 0) {
+					value = 0
+				}
+				text = append(text, uint16(ToUint32(value)))
+			}
+			return Expr{Loc: loc, Data: &EString{Value: text}}
+		}
+
+	case "Math":
+		switch name {
+		case "abs", "ceil", "floor", "round", "sign", "sqrt", "trunc":
+			if len(args) == 1 {
+				if value, ok := ToNumberWithoutSideEffects(args[0].Data); ok {
+					return Expr{Loc: loc, Data: &ENumber{Value: foldMathFunction(name, value)}}
+				}
+			}
+
+		case "min", "max":
+			result := math.Inf(1)
+			if name == "max" {
+				result = math.Inf(-1)
+			}
+			for _, arg := range args {
+				value, ok := ToNumberWithoutSideEffects(arg.Data)
+				if !ok {
+					return Expr{}
+				}
+				if math.IsNaN(value) || math.IsNaN(result) {
+					result = math.NaN()
+				} else if name == "min" {
+					result = math.Min(result, value)
+				} else {
+					result = math.Max(result, value)
+				}
+			}
+			return Expr{Loc: loc, Data: &ENumber{Value: result}}
+
+		case "pow":
+			// Only fold when the result is an exactly-representable integer since
+			// engines are allowed to differ in the precision of other results
+			if len(args) == 2 {
+				if base, exponent, ok := extractNumericValues(args[0], args[1]); ok &&
+					base == math.Trunc(base) && exponent == math.Trunc(exponent) && exponent >= 0 {
+					if value := math.Pow(base, exponent); math.Abs(value) <= maxSafeInteger {
+						return Expr{Loc: loc, Data: &ENumber{Value: value}}
+					}
+				}
+			}
+		}
+
+	case "Number":
+		switch name {
+		case "isNaN", "isFinite", "isInteger", "isSafeInteger":
+			if len(args) == 0 {
+				return Expr{Loc: loc, Data: &EBoolean{Value: false}}
+			}
+			if len(args) == 1 {
+				if value, ok := extractNumericValue(args[0].Data); ok {
+					var result bool
+					switch name {
+					case "isNaN":
+						result = math.IsNaN(value)
+					case "isFinite":
+						result = !math.IsNaN(value) && !math.IsInf(value, 0)
+					case "isInteger":
+						result = !math.IsInf(value, 0) && value == math.Trunc(value)
+					case "isSafeInteger":
+						result = value == math.Trunc(value) && math.Abs(value) <= maxSafeInteger
+					}
+					return Expr{Loc: loc, Data: &EBoolean{Value: result}}
+				}
+
+				// None of these functions convert their argument to a number
+				if IsPrimitiveLiteral(args[0].Data) {
+					return Expr{Loc: loc, Data: &EBoolean{Value: false}}
+				}
+			}
+
+		case "parseInt", "parseFloat":
+			// "Number.parseInt" is the same function as the global "parseInt"
+			return FoldKnownGlobalCall(loc, name, args)
+		}
+	}
+
+	return Expr{}
+}
+
+const maxSafeInteger = 9007199254740991
+
+func foldMathFunction(name string, value float64) float64 {
+	switch name {
+	case "abs":
+		return math.Abs(value)
+
+	case "ceil":
+		return math.Ceil(value)
+
+	case "floor":
+		return math.Floor(value)
+
+	case "round":
+		// JavaScript rounds ties toward positive infinity and preserves "-0"
+		if math.IsNaN(value) || math.IsInf(value, 0) || value == 0 {
+			return value
+		}
+		result := math.Floor(value)
+		if value-result >= 0.5 {
+			result++
+		}
+		if result == 0 && value < 0 {
+			return math.Copysign(0, -1)
+		}
+		return result
+
+	case "sign":
+		if math.IsNaN(value) || value == 0 {
+			return value
+		}
+		if value < 0 {
+			return -1
+		}
+		return 1
+
+	case "sqrt":
+		return math.Sqrt(value)
+
+	case "trunc":
+		return math.Trunc(value)
+	}
+	panic("Internal error")
+}
+
+// "'abc'.charCodeAt(1)" => "98"
+// "'ABC'.toLowerCase()" => "'abc'"
+//
+// This folds calls to certain string methods with constant arguments.
+//
+// This function intentionally avoids mutating the input AST so it can be
+// called after the AST has been frozen (i.e. after parsing ends).
+func FoldStringMethodCall(loc logger.Loc, value []uint16, name string, args []Expr) Expr {
+	switch name {
+	case "charAt", "charCodeAt":
+		if len(args) <= 1 {
+			index := 0.0
+			if len(args) == 1 {
+				value, ok := ToNumberWithoutSideEffects(args[0].Data)
+				if !ok {
+					break
+				}
+				index = toIntegerOrInfinity(value)
+			}
+			inRange := index >= 0 && index < float64(len(value))
+			if name == "charAt" {
+				if !inRange {
+					return Expr{Loc: loc, Data: &EString{}}
+				}
+				return Expr{Loc: loc, Data: &EString{Value: []uint16{value[int(index)]}}}
+			}
+			if !inRange {
+				return Expr{Loc: loc, Data: &ENumber{Value: math.NaN()}}
+			}
+			return Expr{Loc: loc, Data: &ENumber{Value: float64(value[int(index)])}}
+		}
+
+	case "indexOf", "includes", "startsWith", "endsWith":
+		if len(args) == 1 {
+			if search, ok := args[0].Data.(*EString); ok {
+				index := indexOfUTF16(value, search.Value)
+				switch name {
+				case "indexOf":
+					return Expr{Loc: loc, Data: &ENumber{Value: float64(index)}}
+				case "includes":
+					return Expr{Loc: loc, Data: &EBoolean{Value: index != -1}}
+				case "startsWith":
+					return Expr{Loc: loc, Data: &EBoolean{Value: len(search.Value) <= len(value) &&
+						helpers.UTF16EqualsUTF16(value[:len(search.Value)], search.Value)}}
+				case "endsWith":
+					return Expr{Loc: loc, Data: &EBoolean{Value: len(search.Value) <= len(value) &&
+						helpers.UTF16EqualsUTF16(value[len(value)-len(search.Value):], search.Value)}}
+				}
+			}
+		}
+
+	case "toLowerCase", "toUpperCase":
+		// Only do this for ASCII strings to avoid having to implement Unicode case mapping
+		if len(args) == 0 {
+			result := make([]uint16, len(value))
+			for i, c := range value {
+				if c >= 0x80 {
+					return Expr{}
+				}
+				if name == "toLowerCase" && c >= 'A' && c <= 'Z' {
+					c += 'a' - 'A'
+				} else if name == "toUpperCase" && c >= 'a' && c <= 'z' {
+					c -= 'a' - 'A'
+				}
+				result[i] = c
+			}
+			return Expr{Loc: loc, Data: &EString{Value: result}}
+		}
+
+	case "slice":
+		if len(args) <= 2 {
+			n := float64(len(value))
+			start, end := 0.0, n
+			for i, arg := range args {
+				number, ok := ToNumberWithoutSideEffects(arg.Data)
+				if !ok {
+					return Expr{}
+				}
+				if _, ok := arg.Data.(*EUndefined); ok && i == 1 {
+					continue
+				}
+				number = toIntegerOrInfinity(number)
+				if number < 0 {
+					number = math.Max(n+number, 0)
+				} else {
+					number = math.Min(number, n)
+				}
+				if i == 0 {
+					start = number
+				} else {
+					end = number
+				}
+			}
+			if start >= end {
+				return Expr{Loc: loc, Data: &EString{}}
+			}
+			return Expr{Loc: loc, Data: &EString{Value: value[int(start):int(end)]}}
+		}
+	}
+
+	return Expr{}
+}
+
+func indexOfUTF16(text []uint16, search []uint16) int {
+	for i := 0; i+len(search) <= len(text); i++ {
+		if helpers.UTF16EqualsUTF16(text[i:i+len(search)], search) {
+			return i
+		}
+	}
+	return -1
+}
+
+// https://tc39.es/ecma262/#sec-tointegerorinfinity
+func toIntegerOrInfinity(value float64) float64 {
+	if math.IsNaN(value) {
+		return 0
+	}
+	return math.Trunc(value)
+}
+
+// This implements the "Number::toString" operation for the cases where Go's
+// formatting matches JavaScript's formatting.
+func toStringWithoutSideEffects(data E) (string, bool) {
+	switch e := data.(type) {
+	case *ENull:
+		return "null", true
+
+	case *EUndefined:
+		return "undefined", true
+
+	case *EBoolean:
+		if e.Value {
+			return "true", true
+		}
+		return "false", true
+
+	case *ENumber:
+		value := e.Value
+		if math.IsNaN(value) {
+			return "NaN", true
+		}
+		if math.IsInf(value, 1) {
+			return "Infinity", true
+		}
+		if math.IsInf(value, -1) {
+			return "-Infinity", true
+		}
+		if value == 0 {
+			return "0", true
+		}
+
+		// JavaScript uses exponential notation outside of this range
+		if abs := math.Abs(value); abs >= 1e-6 && abs < 1e21 {
+			return strconv.FormatFloat(value, 'f', -1, 64), true
+		}
+	}
+
+	return "", false
+}
+
+// https://tc39.es/ecma262/#sec-white-space
+// https://tc39.es/ecma262/#sec-line-terminators
+func isWhitespaceOrLineTerminator(c uint16) bool {
+	switch c {
+	case '\t', '\n', '\v', '\f', '\r', ' ', 0xA0, 0x1680, 0x2028, 0x2029, 0x202F, 0x205F, 0x3000, 0xFEFF:
+		return true
+	}
+	return c >= 0x2000 && c <= 0x200A
+}
+
+func trimWhitespaceOrLineTerminators(text []uint16) []uint16 {
+	for len(text) > 0 && isWhitespaceOrLineTerminator(text[0]) {
+		text = text[1:]
+	}
+	for len(text) > 0 && isWhitespaceOrLineTerminator(text[len(text)-1]) {
+		text = text[:len(text)-1]
+	}
+	return text
+}
+
+// This returns the length of the longest prefix of the text that is a valid
+// "StrDecimalLiteral", or 0 if there is no such prefix.
+func decimalLiteralPrefixLength(text []uint16) int {
+	isDigit := func(i int) bool { return i < len(text) && text[i] >= '0' && text[i] <= '9' }
+	i := 0
+	if i < len(text) && (text[i] == '+' || text[i] == '-') {
+		i++
+	}
+	if i+8 <= len(text) && helpers.UTF16EqualsString(text[i:i+8], "Infinity") {
+		return i + 8
+	}
+
+	intStart := i
+	for isDigit(i) {
+		i++
+	}
+	hasDigits := i > intStart
+	if i < len(text) && text[i] == '.' {
+		fracStart := i + 1
+		j := fracStart
+		for isDigit(j) {
+			j++
+		}
+		if hasDigits || j > fracStart {
+			hasDigits = true
+			i = j
+		}
+	}
+	if !hasDigits {
+		return 0
+	}
+
+	if i < len(text) && (text[i] == 'e' || text[i] == 'E') {
+		j := i + 1
+		if j < len(text) && (text[j] == '+' || text[j] == '-') {
+			j++
+		}
+		expStart := j
+		for isDigit(j) {
+			j++
+		}
+		if j > expStart {
+			i = j
+		}
+	}
+	return i
+}
+
+func parseDecimalLiteral(text []uint16) float64 {
+	str := helpers.UTF16ToString(text)
+	switch str {
+	case "Infinity", "+Infinity":
+		return math.Inf(1)
+	case "-Infinity":
+		return math.Inf(-1)
+	}
+
+	// Overflow and underflow result in an infinity or zero, which is also
+	// what JavaScript does, so ignore the error in that case
+	value, _ := strconv.ParseFloat(str, 64)
+	return value
+}
+
+// This parses the digits of a non-negative integer in the given radix. It
+// fails if the result can't be represented exactly.
+func parseDigitsInRadix(text []uint16, radix uint64) (uint64, bool) {
+	var value uint64
+	for _, c := range text {
+		var digit uint64
+		switch {
+		case c >= '0' && c <= '9':
+			digit = uint64(c - '0')
+		case c >= 'a' && c <= 'z':
+			digit = uint64(c-'a') + 10
+		case c >= 'A' && c <= 'Z':
+			digit = uint64(c-'A') + 10
+		default:
+			return 0, false
+		}
+		if digit >= radix {
+			return 0, false
+		}
+		value = value*radix + digit
+		if value > 1<<53 {
+			return 0, false
+		}
+	}
+	return value, true
+}
+
+// https://tc39.es/ecma262/#sec-stringtonumber
+func stringToNumber(text []uint16) (float64, bool) {
+	text = trimWhitespaceOrLineTerminators(text)
+	if len(text) == 0 {
+		return 0, true
+	}
+
+	// Handle "0x", "0o", and "0b" prefixes
+	if len(text) > 2 && text[0] == '0' {
+		var radix uint64
+		switch text[1] {
+		case 'x', 'X':
+			radix = 16
+		case 'o', 'O':
+			radix = 8
+		case 'b', 'B':
+			radix = 2
+		}
+		if radix != 0 {
+			if value, ok := parseDigitsInRadix(text[2:], radix); ok {
+				return float64(value), true
+			}
+
+			// Don't try to fold this if the result would be imprecise
+			for _, c := range text[2:] {
+				if c >= 0x80 || !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+					return math.NaN(), true
+				}
+			}
+			return 0, false
+		}
+	}
+
+	if decimalLiteralPrefixLength(text) != len(text) {
+		return math.NaN(), true
+	}
+	return parseDecimalLiteral(text), true
+}
+
+// https://tc39.es/ecma262/#sec-parseint-string-radix
+func parseIntFromString(text []uint16, radixValue float64) (float64, bool) {
+	for len(text) > 0 && isWhitespaceOrLineTerminator(text[0]) {
+		text = text[1:]
+	}
+
+	sign := 1.0
+	if len(text) > 0 && (text[0] == '+' || text[0] == '-') {
+		if text[0] == '-' {
+			sign = -1
+		}
+		text = text[1:]
+	}
+
+	radix := uint64(ToInt32(radixValue))
+	stripPrefix := true
+	if radix != 0 {
+		if radix < 2 || radix > 36 {
+			return math.NaN(), true
+		}
+		if radix != 16 {
+			stripPrefix = false
+		}
+	} else {
+		radix = 10
+	}
+	if stripPrefix && len(text) >= 2 && text[0] == '0' && (text[1] == 'x' || text[1] == 'X') {
+		text = text[2:]
+		radix = 16
+	}
+
+	// Only use the leading characters that are valid digits
+	end := 0
+	for end < len(text) {
+		if _, ok := parseDigitsInRadix(text[end:end+1], radix); !ok {
+			break
+		}
+		end++
+	}
+	if end == 0 {
+		return math.NaN(), true
+	}
+
+	// Don't try to fold this if the result would be imprecise
+	value, ok := parseDigitsInRadix(text[:end], radix)
+	if !ok {
+		return 0, false
+	}
+	return sign * float64(value), true
+}
+
+// https://tc39.es/ecma262/#sec-parsefloat-string
+func parseFloatFromString(text []uint16) (float64, bool) {
+	for len(text) > 0 && isWhitespaceOrLineTerminator(text[0]) {
+		text = text[1:]
+	}
+	n := decimalLiteralPrefixLength(text)
+	if n == 0 {
+		return math.NaN(), true
+	}
+	return parseDecimalLiteral(text[:n]), true
+}
+
 // "`a${'b'}c`" => "`abc`"
+// "`a${1}b`" => "`a1b`"
+// "`a${`b${x}`}c`" => "`ab${x}c`"
 //
 // This function intentionally avoids mutating the input AST so it can be
 // called after the AST has been frozen (i.e. after parsing ends).
@@ -1379,15 +1984,26 @@ func InlineStringsIntoTemplate(loc logger.Loc, e *ETemplate) Expr {
 
 	headCooked := e.HeadCooked
 	parts := make([]TemplatePart, 0, len(e.Parts))
+	appendText := func(text []uint16) {
+		if len(parts) == 0 {
+			headCooked = joinStrings(headCooked, text)
+		} else {
+			prevPart := &parts[len(parts)-1]
+			prevPart.TailCooked = joinStrings(prevPart.TailCooked, text)
+		}
+	}
 
 	for _, part := range e.Parts {
 		if str, ok := part.Value.Data.(*EString); ok {
-			if len(parts) == 0 {
-				headCooked = append(append(headCooked, str.Value...), part.TailCooked...)
-			} else {
-				prevPart := &parts[len(parts)-1]
-				prevPart.TailCooked = append(append(prevPart.TailCooked, str.Value...), part.TailCooked...)
-			}
+			appendText(str.Value)
+			appendText(part.TailCooked)
+		} else if text, ok := toStringWithoutSideEffects(part.Value.Data); ok {
+			appendText(helpers.StringToUTF16(text))
+			appendText(part.TailCooked)
+		} else if nested, ok := part.Value.Data.(*ETemplate); ok && nested.TagOrNil.Data == nil {
+			appendText(nested.HeadCooked)
+			parts = append(parts, nested.Parts...)
+			appendText(part.TailCooked)
 		} else {
 			parts = append(parts, part)
 		}
@@ -1408,6 +2024,211 @@ func InlineStringsIntoTemplate(loc logger.Loc, e *ETemplate) Expr {
 	}}
 }
 
+// "'a' + x + 'b'" => "`a${x}b`"
+// "`a${x}`" => "'a' + x"
+// "'a' + 1" => "'a1'"
+//
+// This converts between template literals and chains of string additions,
+// whichever is shorter. Template literals convert their substitutions to
+// strings differently than string addition does for objects (i.e. "toString"
+// vs. "valueOf") so this is only done when all substitutions are known to
+// be primitives. The caller is responsible for checking that template
+// literals are supported.
+//
+// This function intentionally avoids mutating the input AST so it can be
+// called after the AST has been frozen (i.e. after parsing ends).
+func ShortenStringConcatenation(expr Expr) Expr {
+	var head []uint16
+	var parts []TemplatePart
+	var headLoc logger.Loc
+	isTemplate := false
+
+	switch e := expr.Data.(type) {
+	case *ETemplate:
+		if e.TagOrNil.Data != nil {
+			return expr
+		}
+		for _, part := range e.Parts {
+			if KnownPrimitiveType(part.Value) == PrimitiveUnknown {
+				return expr
+			}
+		}
+		head, parts, headLoc = e.HeadCooked, e.Parts, e.HeadLoc
+		isTemplate = true
+
+	case *EBinary:
+		var ok bool
+		if head, parts, headLoc, ok = flattenStringAddition(expr); !ok {
+			return expr
+		}
+
+		// "'a' + 1" => "'a1'"
+		if len(parts) == 0 {
+			return Expr{Loc: expr.Loc, Data: &EString{Value: head}}
+		}
+
+	default:
+		return expr
+	}
+
+	// Compare the sizes of both forms. The substitutions are the same in both
+	// forms so only the extra characters need to be counted.
+	templateCost := 2 + templateTextCost(head) + 3*len(parts)
+	concatCost := 0
+	operands := 0
+	if len(head) > 0 || KnownPrimitiveType(parts[0].Value) != PrimitiveString {
+		concatCost += stringTextCost(head)
+		operands++
+	}
+	for _, part := range parts {
+		templateCost += templateTextCost(part.TailCooked)
+		if needsParenthesesInStringAddition(part.Value) {
+			concatCost += 2
+		}
+		operands++
+		if len(part.TailCooked) > 0 {
+			concatCost += stringTextCost(part.TailCooked)
+			operands++
+		}
+	}
+	concatCost += operands - 1
+
+	if isTemplate && concatCost < templateCost {
+		var result Expr
+		add := func(operand Expr) {
+			if result.Data == nil {
+				result = operand
+			} else {
+				result = Expr{Loc: expr.Loc, Data: &EBinary{Op: BinOpAdd, Left: result, Right: operand}}
+			}
+		}
+		if len(head) > 0 || KnownPrimitiveType(parts[0].Value) != PrimitiveString {
+			add(Expr{Loc: headLoc, Data: &EString{Value: head}})
+		}
+		for _, part := range parts {
+			add(part.Value)
+			if len(part.TailCooked) > 0 {
+				add(Expr{Loc: part.TailLoc, Data: &EString{Value: part.TailCooked}})
+			}
+		}
+		return result
+	}
+
+	if !isTemplate && templateCost < concatCost {
+		return Expr{Loc: expr.Loc, Data: &ETemplate{
+			HeadLoc:    headLoc,
+			HeadCooked: head,
+			Parts:      parts,
+		}}
+	}
+
+	return expr
+}
+
+// This flattens a chain of string additions into the parts of a template
+// literal. It fails unless the leftmost operand is a string and all other
+// operands are known to be primitives.
+func flattenStringAddition(expr Expr) (head []uint16, parts []TemplatePart, headLoc logger.Loc, ok bool) {
+	switch e := expr.Data.(type) {
+	case *EString:
+		return e.Value, nil, expr.Loc, true
+
+	case *ETemplate:
+		if e.TagOrNil.Data == nil {
+			return e.HeadCooked, e.Parts, e.HeadLoc, true
+		}
+
+	case *EBinary:
+		if e.Op != BinOpAdd {
+			break
+		}
+		if head, parts, headLoc, ok = flattenStringAddition(e.Left); !ok {
+			break
+		}
+		parts = append([]TemplatePart{}, parts...)
+		appendText := func(text []uint16) {
+			if len(parts) == 0 {
+				head = joinStrings(head, text)
+			} else {
+				parts[len(parts)-1].TailCooked = joinStrings(parts[len(parts)-1].TailCooked, text)
+			}
+		}
+
+		switch r := e.Right.Data.(type) {
+		case *EString:
+			appendText(r.Value)
+			return head, parts, headLoc, true
+
+		case *ETemplate:
+			if r.TagOrNil.Data == nil {
+				appendText(r.HeadCooked)
+				parts = append(parts, r.Parts...)
+				return head, parts, headLoc, true
+			}
+		}
+
+		if text, ok := toStringWithoutSideEffects(e.Right.Data); ok {
+			appendText(helpers.StringToUTF16(text))
+			return head, parts, headLoc, true
+		}
+		if KnownPrimitiveType(e.Right) != PrimitiveUnknown {
+			parts = append(parts, TemplatePart{Value: e.Right, TailLoc: e.Right.Loc})
+			return head, parts, headLoc, true
+		}
+	}
+
+	return nil, nil, logger.Loc{}, false
+}
+
+// This estimates how many characters the text takes up inside a template
+// literal (not including the surrounding backticks)
+func templateTextCost(text []uint16) int {
+	cost := len(text)
+	for i, c := range text {
+		switch c {
+		case '`', '\\', '\r':
+			cost++
+		case '$':
+			if i+1 < len(text) && text[i+1] == '{' {
+				cost++
+			}
+		}
+	}
+	return cost
+}
+
+// This estimates how many characters the text takes up as a string literal
+// (including the surrounding quotes)
+func stringTextCost(text []uint16) int {
+	cost := len(text) + 2
+	doubleQuotes := 0
+	singleQuotes := 0
+	for _, c := range text {
+		switch c {
+		case '\\', '\n', '\r':
+			cost++
+		case '"':
+			doubleQuotes++
+		case '\'':
+			singleQuotes++
+		}
+	}
+	if doubleQuotes < singleQuotes {
+		return cost + doubleQuotes
+	}
+	return cost + singleQuotes
+}
+
+func needsParenthesesInStringAddition(expr Expr) bool {
+	switch e := expr.Data.(type) {
+	case *EBinary:
+		return OpTable[e.Op].Level <= LAdd
+	case *EIf, *EArrow, *EYield:
+		return true
+	}
+	return false
+}
+
 type SideEffects uint8
 
 const (
@@ -1683,6 +2504,10 @@ func StmtsCanBeRemovedIfUnused(stmts []Stmt, flags StmtsCanBeRemovedIfUnusedFlag
 			}
 
 		case *SLocal:
+			// "using" declarations have side effects when they go out of scope
+			if s.Kind.IsUsing() {
+				return false
+			}
 			for _, decl := range s.Decls {
 				if _, ok := decl.Binding.Data.(*BIdentifier); !ok {
 					return false
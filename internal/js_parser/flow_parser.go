@@ -0,0 +1,368 @@
+// This file contains code for parsing Flow syntax. Flow is parsed using the
+// TypeScript parser with "ts.Flow" enabled, since most of the type syntax is
+// the same. The code here handles the parts of Flow that don't have a direct
+// TypeScript equivalent. Like with TypeScript, types are skipped over as if
+// they are whitespace.
+
+package js_parser
+
+import (
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// Since Flow is parsed using the TypeScript parser, syntax that only exists in
+// TypeScript must be rejected explicitly. Otherwise it would silently be given
+// TypeScript semantics. For example, Flow enums are not TypeScript enums.
+func (p *parser) forbidTypeScriptSyntaxInFlow(r logger.Range, text string) {
+	if p.options.ts.Flow {
+		p.log.AddError(&p.tracker, r, text)
+	}
+}
+
+// Returns true if the current token is the name of a parameter in a function
+// type. Flow allows the names of function type parameters to be omitted:
+//
+//	type Fn = (x: string, y?: number) => void
+//	type Fn = (string, number) => void
+func (p *parser) isFlowNamedFnParam() (result bool) {
+	if p.lexer.Token != js_lexer.TIdentifier && p.lexer.Token != js_lexer.TThis {
+		return false
+	}
+
+	// Look ahead to see if this is followed by a type annotation
+	oldLexer := p.lexer
+	p.lexer.Next()
+	result = p.lexer.Token == js_lexer.TColon || p.lexer.Token == js_lexer.TQuestion
+
+	// Restore the lexer
+	p.lexer = oldLexer
+	return
+}
+
+// Returns true if the current "|" token is the end of an exact object type
+func (p *parser) isFlowExactObjectTypeEnd() (result bool) {
+	oldLexer := p.lexer
+	p.lexer.Next()
+	result = p.lexer.Token == js_lexer.TCloseBrace
+
+	// Restore the lexer
+	p.lexer = oldLexer
+	return
+}
+
+// Returns true if the current less-than token is the start of an arrow
+// function with type parameters. Flow files are parsed as JSX, so this is
+// ambiguous with a JSX element. Unlike TypeScript, Flow doesn't require a
+// trailing comma after the type parameters:
+//
+//	<T>(x: T): T => x
+//	<T>(x) => x
+func (p *parser) isFlowArrowFnJSX() (isArrowFn bool) {
+	oldLexer := p.lexer
+	p.lexer.IsLogDisabled = true
+
+	// Implement backtracking by restoring the lexer's memory to its original state
+	defer func() {
+		r := recover()
+		if _, isLexerPanic := r.(js_lexer.LexerPanic); isLexerPanic {
+			isArrowFn = false
+		} else if r != nil {
+			panic(r)
+		}
+		p.lexer = oldLexer
+	}()
+
+	p.skipTypeScriptTypeParameters(0)
+	p.skipTypeScriptFnArgs()
+	if p.lexer.Token == js_lexer.TColon {
+		p.lexer.Next()
+		p.skipTypeScriptReturnType()
+	}
+	return p.lexer.Token == js_lexer.TEqualsGreaterThan
+}
+
+// This skips over a type predicate such as "%checks" or "%checks(x != null)"
+func (p *parser) skipFlowPredicate() {
+	p.lexer.Expect(js_lexer.TPercent)
+	p.lexer.ExpectContextualKeyword("checks")
+
+	// "declare function isString(x: mixed): boolean %checks(typeof x === 'string')"
+	if p.lexer.Token == js_lexer.TOpenParen {
+		p.lexer.Next()
+		for depth := 1; depth > 0; {
+			switch p.lexer.Token {
+			case js_lexer.TOpenParen:
+				depth++
+			case js_lexer.TCloseParen:
+				depth--
+			case js_lexer.TEndOfFile:
+				p.lexer.Expect(js_lexer.TCloseParen)
+			}
+			p.lexer.Next()
+		}
+	}
+}
+
+// This assumes the "opaque type" tokens have already been parsed
+func (p *parser) skipFlowOpaqueTypeStmt(opts parseStmtOpts) {
+	name := p.lexer.Identifier.String
+	p.lexer.Expect(js_lexer.TIdentifier)
+
+	if opts.isModuleScope {
+		p.localTypeNames[name] = true
+	}
+
+	p.skipTypeScriptTypeParameters(0)
+
+	// "opaque type Foo: string = string"
+	if p.lexer.Token == js_lexer.TColon {
+		p.lexer.Next()
+		p.skipTypeScriptType(js_ast.LLowest)
+	}
+
+	// "declare opaque type Foo"
+	if p.lexer.Token == js_lexer.TEquals || !opts.isTypeScriptDeclare {
+		p.lexer.Expect(js_lexer.TEquals)
+		p.skipTypeScriptType(js_ast.LLowest)
+	}
+
+	p.lexer.ExpectOrInsertSemicolon()
+}
+
+// This assumes the "import type" or "import typeof" tokens have already been
+// parsed. None of the imported names are bound since they are all types.
+func (p *parser) skipFlowTypeOnlyImportClause() {
+	switch p.lexer.Token {
+	case js_lexer.TAsterisk:
+		// "import typeof * as foo from 'bar'"
+		p.lexer.Next()
+		p.lexer.ExpectContextualKeyword("as")
+		p.lexer.Expect(js_lexer.TIdentifier)
+
+	case js_lexer.TOpenBrace:
+		// "import typeof {foo} from 'bar'"
+		p.parseImportClause()
+
+	default:
+		// "import typeof foo from 'bar'"
+		// "import typeof foo, {bar} from 'baz'"
+		// "import typeof foo, * as bar from 'baz'"
+		p.lexer.Expect(js_lexer.TIdentifier)
+		if p.lexer.Token == js_lexer.TComma {
+			p.lexer.Next()
+			if p.lexer.Token == js_lexer.TAsterisk {
+				p.lexer.Next()
+				p.lexer.ExpectContextualKeyword("as")
+				p.lexer.Expect(js_lexer.TIdentifier)
+			} else {
+				p.parseImportClause()
+			}
+		}
+	}
+
+	p.lexer.ExpectContextualKeyword("from")
+	p.parsePath()
+	p.lexer.ExpectOrInsertSemicolon()
+}
+
+// This assumes the "declare" token has already been parsed. Flow declarations
+// only describe the types of things that exist elsewhere, so they are skipped.
+func (p *parser) skipFlowDeclareStmt(opts parseStmtOpts) {
+	opts.isTypeScriptDeclare = true
+
+	switch p.lexer.Token {
+	case js_lexer.TExport:
+		p.lexer.Next()
+
+		switch p.lexer.Token {
+		case js_lexer.TDefault:
+			// "declare export default class Foo {}"
+			// "declare export default function foo(): void"
+			// "declare export default string"
+			p.lexer.Next()
+			if p.lexer.Token != js_lexer.TClass && p.lexer.Token != js_lexer.TFunction {
+				p.skipTypeScriptType(js_ast.LLowest)
+				p.lexer.ExpectOrInsertSemicolon()
+				return
+			}
+
+		case js_lexer.TAsterisk:
+			// "declare export * from 'foo'"
+			// "declare export * as foo from 'foo'"
+			p.lexer.Next()
+			if p.lexer.IsContextualKeyword("as") {
+				p.lexer.Next()
+				p.parseClauseAlias("export")
+				p.lexer.Next()
+			}
+			p.lexer.ExpectContextualKeyword("from")
+			p.parsePath()
+			p.lexer.ExpectOrInsertSemicolon()
+			return
+
+		case js_lexer.TOpenBrace:
+			// "declare export { foo }"
+			// "declare export { foo } from 'foo'"
+			p.parseExportClause()
+			if p.lexer.IsContextualKeyword("from") {
+				p.lexer.Next()
+				p.parsePath()
+			}
+			p.lexer.ExpectOrInsertSemicolon()
+			return
+		}
+
+		// "declare export var foo: number"
+		p.skipFlowDeclareStmt(opts)
+
+	case js_lexer.TVar, js_lexer.TConst:
+		// "declare var foo: number"
+		p.lexer.Next()
+		p.skipFlowDeclareVar()
+
+	case js_lexer.TFunction:
+		// "declare function foo(x: number): string"
+		p.lexer.Next()
+		p.lexer.Expect(js_lexer.TIdentifier)
+		p.skipTypeScriptTypeParameters(0)
+		p.skipTypeScriptFnArgs()
+		p.lexer.Expect(js_lexer.TColon)
+		p.skipTypeScriptReturnType()
+		p.lexer.ExpectOrInsertSemicolon()
+
+	case js_lexer.TClass:
+		// "declare class Foo<T> extends Bar<T> { x: T }"
+		p.lexer.Next()
+		p.skipFlowDeclareClass()
+
+	case js_lexer.TIdentifier:
+		switch p.lexer.Identifier.String {
+		case "let":
+			// "declare let foo: number"
+			p.lexer.Next()
+			p.skipFlowDeclareVar()
+			return
+
+		case "type":
+			// "declare type Foo = number"
+			p.lexer.Next()
+			p.skipTypeScriptTypeStmt(opts)
+			return
+
+		case "opaque":
+			// "declare opaque type Foo"
+			// "declare opaque type Foo: string"
+			p.lexer.Next()
+			p.lexer.ExpectContextualKeyword("type")
+			p.skipFlowOpaqueTypeStmt(opts)
+			return
+
+		case "interface":
+			// "declare interface Foo { x: number }"
+			p.lexer.Next()
+			p.skipTypeScriptInterfaceStmt(opts)
+			return
+
+		case "module":
+			p.lexer.Next()
+
+			// "declare module.exports: { foo: number }"
+			if p.lexer.Token == js_lexer.TDot {
+				p.lexer.Next()
+				p.lexer.ExpectContextualKeyword("exports")
+				p.lexer.Expect(js_lexer.TColon)
+				p.skipTypeScriptType(js_ast.LLowest)
+				p.lexer.ExpectOrInsertSemicolon()
+				return
+			}
+
+			// "declare module 'foo' { declare export var x: number }"
+			if p.lexer.Token == js_lexer.TStringLiteral {
+				p.lexer.Next()
+			} else {
+				p.lexer.Expect(js_lexer.TIdentifier)
+			}
+			p.lexer.Expect(js_lexer.TOpenBrace)
+			for p.lexer.Token != js_lexer.TCloseBrace {
+				p.skipFlowDeclareModuleStmt()
+			}
+			p.lexer.Next()
+			return
+		}
+
+		p.lexer.Unexpected()
+
+	default:
+		p.lexer.Unexpected()
+	}
+}
+
+func (p *parser) skipFlowDeclareVar() {
+	p.lexer.Expect(js_lexer.TIdentifier)
+	if p.lexer.Token == js_lexer.TColon {
+		p.lexer.Next()
+		p.skipTypeScriptType(js_ast.LLowest)
+	}
+	p.lexer.ExpectOrInsertSemicolon()
+}
+
+// This assumes the "class" token has already been parsed. The body of a
+// declared class uses object type syntax instead of class body syntax.
+func (p *parser) skipFlowDeclareClass() {
+	p.lexer.Expect(js_lexer.TIdentifier)
+	p.skipTypeScriptTypeParameters(0)
+
+	for p.lexer.Token == js_lexer.TExtends || p.lexer.IsContextualKeyword("mixins") || p.lexer.IsContextualKeyword("implements") {
+		p.lexer.Next()
+		for {
+			p.skipTypeScriptType(js_ast.LLowest)
+			if p.lexer.Token != js_lexer.TComma {
+				break
+			}
+			p.lexer.Next()
+		}
+	}
+
+	p.skipTypeScriptObjectType()
+}
+
+// Statements inside "declare module" are all type declarations
+func (p *parser) skipFlowDeclareModuleStmt() {
+	opts := parseStmtOpts{isTypeScriptDeclare: true}
+
+	switch p.lexer.Token {
+	case js_lexer.TSemicolon:
+		p.lexer.Next()
+		return
+
+	case js_lexer.TImport:
+		// "import type { Foo } from 'foo'"
+		p.lexer.Next()
+		if p.lexer.Token == js_lexer.TTypeof || p.lexer.IsContextualKeyword("type") {
+			p.lexer.Next()
+		}
+		p.skipFlowTypeOnlyImportClause()
+		return
+
+	case js_lexer.TExport:
+		// "export type Foo = number"
+		p.skipFlowDeclareStmt(opts)
+		return
+
+	case js_lexer.TIdentifier:
+		switch p.lexer.Identifier.String {
+		case "declare":
+			p.lexer.Next()
+			p.skipFlowDeclareStmt(opts)
+			return
+
+		case "type", "opaque", "interface":
+			p.skipFlowDeclareStmt(opts)
+			return
+		}
+	}
+
+	p.lexer.Unexpected()
+}
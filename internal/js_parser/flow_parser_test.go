@@ -0,0 +1,201 @@
+package js_parser
+
+import (
+	"testing"
+
+	"github.com/evanw/esbuild/internal/config"
+)
+
+func expectParseErrorFlow(t *testing.T, contents string, expected string) {
+	t.Helper()
+	expectParseErrorCommon(t, contents, expected, config.Options{
+		TS: config.TSOptions{
+			Parse: true,
+			Flow:  true,
+		},
+		JSX: config.JSXOptions{
+			Parse: true,
+		},
+		UnusedImportFlagsTS: config.UnusedImportKeepValues,
+	})
+}
+
+func expectPrintedFlow(t *testing.T, contents string, expected string) {
+	t.Helper()
+	expectPrintedCommon(t, contents, expected, config.Options{
+		TS: config.TSOptions{
+			Parse: true,
+			Flow:  true,
+		},
+		JSX: config.JSXOptions{
+			Parse: true,
+		},
+		UnusedImportFlagsTS: config.UnusedImportKeepValues,
+	})
+}
+
+func TestFlowTypes(t *testing.T) {
+	expectPrintedFlow(t, "let x: number = 1", "let x = 1;\n")
+	expectPrintedFlow(t, "let x: ?number = 1", "let x = 1;\n")
+	expectPrintedFlow(t, "let x: ??number = 1", "let x = 1;\n")
+	expectPrintedFlow(t, "let x: ?number[] = 1", "let x = 1;\n")
+	expectPrintedFlow(t, "let x: ?string | number = 1", "let x = 1;\n")
+	expectPrintedFlow(t, "let x: * = 1", "let x = 1;\n")
+	expectPrintedFlow(t, "let x: Array<*> = 1", "let x = 1;\n")
+	expectPrintedFlow(t, "let x: {| a: number, b?: string |} = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: {| a: A | B |} = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: {||} = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: {| |} = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: { ...A, b: number } = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: {| ...A, ...B |} = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: { a: number, ... } = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: { ... } = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: { +a: number, -b: string } = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: { [string]: number } = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: { [key: string]: number } = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: { (number): string } = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: Obj['a'] = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: Obj?.['a']['b'] = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: $Keys<typeof obj> = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: [number, string] = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: 'a' | 'b' = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: typeof y.z = y", "let x = y;\n")
+}
+
+func TestFlowFunctionTypes(t *testing.T) {
+	expectPrintedFlow(t, "let x: (a: number, b?: string) => void = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: (number, string) => void = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: (?number, string[]) => void = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: (...Array<number>) => void = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: <T>(T) => T = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: string => void = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: string => number => void = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: (string => void) | null = y", "let x = y;\n")
+	expectPrintedFlow(t, "let x: (number | string) = y", "let x = y;\n")
+	expectPrintedFlow(t, "function f(cb: string => void) {}", "function f(cb) {\n}\n")
+	expectPrintedFlow(t, "function f(x: mixed): boolean %checks { return !!x }", "function f(x) {\n  return !!x;\n}\n")
+	expectPrintedFlow(t, "function f(x: mixed): %checks { return !!x }", "function f(x) {\n  return !!x;\n}\n")
+}
+
+func TestFlowFunctions(t *testing.T) {
+	expectPrintedFlow(t, "function f<T>(x: T, y?: number): T { return x }", "function f(x, y) {\n  return x;\n}\n")
+	expectPrintedFlow(t, "function f<T: Object = {}>(x: T): T { return x }", "function f(x) {\n  return x;\n}\n")
+	expectPrintedFlow(t, "let f = (x: number): string => x", "let f = (x) => x;\n")
+	expectPrintedFlow(t, "let f = async (x: number): Promise<void> => {}", "let f = async (x) => {\n};\n")
+	expectPrintedFlow(t, "let f = <T>(x: T): T => x", "let f = (x) => x;\n")
+	expectPrintedFlow(t, "let f = <T>(x) => x", "let f = (x) => x;\n")
+	expectPrintedFlow(t, "let f = <T: Object>(x: T) => x", "let f = (x) => x;\n")
+	expectPrintedFlow(t, "let f = <div>(x)</div>", "let f = /* @__PURE__ */ React.createElement(\"div\", null, \"(x)\");\n")
+	expectPrintedFlow(t, "f<T>(x)", "f(x);\n")
+	expectPrintedFlow(t, "new C<T>(x)", "new C(x);\n")
+}
+
+func TestFlowTypeCasts(t *testing.T) {
+	expectPrintedFlow(t, "let x = (y: any)", "let x = y;\n")
+	expectPrintedFlow(t, "let x = ((y: any): string)", "let x = y;\n")
+	expectPrintedFlow(t, "let x = ({}: { [string]: number })", "let x = {};\n")
+	expectPrintedFlow(t, "f((y: any), (z: ?string))", "f(y, z);\n")
+	expectPrintedFlow(t, "let x = (a ? b : c: any)", "let x = a ? b : c;\n")
+	expectParseErrorFlow(t, "let x = (a: any, b)", "<stdin>: ERROR: Unexpected \":\"\n")
+	expectParseErrorFlow(t, "let x = (...a: any)", "<stdin>: ERROR: Unexpected \":\"\n")
+}
+
+func TestFlowClasses(t *testing.T) {
+	expectPrintedFlow(t, "class Foo<+T, -U> extends Bar<T> implements Baz<U> {}", "class Foo extends Bar {\n}\n")
+	expectPrintedFlow(t, "class Foo { x: number = 1; +y: string = ''; -z: T = z }", "class Foo {\n  x = 1;\n  y = \"\";\n  z = z;\n}\n")
+	expectPrintedFlow(t, "class Foo { static +x: number = 1 }", "class Foo {\n  static x = 1;\n}\n")
+	expectPrintedFlow(t, "class Foo { declare x: number }", "class Foo {\n}\n")
+	expectPrintedFlow(t, "class Foo { foo<T>(x: T): T { return x } }", "class Foo {\n  foo(x) {\n    return x;\n  }\n}\n")
+}
+
+func TestFlowTypeDeclarations(t *testing.T) {
+	expectPrintedFlow(t, "type Foo = number", "")
+	expectPrintedFlow(t, "type Foo<+T: Object = {}> = {| x: T |}", "")
+	expectPrintedFlow(t, "export type Foo = number", "")
+	expectPrintedFlow(t, "export type { Foo, Bar }", "")
+	expectPrintedFlow(t, "opaque type Foo = number", "")
+	expectPrintedFlow(t, "opaque type Foo: number = number", "")
+	expectPrintedFlow(t, "export opaque type Foo<T> = Array<T>", "")
+	expectPrintedFlow(t, "interface Foo { x: number; m(): void }", "")
+	expectPrintedFlow(t, "export interface Foo extends Bar { x: number }", "")
+	expectPrintedFlow(t, "opaque(type)", "opaque(type);\n")
+	expectParseErrorFlow(t, "opaque type Foo", "<stdin>: ERROR: Expected \"=\" but found end of file\n")
+}
+
+func TestFlowDeclare(t *testing.T) {
+	expectPrintedFlow(t, "declare var x: number", "")
+	expectPrintedFlow(t, "declare let x: number", "")
+	expectPrintedFlow(t, "declare const x: number", "")
+	expectPrintedFlow(t, "declare var x", "")
+	expectPrintedFlow(t, "declare function f<T>(x: T, string): T", "")
+	expectPrintedFlow(t, "declare function f(x: mixed): boolean %checks(typeof x === 'string')", "")
+	expectPrintedFlow(t, "declare class Foo<T> extends Bar<T> mixins Baz { static x: T; m(): void; +y: number }", "")
+	expectPrintedFlow(t, "declare type Foo = number", "")
+	expectPrintedFlow(t, "declare opaque type Foo", "")
+	expectPrintedFlow(t, "declare opaque type Foo: string", "")
+	expectPrintedFlow(t, "declare interface Foo { x: number }", "")
+	expectPrintedFlow(t, "declare module.exports: { foo: number }", "")
+	expectPrintedFlow(t, "declare export default string", "")
+	expectPrintedFlow(t, "declare export default class Foo {}", "")
+	expectPrintedFlow(t, "declare export function f(): void", "")
+	expectPrintedFlow(t, "declare export var x: number", "")
+	expectPrintedFlow(t, "declare export * from 'foo'", "")
+	expectPrintedFlow(t, "declare export { a, b } from 'foo'", "")
+	expectPrintedFlow(t, `declare module 'foo' {
+		import type { Bar } from 'bar';
+		declare export var x: Bar;
+		declare module.exports: { x: number };
+		export type Baz = number;
+		type Foo = string;
+	}`, "")
+	expectPrintedFlow(t, "declare(x)", "declare(x);\n")
+	expectPrintedFlow(t, "declare\nvar x", "declare;\nvar x;\n")
+}
+
+func TestFlowImportsAndExports(t *testing.T) {
+	expectPrintedFlow(t, "import type Foo from 'foo'", "")
+	expectPrintedFlow(t, "import type Foo, { Bar } from 'foo'", "")
+	expectPrintedFlow(t, "import type { Foo } from 'foo'", "")
+	expectPrintedFlow(t, "import type * as Foo from 'foo'", "")
+	expectPrintedFlow(t, "import typeof Foo from 'foo'", "")
+	expectPrintedFlow(t, "import typeof Foo, { Bar } from 'foo'", "")
+	expectPrintedFlow(t, "import typeof { Foo } from 'foo'", "")
+	expectPrintedFlow(t, "import typeof * as Foo from 'foo'", "")
+	expectPrintedFlow(t, "import type from 'foo'", "import type from \"foo\";\n")
+
+	// Unlike TypeScript, unused imports are not removed
+	expectPrintedFlow(t, "import Foo from 'foo'", "import Foo from \"foo\";\n")
+	expectPrintedFlow(t, "import { type Foo, typeof Bar, baz } from 'foo'", "import { baz } from \"foo\";\n")
+	expectPrintedFlow(t, "import { type Foo, typeof Bar } from 'foo'", "")
+	expectPrintedFlow(t, "import { typeof as Foo } from 'foo'", "import { typeof as Foo } from \"foo\";\n")
+}
+
+func TestFlowExpressions(t *testing.T) {
+	expectPrintedFlow(t, "let x = <div>{y}</div>", "let x = /* @__PURE__ */ React.createElement(\"div\", null, y);\n")
+	expectPrintedFlow(t, "let x = y as any", "let x = y;\n")
+	expectPrintedFlow(t, "let x = a ? (b) : c", "let x = a ? b : c;\n")
+	expectPrintedFlow(t, "let x = a * b", "let x = a * b;\n")
+	expectPrintedFlow(t, "let x = a < b > c", "let x = a < b > c;\n")
+}
+
+func TestFlowTypeScriptOnlySyntax(t *testing.T) {
+	expectParseErrorFlow(t, "enum Foo { A, B }", "<stdin>: ERROR: Flow enums are not supported\n")
+	expectParseErrorFlow(t, "export enum Foo { A, B }", "<stdin>: ERROR: Flow enums are not supported\n")
+	expectParseErrorFlow(t, "namespace Foo { export let x = 1 }", "<stdin>: ERROR: TypeScript namespaces are not supported in Flow files\n")
+	expectParseErrorFlow(t, "export module Foo {}", "<stdin>: ERROR: TypeScript namespaces are not supported in Flow files\n")
+	expectParseErrorFlow(t, "let x = y!", "<stdin>: ERROR: TypeScript non-null assertions are not supported in Flow files\n")
+	expectParseErrorFlow(t, "let x = y!.z", "<stdin>: ERROR: TypeScript non-null assertions are not supported in Flow files\n")
+	expectParseErrorFlow(t, "class Foo { constructor(private x) {} }",
+		"<stdin>: ERROR: TypeScript parameter properties are not supported in Flow files\n")
+	expectParseErrorFlow(t, "class Foo { constructor(public readonly x) {} }",
+		"<stdin>: ERROR: TypeScript parameter properties are not supported in Flow files\n")
+	expectParseErrorFlow(t, "class Foo { private x = 1 }", "<stdin>: ERROR: The TypeScript \"private\" modifier is not supported in Flow files\n")
+	expectParseErrorFlow(t, "class Foo { static readonly x = 1 }", "<stdin>: ERROR: The TypeScript \"readonly\" modifier is not supported in Flow files\n")
+	expectParseErrorFlow(t, "abstract class Foo {}", "<stdin>: ERROR: TypeScript abstract classes are not supported in Flow files\n")
+
+	// These are still allowed since they aren't TypeScript syntax
+	expectPrintedFlow(t, "let namespace = 1; namespace", "let namespace = 1;\nnamespace;\n")
+	expectPrintedFlow(t, "module.exports = x", "module.exports = x;\n")
+	expectPrintedFlow(t, "let x = y != z", "let x = y != z;\n")
+	expectPrintedFlow(t, "class Foo { private() {} constructor(x) {} }", "class Foo {\n  private() {\n  }\n  constructor(x) {\n  }\n}\n")
+}
@@ -50,8 +50,10 @@ type parser struct {
 	injectedDotNames           map[string][]injectedDotName
 	exprComments               map[logger.Loc][]string
 	mangledProps               map[string]js_ast.Ref
+	mangledPropMethodCounts    map[js_ast.Ref]uint32
 	reservedProps              map[string]bool
 	symbolUses                 map[js_ast.Ref]js_ast.SymbolUse
+	stringLiteralUses          map[string]uint32
 	importSymbolPropertyUses   map[js_ast.Ref]map[string]js_ast.SymbolUse
 	symbolCallUses             map[js_ast.Ref]js_ast.SymbolCallUse
 	declaredSymbols            []js_ast.DeclaredSymbol
@@ -90,8 +92,13 @@ type parser struct {
 	localTypeNames             map[string]bool
 	tsEnums                    map[js_ast.Ref]map[string]js_ast.TSEnumValue
 	constValues                map[js_ast.Ref]js_ast.ConstValue
+	constReturnValues          map[js_ast.Ref]js_ast.ConstValue
 	propMethodValue            js_ast.E
-	propMethodTSDecoratorScope *js_ast.Scope
+	propMethodDecoratorScope   *js_ast.Scope
+
+	// This is the number of statements that were inserted at the start of the
+	// most recently visited class constructor when lowering its arguments to ES5
+	loweredCtorArgsStmtCount int
 
 	// This is the reference to the generated function argument for the namespace,
 	// which is different than the reference to the namespace itself:
@@ -117,6 +124,7 @@ type parser struct {
 	// These are for handling ES6 imports and exports
 	importItemsForNamespace map[js_ast.Ref]namespaceImportItems
 	isImportItem            map[js_ast.Ref]bool
+	defaultImportReads      map[js_ast.Ref]*propertyReads
 	namedImports            map[js_ast.Ref]js_ast.NamedImport
 	namedExports            map[string]js_ast.NamedExport
 	topLevelSymbolToParts   map[js_ast.Ref][]uint32
@@ -147,6 +155,7 @@ type parser struct {
 	dotOrIndexTarget js_ast.E
 	templateTag      js_ast.E
 	deleteTarget     js_ast.E
+	typeofTarget     js_ast.E
 	loopBody         js_ast.S
 	moduleScope      *js_ast.Scope
 
@@ -158,6 +167,10 @@ type parser struct {
 	// warnings about non-string import paths will be omitted inside try blocks.
 	awaitTarget js_ast.E
 
+	// This is the name of the property being accessed on "import.meta" (if any)
+	// and is used to make the warning about a missing "import.meta" more useful
+	importMetaPropertyName string
+
 	// This helps recognize the "import().catch()" pattern. We also try to avoid
 	// warning about this just like the "try { await import() }" pattern.
 	thenCatchChain thenCatchChain
@@ -188,6 +201,15 @@ type parser struct {
 	// which are used in a brand check anywhere in the file.
 	classPrivateBrandChecksToLower map[string]bool
 
+	// This is used when "let" and "const" are converted to "var". Closures that
+	// capture a variable declared inside a loop must be wrapped to preserve the
+	// per-iteration binding of that variable. There is one entry on the stack
+	// for each function or arrow function that we're currently inside.
+	loweredLoopLets          map[js_ast.Ref]*loweredLoopLet
+	loweredLoopLetCaptures   [][]js_ast.Ref
+	loweredLoopLetFnCaptures map[*js_ast.Fn][]js_ast.Ref
+	loopBodyDepth            int
+
 	// Temporary variables used for lowering
 	tempLetsToDeclare         []js_ast.Ref
 	tempRefsToDeclare         []tempRef
@@ -195,6 +217,11 @@ type parser struct {
 
 	lexer js_lexer.Lexer
 
+	// This is a copy of the lexer at the start of the current top-level
+	// statement. It's used to skip over the rest of that statement after a
+	// syntax error so that parsing can resume with the next one.
+	topLevelStmtLexer js_lexer.Lexer
+
 	// Temporary variables used for lowering
 	tempRefCount         int
 	topLevelTempRefCount int
@@ -217,6 +244,10 @@ type parser struct {
 	jsxRuntimeImports map[string]js_ast.LocRef
 	jsxLegacyImports  map[string]js_ast.LocRef
 
+	// Each "import.meta.glob()" call imports the default export of a module
+	// that is generated at build time from the file system
+	importGlobs []importGlob
+
 	// For lowering private methods
 	weakMapRef js_ast.Ref
 	weakSetRef js_ast.Ref
@@ -350,6 +381,11 @@ type namespaceImportItems struct {
 	importRecordIndex uint32
 }
 
+type propertyReads struct {
+	names map[string]bool
+	count uint32
+}
+
 type stringLocalForYarnPnP struct {
 	value []uint16
 	loc   logger.Loc
@@ -396,6 +432,9 @@ type Options struct {
 	tsAlwaysStrict *config.TSAlwaysStrict
 	mangleProps    *regexp.Regexp
 	reserveProps   *regexp.Regexp
+	commentsFilter *regexp.Regexp
+	dropLabels     []string
+	reserveNames   []string
 
 	// This pointer will always be different for each build but the contents
 	// shouldn't ever behave different semantically. We ignore this field for the
@@ -410,6 +449,7 @@ type Options struct {
 
 type optionsThatSupportStructuralEquality struct {
 	originalTargetEnv                 string
+	importMetaShim                    string
 	moduleTypeData                    js_ast.ModuleTypeData
 	unsupportedJSFeatures             compat.JSFeature
 	unsupportedJSFeatureOverrides     compat.JSFeature
@@ -420,7 +460,9 @@ type optionsThatSupportStructuralEquality struct {
 	mode                    config.Mode
 	platform                config.Platform
 	outputFormat            config.Format
+	dirname                 config.DirnameMode
 	targetFromAPI           config.TargetFromAPI
+	comments                config.CommentsMode
 	asciiOnly               bool
 	keepNames               bool
 	minifySyntax            bool
@@ -432,8 +474,10 @@ type optionsThatSupportStructuralEquality struct {
 	treeShaking             bool
 	dropDebugger            bool
 	mangleQuoted            bool
+	inlineWorkers           bool
 	unusedImportFlagsTS     config.UnusedImportFlagsTS
 	useDefineForClassFields config.MaybeBool
+	emitDecoratorMetadata   bool
 
 	// This is an internal-only option used for the implementation of Yarn PnP
 	decodeHydrateRuntimeStateYarnPnP bool
@@ -456,18 +500,24 @@ func OptionsFromConfig(options *config.Options) Options {
 		tsAlwaysStrict: options.TSAlwaysStrict,
 		mangleProps:    options.MangleProps,
 		reserveProps:   options.ReserveProps,
+		commentsFilter: options.CommentsFilter,
+		dropLabels:     options.DropLabels,
+		reserveNames:   options.ReserveNames,
 
 		optionsThatSupportStructuralEquality: optionsThatSupportStructuralEquality{
 			unsupportedJSFeatures:             options.UnsupportedJSFeatures,
 			unsupportedJSFeatureOverrides:     options.UnsupportedJSFeatureOverrides,
 			unsupportedJSFeatureOverridesMask: options.UnsupportedJSFeatureOverridesMask,
 			originalTargetEnv:                 options.OriginalTargetEnv,
+			importMetaShim:                    options.ImportMetaShim,
 			ts:                                options.TS,
 			mode:                              options.Mode,
 			platform:                          options.Platform,
 			outputFormat:                      options.OutputFormat,
+			dirname:                           options.Dirname,
 			moduleTypeData:                    options.ModuleTypeData,
 			targetFromAPI:                     options.TargetFromAPI,
+			comments:                          options.Comments,
 			asciiOnly:                         options.ASCIIOnly,
 			keepNames:                         options.KeepNames,
 			minifySyntax:                      options.MinifySyntax,
@@ -479,12 +529,21 @@ func OptionsFromConfig(options *config.Options) Options {
 			treeShaking:                       options.TreeShaking,
 			dropDebugger:                      options.DropDebugger,
 			mangleQuoted:                      options.MangleQuoted,
+			inlineWorkers:                     options.InlineWorkers,
 			unusedImportFlagsTS:               options.UnusedImportFlagsTS,
 			useDefineForClassFields:           options.UseDefineForClassFields,
+			emitDecoratorMetadata:             options.EmitDecoratorMetadata,
 		},
 	}
 }
 
+// Comments are normally removed when minifying whitespace, but comments that
+// were asked for are kept anyway. That's the case for comments that match the
+// comments filter and for every comment when all comments are being kept.
+func (opts *Options) keepsCommentsWhenMinifying() bool {
+	return opts.commentsFilter != nil || opts.comments == config.CommentsAll
+}
+
 func (a *Options) Equal(b *Options) bool {
 	// Compare "optionsThatSupportStructuralEquality"
 	if a.optionsThatSupportStructuralEquality != b.optionsThatSupportStructuralEquality {
@@ -508,13 +567,28 @@ func (a *Options) Equal(b *Options) bool {
 		return false
 	}
 
+	// Compare "CommentsFilter"
+	if !isSameRegexp(a.commentsFilter, b.commentsFilter) {
+		return false
+	}
+
+	// Compare "DropLabels"
+	if !helpers.StringArraysEqual(a.dropLabels, b.dropLabels) {
+		return false
+	}
+
+	// Compare "ReserveNames"
+	if !helpers.StringArraysEqual(a.reserveNames, b.reserveNames) {
+		return false
+	}
+
 	// Compare "InjectedFiles"
 	if len(a.injectedFiles) != len(b.injectedFiles) {
 		return false
 	}
 	for i, x := range a.injectedFiles {
 		y := b.injectedFiles[i]
-		if x.Source != y.Source || x.DefineName != y.DefineName || len(x.Exports) != len(y.Exports) {
+		if x.Source != y.Source || x.DefineName != y.DefineName || x.IsNodeGlobalShim != y.IsNodeGlobalShim || len(x.Exports) != len(y.Exports) {
 			return false
 		}
 		for j := range x.Exports {
@@ -595,7 +669,7 @@ const (
 // arrow expressions.
 type fnOrArrowDataParse struct {
 	arrowArgErrors      *deferredArrowArgErrors
-	tsDecoratorScope    *js_ast.Scope
+	decoratorScope      *js_ast.Scope
 	asyncRange          logger.Range
 	needsAsyncLoc       logger.Loc
 	await               awaitOrYield
@@ -661,6 +735,24 @@ type fnOnlyDataVisit struct {
 	// if one is missing so this will always be present inside a class body.
 	classNameRef *js_ast.Ref
 
+	// This is the constructor of the enclosing class if there is one. Classes
+	// are lowered to functions when targeting ES5, so this is used to know what
+	// "new.target" means.
+	classCtorFn *js_ast.Fn
+
+	// This is the function that "new.target" refers to. It's nil for class
+	// methods and class field initializers, where "new.target" is undefined.
+	// Lowering "new.target" may require giving that function a name, which is
+	// declared in the function's argument scope.
+	newTargetFn    *js_ast.Fn
+	newTargetScope *js_ast.Scope
+
+	// This is non-nil inside the constructor and the instance field initializers
+	// of a derived class that's being lowered to ES5. In that case "this" is
+	// replaced by a local variable that holds the value returned by the base
+	// class constructor.
+	es5DerivedClass *es5DerivedClass
+
 	// If we're inside an async arrow function and async functions are not
 	// supported, then we will have to convert that arrow function to a generator
 	// function. That means references to "arguments" inside the arrow function
@@ -896,6 +988,16 @@ func jumpStmtsLookTheSame(left js_ast.S, right js_ast.S) bool {
 }
 
 func (p *parser) selectLocalKind(kind js_ast.LocalKind) js_ast.LocalKind {
+	// "using" declarations are either kept as-is or lowered separately
+	if kind.IsUsing() {
+		return kind
+	}
+
+	// Use "var" instead of "let" and "const" if the target doesn't support them
+	if p.options.unsupportedJSFeatures.Has(compat.ConstAndLet) {
+		return js_ast.LocalVar
+	}
+
 	// Safari workaround: Automatically avoid TDZ issues when bundling
 	if p.options.mode == config.ModeBundle && p.currentScope.Parent == nil {
 		return js_ast.LocalVar
@@ -1093,6 +1195,15 @@ func (p *parser) newSymbol(kind js_ast.SymbolKind, name string) js_ast.Ref {
 	if p.options.ts.Parse {
 		p.tsUseCounts = append(p.tsUseCounts, 0)
 	}
+
+	// Symbols with a reserved name keep their name so that code outside of the
+	// bundle (e.g. another script on the page) can still refer to them
+	for _, reserved := range p.options.reserveNames {
+		if name == reserved {
+			p.symbols[ref.InnerIndex].Flags |= js_ast.MustNotBeRenamed
+			break
+		}
+	}
 	return ref
 }
 
@@ -1617,6 +1728,96 @@ func (p *parser) importJSXSymbol(loc logger.Loc, jsx JSXImport) js_ast.Expr {
 	})
 }
 
+type importGlob struct {
+	glob *ast.ImportGlob
+	ref  js_ast.Ref
+	loc  logger.Loc
+}
+
+// This handles Vite's "import.meta.glob(patterns, { eager, import })" feature.
+// The call is replaced with a reference to the default export of a module that
+// the bundler generates by searching the file system for the patterns.
+func (p *parser) maybeImportGlob(loc logger.Loc, e *js_ast.ECall) (js_ast.Expr, bool) {
+	glob := ast.ImportGlob{}
+
+	switch arg := e.Args[0].Data.(type) {
+	case *js_ast.EString:
+		glob.Patterns = []string{helpers.UTF16ToString(arg.Value)}
+
+	case *js_ast.EArray:
+		for _, item := range arg.Items {
+			str, ok := item.Data.(*js_ast.EString)
+			if !ok {
+				glob.Patterns = nil
+				break
+			}
+			glob.Patterns = append(glob.Patterns, helpers.UTF16ToString(str.Value))
+		}
+	}
+	if len(glob.Patterns) == 0 {
+		p.log.AddError(&p.tracker, logger.Range{Loc: e.Args[0].Loc},
+			"The first argument to \"import.meta.glob\" must be a string or an array of strings")
+		return js_ast.Expr{}, false
+	}
+
+	if len(e.Args) > 1 {
+		options, ok := e.Args[1].Data.(*js_ast.EObject)
+		if !ok {
+			p.log.AddError(&p.tracker, logger.Range{Loc: e.Args[1].Loc},
+				"The second argument to \"import.meta.glob\" must be an object literal")
+			return js_ast.Expr{}, false
+		}
+		for _, property := range options.Properties {
+			key, ok := property.Key.Data.(*js_ast.EString)
+			if !ok || property.Kind != js_ast.PropertyNormal || property.Flags.Has(js_ast.PropertyIsComputed) {
+				p.log.AddError(&p.tracker, logger.Range{Loc: property.Key.Loc}, "Expected a property name")
+				return js_ast.Expr{}, false
+			}
+			name := helpers.UTF16ToString(key.Value)
+			r := p.source.RangeOfString(property.ValueOrNil.Loc)
+
+			switch name {
+			case "eager":
+				value, ok := property.ValueOrNil.Data.(*js_ast.EBoolean)
+				if !ok {
+					p.log.AddError(&p.tracker, r, "The \"eager\" option must be a boolean literal")
+					return js_ast.Expr{}, false
+				}
+				glob.Eager = value.Value
+
+			case "import":
+				value, ok := property.ValueOrNil.Data.(*js_ast.EString)
+				if !ok || !js_ast.IsIdentifier(helpers.UTF16ToString(value.Value)) {
+					p.log.AddError(&p.tracker, r, "The \"import\" option must be a string literal containing an identifier")
+					return js_ast.Expr{}, false
+				}
+				glob.Import = helpers.UTF16ToString(value.Value)
+
+			default:
+				p.log.AddError(&p.tracker, logger.Range{Loc: property.Key.Loc, Len: int32(len(name))},
+					fmt.Sprintf("The %q option for \"import.meta.glob\" is not supported", name))
+				return js_ast.Expr{}, false
+			}
+		}
+	}
+
+	// Ignore calls to import.meta.glob() if the control flow is provably dead
+	// here. We don't want to spend time scanning the file system if the result
+	// will never be used.
+	if p.isControlFlowDead {
+		return js_ast.Expr{Loc: loc, Data: js_ast.ENullShared}, true
+	}
+
+	ref := p.newSymbol(js_ast.SymbolOther, "glob")
+	p.moduleScope.Generated = append(p.moduleScope.Generated, ref)
+	p.isImportItem[ref] = true
+	p.importGlobs = append(p.importGlobs, importGlob{glob: &glob, ref: ref, loc: loc})
+	p.recordUsage(ref)
+	return p.handleIdentifier(loc, &js_ast.EIdentifier{Ref: ref}, identifierOpts{
+		wasOriginallyIdentifier: true,
+	}), true
+}
+
 func (p *parser) valueToSubstituteForRequire(loc logger.Loc) js_ast.Expr {
 	if p.source.Index != runtime.SourceIndex &&
 		config.ShouldCallRuntimeRequire(p.options.mode, p.options.outputFormat) {
@@ -1684,6 +1885,15 @@ func (p *parser) loadNameFromRef(ref js_ast.Ref) string {
 	}
 }
 
+func (p *parser) isDroppedLabel(name string) bool {
+	for _, label := range p.options.dropLabels {
+		if name == label {
+			return true
+		}
+	}
+	return false
+}
+
 // Due to ES6 destructuring patterns, there are many cases where it's
 // impossible to distinguish between an array or object literal and a
 // destructuring assignment until we hit the "=" operator later on.
@@ -1693,7 +1903,6 @@ type deferredErrors struct {
 	// These are errors for expressions
 	invalidExprDefaultValue  logger.Range
 	invalidExprAfterQuestion logger.Range
-	arraySpreadFeature       logger.Range
 
 	// These errors are for arrow functions
 	invalidParens []logger.Range
@@ -1706,9 +1915,6 @@ func (from *deferredErrors) mergeInto(to *deferredErrors) {
 	if from.invalidExprAfterQuestion.Len > 0 {
 		to.invalidExprAfterQuestion = from.invalidExprAfterQuestion
 	}
-	if from.arraySpreadFeature.Len > 0 {
-		to.arraySpreadFeature = from.arraySpreadFeature
-	}
 	if len(from.invalidParens) > 0 {
 		if len(to.invalidParens) > 0 {
 			to.invalidParens = append(to.invalidParens, from.invalidParens...)
@@ -1727,10 +1933,6 @@ func (p *parser) logExprErrors(errors *deferredErrors) {
 		r := errors.invalidExprAfterQuestion
 		p.log.AddError(&p.tracker, r, fmt.Sprintf("Unexpected %q", p.source.Contents[r.Loc.Start:r.Loc.Start+r.Len]))
 	}
-
-	if errors.arraySpreadFeature.Len > 0 {
-		p.markSyntaxFeature(compat.ArraySpread, errors.arraySpreadFeature)
-	}
 }
 
 func (p *parser) logDeferredArrowArgErrors(errors *deferredErrors) {
@@ -1864,11 +2066,12 @@ func (p *parser) checkForLegacyOctalLiteral(e js_ast.E) {
 }
 
 func (p *parser) notesForAssertTypeJSON(record *ast.ImportRecord, alias string) []logger.MsgData {
+	description := record.Assertions.Keyword.Description()
 	return []logger.MsgData{p.tracker.MsgData(
 		js_lexer.RangeOfImportAssertion(p.source, *ast.FindAssertion(record.Assertions.Entries, "type")),
-		"This is considered an import of a standard JSON module because of the import assertion here:"),
-		{Text: fmt.Sprintf("You can either keep the import assertion and only use the \"default\" import, "+
-			"or you can remove the import assertion and use the %q import (which is non-standard behavior).", alias)}}
+		fmt.Sprintf("This is considered an import of a standard JSON module because of the %s here:", description)),
+		{Text: fmt.Sprintf("You can either keep the %s and only use the \"default\" import, "+
+			"or you can remove the %s and use the %q import (which is non-standard behavior).", description, description, alias)}}
 }
 
 // This assumes the caller has already checked for TStringLiteral or TNoSubstitutionTemplateLiteral
@@ -1889,13 +2092,14 @@ func (p *parser) parseStringLiteral() js_ast.Expr {
 }
 
 type propertyOpts struct {
-	tsDecorators     []js_ast.Expr
-	tsDecoratorScope *js_ast.Scope
+	decorators     []js_ast.Expr
+	decoratorScope *js_ast.Scope
 
 	asyncRange     logger.Range
 	generatorRange logger.Range
 	tsDeclareRange logger.Range
 	classKeyword   logger.Range
+	accessorRange  logger.Range
 	isAsync        bool
 	isGenerator    bool
 
@@ -1912,6 +2116,12 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 	var closeBracketLoc logger.Loc
 	keyRange := p.lexer.Range()
 
+	// Flow: "class Foo { +x: number; -y: string }"
+	if p.options.ts.Flow && opts.isClass && (p.lexer.Token == js_lexer.TPlus || p.lexer.Token == js_lexer.TMinus) {
+		p.lexer.Next()
+		keyRange = p.lexer.Range()
+	}
+
 	switch p.lexer.Token {
 	case js_lexer.TNumericLiteral:
 		key = js_ast.Expr{Loc: p.lexer.Loc(), Data: &js_ast.ENumber{Value: p.lexer.Number}}
@@ -1930,7 +2140,7 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 		p.lexer.Next()
 
 	case js_lexer.TPrivateIdentifier:
-		if !opts.isClass || len(opts.tsDecorators) > 0 {
+		if !opts.isClass || (len(opts.decorators) > 0 && p.options.ts.Parse) {
 			p.lexer.Expected(js_lexer.TIdentifier)
 		}
 		if opts.tsDeclareRange.Len != 0 {
@@ -1941,7 +2151,6 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 
 	case js_lexer.TOpenBracket:
 		flags |= js_ast.PropertyIsComputed
-		p.markSyntaxFeature(compat.ObjectExtensions, p.lexer.Range())
 		p.lexer.Next()
 		wasIdentifier := p.lexer.Token == js_lexer.TIdentifier
 		expr := p.parseExpr(js_ast.LComma)
@@ -1997,11 +2206,15 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 				case js_lexer.TOpenBracket, js_lexer.TNumericLiteral, js_lexer.TStringLiteral,
 					js_lexer.TAsterisk, js_lexer.TPrivateIdentifier:
 					couldBeModifierKeyword = true
+
+				case js_lexer.TPlus, js_lexer.TMinus:
+					// Flow: "static +x: number"
+					couldBeModifierKeyword = p.options.ts.Flow && opts.isClass
 				}
 			}
 
-			// If so, check for a modifier keyword
-			if couldBeModifierKeyword {
+			// If so, check for a modifier keyword. Nothing can come after "accessor".
+			if couldBeModifierKeyword && opts.accessorRange.Len == 0 {
 				switch name.String {
 				case "get":
 					if !opts.isAsync && raw == name.String {
@@ -2028,6 +2241,12 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 						return p.parseProperty(startLoc, kind, opts, nil)
 					}
 
+				case "accessor":
+					if opts.isClass && !opts.isAsync && raw == name.String && !p.lexer.HasNewlineBefore {
+						opts.accessorRange = nameRange
+						return p.parseProperty(startLoc, kind, opts, nil)
+					}
+
 				case "declare":
 					if opts.isClass && p.options.ts.Parse && opts.tsDeclareRange.Len == 0 && raw == name.String {
 						opts.tsDeclareRange = nameRange
@@ -2054,6 +2273,7 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 
 				case "abstract":
 					if opts.isClass && p.options.ts.Parse && !opts.isTSAbstract && raw == name.String {
+						p.forbidTypeScriptSyntaxInFlow(nameRange, "TypeScript abstract class members are not supported in Flow files")
 						opts.isTSAbstract = true
 						scopeIndex := len(p.scopesInOrder)
 						p.parseProperty(startLoc, kind, opts, nil)
@@ -2064,6 +2284,7 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 				case "private", "protected", "public", "readonly", "override":
 					// Skip over TypeScript keywords
 					if opts.isClass && p.options.ts.Parse && raw == name.String {
+						p.forbidTypeScriptSyntaxInFlow(nameRange, fmt.Sprintf("The TypeScript %q modifier is not supported in Flow files", name.String))
 						return p.parseProperty(startLoc, kind, opts, nil)
 					}
 				}
@@ -2146,7 +2367,7 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 
 		// "class X { foo?<T>(): T }"
 		// "const x = { foo<T>(): T {} }"
-		p.skipTypeScriptTypeParameters(typeParametersNormal)
+		p.skipTypeScriptTypeParameters(allowConstModifier)
 	}
 
 	// Parse a class field with an optional initial value
@@ -2163,7 +2384,17 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 		}
 
 		// Skip over types
-		if p.options.ts.Parse && p.lexer.Token == js_lexer.TColon {
+		var tsMetadataTypeOrNil js_ast.Expr
+		if p.options.ts.Parse && p.options.emitDecoratorMetadata {
+			metadata := tsMetadata{kind: tsMetadataObject}
+			metadataLoc := p.lexer.Loc()
+			if p.lexer.Token == js_lexer.TColon {
+				p.lexer.Next()
+				metadataLoc = p.lexer.Loc()
+				metadata = p.skipTypeScriptTypeWithMetadata(0)
+			}
+			tsMetadataTypeOrNil = p.tsMetadataToExpr(metadataLoc, metadata)
+		} else if p.options.ts.Parse && p.lexer.Token == js_lexer.TColon {
 			p.lexer.Next()
 			p.skipTypeScriptType(js_ast.LLowest)
 		}
@@ -2190,12 +2421,27 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 				p.log.AddError(&p.tracker, keyRange, fmt.Sprintf("Invalid field name %q", name))
 			}
 			var declare js_ast.SymbolKind
-			if opts.isStatic {
+			if opts.accessorRange.Len != 0 {
+				// An auto-accessor acts like a getter/setter pair
+				if opts.isStatic {
+					declare = js_ast.SymbolPrivateStaticGetSetPair
+				} else {
+					declare = js_ast.SymbolPrivateGetSetPair
+				}
+			} else if opts.isStatic {
 				declare = js_ast.SymbolPrivateStaticField
 			} else {
 				declare = js_ast.SymbolPrivateField
 			}
 			private.Ref = p.declareSymbol(declare, key.Loc, name)
+			if opts.accessorRange.Len != 0 {
+				p.privateGetters[private.Ref] = p.newSymbol(js_ast.SymbolOther, name[1:]+"_get")
+				p.privateSetters[private.Ref] = p.newSymbol(js_ast.SymbolOther, name[1:]+"_set")
+			}
+		}
+
+		if opts.accessorRange.Len != 0 {
+			kind = js_ast.PropertyAutoAccessor
 		}
 
 		p.lexer.ExpectOrInsertSemicolon()
@@ -2203,22 +2449,22 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 			flags |= js_ast.PropertyIsStatic
 		}
 		return js_ast.Property{
-			TSDecorators:     opts.tsDecorators,
+			Decorators:       opts.decorators,
 			Loc:              startLoc,
 			Kind:             kind,
 			Flags:            flags,
 			Key:              key,
 			InitializerOrNil: initializerOrNil,
 			CloseBracketLoc:  closeBracketLoc,
+
+			TSMetadataTypeOrNil: tsMetadataTypeOrNil,
 		}, true
 	}
 
 	// Parse a method expression
 	if p.lexer.Token == js_lexer.TOpenParen || kind != js_ast.PropertyNormal ||
 		opts.isClass || opts.isAsync || opts.isGenerator {
-		hasError := false
-
-		if !hasError && opts.tsDeclareRange.Len != 0 {
+		if opts.tsDeclareRange.Len != 0 {
 			what := "method"
 			if kind == js_ast.PropertyGet {
 				what = "getter"
@@ -2226,19 +2472,14 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 				what = "setter"
 			}
 			p.log.AddError(&p.tracker, opts.tsDeclareRange, "\"declare\" cannot be used with a "+what)
-			hasError = true
 		}
 
-		if opts.isAsync && p.markAsyncFn(opts.asyncRange, opts.isGenerator) {
-			hasError = true
+		if opts.accessorRange.Len != 0 {
+			p.log.AddError(&p.tracker, opts.accessorRange, "\"accessor\" cannot be used with a method")
 		}
 
-		if !hasError && opts.isGenerator && p.markSyntaxFeature(compat.Generator, opts.generatorRange) {
-			hasError = true
-		}
-
-		if !hasError && p.lexer.Token == js_lexer.TOpenParen && kind != js_ast.PropertyGet && kind != js_ast.PropertySet && p.markSyntaxFeature(compat.ObjectExtensions, p.lexer.Range()) {
-			hasError = true
+		if opts.isAsync {
+			p.markAsyncFn(opts.asyncRange, opts.isGenerator)
 		}
 
 		loc := p.lexer.Loc()
@@ -2283,7 +2524,7 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 			yield:              yield,
 			allowSuperCall:     opts.classHasExtends && isConstructor,
 			allowSuperProperty: true,
-			tsDecoratorScope:   opts.tsDecoratorScope,
+			decoratorScope:     opts.decoratorScope,
 			isConstructor:      isConstructor,
 
 			// Only allow omitting the body if we're parsing TypeScript class
@@ -2299,6 +2540,37 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 
 		p.popScope()
 		fn.IsUniqueFormalParameters = true
+
+		// Methods also have types for "emitDecoratorMetadata"
+		var tsMetadataTypeOrNil js_ast.Expr
+		if p.options.ts.Parse && p.options.emitDecoratorMetadata && opts.decoratorScope != nil {
+			switch kind {
+			case js_ast.PropertyGet:
+				// The return type of a getter is the type of the property
+				tsMetadataTypeOrNil = fn.TSMetadataReturnTypeOrNil
+				fn.TSMetadataReturnTypeOrNil = js_ast.Expr{}
+				if tsMetadataTypeOrNil.Data == nil {
+					tsMetadataTypeOrNil = p.tsMetadataToExpr(key.Loc, tsMetadata{kind: tsMetadataObject})
+				}
+
+			case js_ast.PropertySet:
+				// The argument type of a setter is the type of the property
+				if len(fn.Args) > 0 {
+					tsMetadataTypeOrNil = cloneTSMetadataExpr(fn.Args[0].TSMetadataTypeOrNil)
+				}
+
+			default:
+				tsMetadataTypeOrNil = p.tsMetadataToExpr(key.Loc, tsMetadata{kind: tsMetadataFunction})
+				if fn.TSMetadataReturnTypeOrNil.Data == nil {
+					metadata := tsMetadata{kind: tsMetadataVoid}
+					if opts.isAsync {
+						metadata.kind = tsMetadataPromise
+					}
+					fn.TSMetadataReturnTypeOrNil = p.tsMetadataToExpr(key.Loc, metadata)
+				}
+			}
+		}
+
 		value := js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: fn}}
 
 		// Enforce argument rules for accessors
@@ -2363,13 +2635,15 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 			flags |= js_ast.PropertyIsStatic
 		}
 		return js_ast.Property{
-			TSDecorators:    opts.tsDecorators,
+			Decorators:      opts.decorators,
 			Loc:             startLoc,
 			Kind:            kind,
 			Flags:           flags | js_ast.PropertyIsMethod,
 			Key:             key,
 			ValueOrNil:      value,
 			CloseBracketLoc: closeBracketLoc,
+
+			TSMetadataTypeOrNil: tsMetadataTypeOrNil,
 		}, true
 	}
 
@@ -2723,14 +2997,10 @@ func (p *parser) parseAsyncPrefixExpr(asyncRange logger.Range, level js_ast.L, f
 func (p *parser) parseFnExpr(loc logger.Loc, isAsync bool, asyncRange logger.Range) js_ast.Expr {
 	p.lexer.Next()
 	isGenerator := p.lexer.Token == js_lexer.TAsterisk
-	hasError := false
 	if isAsync {
-		hasError = p.markAsyncFn(asyncRange, isGenerator)
+		p.markAsyncFn(asyncRange, isGenerator)
 	}
 	if isGenerator {
-		if !hasError {
-			p.markSyntaxFeature(compat.Generator, p.lexer.Range())
-		}
 		p.lexer.Next()
 	}
 	var name *js_ast.LocRef
@@ -2752,7 +3022,7 @@ func (p *parser) parseFnExpr(loc logger.Loc, isAsync bool, asyncRange logger.Ran
 
 	// Even anonymous functions can have TypeScript type parameters
 	if p.options.ts.Parse {
-		p.skipTypeScriptTypeParameters(typeParametersNormal)
+		p.skipTypeScriptTypeParameters(allowConstModifier)
 	}
 
 	await := allowIdent
@@ -2813,7 +3083,6 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 
 		if isSpread {
 			spreadRange = p.lexer.Range()
-			p.markSyntaxFeature(compat.RestArgument, spreadRange)
 			p.lexer.Next()
 		}
 
@@ -2888,6 +3157,11 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 			binding, initializerOrNil, log := p.convertExprToBindingAndInitializer(item, invalidLog, isSpread)
 			invalidLog = log
 			args = append(args, js_ast.Arg{Binding: binding, DefaultOrNil: initializerOrNil})
+
+			// Comments before a parameter are only kept when all comments are kept
+			if p.exprComments != nil && p.options.comments != config.CommentsAll {
+				delete(p.exprComments, binding.Loc)
+			}
 		}
 
 		// Avoid parsing TypeScript code like "a ? (1 + 2) : (3 + 4)" as an arrow
@@ -2912,11 +3186,6 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 				panic(js_lexer.LexerPanic{})
 			}
 
-			// Also report syntax features used in bindings
-			for _, entry := range invalidLog.syntaxFeatures {
-				p.markSyntaxFeature(entry.feature, entry.token)
-			}
-
 			await := allowIdent
 			if isAsync {
 				await = allowExpr
@@ -2938,8 +3207,9 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 	// parent scope as if the scope was never pushed in the first place.
 	p.popAndFlattenScope(scopeIndex)
 
-	// If this isn't an arrow function, then types aren't allowed
-	if typeColonRange.Len > 0 {
+	// If this isn't an arrow function, then types aren't allowed (except for
+	// Flow type casts, which look like "(x: any)")
+	if typeColonRange.Len > 0 && (!p.options.ts.Flow || len(items) != 1 || isAsync || spreadRange.Len > 0) {
 		p.log.AddError(&p.tracker, typeColonRange, "Unexpected \":\"")
 		panic(js_lexer.LexerPanic{})
 	}
@@ -2973,13 +3243,7 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 }
 
 type invalidLog struct {
-	invalidTokens  []logger.Range
-	syntaxFeatures []syntaxFeature
-}
-
-type syntaxFeature struct {
-	feature compat.JSFeature
-	token   logger.Range
+	invalidTokens []logger.Range
 }
 
 func (p *parser) convertExprToBindingAndInitializer(
@@ -2995,11 +3259,6 @@ func (p *parser) convertExprToBindingAndInitializer(
 		equalsRange := p.source.RangeOfOperatorBefore(initializerOrNil.Loc, "=")
 		if isSpread {
 			p.log.AddError(&p.tracker, equalsRange, "A rest argument cannot have a default initializer")
-		} else {
-			invalidLog.syntaxFeatures = append(invalidLog.syntaxFeatures, syntaxFeature{
-				feature: compat.DefaultArgument,
-				token:   equalsRange,
-			})
 		}
 	}
 	return binding, initializerOrNil, invalidLog
@@ -3021,15 +3280,16 @@ func (p *parser) convertExprToBinding(expr js_ast.Expr, invalidLog invalidLog) (
 		if e.CommaAfterSpread.Start != 0 {
 			invalidLog.invalidTokens = append(invalidLog.invalidTokens, logger.Range{Loc: e.CommaAfterSpread, Len: 1})
 		}
-		invalidLog.syntaxFeatures = append(invalidLog.syntaxFeatures,
-			syntaxFeature{feature: compat.Destructuring, token: p.source.RangeOfOperatorAfter(expr.Loc, "[")})
 		items := []js_ast.ArrayBinding{}
 		isSpread := false
 		for _, item := range e.Items {
 			if i, ok := item.Data.(*js_ast.ESpread); ok {
 				isSpread = true
 				item = i.Value
-				if _, ok := item.Data.(*js_ast.EIdentifier); !ok {
+
+				// Nested rest bindings are lowered along with all other destructuring
+				// patterns if destructuring itself isn't supported
+				if _, ok := item.Data.(*js_ast.EIdentifier); !ok && !p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
 					p.markSyntaxFeature(compat.NestedRestBinding, p.source.RangeOfOperatorAfter(item.Loc, "["))
 				}
 			}
@@ -3052,8 +3312,6 @@ func (p *parser) convertExprToBinding(expr js_ast.Expr, invalidLog invalidLog) (
 		if e.CommaAfterSpread.Start != 0 {
 			invalidLog.invalidTokens = append(invalidLog.invalidTokens, logger.Range{Loc: e.CommaAfterSpread, Len: 1})
 		}
-		invalidLog.syntaxFeatures = append(invalidLog.syntaxFeatures,
-			syntaxFeature{feature: compat.Destructuring, token: p.source.RangeOfOperatorAfter(expr.Loc, "{")})
 		properties := []js_ast.PropertyBinding{}
 		for _, property := range e.Properties {
 			if property.Flags.Has(js_ast.PropertyIsMethod) || property.Kind == js_ast.PropertyGet || property.Kind == js_ast.PropertySet {
@@ -3089,11 +3347,15 @@ func (p *parser) convertExprToBinding(expr js_ast.Expr, invalidLog invalidLog) (
 func (p *parser) saveExprCommentsHere() logger.Loc {
 	loc := p.lexer.Loc()
 	if p.exprComments != nil && len(p.lexer.CommentsBeforeToken) > 0 {
-		comments := make([]string, len(p.lexer.CommentsBeforeToken))
-		for i, comment := range p.lexer.CommentsBeforeToken {
-			comments[i] = p.source.CommentTextWithoutIndent(comment)
+		comments := make([]string, 0, len(p.lexer.CommentsBeforeToken))
+		for _, comment := range p.lexer.CommentsBeforeToken {
+			if text := p.source.CommentTextWithoutIndent(comment); p.isCommentKept(text) {
+				comments = append(comments, text)
+			}
+		}
+		if len(comments) > 0 {
+			p.exprComments[loc] = comments
 		}
-		p.exprComments[loc] = comments
 		p.lexer.CommentsBeforeToken = p.lexer.CommentsBeforeToken[0:]
 	}
 	return loc
@@ -3102,7 +3364,7 @@ func (p *parser) saveExprCommentsHere() logger.Loc {
 type exprFlag uint8
 
 const (
-	exprFlagTSDecorator exprFlag = 1 << iota
+	exprFlagDecorator exprFlag = 1 << iota
 	exprFlagForLoopInit
 	exprFlagForAwaitLoopInit
 )
@@ -3408,33 +3670,22 @@ func (p *parser) parsePrefix(level js_ast.L, errors *deferredErrors, flags exprF
 		return p.parseFnExpr(loc, false /* isAsync */, logger.Range{})
 
 	case js_lexer.TClass:
-		classKeyword := p.lexer.Range()
-		p.markSyntaxFeature(compat.Class, classKeyword)
-		p.lexer.Next()
-		var name *js_ast.LocRef
+		return p.parseClassExpr(loc, nil)
 
-		p.pushScopeForParsePass(js_ast.ScopeClassName, loc)
-
-		// Parse an optional class name
-		if p.lexer.Token == js_lexer.TIdentifier {
-			if nameText := p.lexer.Identifier.String; !p.options.ts.Parse || nameText != "implements" {
-				if p.fnOrArrowDataParse.await != allowIdent && nameText == "await" {
-					p.log.AddError(&p.tracker, p.lexer.Range(), "Cannot use \"await\" as an identifier here:")
-				}
-				name = &js_ast.LocRef{Loc: p.lexer.Loc(), Ref: p.newSymbol(js_ast.SymbolOther, nameText)}
-				p.lexer.Next()
+	case js_lexer.TAt:
+		// JavaScript decorators can be used with class expressions, but
+		// TypeScript's experimental decorators can't
+		if !p.options.ts.Parse {
+			scopeIndex := len(p.scopesInOrder)
+			decorators := p.parseDecorators(p.currentScope)
+			if p.lexer.Token != js_lexer.TClass {
+				p.logMisplacedDecoratorError(&deferredDecorators{values: decorators, scopeIndex: scopeIndex})
 			}
+			return p.parseClassExpr(loc, decorators)
 		}
 
-		// Even anonymous classes can have TypeScript type parameters
-		if p.options.ts.Parse {
-			p.skipTypeScriptTypeParameters(typeParametersNormal)
-		}
-
-		class := p.parseClass(classKeyword, name, parseClassOpts{})
-
-		p.popScope()
-		return js_ast.Expr{Loc: loc, Data: &js_ast.EClass{Class: class}}
+		p.lexer.Unexpected()
+		return js_ast.Expr{}
 
 	case js_lexer.TNew:
 		p.lexer.Next()
@@ -3446,7 +3697,6 @@ func (p *parser) parsePrefix(level js_ast.L, errors *deferredErrors, flags exprF
 				p.lexer.Unexpected()
 			}
 			r := logger.Range{Loc: loc, Len: p.lexer.Range().End() - loc.Start}
-			p.markSyntaxFeature(compat.NewTarget, r)
 			p.lexer.Next()
 			return js_ast.Expr{Loc: loc, Data: &js_ast.ENewTarget{Range: r}}
 		}
@@ -3484,11 +3734,6 @@ func (p *parser) parsePrefix(level js_ast.L, errors *deferredErrors, flags exprF
 				items = append(items, js_ast.Expr{Loc: p.lexer.Loc(), Data: js_ast.EMissingShared})
 
 			case js_lexer.TDotDotDot:
-				if errors != nil {
-					errors.arraySpreadFeature = p.lexer.Range()
-				} else {
-					p.markSyntaxFeature(compat.ArraySpread, p.lexer.Range())
-				}
 				dotsLoc := p.saveExprCommentsHere()
 				p.lexer.Next()
 				item := p.parseExprOrBindings(js_ast.LComma, &selfErrors)
@@ -3643,8 +3888,8 @@ func (p *parser) parsePrefix(level js_ast.L, errors *deferredErrors, flags exprF
 		//     <A>(x) => {}
 		//     <A = B>(x) => {}
 
-		if p.options.ts.Parse && p.options.jsx.Parse && p.isTSArrowFnJSX() {
-			p.skipTypeScriptTypeParameters(typeParametersNormal)
+		if p.options.ts.Parse && p.options.jsx.Parse && (p.isTSArrowFnJSX() || (p.options.ts.Flow && p.isFlowArrowFnJSX())) {
+			p.skipTypeScriptTypeParameters(allowConstModifier)
 			p.lexer.Expect(js_lexer.TOpenParen)
 			return p.parseParenExpr(loc, level, parenExprOpts{forceArrowFn: true})
 		}
@@ -4059,7 +4304,7 @@ func (p *parser) parseSuffix(left js_ast.Expr, level js_ast.L, errors *deferredE
 			//   }
 			//
 			// This matches the behavior of the TypeScript compiler.
-			if (flags & exprFlagTSDecorator) != 0 {
+			if (flags & exprFlagDecorator) != 0 {
 				return left
 			}
 
@@ -4141,6 +4386,7 @@ func (p *parser) parseSuffix(left js_ast.Expr, level js_ast.L, errors *deferredE
 			if !p.options.ts.Parse {
 				p.lexer.Unexpected()
 			}
+			p.forbidTypeScriptSyntaxInFlow(p.lexer.Range(), "TypeScript non-null assertions are not supported in Flow files")
 			p.lexer.Next()
 			optionalChain = oldOptionalChain
 
@@ -4568,14 +4814,39 @@ func (p *parser) parseSuffix(left js_ast.Expr, level js_ast.L, errors *deferredE
 func (p *parser) parseExprOrLetStmt(opts parseStmtOpts) (js_ast.Expr, js_ast.Stmt, []js_ast.Decl) {
 	letRange := p.lexer.Range()
 
-	if p.lexer.Token != js_lexer.TIdentifier || p.lexer.Raw() != "let" {
-		var flags exprFlag
-		if opts.isForLoopInit {
-			flags |= exprFlagForLoopInit
+	var flags exprFlag
+	if opts.isForLoopInit {
+		flags |= exprFlagForLoopInit
+	}
+	if opts.isForAwaitLoopInit {
+		flags |= exprFlagForAwaitLoopInit
+	}
+
+	// "await using x = y"
+	if p.lexer.IsContextualKeyword("await") && p.fnOrArrowDataParse.await == allowExpr && p.isAwaitUsingDecl(opts) {
+		if p.fnOrArrowDataParse.isTopLevel {
+			p.topLevelAwaitKeyword = letRange
 		}
-		if opts.isForAwaitLoopInit {
-			flags |= exprFlagForAwaitLoopInit
+		p.lexer.Next()
+		p.lexer.Next()
+		stmt, decls := p.parseUsingDecls(letRange.Loc, js_ast.LocalAwaitUsing, opts)
+		return js_ast.Expr{}, stmt, decls
+	}
+
+	// "using x = y"
+	if p.lexer.IsContextualKeyword("using") {
+		name := p.lexer.Identifier
+		p.lexer.Next()
+		if p.lexer.Token == js_lexer.TIdentifier && !p.lexer.HasNewlineBefore && (!opts.isForLoopInit || !p.lexer.IsContextualKeyword("of")) {
+			stmt, decls := p.parseUsingDecls(letRange.Loc, js_ast.LocalUsing, opts)
+			return js_ast.Expr{}, stmt, decls
 		}
+		ref := p.storeNameInRef(name)
+		expr := js_ast.Expr{Loc: letRange.Loc, Data: &js_ast.EIdentifier{Ref: ref}}
+		return p.parseSuffix(expr, js_ast.LLowest, nil, flags), js_ast.Stmt{}, nil
+	}
+
+	if p.lexer.Token != js_lexer.TIdentifier || p.lexer.Raw() != "let" {
 		return p.parseExprCommon(js_ast.LLowest, nil, flags), js_ast.Stmt{}, nil
 	}
 
@@ -4588,7 +4859,6 @@ func (p *parser) parseExprOrLetStmt(opts parseStmtOpts) (js_ast.Expr, js_ast.Stm
 			if opts.lexicalDecl != lexicalDeclAllowAll {
 				p.forbidLexicalDecl(letRange.Loc)
 			}
-			p.markSyntaxFeature(compat.ConstAndLet, letRange)
 			decls := p.parseAndDeclareDecls(js_ast.SymbolOther, opts)
 			return js_ast.Expr{}, js_ast.Stmt{Loc: letRange.Loc, Data: &js_ast.SLocal{
 				Kind:     js_ast.LocalLet,
@@ -4603,6 +4873,57 @@ func (p *parser) parseExprOrLetStmt(opts parseStmtOpts) (js_ast.Expr, js_ast.Stm
 	return p.parseSuffix(expr, js_ast.LLowest, nil, 0), js_ast.Stmt{}, nil
 }
 
+// This returns true if the upcoming tokens are "await using" followed by an
+// identifier without any newlines in between. The lexer is left unchanged.
+func (p *parser) isAwaitUsingDecl(opts parseStmtOpts) (result bool) {
+	oldLexer := p.lexer
+	p.lexer.IsLogDisabled = true
+
+	// Implement backtracking by restoring the lexer's memory to its original state
+	defer func() {
+		r := recover()
+		if _, isLexerPanic := r.(js_lexer.LexerPanic); !isLexerPanic && r != nil {
+			panic(r)
+		}
+		p.lexer = oldLexer
+	}()
+
+	p.lexer.Next()
+	if !p.lexer.IsContextualKeyword("using") || p.lexer.HasNewlineBefore {
+		return false
+	}
+	p.lexer.Next()
+	return p.lexer.Token == js_lexer.TIdentifier && !p.lexer.HasNewlineBefore && (!opts.isForLoopInit || !p.lexer.IsContextualKeyword("of"))
+}
+
+func (p *parser) parseUsingDecls(loc logger.Loc, kind js_ast.LocalKind, opts parseStmtOpts) (js_ast.Stmt, []js_ast.Decl) {
+	if opts.lexicalDecl != lexicalDeclAllowAll {
+		p.forbidLexicalDecl(loc)
+	}
+	decls := p.parseAndDeclareDecls(js_ast.SymbolConst, opts)
+	for _, decl := range decls {
+		if _, ok := decl.Binding.Data.(*js_ast.BIdentifier); !ok {
+			p.log.AddError(&p.tracker, logger.Range{Loc: decl.Binding.Loc},
+				fmt.Sprintf("Destructuring is not allowed in %q declarations", usingKeyword(kind)))
+		}
+	}
+	if !opts.isForLoopInit {
+		p.requireInitializers(decls)
+	}
+	return js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{
+		Kind:     kind,
+		Decls:    decls,
+		IsExport: opts.isExport,
+	}}, decls
+}
+
+func usingKeyword(kind js_ast.LocalKind) string {
+	if kind == js_ast.LocalAwaitUsing {
+		return "await using"
+	}
+	return "using"
+}
+
 func (p *parser) parseCallArgs() (args []js_ast.Expr, closeParenLoc logger.Loc, isMultiLine bool) {
 	// Allow "in" inside call arguments
 	oldAllowIn := p.allowIn
@@ -4617,7 +4938,6 @@ func (p *parser) parseCallArgs() (args []js_ast.Expr, closeParenLoc logger.Loc,
 		loc := p.lexer.Loc()
 		isSpread := p.lexer.Token == js_lexer.TDotDotDot
 		if isSpread {
-			p.markSyntaxFeature(compat.RestArgument, p.lexer.Range())
 			p.lexer.Next()
 		}
 		arg := p.parseExpr(js_ast.LComma)
@@ -4933,7 +5253,6 @@ func (p *parser) parseJSXElement(loc logger.Loc) js_ast.Expr {
 					// behavior. Note that TypeScript's behavior changed in TypeScript 4.5.
 					// Before that, the "..." was omitted instead of being preserved.
 					itemLoc := p.lexer.Loc()
-					p.markSyntaxFeature(compat.RestArgument, p.lexer.Range())
 					p.lexer.Next()
 					children = append(children, js_ast.Expr{Loc: itemLoc, Data: &js_ast.ESpread{Value: p.parseExpr(js_ast.LLowest)}})
 				} else {
@@ -5149,7 +5468,10 @@ func (p *parser) parseImportClause() ([]js_ast.ClauseItem, bool) {
 		// "import { type as } from 'mod'"
 		// "import { type as as } from 'mod'"
 		// "import { type as as as } from 'mod'"
-		if p.options.ts.Parse && alias.String == "type" && p.lexer.Token != js_lexer.TComma && p.lexer.Token != js_lexer.TCloseBrace {
+		//
+		// Flow also has "import { typeof xx } from 'mod'"
+		if p.options.ts.Parse && (alias.String == "type" || (p.options.ts.Flow && alias.String == "typeof")) &&
+			p.lexer.Token != js_lexer.TComma && p.lexer.Token != js_lexer.TCloseBrace {
 			if p.lexer.IsContextualKeyword("as") {
 				p.lexer.Next()
 				if p.lexer.IsContextualKeyword("as") {
@@ -5408,7 +5730,6 @@ func (p *parser) parseBinding() js_ast.Binding {
 		return js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}
 
 	case js_lexer.TOpenBracket:
-		p.markSyntaxFeature(compat.Destructuring, p.lexer.Range())
 		p.lexer.Next()
 		isSingleLine := !p.lexer.HasNewlineBefore
 		items := []js_ast.ArrayBinding{}
@@ -5432,8 +5753,10 @@ func (p *parser) parseBinding() js_ast.Binding {
 					p.lexer.Next()
 					hasSpread = true
 
-					// This was a bug in the ES2015 spec that was fixed in ES2016
-					if p.lexer.Token != js_lexer.TIdentifier {
+					// This was a bug in the ES2015 spec that was fixed in ES2016. Nested
+					// rest bindings are lowered along with all other destructuring
+					// patterns if destructuring itself isn't supported.
+					if p.lexer.Token != js_lexer.TIdentifier && !p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
 						p.markSyntaxFeature(compat.NestedRestBinding, p.lexer.Range())
 					}
 				}
@@ -5487,7 +5810,6 @@ func (p *parser) parseBinding() js_ast.Binding {
 		}}
 
 	case js_lexer.TOpenBrace:
-		p.markSyntaxFeature(compat.Destructuring, p.lexer.Range())
 		p.lexer.Next()
 		isSingleLine := !p.lexer.HasNewlineBefore
 		properties := []js_ast.PropertyBinding{}
@@ -5546,6 +5868,9 @@ func (p *parser) parseFn(name *js_ast.LocRef, classKeyword logger.Range, data fn
 	fn.OpenParenLoc = p.lexer.Loc()
 	p.lexer.Expect(js_lexer.TOpenParen)
 
+	// TypeScript class methods may need type information for their decorators
+	emitDecoratorMetadata := p.options.ts.Parse && p.options.emitDecoratorMetadata && data.decoratorScope != nil
+
 	// Await and yield are not allowed in function arguments
 	oldFnOrArrowData := p.fnOrArrowDataParse
 	if data.await == allowExpr {
@@ -5581,8 +5906,8 @@ func (p *parser) parseFn(name *js_ast.LocRef, classKeyword logger.Range, data fn
 			continue
 		}
 
-		var tsDecorators []js_ast.Expr
-		if data.tsDecoratorScope != nil {
+		var decorators []js_ast.Expr
+		if data.decoratorScope != nil && p.options.ts.Parse {
 			oldAwait := p.fnOrArrowDataParse.await
 			oldNeedsAsyncLoc := p.fnOrArrowDataParse.needsAsyncLoc
 
@@ -5633,7 +5958,7 @@ func (p *parser) parseFn(name *js_ast.LocRef, classKeyword logger.Range, data fn
 				p.fnOrArrowDataParse.needsAsyncLoc = oldFnOrArrowData.needsAsyncLoc
 			}
 
-			tsDecorators = p.parseTypeScriptDecorators(data.tsDecoratorScope)
+			decorators = p.parseDecorators(data.decoratorScope)
 
 			p.fnOrArrowDataParse.await = oldAwait
 			p.fnOrArrowDataParse.needsAsyncLoc = oldNeedsAsyncLoc
@@ -5642,14 +5967,19 @@ func (p *parser) parseFn(name *js_ast.LocRef, classKeyword logger.Range, data fn
 		}
 
 		if !fn.HasRestArg && p.lexer.Token == js_lexer.TDotDotDot {
-			p.markSyntaxFeature(compat.RestArgument, p.lexer.Range())
 			p.lexer.Next()
 			fn.HasRestArg = true
 		}
 
+		var tsMetadataTypeOrNil js_ast.Expr
 		isTypeScriptCtorField := false
 		isIdentifier := p.lexer.Token == js_lexer.TIdentifier
 		text := p.lexer.Identifier.String
+
+		// Comments before a parameter are only kept when all comments are kept
+		if p.options.comments == config.CommentsAll {
+			p.saveExprCommentsHere()
+		}
 		arg := p.parseBinding()
 
 		if p.options.ts.Parse {
@@ -5661,6 +5991,10 @@ func (p *parser) parseFn(name *js_ast.LocRef, classKeyword logger.Range, data fn
 					if text != "public" && text != "private" && text != "protected" && text != "readonly" && text != "override" {
 						break
 					}
+					if !isTypeScriptCtorField {
+						p.forbidTypeScriptSyntaxInFlow(js_lexer.RangeOfIdentifier(p.source, arg.Loc),
+							"TypeScript parameter properties are not supported in Flow files")
+					}
 					isTypeScriptCtorField = true
 
 					// TypeScript requires an identifier binding
@@ -5680,7 +6014,26 @@ func (p *parser) parseFn(name *js_ast.LocRef, classKeyword logger.Range, data fn
 			}
 
 			// "function foo(a: any) {}"
-			if p.lexer.Token == js_lexer.TColon {
+			if emitDecoratorMetadata {
+				metadata := tsMetadata{kind: tsMetadataObject}
+				metadataLoc := p.lexer.Loc()
+				if p.lexer.Token == js_lexer.TColon {
+					p.lexer.Next()
+					metadataLoc = p.lexer.Loc()
+					metadata = p.skipTypeScriptTypeWithMetadata(0)
+				}
+
+				// "function foo(...args: number[]) {}"
+				if fn.HasRestArg {
+					if metadata.kind == tsMetadataArray && metadata.elements != nil {
+						metadata = *metadata.elements
+					} else {
+						metadata = tsMetadata{kind: tsMetadataObject}
+					}
+				}
+
+				tsMetadataTypeOrNil = p.tsMetadataToExpr(metadataLoc, metadata)
+			} else if p.lexer.Token == js_lexer.TColon {
 				p.lexer.Next()
 				p.skipTypeScriptType(js_ast.LLowest)
 			}
@@ -5690,18 +6043,19 @@ func (p *parser) parseFn(name *js_ast.LocRef, classKeyword logger.Range, data fn
 
 		var defaultValueOrNil js_ast.Expr
 		if !fn.HasRestArg && p.lexer.Token == js_lexer.TEquals {
-			p.markSyntaxFeature(compat.DefaultArgument, p.lexer.Range())
 			p.lexer.Next()
 			defaultValueOrNil = p.parseExpr(js_ast.LComma)
 		}
 
 		fn.Args = append(fn.Args, js_ast.Arg{
-			TSDecorators: tsDecorators,
+			Decorators:   decorators,
 			Binding:      arg,
 			DefaultOrNil: defaultValueOrNil,
 
 			// We need to track this because it affects code generation
 			IsTypeScriptCtorField: isTypeScriptCtorField,
+
+			TSMetadataTypeOrNil: tsMetadataTypeOrNil,
 		})
 
 		if p.lexer.Token != js_lexer.TComma {
@@ -5735,7 +6089,12 @@ func (p *parser) parseFn(name *js_ast.LocRef, classKeyword logger.Range, data fn
 	// "function foo(): any {}"
 	if p.options.ts.Parse && p.lexer.Token == js_lexer.TColon {
 		p.lexer.Next()
-		p.skipTypeScriptReturnType()
+		if emitDecoratorMetadata {
+			metadataLoc := p.lexer.Loc()
+			fn.TSMetadataReturnTypeOrNil = p.tsMetadataToExpr(metadataLoc, p.skipTypeScriptTypeWithMetadata(isReturnTypeFlag))
+		} else {
+			p.skipTypeScriptReturnType()
+		}
 	}
 
 	// "function foo(): any;"
@@ -5777,11 +6136,44 @@ func (p *parser) validateDeclaredSymbolName(loc logger.Loc, name string) {
 	}
 }
 
+func (p *parser) parseClassExpr(loc logger.Loc, decorators []js_ast.Expr) js_ast.Expr {
+	classKeyword := p.lexer.Range()
+	p.lexer.Expect(js_lexer.TClass)
+	var name *js_ast.LocRef
+
+	p.pushScopeForParsePass(js_ast.ScopeClassName, loc)
+
+	// Parse an optional class name
+	if p.lexer.Token == js_lexer.TIdentifier {
+		if nameText := p.lexer.Identifier.String; !p.options.ts.Parse || nameText != "implements" {
+			if p.fnOrArrowDataParse.await != allowIdent && nameText == "await" {
+				p.log.AddError(&p.tracker, p.lexer.Range(), "Cannot use \"await\" as an identifier here:")
+			}
+			name = &js_ast.LocRef{Loc: p.lexer.Loc(), Ref: p.newSymbol(js_ast.SymbolOther, nameText)}
+			p.lexer.Next()
+		}
+	}
+
+	// Even anonymous classes can have TypeScript type parameters
+	if p.options.ts.Parse {
+		p.skipTypeScriptTypeParameters(allowConstModifier)
+	}
+
+	// Members of class expressions can only have JavaScript decorators
+	classOpts := parseClassOpts{decorators: decorators}
+	if !p.options.ts.Parse {
+		classOpts.decoratorScope = p.currentScope.Parent
+	}
+	class := p.parseClass(classKeyword, name, classOpts)
+
+	p.popScope()
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EClass{Class: class}}
+}
+
 func (p *parser) parseClassStmt(loc logger.Loc, opts parseStmtOpts) js_ast.Stmt {
 	var name *js_ast.LocRef
 	classKeyword := p.lexer.Range()
 	if p.lexer.Token == js_lexer.TClass {
-		p.markSyntaxFeature(compat.Class, classKeyword)
 		p.lexer.Next()
 	} else {
 		p.lexer.Expected(js_lexer.TClass)
@@ -5802,15 +6194,15 @@ func (p *parser) parseClassStmt(loc logger.Loc, opts parseStmtOpts) js_ast.Stmt
 
 	// Even anonymous classes can have TypeScript type parameters
 	if p.options.ts.Parse {
-		p.skipTypeScriptTypeParameters(typeParametersWithInOutVarianceAnnotations)
+		p.skipTypeScriptTypeParameters(allowInOutVarianceAnnotations | allowConstModifier)
 	}
 
 	classOpts := parseClassOpts{
-		tsDecoratorScope:    p.currentScope,
+		decoratorScope:      p.currentScope,
 		isTypeScriptDeclare: opts.isTypeScriptDeclare,
 	}
-	if opts.tsDecorators != nil {
-		classOpts.tsDecorators = opts.tsDecorators.values
+	if opts.decorators != nil {
+		classOpts.decorators = opts.decorators.values
 	}
 	scopeIndex := p.pushScopeForParsePass(js_ast.ScopeClassName, loc)
 	class := p.parseClass(classKeyword, name, classOpts)
@@ -5830,8 +6222,8 @@ func (p *parser) parseClassStmt(loc logger.Loc, opts parseStmtOpts) js_ast.Stmt
 }
 
 type parseClassOpts struct {
-	tsDecorators        []js_ast.Expr
-	tsDecoratorScope    *js_ast.Scope
+	decorators          []js_ast.Expr
+	decoratorScope      *js_ast.Scope
 	isTypeScriptDeclare bool
 }
 
@@ -5881,10 +6273,10 @@ func (p *parser) parseClass(classKeyword logger.Range, name *js_ast.LocRef, clas
 	scopeIndex := p.pushScopeForParsePass(js_ast.ScopeClassBody, bodyLoc)
 
 	opts := propertyOpts{
-		isClass:          true,
-		tsDecoratorScope: classOpts.tsDecoratorScope,
-		classHasExtends:  extendsOrNil.Data != nil,
-		classKeyword:     classKeyword,
+		isClass:         true,
+		decoratorScope:  classOpts.decoratorScope,
+		classHasExtends: extendsOrNil.Data != nil,
+		classKeyword:    classKeyword,
 	}
 	hasConstructor := false
 
@@ -5896,10 +6288,10 @@ func (p *parser) parseClass(classKeyword logger.Range, name *js_ast.LocRef, clas
 
 		// Parse decorators for this property
 		firstDecoratorLoc := p.lexer.Loc()
-		if opts.tsDecoratorScope != nil {
-			opts.tsDecorators = p.parseTypeScriptDecorators(opts.tsDecoratorScope)
+		if opts.decoratorScope != nil {
+			opts.decorators = p.parseDecorators(opts.decoratorScope)
 		} else {
-			opts.tsDecorators = nil
+			opts.decorators = nil
 			p.logInvalidDecoratorError(classKeyword)
 		}
 
@@ -5907,11 +6299,22 @@ func (p *parser) parseClass(classKeyword logger.Range, name *js_ast.LocRef, clas
 		if property, ok := p.parseProperty(p.saveExprCommentsHere(), js_ast.PropertyNormal, opts, nil); ok {
 			properties = append(properties, property)
 
+			// Forbid decorators on class static blocks
+			if property.Kind == js_ast.PropertyClassStaticBlock && len(opts.decorators) > 0 {
+				p.log.AddError(&p.tracker, logger.Range{Loc: firstDecoratorLoc},
+					"Decorators are not allowed on class static blocks")
+			}
+
 			// Forbid decorators on class constructors
 			if key, ok := property.Key.Data.(*js_ast.EString); ok && helpers.UTF16EqualsString(key.Value, "constructor") {
-				if len(opts.tsDecorators) > 0 {
-					p.log.AddError(&p.tracker, logger.Range{Loc: firstDecoratorLoc},
-						"TypeScript does not allow decorators on class constructors")
+				if len(opts.decorators) > 0 {
+					if p.options.ts.Parse {
+						p.log.AddError(&p.tracker, logger.Range{Loc: firstDecoratorLoc},
+							"TypeScript does not allow decorators on class constructors")
+					} else {
+						p.log.AddError(&p.tracker, logger.Range{Loc: firstDecoratorLoc},
+							"Decorators are not allowed on class constructors")
+					}
 				}
 				if property.Flags.Has(js_ast.PropertyIsMethod) && !property.Flags.Has(js_ast.PropertyIsStatic) && !property.Flags.Has(js_ast.PropertyIsComputed) {
 					if hasConstructor {
@@ -5938,7 +6341,7 @@ func (p *parser) parseClass(classKeyword logger.Range, name *js_ast.LocRef, clas
 	p.lexer.Expect(js_lexer.TCloseBrace)
 	return js_ast.Class{
 		ClassKeyword:  classKeyword,
-		TSDecorators:  classOpts.tsDecorators,
+		Decorators:    classOpts.decorators,
 		Name:          name,
 		ExtendsOrNil:  extendsOrNil,
 		BodyLoc:       bodyLoc,
@@ -5967,12 +6370,17 @@ func (p *parser) parsePath() (logger.Loc, string, *ast.ImportAssertions, ast.Imp
 		p.lexer.Expect(js_lexer.TStringLiteral)
 	}
 
-	// See https://github.com/tc39/proposal-import-assertions for more info
+	// See https://github.com/tc39/proposal-import-attributes for more info
 	var assertions *ast.ImportAssertions
-	if !p.lexer.HasNewlineBefore && p.lexer.IsContextualKeyword("assert") {
+	if p.lexer.Token == js_lexer.TWith || (!p.lexer.HasNewlineBefore && p.lexer.IsContextualKeyword("assert")) {
 		// "import './foo.json' assert { type: 'json' }"
+		// "import './foo.json' with { type: 'json' }"
 		var entries []ast.AssertEntry
 		duplicates := make(map[string]logger.Range)
+		keyword := ast.AssertKeyword
+		if p.lexer.Token == js_lexer.TWith {
+			keyword = ast.WithKeyword
+		}
 		assertLoc := p.saveExprCommentsHere()
 		p.lexer.Next()
 		openBraceLoc := p.saveExprCommentsHere()
@@ -5995,7 +6403,7 @@ func (p *parser) parsePath() (logger.Loc, string, *ast.ImportAssertions, ast.Imp
 				p.lexer.Expect(js_lexer.TIdentifier)
 			}
 			if prevRange, ok := duplicates[keyText]; ok {
-				p.log.AddErrorWithNotes(&p.tracker, p.lexer.Range(), fmt.Sprintf("Duplicate import assertion %q", keyText),
+				p.log.AddErrorWithNotes(&p.tracker, p.lexer.Range(), fmt.Sprintf("Duplicate %s %q", keyword.Description(), keyText),
 					[]logger.MsgData{p.tracker.MsgData(prevRange, fmt.Sprintf("The first %q was here:", keyText))})
 			}
 			duplicates[keyText] = p.lexer.Range()
@@ -6015,7 +6423,7 @@ func (p *parser) parsePath() (logger.Loc, string, *ast.ImportAssertions, ast.Imp
 				PreferQuotedKey: preferQuotedKey,
 			})
 
-			// Using "assert: { type: 'json' }" triggers special behavior
+			// Using "assert { type: 'json' }" or "with { type: 'json' }" triggers special behavior
 			if helpers.UTF16EqualsString(key, "type") && helpers.UTF16EqualsString(value, "json") {
 				flags |= ast.AssertTypeJSON
 			}
@@ -6030,6 +6438,7 @@ func (p *parser) parsePath() (logger.Loc, string, *ast.ImportAssertions, ast.Imp
 		p.lexer.Expect(js_lexer.TCloseBrace)
 		assertions = &ast.ImportAssertions{
 			Entries:            entries,
+			Keyword:            keyword,
 			AssertLoc:          assertLoc,
 			InnerOpenBraceLoc:  openBraceLoc,
 			InnerCloseBraceLoc: closeBraceLoc,
@@ -6042,14 +6451,10 @@ func (p *parser) parsePath() (logger.Loc, string, *ast.ImportAssertions, ast.Imp
 // This assumes the "function" token has already been parsed
 func (p *parser) parseFnStmt(loc logger.Loc, opts parseStmtOpts, isAsync bool, asyncRange logger.Range) js_ast.Stmt {
 	isGenerator := p.lexer.Token == js_lexer.TAsterisk
-	hasError := false
 	if isAsync {
-		hasError = p.markAsyncFn(asyncRange, isGenerator)
+		p.markAsyncFn(asyncRange, isGenerator)
 	}
 	if isGenerator {
-		if !hasError {
-			p.markSyntaxFeature(compat.Generator, p.lexer.Range())
-		}
 		p.lexer.Next()
 	}
 
@@ -6080,7 +6485,7 @@ func (p *parser) parseFnStmt(loc logger.Loc, opts parseStmtOpts, isAsync bool, a
 
 	// Even anonymous functions can have TypeScript type parameters
 	if p.options.ts.Parse {
-		p.skipTypeScriptTypeParameters(typeParametersNormal)
+		p.skipTypeScriptTypeParameters(allowConstModifier)
 	}
 
 	// Introduce a fake block scope for function declarations inside if statements
@@ -6154,7 +6559,7 @@ func (p *parser) parseFnStmt(loc logger.Loc, opts parseStmtOpts, isAsync bool, a
 	return js_ast.Stmt{Loc: loc, Data: &js_ast.SFunction{Fn: fn, IsExport: opts.isExport}}
 }
 
-type deferredTSDecorators struct {
+type deferredDecorators struct {
 	values []js_ast.Expr
 
 	// If this turns out to be a "declare class" statement, we need to undo the
@@ -6172,7 +6577,7 @@ const (
 )
 
 type parseStmtOpts struct {
-	tsDecorators           *deferredTSDecorators
+	decorators             *deferredDecorators
 	lexicalDecl            lexicalDecl
 	isModuleScope          bool
 	isNamespaceScope       bool
@@ -6211,9 +6616,9 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 		// "@decorator export default abstract class Foo {}"
 		// "@decorator export declare class Foo {}"
 		// "@decorator export declare abstract class Foo {}"
-		if opts.tsDecorators != nil && p.lexer.Token != js_lexer.TClass && p.lexer.Token != js_lexer.TDefault &&
+		if opts.decorators != nil && p.lexer.Token != js_lexer.TClass && p.lexer.Token != js_lexer.TDefault &&
 			!p.lexer.IsContextualKeyword("abstract") && !p.lexer.IsContextualKeyword("declare") {
-			p.logMisplacedDecoratorError(opts.tsDecorators)
+			p.logMisplacedDecoratorError(opts.decorators)
 		}
 
 		switch p.lexer.Token {
@@ -6221,6 +6626,12 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 			opts.isExport = true
 			return p.parseStmt(opts)
 
+		case js_lexer.TAt:
+			// JavaScript decorators can also come after "export"
+			// "export @decorator class Foo {}"
+			opts.isExport = true
+			return p.parseStmt(opts)
+
 		case js_lexer.TImport:
 			// "export import foo = bar"
 			if p.options.ts.Parse && (opts.isModuleScope || opts.isNamespaceScope) {
@@ -6281,6 +6692,15 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 					p.skipTypeScriptTypeStmt(parseStmtOpts{isModuleScope: opts.isModuleScope, isExport: true})
 					return js_ast.Stmt{Loc: loc, Data: &js_ast.STypeScript{}}
 
+				case "opaque":
+					// "export opaque type Foo = ..."
+					if p.options.ts.Flow {
+						p.lexer.Next()
+						p.lexer.ExpectContextualKeyword("type")
+						p.skipFlowOpaqueTypeStmt(parseStmtOpts{isModuleScope: opts.isModuleScope})
+						return js_ast.Stmt{Loc: loc, Data: &js_ast.STypeScript{}}
+					}
+
 				case "namespace", "abstract", "module", "interface":
 					// "export namespace Foo {}"
 					// "export abstract class Foo {}"
@@ -6317,11 +6737,22 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 				return defaultName
 			}
 
-			// TypeScript decorators only work on class declarations
+			// JavaScript decorators can also come after "export default"
+			// "export default @decorator class Foo {}"
+			if p.lexer.Token == js_lexer.TAt && opts.decorators == nil {
+				scopeIndex := len(p.scopesInOrder)
+				decorators := p.parseDecorators(p.currentScope)
+				opts.decorators = &deferredDecorators{
+					values:     decorators,
+					scopeIndex: scopeIndex,
+				}
+			}
+
+			// Decorators only work on class declarations
 			// "@decorator export default class Foo {}"
 			// "@decorator export default abstract class Foo {}"
-			if opts.tsDecorators != nil && p.lexer.Token != js_lexer.TClass && !p.lexer.IsContextualKeyword("abstract") {
-				p.logMisplacedDecoratorError(opts.tsDecorators)
+			if opts.decorators != nil && p.lexer.Token != js_lexer.TClass && !p.lexer.IsContextualKeyword("abstract") {
+				p.logMisplacedDecoratorError(opts.decorators)
 			}
 
 			if p.lexer.IsContextualKeyword("async") {
@@ -6358,7 +6789,7 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 
 			if p.lexer.Token == js_lexer.TFunction || p.lexer.Token == js_lexer.TClass || p.lexer.IsContextualKeyword("interface") {
 				stmt := p.parseStmt(parseStmtOpts{
-					tsDecorators:   opts.tsDecorators,
+					decorators:     opts.decorators,
 					isNameOptional: true,
 					lexicalDecl:    lexicalDeclAllowAll,
 				})
@@ -6394,9 +6825,9 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 
 			// Handle the default export of an abstract class in TypeScript
 			if p.options.ts.Parse && isIdentifier && name == "abstract" {
-				if _, ok := expr.Data.(*js_ast.EIdentifier); ok && (p.lexer.Token == js_lexer.TClass || opts.tsDecorators != nil) {
+				if _, ok := expr.Data.(*js_ast.EIdentifier); ok && (p.lexer.Token == js_lexer.TClass || opts.decorators != nil) {
 					stmt := p.parseClassStmt(loc, parseStmtOpts{
-						tsDecorators:   opts.tsDecorators,
+						decorators:     opts.decorators,
 						isNameOptional: true,
 					})
 
@@ -6514,28 +6945,29 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 		if !p.options.ts.Parse {
 			p.lexer.Unexpected()
 		}
+		p.forbidTypeScriptSyntaxInFlow(p.lexer.Range(), "Flow enums are not supported")
 		return p.parseTypeScriptEnumStmt(loc, opts)
 
 	case js_lexer.TAt:
 		// Parse decorators before class statements, which are potentially exported
-		if p.options.ts.Parse {
-			scopeIndex := len(p.scopesInOrder)
-			tsDecorators := p.parseTypeScriptDecorators(p.currentScope)
+		scopeIndex := len(p.scopesInOrder)
+		decorators := p.parseDecorators(p.currentScope)
 
-			// If this turns out to be a "declare class" statement, we need to undo the
-			// scopes that were potentially pushed while parsing the decorator arguments.
-			// That can look like any one of the following:
-			//
-			//   "@decorator declare class Foo {}"
-			//   "@decorator declare abstract class Foo {}"
-			//   "@decorator export declare class Foo {}"
-			//   "@decorator export declare abstract class Foo {}"
-			//
-			opts.tsDecorators = &deferredTSDecorators{
-				values:     tsDecorators,
-				scopeIndex: scopeIndex,
-			}
+		// If this turns out to be a "declare class" statement, we need to undo the
+		// scopes that were potentially pushed while parsing the decorator arguments.
+		// That can look like any one of the following:
+		//
+		//   "@decorator declare class Foo {}"
+		//   "@decorator declare abstract class Foo {}"
+		//   "@decorator export declare class Foo {}"
+		//   "@decorator export declare abstract class Foo {}"
+		//
+		opts.decorators = &deferredDecorators{
+			values:     decorators,
+			scopeIndex: scopeIndex,
+		}
 
+		if p.options.ts.Parse {
 			// "@decorator class Foo {}"
 			// "@decorator abstract class Foo {}"
 			// "@decorator declare class Foo {}"
@@ -6546,16 +6978,23 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 			// "@decorator export declare abstract class Foo {}"
 			// "@decorator export default class Foo {}"
 			// "@decorator export default abstract class Foo {}"
-			if p.lexer.Token != js_lexer.TClass && p.lexer.Token != js_lexer.TExport &&
+			// "export @decorator class Foo {}"
+			// "export @decorator abstract class Foo {}"
+			if p.lexer.Token != js_lexer.TClass && (p.lexer.Token != js_lexer.TExport || opts.isExport) &&
 				!p.lexer.IsContextualKeyword("abstract") && !p.lexer.IsContextualKeyword("declare") {
-				p.logMisplacedDecoratorError(opts.tsDecorators)
+				p.logMisplacedDecoratorError(opts.decorators)
+			}
+		} else {
+			// "@decorator class Foo {}"
+			// "@decorator export class Foo {}"
+			// "@decorator export default class Foo {}"
+			// "export @decorator class Foo {}"
+			if p.lexer.Token != js_lexer.TClass && (p.lexer.Token != js_lexer.TExport || opts.isExport) {
+				p.logMisplacedDecoratorError(opts.decorators)
 			}
-
-			return p.parseStmt(opts)
 		}
 
-		p.lexer.Unexpected()
-		return js_ast.Stmt{}
+		return p.parseStmt(opts)
 
 	case js_lexer.TClass:
 		if opts.lexicalDecl != lexicalDeclAllowAll {
@@ -6577,7 +7016,6 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 		if opts.lexicalDecl != lexicalDeclAllowAll {
 			p.forbidLexicalDecl(loc)
 		}
-		p.markSyntaxFeature(compat.ConstAndLet, p.lexer.Range())
 		p.lexer.Next()
 
 		if p.options.ts.Parse && p.lexer.Token == js_lexer.TEnum {
@@ -6796,16 +7234,9 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 				p.log.AddError(&p.tracker, awaitRange, "Cannot use \"await\" outside an async function")
 				awaitRange = logger.Range{}
 			} else {
-				didGenerateError := false
 				if p.fnOrArrowDataParse.isTopLevel {
 					p.topLevelAwaitKeyword = awaitRange
 				}
-				if !didGenerateError && p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) && p.options.unsupportedJSFeatures.Has(compat.Generator) {
-					// If for-await loops aren't supported, then we only support lowering
-					// if either async/await or generators is supported. Otherwise we
-					// cannot lower for-await loops.
-					p.markSyntaxFeature(compat.ForAwait, awaitRange)
-				}
 			}
 			p.lexer.Next()
 		}
@@ -6834,7 +7265,6 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 			initOrNil = js_ast.Stmt{Loc: initLoc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: decls}}
 
 		case js_lexer.TConst:
-			p.markSyntaxFeature(compat.ConstAndLet, p.lexer.Range())
 			p.lexer.Next()
 			decls = p.parseAndDeclareDecls(js_ast.SymbolConst, parseStmtOpts{})
 			initOrNil = js_ast.Stmt{Loc: initLoc, Data: &js_ast.SLocal{Kind: js_ast.LocalConst, Decls: decls}}
@@ -6873,7 +7303,6 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 				}
 			}
 			p.forbidInitializers(decls, "of", false)
-			p.markSyntaxFeature(compat.ForOf, p.lexer.Range())
 			p.lexer.Next()
 			value := p.parseExpr(js_ast.LComma)
 			p.lexer.Expect(js_lexer.TCloseParen)
@@ -6883,6 +7312,10 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 
 		// Detect for-in loops
 		if p.lexer.Token == js_lexer.TIn {
+			if local, ok := initOrNil.Data.(*js_ast.SLocal); ok && local.Kind.IsUsing() {
+				p.log.AddError(&p.tracker, js_lexer.RangeOfIdentifier(p.source, initOrNil.Loc),
+					fmt.Sprintf("%q declarations are not allowed in for-in loops", usingKeyword(local.Kind)))
+			}
 			p.forbidInitializers(decls, "in", isVar)
 			p.lexer.Next()
 			value := p.parseExpr(js_ast.LLowest)
@@ -6891,8 +7324,8 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 			return js_ast.Stmt{Loc: loc, Data: &js_ast.SForIn{Init: initOrNil, Value: value, Body: body}}
 		}
 
-		// Only require "const" statement initializers when we know we're a normal for loop
-		if local, ok := initOrNil.Data.(*js_ast.SLocal); ok && local.Kind == js_ast.LocalConst {
+		// Only require "const" and "using" statement initializers when we know we're a normal for loop
+		if local, ok := initOrNil.Data.(*js_ast.SLocal); ok && (local.Kind == js_ast.LocalConst || local.Kind.IsUsing()) {
 			p.requireInitializers(decls)
 		}
 
@@ -6975,7 +7408,18 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 			stmt.IsSingleLine = isSingleLine
 			p.lexer.ExpectContextualKeyword("from")
 
-		case js_lexer.TIdentifier:
+		case js_lexer.TTypeof:
+			// Flow: "import typeof foo from 'bar'"
+			if !p.options.ts.Flow || !opts.isModuleScope {
+				p.lexer.Unexpected()
+				return js_ast.Stmt{}
+			}
+
+			p.lexer.Next()
+			p.skipFlowTypeOnlyImportClause()
+			return js_ast.Stmt{Loc: loc, Data: &js_ast.STypeScript{}}
+
+		case js_lexer.TIdentifier:
 			// "import defaultItem from 'path'"
 			// "import foo = bar"
 			if !opts.isModuleScope && !opts.isNamespaceScope {
@@ -6990,6 +7434,12 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 			if p.options.ts.Parse {
 				// Skip over type-only imports
 				if defaultName.String == "type" {
+					// Flow: "import type foo, {bar} from 'baz'"
+					if p.options.ts.Flow && p.lexer.Token != js_lexer.TComma && !p.lexer.IsContextualKeyword("from") {
+						p.skipFlowTypeOnlyImportClause()
+						return js_ast.Stmt{Loc: loc, Data: &js_ast.STypeScript{}}
+					}
+
 					switch p.lexer.Token {
 					case js_lexer.TIdentifier:
 						if p.lexer.Identifier.String != "from" {
@@ -7118,6 +7568,9 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 			ref := p.declareSymbol(js_ast.SymbolImport, stmt.DefaultName.Loc, name)
 			p.isImportItem[ref] = true
 			stmt.DefaultName.Ref = ref
+			if p.options.mode == config.ModeBundle {
+				p.defaultImportReads[ref] = &propertyReads{names: make(map[string]bool)}
+			}
 		}
 
 		// Link each import item to the namespace
@@ -7223,7 +7676,7 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 
 		if isIdentifier {
 			if ident, ok := expr.Data.(*js_ast.EIdentifier); ok {
-				if p.lexer.Token == js_lexer.TColon && opts.tsDecorators == nil {
+				if p.lexer.Token == js_lexer.TColon && opts.decorators == nil {
 					p.pushScopeForParsePass(js_ast.ScopeLabel, loc)
 					defer p.popScope()
 
@@ -7247,6 +7700,14 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 							return js_ast.Stmt{Loc: loc, Data: &js_ast.STypeScript{}}
 						}
 
+					case "opaque":
+						if p.options.ts.Flow && p.lexer.IsContextualKeyword("type") && !p.lexer.HasNewlineBefore {
+							// "opaque type Foo = string"
+							p.lexer.Next()
+							p.skipFlowOpaqueTypeStmt(parseStmtOpts{isModuleScope: opts.isModuleScope})
+							return js_ast.Stmt{Loc: loc, Data: &js_ast.STypeScript{}}
+						}
+
 					case "namespace", "module":
 						// "namespace Foo {}"
 						// "module Foo {}"
@@ -7254,6 +7715,8 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 						// "declare module 'fs';"
 						if (opts.isModuleScope || opts.isNamespaceScope) && (p.lexer.Token == js_lexer.TIdentifier ||
 							(p.lexer.Token == js_lexer.TStringLiteral && opts.isTypeScriptDeclare)) {
+							p.forbidTypeScriptSyntaxInFlow(js_lexer.RangeOfIdentifier(p.source, expr.Loc),
+								"TypeScript namespaces are not supported in Flow files")
 							return p.parseTypeScriptNamespaceStmt(loc, opts)
 						}
 
@@ -7263,7 +7726,9 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 						return js_ast.Stmt{Loc: loc, Data: &js_ast.STypeScript{}}
 
 					case "abstract":
-						if p.lexer.Token == js_lexer.TClass || opts.tsDecorators != nil {
+						if p.lexer.Token == js_lexer.TClass || opts.decorators != nil {
+							p.forbidTypeScriptSyntaxInFlow(js_lexer.RangeOfIdentifier(p.source, expr.Loc),
+								"TypeScript abstract classes are not supported in Flow files")
 							return p.parseClassStmt(loc, opts)
 						}
 
@@ -7277,13 +7742,22 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 						}
 
 					case "declare":
+						// Flow declarations are always removed
+						if p.options.ts.Flow {
+							if !p.lexer.HasNewlineBefore {
+								p.skipFlowDeclareStmt(parseStmtOpts{isModuleScope: opts.isModuleScope})
+								return js_ast.Stmt{Loc: loc, Data: &js_ast.STypeScript{}}
+							}
+							break
+						}
+
 						opts.lexicalDecl = lexicalDeclAllowAll
 						opts.isTypeScriptDeclare = true
 
 						// "@decorator declare class Foo {}"
 						// "@decorator declare abstract class Foo {}"
-						if opts.tsDecorators != nil && p.lexer.Token != js_lexer.TClass && !p.lexer.IsContextualKeyword("abstract") {
-							p.logMisplacedDecoratorError(opts.tsDecorators)
+						if opts.decorators != nil && p.lexer.Token != js_lexer.TClass && !p.lexer.IsContextualKeyword("abstract") {
+							p.logMisplacedDecoratorError(opts.decorators)
 						}
 
 						// "declare global { ... }"
@@ -7297,8 +7771,8 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 
 						// "declare const x: any"
 						stmt := p.parseStmt(opts)
-						if opts.tsDecorators != nil {
-							p.discardScopesUpTo(opts.tsDecorators.scopeIndex)
+						if opts.decorators != nil {
+							p.discardScopesUpTo(opts.decorators.scopeIndex)
 						}
 
 						// Unlike almost all uses of "declare", statements that use
@@ -7406,6 +7880,36 @@ func (p *parser) parseFnBody(data fnOrArrowDataParse) js_ast.FnBody {
 	return js_ast.FnBody{Loc: loc, Block: js_ast.SBlock{Stmts: stmts, CloseBraceLoc: closeBraceLoc}}
 }
 
+// This is used when all comments are being preserved. Legal comments are
+// always kept but other comments must match the comments filter, if any. The
+// comments are consumed so that they aren't also attached to the expression
+// at the start of the next statement.
+func (p *parser) appendAllStmtComments(stmts []js_ast.Stmt) []js_ast.Stmt {
+	legalComments := p.lexer.LegalCommentsBeforeToken
+	for _, comment := range p.lexer.CommentsBeforeToken {
+		isLegalComment := len(legalComments) > 0 && legalComments[0].Loc == comment.Loc
+		if isLegalComment {
+			legalComments = legalComments[1:]
+		}
+		text := p.source.CommentTextWithoutIndent(comment)
+		if isLegalComment || p.isCommentKept(text) {
+			stmts = append(stmts, js_ast.Stmt{
+				Loc: comment.Loc,
+				Data: &js_ast.SComment{
+					Text:           text,
+					IsLegalComment: isLegalComment,
+				},
+			})
+		}
+	}
+	p.lexer.CommentsBeforeToken = p.lexer.CommentsBeforeToken[:0]
+	return stmts
+}
+
+func (p *parser) isCommentKept(text string) bool {
+	return p.options.commentsFilter == nil || p.options.commentsFilter.MatchString(text)
+}
+
 func (p *parser) forbidLexicalDecl(loc logger.Loc) {
 	r := js_lexer.RangeOfIdentifier(p.source, loc)
 	p.log.AddError(&p.tracker, r, "Cannot use a declaration in a single-statement context")
@@ -7419,16 +7923,20 @@ func (p *parser) parseStmtsUpTo(end js_lexer.T, opts parseStmtOpts) []js_ast.Stm
 
 	for {
 		// Preserve some statement-level comments
-		comments := p.lexer.LegalCommentsBeforeToken
-		if len(comments) > 0 {
-			for _, comment := range comments {
-				stmts = append(stmts, js_ast.Stmt{
-					Loc: comment.Loc,
-					Data: &js_ast.SComment{
-						Text:           p.source.CommentTextWithoutIndent(comment),
-						IsLegalComment: true,
-					},
-				})
+		if p.options.comments == config.CommentsAll && (!p.options.minifyWhitespace || p.options.keepsCommentsWhenMinifying()) {
+			stmts = p.appendAllStmtComments(stmts)
+		} else {
+			comments := p.lexer.LegalCommentsBeforeToken
+			if len(comments) > 0 {
+				for _, comment := range comments {
+					stmts = append(stmts, js_ast.Stmt{
+						Loc: comment.Loc,
+						Data: &js_ast.SComment{
+							Text:           p.source.CommentTextWithoutIndent(comment),
+							IsLegalComment: true,
+						},
+					})
+				}
 			}
 		}
 
@@ -7436,6 +7944,10 @@ func (p *parser) parseStmtsUpTo(end js_lexer.T, opts parseStmtOpts) []js_ast.Stm
 			break
 		}
 
+		if opts.isModuleScope {
+			p.topLevelStmtLexer = p.lexer
+		}
+
 		stmt := p.parseStmt(opts)
 
 		// Skip TypeScript types entirely
@@ -7516,6 +8028,187 @@ func (p *parser) parseStmtsUpTo(end js_lexer.T, opts parseStmtOpts) []js_ast.Stm
 	return stmts
 }
 
+// This parses all top-level statements in the file. Syntax errors normally
+// stop the parser immediately, which means only the first syntax error in a
+// file would ever be reported. To give better feedback, the parser instead
+// skips over the top-level statement containing the syntax error and keeps
+// looking for more syntax errors in the rest of the file. Parsing still fails
+// in this case since the AST for the skipped statements is missing.
+func (p *parser) parseModuleStmts() []js_ast.Stmt {
+	opts := parseStmtOpts{
+		isModuleScope:          true,
+		allowDirectivePrologue: true,
+	}
+	moduleScope := p.currentScope
+
+	defer func() {
+		r := recover()
+		if _, isLexerPanic := r.(js_lexer.LexerPanic); isLexerPanic {
+			p.reportRemainingSyntaxErrors(moduleScope)
+		}
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	return p.parseStmtsUpTo(js_lexer.TEndOfFile, opts)
+}
+
+func (p *parser) reportRemainingSyntaxErrors(moduleScope *js_ast.Scope) {
+	// Only report additional errors. Warnings aren't useful at this point since
+	// the file won't be compiled anyway.
+	log := p.log
+	p.log.AddMsg = func(msg logger.Msg) {
+		if msg.Kind == logger.Error {
+			log.AddMsg(msg)
+		}
+	}
+
+	// The parser state is the same at the start of every top-level statement
+	fnOrArrowDataParse := fnOrArrowDataParse{
+		await:      allowExpr,
+		isTopLevel: true,
+	}
+
+	for p.skipToNextTopLevelStmt() {
+		p.currentScope = moduleScope
+		p.fnOrArrowDataParse = fnOrArrowDataParse
+		p.allowIn = true
+		p.allowPrivateIdentifiers = false
+
+		if p.tryToParseRemainingTopLevelStmts() {
+			break
+		}
+	}
+}
+
+// Returns false if there was another syntax error
+func (p *parser) tryToParseRemainingTopLevelStmts() (ok bool) {
+	defer func() {
+		r := recover()
+		if _, isLexerPanic := r.(js_lexer.LexerPanic); isLexerPanic {
+			ok = false
+		} else if r != nil {
+			panic(r)
+		}
+	}()
+
+	p.parseStmtsUpTo(js_lexer.TEndOfFile, parseStmtOpts{isModuleScope: true})
+	return true
+}
+
+// This moves the lexer past the top-level statement containing the syntax
+// error at the current token. The parser could have been anywhere inside of
+// that statement when the error happened, so the lexer is rewound to the
+// start of the statement and the tokens are matched up without parsing them.
+// Returns false if the end of the file was reached or if the tokens can't be
+// matched up (e.g. due to a lexer error), in which case no more syntax errors
+// are reported.
+func (p *parser) skipToNextTopLevelStmt() (ok bool) {
+	errorStart := p.lexer.Loc().Start
+	p.lexer = p.topLevelStmtLexer
+	p.lexer.IsLogDisabled = true
+
+	defer func() {
+		r := recover()
+		if _, isLexerPanic := r.(js_lexer.LexerPanic); isLexerPanic {
+			ok = false
+		} else if r != nil {
+			panic(r)
+		}
+		p.lexer.IsLogDisabled = false
+	}()
+
+	depth := 0
+	var templateDepths []int
+	prevToken := js_lexer.TSemicolon
+
+	for {
+		switch p.lexer.Token {
+		case js_lexer.TOpenBrace, js_lexer.TOpenBracket, js_lexer.TOpenParen:
+			depth++
+
+		case js_lexer.TCloseBracket, js_lexer.TCloseParen:
+			if depth > 0 {
+				depth--
+			}
+
+		case js_lexer.TCloseBrace:
+			// This may be the end of a substitution in a template literal
+			if n := len(templateDepths); n > 0 && templateDepths[n-1] == depth {
+				p.lexer.RescanCloseBraceAsTemplateToken()
+				if p.lexer.Token == js_lexer.TTemplateTail {
+					templateDepths = templateDepths[:n-1]
+				}
+			} else if depth > 0 {
+				depth--
+			}
+
+		case js_lexer.TTemplateHead:
+			templateDepths = append(templateDepths, depth)
+
+		case js_lexer.TSyntaxError:
+			// The lexer doesn't advance past invalid characters
+			return false
+
+		case js_lexer.TSlash, js_lexer.TSlashEquals:
+			// Guess whether this is a regular expression or a division operator
+			if !canTokenEndExpr(prevToken) {
+				p.lexer.ScanRegExp()
+			}
+		}
+
+		prevToken = p.lexer.Token
+		p.lexer.Next()
+
+		if p.lexer.Token == js_lexer.TEndOfFile {
+			return false
+		}
+
+		// Stop at the start of the next statement once we're past the error
+		if depth == 0 && len(templateDepths) == 0 && p.lexer.Loc().Start > errorStart &&
+			p.lexer.Token != js_lexer.TElse && p.lexer.Token != js_lexer.TCatch && p.lexer.Token != js_lexer.TFinally {
+			if prevToken == js_lexer.TSemicolon {
+				return true
+			}
+			if p.lexer.HasNewlineBefore && (prevToken == js_lexer.TCloseBrace ||
+				// Avoid stopping at the body of "if (x)\n return" and "else\n return"
+				(canTokenEndExpr(prevToken) && prevToken != js_lexer.TCloseParen &&
+					(isStmtKeyword(p.lexer.Token) || p.lexer.IsContextualKeyword("let")))) {
+				return true
+			}
+		}
+	}
+}
+
+// Returns true if this token can end an expression, in which case a following
+// "/" token is a division operator instead of the start of a regular expression
+func canTokenEndExpr(token js_lexer.T) bool {
+	switch token {
+	case js_lexer.TIdentifier, js_lexer.TEscapedKeyword, js_lexer.TPrivateIdentifier,
+		js_lexer.TNoSubstitutionTemplateLiteral, js_lexer.TNumericLiteral, js_lexer.TStringLiteral,
+		js_lexer.TBigIntegerLiteral, js_lexer.TTemplateTail, js_lexer.TCloseBrace, js_lexer.TCloseBracket,
+		js_lexer.TCloseParen, js_lexer.TPlusPlus, js_lexer.TMinusMinus, js_lexer.TFalse, js_lexer.TNull,
+		js_lexer.TSuper, js_lexer.TThis, js_lexer.TTrue:
+		return true
+	}
+	return false
+}
+
+// Returns true if this token starts a statement when it comes after a newline.
+// Automatic semicolon insertion means none of these can continue the previous
+// statement in that case.
+func isStmtKeyword(token js_lexer.T) bool {
+	switch token {
+	case js_lexer.TBreak, js_lexer.TClass, js_lexer.TConst, js_lexer.TContinue, js_lexer.TDebugger,
+		js_lexer.TDo, js_lexer.TExport, js_lexer.TFor, js_lexer.TFunction, js_lexer.TIf, js_lexer.TImport,
+		js_lexer.TReturn, js_lexer.TSwitch, js_lexer.TThrow, js_lexer.TTry, js_lexer.TVar,
+		js_lexer.TWhile, js_lexer.TWith:
+		return true
+	}
+	return false
+}
+
 type generateTempRefArg uint8
 
 const (
@@ -7550,6 +8243,11 @@ func (p *parser) generateTempRef(declare generateTempRefArg, optionalName string
 		p.tempLetsToDeclare = append(p.tempLetsToDeclare, ref)
 	} else if declare != tempRefNoDeclare {
 		p.tempRefsToDeclare = append(p.tempRefsToDeclare, tempRef{ref: ref})
+	} else if scope == p.moduleScope {
+		// The caller declares this symbol in the current statement, so make sure
+		// the renamer knows about it. Otherwise it could end up with the same name
+		// as another top-level symbol.
+		p.declaredSymbols = append(p.declaredSymbols, js_ast.DeclaredSymbol{Ref: ref, IsTopLevel: true})
 	}
 
 	scope.Generated = append(scope.Generated, ref)
@@ -7715,6 +8413,54 @@ func findIdentifiers(binding js_ast.Binding, identifiers []js_ast.Decl) []js_ast
 //
 // We can't trim the entire branch as dead or calling foo() will incorrectly
 // assign to a global variable instead.
+// If the test of a switch statement is a constant that can be compared with
+// all case values up to the matching one, this returns the range of cases that
+// can be reached. The range starts at the matching case (or at the "default"
+// case if nothing matches) and continues through any cases that are reached by
+// falling through. The case values are checked before they are visited, so
+// this only succeeds for primitive literals.
+func reachableSwitchCases(test js_ast.Expr, cases []js_ast.Case) (start int, end int, ok bool) {
+	start = -1
+	defaultIndex := -1
+	for i, c := range cases {
+		if c.ValueOrNil.Data == nil {
+			defaultIndex = i
+			continue
+		}
+		equal, ok := js_ast.CheckEqualityIfNoSideEffects(test.Data, c.ValueOrNil.Data)
+		if !ok {
+			// Primitive literals of different types are never strictly equal
+			if !js_ast.IsPrimitiveLiteral(test.Data) || !js_ast.IsPrimitiveLiteral(c.ValueOrNil.Data) {
+				return 0, 0, false
+			}
+			equal = false
+		}
+		if equal {
+			start = i
+			break
+		}
+	}
+
+	// Use the "default" case if nothing matches
+	if start == -1 {
+		if defaultIndex == -1 {
+			return 0, 0, true
+		}
+		start = defaultIndex
+	}
+
+	// Stop after the first case that can't fall through to the next one
+	end = start
+	for end < len(cases) {
+		body := cases[end].Body
+		end++
+		if len(body) > 0 && isJumpStatement(body[len(body)-1].Data) {
+			break
+		}
+	}
+	return start, end, true
+}
+
 func shouldKeepStmtInDeadControlFlow(stmt js_ast.Stmt) bool {
 	switch s := stmt.Data.(type) {
 	case *js_ast.SEmpty, *js_ast.SExpr, *js_ast.SThrow, *js_ast.SReturn,
@@ -7878,7 +8624,14 @@ func (p *parser) visitStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt {
 		for _, ref := range p.tempLetsToDeclare {
 			decls = append(decls, js_ast.Decl{Binding: js_ast.Binding{Data: &js_ast.BIdentifier{Ref: ref}}})
 		}
-		before = append(before, js_ast.Stmt{Data: &js_ast.SLocal{Kind: js_ast.LocalLet, Decls: decls}})
+		kind := js_ast.LocalLet
+		if p.options.unsupportedJSFeatures.Has(compat.ConstAndLet) {
+			kind = js_ast.LocalVar
+			for _, ref := range p.tempLetsToDeclare {
+				p.hoistLoweredLetOrConstRef(ref)
+			}
+		}
+		before = append(before, js_ast.Stmt{Data: &js_ast.SLocal{Kind: kind, Decls: decls}})
 	}
 	p.tempLetsToDeclare = oldTempLetsToDeclare
 
@@ -7910,6 +8663,9 @@ func (p *parser) visitStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt {
 					// Merge the two identifiers back into a single one
 					p.symbols[hoistedRef.InnerIndex].Link = s.Fn.Name.Ref
 				}
+				if loopLetCaptures, ok := p.loweredLoopLetFnCaptures[&s.Fn]; ok {
+					p.logUnwrappableClosureCapturingLoopLets(loopLetCaptures)
+				}
 				nonFnStmts = append(nonFnStmts, stmt)
 				continue
 			}
@@ -7932,8 +8688,12 @@ func (p *parser) visitStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt {
 			}
 
 			// The last function statement for a given symbol wins
+			loopLetCaptures := p.loweredLoopLetFnCaptures[&s.Fn]
 			s.Fn.Name = nil
 			letDecls[index].ValueOrNil = js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EFunction{Fn: s.Fn}}
+			if len(loopLetCaptures) > 0 {
+				letDecls[index].ValueOrNil = p.wrapClosureCapturingLoopLets(letDecls[index].ValueOrNil, loopLetCaptures)
+			}
 		}
 
 		// Reuse memory from "before"
@@ -7941,6 +8701,9 @@ func (p *parser) visitStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt {
 		kind := js_ast.LocalLet
 		if p.options.unsupportedJSFeatures.Has(compat.ConstAndLet) {
 			kind = js_ast.LocalVar
+			for _, decl := range letDecls {
+				p.hoistLoweredLetOrConstRef(decl.Binding.Data.(*js_ast.BIdentifier).Ref)
+			}
 		}
 		if len(letDecls) > 0 {
 			before = append(before, js_ast.Stmt{Loc: letDecls[0].ValueOrNil.Loc, Data: &js_ast.SLocal{Kind: kind, Decls: letDecls}})
@@ -7968,7 +8731,7 @@ func (p *parser) visitStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt {
 
 	// Stop now if we're not mangling
 	if !p.options.minifySyntax {
-		return visited
+		return p.maybeLowerUsingDeclarations(visited)
 	}
 
 	// If this is in a dead branch, trim as much dead code as we can
@@ -7994,7 +8757,7 @@ func (p *parser) visitStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt {
 		return visited[:end]
 	}
 
-	return p.mangleStmts(visited, kind)
+	return p.maybeLowerUsingDeclarations(p.mangleStmts(visited, kind))
 }
 
 func isDirectiveSupported(s *js_ast.SDirective) bool {
@@ -8002,8 +8765,12 @@ func isDirectiveSupported(s *js_ast.SDirective) bool {
 	// that should be the only one that is ever really used by engines in
 	// practice. We don't support "use asm" even though that's also
 	// technically used in practice because the rest of our minifier would
-	// likely cause asm.js code to fail validation anyway.
-	return helpers.UTF16EqualsString(s.Value, "use strict")
+	// likely cause asm.js code to fail validation anyway. The "use client"
+	// and "use server" directives are also kept because React frameworks
+	// use them to split code between the client and the server.
+	return helpers.UTF16EqualsString(s.Value, "use strict") ||
+		helpers.UTF16EqualsString(s.Value, "use client") ||
+		helpers.UTF16EqualsString(s.Value, "use server")
 }
 
 func (p *parser) mangleStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt {
@@ -8042,6 +8809,10 @@ func (p *parser) mangleStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt
 		}
 	}
 
+	if kind == stmtsFnBody && p.currentScope != p.moduleScope && !p.currentScope.ContainsDirectEval {
+		p.inlineSingleUseFnCalls(stmts)
+	}
+
 	// Merge adjacent statements during mangling
 	result := make([]js_ast.Stmt, 0, len(stmts))
 	isControlFlowDead := false
@@ -8086,7 +8857,7 @@ func (p *parser) mangleStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt
 				// should have visited all the uses of "let" and "const" declarations
 				// by now since they are scoped to this block which we just finished
 				// visiting.
-				if prevS, ok := result[len(result)-1].Data.(*js_ast.SLocal); ok && prevS.Kind != js_ast.LocalVar {
+				if prevS, ok := result[len(result)-1].Data.(*js_ast.SLocal); ok && prevS.Kind != js_ast.LocalVar && !prevS.Kind.IsUsing() {
 					// The variable must be initialized, since we will be substituting
 					// the value into the usage.
 					if last := prevS.Decls[len(prevS.Decls)-1]; last.ValueOrNil.Data != nil {
@@ -8136,6 +8907,20 @@ func (p *parser) mangleStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt
 			}
 
 		case *js_ast.SLocal:
+			// "var a = 1; b(); var c = 2;" => "var a = 1, c = (b(), 2);"
+			//
+			// This is only done when the previous expression statement is in
+			// between two local statements of the same kind so that the local
+			// statements can be merged. Otherwise it would make the code bigger.
+			if n := len(result); n >= 2 && len(s.Decls) > 0 && s.Decls[0].ValueOrNil.Data != nil && !s.Kind.IsUsing() {
+				if prevS, ok := result[n-1].Data.(*js_ast.SExpr); ok {
+					if prevPrevS, ok := result[n-2].Data.(*js_ast.SLocal); ok && s.Kind == prevPrevS.Kind && s.IsExport == prevPrevS.IsExport {
+						s.Decls[0].ValueOrNil = js_ast.JoinWithComma(prevS.Value, s.Decls[0].ValueOrNil)
+						result = result[:n-1]
+					}
+				}
+			}
+
 			// Merge adjacent local statements
 			if len(result) > 0 {
 				prevStmt := result[len(result)-1]
@@ -8146,6 +8931,28 @@ func (p *parser) mangleStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt
 			}
 
 		case *js_ast.SExpr:
+			// "var a; a = 1;" => "var a = 1;"
+			//
+			// This is always safe for "var" declarations. It's only done for "let"
+			// declarations when the value is a primitive literal since otherwise
+			// evaluating the value could observe the variable in the temporal dead
+			// zone, which would throw instead of returning undefined.
+			if len(result) > 0 {
+				if prevS, ok := result[len(result)-1].Data.(*js_ast.SLocal); ok && (prevS.Kind == js_ast.LocalVar || prevS.Kind == js_ast.LocalLet) {
+					if last := &prevS.Decls[len(prevS.Decls)-1]; last.ValueOrNil.Data == nil {
+						if id, ok := last.Binding.Data.(*js_ast.BIdentifier); ok {
+							if assign, ok := s.Value.Data.(*js_ast.EBinary); ok && assign.Op == js_ast.BinOpAssign {
+								if target, ok := assign.Left.Data.(*js_ast.EIdentifier); ok && target.Ref == id.Ref &&
+									(prevS.Kind == js_ast.LocalVar || js_ast.IsPrimitiveLiteral(assign.Right.Data)) {
+									last.ValueOrNil = assign.Right
+									continue
+								}
+							}
+						}
+					}
+				}
+			}
+
 			// Merge adjacent expression statements
 			if len(result) > 0 {
 				prevStmt := result[len(result)-1]
@@ -8176,6 +8983,42 @@ func (p *parser) mangleStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt
 				}
 			}
 
+			// "let x = () => { if (y) { z(); return; } w(); };" => "let x = () => { if (y) z(); else w(); };" => "let x = () => { y ? z() : w(); };"
+			// "while (x) { if (y) { z(); continue; } w(); }" => "while (x) { if (y) z(); else w(); }" => "for (; x;) y ? z() : w();"
+			if block, ok := s.Yes.Data.(*js_ast.SBlock); ok && len(block.Stmts) > 1 && isImplicitJumpAtEnd(block.Stmts[len(block.Stmts)-1], kind) {
+				var body []js_ast.Stmt
+				if s.NoOrNil.Data != nil {
+					body = append(body, s.NoOrNil)
+				}
+				body = append(body, stmts[i+1:]...)
+
+				// The remaining statements will be nested inside the "else" branch, so
+				// this has the same restrictions as the implicit jump optimization below
+				canMoveStmtsIntoElseBranch := true
+				for _, stmt := range body {
+					if statementCaresAboutScope(stmt) {
+						canMoveStmtsIntoElseBranch = false
+						break
+					}
+				}
+
+				if canMoveStmtsIntoElseBranch {
+					yesStmts := block.Stmts[:len(block.Stmts)-1]
+					yes := stmtsToSingleStmt(s.Yes.Loc, yesStmts)
+					for _, stmt := range yesStmts {
+						if statementCaresAboutScope(stmt) {
+							yes = js_ast.Stmt{Loc: s.Yes.Loc, Data: &js_ast.SBlock{Stmts: yesStmts, CloseBraceLoc: block.CloseBraceLoc}}
+							break
+						}
+					}
+					var no js_ast.Stmt
+					if body = p.mangleStmts(body, kind); len(body) > 0 {
+						no = stmtsToSingleStmt(body[0].Loc, body)
+					}
+					return p.mangleIf(result, stmt.Loc, &js_ast.SIf{Test: s.Test, Yes: yes, NoOrNil: no})
+				}
+			}
+
 			if isJumpStatement(s.Yes.Data) {
 				optimizeImplicitJump := false
 
@@ -8825,11 +9668,388 @@ func (p *parser) substituteSingleUseSymbolInExpr(
 	return expr, substituteFailure
 }
 
+// Inline calls to single-use functions whose body is a single "return"
+// statement. This is only done in nested scopes for the same reason as the
+// inlining of single-use variables in "mangleStmts":
+//
+//	// Before
+//	function fn() {
+//	  function add(a, b) { return a + b }
+//	  return add(1, 2)
+//	}
+//
+//	// After
+//	function fn() {
+//	  return 1 + 2
+//	}
+//
+// The call must be directly inside one of the statements in this list and not
+// inside of a nested function, so the function body ends up in the same scope
+// it came from. Arguments must not have side effects because they are now
+// evaluated where the corresponding parameter was used instead of before the
+// call. If the function body may have side effects, those side effects could
+// change the value of the arguments, so only primitive literals and local
+// variables that are never assigned to are allowed then.
+func (p *parser) inlineSingleUseFnCalls(stmts []js_ast.Stmt) {
+	for i, stmt := range stmts {
+		s, ok := stmt.Data.(*js_ast.SFunction)
+		if !ok || s.IsExport || s.Fn.IsAsync || s.Fn.IsGenerator || s.Fn.HasRestArg || s.Fn.HasIfScope ||
+			len(s.Fn.Body.Block.Stmts) != 1 {
+			continue
+		}
+		ret, ok := s.Fn.Body.Block.Stmts[0].Data.(*js_ast.SReturn)
+		if !ok || ret.ValueOrNil.Data == nil {
+			continue
+		}
+		if symbol := p.symbols[s.Fn.Name.Ref.InnerIndex]; symbol.UseCountEstimate != 1 || symbol.Flags.Has(js_ast.DidKeepName) {
+			continue
+		}
+
+		// Each parameter must be a plain identifier that is used at most once
+		fn := inlinableFn{args: make([]js_ast.Ref, 0, len(s.Fn.Args))}
+		for _, arg := range s.Fn.Args {
+			id, ok := arg.Binding.Data.(*js_ast.BIdentifier)
+			if !ok || arg.DefaultOrNil.Data != nil || p.symbols[id.Ref.InnerIndex].UseCountEstimate > 1 {
+				fn.args = nil
+				break
+			}
+			fn.args = append(fn.args, id.Ref)
+		}
+		if fn.args == nil || !p.fnBodyCanBeInlined(ret.ValueOrNil, s.Fn.ArgumentsRef, &fn) {
+			continue
+		}
+		fn.ref = s.Fn.Name.Ref
+		fn.body = ret.ValueOrNil
+		fn.bodyCanBeRemoved = js_ast.ExprCanBeRemovedIfUnused(ret.ValueOrNil, p.isUnbound)
+
+		for _, other := range stmts {
+			if p.inlineFnCallInStmt(other, &fn) {
+				p.ignoreUsage(fn.ref)
+				stmts[i].Data = js_ast.SEmptyShared
+				break
+			}
+		}
+	}
+}
+
+type inlinableFn struct {
+	body js_ast.Expr
+
+	args []js_ast.Ref
+
+	// Parameters that are called can't be substituted with a property access
+	// since that would change the value of "this" for the call
+	calledArgs map[js_ast.Ref]bool
+
+	ref              js_ast.Ref
+	bodyCanBeRemoved bool
+}
+
+// This only allows expressions that behave the same way when moved out of
+// the function. For example, "this" and "arguments" are not allowed, and
+// parameters can't be assigned to since they will be replaced by arguments.
+func (p *parser) fnBodyCanBeInlined(expr js_ast.Expr, argumentsRef js_ast.Ref, fn *inlinableFn) bool {
+	switch e := expr.Data.(type) {
+	case *js_ast.EIdentifier:
+		return e.Ref != argumentsRef
+
+	case *js_ast.ENull, *js_ast.EUndefined, *js_ast.EBoolean, *js_ast.ENumber, *js_ast.EBigInt,
+		*js_ast.EString, *js_ast.ERegExp, *js_ast.EInlinedEnum, *js_ast.EImportIdentifier:
+		return true
+
+	case *js_ast.EUnary:
+		return e.Op.UnaryAssignTarget() == js_ast.AssignTargetNone && e.Op != js_ast.UnOpDelete &&
+			p.fnBodyCanBeInlined(e.Value, argumentsRef, fn)
+
+	case *js_ast.EBinary:
+		return e.Op.BinaryAssignTarget() == js_ast.AssignTargetNone &&
+			p.fnBodyCanBeInlined(e.Left, argumentsRef, fn) && p.fnBodyCanBeInlined(e.Right, argumentsRef, fn)
+
+	case *js_ast.EIf:
+		return p.fnBodyCanBeInlined(e.Test, argumentsRef, fn) &&
+			p.fnBodyCanBeInlined(e.Yes, argumentsRef, fn) && p.fnBodyCanBeInlined(e.No, argumentsRef, fn)
+
+	case *js_ast.EDot:
+		return p.fnBodyCanBeInlined(e.Target, argumentsRef, fn)
+
+	case *js_ast.EIndex:
+		if _, ok := e.Index.Data.(*js_ast.EPrivateIdentifier); ok {
+			return false
+		}
+		return p.fnBodyCanBeInlined(e.Target, argumentsRef, fn) && p.fnBodyCanBeInlined(e.Index, argumentsRef, fn)
+
+	case *js_ast.ECall:
+		if e.Kind == js_ast.DirectEval {
+			return false
+		}
+		p.markCalledArg(e.Target, fn)
+		return p.fnBodyCanBeInlined(e.Target, argumentsRef, fn) && p.fnBodyExprsCanBeInlined(e.Args, argumentsRef, fn)
+
+	case *js_ast.ENew:
+		return p.fnBodyCanBeInlined(e.Target, argumentsRef, fn) && p.fnBodyExprsCanBeInlined(e.Args, argumentsRef, fn)
+
+	case *js_ast.EArray:
+		return p.fnBodyExprsCanBeInlined(e.Items, argumentsRef, fn)
+
+	case *js_ast.ESpread:
+		return p.fnBodyCanBeInlined(e.Value, argumentsRef, fn)
+
+	case *js_ast.ETemplate:
+		if e.TagOrNil.Data != nil {
+			p.markCalledArg(e.TagOrNil, fn)
+			if !p.fnBodyCanBeInlined(e.TagOrNil, argumentsRef, fn) {
+				return false
+			}
+		}
+		for _, part := range e.Parts {
+			if !p.fnBodyCanBeInlined(part.Value, argumentsRef, fn) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) fnBodyExprsCanBeInlined(exprs []js_ast.Expr, argumentsRef js_ast.Ref, fn *inlinableFn) bool {
+	for _, expr := range exprs {
+		if !p.fnBodyCanBeInlined(expr, argumentsRef, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) markCalledArg(target js_ast.Expr, fn *inlinableFn) {
+	if id, ok := target.Data.(*js_ast.EIdentifier); ok {
+		if fn.calledArgs == nil {
+			fn.calledArgs = make(map[js_ast.Ref]bool)
+		}
+		fn.calledArgs[id.Ref] = true
+	}
+}
+
+func (p *parser) inlineFnCallInStmt(stmt js_ast.Stmt, fn *inlinableFn) bool {
+	switch s := stmt.Data.(type) {
+	case *js_ast.SExpr:
+		return p.inlineFnCallInExpr(&s.Value, fn)
+	case *js_ast.SThrow:
+		return p.inlineFnCallInExpr(&s.Value, fn)
+	case *js_ast.SReturn:
+		return s.ValueOrNil.Data != nil && p.inlineFnCallInExpr(&s.ValueOrNil, fn)
+	case *js_ast.SIf:
+		return p.inlineFnCallInExpr(&s.Test, fn)
+	case *js_ast.SSwitch:
+		return p.inlineFnCallInExpr(&s.Test, fn)
+	case *js_ast.SLocal:
+		for i := range s.Decls {
+			if decl := &s.Decls[i]; decl.ValueOrNil.Data != nil && p.inlineFnCallInExpr(&decl.ValueOrNil, fn) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// This doesn't look inside of nested functions or classes
+func (p *parser) inlineFnCallInExpr(expr *js_ast.Expr, fn *inlinableFn) bool {
+	switch e := expr.Data.(type) {
+	case *js_ast.ECall:
+		if id, ok := e.Target.Data.(*js_ast.EIdentifier); ok && id.Ref == fn.ref {
+			return p.tryToInlineFnCall(expr, e, fn)
+		}
+		return p.inlineFnCallInExpr(&e.Target, fn) || p.inlineFnCallInExprs(e.Args, fn)
+
+	case *js_ast.ENew:
+		return p.inlineFnCallInExpr(&e.Target, fn) || p.inlineFnCallInExprs(e.Args, fn)
+
+	case *js_ast.EUnary:
+		return p.inlineFnCallInExpr(&e.Value, fn)
+
+	case *js_ast.EBinary:
+		return p.inlineFnCallInExpr(&e.Left, fn) || p.inlineFnCallInExpr(&e.Right, fn)
+
+	case *js_ast.EIf:
+		return p.inlineFnCallInExpr(&e.Test, fn) || p.inlineFnCallInExpr(&e.Yes, fn) || p.inlineFnCallInExpr(&e.No, fn)
+
+	case *js_ast.EDot:
+		return p.inlineFnCallInExpr(&e.Target, fn)
+
+	case *js_ast.EIndex:
+		return p.inlineFnCallInExpr(&e.Target, fn) || p.inlineFnCallInExpr(&e.Index, fn)
+
+	case *js_ast.EArray:
+		return p.inlineFnCallInExprs(e.Items, fn)
+
+	case *js_ast.ESpread:
+		return p.inlineFnCallInExpr(&e.Value, fn)
+
+	case *js_ast.EAwait:
+		return p.inlineFnCallInExpr(&e.Value, fn)
+
+	case *js_ast.EYield:
+		return e.ValueOrNil.Data != nil && p.inlineFnCallInExpr(&e.ValueOrNil, fn)
+
+	case *js_ast.EObject:
+		for i := range e.Properties {
+			property := &e.Properties[i]
+			if property.Flags.Has(js_ast.PropertyIsComputed) && p.inlineFnCallInExpr(&property.Key, fn) {
+				return true
+			}
+			if property.ValueOrNil.Data != nil && p.inlineFnCallInExpr(&property.ValueOrNil, fn) {
+				return true
+			}
+		}
+
+	case *js_ast.ETemplate:
+		if e.TagOrNil.Data != nil && p.inlineFnCallInExpr(&e.TagOrNil, fn) {
+			return true
+		}
+		for i := range e.Parts {
+			if p.inlineFnCallInExpr(&e.Parts[i].Value, fn) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p *parser) inlineFnCallInExprs(exprs []js_ast.Expr, fn *inlinableFn) bool {
+	for i := range exprs {
+		if p.inlineFnCallInExpr(&exprs[i], fn) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) tryToInlineFnCall(expr *js_ast.Expr, call *js_ast.ECall, fn *inlinableFn) bool {
+	if call.OptionalChain != js_ast.OptionalChainNone {
+		return false
+	}
+
+	for i, arg := range call.Args {
+		if _, ok := arg.Data.(*js_ast.ESpread); ok || !js_ast.ExprCanBeRemovedIfUnused(arg, p.isUnbound) {
+			return false
+		}
+		if i < len(fn.args) && p.symbols[fn.args[i].InnerIndex].UseCountEstimate > 0 {
+			if !fn.bodyCanBeRemoved && !js_ast.IsPrimitiveLiteral(arg.Data) && !p.isUnmutatedLocalIdentifier(arg) {
+				return false
+			}
+			if fn.calledArgs[fn.args[i]] {
+				switch arg.Data.(type) {
+				case *js_ast.EDot, *js_ast.EIndex:
+					return false
+				}
+			}
+		}
+	}
+
+	// Missing arguments are "undefined" and extra arguments are dropped
+	replacements := make(map[js_ast.Ref]js_ast.Expr, len(fn.args))
+	for i, ref := range fn.args {
+		if i < len(call.Args) {
+			replacements[ref] = call.Args[i]
+		} else {
+			replacements[ref] = js_ast.Expr{Loc: call.CloseParenLoc, Data: js_ast.EUndefinedShared}
+		}
+	}
+	*expr = substituteFnArgs(fn.body, replacements)
+	return true
+}
+
+// Side effects can't change the value of a local variable that is never
+// assigned to. All assignments to it have been visited by now since it's
+// declared in the current function.
+func (p *parser) isUnmutatedLocalIdentifier(expr js_ast.Expr) bool {
+	id, ok := expr.Data.(*js_ast.EIdentifier)
+	if !ok {
+		return false
+	}
+	symbol := &p.symbols[id.Ref.InnerIndex]
+	if symbol.Flags.Has(js_ast.CouldPotentiallyBeMutated) {
+		return false
+	}
+	for scope := p.currentScope; scope != nil && scope != p.moduleScope; scope = scope.Parent {
+		if member, ok := scope.Members[symbol.OriginalName]; ok && member.Ref == id.Ref {
+			return true
+		}
+		if scope.Kind == js_ast.ScopeFunctionArgs {
+			break
+		}
+	}
+	return false
+}
+
+// The function body has already been checked by "fnBodyCanBeInlined" so this
+// only needs to handle the expressions allowed there
+func substituteFnArgs(expr js_ast.Expr, replacements map[js_ast.Ref]js_ast.Expr) js_ast.Expr {
+	switch e := expr.Data.(type) {
+	case *js_ast.EIdentifier:
+		if replacement, ok := replacements[e.Ref]; ok {
+			return replacement
+		}
+
+	case *js_ast.EUnary:
+		e.Value = substituteFnArgs(e.Value, replacements)
+
+	case *js_ast.EBinary:
+		e.Left = substituteFnArgs(e.Left, replacements)
+		e.Right = substituteFnArgs(e.Right, replacements)
+
+	case *js_ast.EIf:
+		e.Test = substituteFnArgs(e.Test, replacements)
+		e.Yes = substituteFnArgs(e.Yes, replacements)
+		e.No = substituteFnArgs(e.No, replacements)
+
+	case *js_ast.EDot:
+		e.Target = substituteFnArgs(e.Target, replacements)
+
+	case *js_ast.EIndex:
+		e.Target = substituteFnArgs(e.Target, replacements)
+		e.Index = substituteFnArgs(e.Index, replacements)
+
+	case *js_ast.ECall:
+		e.Target = substituteFnArgs(e.Target, replacements)
+		for i, arg := range e.Args {
+			e.Args[i] = substituteFnArgs(arg, replacements)
+		}
+
+	case *js_ast.ENew:
+		e.Target = substituteFnArgs(e.Target, replacements)
+		for i, arg := range e.Args {
+			e.Args[i] = substituteFnArgs(arg, replacements)
+		}
+
+	case *js_ast.EArray:
+		for i, item := range e.Items {
+			e.Items[i] = substituteFnArgs(item, replacements)
+		}
+
+	case *js_ast.ESpread:
+		e.Value = substituteFnArgs(e.Value, replacements)
+
+	case *js_ast.ETemplate:
+		if e.TagOrNil.Data != nil {
+			e.TagOrNil = substituteFnArgs(e.TagOrNil, replacements)
+		}
+		for i, part := range e.Parts {
+			e.Parts[i].Value = substituteFnArgs(part.Value, replacements)
+		}
+	}
+
+	return expr
+}
+
 func (p *parser) visitLoopBody(stmt js_ast.Stmt) js_ast.Stmt {
 	oldIsInsideLoop := p.fnOrArrowDataVisit.isInsideLoop
 	p.fnOrArrowDataVisit.isInsideLoop = true
 	p.loopBody = stmt.Data
+	p.loopBodyDepth++
 	stmt = p.visitSingleStmt(stmt, stmtsLoopBody)
+	p.loopBodyDepth--
 	p.fnOrArrowDataVisit.isInsideLoop = oldIsInsideLoop
 	return stmt
 }
@@ -8856,6 +10076,18 @@ func (p *parser) visitSingleStmt(stmt js_ast.Stmt, kind stmtsKind) js_ast.Stmt {
 }
 
 // One statement could potentially expand to several statements
+// This returns true if the statement is a jump to the place where control
+// flow would go anyway after the end of the statement list
+func isImplicitJumpAtEnd(stmt js_ast.Stmt, kind stmtsKind) bool {
+	switch s := stmt.Data.(type) {
+	case *js_ast.SReturn:
+		return kind == stmtsFnBody && s.ValueOrNil.Data == nil
+	case *js_ast.SContinue:
+		return kind == stmtsLoopBody && s.Label == nil
+	}
+	return false
+}
+
 func stmtsToSingleStmt(loc logger.Loc, stmts []js_ast.Stmt) js_ast.Stmt {
 	if len(stmts) == 0 {
 		return js_ast.Stmt{Loc: loc, Data: js_ast.SEmptyShared}
@@ -8887,6 +10119,7 @@ func (p *parser) visitForLoopInit(stmt js_ast.Stmt, isInOrOf bool) js_ast.Stmt {
 				d.ValueOrNil = p.visitExpr(d.ValueOrNil)
 			}
 		}
+		p.lowerLetAndConstToVar(s, true /* isForLoopInit */, isInOrOf)
 		s.Decls = p.lowerObjectRestInDecls(s.Decls)
 		s.Kind = p.selectLocalKind(s.Kind)
 
@@ -9505,9 +10738,35 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		case *js_ast.SFor, *js_ast.SForIn, *js_ast.SForOf, *js_ast.SWhile, *js_ast.SDoWhile:
 			p.currentScope.LabelStmtIsLoop = true
 		}
+
+		// Drop labeled statements whose label was listed in "dropLabels". The body
+		// is still visited so that scopes line up, but it's treated as dead code
+		// so that references inside it don't count as uses.
+		if p.isDroppedLabel(name) {
+			old := p.isControlFlowDead
+			p.isControlFlowDead = true
+			p.visitSingleStmt(s.Stmt, stmtsNormal)
+			p.isControlFlowDead = old
+			p.popScope()
+			return stmts
+		}
+
+		_, wasForOf := s.Stmt.Data.(*js_ast.SForOf)
 		s.Stmt = p.visitSingleStmt(s.Stmt, stmtsNormal)
 		p.popScope()
 
+		// Lowering a "for-of" loop wraps it in a "try" statement. The label must
+		// stay on the loop itself or "continue" statements that reference it will
+		// become syntax errors.
+		if wasForOf {
+			if try, ok := s.Stmt.Data.(*js_ast.STry); ok && len(try.Block.Stmts) == 1 {
+				if loop, ok := try.Block.Stmts[0].Data.(*js_ast.SFor); ok {
+					try.Block.Stmts[0].Data = &js_ast.SLabel{Name: s.Name, Stmt: js_ast.Stmt{Loc: try.Block.Stmts[0].Loc, Data: loop}}
+					return append(stmts, s.Stmt)
+				}
+			}
+		}
+
 		// Optimize "x: break x" which some people apparently write by hand
 		if p.options.minifySyntax {
 			if child, ok := s.Stmt.Data.(*js_ast.SBreak); ok && child.Label != nil && child.Label.Ref == s.Name.Ref {
@@ -9519,6 +10778,12 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		// Local statements do not end the const local prefix
 		p.currentScope.IsAfterConstLocalPrefix = wasAfterAfterConstLocalPrefix
 
+		// "await using" declarations are a form of top-level await
+		if s.Kind == js_ast.LocalAwaitUsing && p.fnOrArrowDataVisit.isOutsideFnOrArrow && !p.isControlFlowDead {
+			p.liveTopLevelAwaitKeyword = logger.Range{Loc: stmt.Loc, Len: 5}
+			p.markSyntaxFeature(compat.TopLevelAwait, logger.Range{Loc: stmt.Loc, Len: 5})
+		}
+
 		for i := range s.Decls {
 			d := &s.Decls[i]
 			p.visitBinding(d.Binding, bindingOpts{})
@@ -9622,6 +10887,7 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 			return stmts
 		}
 
+		p.lowerLetAndConstToVar(s, false /* isForLoopInit */, false /* isForInOrOf */)
 		s.Decls = p.lowerObjectRestInDecls(s.Decls)
 		s.Kind = p.selectLocalKind(s.Kind)
 
@@ -9671,6 +10937,13 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 			}
 		}
 
+		// The constructor of a derived class that's lowered to ES5 must return
+		// the value returned by the base class constructor
+		if class := p.fnOnlyDataVisit.es5DerivedClass; class != nil && s.ValueOrNil.Data == nil && !p.fnOrArrowDataVisit.isArrow {
+			p.recordUsage(class.thisRef)
+			s.ValueOrNil = js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EIdentifier{Ref: class.thisRef}}
+		}
+
 		if s.ValueOrNil.Data != nil {
 			s.ValueOrNil = p.visitExpr(s.ValueOrNil)
 
@@ -9830,6 +11103,15 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 			mangleFor(s)
 		}
 
+		// Lower "for (using x = y;;)" to "{ using x = y; for (;;) }"
+		if init, ok := s.InitOrNil.Data.(*js_ast.SLocal); ok && init.Kind.IsUsing() && p.options.unsupportedJSFeatures.Has(compat.Using) {
+			initStmt := s.InitOrNil
+			s.InitOrNil = js_ast.Stmt{}
+			return append(stmts, js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SBlock{
+				Stmts: p.lowerUsingDeclarations(initStmt.Loc, []js_ast.Stmt{initStmt, stmt}),
+			}})
+		}
+
 	case *js_ast.SForIn:
 		p.pushScopeForVisitPass(js_ast.ScopeBlock, stmt.Loc)
 		p.visitForLoopInit(s.Init, true)
@@ -9889,10 +11171,16 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 
 		p.popScope()
 
+		p.lowerUsingDeclarationInForOf(s.Init, &s.Body)
 		p.lowerObjectRestInForLoopInit(s.Init, &s.Body)
 
 		if s.Await.Len > 0 && p.options.unsupportedJSFeatures.Has(compat.ForAwait) {
-			return p.lowerForAwaitLoop(stmt.Loc, s, stmts)
+			return p.lowerForOfLoop(stmt.Loc, s, stmts)
+		}
+
+		// Lower for-of loops for browsers that don't support them
+		if s.Await.Len == 0 && p.options.unsupportedJSFeatures.Has(compat.ForOf) {
+			return p.lowerForOfLoop(stmt.Loc, s, stmts)
 		}
 
 	case *js_ast.STry:
@@ -9931,6 +11219,10 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 
 	case *js_ast.SSwitch:
 		s.Test = p.visitExpr(s.Test)
+
+		// Fold constants
+		liveStart, liveEnd, isConstant := reachableSwitchCases(s.Test, s.Cases)
+
 		p.pushScopeForVisitPass(js_ast.ScopeBlock, s.BodyLoc)
 		oldIsInsideSwitch := p.fnOrArrowDataVisit.isInsideSwitch
 		p.fnOrArrowDataVisit.isInsideSwitch = true
@@ -9940,7 +11232,16 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 				p.warnAboutEqualityCheck("case", c.ValueOrNil, c.ValueOrNil.Loc)
 				p.warnAboutTypeofAndString(s.Test, c.ValueOrNil, onlyCheckOriginalOrder)
 			}
-			c.Body = p.visitStmts(c.Body, stmtsNormal)
+
+			// Mark the control flow as dead if the case is never reached
+			if isConstant && (i < liveStart || i >= liveEnd) {
+				old := p.isControlFlowDead
+				p.isControlFlowDead = true
+				c.Body = p.visitStmts(c.Body, stmtsNormal)
+				p.isControlFlowDead = old
+			} else {
+				c.Body = p.visitStmts(c.Body, stmtsNormal)
+			}
 
 			// Make sure the assignment to the body above is preserved
 			s.Cases[i] = c
@@ -9956,8 +11257,36 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 			}
 		}
 
+		// "switch (1) { case 0: a(); break; case 1: b(); break; case 2: c() }" => "switch (1) { case 1: b(); break; }"
+		if p.options.minifySyntax && isConstant {
+			cases := s.Cases[:0]
+			for i, c := range s.Cases {
+				// Dead cases may still need to be kept for their "var" declarations
+				if (i >= liveStart && i < liveEnd) || len(c.Body) > 0 {
+					cases = append(cases, c)
+				}
+			}
+			s.Cases = cases
+
+			// The test has no side effects, so drop the whole statement if it's empty
+			if len(s.Cases) == 0 {
+				return stmts
+			}
+		}
+
 	case *js_ast.SFunction:
-		p.visitFn(&s.Fn, s.Fn.OpenParenLoc, visitFnOpts{})
+		if loopLetCaptures := p.visitFn(&s.Fn, s.Fn.OpenParenLoc, visitFnOpts{}); len(loopLetCaptures) > 0 {
+			// Block-level function declarations may be converted into variables
+			// below, in which case the function can be wrapped at that point
+			if !p.currentScope.Kind.StopsHoisting() && p.symbols[s.Fn.Name.Ref.InnerIndex].Kind == js_ast.SymbolHoistedFunction {
+				if p.loweredLoopLetFnCaptures == nil {
+					p.loweredLoopLetFnCaptures = make(map[*js_ast.Fn][]js_ast.Ref)
+				}
+				p.loweredLoopLetFnCaptures[&s.Fn] = loopLetCaptures
+			} else {
+				p.logUnwrappableClosureCapturingLoopLets(loopLetCaptures)
+			}
+		}
 
 		// Strip this function declaration if it was overwritten
 		if p.symbols[s.Fn.Name.Ref.InnerIndex].Flags.Has(js_ast.RemoveOverwrittenFunctionDeclaration) && !s.IsExport {
@@ -9989,6 +11318,28 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 					}
 				}
 			}
+
+			// Mark if this function always returns the same constant
+			if len(s.Fn.Body.Block.Stmts) == 1 {
+				if ret, ok := s.Fn.Body.Block.Stmts[0].Data.(*js_ast.SReturn); ok {
+					if value := js_ast.ExprToConstValue(ret.ValueOrNil); value.Kind != js_ast.ConstValueNone {
+						hasSideEffectFreeArguments := true
+						for _, arg := range s.Fn.Args {
+							if _, ok := arg.Binding.Data.(*js_ast.BIdentifier); !ok || arg.DefaultOrNil.Data != nil {
+								hasSideEffectFreeArguments = false
+								break
+							}
+						}
+						if hasSideEffectFreeArguments {
+							if p.constReturnValues == nil {
+								p.constReturnValues = make(map[js_ast.Ref]js_ast.ConstValue)
+							}
+							p.constReturnValues[s.Fn.Name.Ref] = value
+							p.symbols[s.Fn.Name.Ref.InnerIndex].Flags |= js_ast.IsConstReturnFunction
+						}
+					}
+				}
+			}
 		}
 
 		// Handle exporting this function from a namespace
@@ -10524,33 +11875,110 @@ func (p *parser) captureValueWithPossibleSideEffects(
 	}, wrapFunc
 }
 
-func (p *parser) visitTSDecorators(tsDecorators []js_ast.Expr, tsDecoratorScope *js_ast.Scope) []js_ast.Expr {
-	if tsDecorators != nil {
+func (p *parser) visitDecorators(decorators []js_ast.Expr, decoratorScope *js_ast.Scope) []js_ast.Expr {
+	if decorators != nil {
 		// TypeScript decorators cause us to temporarily revert to the scope that
 		// encloses the class declaration, since that's where the generated code
 		// for TypeScript decorators will be inserted.
 		oldScope := p.currentScope
-		p.currentScope = tsDecoratorScope
+		p.currentScope = decoratorScope
 
-		for i, decorator := range tsDecorators {
-			tsDecorators[i] = p.visitExpr(decorator)
+		for i, decorator := range decorators {
+			decorators[i] = p.visitExpr(decorator)
 		}
 
 		// Avoid "popScope" because this decorator scope is not hierarchical
 		p.currentScope = oldScope
 	}
 
-	return tsDecorators
+	return decorators
+}
+
+// The types for TypeScript's "emitDecoratorMetadata" setting are only used if
+// there are decorators. Otherwise they are dropped without being visited so
+// that they don't cause anything (such as imports) to be considered used.
+func (p *parser) visitTSDecoratorMetadata(class *js_ast.Class, decoratorScope *js_ast.Scope) {
+	var types []*js_ast.Expr
+
+	for i := range class.Properties {
+		property := &class.Properties[i]
+		isDecorated := len(property.Decorators) > 0
+		isConstructor := false
+
+		if fn, ok := property.ValueOrNil.Data.(*js_ast.EFunction); ok && property.Flags.Has(js_ast.PropertyIsMethod) {
+			if str, ok := property.Key.Data.(*js_ast.EString); ok && helpers.UTF16EqualsString(str.Value, "constructor") {
+				isConstructor = true
+			}
+
+			// Parameter decorators on the constructor decorate the class instead
+			hasParameterDecorators := false
+			for _, arg := range fn.Fn.Args {
+				if len(arg.Decorators) > 0 {
+					hasParameterDecorators = true
+					break
+				}
+			}
+			if isConstructor {
+				isDecorated = len(class.Decorators) > 0 || hasParameterDecorators
+			} else if hasParameterDecorators {
+				isDecorated = true
+			}
+
+			for j := range fn.Fn.Args {
+				arg := &fn.Fn.Args[j]
+				if arg.TSMetadataTypeOrNil.Data != nil {
+					if isDecorated {
+						types = append(types, &arg.TSMetadataTypeOrNil)
+					} else {
+						arg.TSMetadataTypeOrNil = js_ast.Expr{}
+					}
+				}
+			}
+			if fn.Fn.TSMetadataReturnTypeOrNil.Data != nil {
+				if isDecorated && !isConstructor {
+					types = append(types, &fn.Fn.TSMetadataReturnTypeOrNil)
+				} else {
+					fn.Fn.TSMetadataReturnTypeOrNil = js_ast.Expr{}
+				}
+			}
+		}
+
+		if property.TSMetadataTypeOrNil.Data != nil {
+			if isDecorated && !isConstructor {
+				types = append(types, &property.TSMetadataTypeOrNil)
+			} else {
+				property.TSMetadataTypeOrNil = js_ast.Expr{}
+			}
+		}
+	}
+
+	if types != nil {
+		// These are evaluated in the same place as the decorators
+		oldScope := p.currentScope
+		p.currentScope = decoratorScope
+
+		for _, expr := range types {
+			*expr = p.visitExpr(*expr)
+		}
+
+		// Avoid "popScope" because this decorator scope is not hierarchical
+		p.currentScope = oldScope
+	}
 }
 
 type visitClassResult struct {
-	shadowRef    js_ast.Ref
-	superCtorRef js_ast.Ref
+	shadowRef                js_ast.Ref
+	superCtorRef             js_ast.Ref
+	es5DerivedClass          *es5DerivedClass
+	loweredCtorArgsStmtCount int
 }
 
 func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class, defaultNameRef js_ast.Ref) (result visitClassResult) {
-	tsDecoratorScope := p.currentScope
-	class.TSDecorators = p.visitTSDecorators(class.TSDecorators, tsDecoratorScope)
+	decoratorScope := p.currentScope
+	class.Decorators = p.visitDecorators(class.Decorators, decoratorScope)
+	if p.options.ts.Parse && p.options.emitDecoratorMetadata {
+		p.visitTSDecoratorMetadata(class, decoratorScope)
+	}
 
 	if class.Name != nil {
 		p.recordDeclaredSymbol(class.Name.Ref)
@@ -10595,6 +12023,18 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class, defaul
 		}
 	}
 
+	// Lowered decorators on private members need to be able to replace the
+	// private member, which is only possible if the private member is lowered
+	// too. This also applies to the getter and setter of a private auto-accessor.
+	if classLoweringInfo.lowerDecorators {
+		for _, prop := range class.Properties {
+			if private, ok := prop.Key.Data.(*js_ast.EPrivateIdentifier); ok && len(prop.Decorators) > 0 {
+				p.symbols[private.Ref.InnerIndex].Flags |= js_ast.PrivateSymbolMustBeLowered
+				recomputeClassLoweringInfo = true
+			}
+		}
+	}
+
 	// Conservatively lower all private names that have been used in a private
 	// brand check anywhere in the file. See the comment on this map for details.
 	if p.classPrivateBrandChecksToLower != nil {
@@ -10634,6 +12074,15 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class, defaul
 	oldSuperCtorRef := p.superCtorRef
 	p.superCtorRef = result.superCtorRef
 
+	// Derived classes that are lowered to ES5 need a symbol for the base class
+	// and a symbol for the value of "this" inside the constructor
+	if p.options.unsupportedJSFeatures.Has(compat.Class) && class.ExtendsOrNil.Data != nil {
+		result.es5DerivedClass = &es5DerivedClass{
+			superRef: p.newSymbol(js_ast.SymbolOther, "_super"),
+		}
+		p.currentScope.Generated = append(p.currentScope.Generated, result.es5DerivedClass.superRef)
+	}
+
 	// Insert a shadowing name that spans the whole class, which matches
 	// JavaScript's semantics. The class body (and extends clause) "captures" the
 	// original value of the name. This matters for class statements because the
@@ -10662,6 +12111,12 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class, defaul
 	p.pushScopeForVisitPass(js_ast.ScopeClassBody, class.BodyLoc)
 	defer p.popScope()
 
+	if result.es5DerivedClass != nil {
+		result.es5DerivedClass.thisRef = p.newSymbol(js_ast.SymbolOther, "_this")
+		p.currentScope.Generated = append(p.currentScope.Generated, result.es5DerivedClass.thisRef)
+	}
+	ctorFn := classConstructorFn(class)
+
 	end := 0
 
 	for i := range class.Properties {
@@ -10707,7 +12162,7 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class, defaul
 			continue
 		}
 
-		property.TSDecorators = p.visitTSDecorators(property.TSDecorators, tsDecoratorScope)
+		property.Decorators = p.visitDecorators(property.Decorators, decoratorScope)
 
 		// Special-case certain expressions to allow them here
 		switch k := property.Key.Data.(type) {
@@ -10717,6 +12172,17 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class, defaul
 		case *js_ast.EMangledProp:
 			k.Ref = p.symbolForMangledProp(p.loadNameFromRef(k.Ref))
 
+			// Track method definitions separately from other uses so that the linker
+			// can remove methods that are never referenced. Decorators can observe
+			// methods without referencing them by name, so those are left alone.
+			if !p.isControlFlowDead && len(class.Decorators) == 0 && len(property.Decorators) == 0 &&
+				(property.Flags.Has(js_ast.PropertyIsMethod) || property.Kind == js_ast.PropertyGet || property.Kind == js_ast.PropertySet) {
+				if p.mangledPropMethodCounts == nil {
+					p.mangledPropMethodCounts = make(map[js_ast.Ref]uint32)
+				}
+				p.mangledPropMethodCounts[k.Ref]++
+			}
+
 		default:
 			key, _ := p.visitExprInOut(property.Key, exprIn{
 				shouldMangleStringsAsProps: true,
@@ -10765,6 +12231,17 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class, defaul
 		p.fnOnlyDataVisit.isNewTargetAllowed = true
 		p.fnOnlyDataVisit.isInStaticClassContext = property.Flags.Has(js_ast.PropertyIsStatic)
 		p.fnOnlyDataVisit.classNameRef = &result.shadowRef
+		p.fnOnlyDataVisit.classCtorFn = ctorFn
+		p.fnOnlyDataVisit.newTargetFn = nil
+		if !property.Flags.Has(js_ast.PropertyIsStatic) {
+			p.fnOnlyDataVisit.es5DerivedClass = result.es5DerivedClass
+		}
+
+		// Methods are moved outside of the class body when classes are lowered
+		// to ES5, so "super" property accesses must be rewritten everywhere
+		if p.options.unsupportedJSFeatures.Has(compat.Class) {
+			p.fnOrArrowDataVisit.shouldLowerSuperPropertyAccess = true
+		}
 
 		// We need to explicitly assign the name to the property initializer if it
 		// will be transformed such that it is no longer an inline initializer.
@@ -10790,13 +12267,17 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class, defaul
 
 		if property.ValueOrNil.Data != nil {
 			p.propMethodValue = property.ValueOrNil.Data
-			p.propMethodTSDecoratorScope = tsDecoratorScope
+			p.propMethodDecoratorScope = decoratorScope
+			p.loweredCtorArgsStmtCount = 0
 			if nameToKeep != "" {
 				wasAnonymousNamedExpr := p.isAnonymousNamedExpr(property.ValueOrNil)
 				property.ValueOrNil = p.maybeKeepExprSymbolName(p.visitExpr(property.ValueOrNil), nameToKeep, wasAnonymousNamedExpr)
 			} else {
 				property.ValueOrNil = p.visitExpr(property.ValueOrNil)
 			}
+			if fn, ok := property.ValueOrNil.Data.(*js_ast.EFunction); ok && &fn.Fn == ctorFn {
+				result.loweredCtorArgsStmtCount = p.loweredCtorArgsStmtCount
+			}
 		}
 
 		if property.InitializerOrNil.Data != nil {
@@ -10882,9 +12363,9 @@ func fnBodyContainsUseStrict(body []js_ast.Stmt) (logger.Loc, bool) {
 }
 
 type visitArgsOpts struct {
-	body             []js_ast.Stmt
-	tsDecoratorScope *js_ast.Scope
-	hasRestArg       bool
+	body           []js_ast.Stmt
+	decoratorScope *js_ast.Scope
+	hasRestArg     bool
 
 	// This is true if the function is an arrow function or a method
 	isUniqueFormalParameters bool
@@ -10913,7 +12394,7 @@ func (p *parser) visitArgs(args []js_ast.Arg, opts visitArgsOpts) {
 
 	for i := range args {
 		arg := &args[i]
-		arg.TSDecorators = p.visitTSDecorators(arg.TSDecorators, opts.tsDecoratorScope)
+		arg.Decorators = p.visitDecorators(arg.Decorators, opts.decoratorScope)
 		p.visitBinding(arg.Binding, bindingOpts{
 			duplicateArgCheck: duplicateArgCheck,
 		})
@@ -11253,6 +12734,16 @@ func (p *parser) maybeRewritePropertyAccess(
 	isTemplateTag bool,
 	preferQuotedKey bool,
 ) (js_ast.Expr, bool) {
+	// Track property reads off of default imports. If a default import is only
+	// ever used to read properties with static names, the linker can omit the
+	// other properties when the default export is the object from a JSON file.
+	if id, ok := target.Data.(*js_ast.EImportIdentifier); ok && assignTarget == js_ast.AssignTargetNone && !isDeleteTarget && !p.isControlFlowDead {
+		if reads, ok := p.defaultImportReads[id.Ref]; ok {
+			reads.names[name] = true
+			reads.count++
+		}
+	}
+
 	if id, ok := target.Data.(*js_ast.EIdentifier); ok {
 		// Rewrite property accesses on explicit namespace imports as an identifier.
 		// This lets us replace them easily in the printer to rebind them to
@@ -11633,9 +13124,224 @@ func (p *parser) valueForThis(
 	return js_ast.Expr{}, false
 }
 
+// This returns the path in "new URL('./file', import.meta.url)", which is the
+// standard way to reference a file relative to the current module. Only
+// relative paths are returned since only those refer to files on the file
+// system that can be bundled.
+func (p *parser) relativePathForNewURL(e *js_ast.ENew) (string, bool) {
+	if len(e.Args) != 2 || !p.isDotOrIndexDefineMatch(e.Target, []string{"URL"}) ||
+		!p.isDotOrIndexDefineMatch(e.Args[1], []string{"import", "meta", "url"}) {
+		return "", false
+	}
+
+	str, ok := e.Args[0].Data.(*js_ast.EString)
+	if !ok {
+		return "", false
+	}
+
+	path := helpers.UTF16ToString(str.Value)
+	if !strings.HasPrefix(path, "./") && !strings.HasPrefix(path, "../") {
+		return "", false
+	}
+
+	// Ignore files if the control flow is provably dead here. We don't want
+	// to spend time scanning the file if it will never be used.
+	if p.isControlFlowDead {
+		return "", false
+	}
+
+	return path, true
+}
+
+// This handles "new Worker(new URL('./worker.js', import.meta.url))". The
+// worker script is bundled separately as its own entry point and the path is
+// rewritten to point to the resulting output file. Appending "?inline" to the
+// path (or enabling inline workers for the whole build) embeds the worker's
+// code directly instead. This returns true if the worker is an inline worker,
+// in which case the caller must wrap the first argument in a call to the
+// "__toBlobURL" runtime helper after visiting it.
+func (p *parser) maybeRecordWorkerImport(e *js_ast.ENew) bool {
+	if !p.isDotOrIndexDefineMatch(e.Target, []string{"Worker"}) && !p.isDotOrIndexDefineMatch(e.Target, []string{"SharedWorker"}) {
+		return false
+	}
+
+	url, ok := e.Args[0].Data.(*js_ast.ENew)
+	if !ok {
+		return false
+	}
+
+	path, ok := p.relativePathForNewURL(url)
+	if !ok {
+		return false
+	}
+
+	var flags ast.ImportRecordFlags
+	if strings.HasSuffix(path, "?inline") {
+		path = strings.TrimSuffix(path, "?inline")
+		flags |= ast.InlineWorker
+	} else if p.options.inlineWorkers {
+		flags |= ast.InlineWorker
+	}
+
+	importRecordIndex := p.addImportRecord(ast.ImportWorker, url.Args[0].Loc, path, nil, flags)
+	p.importRecordsForCurrentPart = append(p.importRecordsForCurrentPart, importRecordIndex)
+	pathExpr := js_ast.Expr{Loc: url.Args[0].Loc, Data: &js_ast.EImportPath{ImportRecordIndex: importRecordIndex}}
+
+	// Inline workers don't need the "new URL()" wrapper at all
+	if flags.Has(ast.InlineWorker) {
+		e.Args[0] = pathExpr
+		return true
+	}
+
+	url.Args[0] = pathExpr
+	return false
+}
+
+// This handles "new URL('./file.png', import.meta.url)" outside of a worker.
+// The file is treated as an asset and the path is rewritten to point to the
+// file that the loader for that file emits into the output directory.
+func (p *parser) maybeRecordNewURLImport(e *js_ast.ENew) {
+	if path, ok := p.relativePathForNewURL(e); ok {
+		importRecordIndex := p.addImportRecord(ast.ImportNewURL, e.Args[0].Loc, path, nil, 0)
+		p.importRecordsForCurrentPart = append(p.importRecordsForCurrentPart, importRecordIndex)
+		e.Args[0].Data = &js_ast.EImportPath{ImportRecordIndex: importRecordIndex}
+	}
+}
+
+// This handles webpack's "require.context(directory, useSubdirectories,
+// regExp, mode)" feature. All arguments must be literals since the directory
+// is searched at build time. Only the default "sync" mode is supported.
+func (p *parser) maybeRequireContext(loc logger.Loc, e *js_ast.ECall) (js_ast.Expr, bool) {
+	dir, ok := e.Args[0].Data.(*js_ast.EString)
+	if !ok {
+		return js_ast.Expr{}, false
+	}
+	context := ast.RequireContext{
+		RegExp:            "/^\\.\\/.*$/",
+		UseSubdirectories: true,
+	}
+	if len(e.Args) > 1 {
+		useSubdirectories, ok := e.Args[1].Data.(*js_ast.EBoolean)
+		if !ok {
+			return js_ast.Expr{}, false
+		}
+		context.UseSubdirectories = useSubdirectories.Value
+	}
+	if len(e.Args) > 2 {
+		regExp, ok := e.Args[2].Data.(*js_ast.ERegExp)
+		if !ok {
+			return js_ast.Expr{}, false
+		}
+		context.RegExp = regExp.Value
+	}
+	if len(e.Args) > 3 {
+		if mode, ok := e.Args[3].Data.(*js_ast.EString); !ok || !helpers.UTF16EqualsString(mode.Value, "sync") {
+			r := js_lexer.RangeOfIdentifier(p.source, e.Target.Loc)
+			p.log.AddID(logger.MsgID_JS_UnsupportedRequireCall, logger.Debug, &p.tracker, r,
+				"This call to \"require.context\" will not be bundled because only the \"sync\" mode is supported")
+			return js_ast.Expr{}, false
+		}
+	}
+
+	// Ignore calls to require.context() if the control flow is provably dead
+	// here. We don't want to spend time scanning the directory if it will
+	// never be used.
+	if p.isControlFlowDead {
+		return js_ast.Expr{Loc: loc, Data: js_ast.ENullShared}, true
+	}
+
+	importRecordIndex := p.addImportRecord(ast.ImportRequire, e.Args[0].Loc, helpers.UTF16ToString(dir.Value), nil, 0)
+	record := &p.importRecords[importRecordIndex]
+	record.RequireContext = &context
+	if p.fnOrArrowDataVisit.tryBodyCount != 0 {
+		record.Flags |= ast.HandlesImportErrors
+		record.ErrorHandlerLoc = p.fnOrArrowDataVisit.tryCatchLoc
+	}
+	p.importRecordsForCurrentPart = append(p.importRecordsForCurrentPart, importRecordIndex)
+
+	// The generated module exports the context function using "module.exports"
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ERequireString{
+		ImportRecordIndex: importRecordIndex,
+		CloseParenLoc:     e.CloseParenLoc,
+	}}, true
+}
+
+// This returns the user-provided replacement for "import.meta" (if any), which
+// is either an entity name such as "globalThis.meta" or a JSON object. A new
+// copy of the JSON object is returned each time since it ends up in the AST.
+func (p *parser) importMetaShim() (config.DefineExpr, *js_ast.EObject) {
+	if p.options.importMetaShim == "" {
+		return config.DefineExpr{}, nil
+	}
+	defineExpr, injectExpr := ParseDefineExprOrJSON(p.options.importMetaShim)
+	object, _ := injectExpr.(*js_ast.EObject)
+	return defineExpr, object
+}
+
+// Returns true if the user-provided replacement for "import.meta" provides
+// this property. An entity name is assumed to provide every property.
+func (p *parser) importMetaShimHasProperty(name string) bool {
+	defineExpr, object := p.importMetaShim()
+	if defineExpr.Parts != nil {
+		return true
+	}
+	if object != nil {
+		for _, property := range object.Properties {
+			if str, ok := property.Key.Data.(*js_ast.EString); ok && helpers.UTF16EqualsString(str.Value, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *parser) valueForImportMetaURL(loc logger.Loc) (js_ast.Expr, bool) {
+	if p.options.unsupportedJSFeatures.Has(compat.ImportMeta) || p.options.mode == config.ModePassThrough ||
+		p.options.outputFormat.KeepESMImportExportSyntax() || p.importMetaShimHasProperty("url") {
+		return js_ast.Expr{}, false
+	}
+
+	switch p.options.platform {
+	case config.PlatformBrowser:
+		// Use the URL of the script that's running, which is only available when
+		// the script is first evaluated. The runtime helper captures it then.
+		return p.importFromRuntime(loc, "__importMetaURL"), true
+
+	case config.PlatformNode:
+		// Use "__filename" below
+
+	default:
+		return js_ast.Expr{}, false
+	}
+
+	// Generate "require('url').pathToFileURL(__filename).href" and then visit
+	// it so that "require" and "__filename" are handled like they normally are
+	value := js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+		Target: js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+			Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+				Target: js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+					Target: js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.storeNameInRef(js_lexer.MaybeSubstring{String: "require"})}},
+					Args:   []js_ast.Expr{{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("url")}}},
+				}},
+				Name:    "pathToFileURL",
+				NameLoc: loc,
+			}},
+			Args: []js_ast.Expr{{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.storeNameInRef(js_lexer.MaybeSubstring{String: "__filename"})}}},
+			Kind: js_ast.TargetWasOriginallyPropertyAccess,
+		}},
+		Name:    "href",
+		NameLoc: loc,
+	}}
+	return p.visitExpr(value), true
+}
+
+func (p *parser) isImportMetaUnavailable() bool {
+	return p.options.unsupportedJSFeatures.Has(compat.ImportMeta) ||
+		(p.options.mode != config.ModePassThrough && !p.options.outputFormat.KeepESMImportExportSyntax())
+}
+
 func (p *parser) valueForImportMeta(loc logger.Loc) (js_ast.Expr, bool) {
-	if p.options.unsupportedJSFeatures.Has(compat.ImportMeta) ||
-		(p.options.mode != config.ModePassThrough && !p.options.outputFormat.KeepESMImportExportSyntax()) {
+	if p.isImportMetaUnavailable() {
 		// Generate the variable if it doesn't exist yet
 		if p.importMetaRef == js_ast.InvalidRef {
 			p.importMetaRef = p.newSymbol(js_ast.SymbolOther, "import_meta")
@@ -11650,6 +13356,82 @@ func (p *parser) valueForImportMeta(loc logger.Loc) (js_ast.Expr, bool) {
 	return js_ast.Expr{}, false
 }
 
+func (p *parser) valueForDirnameOrFilename(loc logger.Loc, name string, isTypeofTarget bool) (js_ast.Expr, bool) {
+	switch p.options.dirname {
+	case config.DirnameSource:
+		// Substitute the path of the original source file relative to the current
+		// working directory. Absolute paths would make the output depend on where
+		// it was built, and non-file paths have no directory to speak of.
+		if p.source.KeyPath.Namespace != "file" {
+			return js_ast.Expr{}, false
+		}
+		text := p.source.PrettyPath
+		if name == "__dirname" {
+			if slash := strings.LastIndexByte(text, '/'); slash > 0 {
+				text = text[:slash]
+			} else if slash == 0 {
+				text = "/"
+			} else {
+				text = "."
+			}
+		}
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(text)}}, true
+
+	case config.DirnameRuntime:
+		switch p.options.outputFormat {
+		case config.FormatCommonJS:
+			// These are already available at run-time in CommonJS
+			return js_ast.Expr{}, false
+
+		case config.FormatESModule:
+			// The linker defines these using "import.meta.url" for node
+			if p.options.platform == config.PlatformNode {
+				return js_ast.Expr{}, false
+			}
+
+			// Otherwise compute them from "import.meta.url" directly:
+			//
+			//   __filename => new URL(import.meta.url).pathname
+			//   __dirname => new URL(".", import.meta.url).pathname
+			//
+			args := []js_ast.Expr{{Loc: loc, Data: &js_ast.EDot{
+				Target:  js_ast.Expr{Loc: loc, Data: &js_ast.EImportMeta{}},
+				Name:    "url",
+				NameLoc: loc,
+			}}}
+			if name == "__dirname" {
+				args = append([]js_ast.Expr{{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(".")}}}, args...)
+			}
+			return js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+				Target: js_ast.Expr{Loc: loc, Data: &js_ast.ENew{
+					Target: p.handleIdentifier(loc, &js_ast.EIdentifier{Ref: p.findSymbol(loc, "URL").ref}, identifierOpts{}),
+					Args:   args,
+				}},
+				Name:    "pathname",
+				NameLoc: loc,
+			}}, true
+		}
+
+		if !isTypeofTarget {
+			r := js_lexer.RangeOfIdentifier(p.source, loc)
+			p.log.AddError(&p.tracker, r, fmt.Sprintf("There is no run-time equivalent of %q for the %q output format", name, p.options.outputFormat.String()))
+		}
+		return js_ast.Expr{}, false
+
+	case config.DirnameError:
+		// Allow feature detection with "typeof" to still work
+		if !isTypeofTarget {
+			r := js_lexer.RangeOfIdentifier(p.source, loc)
+			p.log.AddErrorWithNotes(&p.tracker, r, fmt.Sprintf("Cannot use %q in bundled code", name),
+				[]logger.MsgData{{Text: fmt.Sprintf("The value of %q depends on where the original source file was located, "+
+					"which is usually not where the bundled code is run. References to it have been configured to be an error.", name)}})
+		}
+		return js_ast.Expr{}, false
+	}
+
+	return js_ast.Expr{}, false
+}
+
 func locAfterOp(e *js_ast.EBinary) logger.Loc {
 	if e.Left.Loc.Start < e.Right.Loc.Start {
 		return e.Right.Loc
@@ -11853,7 +13635,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 	}
 
 	switch e := expr.Data.(type) {
-	case *js_ast.ENull, *js_ast.ESuper, *js_ast.EBoolean, *js_ast.EBigInt, *js_ast.EUndefined:
+	case *js_ast.ENull, *js_ast.ESuper, *js_ast.EBoolean, *js_ast.EBigInt, *js_ast.EUndefined, *js_ast.EImportPath:
 
 	case *js_ast.ERegExp:
 		// "/pattern/flags" => "new RegExp('pattern', 'flags')"
@@ -11880,6 +13662,8 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 	case *js_ast.ENewTarget:
 		if !p.fnOnlyDataVisit.isNewTargetAllowed {
 			p.log.AddError(&p.tracker, e.Range, "Cannot use \"new.target\" here:")
+		} else if p.options.unsupportedJSFeatures.Has(compat.NewTarget) {
+			return p.lowerNewTarget(expr.Loc), exprOut{}
 		}
 
 	case *js_ast.EString:
@@ -11900,6 +13684,18 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			}
 		}
 
+		// Count string literals so that the linker can hoist repeated ones into
+		// shared variables. Strings used as property names are skipped because
+		// they are typically printed as identifiers instead.
+		if p.options.minifySyntax && p.options.minifyIdentifiers && !in.shouldMangleStringsAsProps {
+			if text, _, ok := helpers.UTF16ToStringWithValidation(e.Value); ok {
+				if p.stringLiteralUses == nil {
+					p.stringLiteralUses = make(map[string]uint32)
+				}
+				p.stringLiteralUses[text]++
+			}
+		}
+
 	case *js_ast.ENumber:
 		if p.legacyOctalLiterals != nil && p.isStrictMode() {
 			if r, ok := p.legacyOctalLiterals[expr.Data]; ok {
@@ -11915,10 +13711,8 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			return value, exprOut{}
 		}
 
-		// Capture "this" inside arrow functions that will be lowered into normal
-		// function expressions for older language environments
-		if p.fnOrArrowDataVisit.isArrow && p.options.unsupportedJSFeatures.Has(compat.Arrow) && p.fnOnlyDataVisit.isThisNested {
-			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: p.captureThis()}}, exprOut{}
+		if value, ok := p.valueForLoweredThis(expr.Loc); ok {
+			return value, exprOut{}
 		}
 
 	case *js_ast.EImportMeta:
@@ -11953,7 +13747,26 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		}
 
 		// Warn about "import.meta" if it's not replaced by a define
-		if p.options.unsupportedJSFeatures.Has(compat.ImportMeta) {
+		propertyName := ""
+		if p.dotOrIndexTarget == e {
+			propertyName = p.importMetaPropertyName
+		}
+		p.importMetaPropertyName = ""
+
+		// Substitute the user-provided replacement if "import.meta" isn't available
+		if p.isImportMetaUnavailable() {
+			if defineExpr, _ := p.importMetaShim(); defineExpr.Parts != nil {
+				return p.instantiateDefineExpr(expr.Loc, defineExpr, identifierOpts{
+					assignTarget:   in.assignTarget,
+					isCallTarget:   isCallTarget,
+					isDeleteTarget: isDeleteTarget,
+				}), exprOut{}
+			}
+		}
+
+		if _, object := p.importMetaShim(); object != nil && (propertyName == "" || p.importMetaShimHasProperty(propertyName)) {
+			// The user-provided replacement provides this, so there's nothing to warn about
+		} else if p.options.unsupportedJSFeatures.Has(compat.ImportMeta) {
 			r := logger.Range{Loc: expr.Loc, Len: e.RangeLen}
 			p.markSyntaxFeature(compat.ImportMeta, r)
 		} else if p.options.mode != config.ModePassThrough && !p.options.outputFormat.KeepESMImportExportSyntax() {
@@ -11962,9 +13775,17 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			if p.suppressWarningsAboutWeirdCode || p.fnOrArrowDataVisit.tryBodyCount > 0 {
 				kind = logger.Debug
 			}
-			p.log.AddIDWithNotes(logger.MsgID_JS_EmptyImportMeta, kind, &p.tracker, r, fmt.Sprintf(
-				"\"import.meta\" is not available with the %q output format and will be empty", p.options.outputFormat.String()),
-				[]logger.MsgData{{Text: "You need to set the output format to \"esm\" for \"import.meta\" to work correctly."}})
+			if propertyName != "" {
+				name := "import.meta." + propertyName
+				p.log.AddIDWithNotes(logger.MsgID_JS_EmptyImportMeta, kind, &p.tracker, r, fmt.Sprintf(
+					"%q is not available with the %q output format and will be undefined", name, p.options.outputFormat.String()),
+					[]logger.MsgData{{Text: fmt.Sprintf("You can use the \"import meta shim\" or \"define\" features to provide a value for %q, "+
+						"or you can set the output format to \"esm\" for \"import.meta\" to work correctly.", name)}})
+			} else {
+				p.log.AddIDWithNotes(logger.MsgID_JS_EmptyImportMeta, kind, &p.tracker, r, fmt.Sprintf(
+					"\"import.meta\" is not available with the %q output format and will be empty", p.options.outputFormat.String()),
+					[]logger.MsgData{{Text: "You need to set the output format to \"esm\" for \"import.meta\" to work correctly."}})
+			}
 		}
 
 		// Convert "import.meta" to a variable if it's not supported in the output format
@@ -11985,6 +13806,9 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		result := p.findSymbol(expr.Loc, name)
 		e.MustKeepDueToWithStmt = result.isInsideWithScope
 		e.Ref = result.ref
+		if p.loweredLoopLets != nil {
+			p.recordLoweredLoopLetUse(expr.Loc, result.ref, in.assignTarget != js_ast.AssignTargetNone)
+		}
 
 		// Handle assigning to a constant
 		if in.assignTarget != js_ast.AssignTargetNone {
@@ -12049,6 +13873,16 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			}
 		}
 
+		// Handle references to the CommonJS "__dirname" and "__filename" globals
+		if p.options.dirname != config.DirnameDefault && p.options.mode == config.ModeBundle &&
+			(name == "__dirname" || name == "__filename") && p.symbols[e.Ref.InnerIndex].Kind == js_ast.SymbolUnbound &&
+			!result.isInsideWithScope && in.assignTarget == js_ast.AssignTargetNone && !isDeleteTarget {
+			if value, ok := p.valueForDirnameOrFilename(expr.Loc, name, e == p.typeofTarget); ok {
+				p.ignoreUsage(e.Ref)
+				return value, exprOut{}
+			}
+		}
+
 		return p.handleIdentifier(expr.Loc, e, identifierOpts{
 				assignTarget:            in.assignTarget,
 				isCallTarget:            isCallTarget,
@@ -12398,6 +14232,9 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		// a plain string literal instead).
 		if p.options.minifySyntax {
 			expr = js_ast.InlineStringsIntoTemplate(expr.Loc, e)
+
+			// "`a${x}`" => "'a' + x"
+			expr = js_ast.ShortenStringConcatenation(expr)
 		}
 
 		shouldLowerTemplateLiteral := p.options.unsupportedJSFeatures.Has(compat.TemplateLiteral)
@@ -12717,6 +14554,13 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 				}
 			}
 
+			// "'a' + x + 'b'" => "`a${x}b`"
+			if p.options.minifySyntax && !p.options.unsupportedJSFeatures.Has(compat.TemplateLiteral) {
+				if result := js_ast.ShortenStringConcatenation(expr); result.Data != expr.Data {
+					return result, exprOut{}
+				}
+			}
+
 		case js_ast.BinOpPow:
 			// Lower the exponentiation operator for browsers that don't support it
 			if p.options.unsupportedJSFeatures.Has(compat.ExponentOperator) {
@@ -12977,6 +14821,16 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			}
 		}
 
+		// Shim "import.meta.url" if "import.meta" isn't available
+		if _, ok := e.Target.Data.(*js_ast.EImportMeta); ok {
+			if e.Name == "url" && in.assignTarget == js_ast.AssignTargetNone && !isDeleteTarget {
+				if value, ok := p.valueForImportMetaURL(expr.Loc); ok {
+					return value, exprOut{}
+				}
+			}
+			p.importMetaPropertyName = e.Name
+		}
+
 		p.dotOrIndexTarget = e.Target.Data
 		target, out := p.visitExprInOut(e.Target, exprIn{
 			hasChainParent: e.OptionalChain == js_ast.OptionalChainContinue,
@@ -13221,6 +15075,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 	case *js_ast.EUnary:
 		switch e.Op {
 		case js_ast.UnOpTypeof:
+			p.typeofTarget = e.Value.Data
 			e.Value, _ = p.visitExprInOut(e.Value, exprIn{assignTarget: e.Op.UnaryAssignTarget()})
 
 			// Compile-time "typeof" evaluation
@@ -13418,7 +15273,6 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			if e.CommaAfterSpread.Start != 0 {
 				p.log.AddError(&p.tracker, logger.Range{Loc: e.CommaAfterSpread, Len: 1}, "Unexpected \",\" after rest pattern")
 			}
-			p.markSyntaxFeature(compat.Destructuring, logger.Range{Loc: expr.Loc, Len: 1})
 		}
 		hasSpread := false
 		for i, item := range e.Items {
@@ -13452,12 +15306,20 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			e.Items = js_ast.InlineSpreadsOfArrayLiterals(e.Items)
 		}
 
+		// "[1, ...a, 2]" => "[1].concat(__toArray(a), [2])"
+		if hasSpread && in.assignTarget == js_ast.AssignTargetNone && p.options.unsupportedJSFeatures.Has(compat.ArraySpread) {
+			for _, item := range e.Items {
+				if _, ok := item.Data.(*js_ast.ESpread); ok {
+					return p.lowerSpreadItems(expr.Loc, e.Items, true), exprOut{}
+				}
+			}
+		}
+
 	case *js_ast.EObject:
 		if in.assignTarget != js_ast.AssignTargetNone {
 			if e.CommaAfterSpread.Start != 0 {
 				p.log.AddError(&p.tracker, logger.Range{Loc: e.CommaAfterSpread, Len: 1}, "Unexpected \",\" after rest pattern")
 			}
-			p.markSyntaxFeature(compat.Destructuring, logger.Range{Loc: expr.Loc, Len: 1})
 		}
 
 		hasSpread := false
@@ -13610,7 +15472,12 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 
 			// Object expressions represent both object literals and binding patterns.
 			// Only lower object spread if we're an object literal, not a binding pattern.
-			value := p.lowerObjectSpread(expr.Loc, e)
+			var value js_ast.Expr
+			if p.options.unsupportedJSFeatures.Has(compat.ObjectExtensions) {
+				value = p.lowerObjectExtensions(expr.Loc, e)
+			} else {
+				value = p.lowerObjectSpread(expr.Loc, e)
+			}
 
 			// If we generated and used the temporary variable for a lowered "super"
 			// property reference inside a lowered "async" method, then initialize
@@ -13642,11 +15509,15 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			whyLoc := e.OptionsOrNil.Loc
 
 			// However, make a special case for an additional argument that contains
-			// only an "assert" clause. In that case we can split this AST node.
+			// only an "assert" or "with" clause. In that case we can split this AST node.
 			if object, ok := e.OptionsOrNil.Data.(*js_ast.EObject); ok {
 				if len(object.Properties) == 1 {
 					if prop := object.Properties[0]; prop.Kind == js_ast.PropertyNormal && !prop.Flags.Has(js_ast.PropertyIsComputed) && !prop.Flags.Has(js_ast.PropertyIsMethod) {
-						if str, ok := prop.Key.Data.(*js_ast.EString); ok && helpers.UTF16EqualsString(str.Value, "assert") {
+						if str, ok := prop.Key.Data.(*js_ast.EString); ok && (helpers.UTF16EqualsString(str.Value, "assert") || helpers.UTF16EqualsString(str.Value, "with")) {
+							keyword := ast.AssertKeyword
+							if helpers.UTF16EqualsString(str.Value, "with") {
+								keyword = ast.WithKeyword
+							}
 							if value, ok := prop.ValueOrNil.Data.(*js_ast.EObject); ok {
 								entries := []ast.AssertEntry{}
 								for _, p := range value.Properties {
@@ -13683,6 +15554,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 								if entries != nil {
 									assertions = &ast.ImportAssertions{
 										Entries:            entries,
+										Keyword:            keyword,
 										AssertLoc:          prop.Key.Loc,
 										InnerOpenBraceLoc:  prop.ValueOrNil.Loc,
 										InnerCloseBraceLoc: value.CloseBraceLoc,
@@ -13692,11 +15564,11 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 									why = ""
 								}
 							} else {
-								why = "the value for \"assert\" was not an object literal"
+								why = fmt.Sprintf("the value for %q was not an object literal", keyword.String())
 								whyLoc = prop.ValueOrNil.Loc
 							}
 						} else {
-							why = "this property was not called \"assert\""
+							why = "this property was not called \"assert\" or \"with\""
 							whyLoc = prop.Key.Loc
 						}
 					} else {
@@ -13704,7 +15576,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 						whyLoc = prop.Key.Loc
 					}
 				} else {
-					why = "the second argument was not an object literal with a single property called \"assert\""
+					why = "the second argument was not an object literal with a single property called \"assert\" or \"with\""
 					whyLoc = e.OptionsOrNil.Loc
 				}
 			}
@@ -13726,7 +15598,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 				// just not print them because they may have important side effects.
 				// Attempt to discard them without changing side effects and generate an
 				// error if that isn't possible.
-				if p.options.unsupportedJSFeatures.Has(compat.ImportAssertions) {
+				if p.options.unsupportedJSFeatures.Has(compat.ImportAssertions) && p.options.unsupportedJSFeatures.Has(compat.ImportAttributes) {
 					if js_ast.ExprCanBeRemovedIfUnused(e.OptionsOrNil, p.isUnbound) {
 						e.OptionsOrNil = js_ast.Expr{}
 					} else {
@@ -13826,6 +15698,24 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		}), exprOut{}
 
 	case *js_ast.ECall:
+		// Recognize "require.context()" calls. This must be done before visiting
+		// since visiting may replace "require" with a reference to "__require".
+		if p.options.mode == config.ModeBundle && e.OptionalChain == js_ast.OptionalChainNone && len(e.Args) >= 1 && len(e.Args) <= 4 &&
+			p.isDotOrIndexDefineMatch(e.Target, []string{"require", "context"}) {
+			if value, ok := p.maybeRequireContext(expr.Loc, e); ok {
+				return value, exprOut{}
+			}
+		}
+
+		// Recognize "import.meta.glob()" calls. This must be done before visiting
+		// since visiting may replace "import.meta".
+		if p.options.mode == config.ModeBundle && e.OptionalChain == js_ast.OptionalChainNone && len(e.Args) >= 1 && len(e.Args) <= 2 &&
+			p.isDotOrIndexDefineMatch(e.Target, []string{"import", "meta", "glob"}) {
+			if value, ok := p.maybeImportGlob(expr.Loc, e); ok {
+				return value, exprOut{}
+			}
+		}
+
 		p.callTarget = e.Target.Data
 
 		// Track ".then().catch()" chains
@@ -13949,6 +15839,39 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			e.Args = js_ast.InlineSpreadsOfArrayLiterals(e.Args)
 		}
 
+		// "Number('3')" => "3"
+		// "'abc'.charCodeAt(1)" => "98"
+		// "Math.pow(2, 8)" => "256"
+		if p.options.minifySyntax && !hasSpread && e.OptionalChain == js_ast.OptionalChainNone {
+			switch t := target.Data.(type) {
+			case *js_ast.EIdentifier:
+				if p.symbols[t.Ref.InnerIndex].Kind == js_ast.SymbolUnbound {
+					if result := js_ast.FoldKnownGlobalCall(expr.Loc, p.symbols[t.Ref.InnerIndex].OriginalName, e.Args); result.Data != nil {
+						p.ignoreUsage(t.Ref)
+						return result, exprOut{}
+					}
+				}
+
+			case *js_ast.EDot:
+				if t.OptionalChain == js_ast.OptionalChainNone {
+					switch object := t.Target.Data.(type) {
+					case *js_ast.EString:
+						if result := js_ast.FoldStringMethodCall(expr.Loc, object.Value, t.Name, e.Args); result.Data != nil {
+							return result, exprOut{}
+						}
+
+					case *js_ast.EIdentifier:
+						if symbol := &p.symbols[object.Ref.InnerIndex]; symbol.Kind == js_ast.SymbolUnbound {
+							if result := js_ast.FoldKnownGlobalMethodCall(expr.Loc, symbol.OriginalName, t.Name, e.Args); result.Data != nil {
+								p.ignoreUsage(object.Ref)
+								return result, exprOut{}
+							}
+						}
+					}
+				}
+			}
+		}
+
 		switch t := target.Data.(type) {
 		case *js_ast.EImportIdentifier:
 			// If this function is inlined, allow it to be tree-shaken
@@ -14082,6 +16005,8 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 				p.recordUsage(p.superCtorRef)
 				target.Data = &js_ast.EIdentifier{Ref: p.superCtorRef}
 				e.Target.Data = target.Data
+			} else if class := p.fnOnlyDataVisit.es5DerivedClass; class != nil {
+				return p.lowerSuperCallForES5(expr.Loc, e.Args, class), exprOut{}
 			}
 		}
 
@@ -14102,7 +16027,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			if target, loc, private := p.extractPrivateIndex(e.Target); private != nil {
 				// "foo.#bar(123)" => "__privateGet(_a = foo, #bar).call(_a, 123)"
 				targetFunc, targetWrapFunc := p.captureValueWithPossibleSideEffects(target.Loc, 2, target, valueCouldBeMutated)
-				return targetWrapFunc(js_ast.Expr{Loc: target.Loc, Data: &js_ast.ECall{
+				call := &js_ast.ECall{
 					Target: js_ast.Expr{Loc: target.Loc, Data: &js_ast.EDot{
 						Target:  p.lowerPrivateGet(targetFunc(), loc, private),
 						Name:    "call",
@@ -14111,7 +16036,11 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 					Args:                   append([]js_ast.Expr{targetFunc()}, e.Args...),
 					CanBeUnwrappedIfUnused: e.CanBeUnwrappedIfUnused,
 					Kind:                   js_ast.TargetWasOriginallyPropertyAccess,
-				}}), exprOut{}
+				}
+				if hasSpread && p.options.unsupportedJSFeatures.Has(compat.RestArgument) {
+					return targetWrapFunc(p.lowerCallSpread(target.Loc, call)), exprOut{}
+				}
+				return targetWrapFunc(js_ast.Expr{Loc: target.Loc, Data: call}), exprOut{}
 			}
 			p.maybeLowerSuperPropertyGetInsideCall(e)
 		}
@@ -14188,6 +16117,13 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			}
 		}
 
+		// Lower spread arguments for browsers that don't support them
+		if hasSpread && !containsOptionalChain && p.options.unsupportedJSFeatures.Has(compat.RestArgument) {
+			if _, ok := e.Target.Data.(*js_ast.ESuper); !ok {
+				return p.lowerCallSpread(expr.Loc, e), exprOut{}
+			}
+		}
+
 		out = exprOut{
 			childContainsOptionalChain: containsOptionalChain,
 			thisArgFunc:                out.thisArgFunc,
@@ -14202,6 +16138,15 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 	case *js_ast.ENew:
 		hasSpread := false
 
+		// Recognize "new Worker(new URL('./worker.js', import.meta.url))" and
+		// "new URL('./file.png', import.meta.url)". This must be done before
+		// visiting since visiting may replace "import.meta".
+		isInlineWorker := false
+		if p.options.mode == config.ModeBundle && len(e.Args) > 0 {
+			isInlineWorker = p.maybeRecordWorkerImport(e)
+			p.maybeRecordNewURLImport(e)
+		}
+
 		e.Target = p.visitExpr(e.Target)
 		p.warnAboutImportNamespaceCall(e.Target, exprKindNew)
 
@@ -14218,6 +16163,20 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			e.Args = js_ast.InlineSpreadsOfArrayLiterals(e.Args)
 		}
 
+		// "new foo(...bar)" => "__construct(foo, __toArray(bar))"
+		if hasSpread && p.options.unsupportedJSFeatures.Has(compat.RestArgument) {
+			for _, arg := range e.Args {
+				if _, ok := arg.Data.(*js_ast.ESpread); ok {
+					return p.callRuntime(expr.Loc, "__construct", []js_ast.Expr{e.Target, p.lowerSpreadItems(expr.Loc, e.Args, false)}), exprOut{}
+				}
+			}
+		}
+
+		// Inline workers are created from the worker's code using a blob URL
+		if isInlineWorker {
+			e.Args[0] = p.callRuntime(e.Args[0].Loc, "__toBlobURL", []js_ast.Expr{e.Args[0]})
+		}
+
 		p.maybeMarkKnownGlobalConstructorAsPure(e)
 
 	case *js_ast.EArrow:
@@ -14238,6 +16197,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			p.fnOnlyDataVisit.isInsideAsyncArrowFn = true
 		}
 
+		p.pushLoweredLoopLetClosure()
 		p.pushScopeForVisitPass(js_ast.ScopeFunctionArgs, expr.Loc)
 		p.visitArgs(e.Args, visitArgsOpts{
 			hasRestArg:               e.HasRestArg,
@@ -14249,6 +16209,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		p.popScope()
 		p.lowerFunction(&e.IsAsync, &e.Args, e.Body.Loc, &e.Body.Block, &e.PreferExpr, &e.HasRestArg, true /* isArrow */)
 		p.popScope()
+		loopLetCaptures := p.popLoweredLoopLetClosure()
 
 		if p.options.minifySyntax && len(e.Body.Block.Stmts) == 1 {
 			if s, ok := e.Body.Block.Stmts[0].Data.(*js_ast.SReturn); ok {
@@ -14267,17 +16228,22 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 
 		// Convert arrow functions to function expressions when lowering
 		if p.options.unsupportedJSFeatures.Has(compat.Arrow) {
-			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{
+			expr = js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{
 				Args:         e.Args,
 				Body:         e.Body,
 				ArgumentsRef: js_ast.InvalidRef,
 				IsAsync:      e.IsAsync,
 				HasRestArg:   e.HasRestArg,
-			}}}, exprOut{}
+			}}}
+		}
+
+		if len(loopLetCaptures) > 0 {
+			expr = p.wrapClosureCapturingLoopLets(expr, loopLetCaptures)
 		}
 
 	case *js_ast.EFunction:
-		p.visitFn(&e.Fn, expr.Loc, visitFnOpts{isClassMethod: e == p.propMethodValue})
+		isClassMethod := e == p.propMethodValue
+		loopLetCaptures := p.visitFn(&e.Fn, expr.Loc, visitFnOpts{isClassMethod: isClassMethod})
 		name := e.Fn.Name
 
 		// Remove unused function names when minifying
@@ -14291,6 +16257,14 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			expr = p.keepExprSymbolName(expr, p.symbols[name.Ref.InnerIndex].OriginalName)
 		}
 
+		if len(loopLetCaptures) > 0 {
+			if isClassMethod {
+				p.logUnwrappableClosureCapturingLoopLets(loopLetCaptures)
+			} else {
+				expr = p.wrapClosureCapturingLoopLets(expr, loopLetCaptures)
+			}
+		}
+
 	case *js_ast.EClass:
 		result := p.visitClass(expr.Loc, &e.Class, js_ast.InvalidRef)
 
@@ -14690,8 +16664,8 @@ type visitFnOpts struct {
 	isClassMethod bool
 }
 
-func (p *parser) visitFn(fn *js_ast.Fn, scopeLoc logger.Loc, opts visitFnOpts) {
-	var tsDecoratorScope *js_ast.Scope
+func (p *parser) visitFn(fn *js_ast.Fn, scopeLoc logger.Loc, opts visitFnOpts) (loopLetCaptures []js_ast.Ref) {
+	var decoratorScope *js_ast.Scope
 	oldFnOrArrowData := p.fnOrArrowDataVisit
 	oldFnOnlyData := p.fnOnlyDataVisit
 	p.fnOrArrowDataVisit = fnOrArrowDataVisit{
@@ -14703,27 +16677,39 @@ func (p *parser) visitFn(fn *js_ast.Fn, scopeLoc logger.Loc, opts visitFnOpts) {
 		isThisNested:       true,
 		isNewTargetAllowed: true,
 		argumentsRef:       &fn.ArgumentsRef,
+		newTargetFn:        fn,
 	}
 
 	if opts.isClassMethod {
-		tsDecoratorScope = p.propMethodTSDecoratorScope
+		decoratorScope = p.propMethodDecoratorScope
 		p.fnOnlyDataVisit.classNameRef = oldFnOnlyData.classNameRef
 		p.fnOnlyDataVisit.isInStaticClassContext = oldFnOnlyData.isInStaticClassContext
 		if oldFnOrArrowData.shouldLowerSuperPropertyAccess {
 			p.fnOrArrowDataVisit.shouldLowerSuperPropertyAccess = true
 		}
+
+		// Methods can't be constructed, so "new.target" is always undefined
+		// inside them. The class constructor is the exception.
+		if fn == oldFnOnlyData.classCtorFn {
+			p.fnOnlyDataVisit.classCtorFn = fn
+			p.fnOnlyDataVisit.es5DerivedClass = oldFnOnlyData.es5DerivedClass
+		} else {
+			p.fnOnlyDataVisit.newTargetFn = nil
+		}
 	}
 
 	if fn.Name != nil {
 		p.recordDeclaredSymbol(fn.Name.Ref)
 	}
 
+	p.pushLoweredLoopLetClosure()
 	p.pushScopeForVisitPass(js_ast.ScopeFunctionArgs, scopeLoc)
+	p.fnOnlyDataVisit.newTargetScope = p.currentScope
 	p.visitArgs(fn.Args, visitArgsOpts{
 		hasRestArg:               fn.HasRestArg,
 		body:                     fn.Body.Block.Stmts,
 		isUniqueFormalParameters: fn.IsUniqueFormalParameters,
-		tsDecoratorScope:         tsDecoratorScope,
+		decoratorScope:           decoratorScope,
 	})
 	p.pushScopeForVisitPass(js_ast.ScopeFunctionBody, fn.Body.Loc)
 	if fn.Name != nil {
@@ -14732,10 +16718,16 @@ func (p *parser) visitFn(fn *js_ast.Fn, scopeLoc logger.Loc, opts visitFnOpts) {
 	fn.Body.Block.Stmts = p.visitStmtsAndPrependTempRefs(fn.Body.Block.Stmts, prependTempRefsOpts{fnBodyLoc: &fn.Body.Loc, kind: stmtsFnBody})
 	p.popScope()
 	p.lowerFunction(&fn.IsAsync, &fn.Args, fn.Body.Loc, &fn.Body.Block, nil, &fn.HasRestArg, false /* isArrow */)
+	if fn.IsGenerator && !fn.IsAsync && p.options.unsupportedJSFeatures.Has(compat.Generator) {
+		p.lowerGeneratorBody(fn.Body.Loc, &fn.Body.Block)
+		fn.IsGenerator = false
+	}
 	p.popScope()
+	loopLetCaptures = p.popLoweredLoopLetClosure()
 
 	p.fnOrArrowDataVisit = oldFnOrArrowData
 	p.fnOnlyDataVisit = oldFnOnlyData
+	return
 }
 
 func (p *parser) recordExport(loc logger.Loc, alias string, ref js_ast.Ref) {
@@ -15031,12 +17023,19 @@ func (p *parser) scanForImportsAndExports(stmts []js_ast.Stmt) (result importsEx
 				}
 
 				if s.DefaultName != nil {
-					p.namedImports[s.DefaultName.Ref] = js_ast.NamedImport{
+					namedImport := js_ast.NamedImport{
 						Alias:             "default",
 						AliasLoc:          s.DefaultName.Loc,
 						NamespaceRef:      s.NamespaceRef,
 						ImportRecordIndex: s.ImportRecordIndex,
 					}
+
+					// Remember if every use of this import is a static property read
+					if reads, ok := p.defaultImportReads[s.DefaultName.Ref]; ok && reads.count == p.symbols[s.DefaultName.Ref.InnerIndex].UseCountEstimate {
+						namedImport.PropertyReadsOrNil = reads.names
+					}
+
+					p.namedImports[s.DefaultName.Ref] = namedImport
 				}
 
 				if s.StarNameLoc != nil {
@@ -15179,6 +17178,7 @@ func (p *parser) appendPart(parts []js_ast.Part, stmts []js_ast.Stmt) []js_ast.P
 	p.symbolUses = make(map[js_ast.Ref]js_ast.SymbolUse)
 	p.importSymbolPropertyUses = nil
 	p.symbolCallUses = nil
+	p.stringLiteralUses = nil
 	p.declaredSymbols = nil
 	p.importRecordsForCurrentPart = nil
 	p.scopesForCurrentPart = nil
@@ -15227,6 +17227,7 @@ func (p *parser) appendPart(parts []js_ast.Part, stmts []js_ast.Stmt) []js_ast.P
 		part.ImportRecordIndices = p.importRecordsForCurrentPart
 		part.ImportSymbolPropertyUses = p.importSymbolPropertyUses
 		part.SymbolCallUses = p.symbolCallUses
+		part.StringLiteralUses = p.stringLiteralUses
 		part.Scopes = p.scopesForCurrentPart
 		parts = append(parts, part)
 	}
@@ -15269,6 +17270,7 @@ func newParser(log logger.Log, source logger.Source, lexer js_lexer.Lexer, optio
 		// These are for handling ES6 imports and exports
 		importItemsForNamespace: make(map[js_ast.Ref]namespaceImportItems),
 		isImportItem:            make(map[js_ast.Ref]bool),
+		defaultImportReads:      make(map[js_ast.Ref]*propertyReads),
 		namedImports:            make(map[js_ast.Ref]js_ast.NamedImport),
 		namedExports:            make(map[string]js_ast.NamedExport),
 
@@ -15279,7 +17281,8 @@ func newParser(log logger.Log, source logger.Source, lexer js_lexer.Lexer, optio
 		suppressWarningsAboutWeirdCode: helpers.IsInsideNodeModules(source.KeyPath.Text),
 	}
 
-	if !options.minifyWhitespace {
+	if (!options.minifyWhitespace || options.keepsCommentsWhenMinifying()) &&
+		(options.comments == config.CommentsDefault || options.comments == config.CommentsAll) {
 		p.exprComments = make(map[logger.Loc][]string)
 	}
 
@@ -15319,7 +17322,7 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 		options.jsx.ImportSource = defaultJSXImportSource
 	}
 
-	if !options.ts.Parse {
+	if !options.ts.Parse || options.ts.Flow {
 		// Non-TypeScript files always get the real JavaScript class field behavior
 		options.useDefineForClassFields = config.True
 	} else if options.useDefineForClassFields == config.Unspecified {
@@ -15367,33 +17370,64 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 	p.fnOrArrowDataParse.isTopLevel = true
 
 	// Parse the file in the first pass, but do not bind symbols
-	stmts := p.parseStmtsUpTo(js_lexer.TEndOfFile, parseStmtOpts{
-		isModuleScope:          true,
-		allowDirectivePrologue: true,
-	})
+	stmts := p.parseModuleStmts()
 	p.prepareForVisitPass()
 
 	// Insert a "use strict" directive if "alwaysStrict" is active
-	directive := ""
+	var directives []string
 	if tsAlwaysStrict := p.options.tsAlwaysStrict; tsAlwaysStrict != nil && tsAlwaysStrict.Value {
-		directive = "use strict"
+		directives = append(directives, "use strict")
 	}
 
-	// Strip off a leading "use strict" directive when not bundling
-	for i, stmt := range stmts {
-		switch s := stmt.Data.(type) {
-		case *js_ast.SComment:
-			continue
-		case *js_ast.SDirective:
-			if !isDirectiveSupported(s) {
+	// Strip off all leading supported directives. They are stored separately so
+	// that they can be kept at the top of the output file even when bundling.
+	// Other directives are left in place like any other statement.
+	{
+		totalCount := 0
+		keptCount := 0
+
+	directivePrologue:
+		for _, stmt := range stmts {
+			switch s := stmt.Data.(type) {
+			case *js_ast.SComment:
+				stmts[keptCount] = stmt
+				keptCount++
+				totalCount++
+				continue
+
+			case *js_ast.SDirective:
+				if !isDirectiveSupported(s) {
+					stmts[keptCount] = stmt
+					keptCount++
+					totalCount++
+					continue
+				}
+
+				if p.isStrictMode() && s.LegacyOctalLoc.Start > 0 {
+					p.markStrictModeFeature(legacyOctalEscape, p.source.RangeOfLegacyOctalEscape(s.LegacyOctalLoc), "")
+				}
+
+				// Remove duplicate directives
+				directive := helpers.UTF16ToString(s.Value)
+				isDuplicate := false
+				for _, existing := range directives {
+					if existing == directive {
+						isDuplicate = true
+						break
+					}
+				}
+				if !isDuplicate {
+					directives = append(directives, directive)
+				}
+				totalCount++
 				continue
 			}
-			directive = helpers.UTF16ToString(s.Value)
+			break directivePrologue
+		}
 
-			// Remove this directive from the statement list
-			stmts = append(stmts[:i], stmts[i+1:]...)
+		if keptCount < totalCount {
+			stmts = append(stmts[:keptCount], stmts[totalCount:]...)
 		}
-		break
 	}
 
 	// Add an empty part for the namespace export that we can fill in later
@@ -15407,6 +17441,13 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 	var after []js_ast.Part
 
 	// Insert any injected import statements now that symbols have been declared
+	type nodeGlobalShim struct {
+		path    string
+		index   uint32
+		exports []string
+		symbols map[string]js_ast.LocRef
+	}
+	var nodeGlobalShims []nodeGlobalShim
 	for _, file := range p.options.injectedFiles {
 		exportsNoConflict := make([]string, 0, len(file.Exports))
 		symbols := make(map[string]js_ast.LocRef)
@@ -15467,15 +17508,35 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 			}
 		}
 
+		// Shims for node globals are only imported if this file references the
+		// global, which isn't known until after the visit pass. The symbols are
+		// still marked as import items now so that references to them become
+		// import identifiers, which is needed if the shim comes from CommonJS.
+		if file.IsNodeGlobalShim {
+			for _, alias := range exportsNoConflict {
+				p.isImportItem[symbols[alias].Ref] = true
+			}
+			nodeGlobalShims = append(nodeGlobalShims, nodeGlobalShim{
+				path:    file.Source.KeyPath.Text,
+				index:   file.Source.Index,
+				exports: exportsNoConflict,
+				symbols: symbols,
+			})
+			continue
+		}
+
 		before = p.generateImportStmt(file.Source.KeyPath.Text, exportsNoConflict, &file.Source.Index, before, symbols)
 	}
+	shimPartIndex := len(before)
 
 	// Bind symbols in a second pass over the AST. I started off doing this in a
 	// single pass, but it turns out it's pretty much impossible to do this
 	// correctly while handling arrow functions because of the grammar
 	// ambiguities.
-	if !p.options.treeShaking {
-		// When tree shaking is disabled, everything comes in a single part
+	if !p.options.treeShaking || p.willWrapModuleInTryCatchForUsing(stmts) {
+		// When tree shaking is disabled, everything comes in a single part. This
+		// is also the case when the whole module will be wrapped in a try/catch
+		// statement to lower top-level "using" declarations.
 		parts = p.appendPart(parts, stmts)
 	} else {
 		// When tree shaking is enabled, each top-level statement is potentially a separate part
@@ -15519,16 +17580,43 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 		}
 	}
 
+	// Now that all identifiers have been bound, import the shims for the node
+	// globals that this file references. They go where the other injected
+	// imports are so that they are evaluated before this file's own imports.
+	if len(nodeGlobalShims) > 0 {
+		var shimParts []js_ast.Part
+		for _, shim := range nodeGlobalShims {
+			var exports []string
+			for _, alias := range shim.exports {
+				if p.symbols[shim.symbols[alias].Ref.InnerIndex].UseCountEstimate > 0 {
+					exports = append(exports, alias)
+				} else if member, ok := p.moduleScope.Members[alias]; ok && member.Ref == shim.symbols[alias].Ref {
+					// Don't reserve the name of a global that this file doesn't use
+					delete(p.moduleScope.Members, alias)
+				}
+			}
+			if len(exports) > 0 {
+				index := shim.index
+				shimParts = p.generateImportStmt(shim.path, exports, &index, shimParts, shim.symbols)
+			}
+		}
+		before = append(before[:shimPartIndex], append(shimParts, before[shimPartIndex:]...)...)
+	}
+
 	// Insert a variable for "import.meta" at the top of the file if it was used.
 	// We don't need to worry about "use strict" directives because this only
 	// happens when bundling, in which case we are flatting the module scopes of
 	// all modules together anyway so such directives are meaningless.
 	if p.importMetaRef != js_ast.InvalidRef {
+		value := js_ast.Expr{Data: &js_ast.EObject{}}
+		if _, object := p.importMetaShim(); object != nil {
+			value.Data = object
+		}
 		importMetaStmt := js_ast.Stmt{Data: &js_ast.SLocal{
 			Kind: p.selectLocalKind(js_ast.LocalConst),
 			Decls: []js_ast.Decl{{
 				Binding:    js_ast.Binding{Data: &js_ast.BIdentifier{Ref: p.importMetaRef}},
-				ValueOrNil: js_ast.Expr{Data: &js_ast.EObject{}},
+				ValueOrNil: value,
 			}},
 		}}
 		before = append(before, js_ast.Part{
@@ -15542,11 +17630,22 @@ func Parse(log logger.Log, source logger.Source, options Options) (result js_ast
 	// Pop the module scope to apply the "ContainsDirectEval" rules
 	p.popScope()
 
-	result = p.toAST(before, parts, after, hashbang, directive)
+	result = p.toAST(before, parts, after, hashbang, directives)
 	result.SourceMapComment = p.lexer.SourceMappingURL
 	return
 }
 
+func (p *parser) willWrapModuleInTryCatchForUsing(stmts []js_ast.Stmt) bool {
+	if p.options.unsupportedJSFeatures.Has(compat.Using) {
+		for _, stmt := range stmts {
+			if s, ok := stmt.Data.(*js_ast.SLocal); ok && s.Kind.IsUsing() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func LazyExportAST(log logger.Log, source logger.Source, options Options, expr js_ast.Expr, apiCall string) js_ast.AST {
 	// Don't create a new lexer using js_lexer.NewLexer() here since that will
 	// actually attempt to parse the first token, which might cause a syntax
@@ -15573,7 +17672,7 @@ func LazyExportAST(log logger.Log, source logger.Source, options Options, expr j
 	}
 	p.symbolUses = nil
 
-	ast := p.toAST(nil, []js_ast.Part{nsExportPart, part}, nil, "", "")
+	ast := p.toAST(nil, []js_ast.Part{nsExportPart, part}, nil, "", nil)
 	ast.HasLazyExport = true
 	return ast
 }
@@ -15952,7 +18051,7 @@ func sortedKeysOfMapStringLocRef(in map[string]js_ast.LocRef) []string {
 	return keys
 }
 
-func (p *parser) toAST(before, parts, after []js_ast.Part, hashbang string, directive string) js_ast.AST {
+func (p *parser) toAST(before, parts, after []js_ast.Part, hashbang string, directives []string) js_ast.AST {
 	// Insert an import statement for any runtime imports we generated
 	if len(p.runtimeImports) > 0 && !p.options.omitRuntimeForTests {
 		keys := sortedKeysOfMapStringLocRef(p.runtimeImports)
@@ -15975,6 +18074,13 @@ func (p *parser) toAST(before, parts, after []js_ast.Part, hashbang string, dire
 		before = p.generateImportStmt(path, keys, nil, before, p.jsxRuntimeImports)
 	}
 
+	// Insert an import statement for each "import.meta.glob()" call
+	for _, it := range p.importGlobs {
+		before = p.generateImportStmt(it.glob.Patterns[0], []string{"default"}, nil, before,
+			map[string]js_ast.LocRef{"default": {Loc: it.loc, Ref: it.ref}})
+		p.importRecords[len(p.importRecords)-1].ImportGlob = it.glob
+	}
+
 	// Insert an import statement for any legacy jsx imports we generated (i.e., createElement)
 	if len(p.jsxLegacyImports) > 0 && !p.options.omitJSXRuntimeForTests {
 		keys := sortedKeysOfMapStringLocRef(p.jsxLegacyImports)
@@ -16156,11 +18262,12 @@ func (p *parser) toAST(before, parts, after []js_ast.Part, hashbang string, dire
 		ModuleRef:                       p.moduleRef,
 		WrapperRef:                      wrapperRef,
 		Hashbang:                        hashbang,
-		Directive:                       directive,
+		Directives:                      directives,
 		NamedImports:                    p.namedImports,
 		NamedExports:                    p.namedExports,
 		TSEnums:                         p.tsEnums,
 		ConstValues:                     p.constValues,
+		ConstReturnValues:               p.constReturnValues,
 		ExprComments:                    p.exprComments,
 		NestedScopeSlotCounts:           nestedScopeSlotCounts,
 		TopLevelSymbolToPartsFromParser: p.topLevelSymbolToParts,
@@ -16168,6 +18275,7 @@ func (p *parser) toAST(before, parts, after []js_ast.Part, hashbang string, dire
 		ImportRecords:                   p.importRecords,
 		ApproximateLineCount:            int32(p.lexer.ApproximateNewlineCount) + 1,
 		MangledProps:                    p.mangledProps,
+		MangledPropMethodCounts:         p.mangledPropMethodCounts,
 		ReservedProps:                   p.reservedProps,
 		ManifestForYarnPnP:              p.manifestForYarnPnP,
 
@@ -16180,5 +18288,8 @@ func (p *parser) toAST(before, parts, after []js_ast.Part, hashbang string, dire
 		ExportKeyword:            p.esmExportKeyword,
 		TopLevelAwaitKeyword:     p.topLevelAwaitKeyword,
 		LiveTopLevelAwaitKeyword: p.liveTopLevelAwaitKeyword,
+
+		// TypeScript features
+		IsTypeScriptScript: p.options.ts.Parse && !p.options.ts.Flow && exportsKind == js_ast.ExportsNone && p.esmImportStatementKeyword.Len == 0,
 	}
 }
@@ -63,36 +63,12 @@ func (p *parser) markSyntaxFeature(feature compat.JSFeature, r logger.Range) (di
 	where, notes := p.prettyPrintTargetEnvironment(feature)
 
 	switch feature {
-	case compat.DefaultArgument:
-		name = "default arguments"
-
 	case compat.RestArgument:
 		name = "rest arguments"
 
-	case compat.ArraySpread:
-		name = "array spread"
-
-	case compat.ForOf:
-		name = "for-of loops"
-
 	case compat.ObjectAccessors:
 		name = "object accessors"
 
-	case compat.ObjectExtensions:
-		name = "object literal extensions"
-
-	case compat.Destructuring:
-		name = "destructuring"
-
-	case compat.NewTarget:
-		name = "new.target"
-
-	case compat.ConstAndLet:
-		name = p.source.TextForRange(r)
-
-	case compat.Class:
-		name = "class syntax"
-
 	case compat.Generator:
 		name = "generator functions"
 
@@ -263,15 +239,10 @@ func (p *parser) markAsyncFn(asyncRange logger.Range, isGenerator bool) (didGene
 		return p.markSyntaxFeature(compat.AsyncGenerator, asyncRange)
 	}
 
-	// Lowered async functions are implemented in terms of generators. So if
-	// generators aren't supported, async functions aren't supported either.
-	// But if generators are supported, then async functions are unconditionally
-	// supported because we can use generators to implement them.
-	if !p.options.unsupportedJSFeatures.Has(compat.Generator) {
-		return false
-	}
-
-	return p.markSyntaxFeature(compat.AsyncAwait, asyncRange)
+	// Lowered async functions are implemented in terms of generators, which are
+	// themselves lowered if they aren't supported. So async functions are
+	// unconditionally supported.
+	return false
 }
 
 func (p *parser) privateSymbolNeedsToBeLowered(private *js_ast.EPrivateIdentifier) bool {
@@ -283,6 +254,13 @@ func (p *parser) captureThis() js_ast.Ref {
 	if p.fnOnlyDataVisit.thisCaptureRef == nil {
 		ref := p.newSymbol(js_ast.SymbolHoisted, "_this")
 		p.fnOnlyDataVisit.thisCaptureRef = &ref
+
+		// Function bodies declare this variable after they have been visited,
+		// but top-level code isn't inside a function body
+		if !p.fnOnlyDataVisit.isThisNested {
+			p.topLevelTempRefsToDeclare = append(p.topLevelTempRefsToDeclare, tempRef{ref: ref, valueOrNil: js_ast.Expr{Data: js_ast.EThisShared}})
+			p.moduleScope.Generated = append(p.moduleScope.Generated, ref)
+		}
 	}
 
 	ref := *p.fnOnlyDataVisit.thisCaptureRef
@@ -301,6 +279,27 @@ func (p *parser) captureArguments() js_ast.Ref {
 	return ref
 }
 
+// Lowering some syntax changes what "this" refers to. This returns the
+// expression that must be substituted for "this" when that happens.
+func (p *parser) valueForLoweredThis(loc logger.Loc) (js_ast.Expr, bool) {
+	// Inside the constructor of a derived class that's lowered to ES5, "this"
+	// is the value that was returned by the base class constructor
+	if class := p.fnOnlyDataVisit.es5DerivedClass; class != nil {
+		p.recordUsage(class.thisRef)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: class.thisRef}}, true
+	}
+
+	// Capture "this" inside arrow functions that will be lowered into normal
+	// function expressions for older language environments. This also applies
+	// to top-level arrow functions when "valueForThis" didn't already replace
+	// top-level "this" with something else.
+	if p.fnOrArrowDataVisit.isArrow && p.options.unsupportedJSFeatures.Has(compat.Arrow) {
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.captureThis()}}, true
+	}
+
+	return js_ast.Expr{}, false
+}
+
 func (p *parser) lowerFunction(
 	isAsync *bool,
 	args *[]js_ast.Arg,
@@ -310,6 +309,9 @@ func (p *parser) lowerFunction(
 	hasRestArg *bool,
 	isArrow bool,
 ) {
+	// Lower ES2015 argument syntax for ES5
+	p.lowerArgsForES5(args, bodyBlock, hasRestArg, isArrow)
+
 	// Lower object rest binding patterns in function arguments
 	if p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
 		var prefixStmts []js_ast.Stmt
@@ -497,6 +499,12 @@ func (p *parser) lowerFunction(
 			}
 		}
 
+		// Generators may need to be lowered too
+		if p.options.unsupportedJSFeatures.Has(compat.Generator) {
+			p.lowerGeneratorBody(bodyLoc, &fn.Body.Block)
+			fn.IsGenerator = false
+		}
+
 		// "async function foo(a, b) { stmts }" => "function foo(a, b) { return __async(this, null, function* () { stmts }) }"
 		*isAsync = false
 		callAsync := p.callRuntime(bodyLoc, "__async", []js_ast.Expr{
@@ -1217,7 +1225,7 @@ func (p *parser) extractSuperProperty(target js_ast.Expr) js_ast.Expr {
 	return js_ast.Expr{}
 }
 
-func (p *parser) lowerForAwaitLoop(loc logger.Loc, loop *js_ast.SForOf, stmts []js_ast.Stmt) []js_ast.Stmt {
+func (p *parser) lowerForOfLoop(loc logger.Loc, loop *js_ast.SForOf, stmts []js_ast.Stmt) []js_ast.Stmt {
 	// This code:
 	//
 	//   for await (let x of y) z()
@@ -1241,6 +1249,8 @@ func (p *parser) lowerForAwaitLoop(loc logger.Loc, loop *js_ast.SForOf, stmts []
 	//
 	// except that "yield" is used instead of "await" if await is unsupported.
 	// This mostly follows TypeScript's implementation of the syntax transform.
+	// Regular for-of loops are transformed the same way except that they use
+	// "__iterator" instead of "__forAwait" and don't use "await" at all.
 
 	iterRef := p.generateTempRef(tempRefNoDeclare, "iter")
 	moreRef := p.generateTempRef(tempRefNoDeclare, "more")
@@ -1298,7 +1308,10 @@ func (p *parser) lowerForAwaitLoop(loc logger.Loc, loop *js_ast.SForOf, stmts []
 	}}
 
 	// "await" expressions turn into "yield" expressions when lowering
-	if p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) {
+	iterHelper := "__forAwait"
+	if loop.Await.Len == 0 {
+		iterHelper = "__iterator"
+	} else if p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) {
 		awaitIterNext.Data = &js_ast.EYield{ValueOrNil: awaitIterNext}
 		awaitTempCallIter.Data = &js_ast.EYield{ValueOrNil: awaitTempCallIter}
 	} else {
@@ -1312,7 +1325,7 @@ func (p *parser) lowerForAwaitLoop(loc logger.Loc, loop *js_ast.SForOf, stmts []
 			Stmts: []js_ast.Stmt{{Loc: loc, Data: &js_ast.SFor{
 				InitOrNil: js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{
 					{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: iterRef}},
-						ValueOrNil: p.callRuntime(loc, "__forAwait", []js_ast.Expr{loop.Value})},
+						ValueOrNil: p.callRuntime(loc, iterHelper, []js_ast.Expr{loop.Value})},
 					{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: moreRef}}},
 					{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: tempRef}}},
 					{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: errorRef}}},
@@ -1443,15 +1456,32 @@ func exprHasObjectRest(expr js_ast.Expr) bool {
 	return false
 }
 
+func (p *parser) bindingNeedsLowering(binding js_ast.Binding) bool {
+	if _, ok := binding.Data.(*js_ast.BIdentifier); !ok && p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
+		return true
+	}
+	return p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && bindingHasObjectRest(binding)
+}
+
+func (p *parser) exprNeedsLowering(expr js_ast.Expr) bool {
+	switch expr.Data.(type) {
+	case *js_ast.EArray, *js_ast.EObject:
+		if p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
+			return true
+		}
+	}
+	return p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && exprHasObjectRest(expr)
+}
+
 func (p *parser) lowerObjectRestInDecls(decls []js_ast.Decl) []js_ast.Decl {
-	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && !p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
 		return decls
 	}
 
 	// Don't do any allocations if there are no object rest patterns. We want as
 	// little overhead as possible in the common case.
 	for i, decl := range decls {
-		if decl.ValueOrNil.Data != nil && bindingHasObjectRest(decl.Binding) {
+		if decl.ValueOrNil.Data != nil && p.bindingNeedsLowering(decl.Binding) {
 			clone := append([]js_ast.Decl{}, decls[:i]...)
 			for _, decl := range decls[i:] {
 				if decl.ValueOrNil.Data != nil {
@@ -1472,7 +1502,7 @@ func (p *parser) lowerObjectRestInDecls(decls []js_ast.Decl) []js_ast.Decl {
 }
 
 func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stmt) {
-	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && !p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
 		return
 	}
 
@@ -1482,7 +1512,7 @@ func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stm
 	case *js_ast.SExpr:
 		// "for ({...x} in y) {}"
 		// "for ({...x} of y) {}"
-		if exprHasObjectRest(s.Value) {
+		if p.exprNeedsLowering(s.Value) {
 			ref := p.generateTempRef(tempRefNeedsDeclare, "")
 			if expr, ok := p.lowerAssign(s.Value, js_ast.Expr{Loc: init.Loc, Data: &js_ast.EIdentifier{Ref: ref}}, objRestReturnValueIsUnused); ok {
 				p.recordUsage(ref)
@@ -1494,7 +1524,7 @@ func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stm
 	case *js_ast.SLocal:
 		// "for (let {...x} in y) {}"
 		// "for (let {...x} of y) {}"
-		if len(s.Decls) == 1 && bindingHasObjectRest(s.Decls[0].Binding) {
+		if len(s.Decls) == 1 && p.bindingNeedsLowering(s.Decls[0].Binding) {
 			ref := p.generateTempRef(tempRefNoDeclare, "")
 			decl := js_ast.Decl{Binding: s.Decls[0].Binding, ValueOrNil: js_ast.Expr{Loc: init.Loc, Data: &js_ast.EIdentifier{Ref: ref}}}
 			p.recordUsage(ref)
@@ -1517,18 +1547,26 @@ func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stm
 }
 
 func (p *parser) lowerObjectRestInCatchBinding(catch *js_ast.Catch) {
-	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && !p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
 		return
 	}
 
-	if catch.BindingOrNil.Data != nil && bindingHasObjectRest(catch.BindingOrNil) {
+	if catch.BindingOrNil.Data != nil && p.bindingNeedsLowering(catch.BindingOrNil) {
 		ref := p.generateTempRef(tempRefNoDeclare, "")
 		decl := js_ast.Decl{Binding: catch.BindingOrNil, ValueOrNil: js_ast.Expr{Loc: catch.BindingOrNil.Loc, Data: &js_ast.EIdentifier{Ref: ref}}}
 		p.recordUsage(ref)
 		decls := p.lowerObjectRestInDecls([]js_ast.Decl{decl})
 		catch.BindingOrNil.Data = &js_ast.BIdentifier{Ref: ref}
 		stmts := make([]js_ast.Stmt, 0, 1+len(catch.Block.Stmts))
-		stmts = append(stmts, js_ast.Stmt{Loc: catch.BindingOrNil.Loc, Data: &js_ast.SLocal{Kind: js_ast.LocalLet, Decls: decls}})
+		kind := p.selectLocalKind(js_ast.LocalLet)
+		if kind == js_ast.LocalVar {
+			for _, decl := range decls {
+				for _, id := range findIdentifiers(decl.Binding, nil) {
+					p.hoistLoweredLetOrConstRef(id.Binding.Data.(*js_ast.BIdentifier).Ref)
+				}
+			}
+		}
+		stmts = append(stmts, js_ast.Stmt{Loc: catch.BindingOrNil.Loc, Data: &js_ast.SLocal{Kind: kind, Decls: decls}})
 		catch.Block.Stmts = append(stmts, catch.Block.Stmts...)
 	}
 }
@@ -1662,7 +1700,7 @@ func (p *parser) lowerObjectRestHelper(
 	declare generateTempRefArg,
 	mode objRestMode,
 ) (wrapFunc func(js_ast.Expr) js_ast.Expr, ok bool) {
-	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && !p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
 		return nil, false
 	}
 
@@ -1673,6 +1711,11 @@ func (p *parser) lowerObjectRestHelper(
 		return nil, false
 	}
 
+	// Lower all destructuring patterns if the target doesn't support them
+	if p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
+		return p.lowerDestructuringHelper(rootExpr, rootInit, assign, declare, mode), true
+	}
+
 	// Scan for object rest bindings and initialize rest binding containment
 	containsRestBinding := make(map[js_ast.E]bool)
 	var findRestBindings func(js_ast.Expr) bool
@@ -1973,9 +2016,22 @@ type classLoweringInfo struct {
 	avoidTDZ                bool
 	lowerAllInstanceFields  bool
 	lowerAllStaticFields    bool
+	lowerDecorators         bool
 	shimSuperCtorCalls      bool
 }
 
+func classHasJSDecorators(class *js_ast.Class) bool {
+	if len(class.Decorators) > 0 {
+		return true
+	}
+	for _, prop := range class.Properties {
+		if len(prop.Decorators) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *parser) computeClassLoweringInfo(class *js_ast.Class) (result classLoweringInfo) {
 	// TypeScript has legacy behavior that uses assignment semantics instead of
 	// define semantics for class fields by default. This happened before class
@@ -2020,6 +2076,8 @@ func (p *parser) computeClassLoweringInfo(class *js_ast.Class) (result classLowe
 	//   }
 	//   _foo = new WeakMap();
 	//
+	hasAutoAccessors := false
+	hasInstanceAutoAccessors := false
 	for _, prop := range class.Properties {
 		if prop.Kind == js_ast.PropertyClassStaticBlock {
 			if p.options.unsupportedJSFeatures.Has(compat.ClassStaticBlocks) && len(prop.ClassStaticBlock.Block.Stmts) > 0 {
@@ -2028,6 +2086,13 @@ func (p *parser) computeClassLoweringInfo(class *js_ast.Class) (result classLowe
 			continue
 		}
 
+		if prop.Kind == js_ast.PropertyAutoAccessor {
+			hasAutoAccessors = true
+			if !prop.Flags.Has(js_ast.PropertyIsStatic) {
+				hasInstanceAutoAccessors = true
+			}
+		}
+
 		if private, ok := prop.Key.Data.(*js_ast.EPrivateIdentifier); ok {
 			if prop.Flags.Has(js_ast.PropertyIsStatic) {
 				if p.privateSymbolNeedsToBeLowered(private) {
@@ -2185,6 +2250,33 @@ func (p *parser) computeClassLoweringInfo(class *js_ast.Class) (result classLowe
 		}
 	}
 
+	// JavaScript decorators and auto-accessors are lowered together, either
+	// because the target doesn't support them or because some of the fields
+	// they apply to are already being moved outside of the class body. Lowered
+	// decorators are applied after the class body is evaluated, so all fields
+	// must be lowered too to make sure they are initialized afterward:
+	//
+	//   class Foo {
+	//     @dec foo = 123
+	//     accessor bar = 456
+	//   }
+	//
+	// Lowered auto-accessors store their value in a lowered private field, so
+	// they also cause fields of the same type to be lowered.
+	hasDecorators := !p.options.ts.Parse && classHasJSDecorators(class)
+	if hasDecorators || hasAutoAccessors {
+		if p.options.unsupportedJSFeatures.Has(compat.Decorators) || p.options.unsupportedJSFeatures.Has(compat.Class) ||
+			result.lowerAllInstanceFields || result.lowerAllStaticFields {
+			result.lowerDecorators = true
+			if hasDecorators || hasInstanceAutoAccessors {
+				result.lowerAllInstanceFields = true
+				result.lowerAllStaticFields = true
+			} else {
+				result.lowerAllStaticFields = true
+			}
+		}
+	}
+
 	// We need to shim "super()" inside the constructor if this is a derived
 	// class and there are any instance fields that need to be lowered, since
 	// those use "this" and we can only access "this" after "super()" is called
@@ -2192,6 +2284,12 @@ func (p *parser) computeClassLoweringInfo(class *js_ast.Class) (result classLowe
 		result.shimSuperCtorCalls = true
 	}
 
+	// Classes that are lowered to ES5 replace "super()" calls with a call to
+	// the base class constructor instead, so they don't need the shim
+	if p.options.unsupportedJSFeatures.Has(compat.Class) {
+		result.shimSuperCtorCalls = false
+	}
+
 	return
 }
 
@@ -2205,6 +2303,316 @@ func propertyPreventsKeepNames(prop *js_ast.Property) bool {
 	return false
 }
 
+// A class member with JavaScript decorators that have been lowered
+type decoratedElement struct {
+	private    *js_ast.EPrivateIdentifier
+	name       js_ast.Expr
+	decorators js_ast.Ref
+	flags      int
+	fieldIndex int
+}
+
+const (
+	decoratorKindClass = iota
+	decoratorKindMethod
+	decoratorKindGetter
+	decoratorKindSetter
+	decoratorKindAccessor
+	decoratorKindField
+
+	decoratorFlagStatic  = 8
+	decoratorFlagPrivate = 16
+)
+
+type loweredDecorators struct {
+	// Decorators and computed keys are evaluated before the class body
+	prefixExprs []js_ast.Expr
+
+	// Class members are decorated in the order they appear here
+	elements []decoratedElement
+
+	// Maps the index of a field in the class body to the "__runInitializers()"
+	// flags that run the initializers returned from its decorators
+	fieldInitializerFlags map[int]int
+
+	initRef            js_ast.Ref
+	classDecoratorsRef js_ast.Ref
+
+	hasStaticMethodDecorators   bool
+	hasInstanceMethodDecorators bool
+}
+
+// This replaces each auto-accessor with a private field that stores its value
+// and a getter/setter pair that forwards to that private field. It also hoists
+// JavaScript decorators and computed keys out of the class body if they need
+// to be lowered, since they must be evaluated before the class is defined.
+// The returned state is nil if there are no JavaScript decorators to lower.
+func (p *parser) lowerAutoAccessorsAndDecorators(class *js_ast.Class, nameToKeep string) *loweredDecorators {
+	var d *loweredDecorators
+	if !p.options.ts.Parse && classHasJSDecorators(class) {
+		d = &loweredDecorators{
+			initRef:               p.generateTempRef(tempRefNeedsDeclare, "_init"),
+			classDecoratorsRef:    js_ast.InvalidRef,
+			fieldInitializerFlags: make(map[int]int),
+		}
+		if len(class.Decorators) > 0 {
+			name := "_decorators"
+			if nameToKeep != "" {
+				name = "_" + js_ast.ForceValidIdentifier(nameToKeep) + "_decorators"
+			}
+			d.classDecoratorsRef = p.generateTempRef(tempRefNeedsDeclare, name)
+			d.prefixExprs = append(d.prefixExprs, js_ast.Assign(
+				js_ast.Expr{Loc: class.Decorators[0].Loc, Data: &js_ast.EIdentifier{Ref: d.classDecoratorsRef}},
+				js_ast.Expr{Loc: class.Decorators[0].Loc, Data: &js_ast.EArray{Items: class.Decorators, IsSingleLine: true}},
+			))
+			p.recordUsage(d.classDecoratorsRef)
+			class.Decorators = nil
+		}
+	}
+
+	// Static members are decorated before instance members, and methods are
+	// decorated before fields
+	var staticMethods, instanceMethods, staticFields, instanceFields []decoratedElement
+	properties := make([]js_ast.Property, 0, len(class.Properties))
+
+	for _, prop := range class.Properties {
+		if prop.Kind == js_ast.PropertyClassStaticBlock {
+			properties = append(properties, prop)
+			continue
+		}
+
+		// Evaluate the decorators and the computed key of this member in order
+		decoratorsRef := js_ast.InvalidRef
+		if d != nil {
+			if len(prop.Decorators) > 0 {
+				name := "_dec"
+				switch k := prop.Key.Data.(type) {
+				case *js_ast.EString:
+					name = "_" + js_ast.ForceValidIdentifier(helpers.UTF16ToString(k.Value)) + "_dec"
+				case *js_ast.EPrivateIdentifier:
+					name = "_" + p.symbols[k.Ref.InnerIndex].OriginalName[1:] + "_dec"
+				}
+				decoratorsRef = p.generateTempRef(tempRefNeedsDeclare, name)
+				d.prefixExprs = append(d.prefixExprs, js_ast.Assign(
+					js_ast.Expr{Loc: prop.Decorators[0].Loc, Data: &js_ast.EIdentifier{Ref: decoratorsRef}},
+					js_ast.Expr{Loc: prop.Decorators[0].Loc, Data: &js_ast.EArray{Items: prop.Decorators, IsSingleLine: true}},
+				))
+				p.recordUsage(decoratorsRef)
+				prop.Decorators = nil
+			}
+			if prop.Flags.Has(js_ast.PropertyIsComputed) {
+				if _, ok := prop.Key.Data.(*js_ast.EString); !ok {
+					ref := p.generateTempRef(tempRefNeedsDeclare, "")
+					d.prefixExprs = append(d.prefixExprs, js_ast.Assign(js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.EIdentifier{Ref: ref}}, prop.Key))
+					prop.Key = js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.EIdentifier{Ref: ref}}
+					p.recordUsage(ref)
+				}
+			}
+		}
+
+		private, _ := prop.Key.Data.(*js_ast.EPrivateIdentifier)
+		fieldIndex := -1
+		var kind int
+
+		switch {
+		case prop.Kind == js_ast.PropertyAutoAccessor:
+			kind = decoratorKindAccessor
+			fieldIndex = len(properties)
+			properties = append(properties, p.lowerAutoAccessor(prop)...)
+
+		case prop.Flags.Has(js_ast.PropertyIsMethod):
+			switch prop.Kind {
+			case js_ast.PropertyGet:
+				kind = decoratorKindGetter
+			case js_ast.PropertySet:
+				kind = decoratorKindSetter
+			default:
+				kind = decoratorKindMethod
+			}
+			properties = append(properties, prop)
+
+		default:
+			kind = decoratorKindField
+			fieldIndex = len(properties)
+			properties = append(properties, prop)
+		}
+
+		if decoratorsRef == js_ast.InvalidRef {
+			continue
+		}
+
+		element := decoratedElement{
+			private:    private,
+			decorators: decoratorsRef,
+			flags:      kind,
+			fieldIndex: fieldIndex,
+		}
+		switch k := prop.Key.Data.(type) {
+		case *js_ast.EPrivateIdentifier:
+			element.name = js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(p.symbols[k.Ref.InnerIndex].OriginalName)}}
+			element.flags |= decoratorFlagPrivate
+		case *js_ast.EString:
+			element.name = js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.EString{Value: k.Value}}
+		case *js_ast.ENumber:
+			element.name = js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.ENumber{Value: k.Value}}
+		case *js_ast.EIdentifier:
+			element.name = js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.EIdentifier{Ref: k.Ref}}
+			p.recordUsage(k.Ref)
+		case *js_ast.EMangledProp:
+			element.name = js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.EMangledProp{Ref: k.Ref}}
+		default:
+			panic("Internal error")
+		}
+
+		isStatic := prop.Flags.Has(js_ast.PropertyIsStatic)
+		if isStatic {
+			element.flags |= decoratorFlagStatic
+		}
+		switch {
+		case kind != decoratorKindField && isStatic:
+			staticMethods = append(staticMethods, element)
+			d.hasStaticMethodDecorators = true
+		case kind != decoratorKindField:
+			instanceMethods = append(instanceMethods, element)
+			d.hasInstanceMethodDecorators = true
+		case isStatic:
+			staticFields = append(staticFields, element)
+		default:
+			instanceFields = append(instanceFields, element)
+		}
+	}
+
+	class.Properties = properties
+
+	// Fields and accessors are assigned initializer slots in the order that
+	// they are decorated
+	if d != nil {
+		d.elements = append(d.elements, staticMethods...)
+		d.elements = append(d.elements, instanceMethods...)
+		d.elements = append(d.elements, staticFields...)
+		d.elements = append(d.elements, instanceFields...)
+		slot := 0
+		for _, element := range d.elements {
+			if element.fieldIndex != -1 {
+				d.fieldInitializerFlags[element.fieldIndex] = (4 + 2*slot) << 1
+				slot++
+			}
+		}
+	}
+	return d
+}
+
+// This converts an auto-accessor into a private field that stores the value
+// and a getter/setter pair that reads and writes that private field:
+//
+//	class Foo {
+//	  accessor foo = 123
+//	}
+//
+// becomes:
+//
+//	var _foo;
+//	class Foo {
+//	  constructor() {
+//	    __privateAdd(this, _foo, 123);
+//	  }
+//	  get foo() {
+//	    return __privateGet(this, _foo);
+//	  }
+//	  set foo(_) {
+//	    __privateSet(this, _foo, _);
+//	  }
+//	}
+//	_foo = new WeakMap();
+//
+// The private field is always lowered since it can't be referenced by name.
+func (p *parser) lowerAutoAccessor(prop js_ast.Property) []js_ast.Property {
+	loc := prop.Loc
+	isStatic := prop.Flags & js_ast.PropertyIsStatic
+
+	// Generate a private symbol for the storage
+	name := "#"
+	switch k := prop.Key.Data.(type) {
+	case *js_ast.EString:
+		name += js_ast.ForceValidIdentifier(helpers.UTF16ToString(k.Value))
+	case *js_ast.EPrivateIdentifier:
+		name = p.symbols[k.Ref.InnerIndex].OriginalName
+	default:
+		name += "accessor"
+	}
+	kind := js_ast.SymbolPrivateField
+	if isStatic != 0 {
+		kind = js_ast.SymbolPrivateStaticField
+	}
+	storage := &js_ast.EPrivateIdentifier{Ref: p.newSymbol(kind, name)}
+	p.symbols[storage.Ref.InnerIndex].Flags |= js_ast.PrivateSymbolMustBeLowered
+
+	// The getter and setter share the same key, so a computed key must only be
+	// evaluated once
+	getterKey := prop.Key
+	var setterKey js_ast.Expr
+	switch k := prop.Key.Data.(type) {
+	case *js_ast.EString:
+		setterKey = js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.EString{Value: k.Value}}
+	case *js_ast.ENumber:
+		setterKey = js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.ENumber{Value: k.Value}}
+	case *js_ast.EPrivateIdentifier:
+		setterKey = js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.EPrivateIdentifier{Ref: k.Ref}}
+	case *js_ast.EMangledProp:
+		setterKey = js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.EMangledProp{Ref: k.Ref}}
+	case *js_ast.EIdentifier:
+		setterKey = js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.EIdentifier{Ref: k.Ref}}
+		p.recordUsage(k.Ref)
+	default:
+		ref := p.generateTempRef(tempRefNeedsDeclare, "")
+		getterKey = js_ast.Assign(js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.EIdentifier{Ref: ref}}, prop.Key)
+		setterKey = js_ast.Expr{Loc: prop.Key.Loc, Data: &js_ast.EIdentifier{Ref: ref}}
+		p.recordUsage(ref)
+		p.recordUsage(ref)
+	}
+
+	// Generate the getter
+	getter := js_ast.Property{
+		Decorators:          prop.Decorators,
+		TSMetadataTypeOrNil: prop.TSMetadataTypeOrNil,
+		Loc:                 loc,
+		Kind:                js_ast.PropertyGet,
+		Flags:               prop.Flags | js_ast.PropertyIsMethod,
+		Key:                 getterKey,
+		ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{Body: js_ast.FnBody{Loc: loc, Block: js_ast.SBlock{Stmts: []js_ast.Stmt{
+			{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: p.lowerPrivateGet(js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}, loc, storage)}},
+		}}}}}},
+	}
+
+	// Generate the setter
+	valueRef := p.newSymbol(js_ast.SymbolOther, "_")
+	p.currentScope.Generated = append(p.currentScope.Generated, valueRef)
+	p.recordUsage(valueRef)
+	setter := js_ast.Property{
+		Loc:   loc,
+		Kind:  js_ast.PropertySet,
+		Flags: prop.Flags | js_ast.PropertyIsMethod,
+		Key:   setterKey,
+		ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{
+			Args: []js_ast.Arg{{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: valueRef}}}},
+			Body: js_ast.FnBody{Loc: loc, Block: js_ast.SBlock{Stmts: []js_ast.Stmt{
+				{Loc: loc, Data: &js_ast.SExpr{Value: p.lowerPrivateSet(js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}, loc, storage,
+					js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: valueRef}})}},
+			}}},
+		}}},
+	}
+
+	// Generate the storage, which takes over the initializer
+	field := js_ast.Property{
+		Loc:              loc,
+		Flags:            isStatic,
+		Key:              js_ast.Expr{Loc: prop.Key.Loc, Data: storage},
+		InitializerOrNil: prop.InitializerOrNil,
+	}
+
+	return []js_ast.Property{field, getter, setter}
+}
+
 // Lower class fields for environments that don't support them. This either
 // takes a statement or an expression.
 func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClassResult) ([]js_ast.Stmt, js_ast.Expr) {
@@ -2344,15 +2752,36 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 
 	classLoweringInfo := p.computeClassLoweringInfo(class)
 
-	for _, prop := range class.Properties {
+	// Instance members are initialized on the value returned by the base class
+	// constructor when a derived class is lowered to ES5
+	instanceThis := func(loc logger.Loc) js_ast.Expr {
+		if result.es5DerivedClass != nil {
+			p.recordUsage(result.es5DerivedClass.thisRef)
+			return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: result.es5DerivedClass.thisRef}}
+		}
+		return js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+	}
+
+	// Auto-accessors and JavaScript decorators generate additional class
+	// members, so they must be handled before anything else
+	var decorators *loweredDecorators
+	if classLoweringInfo.lowerDecorators {
+		decorators = p.lowerAutoAccessorsAndDecorators(class, nameToKeep)
+	}
+
+	var tsCtorFn *js_ast.Fn
+	for i, prop := range class.Properties {
 		if prop.Kind == js_ast.PropertyClassStaticBlock {
-			if p.options.unsupportedJSFeatures.Has(compat.ClassStaticBlocks) {
+			// Static blocks must run after lowered decorators have been applied
+			if p.options.unsupportedJSFeatures.Has(compat.ClassStaticBlocks) || decorators != nil {
 				if block := *prop.ClassStaticBlock; len(block.Block.Stmts) > 0 {
+					body := js_ast.FnBody{Loc: block.Loc, Block: block.Block}
+					var target js_ast.E = &js_ast.EArrow{Body: body}
+					if p.options.unsupportedJSFeatures.Has(compat.Arrow) {
+						target = &js_ast.EFunction{Fn: js_ast.Fn{Body: body}}
+					}
 					staticMembers = append(staticMembers, js_ast.Expr{Loc: prop.Loc, Data: &js_ast.ECall{
-						Target: js_ast.Expr{Loc: prop.Loc, Data: &js_ast.EArrow{Body: js_ast.FnBody{
-							Loc:   block.Loc,
-							Block: block.Block,
-						}}},
+						Target: js_ast.Expr{Loc: prop.Loc, Data: target},
 					}})
 				}
 				continue
@@ -2375,12 +2804,15 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 				if key, ok := prop.Key.Data.(*js_ast.EString); ok {
 					isConstructor = helpers.UTF16EqualsString(key.Value, "constructor")
 				}
+				if isConstructor {
+					tsCtorFn = &fn.Fn
+				}
 				for i, arg := range fn.Fn.Args {
-					for _, decorator := range arg.TSDecorators {
+					for _, decorator := range arg.Decorators {
 						// Generate a call to "__decorateParam()" for this parameter decorator
-						var decorators *[]js_ast.Expr = &prop.TSDecorators
+						var decorators *[]js_ast.Expr = &prop.Decorators
 						if isConstructor {
-							decorators = &class.TSDecorators
+							decorators = &class.Decorators
 						}
 						*decorators = append(*decorators,
 							p.callRuntime(decorator.Loc, "__decorateParam", []js_ast.Expr{
@@ -2393,6 +2825,11 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 			}
 		}
 
+		// Pass type information to TypeScript decorators if requested
+		if p.options.ts.Parse && p.options.emitDecoratorMetadata && len(prop.Decorators) > 0 {
+			prop.Decorators = append(prop.Decorators, p.tsDecoratorMetadata(prop)...)
+		}
+
 		// The TypeScript class field transform requires removing fields without
 		// initializers. If the field is removed, then we only need the key for
 		// its side effects and we don't need a temporary reference for the key.
@@ -2422,12 +2859,14 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 		}
 
 		// Make sure the order of computed property keys doesn't change. These
-		// expressions have side effects and must be evaluated in order.
+		// expressions have side effects and must be evaluated in order. This was
+		// already done for classes with lowered JavaScript decorators.
 		keyExprNoSideEffects := prop.Key
-		if prop.Flags.Has(js_ast.PropertyIsComputed) && (len(prop.TSDecorators) > 0 ||
+		hasTSDecorators := p.options.ts.Parse && len(prop.Decorators) > 0
+		if decorators == nil && prop.Flags.Has(js_ast.PropertyIsComputed) && (hasTSDecorators ||
 			mustLowerField || computedPropertyCache.Data != nil) {
 			needsKey := true
-			if len(prop.TSDecorators) == 0 && (prop.Flags.Has(js_ast.PropertyIsMethod) || shouldOmitFieldInitializer || !mustLowerField) {
+			if !hasTSDecorators && (prop.Flags.Has(js_ast.PropertyIsMethod) || shouldOmitFieldInitializer || !mustLowerField) {
 				needsKey = false
 			}
 
@@ -2456,7 +2895,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 		// Handle decorators
 		if p.options.ts.Parse {
 			// Generate a single call to "__decorateClass()" for this property
-			if len(prop.TSDecorators) > 0 {
+			if hasTSDecorators {
 				loc := prop.Key.Loc
 
 				// Clone the key for the property descriptor
@@ -2487,7 +2926,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 				}
 
 				decorator := p.callRuntime(loc, "__decorateClass", []js_ast.Expr{
-					{Loc: loc, Data: &js_ast.EArray{Items: prop.TSDecorators}},
+					{Loc: loc, Data: &js_ast.EArray{Items: prop.Decorators}},
 					target,
 					descriptorKey,
 					{Loc: loc, Data: &js_ast.ENumber{Value: descriptorKind}},
@@ -2499,6 +2938,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 				} else {
 					instanceDecorators = append(instanceDecorators, decorator)
 				}
+				prop.Decorators = nil
 			}
 		}
 
@@ -2517,7 +2957,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 				if prop.Flags.Has(js_ast.PropertyIsStatic) {
 					target = nameFunc()
 				} else {
-					target = js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+					target = instanceThis(loc)
 				}
 
 				// Generate the assignment initializer
@@ -2528,6 +2968,33 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 					init = js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
 				}
 
+				// Run the initializers returned from any lowered decorators
+				decoratorFlags, isDecorated := 0, false
+				if decorators != nil {
+					decoratorFlags, isDecorated = decorators.fieldInitializerFlags[i]
+				}
+				runInitializers := func(flags int, value js_ast.Expr) js_ast.Expr {
+					var self js_ast.Expr
+					if prop.Flags.Has(js_ast.PropertyIsStatic) {
+						self = nameFunc()
+					} else {
+						self = instanceThis(loc)
+					}
+					args := []js_ast.Expr{
+						{Loc: loc, Data: &js_ast.EIdentifier{Ref: decorators.initRef}},
+						{Loc: loc, Data: &js_ast.ENumber{Value: float64(flags)}},
+						self,
+					}
+					if value.Data != nil {
+						args = append(args, value)
+					}
+					p.recordUsage(decorators.initRef)
+					return p.callRuntime(loc, "__runInitializers", args)
+				}
+				if isDecorated {
+					init = runInitializers(decoratorFlags, prop.InitializerOrNil)
+				}
+
 				// Generate the assignment target
 				var memberExpr js_ast.Expr
 				if mustLowerPrivate {
@@ -2582,6 +3049,11 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 					memberExpr = js_ast.Assign(target, init)
 				}
 
+				// Run the extra initializers after the field has been defined
+				if isDecorated {
+					memberExpr = js_ast.JoinWithComma(memberExpr, runInitializers(decoratorFlags+3, js_ast.Expr{}))
+				}
+
 				if prop.Flags.Has(js_ast.PropertyIsStatic) {
 					// Move this property to an assignment after the class ends
 					staticMembers = append(staticMembers, memberExpr)
@@ -2626,7 +3098,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 					if prop.Flags.Has(js_ast.PropertyIsStatic) {
 						target = nameFunc()
 					} else {
-						target = js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+						target = instanceThis(loc)
 					}
 
 					// Add every newly-constructed instance into this map
@@ -2678,7 +3150,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 								if id, ok := arg.Binding.Data.(*js_ast.BIdentifier); ok {
 									parameterFields = append(parameterFields, js_ast.AssignStmt(
 										js_ast.Expr{Loc: arg.Binding.Loc, Data: p.dotOrMangledPropVisit(
-											js_ast.Expr{Loc: arg.Binding.Loc, Data: js_ast.EThisShared},
+											instanceThis(arg.Binding.Loc),
 											p.symbols[id.Ref.InnerIndex].OriginalName,
 											arg.Binding.Loc,
 										)},
@@ -2700,6 +3172,129 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 	// Finish the filtering operation
 	class.Properties = class.Properties[:end]
 
+	// TypeScript class decorators are passed the types of the constructor's
+	// arguments if requested
+	if p.options.ts.Parse && p.options.emitDecoratorMetadata && len(class.Decorators) > 0 && tsCtorFn != nil {
+		class.Decorators = append(class.Decorators, p.tsDecoratorMetadataCall(classLoc, "design:paramtypes", tsMetadataArgTypes(classLoc, tsCtorFn)))
+	}
+
+	// TypeScript class decorators are applied after the class body
+	var tsClassDecorators []js_ast.Expr
+	if p.options.ts.Parse {
+		tsClassDecorators = class.Decorators
+		class.Decorators = nil
+	}
+
+	// Extra initializers from lowered decorators on instance methods run before
+	// any instance fields are initialized
+	if decorators != nil && decorators.hasInstanceMethodDecorators {
+		instanceMembers = append([]js_ast.Stmt{{Loc: classLoc, Data: &js_ast.SExpr{Value: p.callRuntime(classLoc, "__runInitializers", []js_ast.Expr{
+			{Loc: classLoc, Data: &js_ast.EIdentifier{Ref: decorators.initRef}},
+			{Loc: classLoc, Data: &js_ast.ENumber{Value: 5}},
+			instanceThis(classLoc),
+		})}}}, instanceMembers...)
+		p.recordUsage(decorators.initRef)
+	}
+
+	// Lowered JavaScript decorators are applied after the class body has been
+	// evaluated. Class decorators can replace the class, so this must happen
+	// before static fields are initialized.
+	applyDecorators := func() (exprs []js_ast.Expr) {
+		initRef := func() js_ast.Expr {
+			p.recordUsage(decorators.initRef)
+			return js_ast.Expr{Loc: classLoc, Data: &js_ast.EIdentifier{Ref: decorators.initRef}}
+		}
+		identifier := func(loc logger.Loc, ref js_ast.Ref) js_ast.Expr {
+			p.recordUsage(ref)
+			return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+		}
+		exprs = append(exprs, js_ast.Assign(initRef(), p.callRuntime(classLoc, "__decoratorStart", []js_ast.Expr{nameFunc()})))
+
+		for _, element := range decorators.elements {
+			loc := element.name.Loc
+			args := []js_ast.Expr{
+				initRef(),
+				{Loc: loc, Data: &js_ast.ENumber{Value: float64(element.flags)}},
+				element.name,
+				identifier(loc, element.decorators),
+			}
+
+			// Public members are decorated through the class object
+			if element.private == nil {
+				exprs = append(exprs, p.callRuntime(loc, "__decorateElement", append(args, nameFunc())))
+				continue
+			}
+
+			// Lowered private members are decorated through their "WeakMap" or
+			// "WeakSet", and any replacement functions are stored back
+			args = append(args, identifier(loc, element.private.Ref))
+			switch element.flags & 7 {
+			case decoratorKindMethod, decoratorKindGetter:
+				fnRef := p.privateGetters[element.private.Ref]
+				exprs = append(exprs, js_ast.Assign(identifier(loc, fnRef),
+					p.callRuntime(loc, "__decorateElement", append(args, identifier(loc, fnRef)))))
+
+			case decoratorKindSetter:
+				fnRef := p.privateSetters[element.private.Ref]
+				exprs = append(exprs, js_ast.Assign(identifier(loc, fnRef),
+					p.callRuntime(loc, "__decorateElement", append(args, identifier(loc, fnRef)))))
+
+			case decoratorKindAccessor:
+				getterRef := p.privateGetters[element.private.Ref]
+				setterRef := p.privateSetters[element.private.Ref]
+				descRef := p.generateTempRef(tempRefNeedsDeclare, "")
+				exprs = append(exprs,
+					js_ast.Assign(identifier(loc, descRef), p.callRuntime(loc, "__decorateElement",
+						append(args, identifier(loc, getterRef), identifier(loc, setterRef)))),
+					js_ast.Assign(identifier(loc, getterRef), js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: identifier(loc, descRef), Name: "get", NameLoc: loc}}),
+					js_ast.Assign(identifier(loc, setterRef), js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: identifier(loc, descRef), Name: "set", NameLoc: loc}}),
+				)
+
+			default:
+				exprs = append(exprs, p.callRuntime(loc, "__decorateElement", args))
+			}
+		}
+
+		// Class decorators also define the metadata
+		if decorators.classDecoratorsRef != js_ast.InvalidRef {
+			var name js_ast.Expr
+			if nameToKeep != "" {
+				name = js_ast.Expr{Loc: classLoc, Data: &js_ast.EString{Value: helpers.StringToUTF16(nameToKeep)}}
+			} else {
+				name = js_ast.Expr{Loc: classLoc, Data: js_ast.EUndefinedShared}
+			}
+			exprs = append(exprs, js_ast.Assign(nameFunc(), p.callRuntime(classLoc, "__decorateElement", []js_ast.Expr{
+				initRef(),
+				{Loc: classLoc, Data: &js_ast.ENumber{Value: decoratorKindClass}},
+				name,
+				identifier(classLoc, decorators.classDecoratorsRef),
+				nameFunc(),
+			})))
+		} else {
+			exprs = append(exprs, p.callRuntime(classLoc, "__decoratorMetadata", []js_ast.Expr{initRef(), nameFunc()}))
+		}
+
+		if decorators.hasStaticMethodDecorators {
+			exprs = append(exprs, p.callRuntime(classLoc, "__runInitializers", []js_ast.Expr{
+				initRef(),
+				{Loc: classLoc, Data: &js_ast.ENumber{Value: 3}},
+				nameFunc(),
+			}))
+		}
+		return
+	}
+
+	// Extra initializers from class decorators run after everything else
+	runClassExtraInitializers := func() js_ast.Expr {
+		p.recordUsage(decorators.initRef)
+		return p.callRuntime(classLoc, "__runInitializers", []js_ast.Expr{
+			{Loc: classLoc, Data: &js_ast.EIdentifier{Ref: decorators.initRef}},
+			{Loc: classLoc, Data: &js_ast.ENumber{Value: 1}},
+			nameFunc(),
+		})
+	}
+	hasClassDecorators := len(tsClassDecorators) > 0 || (decorators != nil && decorators.classDecoratorsRef != js_ast.InvalidRef)
+
 	// Insert instance field initializers into the constructor
 	if len(parameterFields) > 0 || len(instancePrivateMethods) > 0 || len(instanceMembers) > 0 || (ctor != nil && result.superCtorRef != js_ast.InvalidRef) {
 		// Create a constructor if one doesn't already exist
@@ -2715,7 +3310,11 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 			})
 
 			// Make sure the constructor has a super() call if needed
-			if class.ExtendsOrNil.Data != nil {
+			if result.es5DerivedClass != nil {
+				ctor.Fn.Body.Block.Stmts = append(ctor.Fn.Body.Block.Stmts, js_ast.Stmt{Loc: classLoc, Data: &js_ast.SExpr{
+					Value: p.defaultSuperCallForES5(classLoc, result.es5DerivedClass),
+				}})
+			} else if class.ExtendsOrNil.Data != nil {
 				target := js_ast.Expr{Loc: classLoc, Data: js_ast.ESuperShared}
 				if classLoweringInfo.shimSuperCtorCalls {
 					p.recordUsage(result.superCtorRef)
@@ -2736,7 +3335,17 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 		generatedStmts = append(generatedStmts, parameterFields...)
 		generatedStmts = append(generatedStmts, instancePrivateMethods...)
 		generatedStmts = append(generatedStmts, instanceMembers...)
-		p.insertStmtsAfterSuperCall(&ctor.Fn.Body, generatedStmts, result.superCtorRef)
+		if result.es5DerivedClass != nil {
+			p.insertStmtsAfterES5SuperCall(&ctor.Fn.Body, generatedStmts, result.es5DerivedClass)
+		} else if n := result.loweredCtorArgsStmtCount; n > 0 {
+			// Default arguments that were moved into the constructor body must
+			// still be evaluated before any instance fields are initialized
+			stmts := append([]js_ast.Stmt{}, ctor.Fn.Body.Block.Stmts[:n]...)
+			stmts = append(stmts, generatedStmts...)
+			ctor.Fn.Body.Block.Stmts = append(stmts, ctor.Fn.Body.Block.Stmts[n:]...)
+		} else {
+			p.insertStmtsAfterSuperCall(&ctor.Fn.Body, generatedStmts, result.superCtorRef)
+		}
 
 		// Sort the constructor first to match the TypeScript compiler's output
 		for i := 0; i < len(class.Properties); i++ {
@@ -2757,11 +3366,16 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 		// Calling "nameFunc" will replace "expr", so make sure to do that first
 		// before joining "expr" with any other expressions
 		var nameToJoin js_ast.Expr
-		if didCaptureClassExpr || computedPropertyCache.Data != nil ||
+		if didCaptureClassExpr || computedPropertyCache.Data != nil || decorators != nil ||
 			len(privateMembers) > 0 || len(staticPrivateMethods) > 0 || len(staticMembers) > 0 {
 			nameToJoin = nameFunc()
 		}
 
+		// Convert the class into a function if classes aren't supported
+		if p.options.unsupportedJSFeatures.Has(compat.Class) {
+			expr = p.lowerClassExprToES5(expr, result)
+		}
+
 		// Optionally preserve the name
 		if p.options.keepNames && nameToKeep != "" {
 			expr = p.keepExprSymbolName(expr, nameToKeep)
@@ -2774,17 +3388,32 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 		for _, value := range privateMembers {
 			expr = js_ast.JoinWithComma(expr, value)
 		}
+		if decorators != nil {
+			for _, value := range applyDecorators() {
+				expr = js_ast.JoinWithComma(expr, value)
+			}
+		}
 		for _, value := range staticPrivateMethods {
 			expr = js_ast.JoinWithComma(expr, value)
 		}
 		for _, value := range staticMembers {
 			expr = js_ast.JoinWithComma(expr, value)
 		}
+		if hasClassDecorators {
+			expr = js_ast.JoinWithComma(expr, runClassExtraInitializers())
+		}
 
 		// Finally join "expr" with the variable that holds the class object
 		if nameToJoin.Data != nil {
 			expr = js_ast.JoinWithComma(expr, nameToJoin)
 		}
+
+		// Decorators and computed keys are evaluated before the class body
+		if decorators != nil {
+			for i := len(decorators.prefixExprs) - 1; i >= 0; i-- {
+				expr = js_ast.JoinWithComma(decorators.prefixExprs[i], expr)
+			}
+		}
 		if wrapFunc != nil {
 			expr = wrapFunc(expr)
 		}
@@ -2802,7 +3431,7 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 			len(staticMembers) > 0 ||
 			len(instanceDecorators) > 0 ||
 			len(staticDecorators) > 0 ||
-			len(class.TSDecorators) > 0)
+			hasClassDecorators)
 
 	// Optionally preserve the name
 	var keepNameStmt js_ast.Stmt
@@ -2811,12 +3440,23 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 		keepNameStmt = p.keepStmtSymbolName(name.Loc, name.Data.(*js_ast.EIdentifier).Ref, nameToKeep)
 	}
 
+	// Lowered decorators reference the class by name, so make sure it has one
+	if decorators != nil {
+		nameFunc()
+	}
+
 	// Pack the class back into a statement, with potentially some extra
 	// statements afterwards
 	var stmts []js_ast.Stmt
 	var nameForClassDecorators js_ast.LocRef
 	generatedLocalStmt := false
-	if len(class.TSDecorators) > 0 || hasPotentialShadowCaptureEscape || classLoweringInfo.avoidTDZ {
+	if decorators != nil {
+		for _, value := range decorators.prefixExprs {
+			stmts = append(stmts, js_ast.Stmt{Loc: value.Loc, Data: &js_ast.SExpr{Value: value}})
+		}
+	}
+	if hasClassDecorators || hasPotentialShadowCaptureEscape || classLoweringInfo.avoidTDZ ||
+		p.options.unsupportedJSFeatures.Has(compat.Class) {
 		generatedLocalStmt = true
 		name := nameFunc()
 		nameRef := name.Data.(*js_ast.EIdentifier).Ref
@@ -2825,7 +3465,14 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 		class = &classExpr.Class
 		init := js_ast.Expr{Loc: classLoc, Data: &classExpr}
 
-		if hasPotentialShadowCaptureEscape && len(class.TSDecorators) == 0 {
+		// Convert the class into a function if classes aren't supported. The
+		// variable that holds it is no longer block-scoped in that case.
+		if p.options.unsupportedJSFeatures.Has(compat.Class) {
+			init = p.lowerClassToES5(classLoc, class, nameRef, result.es5DerivedClass)
+			p.hoistLoweredLetOrConstRef(nameRef)
+		}
+
+		if hasPotentialShadowCaptureEscape && !hasClassDecorators {
 			// If something captures the shadowing name and escapes the class body,
 			// make a new constant to store the class and forward that value to a
 			// mutable alias. That way if the alias is mutated, everything bound to
@@ -2861,6 +3508,9 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 			captureRef := p.newSymbol(js_ast.SymbolOther, p.symbols[result.shadowRef.InnerIndex].OriginalName)
 			p.currentScope.Generated = append(p.currentScope.Generated, captureRef)
 			p.recordDeclaredSymbol(captureRef)
+			if p.options.unsupportedJSFeatures.Has(compat.Class) {
+				p.hoistLoweredLetOrConstRef(captureRef)
+			}
 			p.mergeSymbols(result.shadowRef, captureRef)
 			stmts = append(stmts, js_ast.Stmt{Loc: classLoc, Data: &js_ast.SLocal{
 				Kind: p.selectLocalKind(js_ast.LocalConst),
@@ -2923,23 +3573,31 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 	for _, expr := range privateMembers {
 		stmts = append(stmts, js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
 	}
+	if decorators != nil {
+		for _, expr := range applyDecorators() {
+			stmts = append(stmts, js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
+		}
+	}
 	for _, expr := range staticPrivateMethods {
 		stmts = append(stmts, js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
 	}
 	for _, expr := range staticMembers {
 		stmts = append(stmts, js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
 	}
+	if decorators != nil && decorators.classDecoratorsRef != js_ast.InvalidRef {
+		stmts = append(stmts, js_ast.Stmt{Loc: classLoc, Data: &js_ast.SExpr{Value: runClassExtraInitializers()}})
+	}
 	for _, expr := range instanceDecorators {
 		stmts = append(stmts, js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
 	}
 	for _, expr := range staticDecorators {
 		stmts = append(stmts, js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
 	}
-	if len(class.TSDecorators) > 0 {
+	if len(tsClassDecorators) > 0 {
 		stmts = append(stmts, js_ast.AssignStmt(
 			js_ast.Expr{Loc: nameForClassDecorators.Loc, Data: &js_ast.EIdentifier{Ref: nameForClassDecorators.Ref}},
 			p.callRuntime(classLoc, "__decorateClass", []js_ast.Expr{
-				{Loc: classLoc, Data: &js_ast.EArray{Items: class.TSDecorators}},
+				{Loc: classLoc, Data: &js_ast.EArray{Items: tsClassDecorators}},
 				{Loc: nameForClassDecorators.Loc, Data: &js_ast.EIdentifier{Ref: nameForClassDecorators.Ref}},
 			}),
 		))
@@ -2963,6 +3621,49 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 	return stmts, js_ast.Expr{}
 }
 
+func (p *parser) tsDecoratorMetadataCall(loc logger.Loc, key string, value js_ast.Expr) js_ast.Expr {
+	return p.callRuntime(loc, "__decorateMetadata", []js_ast.Expr{
+		{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(key)}},
+		value,
+	})
+}
+
+func tsMetadataArgTypes(loc logger.Loc, fn *js_ast.Fn) js_ast.Expr {
+	types := make([]js_ast.Expr, 0, len(fn.Args))
+	for _, arg := range fn.Args {
+		if arg.TSMetadataTypeOrNil.Data != nil {
+			types = append(types, arg.TSMetadataTypeOrNil)
+		}
+	}
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EArray{Items: types, IsSingleLine: true}}
+}
+
+// This generates the extra decorators for TypeScript's "emitDecoratorMetadata"
+// setting. They are the same as what the TypeScript compiler generates:
+//
+//	class Foo {                        __decorateClass([
+//	  @dec                               dec,
+//	  foo(x: string): number {}          __decorateMetadata("design:type", Function),
+//	}                                    __decorateMetadata("design:paramtypes", [String]),
+//	                                     __decorateMetadata("design:returntype", Number)
+//	                                   ], Foo.prototype, "foo", 1);
+func (p *parser) tsDecoratorMetadata(prop js_ast.Property) (metadata []js_ast.Expr) {
+	loc := prop.Key.Loc
+
+	if prop.TSMetadataTypeOrNil.Data != nil {
+		metadata = append(metadata, p.tsDecoratorMetadataCall(loc, "design:type", prop.TSMetadataTypeOrNil))
+	}
+
+	if fn, ok := prop.ValueOrNil.Data.(*js_ast.EFunction); ok && prop.Flags.Has(js_ast.PropertyIsMethod) && prop.Kind != js_ast.PropertyGet {
+		metadata = append(metadata, p.tsDecoratorMetadataCall(loc, "design:paramtypes", tsMetadataArgTypes(loc, &fn.Fn)))
+		if fn.Fn.TSMetadataReturnTypeOrNil.Data != nil {
+			metadata = append(metadata, p.tsDecoratorMetadataCall(loc, "design:returntype", fn.Fn.TSMetadataReturnTypeOrNil))
+		}
+	}
+
+	return
+}
+
 // Replace "super()" calls with our shim so that we can guarantee
 // that instance field initialization doesn't happen before "super()"
 // is called, since at that point "this" isn't available.
@@ -3236,17 +3937,24 @@ func (p *parser) shouldLowerSuperPropertyAccess(expr js_ast.Expr) bool {
 	return false
 }
 
+func (p *parser) thisForLoweredSuperProperty(loc logger.Loc) js_ast.Expr {
+	// Handle "this" in lowered static class field initializers
+	if p.fnOnlyDataVisit.shouldReplaceThisWithClassNameRef {
+		p.recordUsage(*p.fnOnlyDataVisit.classNameRef)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: *p.fnOnlyDataVisit.classNameRef}}
+	}
+
+	if value, ok := p.valueForLoweredThis(loc); ok {
+		return value
+	}
+	return js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+}
+
 func (p *parser) callSuperPropertyWrapper(loc logger.Loc, key js_ast.Expr) js_ast.Expr {
 	ref := *p.fnOnlyDataVisit.classNameRef
 	p.recordUsage(ref)
 	class := js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
-	this := js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
-
-	// Handle "this" in lowered static class field initializers
-	if p.fnOnlyDataVisit.shouldReplaceThisWithClassNameRef {
-		p.recordUsage(ref)
-		this.Data = &js_ast.EIdentifier{Ref: ref}
-	}
+	this := p.thisForLoweredSuperProperty(loc)
 
 	if !p.fnOnlyDataVisit.isInStaticClassContext {
 		// "super.foo" => "__superWrapper(Class.prototype, this, 'foo')._"
@@ -3265,13 +3973,7 @@ func (p *parser) lowerSuperPropertyGet(loc logger.Loc, key js_ast.Expr) js_ast.E
 	ref := *p.fnOnlyDataVisit.classNameRef
 	p.recordUsage(ref)
 	class := js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
-	this := js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
-
-	// Handle "this" in lowered static class field initializers
-	if p.fnOnlyDataVisit.shouldReplaceThisWithClassNameRef {
-		p.recordUsage(ref)
-		this.Data = &js_ast.EIdentifier{Ref: ref}
-	}
+	this := p.thisForLoweredSuperProperty(loc)
 
 	if !p.fnOnlyDataVisit.isInStaticClassContext {
 		// "super.foo" => "__superGet(Class.prototype, this, 'foo')"
@@ -3292,13 +3994,7 @@ func (p *parser) lowerSuperPropertySet(loc logger.Loc, key js_ast.Expr, value js
 	ref := *p.fnOnlyDataVisit.classNameRef
 	p.recordUsage(ref)
 	class := js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
-	this := js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
-
-	// Handle "this" in lowered static class field initializers
-	if p.fnOnlyDataVisit.shouldReplaceThisWithClassNameRef {
-		p.recordUsage(ref)
-		this.Data = &js_ast.EIdentifier{Ref: ref}
-	}
+	this := p.thisForLoweredSuperProperty(loc)
 
 	if !p.fnOnlyDataVisit.isInStaticClassContext {
 		// "super.foo = bar" => "__superSet(Class.prototype, this, 'foo', bar)"
@@ -3354,7 +4050,7 @@ func (p *parser) maybeLowerSuperPropertyGetInsideCall(call *js_ast.ECall) {
 		NameLoc: key.Loc,
 		Name:    "call",
 	}
-	thisExpr := js_ast.Expr{Loc: call.Target.Loc, Data: js_ast.EThisShared}
+	thisExpr := p.thisForLoweredSuperProperty(call.Target.Loc)
 	call.Args = append([]js_ast.Expr{thisExpr}, call.Args...)
 }
 
@@ -3376,3 +4072,221 @@ func (p *parser) maybeLowerSetBinOp(left js_ast.Expr, op js_ast.OpCode, right js
 	}
 	return js_ast.Expr{}
 }
+
+func (p *parser) maybeLowerUsingDeclarations(stmts []js_ast.Stmt) []js_ast.Stmt {
+	if !p.options.unsupportedJSFeatures.Has(compat.Using) {
+		return stmts
+	}
+	for _, stmt := range stmts {
+		if s, ok := stmt.Data.(*js_ast.SLocal); ok && s.Kind.IsUsing() {
+			return p.lowerUsingDeclarations(stmt.Loc, stmts)
+		}
+	}
+	return stmts
+}
+
+// Lower "for (using x of y) {}" to "for (const _x of y) { using x = _x }"
+func (p *parser) lowerUsingDeclarationInForOf(init js_ast.Stmt, body *js_ast.Stmt) {
+	local, ok := init.Data.(*js_ast.SLocal)
+	if !ok || !local.Kind.IsUsing() || !p.options.unsupportedJSFeatures.Has(compat.Using) {
+		return
+	}
+
+	id := local.Decls[0].Binding
+	ref := p.generateTempRef(tempRefNoDeclare, "")
+	p.currentScope.Generated = append(p.currentScope.Generated, ref)
+	p.recordUsage(ref)
+	usingStmt := js_ast.Stmt{Loc: init.Loc, Data: &js_ast.SLocal{Kind: local.Kind, Decls: []js_ast.Decl{{
+		Binding:    id,
+		ValueOrNil: js_ast.Expr{Loc: id.Loc, Data: &js_ast.EIdentifier{Ref: ref}},
+	}}}}
+	local.Kind = p.selectLocalKind(js_ast.LocalConst)
+	local.Decls[0].Binding = js_ast.Binding{Loc: id.Loc, Data: &js_ast.BIdentifier{Ref: ref}}
+
+	stmts := []js_ast.Stmt{usingStmt}
+	if block, ok := body.Data.(*js_ast.SBlock); ok {
+		stmts = append(stmts, block.Stmts...)
+	} else {
+		stmts = append(stmts, *body)
+	}
+	*body = js_ast.Stmt{Loc: body.Loc, Data: &js_ast.SBlock{Stmts: p.lowerUsingDeclarations(init.Loc, stmts)}}
+}
+
+// This wraps the statements in a try/catch/finally statement that disposes of
+// the resources from all "using" declarations in reverse order at the end:
+//
+//	var _stack = [], _error, _hasError = false;
+//	try {
+//	  const x = __using(_stack, y);
+//	} catch (_) {
+//	  _error = _, _hasError = true;
+//	} finally {
+//	  __callDispose(_stack, _error, _hasError);
+//	}
+//
+// At the top level, imports, exports, and function declarations are kept
+// outside of the try block since they are hoisted, and everything else is
+// converted to "var" so that it's still visible to those hoisted functions.
+func (p *parser) lowerUsingDeclarations(loc logger.Loc, stmts []js_ast.Stmt) []js_ast.Stmt {
+	isTopLevel := p.currentScope == p.moduleScope
+	hasAwaitUsing := false
+	var outside []js_ast.Stmt
+	var inside []js_ast.Stmt
+	var exports []js_ast.ClauseItem
+
+	// These are declared using "var" so they must have a unique name within the
+	// enclosing function, not just within the current block
+	generateVar := func(name string) js_ast.Ref {
+		ref := p.newSymbol(js_ast.SymbolOther, name)
+		scope := p.currentScope
+		for !scope.Kind.StopsHoisting() {
+			scope = scope.Parent
+		}
+		scope.Generated = append(scope.Generated, ref)
+		p.declaredSymbols = append(p.declaredSymbols, js_ast.DeclaredSymbol{Ref: ref, IsTopLevel: scope == p.moduleScope})
+		return ref
+	}
+	use := func(loc logger.Loc, ref js_ast.Ref) js_ast.Expr {
+		p.recordUsage(ref)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+	}
+	declare := func(loc logger.Loc, ref js_ast.Ref, valueOrNil js_ast.Expr) js_ast.Decl {
+		return js_ast.Decl{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}, ValueOrNil: valueOrNil}
+	}
+	export := func(alias string, name js_ast.LocRef) {
+		exports = append(exports, js_ast.ClauseItem{Alias: alias, AliasLoc: name.Loc, Name: name})
+	}
+	stackRef := generateVar("_stack")
+
+	for _, stmt := range stmts {
+		switch s := stmt.Data.(type) {
+		case *js_ast.SLocal:
+			if s.Kind.IsUsing() {
+				isAsync := s.Kind == js_ast.LocalAwaitUsing
+				hasAwaitUsing = hasAwaitUsing || isAsync
+				for i, decl := range s.Decls {
+					args := []js_ast.Expr{use(decl.Binding.Loc, stackRef), decl.ValueOrNil}
+					if isAsync {
+						args = append(args, js_ast.Expr{Loc: decl.Binding.Loc, Data: &js_ast.EBoolean{Value: true}})
+					}
+					s.Decls[i].ValueOrNil = p.callRuntime(decl.ValueOrNil.Loc, "__using", args)
+				}
+				s.Kind = p.selectLocalKind(js_ast.LocalConst)
+			}
+			if isTopLevel {
+				if s.IsExport {
+					for _, decl := range s.Decls {
+						for _, id := range findIdentifiers(decl.Binding, nil) {
+							ref := id.Binding.Data.(*js_ast.BIdentifier).Ref
+							export(p.symbols[ref.InnerIndex].OriginalName, js_ast.LocRef{Loc: id.Binding.Loc, Ref: ref})
+						}
+					}
+					s.IsExport = false
+				}
+				s.Kind = js_ast.LocalVar
+			}
+
+		case *js_ast.SFunction:
+			if isTopLevel {
+				outside = append(outside, stmt)
+				continue
+			}
+
+		case *js_ast.SClass:
+			if isTopLevel {
+				if s.IsExport {
+					export(p.symbols[s.Class.Name.Ref.InnerIndex].OriginalName, *s.Class.Name)
+				}
+				stmt = js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{
+					declare(s.Class.Name.Loc, s.Class.Name.Ref, js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EClass{Class: s.Class}}),
+				}}}
+			}
+
+		case *js_ast.SImport, *js_ast.SExportFrom, *js_ast.SExportStar:
+			outside = append(outside, stmt)
+			continue
+
+		case *js_ast.SExportClause:
+			exports = append(exports, s.Items...)
+			continue
+
+		case *js_ast.SExportDefault:
+			var value js_ast.Expr
+			switch s2 := s.Value.Data.(type) {
+			case *js_ast.SFunction:
+				outside = append(outside, stmt)
+				continue
+			case *js_ast.SExpr:
+				value = s2.Value
+			case *js_ast.SClass:
+				value = js_ast.Expr{Loc: s.Value.Loc, Data: &js_ast.EClass{Class: s2.Class}}
+			}
+			export("default", s.DefaultName)
+			stmt = js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{
+				declare(s.DefaultName.Loc, s.DefaultName.Ref, value),
+			}}}
+		}
+		inside = append(inside, stmt)
+	}
+
+	errorRef := generateVar("_error")
+	hasErrorRef := generateVar("_hasError")
+	caughtRef := generateVar("_")
+
+	// "_error = _, _hasError = true"
+	catchStmts := []js_ast.Stmt{{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.JoinWithComma(
+		js_ast.Assign(use(loc, errorRef), use(loc, caughtRef)),
+		js_ast.Assign(use(loc, hasErrorRef), js_ast.Expr{Loc: loc, Data: &js_ast.EBoolean{Value: true}}),
+	)}}}
+
+	// "__callDispose(_stack, _error, _hasError)"
+	var finallyStmts []js_ast.Stmt
+	callDispose := p.callRuntime(loc, "__callDispose", []js_ast.Expr{
+		use(loc, stackRef),
+		use(loc, errorRef),
+		use(loc, hasErrorRef),
+	})
+	if hasAwaitUsing {
+		// "var _promise = __callDispose(...); _promise && await _promise"
+		promiseRef := generateVar("_promise")
+		var await js_ast.Expr
+		if p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) && !isTopLevel {
+			await = js_ast.Expr{Loc: loc, Data: &js_ast.EYield{ValueOrNil: use(loc, promiseRef)}}
+		} else {
+			await = js_ast.Expr{Loc: loc, Data: &js_ast.EAwait{Value: use(loc, promiseRef)}}
+		}
+		finallyStmts = []js_ast.Stmt{
+			{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{declare(loc, promiseRef, callDispose)}}},
+			{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+				Op:    js_ast.BinOpLogicalAnd,
+				Left:  use(loc, promiseRef),
+				Right: await,
+			}}}},
+		}
+	} else {
+		finallyStmts = []js_ast.Stmt{{Loc: loc, Data: &js_ast.SExpr{Value: callDispose}}}
+	}
+
+	result := append(outside,
+		js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{
+			declare(loc, stackRef, js_ast.Expr{Loc: loc, Data: &js_ast.EArray{}}),
+			declare(loc, errorRef, js_ast.Expr{}),
+			declare(loc, hasErrorRef, js_ast.Expr{Loc: loc, Data: &js_ast.EBoolean{Value: false}}),
+		}}},
+		js_ast.Stmt{Loc: loc, Data: &js_ast.STry{
+			BlockLoc: loc,
+			Block:    js_ast.SBlock{Stmts: inside},
+			Catch: &js_ast.Catch{
+				Loc:          loc,
+				BindingOrNil: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: caughtRef}},
+				BlockLoc:     loc,
+				Block:        js_ast.SBlock{Stmts: catchStmts},
+			},
+			Finally: &js_ast.Finally{Loc: loc, Block: js_ast.SBlock{Stmts: finallyStmts}},
+		}},
+	)
+	if len(exports) > 0 {
+		result = append(result, js_ast.Stmt{Loc: loc, Data: &js_ast.SExportClause{Items: exports}})
+	}
+	return result
+}
@@ -0,0 +1,2235 @@
+// This file contains code for lowering syntax that was introduced in ES2015
+// (also known as ES6) so that it can run in ES5 environments. This includes
+// "let" and "const", spread and rest syntax, default arguments, destructuring,
+// for-of loops, object literal extensions, and classes.
+
+package js_parser
+
+import (
+	"fmt"
+
+	"github.com/evanw/esbuild/internal/compat"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// A variable declared using "let" or "const" inside a loop gets a fresh
+// binding for each loop iteration. That is lost when it's converted to "var",
+// which is only observable if the variable is captured by a closure. So we
+// track these variables and wrap closures that capture them in a function
+// call that snapshots their current value.
+type loweredLoopLet struct {
+	captureLoc    logger.Loc
+	assignLoc     logger.Loc
+	closureDepth  int
+	loopBodyDepth int
+	isLoopHead    bool
+	hasCapture    bool
+	hasAssign     bool
+	didLogError   bool
+}
+
+func (p *parser) lowerLetAndConstToVar(s *js_ast.SLocal, isForLoopInit bool, isForInOrOf bool) {
+	if s.Kind == js_ast.LocalVar || !p.options.unsupportedJSFeatures.Has(compat.ConstAndLet) {
+		return
+	}
+
+	isInsideLoop := p.fnOrArrowDataVisit.isInsideLoop || isForLoopInit
+	isBlockScoped := !p.currentScope.Kind.StopsHoisting()
+
+	for i := range s.Decls {
+		decl := &s.Decls[i]
+
+		// The variable must be initialized again each time the surrounding block
+		// is entered. For example, "{ let x; x = 1 } { let x; return x }" must
+		// return undefined, not 1.
+		if isBlockScoped && !isForInOrOf && s.Kind == js_ast.LocalLet && decl.ValueOrNil.Data == nil {
+			if _, ok := decl.Binding.Data.(*js_ast.BIdentifier); ok {
+				decl.ValueOrNil = js_ast.Expr{Loc: decl.Binding.Loc, Data: js_ast.EUndefinedShared}
+			}
+		}
+
+		for _, id := range findIdentifiers(decl.Binding, nil) {
+			ref := id.Binding.Data.(*js_ast.BIdentifier).Ref
+			p.hoistLoweredLetOrConstRef(ref)
+			if isInsideLoop {
+				if p.loweredLoopLets == nil {
+					p.loweredLoopLets = make(map[js_ast.Ref]*loweredLoopLet)
+				}
+				p.loweredLoopLets[ref] = &loweredLoopLet{
+					closureDepth:  len(p.loweredLoopLetCaptures),
+					loopBodyDepth: p.loopBodyDepth,
+					isLoopHead:    isForLoopInit,
+				}
+			}
+		}
+	}
+}
+
+// Variables declared using "var" are scoped to the nearest function, so
+// different block-scoped variables that are converted to "var" must not end
+// up with the same name. Adding them to the function scope's list of generated
+// symbols makes the renamer assign them a name that's unique within the whole
+// function instead of just within the block.
+func (p *parser) hoistLoweredLetOrConstRef(ref js_ast.Ref) {
+	scope := p.currentScope
+	if scope.Kind.StopsHoisting() {
+		return
+	}
+	for !scope.Kind.StopsHoisting() {
+		scope = scope.Parent
+	}
+	scope.Generated = append(scope.Generated, ref)
+	if scope == p.moduleScope {
+		p.declaredSymbols = append(p.declaredSymbols, js_ast.DeclaredSymbol{Ref: ref, IsTopLevel: true})
+	}
+}
+
+func (p *parser) pushLoweredLoopLetClosure() {
+	if p.options.unsupportedJSFeatures.Has(compat.ConstAndLet) {
+		p.loweredLoopLetCaptures = append(p.loweredLoopLetCaptures, nil)
+	}
+}
+
+// This returns the loop variables that were captured by the closure that was
+// just visited, if any. The closure should then be wrapped by the caller.
+func (p *parser) popLoweredLoopLetClosure() []js_ast.Ref {
+	if n := len(p.loweredLoopLetCaptures); n > 0 {
+		refs := p.loweredLoopLetCaptures[n-1]
+		p.loweredLoopLetCaptures = p.loweredLoopLetCaptures[:n-1]
+		return refs
+	}
+	return nil
+}
+
+func (p *parser) recordLoweredLoopLetUse(loc logger.Loc, ref js_ast.Ref, isAssignTarget bool) {
+	info, ok := p.loweredLoopLets[ref]
+	if !ok {
+		return
+	}
+
+	// Remember the outermost closure inside the loop that captures this variable
+	if depth := len(p.loweredLoopLetCaptures); depth > info.closureDepth {
+		captures := &p.loweredLoopLetCaptures[info.closureDepth]
+		isNew := true
+		for _, it := range *captures {
+			if it == ref {
+				isNew = false
+				break
+			}
+		}
+		if isNew {
+			*captures = append(*captures, ref)
+		}
+		if !info.hasCapture {
+			info.hasCapture = true
+			info.captureLoc = loc
+		}
+	}
+
+	// The snapshot taken by the closure is only correct if the variable isn't
+	// reassigned after being captured. Assignments in the head of a "for" loop
+	// are fine because they apply to the next iteration's copy of the variable.
+	if isAssignTarget && !info.hasAssign && (len(p.loweredLoopLetCaptures) != info.closureDepth ||
+		p.loopBodyDepth != info.loopBodyDepth || (!info.isLoopHead && info.hasCapture)) {
+		info.hasAssign = true
+		info.assignLoc = loc
+	}
+
+	if info.hasCapture && info.hasAssign && !info.didLogError {
+		info.didLogError = true
+		where, notes := p.prettyPrintTargetEnvironment(compat.ConstAndLet)
+		name := p.symbols[ref.InnerIndex].OriginalName
+		notes = append([]logger.MsgData{p.tracker.MsgData(js_lexer.RangeOfIdentifier(p.source, info.assignLoc),
+			fmt.Sprintf("The variable %q is reassigned here:", name))}, notes...)
+		p.log.AddErrorWithNotes(&p.tracker, js_lexer.RangeOfIdentifier(p.source, info.captureLoc), fmt.Sprintf(
+			"Transforming the loop variable %q to %s is not supported yet because it's both captured by a closure and reassigned",
+			name, where), notes)
+	}
+}
+
+// Wrap a closure inside a loop so that it captures the current values of the
+// loop variables instead of the variables themselves:
+//
+//	"() => x" => "(function(x) { return function() { return x } })(x)"
+func (p *parser) wrapClosureCapturingLoopLets(value js_ast.Expr, refs []js_ast.Ref) js_ast.Expr {
+	loc := value.Loc
+	args := make([]js_ast.Arg, len(refs))
+	values := make([]js_ast.Expr, len(refs))
+	for i, ref := range refs {
+		args[i] = js_ast.Arg{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}}
+		values[i] = js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+		p.recordUsage(ref)
+	}
+	body := js_ast.FnBody{Loc: loc, Block: js_ast.SBlock{Stmts: []js_ast.Stmt{{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: value}}}}}
+	var target js_ast.Expr
+	if p.options.unsupportedJSFeatures.Has(compat.Arrow) {
+		target = js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{Args: args, Body: body, ArgumentsRef: js_ast.InvalidRef}}}
+	} else {
+		target = js_ast.Expr{Loc: loc, Data: &js_ast.EArrow{Args: args, Body: body, PreferExpr: true}}
+	}
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{Target: target, Args: values}}
+}
+
+// Some closures can't be wrapped, such as methods and function declarations
+// that remain declarations.
+func (p *parser) logUnwrappableClosureCapturingLoopLets(refs []js_ast.Ref) {
+	if len(refs) == 0 {
+		return
+	}
+	info := p.loweredLoopLets[refs[0]]
+	where, notes := p.prettyPrintTargetEnvironment(compat.ConstAndLet)
+	p.log.AddErrorWithNotes(&p.tracker, js_lexer.RangeOfIdentifier(p.source, info.captureLoc), fmt.Sprintf(
+		"Transforming the loop variable %q to %s is not supported yet because it's captured by a method or a function declaration",
+		p.symbols[refs[0].InnerIndex].OriginalName, where), notes)
+}
+
+// This returns an expression that accesses the property with the given key
+func (p *parser) memberExprForPropertyKey(target js_ast.Expr, key js_ast.Expr) js_ast.Expr {
+	if str, ok := key.Data.(*js_ast.EString); ok {
+		if name := helpers.UTF16ToString(str.Value); js_ast.IsIdentifierES5AndESNext(name) {
+			return js_ast.Expr{Loc: key.Loc, Data: &js_ast.EDot{Target: target, Name: name, NameLoc: key.Loc}}
+		}
+	}
+	return js_ast.Expr{Loc: key.Loc, Data: &js_ast.EIndex{Target: target, Index: key}}
+}
+
+// Object literals in ES5 can't have methods, shorthand properties, or
+// computed keys. Methods become properties with function values and shorthand
+// properties are already printed in their expanded form. Computed keys are
+// handled by switching from an object literal to a series of assignments to a
+// temporary variable at the first computed key:
+//
+//	"{a, [b]: c, get [d]() {}}" => "(_a = {a: a}, _a[b] = c, __defProp(_a, d, {...}), _a)"
+func (p *parser) lowerObjectExtensions(loc logger.Loc, e *js_ast.EObject) js_ast.Expr {
+	firstComputed := -1
+	for i := range e.Properties {
+		property := &e.Properties[i]
+		property.Flags &= ^js_ast.PropertyIsMethod
+		if property.Flags.Has(js_ast.PropertyIsComputed) {
+			switch property.Key.Data.(type) {
+			case *js_ast.EString, *js_ast.ENumber:
+				// These can be printed as regular keys
+				property.Flags &= ^js_ast.PropertyIsComputed
+
+			default:
+				if firstComputed == -1 && property.Kind != js_ast.PropertySpread {
+					firstComputed = i
+				}
+			}
+		}
+	}
+
+	if firstComputed == -1 {
+		return p.lowerObjectSpread(loc, e)
+	}
+
+	ref := p.generateTempRef(tempRefNeedsDeclare, "")
+	temp := func() js_ast.Expr {
+		p.recordUsage(ref)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+	}
+
+	initial := p.lowerObjectSpread(loc, &js_ast.EObject{
+		Properties:   e.Properties[:firstComputed],
+		IsSingleLine: e.IsSingleLine,
+	})
+	result := js_ast.Assign(temp(), initial)
+
+	for _, property := range e.Properties[firstComputed:] {
+		switch property.Kind {
+		case js_ast.PropertySpread:
+			// "{[a]: b, ...c}" => "(_a = {}, _a[a] = b, __spreadValues(_a, c), _a)"
+			result = js_ast.JoinWithComma(result, p.callRuntime(property.Loc, "__spreadValues",
+				[]js_ast.Expr{temp(), property.ValueOrNil}))
+
+		case js_ast.PropertyGet, js_ast.PropertySet:
+			// "{get [a]() {}}" => "(_a = {}, __defProp(_a, a, { get: function() {}, enumerable: true, configurable: true }), _a)"
+			name := "get"
+			if property.Kind == js_ast.PropertySet {
+				name = "set"
+			}
+			descriptor := js_ast.Expr{Loc: property.Loc, Data: &js_ast.EObject{Properties: []js_ast.Property{
+				{Key: js_ast.Expr{Loc: property.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(name)}}, ValueOrNil: property.ValueOrNil},
+				{Key: js_ast.Expr{Loc: property.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("enumerable")}}, ValueOrNil: js_ast.Expr{Loc: property.Loc, Data: &js_ast.EBoolean{Value: true}}},
+				{Key: js_ast.Expr{Loc: property.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("configurable")}}, ValueOrNil: js_ast.Expr{Loc: property.Loc, Data: &js_ast.EBoolean{Value: true}}},
+			}}}
+			result = js_ast.JoinWithComma(result, p.callRuntime(property.Loc, "__defProp",
+				[]js_ast.Expr{temp(), property.Key, descriptor}))
+
+		default:
+			// "{[a]: b}" => "(_a = {}, _a[a] = b, _a)"
+			result = js_ast.JoinWithComma(result, js_ast.Assign(p.memberExprForPropertyKey(temp(), property.Key), property.ValueOrNil))
+		}
+	}
+
+	return js_ast.JoinWithComma(result, temp())
+}
+
+// This lowers all destructuring patterns when destructuring isn't supported.
+// It uses the same interface as "lowerObjectRestHelper" so that it can be
+// used in all of the same places. Each individual assignment is passed to the
+// "assign" callback, which is either used to create a variable declaration or
+// an assignment expression:
+//
+//	"[a, b = c] = d"  => "_a = __toArray(d, 2), a = _a[0], _b = _a[1], b = _b === void 0 ? c : _b"
+//	"({a, ...b} = c)" => "a = c.a, b = __objRest(c, ['a'])"
+func (p *parser) lowerDestructuringHelper(
+	rootExpr js_ast.Expr,
+	rootInit js_ast.Expr,
+	assign func(js_ast.Expr, js_ast.Expr),
+	declare generateTempRefArg,
+	mode objRestMode,
+) (wrapFunc func(js_ast.Expr) js_ast.Expr) {
+	var visit func(js_ast.Expr, js_ast.Expr)
+
+	// Temporary variables we generate are never reassigned, so they can be
+	// referenced multiple times without being captured again
+	temps := make(map[js_ast.Ref]bool)
+	captureIntoRef := func(expr js_ast.Expr) func() js_ast.Expr {
+		if id, ok := expr.Data.(*js_ast.EIdentifier); ok && temps[id.Ref] {
+			return func() js_ast.Expr {
+				p.recordUsage(id.Ref)
+				return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: id.Ref}}
+			}
+		}
+		ref := p.generateTempRef(declare, "")
+		temps[ref] = true
+		assign(js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: ref}}, expr)
+		p.recordUsage(ref)
+		return func() js_ast.Expr {
+			p.recordUsage(ref)
+			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: ref}}
+		}
+	}
+
+	visit = func(expr js_ast.Expr, init js_ast.Expr) {
+		switch e := expr.Data.(type) {
+		case *js_ast.EBinary:
+			// "[a = b] = c" => "_a = c[0], a = _a === void 0 ? b : _a"
+			if e.Op == js_ast.BinOpAssign {
+				value := captureIntoRef(init)
+				visit(e.Left, js_ast.Expr{Loc: e.Right.Loc, Data: &js_ast.EIf{
+					Test: js_ast.Expr{Loc: e.Right.Loc, Data: &js_ast.EBinary{
+						Op:    js_ast.BinOpStrictEq,
+						Left:  value(),
+						Right: js_ast.Expr{Loc: e.Right.Loc, Data: js_ast.EUndefinedShared},
+					}},
+					Yes: e.Right,
+					No:  value(),
+				}})
+				return
+			}
+
+		case *js_ast.EArray:
+			// Convert the value to an array first since it may be any iterable
+			count := len(e.Items)
+			if count > 0 {
+				if _, ok := e.Items[count-1].Data.(*js_ast.ESpread); ok {
+					count = -1
+				}
+			}
+			args := []js_ast.Expr{init}
+			if count >= 0 {
+				args = append(args, js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ENumber{Value: float64(count)}})
+			}
+			array := captureIntoRef(p.callRuntime(init.Loc, "__toArray", args))
+
+			for i, item := range e.Items {
+				switch item2 := item.Data.(type) {
+				case *js_ast.EMissing:
+					continue
+
+				case *js_ast.ESpread:
+					// "[a, ...b] = c" => "_a = __toArray(c), a = _a[0], b = _a.slice(1)"
+					visit(item2.Value, js_ast.Expr{Loc: item.Loc, Data: &js_ast.ECall{
+						Target: js_ast.Expr{Loc: item.Loc, Data: &js_ast.EDot{Target: array(), Name: "slice", NameLoc: item.Loc}},
+						Args:   []js_ast.Expr{{Loc: item.Loc, Data: &js_ast.ENumber{Value: float64(i)}}},
+						Kind:   js_ast.TargetWasOriginallyPropertyAccess,
+					}})
+
+				default:
+					visit(item, js_ast.Expr{Loc: item.Loc, Data: &js_ast.EIndex{
+						Target: array(),
+						Index:  js_ast.Expr{Loc: item.Loc, Data: &js_ast.ENumber{Value: float64(i)}},
+					}})
+				}
+			}
+
+			// Make sure the iterator is still consumed for empty patterns
+			if len(e.Items) == 0 {
+				array()
+			}
+			return
+
+		case *js_ast.EObject:
+			// Make sure empty patterns still throw for null and undefined
+			if len(e.Properties) == 0 {
+				captureIntoRef(p.callRuntime(init.Loc, "__requireObject", []js_ast.Expr{init}))
+				return
+			}
+
+			// There's no need to store the value in a temporary variable if it's
+			// only going to be referenced once
+			var object func() js_ast.Expr
+			if len(e.Properties) == 1 && e.Properties[0].Kind != js_ast.PropertySpread {
+				object = func() js_ast.Expr { return init }
+			} else {
+				object = captureIntoRef(init)
+			}
+
+			hasRest := len(e.Properties) > 0 && e.Properties[len(e.Properties)-1].Kind == js_ast.PropertySpread
+			var capturedKeys []func() js_ast.Expr
+
+			for _, property := range e.Properties {
+				// "({a, ...b} = c)" => "a = c.a, b = __objRest(c, ['a'])"
+				if property.Kind == js_ast.PropertySpread {
+					keysToExclude := make([]js_ast.Expr, len(capturedKeys))
+					for i, capturedKey := range capturedKeys {
+						keysToExclude[i] = capturedKey()
+					}
+					visit(property.ValueOrNil, p.callRuntime(property.Loc, "__objRest", []js_ast.Expr{object(),
+						{Loc: property.Loc, Data: &js_ast.EArray{Items: keysToExclude, IsSingleLine: e.IsSingleLine}}}))
+					continue
+				}
+
+				// Save a copy of this key so the rest binding can exclude it
+				key := property.Key
+				if hasRest {
+					var capturedKey func() js_ast.Expr
+					key, capturedKey = p.captureKeyForObjectRest(key)
+					capturedKeys = append(capturedKeys, capturedKey)
+				}
+
+				// "({a: b} = c)" => "b = c.a"
+				value := property.ValueOrNil
+				if property.InitializerOrNil.Data != nil {
+					value = js_ast.Assign(value, property.InitializerOrNil)
+				}
+				visit(value, p.memberExprForPropertyKey(object(), key))
+			}
+			return
+		}
+
+		assign(expr, init)
+	}
+
+	// Capture and return the value of the initializer if this is an assignment
+	// expression and the return value is used
+	if mode == objRestMustReturnInitExpr {
+		initFunc, initWrapFunc := p.captureValueWithPossibleSideEffects(rootInit.Loc, 2, rootInit, valueCouldBeMutated)
+		rootInit = initFunc()
+		wrapFunc = func(expr js_ast.Expr) js_ast.Expr {
+			return initWrapFunc(js_ast.JoinWithComma(expr, initFunc()))
+		}
+	}
+
+	visit(rootExpr, rootInit)
+	return
+}
+
+// This moves default values, rest arguments, and destructuring patterns out of
+// the argument list and into the function body:
+//
+//	"function f(a = 1, {b}, ...c) {}" => "function f(a, _a) { if (a === void 0) a = 1; var b = _a.b, c = [].slice.call(arguments, 2); }"
+//
+// This must run after the function body has been visited because the code
+// that's inserted has already been lowered.
+func (p *parser) lowerArgsForES5(args *[]js_ast.Arg, bodyBlock *js_ast.SBlock, hasRestArg *bool, isArrow bool) {
+	lowerDefaults := p.options.unsupportedJSFeatures.Has(compat.DefaultArgument)
+	lowerRest := p.options.unsupportedJSFeatures.Has(compat.RestArgument) && *hasRestArg
+	lowerPatterns := p.options.unsupportedJSFeatures.Has(compat.Destructuring)
+	if !lowerDefaults && !lowerRest && !lowerPatterns {
+		return
+	}
+
+	var prefixStmts []js_ast.Stmt
+	var decls []js_ast.Decl
+
+	// Consecutive variable declarations are merged together
+	flushDecls := func() {
+		if len(decls) > 0 {
+			prefixStmts = append(prefixStmts, js_ast.Stmt{Loc: decls[0].Binding.Loc,
+				Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: p.lowerObjectRestInDecls(decls)}})
+			decls = nil
+		}
+	}
+
+	for i := range *args {
+		arg := &(*args)[i]
+		_, isIdentifier := arg.Binding.Data.(*js_ast.BIdentifier)
+
+		// "function f(...a) {}" => "function f() { var a = [].slice.call(arguments, 0) }"
+		if lowerRest && i+1 == len(*args) {
+			var argumentsRef js_ast.Ref
+			if isArrow {
+				// Arrow functions can only use their own "arguments" after they have
+				// been converted into function expressions
+				if !p.options.unsupportedJSFeatures.Has(compat.Arrow) {
+					p.markSyntaxFeature(compat.RestArgument, p.source.RangeOfOperatorBefore(arg.Binding.Loc, "..."))
+					break
+				}
+				argumentsRef = p.newSymbol(js_ast.SymbolUnbound, "arguments")
+				p.currentScope.Generated = append(p.currentScope.Generated, argumentsRef)
+			} else {
+				argumentsRef = *p.fnOnlyDataVisit.argumentsRef
+				p.recordUsage(argumentsRef)
+			}
+			loc := arg.Binding.Loc
+			decls = append(decls, js_ast.Decl{Binding: arg.Binding, ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+				Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+					Target:  js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: js_ast.Expr{Loc: loc, Data: &js_ast.EArray{}}, Name: "slice", NameLoc: loc}},
+					Name:    "call",
+					NameLoc: loc,
+				}},
+				Args: []js_ast.Expr{
+					{Loc: loc, Data: &js_ast.EIdentifier{Ref: argumentsRef}},
+					{Loc: loc, Data: &js_ast.ENumber{Value: float64(i)}},
+				},
+				Kind: js_ast.TargetWasOriginallyPropertyAccess,
+			}}})
+			*args = (*args)[:i]
+			*hasRestArg = false
+			break
+		}
+
+		hasDefault := lowerDefaults && arg.DefaultOrNil.Data != nil
+		if !hasDefault && (isIdentifier || !lowerPatterns) {
+			continue
+		}
+
+		// "function f(a = 1) {}" => "function f(a) { if (a === void 0) a = 1 }"
+		if hasDefault && isIdentifier {
+			flushDecls()
+			loc := arg.DefaultOrNil.Loc
+			ref := arg.Binding.Data.(*js_ast.BIdentifier).Ref
+			p.recordUsage(ref)
+			p.recordUsage(ref)
+			prefixStmts = append(prefixStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SIf{
+				Test: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+					Op:    js_ast.BinOpStrictEq,
+					Left:  js_ast.Expr{Loc: arg.Binding.Loc, Data: &js_ast.EIdentifier{Ref: ref}},
+					Right: js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared},
+				}},
+				Yes: js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Assign(
+					js_ast.Expr{Loc: arg.Binding.Loc, Data: &js_ast.EIdentifier{Ref: ref}}, arg.DefaultOrNil)}},
+			}})
+			arg.DefaultOrNil = js_ast.Expr{}
+			continue
+		}
+
+		// "function f({a} = b) {}" => "function f(_a) { var a = (_a === void 0 ? b : _a).a }"
+		ref := p.generateTempRef(tempRefNoDeclare, "")
+		value := js_ast.Expr{Loc: arg.Binding.Loc, Data: &js_ast.EIdentifier{Ref: ref}}
+		p.recordUsage(ref)
+		if hasDefault {
+			p.recordUsage(ref)
+			value = js_ast.Expr{Loc: arg.DefaultOrNil.Loc, Data: &js_ast.EIf{
+				Test: js_ast.Expr{Loc: arg.DefaultOrNil.Loc, Data: &js_ast.EBinary{
+					Op:    js_ast.BinOpStrictEq,
+					Left:  value,
+					Right: js_ast.Expr{Loc: arg.DefaultOrNil.Loc, Data: js_ast.EUndefinedShared},
+				}},
+				Yes: arg.DefaultOrNil,
+				No:  js_ast.Expr{Loc: arg.Binding.Loc, Data: &js_ast.EIdentifier{Ref: ref}},
+			}}
+			arg.DefaultOrNil = js_ast.Expr{}
+		}
+		decls = append(decls, js_ast.Decl{Binding: arg.Binding, ValueOrNil: value})
+		arg.Binding.Data = &js_ast.BIdentifier{Ref: ref}
+	}
+
+	flushDecls()
+	if len(prefixStmts) > 0 {
+		bodyBlock.Stmts = append(prefixStmts, bodyBlock.Stmts...)
+	}
+
+	// Lowered class fields must be initialized after these statements
+	if fn := p.fnOnlyDataVisit.classCtorFn; fn != nil && bodyBlock == &fn.Body.Block {
+		p.loweredCtorArgsStmtCount = len(prefixStmts)
+	}
+}
+
+// This converts a list of items that may contain spread elements into a single
+// array expression. If "mustCopy" is false, a lone spread element may evaluate
+// to the original array instead of a copy:
+//
+//	"[a, ...b, c]" => "[a].concat(__toArray(b), [c])"
+func (p *parser) lowerSpreadItems(loc logger.Loc, items []js_ast.Expr, mustCopy bool) js_ast.Expr {
+	var parts []js_ast.Expr
+	var run []js_ast.Expr
+	startsWithSpread := false
+
+	for _, item := range items {
+		if spread, ok := item.Data.(*js_ast.ESpread); ok {
+			if run != nil {
+				parts = append(parts, js_ast.Expr{Loc: run[0].Loc, Data: &js_ast.EArray{Items: run, IsSingleLine: true}})
+				run = nil
+			} else if parts == nil {
+				startsWithSpread = true
+			}
+			parts = append(parts, p.callRuntime(item.Loc, "__toArray", []js_ast.Expr{spread.Value}))
+		} else {
+			run = append(run, item)
+		}
+	}
+	if run != nil {
+		parts = append(parts, js_ast.Expr{Loc: run[0].Loc, Data: &js_ast.EArray{Items: run, IsSingleLine: true}})
+	}
+
+	if startsWithSpread {
+		if len(parts) == 1 && !mustCopy {
+			return parts[0]
+		}
+		parts = append([]js_ast.Expr{{Loc: loc, Data: &js_ast.EArray{}}}, parts...)
+	}
+
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: parts[0], Name: "concat", NameLoc: loc}},
+		Args:   parts[1:],
+		Kind:   js_ast.TargetWasOriginallyPropertyAccess,
+	}}
+}
+
+// "a.b(...c)" => "(_a = a).b.apply(_a, __toArray(c))"
+func (p *parser) lowerCallSpread(loc logger.Loc, e *js_ast.ECall) js_ast.Expr {
+	target := e.Target
+	thisArg := js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
+	wrapFunc := func(expr js_ast.Expr) js_ast.Expr { return expr }
+
+	// Method calls must preserve the value of "this"
+	if e.Kind == js_ast.TargetWasOriginallyPropertyAccess {
+		switch t := target.Data.(type) {
+		case *js_ast.EDot:
+			objFunc, objWrapFunc := p.captureValueWithPossibleSideEffects(t.Target.Loc, 2, t.Target, valueDefinitelyNotMutated)
+			target = js_ast.Expr{Loc: target.Loc, Data: &js_ast.EDot{Target: objFunc(), Name: t.Name, NameLoc: t.NameLoc}}
+			thisArg, wrapFunc = objFunc(), objWrapFunc
+
+		case *js_ast.EIndex:
+			objFunc, objWrapFunc := p.captureValueWithPossibleSideEffects(t.Target.Loc, 2, t.Target, valueDefinitelyNotMutated)
+			target = js_ast.Expr{Loc: target.Loc, Data: &js_ast.EIndex{Target: objFunc(), Index: t.Index}}
+			thisArg, wrapFunc = objFunc(), objWrapFunc
+		}
+	}
+
+	return wrapFunc(js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target: js_ast.Expr{Loc: target.Loc, Data: &js_ast.EDot{Target: target, Name: "apply", NameLoc: target.Loc}},
+		Args:   []js_ast.Expr{thisArg, p.lowerSpreadItems(loc, e.Args, false)},
+		Kind:   js_ast.TargetWasOriginallyPropertyAccess,
+	}})
+}
+
+// This returns the value of "this" for the function that encloses the current
+// code, which is different from "this" inside a lowered arrow function
+func (p *parser) thisOfEnclosingFn(loc logger.Loc) js_ast.Expr {
+	if !p.fnOnlyDataVisit.isThisNested {
+		if value, ok := p.valueForThis(loc, false /* shouldLog */, js_ast.AssignTargetNone, false, false); ok {
+			return value
+		}
+	}
+	if p.fnOrArrowDataVisit.isArrow && p.options.unsupportedJSFeatures.Has(compat.Arrow) {
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.captureThis()}}
+	}
+	return js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+}
+
+// Functions can only be constructed using "new" in ES5, which is detected by
+// checking whether "this" is an instance of the function. Class constructors
+// can only ever be called using "new", so that check is omitted for them:
+//
+//	"function Foo() { new.target }" => "function Foo() { this instanceof Foo ? this.constructor : void 0 }"
+func (p *parser) lowerNewTarget(loc logger.Loc) js_ast.Expr {
+	fn := p.fnOnlyDataVisit.newTargetFn
+	if fn == nil {
+		return js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
+	}
+
+	constructor := js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: p.thisOfEnclosingFn(loc), Name: "constructor", NameLoc: loc}}
+	if fn == p.fnOnlyDataVisit.classCtorFn {
+		return constructor
+	}
+
+	// Anonymous functions need a name so that they can reference themselves
+	if fn.Name == nil {
+		ref := p.newSymbol(js_ast.SymbolOther, "_newTarget")
+		p.fnOnlyDataVisit.newTargetScope.Generated = append(p.fnOnlyDataVisit.newTargetScope.Generated, ref)
+		fn.Name = &js_ast.LocRef{Loc: loc, Ref: ref}
+	}
+	p.recordUsage(fn.Name.Ref)
+
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EIf{
+		Test: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+			Op:    js_ast.BinOpInstanceof,
+			Left:  p.thisOfEnclosingFn(loc),
+			Right: js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: fn.Name.Ref}},
+		}},
+		Yes: constructor,
+		No:  js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared},
+	}}
+}
+
+// This is used when a class that extends another class is lowered to ES5. The
+// base class constructor is called on the derived class's "this" value, and
+// its return value is used as "this" for the rest of the constructor.
+type es5DerivedClass struct {
+	superRef js_ast.Ref
+	thisRef  js_ast.Ref
+
+	// These are all of the lowered "super()" calls in the constructor. They are
+	// used to insert instance field initializers after "super()" is called.
+	superCalls []*js_ast.EBinary
+}
+
+func classConstructorFn(class *js_ast.Class) *js_ast.Fn {
+	for _, prop := range class.Properties {
+		if prop.Kind == js_ast.PropertyNormal && prop.Flags.Has(js_ast.PropertyIsMethod) &&
+			!prop.Flags.Has(js_ast.PropertyIsStatic) && !prop.Flags.Has(js_ast.PropertyIsComputed) {
+			if key, ok := prop.Key.Data.(*js_ast.EString); ok && helpers.UTF16EqualsString(key.Value, "constructor") {
+				if fn, ok := prop.ValueOrNil.Data.(*js_ast.EFunction); ok {
+					return &fn.Fn
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// "super(a, b)" => "_this = _super.call(this, a, b) || this"
+// "super(...a)" => "_this = _super.apply(this, __toArray(a)) || this"
+func (p *parser) lowerSuperCallForES5(loc logger.Loc, args []js_ast.Expr, class *es5DerivedClass) js_ast.Expr {
+	hasSpread := false
+	for _, arg := range args {
+		if _, ok := arg.Data.(*js_ast.ESpread); ok {
+			hasSpread = true
+			break
+		}
+	}
+
+	if hasSpread {
+		args = []js_ast.Expr{p.thisOfEnclosingFn(loc), p.lowerSpreadItems(loc, args, false)}
+		return p.callBaseClassForES5(loc, "apply", args, p.thisOfEnclosingFn(loc), class)
+	}
+	args = append([]js_ast.Expr{p.thisOfEnclosingFn(loc)}, args...)
+	return p.callBaseClassForES5(loc, "call", args, p.thisOfEnclosingFn(loc), class)
+}
+
+// This is the "super()" call in a constructor that was generated for a
+// derived class that's lowered to ES5:
+//
+//	"_this = _super.apply(this, arguments) || this"
+func (p *parser) defaultSuperCallForES5(loc logger.Loc, class *es5DerivedClass) js_ast.Expr {
+	argumentsRef := p.newSymbol(js_ast.SymbolUnbound, "arguments")
+	p.currentScope.Generated = append(p.currentScope.Generated, argumentsRef)
+	args := []js_ast.Expr{{Loc: loc, Data: js_ast.EThisShared}, {Loc: loc, Data: &js_ast.EIdentifier{Ref: argumentsRef}}}
+	return p.callBaseClassForES5(loc, "apply", args, js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}, class)
+}
+
+func (p *parser) callBaseClassForES5(loc logger.Loc, method string, args []js_ast.Expr, this js_ast.Expr, class *es5DerivedClass) js_ast.Expr {
+	p.recordUsage(class.superRef)
+	p.recordUsage(class.thisRef)
+	call := js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+			Target:  js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: class.superRef}},
+			Name:    method,
+			NameLoc: loc,
+		}},
+		Args: args,
+		Kind: js_ast.TargetWasOriginallyPropertyAccess,
+	}}
+	assign := &js_ast.EBinary{
+		Op:    js_ast.BinOpAssign,
+		Left:  js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: class.thisRef}},
+		Right: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{Op: js_ast.BinOpLogicalOr, Left: call, Right: this}},
+	}
+	class.superCalls = append(class.superCalls, assign)
+	return js_ast.Expr{Loc: loc, Data: assign}
+}
+
+// Instance field initializers in a derived class that's lowered to ES5 are
+// inserted after the lowered "super()" call. If "super()" isn't a top-level
+// statement in the constructor, the initializers are evaluated inline after
+// each "super()" call instead.
+func (p *parser) insertStmtsAfterES5SuperCall(body *js_ast.FnBody, stmtsToInsert []js_ast.Stmt, class *es5DerivedClass) {
+	if len(stmtsToInsert) == 0 {
+		return
+	}
+
+	for i, stmt := range body.Block.Stmts {
+		if s, ok := stmt.Data.(*js_ast.SExpr); ok {
+			for _, call := range class.superCalls {
+				if s.Value.Data == call {
+					stmts := append([]js_ast.Stmt{}, body.Block.Stmts[:i+1]...)
+					stmts = append(stmts, stmtsToInsert...)
+					body.Block.Stmts = append(stmts, body.Block.Stmts[i+1:]...)
+					return
+				}
+			}
+		}
+	}
+
+	if len(class.superCalls) == 0 {
+		body.Block.Stmts = append(stmtsToInsert, body.Block.Stmts...)
+		return
+	}
+
+	// "if (a) super(); else super(b)" => "if (a) _this = _super.call(this) || this, _this.x = 1, _this; else ..."
+	for _, call := range class.superCalls {
+		clone := *call
+		value := js_ast.Expr{Loc: clone.Left.Loc, Data: &clone}
+		for _, stmt := range stmtsToInsert {
+			value = js_ast.JoinWithComma(value, stmt.Data.(*js_ast.SExpr).Value)
+		}
+		p.recordUsage(class.thisRef)
+		value = js_ast.JoinWithComma(value, js_ast.Expr{Loc: clone.Left.Loc, Data: &js_ast.EIdentifier{Ref: class.thisRef}})
+		*call = *value.Data.(*js_ast.EBinary)
+	}
+}
+
+// Classes are lowered to ES5 by converting them into a constructor function
+// with methods on its prototype. This is wrapped in a function call so that
+// the result can be used as an expression:
+//
+//	"class Foo extends Bar { foo() {} }" => "(function(_super) {
+//	  __inherit(Foo, _super);
+//	  function Foo() { return _super.apply(this, arguments) || this; }
+//	  Foo.prototype.foo = function() {};
+//	  return Foo;
+//	})(Bar)"
+func (p *parser) lowerClassToES5(loc logger.Loc, class *js_ast.Class, nameRef js_ast.Ref, derived *es5DerivedClass) js_ast.Expr {
+	canBeRemovedIfUnused := js_ast.ExprCanBeRemovedIfUnused(js_ast.Expr{Loc: loc, Data: &js_ast.EClass{Class: *class}}, p.isUnbound)
+	name := func(loc logger.Loc) js_ast.Expr {
+		p.recordUsage(nameRef)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: nameRef}}
+	}
+
+	// Methods are assigned to the class or its prototype. Accessors are defined
+	// using "Object.defineProperty" and getter/setter pairs are merged together.
+	ctor := classConstructorFn(class)
+	var members []js_ast.Stmt
+	var descriptors []*js_ast.EObject
+	descriptorsByKey := make(map[string]*js_ast.EObject)
+	for _, prop := range class.Properties {
+		if !prop.Flags.Has(js_ast.PropertyIsMethod) {
+			continue
+		}
+		if fn, ok := prop.ValueOrNil.Data.(*js_ast.EFunction); ok && &fn.Fn == ctor {
+			continue
+		}
+
+		target := name(prop.Loc)
+		if !prop.Flags.Has(js_ast.PropertyIsStatic) {
+			target = js_ast.Expr{Loc: prop.Loc, Data: &js_ast.EDot{Target: target, Name: "prototype", NameLoc: prop.Loc}}
+		}
+
+		switch prop.Kind {
+		case js_ast.PropertyGet, js_ast.PropertySet:
+			// "class { get foo() {} }" => "__defProp(Foo.prototype, 'foo', { get: function() {}, configurable: true })"
+			kind := "get"
+			if prop.Kind == js_ast.PropertySet {
+				kind = "set"
+			}
+			accessor := js_ast.Property{
+				Key:        js_ast.Expr{Loc: prop.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(kind)}},
+				ValueOrNil: prop.ValueOrNil,
+			}
+			mapKey := ""
+			if str, ok := prop.Key.Data.(*js_ast.EString); ok && !prop.Flags.Has(js_ast.PropertyIsComputed) {
+				mapKey = helpers.UTF16ToString(str.Value)
+				if prop.Flags.Has(js_ast.PropertyIsStatic) {
+					mapKey = "static " + mapKey
+				}
+				if descriptor, ok := descriptorsByKey[mapKey]; ok {
+					descriptor.Properties = append(descriptor.Properties, accessor)
+					continue
+				}
+			}
+			descriptor := &js_ast.EObject{Properties: []js_ast.Property{accessor}}
+			descriptors = append(descriptors, descriptor)
+			if mapKey != "" {
+				descriptorsByKey[mapKey] = descriptor
+			}
+			members = append(members, js_ast.Stmt{Loc: prop.Loc, Data: &js_ast.SExpr{Value: p.callRuntime(prop.Loc, "__defProp",
+				[]js_ast.Expr{target, prop.Key, {Loc: prop.Loc, Data: descriptor}})}})
+
+		default:
+			// "class { foo() {} }" => "Foo.prototype.foo = function() {}"
+			members = append(members, js_ast.AssignStmt(p.memberExprForPropertyKey(target, prop.Key), prop.ValueOrNil))
+		}
+	}
+	for _, descriptor := range descriptors {
+		descriptor.Properties = append(descriptor.Properties, js_ast.Property{
+			Key:        js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("configurable")}},
+			ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EBoolean{Value: true}},
+		})
+	}
+
+	// Generate a constructor if there isn't one
+	if ctor == nil {
+		ctor = &js_ast.Fn{Body: js_ast.FnBody{Loc: loc}}
+		if derived != nil {
+			// "return _super.apply(this, arguments) || this"
+			assign := p.defaultSuperCallForES5(loc, derived).Data.(*js_ast.EBinary)
+			p.ignoreUsage(derived.thisRef)
+			ctor.Body.Block.Stmts = []js_ast.Stmt{{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: assign.Right}}}
+		}
+	} else if derived != nil {
+		// The constructor must declare and return the value of "this"
+		stmts := ctor.Body.Block.Stmts
+		start := 0
+		for start < len(stmts) {
+			if _, ok := stmts[start].Data.(*js_ast.SDirective); !ok {
+				break
+			}
+			start++
+		}
+		decl := js_ast.Decl{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: derived.thisRef}}}
+		local := js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{decl}}}
+		if start < len(stmts) {
+			// "_this = _super.call(this) || this;" => "var _this = _super.call(this) || this;"
+			if s, ok := stmts[start].Data.(*js_ast.SExpr); ok {
+				if assign, ok := s.Value.Data.(*js_ast.EBinary); ok && assign.Op == js_ast.BinOpAssign {
+					if id, ok := assign.Left.Data.(*js_ast.EIdentifier); ok && id.Ref == derived.thisRef {
+						p.ignoreUsage(derived.thisRef)
+						local.Loc = stmts[start].Loc
+						local.Data.(*js_ast.SLocal).Decls[0].ValueOrNil = assign.Right
+						stmts = append(stmts[:start], stmts[start+1:]...)
+					}
+				}
+			}
+		}
+		stmts = append(stmts[:start], append([]js_ast.Stmt{local}, stmts[start:]...)...)
+		switch stmts[len(stmts)-1].Data.(type) {
+		case *js_ast.SReturn, *js_ast.SThrow:
+		default:
+			p.recordUsage(derived.thisRef)
+			stmts = append(stmts, js_ast.Stmt{Loc: ctor.Body.Loc, Data: &js_ast.SReturn{
+				ValueOrNil: js_ast.Expr{Loc: ctor.Body.Loc, Data: &js_ast.EIdentifier{Ref: derived.thisRef}}}})
+		}
+		ctor.Body.Block.Stmts = stmts
+	}
+	ctor.Name = &js_ast.LocRef{Loc: loc, Ref: nameRef}
+
+	// "function Foo() { __classCheck(this, Foo); ... }"
+	checkIndex := 0
+	for checkIndex < len(ctor.Body.Block.Stmts) {
+		if _, ok := ctor.Body.Block.Stmts[checkIndex].Data.(*js_ast.SDirective); !ok {
+			break
+		}
+		checkIndex++
+	}
+	check := js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: p.callRuntime(loc, "__classCheck", []js_ast.Expr{
+		{Loc: loc, Data: js_ast.EThisShared}, name(loc)})}}
+	ctor.Body.Block.Stmts = append(ctor.Body.Block.Stmts[:checkIndex], append([]js_ast.Stmt{check}, ctor.Body.Block.Stmts[checkIndex:]...)...)
+
+	var stmts []js_ast.Stmt
+	var args []js_ast.Arg
+	var callArgs []js_ast.Expr
+	if derived != nil {
+		p.recordUsage(derived.superRef)
+		stmts = append(stmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: p.callRuntime(loc, "__inherit", []js_ast.Expr{
+			name(loc), {Loc: loc, Data: &js_ast.EIdentifier{Ref: derived.superRef}}})}})
+		args = []js_ast.Arg{{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: derived.superRef}}}}
+		callArgs = []js_ast.Expr{class.ExtendsOrNil}
+	}
+	stmts = append(stmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SFunction{Fn: *ctor}})
+	stmts = append(stmts, members...)
+	stmts = append(stmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: name(loc)}})
+
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target: js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{
+			Args: args,
+			Body: js_ast.FnBody{Loc: loc, Block: js_ast.SBlock{Stmts: stmts}},
+		}}},
+		Args:                   callArgs,
+		CanBeUnwrappedIfUnused: canBeRemovedIfUnused,
+	}}
+}
+
+// Class expressions may have been captured in a temporary variable, in which
+// case the class is on the right side of an assignment
+func (p *parser) lowerClassExprToES5(expr js_ast.Expr, result visitClassResult) js_ast.Expr {
+	switch e := expr.Data.(type) {
+	case *js_ast.EClass:
+		var nameRef js_ast.Ref
+		if e.Class.Name != nil {
+			nameRef = e.Class.Name.Ref
+		} else {
+			nameRef = p.newSymbol(js_ast.SymbolOther, "_class")
+			p.currentScope.Generated = append(p.currentScope.Generated, nameRef)
+		}
+		return p.lowerClassToES5(expr.Loc, &e.Class, nameRef, result.es5DerivedClass)
+
+	case *js_ast.EBinary:
+		if class, ok := e.Right.Data.(*js_ast.EClass); ok && e.Op == js_ast.BinOpAssign {
+			if id, ok := e.Left.Data.(*js_ast.EIdentifier); ok {
+				e.Right = p.lowerClassToES5(e.Right.Loc, &class.Class, id.Ref, result.es5DerivedClass)
+			}
+		}
+	}
+	return expr
+}
+
+// Generator functions are lowered into a state machine that is driven by the
+// "__makeGenerator" runtime helper. The function body is split into the cases
+// of a "switch" statement at each "yield" expression, and control flow that
+// crosses a case boundary is turned into an instruction that is returned to
+// the runtime (see the comment on "__makeGenerator" for the instructions):
+//
+//	"function* f() { var x = yield 1; return x }"
+//
+//	"function f() {
+//	   var x;
+//	   return __makeGenerator(this, function(_state) {
+//	     switch (_state.label) {
+//	       case 0:
+//	         return [4, 1];
+//	       case 1:
+//	         x = _state.sent();
+//	         return [2, x];
+//	     }
+//	   });
+//	 }"
+//
+// Variables and nested function declarations are hoisted out of the state
+// machine so that they persist across each re-entry into the function body.
+const (
+	generatorOpReturn     = 2
+	generatorOpJump       = 3
+	generatorOpYield      = 4
+	generatorOpYieldStar  = 5
+	generatorOpEndFinally = 7
+)
+
+type generatorJumpTarget struct {
+	labels        []js_ast.Ref
+	breakLabel    int
+	continueLabel int // This is -1 if this isn't a loop
+
+	// Labeled blocks can't be the target of a "break" without a label
+	isLabelOnly bool
+}
+
+type generatorLowering struct {
+	p                   *parser
+	stateRef            js_ast.Ref
+	argumentsRef        js_ast.Ref
+	argumentsCaptureRef js_ast.Ref
+	exprHasYieldCache   map[js_ast.E]bool
+	stmtHasYieldCache   map[js_ast.S]bool
+	hoistedRefs         []js_ast.Ref
+	hoistedRefSet       map[js_ast.Ref]bool
+	hoistedFns          []js_ast.Stmt
+	tempRefs            map[js_ast.Ref]bool
+	jumpTargets         []generatorJumpTarget
+
+	// Each case holds the statements for one state. Labels are allocated before
+	// the code they refer to is generated, so references to them are numbers
+	// that are filled in at the end once every label has been assigned a case.
+	cases      [][]js_ast.Stmt
+	labelCases []int
+	labelUses  [][]*js_ast.ENumber
+}
+
+func (p *parser) lowerGeneratorBody(bodyLoc logger.Loc, bodyBlock *js_ast.SBlock) {
+	g := generatorLowering{
+		p:                   p,
+		stateRef:            p.newSymbol(js_ast.SymbolOther, "_state"),
+		argumentsRef:        js_ast.InvalidRef,
+		argumentsCaptureRef: js_ast.InvalidRef,
+		exprHasYieldCache:   make(map[js_ast.E]bool),
+		stmtHasYieldCache:   make(map[js_ast.S]bool),
+		hoistedRefSet:       make(map[js_ast.Ref]bool),
+		tempRefs:            make(map[js_ast.Ref]bool),
+		cases:               [][]js_ast.Stmt{nil},
+	}
+	p.currentScope.Generated = append(p.currentScope.Generated, g.stateRef)
+	if p.fnOnlyDataVisit.argumentsRef != nil {
+		g.argumentsRef = *p.fnOnlyDataVisit.argumentsRef
+	}
+
+	// Directives must stay at the top of the outer function
+	var outerStmts []js_ast.Stmt
+	stmts := bodyBlock.Stmts
+	for len(stmts) > 0 {
+		if _, ok := stmts[0].Data.(*js_ast.SDirective); !ok {
+			break
+		}
+		outerStmts = append(outerStmts, stmts[0])
+		stmts = stmts[1:]
+	}
+
+	// Hoist all declarations before splitting up the body. This also scans the
+	// whole body once, which replaces every use of "arguments" with a variable
+	// that captures the "arguments" of the outer function.
+	for i := range stmts {
+		g.hoistStmt(&stmts[i])
+	}
+	for _, stmt := range stmts {
+		g.stmtHasYield(stmt)
+	}
+	g.transformStmts(stmts)
+	if !g.isCaseTerminated() {
+		g.emit(g.instruction(bodyLoc, generatorOpReturn, js_ast.Expr{}))
+	}
+	for label, uses := range g.labelUses {
+		for _, use := range uses {
+			use.Value = float64(g.labelCases[label])
+		}
+	}
+
+	// A body without any suspension points doesn't need a "switch" statement
+	body := g.cases[0]
+	if len(g.cases) > 1 {
+		cases := make([]js_ast.Case, len(g.cases))
+		for i, caseStmts := range g.cases {
+			cases[i] = js_ast.Case{ValueOrNil: js_ast.Expr{Loc: bodyLoc, Data: &js_ast.ENumber{Value: float64(i)}}, Body: caseStmts}
+		}
+		body = []js_ast.Stmt{{Loc: bodyLoc, Data: &js_ast.SSwitch{
+			Test:    js_ast.Expr{Loc: bodyLoc, Data: &js_ast.EDot{Target: g.ident(bodyLoc, g.stateRef), Name: "label", NameLoc: bodyLoc}},
+			Cases:   cases,
+			BodyLoc: bodyLoc,
+		}}}
+	}
+
+	var decls []js_ast.Decl
+	if g.argumentsCaptureRef != js_ast.InvalidRef {
+		decls = append(decls, js_ast.Decl{
+			Binding:    js_ast.Binding{Loc: bodyLoc, Data: &js_ast.BIdentifier{Ref: g.argumentsCaptureRef}},
+			ValueOrNil: g.ident(bodyLoc, g.argumentsRef),
+		})
+	}
+	for _, ref := range g.hoistedRefs {
+		decls = append(decls, js_ast.Decl{Binding: js_ast.Binding{Loc: bodyLoc, Data: &js_ast.BIdentifier{Ref: ref}}})
+	}
+	if len(decls) > 0 {
+		outerStmts = append(outerStmts, js_ast.Stmt{Loc: bodyLoc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: decls}})
+	}
+	outerStmts = append(outerStmts, g.hoistedFns...)
+
+	// "function* f() { stmts }" => "function f() { return __makeGenerator(this, function(_state) { stmts }) }"
+	outerStmts = append(outerStmts, js_ast.Stmt{Loc: bodyLoc, Data: &js_ast.SReturn{ValueOrNil: p.callRuntime(bodyLoc, "__makeGenerator", []js_ast.Expr{
+		{Loc: bodyLoc, Data: js_ast.EThisShared},
+		{Loc: bodyLoc, Data: &js_ast.EFunction{Fn: js_ast.Fn{
+			Args: []js_ast.Arg{{Binding: js_ast.Binding{Loc: bodyLoc, Data: &js_ast.BIdentifier{Ref: g.stateRef}}}},
+			Body: js_ast.FnBody{Loc: bodyLoc, Block: js_ast.SBlock{Stmts: body}},
+		}}},
+	})}})
+	bodyBlock.Stmts = outerStmts
+}
+
+func (g *generatorLowering) ident(loc logger.Loc, ref js_ast.Ref) js_ast.Expr {
+	g.p.recordUsage(ref)
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+}
+
+func (g *generatorLowering) unsupported(loc logger.Loc) {
+	g.p.markSyntaxFeature(compat.Generator, logger.Range{Loc: loc})
+}
+
+func (g *generatorLowering) hoistRef(ref js_ast.Ref) {
+	if !g.hoistedRefSet[ref] {
+		g.hoistedRefSet[ref] = true
+		g.hoistedRefs = append(g.hoistedRefs, ref)
+
+		// Block-level variables may now conflict with other variables
+		g.p.currentScope.Generated = append(g.p.currentScope.Generated, ref)
+	}
+}
+
+func (g *generatorLowering) hoistBinding(binding js_ast.Binding) {
+	for _, decl := range findIdentifiers(binding, nil) {
+		g.hoistRef(decl.Binding.Data.(*js_ast.BIdentifier).Ref)
+	}
+}
+
+// "var a = 1, b" => "a = 1"
+func (g *generatorLowering) hoistLocal(s *js_ast.SLocal) (value js_ast.Expr) {
+	for _, decl := range s.Decls {
+		g.hoistBinding(decl.Binding)
+		if decl.ValueOrNil.Data != nil {
+			value = js_ast.JoinWithComma(value, js_ast.Assign(js_ast.ConvertBindingToExpr(decl.Binding, nil), decl.ValueOrNil))
+		}
+	}
+	return
+}
+
+func (g *generatorLowering) hoistStmt(stmt *js_ast.Stmt) {
+	switch s := stmt.Data.(type) {
+	case *js_ast.SLocal:
+		if value := g.hoistLocal(s); value.Data != nil {
+			stmt.Data = &js_ast.SExpr{Value: value}
+		} else {
+			stmt.Data = js_ast.SEmptyShared
+		}
+
+	case *js_ast.SFunction:
+		g.hoistedFns = append(g.hoistedFns, *stmt)
+		stmt.Data = js_ast.SEmptyShared
+
+	case *js_ast.SClass:
+		// "class Foo {}" => "Foo = class Foo {}"
+		ref := s.Class.Name.Ref
+		g.hoistRef(ref)
+		stmt.Data = &js_ast.SExpr{Value: js_ast.Assign(g.ident(s.Class.Name.Loc, ref), js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EClass{Class: s.Class}})}
+
+	case *js_ast.SBlock:
+		for i := range s.Stmts {
+			g.hoistStmt(&s.Stmts[i])
+		}
+
+	case *js_ast.SIf:
+		g.hoistStmt(&s.Yes)
+		if s.NoOrNil.Data != nil {
+			g.hoistStmt(&s.NoOrNil)
+		}
+
+	case *js_ast.SFor:
+		if local, ok := s.InitOrNil.Data.(*js_ast.SLocal); ok {
+			if value := g.hoistLocal(local); value.Data != nil {
+				s.InitOrNil.Data = &js_ast.SExpr{Value: value}
+			} else {
+				s.InitOrNil = js_ast.Stmt{}
+			}
+		}
+		g.hoistStmt(&s.Body)
+
+	case *js_ast.SForIn:
+		if local, ok := s.Init.Data.(*js_ast.SLocal); ok && len(local.Decls) == 1 {
+			g.hoistBinding(local.Decls[0].Binding)
+			s.Init.Data = &js_ast.SExpr{Value: js_ast.ConvertBindingToExpr(local.Decls[0].Binding, nil)}
+		}
+		g.hoistStmt(&s.Body)
+
+	case *js_ast.SForOf:
+		if local, ok := s.Init.Data.(*js_ast.SLocal); ok && len(local.Decls) == 1 {
+			g.hoistBinding(local.Decls[0].Binding)
+			s.Init.Data = &js_ast.SExpr{Value: js_ast.ConvertBindingToExpr(local.Decls[0].Binding, nil)}
+		}
+		g.hoistStmt(&s.Body)
+
+	case *js_ast.SWhile:
+		g.hoistStmt(&s.Body)
+
+	case *js_ast.SDoWhile:
+		g.hoistStmt(&s.Body)
+
+	case *js_ast.SLabel:
+		g.hoistStmt(&s.Stmt)
+
+	case *js_ast.SWith:
+		g.hoistStmt(&s.Body)
+
+	case *js_ast.SSwitch:
+		for i := range s.Cases {
+			for j := range s.Cases[i].Body {
+				g.hoistStmt(&s.Cases[i].Body[j])
+			}
+		}
+
+	case *js_ast.STry:
+		for i := range s.Block.Stmts {
+			g.hoistStmt(&s.Block.Stmts[i])
+		}
+		if s.Catch != nil {
+			for i := range s.Catch.Block.Stmts {
+				g.hoistStmt(&s.Catch.Block.Stmts[i])
+			}
+		}
+		if s.Finally != nil {
+			for i := range s.Finally.Block.Stmts {
+				g.hoistStmt(&s.Finally.Block.Stmts[i])
+			}
+		}
+	}
+}
+
+// This returns pointers to the child expressions of an expression in the
+// order they are evaluated. Function bodies are not included.
+func generatorExprChildren(expr js_ast.Expr) (children []*js_ast.Expr) {
+	add := func(child *js_ast.Expr) {
+		if spread, ok := child.Data.(*js_ast.ESpread); ok {
+			child = &spread.Value
+		}
+		if child.Data != nil {
+			children = append(children, child)
+		}
+	}
+	addProperties := func(properties []js_ast.Property) {
+		for i := range properties {
+			property := &properties[i]
+			if property.Flags.Has(js_ast.PropertyIsComputed) {
+				add(&property.Key)
+			}
+			add(&property.ValueOrNil)
+			add(&property.InitializerOrNil)
+		}
+	}
+
+	switch e := expr.Data.(type) {
+	case *js_ast.EArray:
+		for i := range e.Items {
+			add(&e.Items[i])
+		}
+
+	case *js_ast.EUnary:
+		add(&e.Value)
+
+	case *js_ast.EBinary:
+		add(&e.Left)
+		add(&e.Right)
+
+	case *js_ast.ENew:
+		add(&e.Target)
+		for i := range e.Args {
+			add(&e.Args[i])
+		}
+
+	case *js_ast.ECall:
+		add(&e.Target)
+		for i := range e.Args {
+			add(&e.Args[i])
+		}
+
+	case *js_ast.EDot:
+		add(&e.Target)
+
+	case *js_ast.EIndex:
+		add(&e.Target)
+		add(&e.Index)
+
+	case *js_ast.EClass:
+		add(&e.Class.ExtendsOrNil)
+		for i := range e.Class.Properties {
+			if property := &e.Class.Properties[i]; property.Flags.Has(js_ast.PropertyIsComputed) {
+				add(&property.Key)
+			}
+		}
+
+	case *js_ast.EJSXElement:
+		add(&e.TagOrNil)
+		addProperties(e.Properties)
+		for i := range e.Children {
+			add(&e.Children[i])
+		}
+
+	case *js_ast.EObject:
+		addProperties(e.Properties)
+
+	case *js_ast.ESpread:
+		add(&e.Value)
+
+	case *js_ast.ETemplate:
+		add(&e.TagOrNil)
+		for i := range e.Parts {
+			add(&e.Parts[i].Value)
+		}
+
+	case *js_ast.EInlinedEnum:
+		add(&e.Value)
+
+	case *js_ast.EAwait:
+		add(&e.Value)
+
+	case *js_ast.EYield:
+		add(&e.ValueOrNil)
+
+	case *js_ast.EIf:
+		add(&e.Test)
+		add(&e.Yes)
+		add(&e.No)
+
+	case *js_ast.EImportCall:
+		add(&e.Expr)
+		add(&e.OptionsOrNil)
+	}
+	return
+}
+
+// This scans the whole expression even after a "yield" has been found since
+// it's also responsible for replacing uses of "arguments"
+func (g *generatorLowering) exprHasYield(expr js_ast.Expr) bool {
+	if expr.Data == nil {
+		return false
+	}
+	if result, ok := g.exprHasYieldCache[expr.Data]; ok {
+		return result
+	}
+	result := false
+	switch e := expr.Data.(type) {
+	case *js_ast.EIdentifier:
+		if e.Ref == g.argumentsRef {
+			if g.argumentsCaptureRef == js_ast.InvalidRef {
+				g.argumentsCaptureRef = g.p.newSymbol(js_ast.SymbolHoisted, "_arguments")
+				g.p.currentScope.Generated = append(g.p.currentScope.Generated, g.argumentsCaptureRef)
+			}
+			e.Ref = g.argumentsCaptureRef
+			g.p.recordUsage(e.Ref)
+		}
+
+	case *js_ast.EYield:
+		result = true
+	}
+	for _, child := range generatorExprChildren(expr) {
+		if g.exprHasYield(*child) {
+			result = true
+		}
+	}
+	g.exprHasYieldCache[expr.Data] = result
+	return result
+}
+
+func (g *generatorLowering) bindingHasYield(binding js_ast.Binding) bool {
+	result := false
+	switch b := binding.Data.(type) {
+	case *js_ast.BArray:
+		for _, item := range b.Items {
+			if g.bindingHasYield(item.Binding) || g.exprHasYield(item.DefaultValueOrNil) {
+				result = true
+			}
+		}
+
+	case *js_ast.BObject:
+		for _, property := range b.Properties {
+			if (property.IsComputed && g.exprHasYield(property.Key)) ||
+				g.bindingHasYield(property.Value) || g.exprHasYield(property.DefaultValueOrNil) {
+				result = true
+			}
+		}
+	}
+	return result
+}
+
+func (g *generatorLowering) stmtsHaveYield(stmts []js_ast.Stmt) bool {
+	result := false
+	for _, stmt := range stmts {
+		if g.stmtHasYield(stmt) {
+			result = true
+		}
+	}
+	return result
+}
+
+func (g *generatorLowering) stmtHasYield(stmt js_ast.Stmt) bool {
+	if stmt.Data == nil {
+		return false
+	}
+	if result, ok := g.stmtHasYieldCache[stmt.Data]; ok {
+		return result
+	}
+	var results []bool
+	switch s := stmt.Data.(type) {
+	case *js_ast.SExpr:
+		results = append(results, g.exprHasYield(s.Value))
+
+	case *js_ast.SLocal:
+		for _, decl := range s.Decls {
+			results = append(results, g.bindingHasYield(decl.Binding), g.exprHasYield(decl.ValueOrNil))
+		}
+
+	case *js_ast.SClass:
+		results = append(results, g.exprHasYield(js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EClass{Class: s.Class}}))
+
+	case *js_ast.SBlock:
+		results = append(results, g.stmtsHaveYield(s.Stmts))
+
+	case *js_ast.SIf:
+		results = append(results, g.exprHasYield(s.Test), g.stmtHasYield(s.Yes), g.stmtHasYield(s.NoOrNil))
+
+	case *js_ast.SFor:
+		results = append(results, g.stmtHasYield(s.InitOrNil), g.exprHasYield(s.TestOrNil), g.exprHasYield(s.UpdateOrNil), g.stmtHasYield(s.Body))
+
+	case *js_ast.SForIn:
+		results = append(results, g.stmtHasYield(s.Init), g.exprHasYield(s.Value), g.stmtHasYield(s.Body))
+
+	case *js_ast.SForOf:
+		results = append(results, g.stmtHasYield(s.Init), g.exprHasYield(s.Value), g.stmtHasYield(s.Body))
+
+	case *js_ast.SWhile:
+		results = append(results, g.exprHasYield(s.Test), g.stmtHasYield(s.Body))
+
+	case *js_ast.SDoWhile:
+		results = append(results, g.stmtHasYield(s.Body), g.exprHasYield(s.Test))
+
+	case *js_ast.SLabel:
+		results = append(results, g.stmtHasYield(s.Stmt))
+
+	case *js_ast.SWith:
+		results = append(results, g.exprHasYield(s.Value), g.stmtHasYield(s.Body))
+
+	case *js_ast.SSwitch:
+		results = append(results, g.exprHasYield(s.Test))
+		for _, c := range s.Cases {
+			results = append(results, g.exprHasYield(c.ValueOrNil), g.stmtsHaveYield(c.Body))
+		}
+
+	case *js_ast.STry:
+		results = append(results, g.stmtsHaveYield(s.Block.Stmts))
+		if s.Catch != nil {
+			results = append(results, g.bindingHasYield(s.Catch.BindingOrNil), g.stmtsHaveYield(s.Catch.Block.Stmts))
+		}
+		if s.Finally != nil {
+			results = append(results, g.stmtsHaveYield(s.Finally.Block.Stmts))
+		}
+
+	case *js_ast.SReturn:
+		results = append(results, g.exprHasYield(s.ValueOrNil))
+
+	case *js_ast.SThrow:
+		results = append(results, g.exprHasYield(s.Value))
+	}
+	result := false
+	for _, r := range results {
+		if r {
+			result = true
+		}
+	}
+	g.stmtHasYieldCache[stmt.Data] = result
+	return result
+}
+
+func (g *generatorLowering) emit(stmt js_ast.Stmt) {
+	// Code after a "return" or "throw" in the same case is unreachable
+	if g.isCaseTerminated() {
+		return
+	}
+	last := len(g.cases) - 1
+	g.cases[last] = append(g.cases[last], stmt)
+}
+
+func (g *generatorLowering) emitExpr(expr js_ast.Expr) {
+	if id, ok := expr.Data.(*js_ast.EIdentifier); ok && g.tempRefs[id.Ref] {
+		return
+	}
+	g.emit(js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
+}
+
+func (g *generatorLowering) isCaseTerminated() bool {
+	if stmts := g.cases[len(g.cases)-1]; len(stmts) > 0 {
+		switch stmts[len(stmts)-1].Data.(type) {
+		case *js_ast.SReturn, *js_ast.SThrow:
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generatorLowering) newLabel() int {
+	g.labelCases = append(g.labelCases, -1)
+	g.labelUses = append(g.labelUses, nil)
+	return len(g.labelCases) - 1
+}
+
+func (g *generatorLowering) labelValue(loc logger.Loc, label int) js_ast.Expr {
+	value := &js_ast.ENumber{}
+	g.labelUses[label] = append(g.labelUses[label], value)
+	return js_ast.Expr{Loc: loc, Data: value}
+}
+
+// This starts a new case for the label unless the current case is still empty
+func (g *generatorLowering) markLabel(loc logger.Loc, label int) {
+	if len(g.cases[len(g.cases)-1]) > 0 {
+		// The runtime relies on "_state.label" being up to date
+		if !g.isCaseTerminated() {
+			g.emitExpr(js_ast.Assign(
+				js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: g.ident(loc, g.stateRef), Name: "label", NameLoc: loc}},
+				g.labelValue(loc, label)))
+		}
+		g.cases = append(g.cases, nil)
+	}
+	g.labelCases[label] = len(g.cases) - 1
+}
+
+func (g *generatorLowering) instruction(loc logger.Loc, op int, valueOrNil js_ast.Expr) js_ast.Stmt {
+	items := []js_ast.Expr{{Loc: loc, Data: &js_ast.ENumber{Value: float64(op)}}}
+	if valueOrNil.Data != nil {
+		items = append(items, valueOrNil)
+	}
+	return js_ast.Stmt{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EArray{Items: items, IsSingleLine: true}}}}
+}
+
+func (g *generatorLowering) jump(loc logger.Loc, label int) js_ast.Stmt {
+	return g.instruction(loc, generatorOpJump, g.labelValue(loc, label))
+}
+
+func (g *generatorLowering) jumpIf(test js_ast.Expr, label int) {
+	g.emit(js_ast.Stmt{Loc: test.Loc, Data: &js_ast.SIf{Test: test, Yes: g.jump(test.Loc, label)}})
+}
+
+func (g *generatorLowering) stateCall(loc logger.Loc, name string, args []js_ast.Expr) js_ast.Expr {
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: g.ident(loc, g.stateRef), Name: name, NameLoc: loc}},
+		Args:   args,
+		Kind:   js_ast.TargetWasOriginallyPropertyAccess,
+	}}
+}
+
+func (g *generatorLowering) newTemp(loc logger.Loc) js_ast.Expr {
+	ref := g.p.generateTempRef(tempRefNoDeclare, "")
+	g.tempRefs[ref] = true
+	g.hoistedRefs = append(g.hoistedRefs, ref)
+	return g.ident(loc, ref)
+}
+
+// Values computed before a "yield" must be saved if they are used after it
+func (g *generatorLowering) captureIfNeeded(expr js_ast.Expr) js_ast.Expr {
+	switch e := expr.Data.(type) {
+	case *js_ast.ENumber, *js_ast.EString, *js_ast.EBoolean, *js_ast.ENull, *js_ast.EUndefined,
+		*js_ast.EBigInt, *js_ast.EThis, *js_ast.EFunction, *js_ast.EArrow, *js_ast.EMissing:
+		return expr
+
+	case *js_ast.EIdentifier:
+		if g.tempRefs[e.Ref] {
+			return expr
+		}
+	}
+	temp := g.newTemp(expr.Loc)
+	g.emitExpr(js_ast.Assign(temp, expr))
+	return g.ident(expr.Loc, temp.Data.(*js_ast.EIdentifier).Ref)
+}
+
+// All operands up to the last one containing a "yield" are evaluated and
+// saved before that "yield" happens, which preserves the evaluation order
+func (g *generatorLowering) spillOperands(operands []*js_ast.Expr) {
+	last := -1
+	for i, operand := range operands {
+		if g.exprHasYield(*operand) {
+			last = i
+		}
+	}
+	for i := 0; i <= last; i++ {
+		value := g.spillExpr(*operands[i])
+		if i < last {
+			value = g.captureIfNeeded(value)
+		}
+		*operands[i] = value
+	}
+}
+
+// This returns an equivalent expression without any "yield" expressions. Any
+// code that must run before that expression is emitted into the state machine.
+func (g *generatorLowering) spillExpr(expr js_ast.Expr) js_ast.Expr {
+	if !g.exprHasYield(expr) {
+		return expr
+	}
+	loc := expr.Loc
+
+	switch e := expr.Data.(type) {
+	case *js_ast.EYield:
+		value := e.ValueOrNil
+		if value.Data != nil {
+			value = g.spillExpr(value)
+		}
+		op := generatorOpYield
+		if e.IsStar {
+			op = generatorOpYieldStar
+		}
+		g.emit(g.instruction(loc, op, value))
+		g.markLabel(loc, g.newLabel())
+		return g.stateCall(loc, "sent", nil)
+
+	case *js_ast.EBinary:
+		switch {
+		case e.Op == js_ast.BinOpComma:
+			g.emitExpr(g.spillExpr(e.Left))
+			return g.spillExpr(e.Right)
+
+		case e.Op == js_ast.BinOpLogicalAnd || e.Op == js_ast.BinOpLogicalOr || e.Op == js_ast.BinOpNullishCoalescing:
+			if g.exprHasYield(e.Right) {
+				return g.spillShortCircuit(loc, e.Op, e.Left, e.Right)
+			}
+
+		case e.Op.BinaryAssignTarget() != js_ast.AssignTargetNone:
+			return g.spillAssign(expr, e)
+		}
+
+	case *js_ast.EIf:
+		if g.exprHasYield(e.Yes) || g.exprHasYield(e.No) {
+			// "a ? yield b : c" => "if (!a) jump else; _a = yield b; jump end; else: _a = c; end:"
+			temp := g.newTemp(loc)
+			elseLabel := g.newLabel()
+			endLabel := g.newLabel()
+			g.jumpIf(js_ast.Not(g.spillExpr(e.Test)), elseLabel)
+			g.emitExpr(js_ast.Assign(temp, g.spillExpr(e.Yes)))
+			g.emit(g.jump(loc, endLabel))
+			g.markLabel(loc, elseLabel)
+			g.emitExpr(js_ast.Assign(g.ident(loc, temp.Data.(*js_ast.EIdentifier).Ref), g.spillExpr(e.No)))
+			g.markLabel(loc, endLabel)
+			return g.ident(loc, temp.Data.(*js_ast.EIdentifier).Ref)
+		}
+
+	case *js_ast.ECall:
+		if e.OptionalChain != js_ast.OptionalChainNone {
+			g.unsupported(loc)
+			return expr
+		}
+
+		// Calling a property must keep the value of "this" if the arguments yield:
+		// "a.b(yield c)" => "_a = a; _b = _a.b; _b.call(_a, yield c)"
+		argsHaveYield := false
+		for _, arg := range e.Args {
+			if g.exprHasYield(arg) {
+				argsHaveYield = true
+			}
+		}
+		if argsHaveYield {
+			var fn js_ast.Expr
+			var this js_ast.Expr
+			switch target := e.Target.Data.(type) {
+			case *js_ast.EDot:
+				this = g.captureIfNeeded(g.spillExpr(target.Target))
+				fn = g.captureIfNeeded(js_ast.Expr{Loc: e.Target.Loc, Data: &js_ast.EDot{Target: this, Name: target.Name, NameLoc: target.NameLoc}})
+			case *js_ast.EIndex:
+				operands := []*js_ast.Expr{&target.Target, &target.Index}
+				for _, operand := range operands {
+					*operand = g.captureIfNeeded(g.spillExpr(*operand))
+				}
+				this = target.Target
+				fn = g.captureIfNeeded(js_ast.Expr{Loc: e.Target.Loc, Data: &js_ast.EIndex{Target: this, Index: target.Index}})
+			}
+			if fn.Data != nil {
+				args := make([]*js_ast.Expr, len(e.Args))
+				for i := range e.Args {
+					args[i] = &e.Args[i]
+				}
+				g.spillOperands(args)
+				if id, ok := this.Data.(*js_ast.EIdentifier); ok {
+					this = g.ident(this.Loc, id.Ref)
+				}
+				return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+					Target: js_ast.Expr{Loc: fn.Loc, Data: &js_ast.EDot{Target: fn, Name: "call", NameLoc: fn.Loc}},
+					Args:   append([]js_ast.Expr{this}, e.Args...),
+					Kind:   js_ast.TargetWasOriginallyPropertyAccess,
+				}}
+			}
+		}
+
+	case *js_ast.EDot:
+		if e.OptionalChain != js_ast.OptionalChainNone {
+			g.unsupported(loc)
+			return expr
+		}
+
+	case *js_ast.EIndex:
+		if e.OptionalChain != js_ast.OptionalChainNone {
+			g.unsupported(loc)
+			return expr
+		}
+
+	case *js_ast.ETemplate:
+		switch e.TagOrNil.Data.(type) {
+		case *js_ast.EDot, *js_ast.EIndex:
+			g.unsupported(loc)
+			return expr
+		}
+	}
+
+	g.spillOperands(generatorExprChildren(expr))
+	g.exprHasYieldCache[expr.Data] = false
+	return expr
+}
+
+// "a && yield b" => "_a = a; if (!_a) jump end; _a = yield b; end:"
+func (g *generatorLowering) spillShortCircuit(loc logger.Loc, op js_ast.OpCode, left js_ast.Expr, right js_ast.Expr) js_ast.Expr {
+	temp := g.newTemp(loc)
+	ref := temp.Data.(*js_ast.EIdentifier).Ref
+	endLabel := g.newLabel()
+	g.emitExpr(js_ast.Assign(temp, g.spillExpr(left)))
+	var test js_ast.Expr
+	switch op {
+	case js_ast.BinOpLogicalAnd:
+		test = js_ast.Not(g.ident(loc, ref))
+	case js_ast.BinOpLogicalOr:
+		test = g.ident(loc, ref)
+	default:
+		test = js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{Op: js_ast.BinOpLooseNe, Left: g.ident(loc, ref), Right: js_ast.Expr{Loc: loc, Data: js_ast.ENullShared}}}
+	}
+	g.jumpIf(test, endLabel)
+	g.emitExpr(js_ast.Assign(g.ident(loc, ref), g.spillExpr(right)))
+	g.markLabel(loc, endLabel)
+	return g.ident(loc, ref)
+}
+
+var generatorCompoundAssignOps = map[js_ast.OpCode]js_ast.OpCode{
+	js_ast.BinOpAddAssign:        js_ast.BinOpAdd,
+	js_ast.BinOpSubAssign:        js_ast.BinOpSub,
+	js_ast.BinOpMulAssign:        js_ast.BinOpMul,
+	js_ast.BinOpDivAssign:        js_ast.BinOpDiv,
+	js_ast.BinOpRemAssign:        js_ast.BinOpRem,
+	js_ast.BinOpPowAssign:        js_ast.BinOpPow,
+	js_ast.BinOpShlAssign:        js_ast.BinOpShl,
+	js_ast.BinOpShrAssign:        js_ast.BinOpShr,
+	js_ast.BinOpUShrAssign:       js_ast.BinOpUShr,
+	js_ast.BinOpBitwiseOrAssign:  js_ast.BinOpBitwiseOr,
+	js_ast.BinOpBitwiseAndAssign: js_ast.BinOpBitwiseAnd,
+	js_ast.BinOpBitwiseXorAssign: js_ast.BinOpBitwiseXor,
+
+	js_ast.BinOpNullishCoalescingAssign: js_ast.BinOpNullishCoalescing,
+	js_ast.BinOpLogicalOrAssign:         js_ast.BinOpLogicalOr,
+	js_ast.BinOpLogicalAndAssign:        js_ast.BinOpLogicalAnd,
+}
+
+func (g *generatorLowering) spillAssign(expr js_ast.Expr, e *js_ast.EBinary) js_ast.Expr {
+	var operands []*js_ast.Expr
+	var clone func() js_ast.Expr
+	switch left := e.Left.Data.(type) {
+	case *js_ast.EIdentifier:
+		clone = func() js_ast.Expr { return g.ident(e.Left.Loc, left.Ref) }
+
+	case *js_ast.EDot:
+		if left.OptionalChain == js_ast.OptionalChainNone {
+			operands = []*js_ast.Expr{&left.Target}
+			clone = func() js_ast.Expr {
+				return js_ast.Expr{Loc: e.Left.Loc, Data: &js_ast.EDot{Target: left.Target, Name: left.Name, NameLoc: left.NameLoc}}
+			}
+		}
+
+	case *js_ast.EIndex:
+		if left.OptionalChain == js_ast.OptionalChainNone {
+			operands = []*js_ast.Expr{&left.Target, &left.Index}
+			clone = func() js_ast.Expr {
+				return js_ast.Expr{Loc: e.Left.Loc, Data: &js_ast.EIndex{Target: left.Target, Index: left.Index}}
+			}
+		}
+
+	default:
+		// Destructuring patterns are evaluated after the value
+		if !g.exprHasYield(e.Left) && e.Op == js_ast.BinOpAssign {
+			e.Right = g.spillExpr(e.Right)
+			return expr
+		}
+	}
+	if clone == nil {
+		g.unsupported(expr.Loc)
+		return expr
+	}
+
+	// "a[yield b] = c" => "_a = a; _a[yield b] = c"
+	if !g.exprHasYield(e.Right) {
+		g.spillOperands(operands)
+		return expr
+	}
+
+	// The assignment target is evaluated before the value
+	for _, operand := range operands {
+		*operand = g.captureIfNeeded(g.spillExpr(*operand))
+	}
+	if e.Op == js_ast.BinOpAssign {
+		e.Right = g.spillExpr(e.Right)
+		return expr
+	}
+	op := generatorCompoundAssignOps[e.Op]
+
+	// "a ||= yield b" => "a || (a = yield b)"
+	if op.IsShortCircuit() {
+		return g.spillShortCircuit(expr.Loc, op, clone(), js_ast.Assign(clone(), e.Right))
+	}
+
+	// "a += yield b" => "_a = a; a = _a + (yield b)"
+	old := g.captureIfNeeded(clone())
+	return js_ast.Assign(clone(), js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EBinary{Op: op, Left: old, Right: g.spillExpr(e.Right)}})
+}
+
+func (g *generatorLowering) pushJumpTarget(labels []js_ast.Ref, breakLabel int, continueLabel int) {
+	g.jumpTargets = append(g.jumpTargets, generatorJumpTarget{labels: labels, breakLabel: breakLabel, continueLabel: continueLabel})
+}
+
+func (g *generatorLowering) popJumpTarget() {
+	g.jumpTargets = g.jumpTargets[:len(g.jumpTargets)-1]
+}
+
+func (g *generatorLowering) findJumpTarget(labelOrNil *js_ast.LocRef, isContinue bool) int {
+	for i := len(g.jumpTargets) - 1; i >= 0; i-- {
+		target := g.jumpTargets[i]
+		if labelOrNil != nil {
+			for _, label := range target.labels {
+				if label == labelOrNil.Ref {
+					if isContinue {
+						return target.continueLabel
+					}
+					return target.breakLabel
+				}
+			}
+		} else if isContinue {
+			if target.continueLabel != -1 {
+				return target.continueLabel
+			}
+		} else if !target.isLabelOnly {
+			return target.breakLabel
+		}
+	}
+	panic("Internal error")
+}
+
+type generatorJumpScope struct {
+	labels     []js_ast.Ref
+	loopDepth  int
+	breakDepth int
+}
+
+// Statements without a "yield" are kept as-is, but "return" statements and
+// jumps to statements that were split up must be turned into instructions
+func (g *generatorLowering) rewriteJumps(stmt *js_ast.Stmt, scope generatorJumpScope) {
+	loop := scope
+	loop.loopDepth++
+	loop.breakDepth++
+
+	switch s := stmt.Data.(type) {
+	case *js_ast.SReturn:
+		*stmt = g.instruction(stmt.Loc, generatorOpReturn, s.ValueOrNil)
+
+	case *js_ast.SBreak:
+		if s.Label != nil {
+			for _, label := range scope.labels {
+				if label == s.Label.Ref {
+					return
+				}
+			}
+		} else if scope.breakDepth > 0 {
+			return
+		}
+		*stmt = g.jump(stmt.Loc, g.findJumpTarget(s.Label, false))
+
+	case *js_ast.SContinue:
+		if s.Label != nil {
+			for _, label := range scope.labels {
+				if label == s.Label.Ref {
+					return
+				}
+			}
+		} else if scope.loopDepth > 0 {
+			return
+		}
+		*stmt = g.jump(stmt.Loc, g.findJumpTarget(s.Label, true))
+
+	case *js_ast.SBlock:
+		for i := range s.Stmts {
+			g.rewriteJumps(&s.Stmts[i], scope)
+		}
+
+	case *js_ast.SIf:
+		g.rewriteJumps(&s.Yes, scope)
+		if s.NoOrNil.Data != nil {
+			g.rewriteJumps(&s.NoOrNil, scope)
+		}
+
+	case *js_ast.SFor:
+		g.rewriteJumps(&s.Body, loop)
+
+	case *js_ast.SForIn:
+		g.rewriteJumps(&s.Body, loop)
+
+	case *js_ast.SForOf:
+		g.rewriteJumps(&s.Body, loop)
+
+	case *js_ast.SWhile:
+		g.rewriteJumps(&s.Body, loop)
+
+	case *js_ast.SDoWhile:
+		g.rewriteJumps(&s.Body, loop)
+
+	case *js_ast.SWith:
+		g.rewriteJumps(&s.Body, scope)
+
+	case *js_ast.SLabel:
+		scope.labels = append(append([]js_ast.Ref{}, scope.labels...), s.Name.Ref)
+		g.rewriteJumps(&s.Stmt, scope)
+
+	case *js_ast.SSwitch:
+		scope.breakDepth++
+		for i := range s.Cases {
+			for j := range s.Cases[i].Body {
+				g.rewriteJumps(&s.Cases[i].Body[j], scope)
+			}
+		}
+
+	case *js_ast.STry:
+		for i := range s.Block.Stmts {
+			g.rewriteJumps(&s.Block.Stmts[i], scope)
+		}
+		if s.Catch != nil {
+			for i := range s.Catch.Block.Stmts {
+				g.rewriteJumps(&s.Catch.Block.Stmts[i], scope)
+			}
+		}
+		if s.Finally != nil {
+			for i := range s.Finally.Block.Stmts {
+				g.rewriteJumps(&s.Finally.Block.Stmts[i], scope)
+			}
+		}
+	}
+}
+
+func (g *generatorLowering) transformStmts(stmts []js_ast.Stmt) {
+	for _, stmt := range stmts {
+		g.transformStmt(stmt, nil)
+	}
+}
+
+func (g *generatorLowering) transformStmt(stmt js_ast.Stmt, labels []js_ast.Ref) {
+	if !g.stmtHasYield(stmt) {
+		if _, ok := stmt.Data.(*js_ast.SEmpty); !ok {
+			g.rewriteJumps(&stmt, generatorJumpScope{})
+			g.emit(stmt)
+		}
+		return
+	}
+	loc := stmt.Loc
+
+	switch s := stmt.Data.(type) {
+	case *js_ast.SExpr:
+		g.emitExpr(g.spillExpr(s.Value))
+
+	case *js_ast.SBlock:
+		g.transformStmts(s.Stmts)
+
+	case *js_ast.SReturn:
+		value := s.ValueOrNil
+		if value.Data != nil {
+			value = g.spillExpr(value)
+		}
+		g.emit(g.instruction(loc, generatorOpReturn, value))
+
+	case *js_ast.SThrow:
+		g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SThrow{Value: g.spillExpr(s.Value)}})
+
+	case *js_ast.SLabel:
+		labels = append(labels, s.Name.Ref)
+		switch s.Stmt.Data.(type) {
+		case *js_ast.SFor, *js_ast.SForIn, *js_ast.SWhile, *js_ast.SDoWhile:
+			g.transformStmt(s.Stmt, labels)
+		default:
+			endLabel := g.newLabel()
+			g.jumpTargets = append(g.jumpTargets, generatorJumpTarget{labels: labels, breakLabel: endLabel, continueLabel: -1, isLabelOnly: true})
+			g.transformStmt(s.Stmt, nil)
+			g.popJumpTarget()
+			g.markLabel(loc, endLabel)
+		}
+
+	case *js_ast.SIf:
+		test := g.spillExpr(s.Test)
+		if !g.stmtHasYield(s.Yes) && !g.stmtHasYield(s.NoOrNil) {
+			g.rewriteJumps(&s.Yes, generatorJumpScope{})
+			if s.NoOrNil.Data != nil {
+				g.rewriteJumps(&s.NoOrNil, generatorJumpScope{})
+			}
+			g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SIf{Test: test, Yes: s.Yes, NoOrNil: s.NoOrNil}})
+			return
+		}
+		endLabel := g.newLabel()
+		if s.NoOrNil.Data == nil {
+			g.jumpIf(js_ast.Not(test), endLabel)
+			g.transformStmt(s.Yes, nil)
+		} else {
+			elseLabel := g.newLabel()
+			g.jumpIf(js_ast.Not(test), elseLabel)
+			g.transformStmt(s.Yes, nil)
+			g.emit(g.jump(loc, endLabel))
+			g.markLabel(loc, elseLabel)
+			g.transformStmt(s.NoOrNil, nil)
+		}
+		g.markLabel(loc, endLabel)
+
+	case *js_ast.SWhile:
+		loopLabel := g.newLabel()
+		endLabel := g.newLabel()
+		g.markLabel(loc, loopLabel)
+		g.jumpUnless(g.spillExpr(s.Test), endLabel)
+		g.pushJumpTarget(labels, endLabel, loopLabel)
+		g.transformStmt(s.Body, nil)
+		g.popJumpTarget()
+		g.emit(g.jump(loc, loopLabel))
+		g.markLabel(loc, endLabel)
+
+	case *js_ast.SDoWhile:
+		bodyLabel := g.newLabel()
+		testLabel := g.newLabel()
+		endLabel := g.newLabel()
+		g.markLabel(loc, bodyLabel)
+		g.pushJumpTarget(labels, endLabel, testLabel)
+		g.transformStmt(s.Body, nil)
+		g.popJumpTarget()
+		g.markLabel(loc, testLabel)
+		g.jumpIf(g.spillExpr(s.Test), bodyLabel)
+		g.markLabel(loc, endLabel)
+
+	case *js_ast.SFor:
+		if s.InitOrNil.Data != nil {
+			g.transformStmt(s.InitOrNil, nil)
+		}
+		if !g.exprHasYield(s.TestOrNil) && !g.exprHasYield(s.UpdateOrNil) && !g.stmtHasYield(s.Body) {
+			g.rewriteJumps(&s.Body, generatorJumpScope{labels: labels, loopDepth: 1, breakDepth: 1})
+			loop := js_ast.Stmt{Loc: loc, Data: &js_ast.SFor{TestOrNil: s.TestOrNil, UpdateOrNil: s.UpdateOrNil, Body: s.Body}}
+			for i := len(labels) - 1; i >= 0; i-- {
+				loop = js_ast.Stmt{Loc: loc, Data: &js_ast.SLabel{Name: js_ast.LocRef{Loc: loc, Ref: labels[i]}, Stmt: loop}}
+			}
+			g.emit(loop)
+			return
+		}
+		testLabel := g.newLabel()
+		updateLabel := g.newLabel()
+		endLabel := g.newLabel()
+		g.markLabel(loc, testLabel)
+		if s.TestOrNil.Data != nil {
+			g.jumpUnless(g.spillExpr(s.TestOrNil), endLabel)
+		}
+		g.pushJumpTarget(labels, endLabel, updateLabel)
+		g.transformStmt(s.Body, nil)
+		g.popJumpTarget()
+		g.markLabel(loc, updateLabel)
+		if s.UpdateOrNil.Data != nil {
+			g.emitExpr(g.spillExpr(s.UpdateOrNil))
+		}
+		g.emit(g.jump(loc, testLabel))
+		g.markLabel(loc, endLabel)
+
+	case *js_ast.SForIn:
+		init, ok := s.Init.Data.(*js_ast.SExpr)
+		if !ok || g.exprHasYield(init.Value) {
+			g.unsupported(loc)
+			g.emit(stmt)
+			return
+		}
+		value := g.spillExpr(s.Value)
+		if !g.stmtHasYield(s.Body) {
+			g.rewriteJumps(&s.Body, generatorJumpScope{labels: labels, loopDepth: 1, breakDepth: 1})
+			loop := js_ast.Stmt{Loc: loc, Data: &js_ast.SForIn{Init: s.Init, Value: value, Body: s.Body}}
+			for i := len(labels) - 1; i >= 0; i-- {
+				loop = js_ast.Stmt{Loc: loc, Data: &js_ast.SLabel{Name: js_ast.LocRef{Loc: loc, Ref: labels[i]}, Stmt: loop}}
+			}
+			g.emit(loop)
+			return
+		}
+
+		// The keys are collected up front since the loop can't be resumed:
+		//
+		//   "for (x in y) stmts" => "_a = []; for (_b in y) _a.push(_b); for (_c = 0; _c < _a.length; _c++) { _b = _a[_c]; if (_b in y) { x = _b; stmts } }"
+		//
+		object := g.newTemp(loc)
+		objectRef := object.Data.(*js_ast.EIdentifier).Ref
+		keys := g.newTemp(loc)
+		keysRef := keys.Data.(*js_ast.EIdentifier).Ref
+		key := g.newTemp(loc)
+		keyRef := key.Data.(*js_ast.EIdentifier).Ref
+		index := g.newTemp(loc)
+		indexRef := index.Data.(*js_ast.EIdentifier).Ref
+		g.emitExpr(js_ast.Assign(object, value))
+		g.emitExpr(js_ast.Assign(keys, js_ast.Expr{Loc: loc, Data: &js_ast.EArray{}}))
+		g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SForIn{
+			Init:  js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: key}},
+			Value: g.ident(loc, objectRef),
+			Body: js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+				Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: g.ident(loc, keysRef), Name: "push", NameLoc: loc}},
+				Args:   []js_ast.Expr{g.ident(loc, keyRef)},
+				Kind:   js_ast.TargetWasOriginallyPropertyAccess,
+			}}}},
+		}})
+		g.emitExpr(js_ast.Assign(index, js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: 0}}))
+		testLabel := g.newLabel()
+		updateLabel := g.newLabel()
+		endLabel := g.newLabel()
+		g.markLabel(loc, testLabel)
+		g.jumpUnless(js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+			Op:    js_ast.BinOpLt,
+			Left:  g.ident(loc, indexRef),
+			Right: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: g.ident(loc, keysRef), Name: "length", NameLoc: loc}},
+		}}, endLabel)
+		g.emitExpr(js_ast.Assign(g.ident(loc, keyRef), js_ast.Expr{Loc: loc, Data: &js_ast.EIndex{Target: g.ident(loc, keysRef), Index: g.ident(loc, indexRef)}}))
+		g.jumpUnless(js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{Op: js_ast.BinOpIn, Left: g.ident(loc, keyRef), Right: g.ident(loc, objectRef)}}, updateLabel)
+		g.emitExpr(js_ast.Assign(init.Value, g.ident(loc, keyRef)))
+		g.pushJumpTarget(labels, endLabel, updateLabel)
+		g.transformStmt(s.Body, nil)
+		g.popJumpTarget()
+		g.markLabel(loc, updateLabel)
+		g.emitExpr(js_ast.Expr{Loc: loc, Data: &js_ast.EUnary{Op: js_ast.UnOpPostInc, Value: g.ident(loc, indexRef)}})
+		g.emit(g.jump(loc, testLabel))
+		g.markLabel(loc, endLabel)
+
+	case *js_ast.SSwitch:
+		// "switch (a) { case b: stmts }" => "_a = a; if (_a === b) jump case; jump end; case: stmts; end:"
+		test := g.spillExpr(s.Test)
+		temp := g.newTemp(loc)
+		g.emitExpr(js_ast.Assign(temp, test))
+		endLabel := g.newLabel()
+		defaultLabel := endLabel
+		caseLabels := make([]int, len(s.Cases))
+		for i, c := range s.Cases {
+			caseLabels[i] = g.newLabel()
+			if c.ValueOrNil.Data == nil {
+				defaultLabel = caseLabels[i]
+				continue
+			}
+			g.jumpIf(js_ast.Expr{Loc: c.Loc, Data: &js_ast.EBinary{
+				Op:    js_ast.BinOpStrictEq,
+				Left:  g.ident(loc, temp.Data.(*js_ast.EIdentifier).Ref),
+				Right: g.spillExpr(c.ValueOrNil),
+			}}, caseLabels[i])
+		}
+		g.emit(g.jump(loc, defaultLabel))
+		g.jumpTargets = append(g.jumpTargets, generatorJumpTarget{labels: labels, breakLabel: endLabel, continueLabel: -1})
+		for i, c := range s.Cases {
+			g.markLabel(c.Loc, caseLabels[i])
+			g.transformStmts(c.Body)
+		}
+		g.popJumpTarget()
+		g.markLabel(loc, endLabel)
+
+	case *js_ast.STry:
+		// "try { a } catch (e) { b } finally { c }" => "try: _state.trys.push([try, catch, finally, end]); a; jump end; catch: e = _state.sent(); b; jump end; finally: c; end finally; end:"
+		tryLabel := g.newLabel()
+		catchLabel := -1
+		finallyLabel := -1
+		endLabel := g.newLabel()
+		region := []js_ast.Expr{g.labelValue(loc, tryLabel), {Loc: loc, Data: js_ast.EMissingShared}, {Loc: loc, Data: js_ast.EMissingShared}, g.labelValue(loc, endLabel)}
+		if s.Catch != nil {
+			catchLabel = g.newLabel()
+			region[1] = g.labelValue(s.Catch.Loc, catchLabel)
+		}
+		if s.Finally != nil {
+			finallyLabel = g.newLabel()
+			region[2] = g.labelValue(s.Finally.Loc, finallyLabel)
+		}
+		g.markLabel(loc, tryLabel)
+		g.emitExpr(js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+			Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+				Target:  js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: g.ident(loc, g.stateRef), Name: "trys", NameLoc: loc}},
+				Name:    "push",
+				NameLoc: loc,
+			}},
+			Args: []js_ast.Expr{{Loc: loc, Data: &js_ast.EArray{Items: region, IsSingleLine: true}}},
+			Kind: js_ast.TargetWasOriginallyPropertyAccess,
+		}})
+		g.transformStmts(s.Block.Stmts)
+		g.emit(g.jump(loc, endLabel))
+		if s.Catch != nil {
+			g.markLabel(s.Catch.Loc, catchLabel)
+			sent := g.stateCall(s.Catch.Loc, "sent", nil)
+			if s.Catch.BindingOrNil.Data != nil {
+				g.hoistBinding(s.Catch.BindingOrNil)
+				g.emitExpr(js_ast.Assign(js_ast.ConvertBindingToExpr(s.Catch.BindingOrNil, nil), sent))
+			} else {
+				g.emitExpr(sent)
+			}
+			g.transformStmts(s.Catch.Block.Stmts)
+			g.emit(g.jump(s.Catch.Loc, endLabel))
+		}
+		if s.Finally != nil {
+			g.markLabel(s.Finally.Loc, finallyLabel)
+			g.transformStmts(s.Finally.Block.Stmts)
+			g.emit(g.instruction(s.Finally.Loc, generatorOpEndFinally, js_ast.Expr{}))
+		}
+		g.markLabel(loc, endLabel)
+
+	default:
+		g.unsupported(loc)
+		g.emit(stmt)
+	}
+}
+
+func (g *generatorLowering) jumpUnless(test js_ast.Expr, label int) {
+	if boolean, ok := test.Data.(*js_ast.EBoolean); ok && boolean.Value {
+		return
+	}
+	g.jumpIf(js_ast.Not(test), label)
+}
@@ -46,9 +46,12 @@ func TestLowerArrowFunction(t *testing.T) {
 	expectPrintedTarget(t, 5, "function foo(a) { return function() { return arguments[0] } }",
 		"function foo(a) {\n  return function() {\n    return arguments[0];\n  };\n}\n")
 
-	// Handling this case isn't implemented yet
 	expectPrintedTarget(t, 5, "var foo = () => this",
-		"var foo = function() {\n  return this;\n};\n")
+		"var _this = this;\nvar foo = function() {\n  return _this;\n};\n")
+	expectPrintedTarget(t, 5, "this.x = 1; var foo = () => () => this.x",
+		"var _this = this;\nthis.x = 1;\nvar foo = function() {\n  return function() {\n    return _this.x;\n  };\n};\n")
+	expectPrintedTarget(t, 5, "export var foo = () => this",
+		"export var foo = function() {\n  return void 0;\n};\n")
 }
 
 func TestLowerNullishCoalescing(t *testing.T) {
@@ -700,12 +703,12 @@ func TestAsyncGeneratorFns(t *testing.T) {
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncAwait, "(async function () {});", err)
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncAwait, "({ async foo() {} });", err)
 
-	err = "<stdin>: ERROR: Transforming generator functions to the configured target environment is not supported yet\n"
+	// Generator functions are lowered to a state machine
 	expectParseErrorWithUnsupportedFeatures(t, compat.Generator, "function* gen() {}", err)
 	expectParseErrorWithUnsupportedFeatures(t, compat.Generator, "(function* () {});", err)
 	expectParseErrorWithUnsupportedFeatures(t, compat.Generator, "({ *foo() {} });", err)
 
-	err = "<stdin>: ERROR: Transforming async functions to the configured target environment is not supported yet\n"
+	// Async functions are lowered to generators, which are then lowered too
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncAwait|compat.Generator, "async function gen() {}", err)
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncAwait|compat.Generator, "(async function () {});", err)
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncAwait|compat.Generator, "({ async foo() {} });", err)
@@ -716,6 +719,30 @@ func TestAsyncGeneratorFns(t *testing.T) {
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncGenerator, "({ async *foo() {} });", err)
 }
 
+func TestLowerUsing(t *testing.T) {
+	expectPrintedWithUnsupportedFeatures(t, compat.Using, "{ using x = y; z(x) }",
+		"{\n  var _stack = [], _error, _hasError = false;\n  try {\n    const x = __using(_stack, y);\n    z(x);\n"+
+			"  } catch (_) {\n    _error = _, _hasError = true;\n  } finally {\n    __callDispose(_stack, _error, _hasError);\n  }\n}\n")
+	expectPrintedWithUnsupportedFeatures(t, compat.Using, "function f() { using x = y; return x }",
+		"function f() {\n  var _stack = [], _error, _hasError = false;\n  try {\n    const x = __using(_stack, y);\n    return x;\n"+
+			"  } catch (_) {\n    _error = _, _hasError = true;\n  } finally {\n    __callDispose(_stack, _error, _hasError);\n  }\n}\n")
+	expectPrintedWithUnsupportedFeatures(t, compat.Using, "async function f() { await using x = y }",
+		"async function f() {\n  var _stack = [], _error, _hasError = false;\n  try {\n    const x = __using(_stack, y, true);\n"+
+			"  } catch (_) {\n    _error = _, _hasError = true;\n  } finally {\n    var _promise = __callDispose(_stack, _error, _hasError);\n    _promise && await _promise;\n  }\n}\n")
+	expectPrintedWithUnsupportedFeatures(t, compat.Using, "function f() { for (using x of y) z(x) }",
+		"function f() {\n  for (const _a of y) {\n    var _stack = [], _error, _hasError = false;\n    try {\n      const x = __using(_stack, _a);\n      z(x);\n"+
+			"    } catch (_) {\n      _error = _, _hasError = true;\n    } finally {\n      __callDispose(_stack, _error, _hasError);\n    }\n  }\n}\n")
+
+	// Declarations that aren't "using" should be unaffected
+	expectPrintedWithUnsupportedFeatures(t, compat.Using, "{ const x = y; z(x) }", "{\n  const x = y;\n  z(x);\n}\n")
+
+	// Exports at the top level must be hoisted outside of the "try" block
+	expectPrintedWithUnsupportedFeatures(t, compat.Using, "export let a = 1; using x = y; export function f() {}",
+		"export function f() {\n}\nvar _stack = [], _error, _hasError = false;\ntry {\n  var a = 1;\n  var x = __using(_stack, y);\n"+
+			"} catch (_) {\n  _error = _, _hasError = true;\n} finally {\n  __callDispose(_stack, _error, _hasError);\n}\n"+
+			"export {\n  a\n};\n")
+}
+
 func TestForAwait(t *testing.T) {
 	err := ""
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncAwait, "async function gen() { for await (x of y) ; }", err)
@@ -727,10 +754,7 @@ func TestForAwait(t *testing.T) {
 	// This is ok because for-await can be lowered to yield
 	expectParseErrorWithUnsupportedFeatures(t, compat.ForAwait|compat.AsyncAwait, "async function gen() { for await (x of y) ; }", err)
 
-	// This is not ok because for-await can't be lowered
-	err =
-		"<stdin>: ERROR: Transforming async functions to the configured target environment is not supported yet\n" +
-			"<stdin>: ERROR: Transforming for-await loops to the configured target environment is not supported yet\n"
+	// This is ok because for-await can be lowered to yield, which can then be lowered to a state machine
 	expectParseErrorWithUnsupportedFeatures(t, compat.ForAwait|compat.AsyncAwait|compat.Generator, "async function gen() { for await (x of y) ; }", err)
 
 	// Can't use for-await at the top-level without top-level await
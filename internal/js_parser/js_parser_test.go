@@ -2,6 +2,7 @@ package js_parser
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -82,6 +83,7 @@ func expectPrintedCommon(t *testing.T, contents string, expected string, options
 		js := js_printer.Print(tree, symbols, r, js_printer.Options{
 			UnsupportedFeatures: options.UnsupportedJSFeatures,
 			ASCIIOnly:           options.ASCIIOnly,
+			MinifyWhitespace:    options.MinifyWhitespace,
 		}).JS
 		test.AssertEqualWithDiff(t, string(js), expected)
 	})
@@ -130,6 +132,36 @@ func expectPrintedASCII(t *testing.T, contents string, expected string) {
 	})
 }
 
+func expectPrintedDropLabels(t *testing.T, labels []string, contents string, expected string) {
+	t.Helper()
+	expectPrintedCommon(t, contents, expected, config.Options{
+		DropLabels: labels,
+	})
+}
+
+func expectPrintedComments(t *testing.T, mode config.CommentsMode, filter string, contents string, expected string) {
+	t.Helper()
+	options := config.Options{
+		Comments: mode,
+	}
+	if filter != "" {
+		options.CommentsFilter = regexp.MustCompile(filter)
+	}
+	expectPrintedCommon(t, contents, expected, options)
+}
+
+func expectPrintedCommentsMinify(t *testing.T, mode config.CommentsMode, filter string, contents string, expected string) {
+	t.Helper()
+	options := config.Options{
+		Comments:         mode,
+		MinifyWhitespace: true,
+	}
+	if filter != "" {
+		options.CommentsFilter = regexp.MustCompile(filter)
+	}
+	expectPrintedCommon(t, contents, expected, options)
+}
+
 func expectPrintedTargetASCII(t *testing.T, esVersion int, contents string, expected string) {
 	t.Helper()
 	expectPrintedCommon(t, contents, expected, config.Options{
@@ -1199,6 +1231,34 @@ func TestASI(t *testing.T) {
 	expectPrinted(t, "{do x;while(y)}", "{\n  do\n    x;\n  while (y);\n}\n")
 }
 
+func TestSyntaxErrorRecovery(t *testing.T) {
+	expectParseError(t, "let x = ;\nlet y = ;",
+		"<stdin>: ERROR: Unexpected \";\"\n<stdin>: ERROR: Unexpected \";\"\n")
+	expectParseError(t, "let x = ; let y = 1; let z = )",
+		"<stdin>: ERROR: Unexpected \";\"\n<stdin>: ERROR: Unexpected \")\"\n")
+	expectParseError(t, "function f() {\n  let x = ;\n  return 1\n}\nlet y = ;",
+		"<stdin>: ERROR: Unexpected \";\"\n<stdin>: ERROR: Unexpected \";\"\n")
+	expectParseError(t, "let a = `${b +}` + `${ {c: 1} }`; let c = ;",
+		"<stdin>: ERROR: Unexpected \"}\"\n<stdin>: ERROR: Unexpected \";\"\n")
+	expectParseError(t, "let x = a ==== b\nlet y = /}/.test(x) ==== c",
+		"<stdin>: ERROR: Unexpected \"=\"\n<stdin>: ERROR: Unexpected \"=\"\n")
+	expectParseError(t, "a ++ b\nclass {}\nfoo(;",
+		"<stdin>: ERROR: Expected \";\" but found \"b\"\n<stdin>: ERROR: Expected identifier but found \"{\"\n<stdin>: ERROR: Unexpected \";\"\n")
+
+	// Only errors are reported after the first syntax error
+	expectParseError(t, "let x = ;\nreturn\nx", "<stdin>: ERROR: Unexpected \";\"\n")
+
+	// Don't resume parsing in the middle of a statement
+	expectParseError(t, "if (x ==== y)\n  return;\nelse\n  return", "<stdin>: ERROR: Unexpected \"=\"\n")
+	expectParseError(t, "try {\n  x ==== y\n}\ncatch {}\nfinally {}", "<stdin>: ERROR: Unexpected \"=\"\n")
+	expectParseError(t, "let x = a ==== b +\nfunction() {}", "<stdin>: ERROR: Unexpected \"=\"\n")
+
+	// Give up if the skipped code can't be lexed without parsing it
+	expectParseError(t, "let x = ;\nlet s = 'abc\nlet y = ;",
+		"<stdin>: ERROR: Unexpected \";\"\n<stdin>: ERROR: Unterminated string literal\n")
+	expectParseError(t, "let s = 'abc\nlet y = ;", "<stdin>: ERROR: Unterminated string literal\n")
+}
+
 func TestLocal(t *testing.T) {
 	expectPrinted(t, "var let = 0", "var let = 0;\n")
 	expectParseError(t, "let let = 0", "<stdin>: ERROR: Cannot use \"let\" as an identifier here:\n")
@@ -1485,6 +1545,35 @@ func TestLexicalDecl(t *testing.T) {
 	expectPrinted(t, "'use strict'; if (foo) { eval(''); function x() {} }", "\"use strict\";\nif (foo) {\n  function x() {\n  }\n  eval(\"\");\n}\n")
 }
 
+func TestUsing(t *testing.T) {
+	expectPrinted(t, "using x = y", "using x = y;\n")
+	expectPrinted(t, "using x = y, z = w", "using x = y, z = w;\n")
+	expectPrinted(t, "{ using x = y }", "{\n  using x = y;\n}\n")
+	expectPrinted(t, "for (using x of y) ;", "for (using x of y)\n  ;\n")
+	expectPrinted(t, "for (using x = y;;) ;", "for (using x = y; ; )\n  ;\n")
+	expectPrinted(t, "await using x = y", "await using x = y;\n")
+	expectPrinted(t, "async function f() { await using x = y }", "async function f() {\n  await using x = y;\n}\n")
+	expectPrinted(t, "async function f() { for (await using x of y) ; }", "async function f() {\n  for (await using x of y)\n    ;\n}\n")
+
+	// These are not "using" declarations
+	expectPrinted(t, "using", "using;\n")
+	expectPrinted(t, "using.x = y", "using.x = y;\n")
+	expectPrinted(t, "using[x] = y", "using[x] = y;\n")
+	expectPrinted(t, "using\nx = y", "using;\nx = y;\n")
+	expectPrinted(t, "for (using of y) ;", "for (using of y)\n  ;\n")
+	expectPrinted(t, "await using\nx = y", "await using;\nx = y;\n")
+	expectPrinted(t, "function f() { using x = y }", "function f() {\n  using x = y;\n}\n")
+
+	expectParseError(t, "using x", "<stdin>: ERROR: The constant \"x\" must be initialized\n")
+	expectParseError(t, "for (using x;;) ;", "<stdin>: ERROR: The constant \"x\" must be initialized\n")
+	expectParseError(t, "using x = y, {z} = w", "<stdin>: ERROR: Destructuring is not allowed in \"using\" declarations\n")
+	expectParseError(t, "for (using x in y) ;", "<stdin>: ERROR: \"using\" declarations are not allowed in for-in loops\n")
+	expectParseError(t, "if (1) using x = y", "<stdin>: ERROR: Cannot use a declaration in a single-statement context\n")
+	expectParseError(t, "function f() { await using x = y }",
+		"<stdin>: ERROR: \"await\" can only be used inside an \"async\" function\n"+
+			"<stdin>: NOTE: Consider adding the \"async\" keyword here:\n")
+}
+
 func TestFunction(t *testing.T) {
 	expectPrinted(t, "function f() {} function f() {}", "function f() {\n}\nfunction f() {\n}\n")
 	expectPrinted(t, "function f() {} function* f() {}", "function f() {\n}\nfunction* f() {\n}\n")
@@ -1937,6 +2026,42 @@ func TestClassStaticBlocks(t *testing.T) {
 	expectParseError(t, "x: { class Foo { static { continue x } } }", "<stdin>: ERROR: There is no containing label named \"x\"\n")
 }
 
+func TestDecorators(t *testing.T) {
+	expectPrinted(t, "@dec class Foo {}", "@dec class Foo {\n}\n")
+	expectPrinted(t, "@x.y() class Foo {}", "@x.y() class Foo {\n}\n")
+	expectPrinted(t, "@(a + b) class Foo {}", "@(a + b) class Foo {\n}\n")
+	expectPrinted(t, "@a @b class Foo {}", "@a @b class Foo {\n}\n")
+	expectPrinted(t, "let x = @dec class {}", "let x = @dec class {\n};\n")
+	expectPrinted(t, "export @dec class Foo {}", "@dec export class Foo {\n}\n")
+	expectPrinted(t, "@dec export class Foo {}", "@dec export class Foo {\n}\n")
+	expectPrinted(t, "export default @dec class {}", "@dec export default class {\n}\n")
+	expectPrinted(t, "class Foo { @dec m() {} @dec static x = 1 }", "class Foo {\n  @dec m() {\n  }\n  @dec static x = 1;\n}\n")
+	expectPrinted(t, "class Foo { @dec #m() {} @dec accessor #x }", "class Foo {\n  @dec #m() {\n  }\n  @dec accessor #x;\n}\n")
+
+	expectParseError(t, "@dec let x", "<stdin>: ERROR: Expected \"class\" after decorator but found \"let\"\n"+
+		"<stdin>: NOTE: The preceding decorator is here:\n"+
+		"NOTE: Decorators can only be used with classes and class members.\n")
+	expectParseError(t, "@a?.b class Foo {}", "<stdin>: ERROR: Expected \"class\" after decorator but found \"?.\"\n"+
+		"<stdin>: NOTE: The preceding decorator is here:\n"+
+		"NOTE: Decorators can only be used with classes and class members.\n")
+	expectParseError(t, "class Foo { @dec static {} }", "<stdin>: ERROR: Decorators are not allowed on class static blocks\n")
+	expectParseError(t, "class Foo { @dec constructor() {} }", "<stdin>: ERROR: Decorators are not allowed on class constructors\n")
+	expectParseError(t, "function foo(@dec x) {}", "<stdin>: ERROR: Expected identifier but found \"@\"\n")
+}
+
+func TestAutoAccessors(t *testing.T) {
+	expectPrinted(t, "class Foo { accessor x }", "class Foo {\n  accessor x;\n}\n")
+	expectPrinted(t, "class Foo { accessor x = 1 }", "class Foo {\n  accessor x = 1;\n}\n")
+	expectPrinted(t, "class Foo { static accessor #x = 1 }", "class Foo {\n  static accessor #x = 1;\n}\n")
+	expectPrinted(t, "class Foo { accessor [x] }", "class Foo {\n  accessor [x];\n}\n")
+	expectPrinted(t, "class Foo { accessor\nx }", "class Foo {\n  accessor;\n  x;\n}\n")
+	expectPrinted(t, "class Foo { accessor() {} }", "class Foo {\n  accessor() {\n  }\n}\n")
+
+	expectPrintedTarget(t, 2022, "class Foo { accessor x = 1 }",
+		"var _x;\nclass Foo {\n  constructor() {\n    __privateAdd(this, _x, 1);\n  }\n  get x() {\n    return __privateGet(this, _x);\n  }\n"+
+			"  set x(_) {\n    __privateSet(this, _x, _);\n  }\n}\n_x = new WeakMap();\n")
+}
+
 func TestGenerator(t *testing.T) {
 	expectParseError(t, "(class { * foo })", "<stdin>: ERROR: Expected \"(\" but found \"}\"\n")
 	expectParseError(t, "(class { * *foo() {} })", "<stdin>: ERROR: Unexpected \"*\"\n")
@@ -2181,6 +2306,20 @@ func TestLabels(t *testing.T) {
 	expectPrintedMangle(t, "y: while (foo()) x: { break y; foo() }", "y:\n  for (; foo(); )\n    x:\n      break y;\n")
 }
 
+func TestDropLabels(t *testing.T) {
+	dev := []string{"DEV"}
+	expectPrintedDropLabels(t, dev, "DEV: foo()", "")
+	expectPrintedDropLabels(t, dev, "DEV: { foo(); bar() }", "")
+	expectPrintedDropLabels(t, dev, "DEV: foo(); bar()", "bar();\n")
+	expectPrintedDropLabels(t, dev, "PROD: foo()", "PROD:\n  foo();\n")
+	expectPrintedDropLabels(t, dev, "function f() { DEV: foo(); return 1 }", "function f() {\n  return 1;\n}\n")
+	expectPrintedDropLabels(t, dev, "DEV: while (x) { break DEV }", "")
+	expectPrintedDropLabels(t, dev, "x: DEV: y: foo()", "x:\n  ;\n")
+	expectPrintedDropLabels(t, dev, "DEV: { let x = () => { y: z() }; x() }", "")
+	expectPrintedDropLabels(t, []string{"DEV", "TEST"}, "DEV: a(); TEST: b(); c()", "c();\n")
+	expectPrintedDropLabels(t, nil, "DEV: foo()", "DEV:\n  foo();\n")
+}
+
 func TestArrow(t *testing.T) {
 	expectParseError(t, "({a: b, c() {}}) => {}", "<stdin>: ERROR: Invalid binding pattern\n")
 	expectParseError(t, "({a: b, get c() {}}) => {}", "<stdin>: ERROR: Invalid binding pattern\n")
@@ -3059,8 +3198,7 @@ func TestMangleLoopJump(t *testing.T) {
 	// Trim trailing continue
 	expectPrintedMangle(t, "while (x()) continue", "for (; x(); )\n  ;\n")
 	expectPrintedMangle(t, "while (x) { y(); continue }", "for (; x; )\n  y();\n")
-	expectPrintedMangle(t, "while (x) { if (y) { z(); continue } }",
-		"for (; x; )\n  if (y) {\n    z();\n    continue;\n  }\n")
+	expectPrintedMangle(t, "while (x) { if (y) { z(); continue } }", "for (; x; )\n  y && z();\n")
 	expectPrintedMangle(t, "label: while (x) while (y) { z(); continue label }",
 		"label:\n  for (; x; )\n    for (; y; ) {\n      z();\n      continue label;\n    }\n")
 
@@ -3072,6 +3210,16 @@ func TestMangleLoopJump(t *testing.T) {
 	expectPrintedMangle(t, "while (x) { debugger; if (y) continue; z(); }", "for (; x; ) {\n  debugger;\n  y || z();\n}\n")
 	expectPrintedMangle(t, "while (x) { debugger; if (y) continue; else z(); w(); }", "for (; x; ) {\n  debugger;\n  y || (z(), w());\n}\n")
 
+	// Optimize implicit continue at the end of a block
+	expectPrintedMangle(t, "while (x) { if (y) { z(); continue } w(); }", "for (; x; )\n  y ? z() : w();\n")
+	expectPrintedMangle(t, "while (x) { if (y) { z(); continue } else v(); w(); }", "for (; x; )\n  y ? z() : (v(), w());\n")
+	expectPrintedMangle(t, "while (x) { if (y) { let z = v(); z(z); continue } w(); }",
+		"for (; x; )\n  if (y) {\n    let z = v();\n    z(z);\n  } else\n    w();\n")
+	expectPrintedMangle(t, "while (x) { if (y) { z(); continue } let w = v(); w(w); }",
+		"for (; x; ) {\n  if (y) {\n    z();\n    continue;\n  }\n  let w = v();\n  w(w);\n}\n")
+	expectPrintedMangle(t, "a: while (x) { if (y) { z(); continue a } w(); }",
+		"a:\n  for (; x; ) {\n    if (y) {\n      z();\n      continue a;\n    }\n    w();\n  }\n")
+
 	// Do not optimize implicit continue for statements that care about scope
 	expectPrintedMangle(t, "while (x) { if (y) continue; function y() {} }", "for (; x; ) {\n  let y = function() {\n  };\n  var y = y;\n}\n")
 	expectPrintedMangle(t, "while (x) { if (y) continue; let y }", "for (; x; ) {\n  if (y)\n    continue;\n  let y;\n}\n")
@@ -3139,10 +3287,28 @@ func TestMangleBlock(t *testing.T) {
 func TestMangleSwitch(t *testing.T) {
 	expectPrintedMangle(t, "x(); switch (y) { case z: return w; }", "switch (x(), y) {\n  case z:\n    return w;\n}\n")
 	expectPrintedMangle(t, "if (t) { x(); switch (y) { case z: return w; } }", "if (t)\n  switch (x(), y) {\n    case z:\n      return w;\n  }\n")
+
+	// Constant tests
+	expectPrintedMangle(t, "switch (1) { case 0: a(); break; case 1: b(); break; case 2: c() }", "switch (1) {\n  case 1:\n    b();\n    break;\n}\n")
+	expectPrintedMangle(t, "switch ('b') { case 'a': a(); case 'b': b(); case 'c': c(); break; default: d() }",
+		"switch (\"b\") {\n  case \"b\":\n    b();\n  case \"c\":\n    c();\n    break;\n}\n")
+	expectPrintedMangle(t, "switch (2) { case 1: a(); break; default: b(); case 3: c(); break; case 4: d() }",
+		"switch (2) {\n  default:\n    b();\n  case 3:\n    c();\n    break;\n}\n")
+	expectPrintedMangle(t, "switch (1) { default: a(); break; case 1: b() }", "switch (1) {\n  case 1:\n    b();\n}\n")
+	expectPrintedMangle(t, "switch (1) { case 1: return a; case b: c() }", "switch (1) {\n  case 1:\n    return a;\n}\n")
+	expectPrintedMangle(t, "switch (1) { case 2: a() }", "")
+	expectPrintedMangle(t, "switch (1) { case 2: var a = b() }", "switch (1) {\n  case 2:\n    var a;\n}\n")
+	expectPrintedMangle(t, "switch (null) { case false: a(); break; case null: b() }", "switch (null) {\n  case null:\n    b();\n}\n")
+	expectPrintedMangle(t, "switch (1) { case '1': a(); break; case 1: b() }", "switch (1) {\n  case 1:\n    b();\n}\n")
+
+	// These should not be folded
+	expectPrintedMangle(t, "switch (1) { case a: b(); break; case 1: c() }", "switch (1) {\n  case a:\n    b();\n    break;\n  case 1:\n    c();\n}\n")
+	expectPrintedMangle(t, "switch (null) { case void 0: a(); break; case null: b() }", "switch (null) {\n  case void 0:\n    a();\n    break;\n  case null:\n    b();\n}\n")
+	expectPrintedMangle(t, "switch (a) { case 1: b(); break; case 2: c() }", "switch (a) {\n  case 1:\n    b();\n    break;\n  case 2:\n    c();\n}\n")
 }
 
 func TestMangleAddEmptyString(t *testing.T) {
-	expectPrintedNormalAndMangle(t, "a = '' + 0", "a = \"\" + 0;\n", "a = \"\" + 0;\n")
+	expectPrintedNormalAndMangle(t, "a = '' + 0", "a = \"\" + 0;\n", "a = \"0\";\n")
 	expectPrintedNormalAndMangle(t, "a = 0 + ''", "a = 0 + \"\";\n", "a = 0 + \"\";\n")
 	expectPrintedNormalAndMangle(t, "a = '' + b", "a = \"\" + b;\n", "a = \"\" + b;\n")
 	expectPrintedNormalAndMangle(t, "a = b + ''", "a = b + \"\";\n", "a = b + \"\";\n")
@@ -3168,6 +3334,125 @@ func TestMangleStringLength(t *testing.T) {
 	expectPrintedMangle(t, "a = '👯‍♂️'.length", "a = 5;\n")
 }
 
+func TestMangleKnownGlobalCalls(t *testing.T) {
+	expectPrintedNormalAndMangle(t, "a = Number('3')", "a = Number(\"3\");\n", "a = 3;\n")
+	expectPrintedMangle(t, "a = Number()", "a = 0;\n")
+	expectPrintedMangle(t, "a = Number('')", "a = 0;\n")
+	expectPrintedMangle(t, "a = Number(' 0x10 ')", "a = 16;\n")
+	expectPrintedMangle(t, "a = Number('-0x10')", "a = NaN;\n")
+	expectPrintedMangle(t, "a = Number('1e3')", "a = 1e3;\n")
+	expectPrintedMangle(t, "a = Number('1px')", "a = NaN;\n")
+	expectPrintedMangle(t, "a = Number(true)", "a = 1;\n")
+	expectPrintedMangle(t, "a = Number(null)", "a = 0;\n")
+	expectPrintedMangle(t, "a = Number(b)", "a = Number(b);\n")
+	expectPrintedMangle(t, "a = Number('1', b)", "a = Number(\"1\", b);\n")
+	expectPrintedMangle(t, "a = Number(...b)", "a = Number(...b);\n")
+	expectPrintedMangle(t, "a = Number?.('1')", "a = Number?.(\"1\");\n")
+	expectPrintedMangle(t, "let Number; a = Number('3')", "let Number;\na = Number(\"3\");\n")
+
+	expectPrintedMangle(t, "a = parseInt('10')", "a = 10;\n")
+	expectPrintedMangle(t, "a = parseInt(' 10px')", "a = 10;\n")
+	expectPrintedMangle(t, "a = parseInt('-0x1F')", "a = -31;\n")
+	expectPrintedMangle(t, "a = parseInt('ff', 16)", "a = 255;\n")
+	expectPrintedMangle(t, "a = parseInt('111', 2)", "a = 7;\n")
+	expectPrintedMangle(t, "a = parseInt('9', 37)", "a = NaN;\n")
+	expectPrintedMangle(t, "a = parseInt('px')", "a = NaN;\n")
+	expectPrintedMangle(t, "a = parseInt('99999999999999999999')", "a = parseInt(\"99999999999999999999\");\n")
+	expectPrintedMangle(t, "a = parseInt('1', b)", "a = parseInt(\"1\", b);\n")
+
+	expectPrintedMangle(t, "a = parseFloat('3.14abc')", "a = 3.14;\n")
+	expectPrintedMangle(t, "a = parseFloat('.5')", "a = 0.5;\n")
+	expectPrintedMangle(t, "a = parseFloat('1e')", "a = 1;\n")
+	expectPrintedMangle(t, "a = parseFloat('-Infinity')", "a = -Infinity;\n")
+	expectPrintedMangle(t, "a = parseFloat('e5')", "a = NaN;\n")
+
+	expectPrintedMangle(t, "a = String(1)", "a = \"1\";\n")
+	expectPrintedMangle(t, "a = String(0.5)", "a = \"0.5\";\n")
+	expectPrintedMangle(t, "a = String(-0)", "a = \"0\";\n")
+	expectPrintedMangle(t, "a = String(1e-7)", "a = String(1e-7);\n")
+	expectPrintedMangle(t, "a = String(1e21)", "a = String(1e21);\n")
+	expectPrintedMangle(t, "a = String(null)", "a = \"null\";\n")
+	expectPrintedMangle(t, "a = String('x')", "a = \"x\";\n")
+
+	expectPrintedMangle(t, "a = Boolean(0)", "a = false;\n")
+	expectPrintedMangle(t, "a = Boolean('x')", "a = true;\n")
+	expectPrintedMangle(t, "a = Boolean(b())", "a = Boolean(b());\n")
+
+	expectPrintedNormalAndMangle(t, "a = String.fromCharCode(65)", "a = String.fromCharCode(65);\n", "a = \"A\";\n")
+	expectPrintedMangle(t, "a = String.fromCharCode()", "a = \"\";\n")
+	expectPrintedMangle(t, "a = String.fromCharCode(104, 105)", "a = \"hi\";\n")
+	expectPrintedMangle(t, "a = String.fromCharCode(65601.5, -65470)", "a = \"AB\";\n")
+	expectPrintedMangle(t, "a = String.fromCharCode(b)", "a = String.fromCharCode(b);\n")
+	expectPrintedMangle(t, "a = String.fromCharCode?.(65)", "a = String.fromCharCode?.(65);\n")
+	expectPrintedMangle(t, "a = String?.fromCharCode(65)", "a = String?.fromCharCode(65);\n")
+	expectPrintedMangle(t, "let String; a = String.fromCharCode(65)", "let String;\na = String.fromCharCode(65);\n")
+
+	expectPrintedMangle(t, "a = Math.pow(2, 8)", "a = 256;\n")
+	expectPrintedMangle(t, "a = Math.pow(-3, 3)", "a = -27;\n")
+	expectPrintedMangle(t, "a = Math.pow(2, 0.5)", "a = Math.pow(2, 0.5);\n")
+	expectPrintedMangle(t, "a = Math.pow(2, -1)", "a = Math.pow(2, -1);\n")
+	expectPrintedMangle(t, "a = Math.pow(2, 60)", "a = Math.pow(2, 60);\n")
+	expectPrintedMangle(t, "a = Math.abs(-1)", "a = 1;\n")
+	expectPrintedMangle(t, "a = Math.floor(1.5)", "a = 1;\n")
+	expectPrintedMangle(t, "a = Math.ceil(1.5)", "a = 2;\n")
+	expectPrintedMangle(t, "a = Math.trunc(-1.5)", "a = -1;\n")
+	expectPrintedMangle(t, "a = Math.round(2.5)", "a = 3;\n")
+	expectPrintedMangle(t, "a = Math.round(-2.5)", "a = -2;\n")
+	expectPrintedMangle(t, "a = Math.round(-0.25)", "a = -0;\n")
+	expectPrintedMangle(t, "a = Math.round(0.49999999999999994)", "a = 0;\n")
+	expectPrintedMangle(t, "a = Math.sign(-5)", "a = -1;\n")
+	expectPrintedMangle(t, "a = Math.sqrt(16)", "a = 4;\n")
+	expectPrintedMangle(t, "a = Math.min(3, 1, 2)", "a = 1;\n")
+	expectPrintedMangle(t, "a = Math.max(3, 1, 2)", "a = 3;\n")
+	expectPrintedMangle(t, "a = Math.max(1, NaN)", "a = NaN;\n")
+	expectPrintedMangle(t, "a = Math.max(1, void 0)", "a = NaN;\n")
+	expectPrintedMangle(t, "a = Math.min(0, -0)", "a = -0;\n")
+	expectPrintedMangle(t, "a = Math.min()", "a = Infinity;\n")
+	expectPrintedMangle(t, "a = Math.sin(0)", "a = Math.sin(0);\n")
+	expectPrintedMangle(t, "a = Math.abs(b)", "a = Math.abs(b);\n")
+
+	expectPrintedMangle(t, "a = Number.isNaN(1)", "a = false;\n")
+	expectPrintedMangle(t, "a = Number.isNaN(NaN)", "a = true;\n")
+	expectPrintedMangle(t, "a = Number.isNaN('x')", "a = false;\n")
+	expectPrintedMangle(t, "a = Number.isNaN()", "a = false;\n")
+	expectPrintedMangle(t, "a = Number.isFinite(Infinity)", "a = false;\n")
+	expectPrintedMangle(t, "a = Number.isInteger(1.5)", "a = false;\n")
+	expectPrintedMangle(t, "a = Number.isInteger(2)", "a = true;\n")
+	expectPrintedMangle(t, "a = Number.isSafeInteger(9007199254740992)", "a = false;\n")
+	expectPrintedMangle(t, "a = Number.isNaN(b)", "a = Number.isNaN(b);\n")
+	expectPrintedMangle(t, "a = Number.parseInt('ff', 16)", "a = 255;\n")
+	expectPrintedMangle(t, "a = Number.parseFloat('1.5px')", "a = 1.5;\n")
+}
+
+func TestMangleStringMethodCalls(t *testing.T) {
+	expectPrintedNormalAndMangle(t, "a = 'abc'.charCodeAt(1)", "a = \"abc\".charCodeAt(1);\n", "a = 98;\n")
+	expectPrintedMangle(t, "a = 'abc'.charCodeAt()", "a = 97;\n")
+	expectPrintedMangle(t, "a = 'abc'.charCodeAt(5)", "a = NaN;\n")
+	expectPrintedMangle(t, "a = 'abc'.charCodeAt(b)", "a = \"abc\".charCodeAt(b);\n")
+	expectPrintedMangle(t, "a = 'abc'.charAt(2)", "a = \"c\";\n")
+	expectPrintedMangle(t, "a = 'abc'.charAt(-1)", "a = \"\";\n")
+
+	expectPrintedMangle(t, "a = 'abc'.indexOf('c')", "a = 2;\n")
+	expectPrintedMangle(t, "a = 'abc'.indexOf('d')", "a = -1;\n")
+	expectPrintedMangle(t, "a = 'abc'.indexOf('a', 1)", "a = \"abc\".indexOf(\"a\", 1);\n")
+	expectPrintedMangle(t, "a = 'abc'.includes('b')", "a = true;\n")
+	expectPrintedMangle(t, "a = 'abc'.startsWith('ab')", "a = true;\n")
+	expectPrintedMangle(t, "a = 'abc'.endsWith('ab')", "a = false;\n")
+
+	expectPrintedMangle(t, "a = 'ABC'.toLowerCase()", "a = \"abc\";\n")
+	expectPrintedMangle(t, "a = 'abc'.toUpperCase()", "a = \"ABC\";\n")
+	expectPrintedMangle(t, "a = 'ǆ'.toUpperCase()", "a = \"ǆ\".toUpperCase();\n")
+
+	expectPrintedMangle(t, "a = 'abcdef'.slice(1, -1)", "a = \"bcde\";\n")
+	expectPrintedMangle(t, "a = 'abcdef'.slice(-2)", "a = \"ef\";\n")
+	expectPrintedMangle(t, "a = 'abcdef'.slice(2, 1)", "a = \"\";\n")
+	expectPrintedMangle(t, "a = 'abc'.slice(b)", "a = \"abc\".slice(b);\n")
+
+	expectPrintedMangle(t, "a = 'abc'?.charCodeAt(1)", "a = 98;\n")
+	expectPrintedMangle(t, "a = 'abc'.charCodeAt?.(1)", "a = \"abc\".charCodeAt?.(1);\n")
+	expectPrintedMangle(t, "a = 'abc'.foo(1)", "a = \"abc\".foo(1);\n")
+}
+
 func TestMangleNot(t *testing.T) {
 	// These can be mangled
 	expectPrintedNormalAndMangle(t, "a = !(b == c)", "a = !(b == c);\n", "a = b != c;\n")
@@ -3683,6 +3968,16 @@ func TestMangleReturn(t *testing.T) {
 	expectPrintedMangle(t, "let foo = function() { x(); return y; }", "let foo = function() {\n  return x(), y;\n};\n")
 	expectPrintedMangle(t, "let foo = () => { x(); return y; }", "let foo = () => (x(), y);\n")
 
+	// Merge the code after an if statement ending in an implicit return into an else branch
+	expectPrintedMangle(t, "function foo() { if (a) { b(); return } c(); d() }", "function foo() {\n  a ? b() : (c(), d());\n}\n")
+	expectPrintedMangle(t, "function foo() { if (a) { b(); return } else c(); d() }", "function foo() {\n  a ? b() : (c(), d());\n}\n")
+	expectPrintedMangle(t, "function foo() { if (a) { b(); return } }", "function foo() {\n  a && b();\n}\n")
+	expectPrintedMangle(t, "function foo() { if (a) { b(); return } return c }", "function foo() {\n  if (a)\n    b();\n  else\n    return c;\n}\n")
+	expectPrintedMangle(t, "function foo() { if (a) { let b = c(); b(b); return } d() }",
+		"function foo() {\n  if (a) {\n    let b = c();\n    b(b);\n  } else\n    d();\n}\n")
+	expectPrintedMangle(t, "function foo() { if (a) { b(); return } let c = d(); c(c) }",
+		"function foo() {\n  if (a) {\n    b();\n    return;\n  }\n  let c = d();\n  c(c);\n}\n")
+
 	// Don't trim a trailing top-level return because we may be compiling a partial module
 	expectPrintedMangle(t, "x(); return;", "x();\nreturn;\n")
 
@@ -3763,6 +4058,33 @@ func TestMangleInitializer(t *testing.T) {
 	expectPrintedNormalAndMangle(t, "var [] = undefined", "var [] = void 0;\n", "var [] = void 0;\n")
 }
 
+func TestMangleMergeLocalsAroundExpression(t *testing.T) {
+	expectPrintedMangle(t, "var a = 1; b(); var c = 2", "var a = 1, c = (b(), 2);\n")
+	expectPrintedMangle(t, "let a = 1; b(); let c = 2", "let a = 1, c = (b(), 2);\n")
+	expectPrintedMangle(t, "var a = 1; b(); c(); var d = 2, e = 3", "var a = 1, d = (b(), c(), 2), e = 3;\n")
+	expectPrintedMangle(t, "export var a = 1; b(); export var c = 2", "export var a = 1, c = (b(), 2);\n")
+
+	// These should not be merged
+	expectPrintedMangle(t, "var a = 1; b(); let c = 2", "var a = 1;\nb();\nlet c = 2;\n")
+	expectPrintedMangle(t, "var a = 1; b(); export var c = 2", "var a = 1;\nb();\nexport var c = 2;\n")
+	expectPrintedMangle(t, "var a = 1; b(); var c", "var a = 1;\nb();\nvar c;\n")
+	expectPrintedMangle(t, "using a = b; c(); using d = e", "using a = b;\nc();\nusing d = e;\n")
+}
+
+func TestMangleMergeAssignIntoLocal(t *testing.T) {
+	expectPrintedNormalAndMangle(t, "var a; a = b(); c(a, a)", "var a;\na = b();\nc(a, a);\n", "var a = b();\nc(a, a);\n")
+	expectPrintedMangle(t, "var a, b; b = c(); var d = 1; e(a, a, b, b, d, d)", "var a, b = c(), d = 1;\ne(a, a, b, b, d, d);\n")
+	expectPrintedMangle(t, "let a; a = 1; b(a, a)", "let a = 1;\nb(a, a);\n")
+	expectPrintedMangle(t, "export var a; a = b()", "export var a = b();\n")
+
+	// These should not be merged
+	expectPrintedMangle(t, "let a; a = b(); c(a, a)", "let a;\na = b(), c(a, a);\n")
+	expectPrintedMangle(t, "var a, b; a = c(); d(a, a, b, b)", "var a, b;\na = c(), d(a, a, b, b);\n")
+	expectPrintedMangle(t, "var a = 1; a = b(); c(a, a)", "var a = 1;\na = b(), c(a, a);\n")
+	expectPrintedMangle(t, "var a; a += b(); c(a, a)", "var a;\na += b(), c(a, a);\n")
+	expectPrintedMangle(t, "var {a} = b; a = c(); d(a, a)", "var { a } = b;\na = c(), d(a, a);\n")
+}
+
 func TestMangleCall(t *testing.T) {
 	expectPrintedNormalAndMangle(t, "x = foo(1, ...[], 2)", "x = foo(1, ...[], 2);\n", "x = foo(1, 2);\n")
 	expectPrintedNormalAndMangle(t, "x = foo(1, ...2, 3)", "x = foo(1, ...2, 3);\n", "x = foo(1, ...2, 3);\n")
@@ -3982,6 +4304,36 @@ _foo = new WeakSet(), foo_fn = function() {
 		"function f(a) {\n  return (0, a.b)`${x}`;\n}\n")
 }
 
+func TestMangleTemplateAndStringAddition(t *testing.T) {
+	// Merge adjacent literal parts
+	expectPrintedNormalAndMangle(t, "_ = `a${1}b${null}c${true}`", "_ = `a${1}b${null}c${true}`;\n", "_ = `a1bnullctrue`;\n")
+	expectPrintedMangle(t, "_ = `a${`b${x}c`}d`", "_ = `ab${x}cd`;\n")
+	expectPrintedMangle(t, "_ = `a${tag`b${x}c`}d`", "_ = `a${tag`b${x}c`}d`;\n")
+	expectPrintedMangle(t, "_ = 'a' + 1 + 'b'", "_ = \"a1b\";\n")
+	expectPrintedMangle(t, "_ = 'a' + 1e21", "_ = \"a\" + 1e21;\n")
+
+	// Convert string addition to a template literal if it's shorter
+	expectPrintedNormalAndMangle(t, "_ = 'a' + typeof x + 'b'", "_ = \"a\" + typeof x + \"b\";\n", "_ = `a${typeof x}b`;\n")
+	expectPrintedMangle(t, "_ = 'Hello ' + (x | 0) + '!'", "_ = `Hello ${x | 0}!`;\n")
+	expectPrintedMangle(t, "_ = 'a' + typeof x", "_ = \"a\" + typeof x;\n")
+	expectPrintedMangle(t, "_ = 'a' + x + 'b'", "_ = \"a\" + x + \"b\";\n")
+	expectPrintedMangle(t, "_ = 'a' + (x | 0) + (y | 0)", "_ = \"a\" + (x | 0) + (y | 0);\n")
+	expectPrintedMangle(t, "_ = 'a' + (x | 0) + 'b' + (y | 0) + 'c'", "_ = `a${x | 0}b${y | 0}c`;\n")
+
+	// Convert a template literal to string addition if it's shorter
+	expectPrintedNormalAndMangle(t, "_ = `${typeof x}`", "_ = `${typeof x}`;\n", "_ = typeof x;\n")
+	expectPrintedMangle(t, "_ = `abc${typeof x}`", "_ = \"abc\" + typeof x;\n")
+	expectPrintedMangle(t, "_ = `${x | 0}${typeof y}`", "_ = \"\" + (x | 0) + typeof y;\n")
+	expectPrintedMangle(t, "_ = `${x | 0}`", "_ = `${x | 0}`;\n")
+	expectPrintedMangle(t, "_ = `${-x}abc`", "_ = `${-x}abc`;\n")
+	expectPrintedMangle(t, "_ = `abc${x}`", "_ = `abc${x}`;\n")
+	expectPrintedMangle(t, "_ = `${x}`", "_ = `${x}`;\n")
+	expectPrintedMangle(t, "_ = `a${x | 0}b${y | 0}c`", "_ = `a${x | 0}b${y | 0}c`;\n")
+
+	// Template literals aren't introduced if they aren't supported
+	expectPrintedMangleTarget(t, 5, "_ = 'a' + typeof x + 'b'", "_ = \"a\" + typeof x + \"b\";\n")
+}
+
 func TestMangleTypeofIdentifier(t *testing.T) {
 	expectPrintedNormalAndMangle(t, "return typeof (123, x)", "return typeof (123, x);\n", "return typeof (0, x);\n")
 	expectPrintedNormalAndMangle(t, "return typeof (123, x.y)", "return typeof (123, x.y);\n", "return typeof x.y;\n")
@@ -4215,6 +4567,8 @@ func TestMangleUnused(t *testing.T) {
 	expectPrintedNormalAndMangle(t, "0; 'abc'", "0;\n\"abc\";\n", "") // Actually a string expression
 	expectPrintedNormalAndMangle(t, "'abc'; 'use strict'", "\"use strict\";\n\"abc\";\n", "\"use strict\";\n")
 	expectPrintedNormalAndMangle(t, "function f() { 'abc'; 'use strict' }", "function f() {\n  \"abc\";\n  \"use strict\";\n}\n", "function f() {\n  \"use strict\";\n}\n")
+	expectPrintedNormalAndMangle(t, "'use client'; 'abc'", "\"use client\";\n\"abc\";\n", "\"use client\";\n")
+	expectPrintedNormalAndMangle(t, "function f() { 'use server'; 'abc' }", "function f() {\n  \"use server\";\n  \"abc\";\n}\n", "function f() {\n  \"use server\";\n}\n")
 	expectPrintedNormalAndMangle(t, "this", "this;\n", "")
 	expectPrintedNormalAndMangle(t, "/regex/", "/regex/;\n", "")
 	expectPrintedNormalAndMangle(t, "(function() {})", "(function() {\n});\n", "")
@@ -4632,6 +4986,51 @@ func TestMangleInlineLocals(t *testing.T) {
 	check("let x = /* @__PURE__ */ arg0(); /* @__PURE__ */ arg1() + x", "/* @__PURE__ */ arg1() + /* @__PURE__ */ arg0();")
 }
 
+func TestMangleInlineSingleUseFunctions(t *testing.T) {
+	check := func(a string, b string) {
+		t.Helper()
+		expectPrintedMangle(t, "function wrapper(arg0, arg1) {"+a+"}",
+			"function wrapper(arg0, arg1) {"+strings.ReplaceAll("\n"+b, "\n", "\n  ")+"\n}\n")
+	}
+
+	check("function f(a, b) { return a + b } return f(1, 2)", "return 1 + 2;")
+	check("function f(a) { return -a } return f(arg0)", "return -arg0;")
+	check("function f(a, b) { return a ? b : 0 } return f(arg0, [])", "return arg0 ? [] : 0;")
+	check("function f(a, b) { return g(a, b) } return f(arg0, 'x', 1)", "return g(arg0, \"x\");")
+	check("function f(a, b) { return [a, b] } return f(1)", "return [1, void 0];")
+	check("function f(a) { return `${a}` } return f(arg0)", "return `${arg0}`;")
+
+	// Functions that are used more than once are not inlined
+	check("function f(a) { return -a } return f(1) + f(2)", "function f(a) {\n  return -a;\n}\nreturn f(1) + f(2);")
+	check("function f(a) { return a + a } return f(1)", "function f(a) {\n  return a + a;\n}\nreturn f(1);")
+	check("function f(a) { return -a } return () => f(1)", "function f(a) {\n  return -a;\n}\nreturn () => f(1);")
+
+	// Arguments with side effects are not inlined
+	check("function f(a) { return -a } return f(g())", "function f(a) {\n  return -a;\n}\nreturn f(g());")
+	check("function f(a) { return -a } return f(1, g())", "function f(a) {\n  return -a;\n}\nreturn f(1, g());")
+	check("function f(a) { return -a } return f(...arg0)", "function f(a) {\n  return -a;\n}\nreturn f(...arg0);")
+
+	// Arguments that side effects in the function body could change are not inlined
+	check("function f(a) { return g() + a } return f(arg0.x)", "function f(a) {\n  return g() + a;\n}\nreturn f(arg0.x);")
+	check("function f(a) { return g() + a } return arg0 = 1, f(arg0)", "function f(a) {\n  return g() + a;\n}\nreturn arg0 = 1, f(arg0);")
+	check("function f(a) { return g() + a } return f(1)", "return g() + 1;")
+
+	// Calling an argument must not change "this"
+	check("function f(a) { return a() } return f(arg0.x)", "function f(a) {\n  return a();\n}\nreturn f(arg0.x);")
+	check("function f(a) { return a() } return f(arg0)", "return arg0();")
+
+	// The function body must not depend on being inside of a function
+	check("function f() { return this.x } return f()", "function f() {\n  return this.x;\n}\nreturn f();")
+	check("function f() { return arguments.length } return f()", "function f() {\n  return arguments.length;\n}\nreturn f();")
+	check("function f() { return () => 1 } return f()", "function f() {\n  return () => 1;\n}\nreturn f();")
+	check("function f(a) { return a = 1 } return f(1)", "function f(a) {\n  return a = 1;\n}\nreturn f(1);")
+	check("async function f(a) { return -a } return f(1)", "async function f(a) {\n  return -a;\n}\nreturn f(1);")
+	check("function f(a = 1) { return -a } return f(1)", "function f(a = 1) {\n  return -a;\n}\nreturn f(1);")
+
+	// Top-level functions are not inlined
+	expectPrintedMangle(t, "function f(a) { return -a } x = f(1)", "function f(a) {\n  return -a;\n}\nx = f(1);\n")
+}
+
 func TestTrimCodeInDeadControlFlow(t *testing.T) {
 	expectPrintedMangle(t, "if (1) a(); else { ; }", "a();\n")
 	expectPrintedMangle(t, "if (1) a(); else { b() }", "a();\n")
@@ -4701,6 +5100,51 @@ func TestPreservedComments(t *testing.T) {
 	expectPrinted(t, "x\u2029    /*!\u2029     * Re-indent test\u2029     */", "x;\n/*!\n * Re-indent test\n */\n")
 }
 
+func TestPreservedCommentsModes(t *testing.T) {
+	legal := config.CommentsLegalOnly
+	all := config.CommentsAll
+
+	expectPrintedComments(t, legal, "", "//! a\n// b\nfoo()", "//! a\nfoo();\n")
+	expectPrintedComments(t, legal, "", "foo(/* a */ 1)", "foo(1);\n")
+	expectPrintedComments(t, legal, "", "x = [/* a */ 1]", "x = [1];\n")
+
+	expectPrintedComments(t, all, "", "// a\nfoo()", "// a\nfoo();\n")
+	expectPrintedComments(t, all, "", "/** a */\nfunction f() {}", "/** a */\nfunction f() {\n}\n")
+	expectPrintedComments(t, all, "", "foo() // a", "foo();\n// a\n")
+	expectPrintedComments(t, all, "", "//! a\n// b\n/*! c */\nfoo()", "//! a\n// b\n/*! c */\nfoo();\n")
+	expectPrintedComments(t, all, "", "if (1) {\n// a\nfoo()\n}", "if (1) {\n  // a\n  foo();\n}\n")
+	expectPrintedComments(t, all, "", "function f() {\n// a\n}", "function f() {\n  // a\n}\n")
+	expectPrintedComments(t, all, "", "foo(/* a */ 1)", "foo(\n  /* a */\n  1\n);\n")
+	expectPrintedComments(t, all, "", "// a\n'use strict'", "\"use strict\";\n// a\n")
+	expectPrintedComments(t, all, "", "/* @__PURE__ */ foo()", "/* @__PURE__ */ foo();\n")
+	expectPrintedComments(t, all, "", "function f(/* inline */ a) {}", "function f(/* inline */ a) {\n}\n")
+	expectPrintedComments(t, all, "", "function f(a, // b\nc, .../* d */ e) {}", "function f(a, /* b */ c, .../* d */ e) {\n}\n")
+	expectPrintedComments(t, all, "", "(/* a */ b) => b", "(/* a */ b) => b;\n")
+	expectPrintedComments(t, all, "", "let /* a */ x = 1", "let x = 1;\n")
+	expectPrintedComments(t, config.CommentsDefault, "", "function f(/* a */ b) {}", "function f(b) {\n}\n")
+	expectPrintedComments(t, config.CommentsDefault, "", "(/* a */ b) => b", "(b) => b;\n")
+
+	expectPrintedComments(t, all, "^// @ts-", "// @ts-ignore\n// other\nfoo()", "// @ts-ignore\nfoo();\n")
+	expectPrintedComments(t, all, "^// @ts-", "//! legal\n// other\nfoo()", "//! legal\nfoo();\n")
+	expectPrintedComments(t, all, "webpack", "import(/* webpackChunkName: 'a' */ 'a', /* b */ {})",
+		"import(\n  /* webpackChunkName: 'a' */\n  \"a\",\n  {}\n);\n")
+	expectPrintedComments(t, config.CommentsDefault, "webpack", "foo(/* webpack */ 1, /* b */ 2)", "foo(\n  /* webpack */\n  1,\n  2\n);\n")
+
+	// Comments that match the filter or that are kept by "all" are kept even
+	// when minifying whitespace
+	expectPrintedCommentsMinify(t, config.CommentsDefault, "", "foo(/* a */ 1)", "foo(1);")
+	expectPrintedCommentsMinify(t, config.CommentsDefault, "@vite-ignore", "import(/* @vite-ignore */ a, /* b */ {})", "import(/* @vite-ignore */a,{});")
+	expectPrintedCommentsMinify(t, config.CommentsDefault, "keep", "foo(// keep\n1)", "foo(/* keep */1);")
+	expectPrintedCommentsMinify(t, config.CommentsDefault, "keep", "x = a / /* keep */ b", "x=a/ /* keep */b;")
+	expectPrintedCommentsMinify(t, config.CommentsDefault, "keep", "// keep\nfoo()", "foo();")
+	expectPrintedCommentsMinify(t, all, "", "// a\nfoo()", "// a\nfoo();")
+	expectPrintedCommentsMinify(t, all, "", "import(/* @vite-ignore */ a, /* b */ {})", "import(/* @vite-ignore */a,/* b */{});")
+	expectPrintedCommentsMinify(t, all, "vite", "import(/* @vite-ignore */ a, /* b */ {})", "import(/* @vite-ignore */a,{});")
+	expectPrintedCommentsMinify(t, all, "", "function f(/* a */ b) {}", "function f(/* a */b){}")
+	expectPrintedCommentsMinify(t, all, "^// @ts-", "// @ts-ignore\n// other\nfoo()", "// @ts-ignore\nfoo();")
+	expectPrintedCommentsMinify(t, legal, "", "//! a\n// b\nfoo(/* c */ 1)", "//! a\nfoo(1);")
+}
+
 func TestUnicodeWhitespace(t *testing.T) {
 	whitespace := []string{
 		"\u0009", // character tabulation
@@ -5422,87 +5866,76 @@ func TestImportAssertions(t *testing.T) {
 	expectParseError(t, "export { foo } from 'x' assert {type: 'json'}", "")
 }
 
+func TestImportAttributes(t *testing.T) {
+	expectPrinted(t, "import 'x' with {}", "import \"x\" with {};\n")
+	expectPrinted(t, "import 'x' with\n{}", "import \"x\" with {};\n")
+	expectPrinted(t, "import 'x'\nwith\n{}", "import \"x\" with {};\n")
+	expectPrinted(t, "import 'x' with {type: 'json'}", "import \"x\" with { type: \"json\" };\n")
+	expectPrinted(t, "import 'x' with {'type': 'json',}", "import \"x\" with { \"type\": \"json\" };\n")
+	expectPrinted(t, "import x from 'x' with {x: 'y'}", "import x from \"x\" with { x: \"y\" };\n")
+	expectPrinted(t, "import * as x from 'x' with {x: 'y'}", "import * as x from \"x\" with { x: \"y\" };\n")
+	expectPrinted(t, "import {} from 'x' with {x: 'y'}", "import {} from \"x\" with { x: \"y\" };\n")
+	expectPrinted(t, "export {} from 'x' with {x: 'y'}", "export {} from \"x\" with { x: \"y\" };\n")
+	expectPrinted(t, "export * from 'x' with {x: 'y'}", "export * from \"x\" with { x: \"y\" };\n")
+	expectPrintedMangle(t, "import 'x' with {'type': 'json'}", "import \"x\" with { type: \"json\" };\n")
+
+	expectParseError(t, "import 'x' with {x: y}", "<stdin>: ERROR: Expected string but found \"y\"\n")
+	expectParseError(t, "import 'x' with {x: 'y', x: 'y'}",
+		"<stdin>: ERROR: Duplicate import attribute \"x\"\n<stdin>: NOTE: The first \"x\" was here:\n")
+
+	expectPrinted(t, "import(x ? 'y' : 'z', {with: {a: 'b'}})",
+		"x ? import(\"y\", { with: { a: \"b\" } }) : import(\"z\", { with: { a: \"b\" } });\n")
+	expectPrinted(t, "import(x ? 'y' : 'z', {with: []})", "import(x ? \"y\" : \"z\", { with: [] });\n")
+
+	expectPrintedTarget(t, 2015, "import 'x' with {x: 'y'}", "import \"x\";\n")
+	expectPrintedTarget(t, 2015, "import(x, {with: {x: 'y'}})", "import(x);\n")
+	expectParseErrorTarget(t, 2015, "import(x ? 'y' : 'z', {with: {x: foo()}})",
+		"<stdin>: ERROR: Using an arbitrary value as the second argument to \"import()\" is not possible in the configured target environment\n")
+}
+
 func TestES5(t *testing.T) {
 	// Do not generate "let" when emulating block-level function declarations and targeting ES5
 	expectPrintedTarget(t, 2015, "if (1) function f() {}", "if (1) {\n  let f = function() {\n  };\n  var f = f;\n}\n")
 	expectPrintedTarget(t, 5, "if (1) function f() {}", "if (1) {\n  var f = function() {\n  };\n  var f = f;\n}\n")
 
-	expectParseErrorTarget(t, 5, "function foo(x = 0) {}",
-		"<stdin>: ERROR: Transforming default arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function(x = 0) {})",
-		"<stdin>: ERROR: Transforming default arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(x = 0) => {}",
-		"<stdin>: ERROR: Transforming default arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo(...x) {}",
-		"<stdin>: ERROR: Transforming rest arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function(...x) {})",
-		"<stdin>: ERROR: Transforming rest arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(...x) => {}",
-		"<stdin>: ERROR: Transforming rest arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "foo(...x)",
-		"<stdin>: ERROR: Transforming rest arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "[...x]",
-		"<stdin>: ERROR: Transforming array spread to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "for (var x of y) ;",
-		"<stdin>: ERROR: Transforming for-of loops to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "function foo(x = 0) {}", "function foo(x) {\n  if (x === void 0)\n    x = 0;\n}\n")
+	expectPrintedTarget(t, 5, "(function(x = 0) {})", "(function(x) {\n  if (x === void 0)\n    x = 0;\n});\n")
+	expectPrintedTarget(t, 5, "(x = 0) => {}", "(function(x) {\n  if (x === void 0)\n    x = 0;\n});\n")
+	expectPrintedTarget(t, 5, "function foo(...x) {}", "function foo() {\n  var x = [].slice.call(arguments, 0);\n}\n")
+	expectPrintedTarget(t, 5, "(function(...x) {})", "(function() {\n  var x = [].slice.call(arguments, 0);\n});\n")
+	expectPrintedTarget(t, 5, "(...x) => {}", "(function() {\n  var x = [].slice.call(arguments, 0);\n});\n")
+	expectPrintedTarget(t, 5, "foo(...x)", "foo.apply(void 0, __toArray(x));\n")
+	expectPrintedTarget(t, 5, "[...x]", "[].concat(__toArray(x));\n")
+	expectPrintedTarget(t, 5, "for (var x of y) ;", "try {\n  for (var iter = __iterator(y), more, temp, error; more = !(temp = iter.next()).done; more = false) {\n    var x = temp.value;\n    ;\n  }\n} catch (temp) {\n  error = [temp];\n} finally {\n  try {\n    more && (temp = iter.return) && temp.call(iter);\n  } finally {\n    if (error)\n      throw error[0];\n  }\n}\n")
+	expectPrintedTarget(t, 5, "a: for (var x of y) continue a", "try {\n  a:\n    for (var iter = __iterator(y), more, temp, error; more = !(temp = iter.next()).done; more = false) {\n      var x = temp.value;\n      continue a;\n    }\n} catch (temp) {\n  error = [temp];\n} finally {\n  try {\n    more && (temp = iter.return) && temp.call(iter);\n  } finally {\n    if (error)\n      throw error[0];\n  }\n}\n")
+	expectPrintedTarget(t, 5, "a: for (var x of y) break a", "try {\n  a:\n    for (var iter = __iterator(y), more, temp, error; more = !(temp = iter.next()).done; more = false) {\n      var x = temp.value;\n      break a;\n    }\n} catch (temp) {\n  error = [temp];\n} finally {\n  try {\n    more && (temp = iter.return) && temp.call(iter);\n  } finally {\n    if (error)\n      throw error[0];\n  }\n}\n")
+	expectPrintedTarget(t, 5, "a: for (var x of y) for (var z of x) continue a", "try {\n  a:\n    for (var iter = __iterator(y), more, temp, error; more = !(temp = iter.next()).done; more = false) {\n      var x = temp.value;\n      try {\n        for (var iter = __iterator(x), more, temp, error; more = !(temp = iter.next()).done; more = false) {\n          var z = temp.value;\n          continue a;\n        }\n      } catch (temp) {\n        error = [temp];\n      } finally {\n        try {\n          more && (temp = iter.return) && temp.call(iter);\n        } finally {\n          if (error)\n            throw error[0];\n        }\n      }\n    }\n} catch (temp) {\n  error = [temp];\n} finally {\n  try {\n    more && (temp = iter.return) && temp.call(iter);\n  } finally {\n    if (error)\n      throw error[0];\n  }\n}\n")
 	expectPrintedTarget(t, 5, "({ x })", "({ x: x });\n")
-	expectParseErrorTarget(t, 5, "({ [x]: y })",
-		"<stdin>: ERROR: Transforming object literal extensions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({ x() {} });",
-		"<stdin>: ERROR: Transforming object literal extensions to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "({ [x]: y })", "var _a;\n_a = {}, _a[x] = y, _a;\n")
+	expectPrintedTarget(t, 5, "({ x() {} });", "({ x: function() {\n} });\n")
 	expectParseErrorTarget(t, 5, "({ get x() {} });", "")
 	expectParseErrorTarget(t, 5, "({ set x(x) {} });", "")
-	expectParseErrorTarget(t, 5, "({ get [x]() {} });",
-		"<stdin>: ERROR: Transforming object literal extensions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({ set [x](x) {} });",
-		"<stdin>: ERROR: Transforming object literal extensions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo([]) {}",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo({}) {}",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function([]) {})",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function({}) {})",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "([]) => {}",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({}) => {}",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "var [] = [];",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "var {} = {};",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "([] = []);",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({} = {});",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "for ([] in []);",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "for ({} in []);",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo([...x]) {}",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function([...x]) {})",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "([...x]) => {}",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo([...[x]]) {}",
-		`<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: ERROR: Transforming non-identifier array rest patterns to the configured target environment is not supported yet
-`)
-	expectParseErrorTarget(t, 5, "(function([...[x]]) {})",
-		`<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: ERROR: Transforming non-identifier array rest patterns to the configured target environment is not supported yet
-`)
-	expectParseErrorTarget(t, 5, "([...[x]]) => {}",
-		`<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: ERROR: Transforming non-identifier array rest patterns to the configured target environment is not supported yet
-`)
-	expectParseErrorTarget(t, 5, "([...[x]])",
-		"<stdin>: ERROR: Transforming array spread to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "({ get [x]() {} });", "var _a;\n_a = {}, __defProp(_a, x, {\n  get: function() {\n  },\n  enumerable: true,\n  configurable: true\n}), _a;\n")
+	expectPrintedTarget(t, 5, "({ set [x](x) {} });", "var _a;\n_a = {}, __defProp(_a, x, {\n  set: function(x) {\n  },\n  enumerable: true,\n  configurable: true\n}), _a;\n")
+	expectPrintedTarget(t, 5, "function foo([]) {}", "function foo(_a) {\n  var _b = __toArray(_a, 0);\n}\n")
+	expectPrintedTarget(t, 5, "function foo({}) {}", "function foo(_a) {\n  var _b = __requireObject(_a);\n}\n")
+	expectPrintedTarget(t, 5, "(function([]) {})", "(function(_a) {\n  var _b = __toArray(_a, 0);\n});\n")
+	expectPrintedTarget(t, 5, "(function({}) {})", "(function(_a) {\n  var _b = __requireObject(_a);\n});\n")
+	expectPrintedTarget(t, 5, "([]) => {}", "(function(_a) {\n  var _b = __toArray(_a, 0);\n});\n")
+	expectPrintedTarget(t, 5, "({}) => {}", "(function(_a) {\n  var _b = __requireObject(_a);\n});\n")
+	expectPrintedTarget(t, 5, "var [] = [];", "var _a = __toArray([], 0);\n")
+	expectPrintedTarget(t, 5, "var {} = {};", "var _a = __requireObject({});\n")
+	expectPrintedTarget(t, 5, "([] = []);", "var _a;\n_a = __toArray([], 0);\n")
+	expectPrintedTarget(t, 5, "({} = {});", "var _a;\n_a = __requireObject({});\n")
+	expectPrintedTarget(t, 5, "for ([] in []);", "var _a, _b;\nfor (_a in []) {\n  _b = __toArray(_a, 0);\n  ;\n}\n")
+	expectPrintedTarget(t, 5, "for ({} in []);", "var _a, _b;\nfor (_a in []) {\n  _b = __requireObject(_a);\n  ;\n}\n")
+	expectPrintedTarget(t, 5, "function foo([...x]) {}", "function foo(_a) {\n  var _b = __toArray(_a), x = _b.slice(0);\n}\n")
+	expectPrintedTarget(t, 5, "(function([...x]) {})", "(function(_a) {\n  var _b = __toArray(_a), x = _b.slice(0);\n});\n")
+	expectPrintedTarget(t, 5, "([...x]) => {}", "(function(_a) {\n  var _b = __toArray(_a), x = _b.slice(0);\n});\n")
+	expectPrintedTarget(t, 5, "function foo([...[x]]) {}", "function foo(_a) {\n  var _b = __toArray(_a), _c = __toArray(_b.slice(0), 1), x = _c[0];\n}\n")
+	expectPrintedTarget(t, 5, "(function([...[x]]) {})", "(function(_a) {\n  var _b = __toArray(_a), _c = __toArray(_b.slice(0), 1), x = _c[0];\n});\n")
+	expectPrintedTarget(t, 5, "([...[x]]) => {}", "(function(_a) {\n  var _b = __toArray(_a), _c = __toArray(_b.slice(0), 1), x = _c[0];\n});\n")
+	expectPrintedTarget(t, 5, "([...[x]])", "[].concat(__toArray([x]));\n")
 	expectPrintedTarget(t, 5, "`abc`;", "\"abc\";\n")
 	expectPrintedTarget(t, 5, "`a${b}`;", "\"a\".concat(b);\n")
 	expectPrintedTarget(t, 5, "`${a}b`;", "\"\".concat(a, \"b\");\n")
@@ -5520,28 +5953,22 @@ func TestES5(t *testing.T) {
 	expectPrintedTarget(t, 5, "tag`a${b}c`;", "var _a;\ntag(_a || (_a = __template([\"a\", \"c\"])), b);\n")
 	expectPrintedTarget(t, 5, "tag`a${b}\\u`;", "var _a;\ntag(_a || (_a = __template([\"a\", void 0], [\"a\", \"\\\\u\"])), b);\n")
 	expectPrintedTarget(t, 5, "tag`\\u${b}c`;", "var _a;\ntag(_a || (_a = __template([void 0, \"c\"], [\"\\\\u\", \"c\"])), b);\n")
-	expectParseErrorTarget(t, 5, "class Foo { constructor() { new.target } }",
-		"<stdin>: ERROR: Transforming class syntax to the configured target environment is not supported yet\n"+
-			"<stdin>: ERROR: Transforming object literal extensions to the configured target environment is not supported yet\n"+
-			"<stdin>: ERROR: Transforming new.target to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "const x = 1;",
-		"<stdin>: ERROR: Transforming const to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "let x = 2;",
-		"<stdin>: ERROR: Transforming let to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "class Foo { constructor() { new.target } }", "var Foo = /* @__PURE__ */ function() {\n  function Foo() {\n    __classCheck(this, Foo);\n    this.constructor;\n  }\n  return Foo;\n}();\n")
+	expectPrintedTarget(t, 5, "const x = 1;", "var x = 1;\n")
+	expectPrintedTarget(t, 5, "let x = 2;", "var x = 2;\n")
 	expectPrintedTarget(t, 5, "async => foo;", "(function(async) {\n  return foo;\n});\n")
 	expectPrintedTarget(t, 5, "x => x;", "(function(x) {\n  return x;\n});\n")
-	expectParseErrorTarget(t, 5, "async () => foo;",
-		"<stdin>: ERROR: Transforming async functions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "class Foo {}",
-		"<stdin>: ERROR: Transforming class syntax to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(class {});",
-		"<stdin>: ERROR: Transforming class syntax to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function* gen() {}",
-		"<stdin>: ERROR: Transforming generator functions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function* () {});",
-		"<stdin>: ERROR: Transforming generator functions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({ *foo() {} });",
-		"<stdin>: ERROR: Transforming generator functions to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "async () => foo;", "(function() {\n  return __async(this, null, function() {\n    return __makeGenerator(this, function(_state) {\n      return [2, foo];\n    });\n  });\n});\n")
+	expectPrintedTarget(t, 5, "class Foo {}", "var Foo = /* @__PURE__ */ function() {\n  function Foo() {\n    __classCheck(this, Foo);\n  }\n  return Foo;\n}();\n")
+	expectPrintedTarget(t, 5, "(class {});", "/* @__PURE__ */ (function() {\n  function _class() {\n    __classCheck(this, _class);\n  }\n  return _class;\n})();\n")
+	expectPrintedTarget(t, 5, "function* gen() {}", "function gen() {\n  return __makeGenerator(this, function(_state) {\n    return [2];\n  });\n}\n")
+	expectPrintedTarget(t, 5, "(function* () {});", "(function() {\n  return __makeGenerator(this, function(_state) {\n    return [2];\n  });\n});\n")
+	expectPrintedTarget(t, 5, "({ *foo() {} });", "({ foo: function() {\n  return __makeGenerator(this, function(_state) {\n    return [2];\n  });\n} });\n")
+	expectPrintedTarget(t, 5, "function* gen() { var x = yield 1; return x }", "function gen() {\n  var x;\n  return __makeGenerator(this, function(_state) {\n    switch (_state.label) {\n      case 0:\n        return [4, 1];\n      case 1:\n        x = _state.sent();\n        return [2, x];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { try { yield 1 } finally { foo() } }", "function gen() {\n  return __makeGenerator(this, function(_state) {\n    switch (_state.label) {\n      case 0:\n        _state.trys.push([0, , 2, 3]);\n        return [4, 1];\n      case 1:\n        _state.sent();\n        return [3, 3];\n      case 2:\n        foo();\n        return [7];\n      case 3:\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "function* gen() { yield* foo }", "function gen() {\n  return __makeGenerator(this, function(_state) {\n    switch (_state.label) {\n      case 0:\n        return [5, foo];\n      case 1:\n        _state.sent();\n        return [2];\n    }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { constructor() { super(); } foo() { return super.foo() } }", "var Foo = function(_super) {\n  __inherit(Foo, _super);\n  function Foo() {\n    __classCheck(this, Foo);\n    var _this = _super.call(this) || this;\n    return _this;\n  }\n  Foo.prototype.foo = function() {\n    return __superGet(Foo.prototype, this, \"foo\").call(this);\n  };\n  return Foo;\n}(Bar);\n")
+	expectPrintedTarget(t, 5, "class Foo { static foo() {} get bar() { return 1 } }", "var Foo = /* @__PURE__ */ function() {\n  function Foo() {\n    __classCheck(this, Foo);\n  }\n  Foo.foo = function() {\n  };\n  __defProp(Foo.prototype, \"bar\", {\n    get: function() {\n      return 1;\n    },\n    configurable: true\n  });\n  return Foo;\n}();\n")
 }
 
 func TestASCIIOnly(t *testing.T) {
@@ -25,11 +25,31 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 		return nil
 	}
 
+	result := parseSourceMapObject(log, source, &tracker, obj, true)
+
+	// Silently fail if the source map is pointless (i.e. empty)
+	if result == nil || len(result.Sources) == 0 {
+		return nil
+	}
+	return result
+}
+
+// This returns nil on failure and an empty source map if the source map is
+// valid but has no sources or no mappings
+func parseSourceMapObject(
+	log logger.Log,
+	source logger.Source,
+	tracker *logger.LineColumnTracker,
+	obj *js_ast.EObject,
+	allowSections bool,
+) *sourcemap.SourceMap {
 	var sources []string
 	var sourcesContent []sourcemap.SourceContent
 	var names []string
 	var mappingsRaw []uint16
 	var mappingsStart int32
+	var sections js_ast.Expr
+	var sectionsRange logger.Range
 	hasVersion := false
 
 	for _, prop := range obj.Properties {
@@ -37,8 +57,13 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 
 		switch helpers.UTF16ToString(prop.Key.Data.(*js_ast.EString).Value) {
 		case "sections":
-			log.AddID(logger.MsgID_SourceMap_SectionsInSourceMap, logger.Warning, &tracker, keyRange, "Source maps with \"sections\" are not supported")
-			return nil
+			if !allowSections {
+				log.AddID(logger.MsgID_SourceMap_SectionsInSourceMap, logger.Warning, tracker, keyRange,
+					"Source maps with nested \"sections\" are not supported")
+				return nil
+			}
+			sections = prop.ValueOrNil
+			sectionsRange = keyRange
 
 		case "version":
 			if value, ok := prop.ValueOrNil.Data.(*js_ast.ENumber); ok && value.Value == 3 {
@@ -97,9 +122,14 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 		return nil
 	}
 
-	// Silently fail if the source map is pointless (i.e. empty)
+	// Indexed source maps are made of other source maps at different offsets
+	if sections.Data != nil {
+		return parseSourceMapSections(log, source, tracker, sections, sectionsRange)
+	}
+
+	// Don't bother parsing the mappings if the source map is pointless
 	if len(sources) == 0 || len(mappingsRaw) == 0 {
-		return nil
+		return &sourcemap.SourceMap{}
 	}
 
 	var mappings mappingArray
@@ -243,7 +273,7 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 
 	if errorText != "" {
 		r := logger.Range{Loc: logger.Loc{Start: mappingsStart + int32(current)}, Len: int32(errorLen)}
-		log.AddID(logger.MsgID_SourceMap_InvalidSourceMappings, logger.Warning, &tracker, r,
+		log.AddID(logger.MsgID_SourceMap_InvalidSourceMappings, logger.Warning, tracker, r,
 			fmt.Sprintf("Bad \"mappings\" data in source map at character %d: %s", current, errorText))
 		return nil
 	}
@@ -264,6 +294,135 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 	}
 }
 
+// Each section of an indexed source map has an offset into the generated file
+// and a regular source map. The sections are combined into a single source
+// map by shifting the generated locations of each section by its offset and
+// appending its "sources" and "names" to those of the previous sections.
+func parseSourceMapSections(
+	log logger.Log,
+	source logger.Source,
+	tracker *logger.LineColumnTracker,
+	value js_ast.Expr,
+	sectionsRange logger.Range,
+) *sourcemap.SourceMap {
+	array, ok := value.Data.(*js_ast.EArray)
+	if !ok {
+		log.AddID(logger.MsgID_SourceMap_SectionsInSourceMap, logger.Warning, tracker, sectionsRange,
+			"The \"sections\" field in this source map must be an array")
+		return nil
+	}
+
+	result := &sourcemap.SourceMap{}
+	needSort := false
+
+	for _, item := range array.Items {
+		var offsetLine int32
+		var offsetColumn int32
+		var sectionMap *sourcemap.SourceMap
+		hasOffset := false
+		sectionRange := logger.Range{Loc: item.Loc}
+
+		section, ok := item.Data.(*js_ast.EObject)
+		if !ok {
+			log.AddID(logger.MsgID_SourceMap_SectionsInSourceMap, logger.Warning, tracker, sectionRange,
+				"Each section in this source map must be an object")
+			return nil
+		}
+
+		for _, prop := range section.Properties {
+			keyRange := source.RangeOfString(prop.Key.Loc)
+
+			switch helpers.UTF16ToString(prop.Key.Data.(*js_ast.EString).Value) {
+			case "offset":
+				if offset, ok := prop.ValueOrNil.Data.(*js_ast.EObject); ok {
+					hasLine := false
+					hasColumn := false
+					for _, offsetProp := range offset.Properties {
+						value, ok := offsetProp.ValueOrNil.Data.(*js_ast.ENumber)
+						if !ok || value.Value < 0 || value.Value != float64(int32(value.Value)) {
+							continue
+						}
+						switch helpers.UTF16ToString(offsetProp.Key.Data.(*js_ast.EString).Value) {
+						case "line":
+							offsetLine = int32(value.Value)
+							hasLine = true
+						case "column":
+							offsetColumn = int32(value.Value)
+							hasColumn = true
+						}
+					}
+					hasOffset = hasLine && hasColumn
+				}
+
+			case "map":
+				if value, ok := prop.ValueOrNil.Data.(*js_ast.EObject); ok {
+					if sectionMap = parseSourceMapObject(log, source, tracker, value, false); sectionMap == nil {
+						return nil
+					}
+				}
+
+			case "url":
+				log.AddID(logger.MsgID_SourceMap_SectionsInSourceMap, logger.Warning, tracker, keyRange,
+					"Source map sections with \"url\" are not supported")
+				return nil
+			}
+		}
+
+		if !hasOffset || sectionMap == nil {
+			log.AddID(logger.MsgID_SourceMap_SectionsInSourceMap, logger.Warning, tracker, sectionRange,
+				"Each section in this source map must have an \"offset\" and a \"map\"")
+			return nil
+		}
+
+		// Only include "sourcesContent" if at least one section has it, and make
+		// sure it lines up with "sources" if some sections don't have it
+		if sectionMap.SourcesContent != nil || result.SourcesContent != nil {
+			for len(result.SourcesContent) < len(result.Sources) {
+				result.SourcesContent = append(result.SourcesContent, sourcemap.SourceContent{})
+			}
+			for i := range sectionMap.Sources {
+				var sourceContent sourcemap.SourceContent
+				if i < len(sectionMap.SourcesContent) {
+					sourceContent = sectionMap.SourcesContent[i]
+				}
+				result.SourcesContent = append(result.SourcesContent, sourceContent)
+			}
+		}
+
+		sourcesOffset := int32(len(result.Sources))
+		namesOffset := uint32(len(result.Names))
+		result.Sources = append(result.Sources, sectionMap.Sources...)
+		result.Names = append(result.Names, sectionMap.Names...)
+
+		for _, mapping := range sectionMap.Mappings {
+			if mapping.GeneratedLine == 0 {
+				mapping.GeneratedColumn += offsetColumn
+			}
+			mapping.GeneratedLine += offsetLine
+			mapping.SourceIndex += sourcesOffset
+			if mapping.OriginalName.IsValid() {
+				mapping.OriginalName = ast.MakeIndex32(mapping.OriginalName.GetIndex() + namesOffset)
+			}
+
+			// Sections are supposed to be in order and not overlap, but be robust
+			// against source maps that don't follow the specification
+			if n := len(result.Mappings); n > 0 {
+				if prev := result.Mappings[n-1]; prev.GeneratedLine > mapping.GeneratedLine ||
+					(prev.GeneratedLine == mapping.GeneratedLine && prev.GeneratedColumn > mapping.GeneratedColumn) {
+					needSort = true
+				}
+			}
+			result.Mappings = append(result.Mappings, mapping)
+		}
+	}
+
+	if needSort {
+		sort.Stable(mappingArray(result.Mappings))
+	}
+
+	return result
+}
+
 // This type is just so we can use Go's native sort function
 type mappingArray []sourcemap.Mapping
 
@@ -1,6 +1,7 @@
 // This file contains code for parsing TypeScript syntax. The parser just skips
 // over type expressions as if they are whitespace and doesn't bother generating
-// an AST because nothing uses type information.
+// an AST because nothing uses type information. The one exception is the rough
+// summary of some types that's needed for "emitDecoratorMetadata".
 
 package js_parser
 
@@ -104,6 +105,17 @@ func (p *parser) skipTypeScriptFnArgs() {
 			p.lexer.Next()
 		}
 
+		// Flow allows function type parameters without names
+		// "(string, number) => void"
+		if p.options.ts.Flow && !p.isFlowNamedFnParam() {
+			p.skipTypeScriptType(js_ast.LLowest)
+			if p.lexer.Token != js_lexer.TComma {
+				break
+			}
+			p.lexer.Next()
+			continue
+		}
+
 		p.skipTypeScriptBinding()
 
 		// "(a?)"
@@ -152,7 +164,18 @@ func (p *parser) skipTypeScriptParenOrFnType() {
 }
 
 func (p *parser) skipTypeScriptReturnType() {
+	// "function isString(x): %checks {}"
+	if p.options.ts.Flow && p.lexer.Token == js_lexer.TPercent {
+		p.skipFlowPredicate()
+		return
+	}
+
 	p.skipTypeScriptTypeWithFlags(js_ast.LLowest, isReturnTypeFlag)
+
+	// "function isString(x): boolean %checks {}"
+	if p.options.ts.Flow && p.lexer.Token == js_lexer.TPercent {
+		p.skipFlowPredicate()
+	}
 }
 
 func (p *parser) skipTypeScriptType(level js_ast.L) {
@@ -251,6 +274,21 @@ loop:
 			p.lexer.Next()
 			continue
 
+		case js_lexer.TQuestion, js_lexer.TQuestionQuestion:
+			// Flow: "?string" is a "maybe type" that also allows null and undefined
+			if !p.options.ts.Flow {
+				p.lexer.Unexpected()
+			}
+			p.lexer.Next()
+			continue
+
+		case js_lexer.TAsterisk:
+			// Flow: "*" is the deprecated existential type
+			if !p.options.ts.Flow {
+				p.lexer.Unexpected()
+			}
+			p.lexer.Next()
+
 		case js_lexer.TImport:
 			// "import('fs')"
 			p.lexer.Next()
@@ -286,12 +324,12 @@ loop:
 				return
 			}
 
-			p.skipTypeScriptTypeParameters(typeParametersNormal)
+			p.skipTypeScriptTypeParameters(allowConstModifier)
 			p.skipTypeScriptParenOrFnType()
 
 		case js_lexer.TLessThan:
 			// "<T>() => Foo<T>"
-			p.skipTypeScriptTypeParameters(typeParametersNormal)
+			p.skipTypeScriptTypeParameters(allowConstModifier)
 			p.skipTypeScriptParenOrFnType()
 
 		case js_lexer.TOpenParen:
@@ -470,7 +508,8 @@ loop:
 	for {
 		switch p.lexer.Token {
 		case js_lexer.TBar:
-			if level >= js_ast.LBitwiseOr {
+			// Flow: "{| x: number |}" must not become a union type
+			if level >= js_ast.LBitwiseOr || (p.options.ts.Flow && p.isFlowExactObjectTypeEnd()) {
 				return
 			}
 			p.lexer.Next()
@@ -517,6 +556,26 @@ loop:
 			}
 			p.lexer.Expect(js_lexer.TCloseBracket)
 
+		case js_lexer.TQuestionDot:
+			// Flow: "Obj?.['key']"
+			if !p.options.ts.Flow || p.lexer.HasNewlineBefore {
+				return
+			}
+			p.lexer.Next()
+			p.lexer.Expect(js_lexer.TOpenBracket)
+			p.skipTypeScriptType(js_ast.LLowest)
+			p.lexer.Expect(js_lexer.TCloseBracket)
+
+		case js_lexer.TEqualsGreaterThan:
+			// Flow: "string => void" is a function type with a single unnamed
+			// parameter. This isn't allowed in a return type because it would be
+			// ambiguous with the arrow in "(x): string => x".
+			if !p.options.ts.Flow || level >= js_ast.LBitwiseOr || flags.has(isReturnTypeFlag) {
+				return
+			}
+			p.lexer.Next()
+			p.skipTypeScriptType(js_ast.LLowest)
+
 		case js_lexer.TExtends:
 			// "{ x: number \n extends: boolean }" must not become a single type
 			if p.lexer.HasNewlineBefore || flags.has(disallowConditionalTypesFlag) {
@@ -537,10 +596,367 @@ loop:
 	}
 }
 
+// When "emitDecoratorMetadata" is enabled, the TypeScript compiler passes a
+// run-time representation of some type annotations to decorators. This is a
+// summary of a type annotation that's just detailed enough to do that. The
+// TypeScript compiler uses type information to do this, which we don't have,
+// so type references are assumed to refer to values and are guarded at
+// run-time.
+type tsMetadataKind uint8
+
+const (
+	tsMetadataObject tsMetadataKind = iota
+	tsMetadataVoid
+	tsMetadataNever
+	tsMetadataNumber
+	tsMetadataString
+	tsMetadataBoolean
+	tsMetadataBigInt
+	tsMetadataSymbol
+	tsMetadataArray
+	tsMetadataFunction
+	tsMetadataPromise
+	tsMetadataReference
+)
+
+type tsMetadata struct {
+	// This is only used for "tsMetadataReference"
+	name []string
+
+	// This is only used for "tsMetadataArray" from "T[]", and is the type of
+	// the elements for rest arguments such as "...args: T[]"
+	elements *tsMetadata
+
+	kind tsMetadataKind
+}
+
+var tsMetadataPrimitives = map[string]tsMetadataKind{
+	"any":       tsMetadataObject,
+	"unknown":   tsMetadataObject,
+	"object":    tsMetadataObject,
+	"never":     tsMetadataNever,
+	"undefined": tsMetadataNever,
+	"number":    tsMetadataNumber,
+	"string":    tsMetadataString,
+	"boolean":   tsMetadataBoolean,
+	"bigint":    tsMetadataBigInt,
+	"symbol":    tsMetadataSymbol,
+}
+
+func (a tsMetadata) equals(b tsMetadata) bool {
+	if a.kind != b.kind || len(a.name) != len(b.name) {
+		return false
+	}
+	for i, part := range a.name {
+		if part != b.name[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// This is like "skipTypeScriptType" except that it also returns a summary of
+// the type for "emitDecoratorMetadata". Unions and intersections only keep
+// their type if all of their members have the same type. Like the TypeScript
+// compiler without "strictNullChecks", "null" and "undefined" are ignored.
+func (p *parser) skipTypeScriptTypeWithMetadata(flags skipTypeFlags) tsMetadata {
+	// Support things like "type Foo = | A | B" and "type Foo = & A & B"
+	if p.lexer.Token == js_lexer.TBar || p.lexer.Token == js_lexer.TAmpersand {
+		p.lexer.Next()
+	}
+
+	result := p.skipTypeScriptTypeOperandWithMetadata(flags)
+	for p.lexer.Token == js_lexer.TBar || p.lexer.Token == js_lexer.TAmpersand {
+		p.lexer.Next()
+		operand := p.skipTypeScriptTypeOperandWithMetadata(flags)
+		if result.kind == tsMetadataNever {
+			result = operand
+		} else if operand.kind != tsMetadataNever {
+			if result.equals(operand) {
+				// Rest arguments only use the element type of a single array type
+				result.elements = nil
+			} else {
+				result = tsMetadata{kind: tsMetadataObject}
+			}
+		}
+	}
+
+	// "null", "undefined", and "never" on their own become "void 0"
+	if result.kind == tsMetadataNever {
+		result.kind = tsMetadataVoid
+	}
+	return result
+}
+
+func (p *parser) skipTypeScriptTypeOperandWithMetadata(flags skipTypeFlags) tsMetadata {
+	var result tsMetadata
+
+	switch p.lexer.Token {
+	case js_lexer.TVoid:
+		p.lexer.Next()
+		result.kind = tsMetadataVoid
+
+	case js_lexer.TNull:
+		p.lexer.Next()
+		result.kind = tsMetadataNever
+
+	case js_lexer.TTrue, js_lexer.TFalse:
+		p.lexer.Next()
+		result.kind = tsMetadataBoolean
+
+	case js_lexer.TNumericLiteral:
+		p.lexer.Next()
+		result.kind = tsMetadataNumber
+
+	case js_lexer.TBigIntegerLiteral:
+		p.lexer.Next()
+		result.kind = tsMetadataBigInt
+
+	case js_lexer.TStringLiteral, js_lexer.TNoSubstitutionTemplateLiteral:
+		p.lexer.Next()
+		result.kind = tsMetadataString
+
+	case js_lexer.TOpenBrace:
+		// "{ x: number }"
+		p.skipTypeScriptObjectType()
+		result.kind = tsMetadataObject
+
+	case js_lexer.TOpenParen:
+		// "() => void"
+		if p.trySkipTypeScriptArrowArgsWithBacktracking() {
+			p.skipTypeScriptReturnType()
+			return tsMetadata{kind: tsMetadataFunction}
+		}
+
+		// "(number | string)"
+		p.lexer.Next()
+		result = p.skipTypeScriptTypeWithMetadata(0)
+		p.lexer.Expect(js_lexer.TCloseParen)
+
+	case js_lexer.TIdentifier:
+		name := p.lexer.Identifier.String
+		kind, isPrimitive := tsMetadataPrimitives[name]
+		if !isPrimitive && tsTypeIdentifierMap[name] != tsTypeIdentifierNormal {
+			// Types such as "keyof T" and "unique symbol" are skipped as usual
+			p.skipTypeScriptTypeWithFlags(js_ast.LBitwiseAnd, flags)
+			return tsMetadata{kind: tsMetadataObject}
+		}
+		p.lexer.Next()
+
+		// "function assert(x: any): x is boolean"
+		if p.lexer.IsContextualKeyword("is") && !p.lexer.HasNewlineBefore {
+			p.lexer.Next()
+			p.skipTypeScriptType(js_ast.LLowest)
+			return tsMetadata{kind: tsMetadataBoolean}
+		}
+
+		if isPrimitive {
+			result.kind = kind
+			break
+		}
+
+		// "Foo<T>"
+		// "foo.Bar<T>"
+		result.kind = tsMetadataReference
+		result.name = []string{name}
+		for {
+			if !p.lexer.HasNewlineBefore {
+				p.skipTypeScriptTypeArguments(false /* isInsideJSXElement */)
+			}
+			if p.lexer.Token != js_lexer.TDot {
+				break
+			}
+			p.lexer.Next()
+			if !p.lexer.IsIdentifierOrKeyword() {
+				p.lexer.Expect(js_lexer.TIdentifier)
+			}
+			if p.lexer.Token == js_lexer.TIdentifier {
+				result.name = append(result.name, p.lexer.Identifier.String)
+			} else {
+				result.name = append(result.name, p.lexer.Raw())
+			}
+			p.lexer.Next()
+		}
+
+		// The TypeScript compiler special-cases these built-in types
+		if len(result.name) == 1 {
+			switch name {
+			case "Array":
+				result = tsMetadata{kind: tsMetadataArray}
+			case "Function":
+				result = tsMetadata{kind: tsMetadataFunction}
+			}
+		}
+
+	default:
+		// Everything else is skipped as usual
+		switch p.lexer.Token {
+		case js_lexer.TOpenBracket:
+			// "[number, string]"
+			result.kind = tsMetadataArray
+		case js_lexer.TLessThan, js_lexer.TNew:
+			// "<T>() => T"
+			// "new () => Foo"
+			result.kind = tsMetadataFunction
+		case js_lexer.TTemplateHead:
+			// "`${'a' | 'b'}-${'c' | 'd'}`"
+			result.kind = tsMetadataString
+		case js_lexer.TMinus:
+			// "-123"
+			result.kind = tsMetadataNumber
+		}
+		p.skipTypeScriptTypeWithFlags(js_ast.LBitwiseAnd, flags)
+		return result
+	}
+
+	for {
+		switch p.lexer.Token {
+		case js_lexer.TExclamation:
+			if p.lexer.HasNewlineBefore {
+				return result
+			}
+			p.lexer.Next()
+
+		case js_lexer.TOpenBracket:
+			// "{ ['x']: string \n ['y']: string }" must not become a single type
+			if p.lexer.HasNewlineBefore {
+				return result
+			}
+			p.lexer.Next()
+			if p.lexer.Token != js_lexer.TCloseBracket {
+				// "Foo['bar']"
+				p.skipTypeScriptType(js_ast.LLowest)
+				result = tsMetadata{kind: tsMetadataObject}
+			} else {
+				// "Foo[]"
+				elements := result
+				result = tsMetadata{kind: tsMetadataArray, elements: &elements}
+			}
+			p.lexer.Expect(js_lexer.TCloseBracket)
+
+		case js_lexer.TExtends:
+			// "{ x: number \n extends: boolean }" must not become a single type
+			if p.lexer.HasNewlineBefore || flags.has(disallowConditionalTypesFlag) {
+				return result
+			}
+			p.lexer.Next()
+
+			// The type following "extends" is not permitted to be another conditional type
+			p.skipTypeScriptTypeWithFlags(js_ast.LLowest, disallowConditionalTypesFlag)
+			p.lexer.Expect(js_lexer.TQuestion)
+			p.skipTypeScriptType(js_ast.LLowest)
+			p.lexer.Expect(js_lexer.TColon)
+			p.skipTypeScriptType(js_ast.LLowest)
+			result = tsMetadata{kind: tsMetadataObject}
+
+		default:
+			return result
+		}
+	}
+}
+
+// This converts a type summary into the expression that the TypeScript
+// compiler would generate for it. Type references are guarded so that type
+// names that don't exist at run-time become "Object" instead of crashing:
+//
+//	typeof Foo === "undefined" ? Object : Foo
+func (p *parser) tsMetadataToExpr(loc logger.Loc, metadata tsMetadata) js_ast.Expr {
+	global := func(name string) js_ast.Expr {
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.storeNameInRef(js_lexer.MaybeSubstring{String: name})}}
+	}
+
+	switch metadata.kind {
+	case tsMetadataVoid, tsMetadataNever:
+		return js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
+	case tsMetadataNumber:
+		return global("Number")
+	case tsMetadataString:
+		return global("String")
+	case tsMetadataBoolean:
+		return global("Boolean")
+	case tsMetadataBigInt:
+		return global("BigInt")
+	case tsMetadataSymbol:
+		return global("Symbol")
+	case tsMetadataArray:
+		return global("Array")
+	case tsMetadataFunction:
+		return global("Function")
+	case tsMetadataPromise:
+		return global("Promise")
+	case tsMetadataReference:
+		value := global(metadata.name[0])
+		for _, name := range metadata.name[1:] {
+			value = js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: value, Name: name, NameLoc: loc}}
+		}
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIf{
+			Test: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+				Op:    js_ast.BinOpStrictEq,
+				Left:  js_ast.Expr{Loc: loc, Data: &js_ast.EUnary{Op: js_ast.UnOpTypeof, Value: global(metadata.name[0]), WasOriginallyTypeofIdentifier: true}},
+				Right: js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("undefined")}},
+			}},
+			Yes: global("Object"),
+			No:  value,
+		}}
+	default:
+		return global("Object")
+	}
+}
+
+// A setter's type is needed twice, once for its type and once for its argument
+// types. Expressions can't be shared, so this copies one before it's visited.
+func cloneTSMetadataExpr(expr js_ast.Expr) js_ast.Expr {
+	switch e := expr.Data.(type) {
+	case *js_ast.EIdentifier:
+		return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: e.Ref}}
+	case *js_ast.EDot:
+		return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EDot{Target: cloneTSMetadataExpr(e.Target), Name: e.Name, NameLoc: e.NameLoc}}
+	case *js_ast.EString:
+		return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EString{Value: e.Value}}
+	case *js_ast.EUnary:
+		return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EUnary{Op: e.Op, Value: cloneTSMetadataExpr(e.Value), WasOriginallyTypeofIdentifier: e.WasOriginallyTypeofIdentifier}}
+	case *js_ast.EBinary:
+		return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EBinary{Op: e.Op, Left: cloneTSMetadataExpr(e.Left), Right: cloneTSMetadataExpr(e.Right)}}
+	case *js_ast.EIf:
+		return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIf{Test: cloneTSMetadataExpr(e.Test), Yes: cloneTSMetadataExpr(e.Yes), No: cloneTSMetadataExpr(e.No)}}
+	}
+	return expr
+}
+
 func (p *parser) skipTypeScriptObjectType() {
 	p.lexer.Expect(js_lexer.TOpenBrace)
 
-	for p.lexer.Token != js_lexer.TCloseBrace {
+	// Flow: "{| x: number |}" is an exact object type
+	isExact := false
+	if p.options.ts.Flow {
+		switch p.lexer.Token {
+		case js_lexer.TBarBar:
+			// "{||}"
+			p.lexer.Next()
+			p.lexer.Expect(js_lexer.TCloseBrace)
+			return
+
+		case js_lexer.TBar:
+			p.lexer.Next()
+			isExact = true
+		}
+	}
+
+	for p.lexer.Token != js_lexer.TCloseBrace && (!isExact || p.lexer.Token != js_lexer.TBar) {
+		// Flow: "{ ...A, b: number }" spreads another object type and
+		// "{ a: number, ... }" makes an object type explicitly inexact
+		if p.options.ts.Flow && p.lexer.Token == js_lexer.TDotDotDot {
+			p.lexer.Next()
+			if p.lexer.Token != js_lexer.TCloseBrace && p.lexer.Token != js_lexer.TBar &&
+				p.lexer.Token != js_lexer.TComma && p.lexer.Token != js_lexer.TSemicolon {
+				p.skipTypeScriptType(js_ast.LLowest)
+			}
+			if p.lexer.Token == js_lexer.TComma || p.lexer.Token == js_lexer.TSemicolon {
+				p.lexer.Next()
+			}
+			continue
+		}
+
 		// "{ -readonly [K in keyof T]: T[K] }"
 		// "{ +readonly [K in keyof T]: T[K] }"
 		if p.lexer.Token == js_lexer.TPlus || p.lexer.Token == js_lexer.TMinus {
@@ -594,7 +1010,7 @@ func (p *parser) skipTypeScriptObjectType() {
 		}
 
 		// Type parameters come right after the optional mark
-		p.skipTypeScriptTypeParameters(typeParametersNormal)
+		p.skipTypeScriptTypeParameters(allowConstModifier)
 
 		switch p.lexer.Token {
 		case js_lexer.TColon:
@@ -626,38 +1042,70 @@ func (p *parser) skipTypeScriptObjectType() {
 			p.lexer.Next()
 
 		default:
-			if !p.lexer.HasNewlineBefore {
+			if !p.lexer.HasNewlineBefore && (!isExact || p.lexer.Token != js_lexer.TBar) {
 				p.lexer.Unexpected()
 			}
 		}
 	}
 
+	if isExact {
+		p.lexer.Expect(js_lexer.TBar)
+	}
 	p.lexer.Expect(js_lexer.TCloseBrace)
 }
 
-type typeParameters uint8
+type typeParameterFlags uint8
 
 const (
-	typeParametersNormal typeParameters = iota
-	typeParametersWithInOutVarianceAnnotations
+	// TypeScript 4.7
+	allowInOutVarianceAnnotations typeParameterFlags = 1 << iota
+
+	// TypeScript 5.0
+	allowConstModifier
 )
 
+func (flags typeParameterFlags) has(flag typeParameterFlags) bool {
+	return (flags & flag) != 0
+}
+
 // This is the type parameter declarations that go with other symbol
 // declarations (class, function, type, etc.)
-func (p *parser) skipTypeScriptTypeParameters(mode typeParameters) {
+func (p *parser) skipTypeScriptTypeParameters(flags typeParameterFlags) {
 	if p.lexer.Token == js_lexer.TLessThan {
 		p.lexer.Next()
 
 		for {
 			hasIn := false
 			hasOut := false
+			hasConst := false
 			expectIdentifier := true
 			invalidModifierRange := logger.Range{}
 
-			// Scan over a sequence of "in" and "out" modifiers (a.k.a. optional variance annotations)
+			// Flow: "class Foo<+T, -U> {}"
+			if p.options.ts.Flow && (p.lexer.Token == js_lexer.TPlus || p.lexer.Token == js_lexer.TMinus) {
+				p.lexer.Next()
+			}
+
+			// Scan over a sequence of "in" and "out" modifiers (a.k.a. optional
+			// variance annotations) as well as "const" modifiers
 			for {
+				if p.lexer.Token == js_lexer.TConst {
+					if invalidModifierRange.Len == 0 && (!flags.has(allowConstModifier) || hasConst) {
+						// Valid:
+						//   "class Foo<const T> {}"
+						// Invalid:
+						//   "interface Foo<const T> {}"
+						//   "class Foo<const const T> {}"
+						invalidModifierRange = p.lexer.Range()
+					}
+					p.lexer.Next()
+					hasConst = true
+					expectIdentifier = true
+					continue
+				}
+
 				if p.lexer.Token == js_lexer.TIn {
-					if invalidModifierRange.Len == 0 && (mode != typeParametersWithInOutVarianceAnnotations || hasIn || hasOut) {
+					if invalidModifierRange.Len == 0 && (!flags.has(allowInOutVarianceAnnotations) || hasIn || hasOut) {
 						// Valid:
 						//   "type Foo<in T> = T"
 						// Invalid:
@@ -673,7 +1121,7 @@ func (p *parser) skipTypeScriptTypeParameters(mode typeParameters) {
 
 				if p.lexer.IsContextualKeyword("out") {
 					r := p.lexer.Range()
-					if invalidModifierRange.Len == 0 && mode != typeParametersWithInOutVarianceAnnotations {
+					if invalidModifierRange.Len == 0 && !flags.has(allowInOutVarianceAnnotations) {
 						invalidModifierRange = r
 					}
 					p.lexer.Next()
@@ -709,6 +1157,12 @@ func (p *parser) skipTypeScriptTypeParameters(mode typeParameters) {
 				p.lexer.Expect(js_lexer.TIdentifier)
 			}
 
+			// Flow: "class Foo<T: number> {}"
+			if p.options.ts.Flow && p.lexer.Token == js_lexer.TColon {
+				p.lexer.Next()
+				p.skipTypeScriptType(js_ast.LLowest)
+			}
+
 			// "class Foo<T extends number> {}"
 			if p.lexer.Token == js_lexer.TExtends {
 				p.lexer.Next()
@@ -798,7 +1252,7 @@ func (p *parser) trySkipTypeScriptTypeParametersThenOpenParenWithBacktracking()
 		}
 	}()
 
-	p.skipTypeScriptTypeParameters(typeParametersNormal)
+	p.skipTypeScriptTypeParameters(allowConstModifier)
 	if p.lexer.Token != js_lexer.TOpenParen {
 		p.lexer.Unexpected()
 	}
@@ -893,6 +1347,9 @@ func (p *parser) isTSArrowFnJSX() (isTSArrowFn bool) {
 	p.lexer.Next()
 
 	// Look ahead to see if this should be an arrow function instead
+	if p.lexer.Token == js_lexer.TConst {
+		p.lexer.Next()
+	}
 	if p.lexer.Token == js_lexer.TIdentifier {
 		p.lexer.Next()
 		if p.lexer.Token == js_lexer.TComma || p.lexer.Token == js_lexer.TEquals {
@@ -1012,7 +1469,7 @@ func (p *parser) skipTypeScriptInterfaceStmt(opts parseStmtOpts) {
 		p.localTypeNames[name] = true
 	}
 
-	p.skipTypeScriptTypeParameters(typeParametersWithInOutVarianceAnnotations)
+	p.skipTypeScriptTypeParameters(allowInOutVarianceAnnotations)
 
 	if p.lexer.Token == js_lexer.TExtends {
 		p.lexer.Next()
@@ -1087,27 +1544,28 @@ func (p *parser) skipTypeScriptTypeStmt(opts parseStmtOpts) {
 		p.localTypeNames[name] = true
 	}
 
-	p.skipTypeScriptTypeParameters(typeParametersWithInOutVarianceAnnotations)
+	p.skipTypeScriptTypeParameters(allowInOutVarianceAnnotations)
 	p.lexer.Expect(js_lexer.TEquals)
 	p.skipTypeScriptType(js_ast.LLowest)
 	p.lexer.ExpectOrInsertSemicolon()
 }
 
-func (p *parser) parseTypeScriptDecorators(tsDecoratorScope *js_ast.Scope) []js_ast.Expr {
-	var tsDecorators []js_ast.Expr
+func (p *parser) parseDecorators(decoratorScope *js_ast.Scope) []js_ast.Expr {
+	var decorators []js_ast.Expr
 
-	if p.options.ts.Parse {
-		// TypeScript decorators cause us to temporarily revert to the scope that
-		// encloses the class declaration, since that's where the generated code
-		// for TypeScript decorators will be inserted.
-		oldScope := p.currentScope
-		p.currentScope = tsDecoratorScope
-
-		for p.lexer.Token == js_lexer.TAt {
-			loc := p.lexer.Loc()
-			p.lexer.Next()
+	// Decorators cause us to temporarily revert to the scope that encloses the
+	// class declaration, since that's where the generated code for decorators
+	// will be inserted.
+	oldScope := p.currentScope
+	p.currentScope = decoratorScope
+
+	for p.lexer.Token == js_lexer.TAt {
+		loc := p.lexer.Loc()
+		p.lexer.Next()
 
-			// Parse a new/call expression with "exprFlagTSDecorator" so we ignore
+		var value js_ast.Expr
+		if p.options.ts.Parse {
+			// Parse a new/call expression with "exprFlagDecorator" so we ignore
 			// EIndex expressions, since they may be part of a computed property:
 			//
 			//   class Foo {
@@ -1115,16 +1573,66 @@ func (p *parser) parseTypeScriptDecorators(tsDecoratorScope *js_ast.Scope) []js_
 			//   }
 			//
 			// This matches the behavior of the TypeScript compiler.
-			value := p.parseExprWithFlags(js_ast.LNew, exprFlagTSDecorator)
-			value.Loc = loc
-			tsDecorators = append(tsDecorators, value)
+			value = p.parseExprWithFlags(js_ast.LNew, exprFlagDecorator)
+		} else {
+			value = p.parseDecorator()
+		}
+		value.Loc = loc
+		decorators = append(decorators, value)
+	}
+
+	// Avoid "popScope" because this decorator scope is not hierarchical
+	p.currentScope = oldScope
+	return decorators
+}
+
+// JavaScript decorators use a restricted grammar. A decorator must be either
+// a parenthesized expression or a chain of property accesses optionally
+// followed by a single call:
+//
+//	@(expr)
+//	@a.b.c
+//	@a.b.c(args)
+func (p *parser) parseDecorator() js_ast.Expr {
+	if p.lexer.Token == js_lexer.TOpenParen {
+		p.lexer.Next()
+		value := p.parseExpr(js_ast.LLowest)
+		p.lexer.Expect(js_lexer.TCloseParen)
+		return value
+	}
+
+	if p.lexer.Token != js_lexer.TIdentifier {
+		p.lexer.Expect(js_lexer.TIdentifier)
+	}
+	value := p.parsePrefix(js_ast.LMember, nil, 0)
+
+	for p.lexer.Token == js_lexer.TDot {
+		p.lexer.Next()
+		if !p.lexer.IsIdentifierOrKeyword() {
+			p.lexer.Expect(js_lexer.TIdentifier)
 		}
+		name := p.lexer.Identifier
+		nameLoc := p.lexer.Loc()
+		p.lexer.Next()
+		value = js_ast.Expr{Loc: value.Loc, Data: p.dotOrMangledPropParse(value, name, nameLoc, js_ast.OptionalChainNone, wasOriginallyDot)}
+	}
 
-		// Avoid "popScope" because this decorator scope is not hierarchical
-		p.currentScope = oldScope
+	if p.lexer.Token == js_lexer.TOpenParen {
+		kind := js_ast.NormalCall
+		if js_ast.IsPropertyAccess(value) {
+			kind = js_ast.TargetWasOriginallyPropertyAccess
+		}
+		args, closeParenLoc, isMultiLine := p.parseCallArgs()
+		value = js_ast.Expr{Loc: value.Loc, Data: &js_ast.ECall{
+			Target:        value,
+			Args:          args,
+			CloseParenLoc: closeParenLoc,
+			IsMultiLine:   isMultiLine,
+			Kind:          kind,
+		}}
 	}
 
-	return tsDecorators
+	return value
 }
 
 func (p *parser) logInvalidDecoratorError(classKeyword logger.Range) {
@@ -1135,23 +1643,30 @@ func (p *parser) logInvalidDecoratorError(classKeyword logger.Range) {
 
 		// Parse and discard decorators for error recovery
 		scopeIndex := len(p.scopesInOrder)
-		p.parseTypeScriptDecorators(p.currentScope)
+		p.parseDecorators(p.currentScope)
 		p.discardScopesUpTo(scopeIndex)
 	}
 }
 
-func (p *parser) logMisplacedDecoratorError(tsDecorators *deferredTSDecorators) {
+func (p *parser) logMisplacedDecoratorError(decorators *deferredDecorators) {
 	found := fmt.Sprintf("%q", p.lexer.Raw())
 	if p.lexer.Token == js_lexer.TEndOfFile {
 		found = "end of file"
 	}
 
 	// Try to be helpful by pointing out the decorator
-	p.lexer.AddRangeErrorWithNotes(p.lexer.Range(), fmt.Sprintf("Expected \"class\" after TypeScript decorator but found %s", found), []logger.MsgData{
-		p.tracker.MsgData(logger.Range{Loc: tsDecorators.values[0].Loc}, "The preceding TypeScript decorator is here:"),
-		{Text: "Decorators can only be used with class declarations in TypeScript."},
-	})
-	p.discardScopesUpTo(tsDecorators.scopeIndex)
+	if p.options.ts.Parse {
+		p.lexer.AddRangeErrorWithNotes(p.lexer.Range(), fmt.Sprintf("Expected \"class\" after TypeScript decorator but found %s", found), []logger.MsgData{
+			p.tracker.MsgData(logger.Range{Loc: decorators.values[0].Loc}, "The preceding TypeScript decorator is here:"),
+			{Text: "Decorators can only be used with class declarations in TypeScript."},
+		})
+	} else {
+		p.lexer.AddRangeErrorWithNotes(p.lexer.Range(), fmt.Sprintf("Expected \"class\" after decorator but found %s", found), []logger.MsgData{
+			p.tracker.MsgData(logger.Range{Loc: decorators.values[0].Loc}, "The preceding decorator is here:"),
+			{Text: "Decorators can only be used with classes and class members."},
+		})
+	}
+	p.discardScopesUpTo(decorators.scopeIndex)
 }
 
 func (p *parser) parseTypeScriptEnumStmt(loc logger.Loc, opts parseStmtOpts) js_ast.Stmt {
@@ -1657,8 +2172,13 @@ func (p *parser) generateClosureForTypeScriptNamespaceOrEnum(
 			}})
 		} else {
 			// Nested namespace: "let"
+			kind := js_ast.LocalLet
+			if p.options.unsupportedJSFeatures.Has(compat.ConstAndLet) {
+				kind = js_ast.LocalVar
+				p.hoistLoweredLetOrConstRef(nameRef)
+			}
 			stmts = append(stmts, js_ast.Stmt{Loc: stmtLoc, Data: &js_ast.SLocal{
-				Kind:  js_ast.LocalLet,
+				Kind:  kind,
 				Decls: decls,
 			}})
 		}
@@ -59,6 +59,16 @@ func expectPrintedTargetTS(t *testing.T, esVersion int, contents string, expecte
 	})
 }
 
+func expectPrintedEmitDecoratorMetadataTS(t *testing.T, contents string, expected string) {
+	t.Helper()
+	expectPrintedCommon(t, contents, expected, config.Options{
+		TS: config.TSOptions{
+			Parse: true,
+		},
+		EmitDecoratorMetadata: true,
+	})
+}
+
 func expectParseErrorTSNoAmbiguousLessThan(t *testing.T, contents string, expected string) {
 	t.Helper()
 	expectParseErrorCommon(t, contents, expected, config.Options{
@@ -374,6 +384,33 @@ func TestTSTypes(t *testing.T) {
 	expectPrintedTS(t, "class Container { get data(): typeof this.#data {} }", "class Container {\n  get data() {\n  }\n}\n")
 	expectPrintedTS(t, "const a: typeof this.#a = 1;", "const a = 1;\n")
 	expectParseErrorTS(t, "const a: typeof #a = 1;", "<stdin>: ERROR: Expected identifier but found \"#a\"\n")
+
+	// TypeScript 5.0
+	expectPrintedTS(t, "class Foo<const T> {}", "class Foo {\n}\n")
+	expectPrintedTS(t, "class Foo<const T extends X> {}", "class Foo {\n}\n")
+	expectPrintedTS(t, "class Foo<const in T> {}", "class Foo {\n}\n")
+	expectPrintedTS(t, "class Foo<in const T> {}", "class Foo {\n}\n")
+	expectPrintedTS(t, "Foo = class <const T> {}", "Foo = class {\n};\n")
+	expectPrintedTS(t, "function foo<const T>() {}", "function foo() {\n}\n")
+	expectPrintedTS(t, "foo = function <const T>() {}", "foo = function() {\n};\n")
+	expectPrintedTS(t, "export default function <const T>() {}", "export default function() {\n}\n")
+	expectPrintedTS(t, "declare function foo<const T>()", "")
+	expectPrintedTS(t, "class Foo { foo<const T>() {} }", "class Foo {\n  foo() {\n  }\n}\n")
+	expectPrintedTS(t, "foo = { foo<const T>() {} }", "foo = { foo() {\n} };\n")
+	expectPrintedTS(t, "foo = <const T>() => {}", "foo = () => {\n};\n")
+	expectPrintedTS(t, "foo = <const T, const U>() => {}", "foo = () => {\n};\n")
+	expectPrintedTS(t, "let x: <const T>() => T", "let x;\n")
+	expectPrintedTS(t, "let x: new <const T>() => T", "let x;\n")
+	expectPrintedTS(t, "let x: { y<const T>(): T }", "let x;\n")
+	expectParseErrorTS(t, "type Foo<const T> = T", "<stdin>: ERROR: The modifier \"const\" is not valid here:\n")
+	expectParseErrorTS(t, "interface Foo<const T> {}", "<stdin>: ERROR: The modifier \"const\" is not valid here:\n")
+	expectParseErrorTS(t, "class Foo<const const T> {}", "<stdin>: ERROR: The modifier \"const\" is not valid here:\n")
+	expectParseErrorTS(t, "function foo<const>() {}", "<stdin>: ERROR: Expected identifier but found \">\"\n")
+	expectParseErrorTS(t, "let foo: Foo<const T>", "<stdin>: ERROR: Expected \">\" but found \"T\"\n")
+	expectPrintedTSX(t, "<const T,>() => {}", "() => {\n};\n")
+	expectPrintedTSX(t, "<const T extends X>() => {}", "() => {\n};\n")
+	expectPrintedTSX(t, "<const T></const>", "/* @__PURE__ */ React.createElement(\"const\", { T: true });\n")
+	expectPrintedTSX(t, "<const T extends={true}></const>", "/* @__PURE__ */ React.createElement(\"const\", { T: true, extends: true });\n")
 }
 
 func TestTSAsCast(t *testing.T) {
@@ -1586,6 +1623,13 @@ func TestTSDecorator(t *testing.T) {
 	expectParseErrorTS(t, "@dec export declare enum foo {}", "<stdin>: ERROR: Expected \"class\" after TypeScript decorator but found \"enum\"\n"+notes)
 	expectParseErrorTS(t, "@dec export declare namespace foo {}", "<stdin>: ERROR: Expected \"class\" after TypeScript decorator but found \"namespace\"\n"+notes)
 	expectParseErrorTS(t, "@dec export declare function foo()", "<stdin>: ERROR: Expected \"class\" after TypeScript decorator but found \"function\"\n"+notes)
+	expectParseErrorTS(t, "export @dec export class Foo {}", "<stdin>: ERROR: Expected \"class\" after TypeScript decorator but found \"export\"\n"+notes)
+	expectParseErrorTS(t, "export @dec function foo() {}", "<stdin>: ERROR: Expected \"class\" after TypeScript decorator but found \"function\"\n"+notes)
+
+	// TypeScript 5.0 allows decorators to come after "export"
+	expectPrintedTS(t, "export @dec class Foo {}", "export let Foo = class {\n};\nFoo = __decorateClass([\n  dec\n], Foo);\n")
+	expectPrintedTS(t, "export @dec abstract class Foo {}", "export let Foo = class {\n};\nFoo = __decorateClass([\n  dec\n], Foo);\n")
+	expectPrintedTS(t, "export default @dec class Foo {}", "let Foo = class {\n};\nFoo = __decorateClass([\n  dec\n], Foo);\nexport {\n  Foo as default\n};\n")
 
 	// Decorators must be forbidden outside class statements
 	note := "<stdin>: NOTE: This is a class expression, not a class declaration:\n"
@@ -1680,6 +1724,99 @@ func TestTSDecorator(t *testing.T) {
 	expectParseErrorTS(t, "function foo() { class Foo { @dec(yield x) foo() {} } }", "<stdin>: ERROR: Cannot use \"yield\" outside a generator function\n")
 }
 
+func TestTSDecoratorMetadata(t *testing.T) {
+	field := func(t *testing.T, ts string, js string) {
+		t.Helper()
+		expectPrintedEmitDecoratorMetadataTS(t, "class Foo { @dec x"+ts+" }",
+			"class Foo {\n}\n__decorateClass([\n  dec,\n  __decorateMetadata(\"design:type\", "+js+")\n], Foo.prototype, \"x\", 2);\n")
+	}
+
+	field(t, "", "Object")
+	field(t, ": any", "Object")
+	field(t, ": unknown", "Object")
+	field(t, ": object", "Object")
+	field(t, ": number", "Number")
+	field(t, ": string", "String")
+	field(t, ": boolean", "Boolean")
+	field(t, ": bigint", "BigInt")
+	field(t, ": symbol", "Symbol")
+	field(t, ": unique symbol", "Object")
+	field(t, ": void", "void 0")
+	field(t, ": null", "void 0")
+	field(t, ": undefined", "void 0")
+	field(t, ": never", "void 0")
+	field(t, ": 123", "Number")
+	field(t, ": -123", "Number")
+	field(t, ": 123n", "BigInt")
+	field(t, ": 'abc'", "String")
+	field(t, ": \"a\" | `b`", "String")
+	field(t, ": `a${'b' | 'c'}`", "String")
+	field(t, ": true", "Boolean")
+	field(t, ": number[]", "Array")
+	field(t, ": [number, string]", "Array")
+	field(t, ": Array<number>", "Array")
+	field(t, ": { x: number }", "Object")
+	field(t, ": { x: number }[]", "Array")
+	field(t, ": keyof T", "Object")
+	field(t, ": typeof x", "Object")
+	field(t, ": T['x']", "Object")
+	field(t, ": T extends U ? V : W", "Object")
+	field(t, ": () => void", "Function")
+	field(t, ": new () => Foo", "Function")
+	field(t, ": <T>(x: T) => T", "Function")
+	field(t, ": Function", "Function")
+	field(t, ": (number)", "Number")
+	field(t, ": number | string", "Object")
+	field(t, ": number | 123", "Number")
+	field(t, ": string | null | undefined", "String")
+	field(t, ": | A | A", "typeof A === \"undefined\" ? Object : A")
+	field(t, ": A & B", "Object")
+	field(t, ": Foo", "typeof Foo === \"undefined\" ? Object : Foo")
+	field(t, ": Foo<T>", "typeof Foo === \"undefined\" ? Object : Foo")
+	field(t, ": a.b.Foo", "typeof a === \"undefined\" ? Object : a.b.Foo")
+
+	expectPrintedEmitDecoratorMetadataTS(t, "class Foo { @dec foo(x: number, y: Bar): string {} }",
+		"class Foo {\n  foo(x, y) {\n  }\n}\n__decorateClass([\n  dec,\n"+
+			"  __decorateMetadata(\"design:type\", Function),\n"+
+			"  __decorateMetadata(\"design:paramtypes\", [Number, typeof Bar === \"undefined\" ? Object : Bar]),\n"+
+			"  __decorateMetadata(\"design:returntype\", String)\n"+
+			"], Foo.prototype, \"foo\", 1);\n")
+	expectPrintedEmitDecoratorMetadataTS(t, "class Foo { foo(this: Foo, @dec x, ...y: string[]) {} }",
+		"class Foo {\n  foo(x, ...y) {\n  }\n}\n__decorateClass([\n  __decorateParam(0, dec),\n"+
+			"  __decorateMetadata(\"design:type\", Function),\n"+
+			"  __decorateMetadata(\"design:paramtypes\", [Object, String]),\n"+
+			"  __decorateMetadata(\"design:returntype\", void 0)\n"+
+			"], Foo.prototype, \"foo\", 1);\n")
+	expectPrintedEmitDecoratorMetadataTS(t, "class Foo { @dec async foo() {} }",
+		"class Foo {\n  async foo() {\n  }\n}\n__decorateClass([\n  dec,\n"+
+			"  __decorateMetadata(\"design:type\", Function),\n"+
+			"  __decorateMetadata(\"design:paramtypes\", []),\n"+
+			"  __decorateMetadata(\"design:returntype\", Promise)\n"+
+			"], Foo.prototype, \"foo\", 1);\n")
+	expectPrintedEmitDecoratorMetadataTS(t, "class Foo { @dec get foo(): number { return 1 } }",
+		"class Foo {\n  get foo() {\n    return 1;\n  }\n}\n__decorateClass([\n  dec,\n"+
+			"  __decorateMetadata(\"design:type\", Number)\n"+
+			"], Foo.prototype, \"foo\", 1);\n")
+	expectPrintedEmitDecoratorMetadataTS(t, "class Foo { @dec set foo(x: number) {} }",
+		"class Foo {\n  set foo(x) {\n  }\n}\n__decorateClass([\n  dec,\n"+
+			"  __decorateMetadata(\"design:type\", Number),\n"+
+			"  __decorateMetadata(\"design:paramtypes\", [Number])\n"+
+			"], Foo.prototype, \"foo\", 1);\n")
+	expectPrintedEmitDecoratorMetadataTS(t, "@dec class Foo { constructor(x: number, private y?: Bar) {} }",
+		"let Foo = class {\n  constructor(x, y) {\n    this.y = y;\n  }\n};\nFoo = __decorateClass([\n  dec,\n"+
+			"  __decorateMetadata(\"design:paramtypes\", [Number, typeof Bar === \"undefined\" ? Object : Bar])\n"+
+			"], Foo);\n")
+	expectPrintedEmitDecoratorMetadataTS(t, "@dec class Foo {}", "let Foo = class {\n};\nFoo = __decorateClass([\n  dec\n], Foo);\n")
+
+	// Types are only used when there are decorators
+	expectPrintedEmitDecoratorMetadataTS(t, "import { Bar } from 'bar'; class Foo { x: Bar; foo(x: Bar): Bar {} }",
+		"class Foo {\n  foo(x) {\n  }\n}\n")
+	expectPrintedEmitDecoratorMetadataTS(t, "import { Bar } from 'bar'; class Foo { @dec x: Bar }",
+		"import { Bar } from \"bar\";\nclass Foo {\n}\n__decorateClass([\n  dec,\n"+
+			"  __decorateMetadata(\"design:type\", typeof Bar === \"undefined\" ? Object : Bar)\n"+
+			"], Foo.prototype, \"x\", 2);\n")
+}
+
 func TestTSTry(t *testing.T) {
 	expectPrintedTS(t, "try {} catch (x: any) {}", "try {\n} catch (x) {\n}\n")
 	expectPrintedTS(t, "try {} catch (x: unknown) {}", "try {\n} catch (x) {\n}\n")
@@ -1771,8 +1908,7 @@ func TestTSArrow(t *testing.T) {
 	expectPrintedTS(t, "function x(): ({y: z}) {}", "function x() {\n}\n")
 
 	expectParseErrorTargetTS(t, 5, "return check ? (hover = 2, bar) : baz()", "")
-	expectParseErrorTargetTS(t, 5, "return check ? (hover = 2, bar) => 0 : baz()",
-		"<stdin>: ERROR: Transforming default arguments to the configured target environment is not supported yet\n")
+	expectParseErrorTargetTS(t, 5, "return check ? (hover = 2, bar) => 0 : baz()", "")
 }
 
 func TestTSSuperCall(t *testing.T) {
@@ -2451,6 +2587,6 @@ func TestTSES5(t *testing.T) {
 	expectPrintedTargetTS(t, 5, "0 ? ({}) : 0", "0 ? {} : 0;\n")
 	expectPrintedTargetTS(t, 2015, "0 ? ([]): 0 => 0 : 0", "0 ? ([]) => 0 : 0;\n")
 	expectPrintedTargetTS(t, 2015, "0 ? ({}): 0 => 0 : 0", "0 ? ({}) => 0 : 0;\n")
-	expectParseErrorTargetTS(t, 5, "0 ? ([]): 0 => 0 : 0", "<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTargetTS(t, 5, "0 ? ({}): 0 => 0 : 0", "<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
+	expectPrintedTargetTS(t, 5, "0 ? ([]): 0 => 0 : 0", "0 ? function(_a) {\n  var _b = __toArray(_a, 0);\n  return 0;\n} : 0;\n")
+	expectPrintedTargetTS(t, 5, "0 ? ({}): 0 => 0 : 0", "0 ? function(_a) {\n  var _b = __requireObject(_a);\n  return 0;\n} : 0;\n")
 }
@@ -0,0 +1,1555 @@
+package js_printer
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// PrintASTJSON serializes a parsed AST as JSON so that it can be consumed by
+// other tools. The output follows ESTree (https://github.com/estree/estree)
+// so that it doesn't depend on esbuild's internal data structures, which can
+// change between releases. The node types and properties are the ones from
+// the ESTree specification up to ES2022 plus the JSX extension, with these
+// differences:
+//
+//   - Each node has a "start" property with the byte offset of the node in
+//     the source file instead of "loc" and "range". There is no end offset
+//     since esbuild doesn't track where nodes end. Top-level directives have
+//     no "start" property since esbuild doesn't track where they are.
+//
+//   - Comments that are kept are returned in a "comments" array on the
+//     "Program" node, like other ESTree parsers do. Comments inside of
+//     expressions are not included.
+//
+//   - The "raw" property of a "TemplateElement" is computed from the cooked
+//     value for untagged templates, and the "cooked" property is null for
+//     tagged templates.
+//
+//   - Numbers that JSON can't represent become the "NaN" and "Infinity"
+//     identifiers, and negative numbers are negated positive numbers.
+//
+//   - The "undefined" value becomes "void 0" because that's how esbuild
+//     represents it internally.
+//
+// This is the AST after esbuild's transformations have been applied, so for
+// example TypeScript types have already been removed.
+func PrintASTJSON(tree js_ast.AST, asciiOnly bool) []byte {
+	p := astJSONPrinter{tree: &tree, asciiOnly: asciiOnly}
+	p.js = append(p.js, "{\"type\":\"Program\",\"start\":0,\"sourceType\":"...)
+	if tree.ExportsKind == js_ast.ExportsESM || tree.ExportsKind == js_ast.ExportsESMWithDynamicFallback {
+		p.js = append(p.js, "\"module\""...)
+	} else {
+		p.js = append(p.js, "\"script\""...)
+	}
+	if tree.Hashbang != "" {
+		p.key("hashbang")
+		p.string(tree.Hashbang)
+	}
+	p.key("body")
+	p.js = append(p.js, '[')
+	isFirst := true
+	for _, directive := range tree.Directives {
+		if !isFirst {
+			p.js = append(p.js, ',')
+		}
+		isFirst = false
+		p.js = append(p.js, "{\"type\":\"ExpressionStatement\",\"expression\":{\"type\":\"Literal\",\"value\":"...)
+		p.string(directive)
+		p.js = append(p.js, "},\"directive\":"...)
+		p.string(directive)
+		p.js = append(p.js, '}')
+	}
+	for _, part := range tree.Parts {
+		for _, stmt := range part.Stmts {
+			if p.willPrintStmt(stmt) {
+				if !isFirst {
+					p.js = append(p.js, ',')
+				}
+				isFirst = false
+				p.stmt(stmt)
+			}
+		}
+	}
+	p.js = append(p.js, ']')
+	p.key("comments")
+	p.js = append(p.js, '[')
+	p.js = append(p.js, p.comments...)
+	p.js = append(p.js, "]}"...)
+	return p.js
+}
+
+type astJSONPrinter struct {
+	tree      *js_ast.AST
+	js        []byte
+	comments  []byte
+	asciiOnly bool
+}
+
+func (p *astJSONPrinter) node(kind string, loc logger.Loc) {
+	p.js = append(p.js, "{\"type\":\""...)
+	p.js = append(p.js, kind...)
+	p.js = append(p.js, "\",\"start\":"...)
+	p.js = strconv.AppendInt(p.js, int64(loc.Start), 10)
+}
+
+func (p *astJSONPrinter) key(key string) {
+	p.js = append(p.js, ",\""...)
+	p.js = append(p.js, key...)
+	p.js = append(p.js, "\":"...)
+}
+
+func (p *astJSONPrinter) string(text string) {
+	p.js = append(p.js, helpers.QuoteForJSON(text, p.asciiOnly)...)
+}
+
+func (p *astJSONPrinter) bool(value bool) {
+	p.js = strconv.AppendBool(p.js, value)
+}
+
+func (p *astJSONPrinter) null() {
+	p.js = append(p.js, "null"...)
+}
+
+func (p *astJSONPrinter) name(ref js_ast.Ref) string {
+	if ref == js_ast.InvalidRef || int(ref.InnerIndex) >= len(p.tree.Symbols) {
+		return ""
+	}
+	return p.tree.Symbols[ref.InnerIndex].OriginalName
+}
+
+func (p *astJSONPrinter) identifier(loc logger.Loc, name string) {
+	p.node("Identifier", loc)
+	p.key("name")
+	p.string(name)
+	p.js = append(p.js, '}')
+}
+
+func (p *astJSONPrinter) identifierOrNull(locRef *js_ast.LocRef) {
+	if locRef == nil {
+		p.null()
+	} else {
+		p.identifier(locRef.Loc, p.name(locRef.Ref))
+	}
+}
+
+func (p *astJSONPrinter) stringLiteral(loc logger.Loc, text string) {
+	p.node("Literal", loc)
+	p.key("value")
+	p.string(text)
+	p.js = append(p.js, '}')
+}
+
+func (p *astJSONPrinter) importPath(loc logger.Loc, importRecordIndex uint32) {
+	path := ""
+	if int(importRecordIndex) < len(p.tree.ImportRecords) {
+		record := &p.tree.ImportRecords[importRecordIndex]
+		path = record.Path.Text
+		if record.Range.Len > 0 {
+			loc = record.Range.Loc
+		}
+	}
+	p.stringLiteral(loc, path)
+}
+
+// TypeScript-only statements have already been removed from the output and
+// comments go in a separate array
+func (p *astJSONPrinter) willPrintStmt(stmt js_ast.Stmt) bool {
+	switch s := stmt.Data.(type) {
+	case *js_ast.STypeScript:
+		return false
+
+	case *js_ast.SComment:
+		if len(p.comments) > 0 {
+			p.comments = append(p.comments, ',')
+		}
+		kind := "Line"
+		text := strings.TrimPrefix(s.Text, "//")
+		if strings.HasPrefix(s.Text, "/*") {
+			kind = "Block"
+			text = strings.TrimSuffix(strings.TrimPrefix(s.Text, "/*"), "*/")
+		}
+		p.comments = append(p.comments, "{\"type\":\""...)
+		p.comments = append(p.comments, kind...)
+		p.comments = append(p.comments, "\",\"start\":"...)
+		p.comments = strconv.AppendInt(p.comments, int64(stmt.Loc.Start), 10)
+		p.comments = append(p.comments, ",\"value\":"...)
+		p.comments = append(p.comments, helpers.QuoteForJSON(text, p.asciiOnly)...)
+		p.comments = append(p.comments, '}')
+		return false
+	}
+	return true
+}
+
+func (p *astJSONPrinter) stmts(stmts []js_ast.Stmt) {
+	p.js = append(p.js, '[')
+	isFirst := true
+	for _, stmt := range stmts {
+		if p.willPrintStmt(stmt) {
+			if !isFirst {
+				p.js = append(p.js, ',')
+			}
+			isFirst = false
+			p.stmt(stmt)
+		}
+	}
+	p.js = append(p.js, ']')
+}
+
+func (p *astJSONPrinter) block(loc logger.Loc, stmts []js_ast.Stmt) {
+	p.node("BlockStatement", loc)
+	p.key("body")
+	p.stmts(stmts)
+	p.js = append(p.js, '}')
+}
+
+func (p *astJSONPrinter) stmtOrNull(stmt js_ast.Stmt) {
+	if stmt.Data == nil {
+		p.null()
+	} else {
+		p.stmt(stmt)
+	}
+}
+
+// The left side of a "for" loop can be a declaration or an expression
+func (p *astJSONPrinter) forInit(stmt js_ast.Stmt, isPattern bool) {
+	switch s := stmt.Data.(type) {
+	case nil:
+		p.null()
+	case *js_ast.SExpr:
+		if isPattern {
+			p.pattern(s.Value)
+		} else {
+			p.expr(s.Value)
+		}
+	default:
+		p.stmt(stmt)
+	}
+}
+
+// Declarations with the "export" keyword are wrapped in an export node
+func (p *astJSONPrinter) exportWrapper(loc logger.Loc, isExport bool) {
+	if isExport {
+		p.node("ExportNamedDeclaration", loc)
+		p.key("specifiers")
+		p.js = append(p.js, "[]"...)
+		p.key("source")
+		p.null()
+		p.key("declaration")
+	}
+}
+
+func (p *astJSONPrinter) endExportWrapper(isExport bool) {
+	if isExport {
+		p.js = append(p.js, '}')
+	}
+}
+
+func (p *astJSONPrinter) stmt(stmt js_ast.Stmt) {
+	loc := stmt.Loc
+
+	switch s := stmt.Data.(type) {
+	case *js_ast.SBlock:
+		p.block(loc, s.Stmts)
+		return
+
+	case *js_ast.SEmpty:
+		p.node("EmptyStatement", loc)
+
+	case *js_ast.SDebugger:
+		p.node("DebuggerStatement", loc)
+
+	case *js_ast.SDirective:
+		text := helpers.UTF16ToString(s.Value)
+		p.node("ExpressionStatement", loc)
+		p.key("expression")
+		p.stringLiteral(loc, text)
+		p.key("directive")
+		p.string(text)
+
+	case *js_ast.SExportClause:
+		p.node("ExportNamedDeclaration", loc)
+		p.key("declaration")
+		p.null()
+		p.key("specifiers")
+		p.exportSpecifiers(s.Items, false)
+		p.key("source")
+		p.null()
+
+	case *js_ast.SExportFrom:
+		p.node("ExportNamedDeclaration", loc)
+		p.key("declaration")
+		p.null()
+		p.key("specifiers")
+		p.exportSpecifiers(s.Items, true)
+		p.key("source")
+		p.importPath(loc, s.ImportRecordIndex)
+
+	case *js_ast.SExportDefault:
+		p.node("ExportDefaultDeclaration", loc)
+		p.key("declaration")
+		if value, ok := s.Value.Data.(*js_ast.SExpr); ok {
+			p.expr(value.Value)
+		} else {
+			p.stmt(s.Value)
+		}
+
+	case *js_ast.SExportStar:
+		p.node("ExportAllDeclaration", loc)
+		p.key("exported")
+		if s.Alias != nil {
+			p.moduleExportName(s.Alias.Loc, s.Alias.OriginalName)
+		} else {
+			p.null()
+		}
+		p.key("source")
+		p.importPath(loc, s.ImportRecordIndex)
+
+	case *js_ast.SLazyExport:
+		p.node("ExportDefaultDeclaration", loc)
+		p.key("declaration")
+		p.expr(s.Value)
+
+	case *js_ast.SExpr:
+		p.node("ExpressionStatement", loc)
+		p.key("expression")
+		p.expr(s.Value)
+
+	case *js_ast.SFunction:
+		p.exportWrapper(loc, s.IsExport)
+		p.fn(loc, "FunctionDeclaration", &s.Fn)
+		p.endExportWrapper(s.IsExport)
+		return
+
+	case *js_ast.SClass:
+		p.exportWrapper(loc, s.IsExport)
+		p.class(loc, "ClassDeclaration", &s.Class)
+		p.endExportWrapper(s.IsExport)
+		return
+
+	case *js_ast.SLabel:
+		p.node("LabeledStatement", loc)
+		p.key("label")
+		p.identifierOrNull(&s.Name)
+		p.key("body")
+		p.stmt(s.Stmt)
+
+	case *js_ast.SIf:
+		p.node("IfStatement", loc)
+		p.key("test")
+		p.expr(s.Test)
+		p.key("consequent")
+		p.stmt(s.Yes)
+		p.key("alternate")
+		p.stmtOrNull(s.NoOrNil)
+
+	case *js_ast.SFor:
+		p.node("ForStatement", loc)
+		p.key("init")
+		p.forInit(s.InitOrNil, false)
+		p.key("test")
+		p.exprOrNull(s.TestOrNil)
+		p.key("update")
+		p.exprOrNull(s.UpdateOrNil)
+		p.key("body")
+		p.stmt(s.Body)
+
+	case *js_ast.SForIn:
+		p.node("ForInStatement", loc)
+		p.key("left")
+		p.forInit(s.Init, true)
+		p.key("right")
+		p.expr(s.Value)
+		p.key("body")
+		p.stmt(s.Body)
+
+	case *js_ast.SForOf:
+		p.node("ForOfStatement", loc)
+		p.key("await")
+		p.bool(s.Await.Len > 0)
+		p.key("left")
+		p.forInit(s.Init, true)
+		p.key("right")
+		p.expr(s.Value)
+		p.key("body")
+		p.stmt(s.Body)
+
+	case *js_ast.SDoWhile:
+		p.node("DoWhileStatement", loc)
+		p.key("body")
+		p.stmt(s.Body)
+		p.key("test")
+		p.expr(s.Test)
+
+	case *js_ast.SWhile:
+		p.node("WhileStatement", loc)
+		p.key("test")
+		p.expr(s.Test)
+		p.key("body")
+		p.stmt(s.Body)
+
+	case *js_ast.SWith:
+		p.node("WithStatement", loc)
+		p.key("object")
+		p.expr(s.Value)
+		p.key("body")
+		p.stmt(s.Body)
+
+	case *js_ast.STry:
+		p.node("TryStatement", loc)
+		p.key("block")
+		p.block(s.BlockLoc, s.Block.Stmts)
+		p.key("handler")
+		if s.Catch != nil {
+			p.node("CatchClause", s.Catch.Loc)
+			p.key("param")
+			p.bindingOrNull(s.Catch.BindingOrNil)
+			p.key("body")
+			p.block(s.Catch.BlockLoc, s.Catch.Block.Stmts)
+			p.js = append(p.js, '}')
+		} else {
+			p.null()
+		}
+		p.key("finalizer")
+		if s.Finally != nil {
+			p.block(s.Finally.Loc, s.Finally.Block.Stmts)
+		} else {
+			p.null()
+		}
+
+	case *js_ast.SSwitch:
+		p.node("SwitchStatement", loc)
+		p.key("discriminant")
+		p.expr(s.Test)
+		p.key("cases")
+		p.js = append(p.js, '[')
+		for i, c := range s.Cases {
+			if i > 0 {
+				p.js = append(p.js, ',')
+			}
+			p.node("SwitchCase", c.Loc)
+			p.key("test")
+			p.exprOrNull(c.ValueOrNil)
+			p.key("consequent")
+			p.stmts(c.Body)
+			p.js = append(p.js, '}')
+		}
+		p.js = append(p.js, ']')
+
+	case *js_ast.SImport:
+		p.node("ImportDeclaration", loc)
+		p.key("specifiers")
+		p.js = append(p.js, '[')
+		isFirst := true
+		if s.DefaultName != nil {
+			isFirst = false
+			p.node("ImportDefaultSpecifier", s.DefaultName.Loc)
+			p.key("local")
+			p.identifierOrNull(s.DefaultName)
+			p.js = append(p.js, '}')
+		}
+		if s.StarNameLoc != nil {
+			if !isFirst {
+				p.js = append(p.js, ',')
+			}
+			isFirst = false
+			p.node("ImportNamespaceSpecifier", *s.StarNameLoc)
+			p.key("local")
+			p.identifier(*s.StarNameLoc, p.name(s.NamespaceRef))
+			p.js = append(p.js, '}')
+		}
+		if s.Items != nil {
+			for _, item := range *s.Items {
+				if !isFirst {
+					p.js = append(p.js, ',')
+				}
+				isFirst = false
+				p.node("ImportSpecifier", item.AliasLoc)
+				p.key("imported")
+				p.moduleExportName(item.AliasLoc, item.Alias)
+				p.key("local")
+				p.identifierOrNull(&item.Name)
+				p.js = append(p.js, '}')
+			}
+		}
+		p.js = append(p.js, ']')
+		p.key("source")
+		p.importPath(loc, s.ImportRecordIndex)
+
+	case *js_ast.SReturn:
+		p.node("ReturnStatement", loc)
+		p.key("argument")
+		p.exprOrNull(s.ValueOrNil)
+
+	case *js_ast.SThrow:
+		p.node("ThrowStatement", loc)
+		p.key("argument")
+		p.expr(s.Value)
+
+	case *js_ast.SLocal:
+		p.exportWrapper(loc, s.IsExport)
+		p.node("VariableDeclaration", loc)
+		p.key("kind")
+		p.string(localKindNames[s.Kind])
+		p.key("declarations")
+		p.js = append(p.js, '[')
+		for i, decl := range s.Decls {
+			if i > 0 {
+				p.js = append(p.js, ',')
+			}
+			p.node("VariableDeclarator", decl.Binding.Loc)
+			p.key("id")
+			p.binding(decl.Binding)
+			p.key("init")
+			p.exprOrNull(decl.ValueOrNil)
+			p.js = append(p.js, '}')
+		}
+		p.js = append(p.js, "]}"...)
+		p.endExportWrapper(s.IsExport)
+		return
+
+	case *js_ast.SBreak:
+		p.node("BreakStatement", loc)
+		p.key("label")
+		p.identifierOrNull(s.Label)
+
+	case *js_ast.SContinue:
+		p.node("ContinueStatement", loc)
+		p.key("label")
+		p.identifierOrNull(s.Label)
+
+	default:
+		p.node("EmptyStatement", loc)
+	}
+
+	p.js = append(p.js, '}')
+}
+
+var localKindNames = []string{
+	js_ast.LocalVar:        "var",
+	js_ast.LocalLet:        "let",
+	js_ast.LocalConst:      "const",
+	js_ast.LocalUsing:      "using",
+	js_ast.LocalAwaitUsing: "await using",
+}
+
+// Export names can be strings: "export { x as 'y' }"
+func (p *astJSONPrinter) moduleExportName(loc logger.Loc, name string) {
+	if js_ast.IsIdentifier(name) {
+		p.identifier(loc, name)
+	} else {
+		p.stringLiteral(loc, name)
+	}
+}
+
+func (p *astJSONPrinter) exportSpecifiers(items []js_ast.ClauseItem, isReExport bool) {
+	p.js = append(p.js, '[')
+	for i, item := range items {
+		if i > 0 {
+			p.js = append(p.js, ',')
+		}
+		p.node("ExportSpecifier", item.Name.Loc)
+		p.key("local")
+		if isReExport {
+			p.moduleExportName(item.Name.Loc, item.OriginalName)
+		} else {
+			p.identifier(item.Name.Loc, p.name(item.Name.Ref))
+		}
+		p.key("exported")
+		p.moduleExportName(item.AliasLoc, item.Alias)
+		p.js = append(p.js, '}')
+	}
+	p.js = append(p.js, ']')
+}
+
+func (p *astJSONPrinter) fnParams(args []js_ast.Arg, hasRestArg bool) {
+	p.js = append(p.js, '[')
+	for i, arg := range args {
+		if i > 0 {
+			p.js = append(p.js, ',')
+		}
+		if hasRestArg && i+1 == len(args) {
+			p.node("RestElement", arg.Binding.Loc)
+			p.key("argument")
+			p.binding(arg.Binding)
+			p.js = append(p.js, '}')
+		} else {
+			p.bindingWithDefault(arg.Binding, arg.DefaultOrNil)
+		}
+	}
+	p.js = append(p.js, ']')
+}
+
+func (p *astJSONPrinter) fn(loc logger.Loc, kind string, fn *js_ast.Fn) {
+	p.node(kind, loc)
+	p.key("id")
+	p.identifierOrNull(fn.Name)
+	p.key("params")
+	p.fnParams(fn.Args, fn.HasRestArg)
+	p.key("body")
+	p.block(fn.Body.Loc, fn.Body.Block.Stmts)
+	p.key("async")
+	p.bool(fn.IsAsync)
+	p.key("generator")
+	p.bool(fn.IsGenerator)
+	p.js = append(p.js, '}')
+}
+
+func (p *astJSONPrinter) decorators(decorators []js_ast.Expr) {
+	if len(decorators) > 0 {
+		p.key("decorators")
+		p.js = append(p.js, '[')
+		for i, decorator := range decorators {
+			if i > 0 {
+				p.js = append(p.js, ',')
+			}
+			p.node("Decorator", decorator.Loc)
+			p.key("expression")
+			p.expr(decorator)
+			p.js = append(p.js, '}')
+		}
+		p.js = append(p.js, ']')
+	}
+}
+
+func (p *astJSONPrinter) class(loc logger.Loc, kind string, class *js_ast.Class) {
+	p.node(kind, loc)
+	p.key("id")
+	p.identifierOrNull(class.Name)
+	p.key("superClass")
+	p.exprOrNull(class.ExtendsOrNil)
+	p.decorators(class.Decorators)
+	p.key("body")
+	p.node("ClassBody", class.BodyLoc)
+	p.key("body")
+	p.js = append(p.js, '[')
+	isFirst := true
+	for _, property := range class.Properties {
+		if property.Kind == js_ast.PropertyDeclare {
+			continue
+		}
+		if !isFirst {
+			p.js = append(p.js, ',')
+		}
+		isFirst = false
+		p.classMember(property)
+	}
+	p.js = append(p.js, "]}}"...)
+}
+
+func (p *astJSONPrinter) classMember(property js_ast.Property) {
+	if property.Kind == js_ast.PropertyClassStaticBlock {
+		p.node("StaticBlock", property.Loc)
+		p.key("body")
+		p.stmts(property.ClassStaticBlock.Block.Stmts)
+		p.js = append(p.js, '}')
+		return
+	}
+
+	isComputed := property.Flags.Has(js_ast.PropertyIsComputed)
+	isStatic := property.Flags.Has(js_ast.PropertyIsStatic)
+
+	if property.Flags.Has(js_ast.PropertyIsMethod) {
+		kind := "method"
+		switch property.Kind {
+		case js_ast.PropertyGet:
+			kind = "get"
+		case js_ast.PropertySet:
+			kind = "set"
+		default:
+			if str, ok := property.Key.Data.(*js_ast.EString); ok && !isComputed && !isStatic &&
+				helpers.UTF16EqualsString(str.Value, "constructor") {
+				kind = "constructor"
+			}
+		}
+		p.node("MethodDefinition", property.Loc)
+		p.key("key")
+		p.propertyKey(property.Key, isComputed, property.Flags.Has(js_ast.PropertyPreferQuotedKey))
+		p.key("value")
+		p.exprOrNull(property.ValueOrNil)
+		p.key("kind")
+		p.string(kind)
+		p.key("computed")
+		p.bool(isComputed)
+		p.key("static")
+		p.bool(isStatic)
+		p.decorators(property.Decorators)
+		p.js = append(p.js, '}')
+		return
+	}
+
+	if property.Kind == js_ast.PropertyAutoAccessor {
+		p.node("AccessorProperty", property.Loc)
+	} else {
+		p.node("PropertyDefinition", property.Loc)
+	}
+	p.key("key")
+	p.propertyKey(property.Key, isComputed, property.Flags.Has(js_ast.PropertyPreferQuotedKey))
+	p.key("value")
+	p.exprOrNull(property.InitializerOrNil)
+	p.key("computed")
+	p.bool(isComputed)
+	p.key("static")
+	p.bool(isStatic)
+	p.decorators(property.Decorators)
+	p.js = append(p.js, '}')
+}
+
+// Non-computed keys that are valid identifiers and weren't quoted are
+// represented as identifiers instead of string literals
+func (p *astJSONPrinter) propertyKey(key js_ast.Expr, isComputed bool, preferQuotedKey bool) {
+	if !isComputed {
+		switch k := key.Data.(type) {
+		case *js_ast.EString:
+			if !preferQuotedKey && js_ast.IsIdentifierUTF16(k.Value) {
+				p.identifier(key.Loc, helpers.UTF16ToString(k.Value))
+				return
+			}
+
+		case *js_ast.EMangledProp:
+			p.identifier(key.Loc, p.name(k.Ref))
+			return
+		}
+	}
+	p.expr(key)
+}
+
+func (p *astJSONPrinter) exprOrNull(expr js_ast.Expr) {
+	if expr.Data == nil {
+		p.null()
+	} else {
+		p.expr(expr)
+	}
+}
+
+func (p *astJSONPrinter) exprs(exprs []js_ast.Expr) {
+	p.js = append(p.js, '[')
+	for i, expr := range exprs {
+		if i > 0 {
+			p.js = append(p.js, ',')
+		}
+		p.expr(expr)
+	}
+	p.js = append(p.js, ']')
+}
+
+func (p *astJSONPrinter) expr(expr js_ast.Expr) {
+	p.exprInChain(expr, false)
+}
+
+// ESTree wraps each optional chain in a "ChainExpression" node. The chain
+// starts at the outermost node that is part of it, so nodes inside of the
+// chain are printed with "isInsideChain" set to avoid wrapping them again.
+func (p *astJSONPrinter) exprInChain(expr js_ast.Expr, isInsideChain bool) {
+	if !isInsideChain && optionalChainOf(expr) != js_ast.OptionalChainNone {
+		p.node("ChainExpression", expr.Loc)
+		p.key("expression")
+		p.exprInChain(expr, true)
+		p.js = append(p.js, '}')
+		return
+	}
+
+	loc := expr.Loc
+
+	switch e := expr.Data.(type) {
+	case *js_ast.EMissing:
+		p.null()
+		return
+
+	case *js_ast.EArray:
+		p.node("ArrayExpression", loc)
+		p.key("elements")
+		p.exprs(e.Items)
+
+	case *js_ast.EUnary:
+		if e.Op.UnaryAssignTarget() != js_ast.AssignTargetNone {
+			p.node("UpdateExpression", loc)
+		} else {
+			p.node("UnaryExpression", loc)
+		}
+		p.key("operator")
+		p.string(js_ast.OpTable[e.Op].Text)
+		p.key("prefix")
+		p.bool(e.Op.IsPrefix())
+		p.key("argument")
+		p.expr(e.Value)
+
+	case *js_ast.EBinary:
+		switch {
+		case e.Op == js_ast.BinOpComma:
+			p.node("SequenceExpression", loc)
+			p.key("expressions")
+			p.js = append(p.js, '[')
+			p.sequence(expr, true)
+			p.js = append(p.js, ']')
+
+		case e.Op.BinaryAssignTarget() != js_ast.AssignTargetNone:
+			p.node("AssignmentExpression", loc)
+			p.key("operator")
+			p.string(js_ast.OpTable[e.Op].Text)
+			p.key("left")
+			p.pattern(e.Left)
+			p.key("right")
+			p.expr(e.Right)
+
+		default:
+			if e.Op == js_ast.BinOpLogicalOr || e.Op == js_ast.BinOpLogicalAnd || e.Op == js_ast.BinOpNullishCoalescing {
+				p.node("LogicalExpression", loc)
+			} else {
+				p.node("BinaryExpression", loc)
+			}
+			p.key("operator")
+			p.string(js_ast.OpTable[e.Op].Text)
+			p.key("left")
+			p.expr(e.Left)
+			p.key("right")
+			p.expr(e.Right)
+		}
+
+	case *js_ast.EBoolean:
+		p.node("Literal", loc)
+		p.key("value")
+		p.bool(e.Value)
+
+	case *js_ast.ENull:
+		p.node("Literal", loc)
+		p.key("value")
+		p.null()
+
+	case *js_ast.EUndefined:
+		p.node("UnaryExpression", loc)
+		p.key("operator")
+		p.string("void")
+		p.key("prefix")
+		p.bool(true)
+		p.key("argument")
+		p.node("Literal", loc)
+		p.key("value")
+		p.js = append(p.js, "0}"...)
+
+	case *js_ast.ENumber:
+		p.number(loc, e.Value)
+		return
+
+	case *js_ast.EBigInt:
+		p.node("Literal", loc)
+		p.key("value")
+		p.null()
+		p.key("bigint")
+		p.string(e.Value)
+
+	case *js_ast.EString:
+		p.stringLiteral(loc, helpers.UTF16ToString(e.Value))
+		return
+
+	case *js_ast.ERegExp:
+		slash := strings.LastIndexByte(e.Value, '/')
+		p.node("Literal", loc)
+		p.key("value")
+		p.null()
+		p.key("regex")
+		p.js = append(p.js, "{\"pattern\":"...)
+		p.string(e.Value[1:slash])
+		p.js = append(p.js, ",\"flags\":"...)
+		p.string(e.Value[slash+1:])
+		p.js = append(p.js, '}')
+
+	case *js_ast.ESuper:
+		p.node("Super", loc)
+
+	case *js_ast.EThis:
+		p.node("ThisExpression", loc)
+
+	case *js_ast.ENewTarget:
+		p.metaProperty(loc, "new", "target")
+		return
+
+	case *js_ast.EImportMeta:
+		p.metaProperty(loc, "import", "meta")
+		return
+
+	case *js_ast.ENew:
+		p.node("NewExpression", loc)
+		p.key("callee")
+		p.expr(e.Target)
+		p.key("arguments")
+		p.exprs(e.Args)
+
+	case *js_ast.ECall:
+		p.node("CallExpression", loc)
+		p.key("callee")
+		p.exprInChain(e.Target, e.OptionalChain != js_ast.OptionalChainNone)
+		p.key("arguments")
+		p.exprs(e.Args)
+		p.key("optional")
+		p.bool(e.OptionalChain == js_ast.OptionalChainStart)
+
+	case *js_ast.EDot:
+		p.node("MemberExpression", loc)
+		p.key("object")
+		p.exprInChain(e.Target, e.OptionalChain != js_ast.OptionalChainNone)
+		p.key("property")
+		p.identifier(e.NameLoc, e.Name)
+		p.key("computed")
+		p.bool(false)
+		p.key("optional")
+		p.bool(e.OptionalChain == js_ast.OptionalChainStart)
+
+	case *js_ast.EIndex:
+		_, isPrivate := e.Index.Data.(*js_ast.EPrivateIdentifier)
+		p.node("MemberExpression", loc)
+		p.key("object")
+		p.exprInChain(e.Target, e.OptionalChain != js_ast.OptionalChainNone)
+		p.key("property")
+		p.expr(e.Index)
+		p.key("computed")
+		p.bool(!isPrivate)
+		p.key("optional")
+		p.bool(e.OptionalChain == js_ast.OptionalChainStart)
+
+	case *js_ast.EArrow:
+		p.node("ArrowFunctionExpression", loc)
+		p.key("id")
+		p.null()
+		p.key("params")
+		p.fnParams(e.Args, e.HasRestArg)
+		p.key("body")
+		isExpression := false
+		if e.PreferExpr && len(e.Body.Block.Stmts) == 1 {
+			if ret, ok := e.Body.Block.Stmts[0].Data.(*js_ast.SReturn); ok && ret.ValueOrNil.Data != nil {
+				isExpression = true
+				p.expr(ret.ValueOrNil)
+			}
+		}
+		if !isExpression {
+			p.block(e.Body.Loc, e.Body.Block.Stmts)
+		}
+		p.key("expression")
+		p.bool(isExpression)
+		p.key("async")
+		p.bool(e.IsAsync)
+		p.key("generator")
+		p.bool(false)
+
+	case *js_ast.EFunction:
+		p.fn(loc, "FunctionExpression", &e.Fn)
+		return
+
+	case *js_ast.EClass:
+		p.class(loc, "ClassExpression", &e.Class)
+		return
+
+	case *js_ast.EIdentifier:
+		p.identifier(loc, p.name(e.Ref))
+		return
+
+	case *js_ast.EImportIdentifier:
+		p.identifier(loc, p.name(e.Ref))
+		return
+
+	case *js_ast.EPrivateIdentifier:
+		p.node("PrivateIdentifier", loc)
+		p.key("name")
+		p.string(strings.TrimPrefix(p.name(e.Ref), "#"))
+
+	case *js_ast.EMangledProp:
+		p.stringLiteral(loc, p.name(e.Ref))
+		return
+
+	case *js_ast.EObject:
+		p.node("ObjectExpression", loc)
+		p.key("properties")
+		p.js = append(p.js, '[')
+		for i, property := range e.Properties {
+			if i > 0 {
+				p.js = append(p.js, ',')
+			}
+			p.objectProperty(property, false)
+		}
+		p.js = append(p.js, ']')
+
+	case *js_ast.ESpread:
+		p.node("SpreadElement", loc)
+		p.key("argument")
+		p.expr(e.Value)
+
+	case *js_ast.ETemplate:
+		if e.TagOrNil.Data != nil {
+			p.node("TaggedTemplateExpression", loc)
+			p.key("tag")
+			p.expr(e.TagOrNil)
+			p.key("quasi")
+			p.template(e.HeadLoc, e)
+		} else {
+			p.template(loc, e)
+			return
+		}
+
+	case *js_ast.EInlinedEnum:
+		p.expr(e.Value)
+		return
+
+	case *js_ast.EAwait:
+		p.node("AwaitExpression", loc)
+		p.key("argument")
+		p.expr(e.Value)
+
+	case *js_ast.EYield:
+		p.node("YieldExpression", loc)
+		p.key("argument")
+		p.exprOrNull(e.ValueOrNil)
+		p.key("delegate")
+		p.bool(e.IsStar)
+
+	case *js_ast.EIf:
+		p.node("ConditionalExpression", loc)
+		p.key("test")
+		p.expr(e.Test)
+		p.key("consequent")
+		p.expr(e.Yes)
+		p.key("alternate")
+		p.expr(e.No)
+
+	case *js_ast.ERequireString:
+		p.node("CallExpression", loc)
+		p.key("callee")
+		p.identifier(loc, "require")
+		p.key("arguments")
+		p.js = append(p.js, '[')
+		p.importPath(loc, e.ImportRecordIndex)
+		p.js = append(p.js, ']')
+		p.key("optional")
+		p.bool(false)
+
+	case *js_ast.ERequireResolveString:
+		p.node("CallExpression", loc)
+		p.key("callee")
+		p.node("MemberExpression", loc)
+		p.key("object")
+		p.identifier(loc, "require")
+		p.key("property")
+		p.identifier(loc, "resolve")
+		p.js = append(p.js, ",\"computed\":false,\"optional\":false}"...)
+		p.key("arguments")
+		p.js = append(p.js, '[')
+		p.importPath(loc, e.ImportRecordIndex)
+		p.js = append(p.js, ']')
+		p.key("optional")
+		p.bool(false)
+
+	case *js_ast.EImportString:
+		p.node("ImportExpression", loc)
+		p.key("source")
+		p.importPath(loc, e.ImportRecordIndex)
+		p.key("options")
+		p.null()
+
+	case *js_ast.EImportPath:
+		p.importPath(loc, e.ImportRecordIndex)
+		return
+
+	case *js_ast.EImportCall:
+		p.node("ImportExpression", loc)
+		p.key("source")
+		p.expr(e.Expr)
+		p.key("options")
+		p.exprOrNull(e.OptionsOrNil)
+
+	case *js_ast.EJSXElement:
+		p.jsxElement(loc, e)
+		return
+
+	default:
+		p.null()
+		return
+	}
+
+	p.js = append(p.js, '}')
+}
+
+func optionalChainOf(expr js_ast.Expr) js_ast.OptionalChain {
+	switch e := expr.Data.(type) {
+	case *js_ast.ECall:
+		return e.OptionalChain
+	case *js_ast.EDot:
+		return e.OptionalChain
+	case *js_ast.EIndex:
+		return e.OptionalChain
+	}
+	return js_ast.OptionalChainNone
+}
+
+// ESTree flattens nested comma operators into a single list
+func (p *astJSONPrinter) sequence(expr js_ast.Expr, isFirst bool) bool {
+	if e, ok := expr.Data.(*js_ast.EBinary); ok && e.Op == js_ast.BinOpComma {
+		isFirst = p.sequence(e.Left, isFirst)
+		return p.sequence(e.Right, isFirst)
+	}
+	if !isFirst {
+		p.js = append(p.js, ',')
+	}
+	p.expr(expr)
+	return false
+}
+
+func (p *astJSONPrinter) number(loc logger.Loc, value float64) {
+	switch {
+	case math.IsNaN(value):
+		p.identifier(loc, "NaN")
+
+	case math.IsInf(value, 1):
+		p.identifier(loc, "Infinity")
+
+	case value < 0 || (value == 0 && math.Signbit(value)):
+		p.node("UnaryExpression", loc)
+		p.key("operator")
+		p.string("-")
+		p.key("prefix")
+		p.bool(true)
+		p.key("argument")
+		p.number(loc, -value)
+		p.js = append(p.js, '}')
+
+	default:
+		p.node("Literal", loc)
+		p.key("value")
+		p.js = strconv.AppendFloat(p.js, value, 'g', -1, 64)
+		p.js = append(p.js, '}')
+	}
+}
+
+func (p *astJSONPrinter) metaProperty(loc logger.Loc, meta string, property string) {
+	p.node("MetaProperty", loc)
+	p.key("meta")
+	p.identifier(loc, meta)
+	p.key("property")
+	p.identifier(loc, property)
+	p.js = append(p.js, '}')
+}
+
+func (p *astJSONPrinter) template(loc logger.Loc, e *js_ast.ETemplate) {
+	isTagged := e.TagOrNil.Data != nil
+	p.node("TemplateLiteral", loc)
+	p.key("quasis")
+	p.js = append(p.js, '[')
+	p.templateElement(e.HeadLoc, isTagged, e.HeadRaw, e.HeadCooked, len(e.Parts) == 0)
+	for i, part := range e.Parts {
+		p.js = append(p.js, ',')
+		p.templateElement(part.TailLoc, isTagged, part.TailRaw, part.TailCooked, i+1 == len(e.Parts))
+	}
+	p.js = append(p.js, ']')
+	p.key("expressions")
+	p.js = append(p.js, '[')
+	for i, part := range e.Parts {
+		if i > 0 {
+			p.js = append(p.js, ',')
+		}
+		p.expr(part.Value)
+	}
+	p.js = append(p.js, "]}"...)
+}
+
+func (p *astJSONPrinter) templateElement(loc logger.Loc, isTagged bool, raw string, cooked []uint16, isTail bool) {
+	p.node("TemplateElement", loc)
+	p.key("value")
+	p.js = append(p.js, "{\"raw\":"...)
+	if isTagged {
+		p.string(raw)
+		p.js = append(p.js, ",\"cooked\":null}"...)
+	} else {
+		text := helpers.UTF16ToString(cooked)
+		p.string(templateRawFromCooked(text))
+		p.js = append(p.js, ",\"cooked\":"...)
+		p.string(text)
+		p.js = append(p.js, '}')
+	}
+	p.key("tail")
+	p.bool(isTail)
+	p.js = append(p.js, '}')
+}
+
+// Only the cooked value is kept for untagged templates, so this escapes the
+// characters that would otherwise end the template or start a substitution
+func templateRawFromCooked(text string) string {
+	var sb strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' || c == '`':
+			sb.WriteByte('\\')
+		case c == '$' && i+1 < len(text) && text[i+1] == '{':
+			sb.WriteByte('\\')
+		case c == '\r':
+			sb.WriteString("\\r")
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+func (p *astJSONPrinter) objectProperty(property js_ast.Property, isPattern bool) {
+	if property.Kind == js_ast.PropertySpread {
+		if isPattern {
+			p.node("RestElement", property.Loc)
+			p.key("argument")
+			p.pattern(property.ValueOrNil)
+		} else {
+			p.node("SpreadElement", property.Loc)
+			p.key("argument")
+			p.expr(property.ValueOrNil)
+		}
+		p.js = append(p.js, '}')
+		return
+	}
+
+	isComputed := property.Flags.Has(js_ast.PropertyIsComputed)
+	kind := "init"
+	switch property.Kind {
+	case js_ast.PropertyGet:
+		kind = "get"
+	case js_ast.PropertySet:
+		kind = "set"
+	}
+
+	p.node("Property", property.Loc)
+	p.key("key")
+	p.propertyKey(property.Key, isComputed, property.Flags.Has(js_ast.PropertyPreferQuotedKey))
+	p.key("value")
+	if isPattern {
+		p.patternWithDefault(property.ValueOrNil, property.InitializerOrNil)
+	} else {
+		p.expr(property.ValueOrNil)
+	}
+	p.key("kind")
+	p.string(kind)
+	p.key("method")
+	p.bool(property.Flags.Has(js_ast.PropertyIsMethod) && kind == "init")
+	p.key("shorthand")
+	p.bool(property.Flags.Has(js_ast.PropertyWasShorthand))
+	p.key("computed")
+	p.bool(isComputed)
+	p.js = append(p.js, '}')
+}
+
+// Assignment targets are stored as expressions, but ESTree uses patterns for
+// them. This converts array and object literals into the equivalent patterns.
+func (p *astJSONPrinter) pattern(expr js_ast.Expr) {
+	switch e := expr.Data.(type) {
+	case *js_ast.EArray:
+		p.node("ArrayPattern", expr.Loc)
+		p.key("elements")
+		p.js = append(p.js, '[')
+		for i, item := range e.Items {
+			if i > 0 {
+				p.js = append(p.js, ',')
+			}
+			switch it := item.Data.(type) {
+			case *js_ast.EMissing:
+				p.null()
+			case *js_ast.ESpread:
+				p.node("RestElement", item.Loc)
+				p.key("argument")
+				p.pattern(it.Value)
+				p.js = append(p.js, '}')
+			case *js_ast.EBinary:
+				if it.Op == js_ast.BinOpAssign {
+					p.patternWithDefault(it.Left, it.Right)
+				} else {
+					p.expr(item)
+				}
+			default:
+				p.pattern(item)
+			}
+		}
+		p.js = append(p.js, "]}"...)
+
+	case *js_ast.EObject:
+		p.node("ObjectPattern", expr.Loc)
+		p.key("properties")
+		p.js = append(p.js, '[')
+		for i, property := range e.Properties {
+			if i > 0 {
+				p.js = append(p.js, ',')
+			}
+			p.objectProperty(property, true)
+		}
+		p.js = append(p.js, "]}"...)
+
+	default:
+		p.expr(expr)
+	}
+}
+
+func (p *astJSONPrinter) patternWithDefault(expr js_ast.Expr, defaultOrNil js_ast.Expr) {
+	if defaultOrNil.Data == nil {
+		p.pattern(expr)
+		return
+	}
+	p.node("AssignmentPattern", expr.Loc)
+	p.key("left")
+	p.pattern(expr)
+	p.key("right")
+	p.expr(defaultOrNil)
+	p.js = append(p.js, '}')
+}
+
+func (p *astJSONPrinter) bindingOrNull(binding js_ast.Binding) {
+	if binding.Data == nil {
+		p.null()
+	} else {
+		p.binding(binding)
+	}
+}
+
+func (p *astJSONPrinter) bindingWithDefault(binding js_ast.Binding, defaultOrNil js_ast.Expr) {
+	if defaultOrNil.Data == nil {
+		p.binding(binding)
+		return
+	}
+	p.node("AssignmentPattern", binding.Loc)
+	p.key("left")
+	p.binding(binding)
+	p.key("right")
+	p.expr(defaultOrNil)
+	p.js = append(p.js, '}')
+}
+
+func (p *astJSONPrinter) binding(binding js_ast.Binding) {
+	switch b := binding.Data.(type) {
+	case *js_ast.BMissing:
+		p.null()
+
+	case *js_ast.BIdentifier:
+		p.identifier(binding.Loc, p.name(b.Ref))
+
+	case *js_ast.BArray:
+		p.node("ArrayPattern", binding.Loc)
+		p.key("elements")
+		p.js = append(p.js, '[')
+		for i, item := range b.Items {
+			if i > 0 {
+				p.js = append(p.js, ',')
+			}
+			if b.HasSpread && i+1 == len(b.Items) {
+				p.node("RestElement", item.Loc)
+				p.key("argument")
+				p.binding(item.Binding)
+				p.js = append(p.js, '}')
+			} else {
+				p.bindingWithDefault(item.Binding, item.DefaultValueOrNil)
+			}
+		}
+		p.js = append(p.js, "]}"...)
+
+	case *js_ast.BObject:
+		p.node("ObjectPattern", binding.Loc)
+		p.key("properties")
+		p.js = append(p.js, '[')
+		for i, property := range b.Properties {
+			if i > 0 {
+				p.js = append(p.js, ',')
+			}
+			if property.IsSpread {
+				p.node("RestElement", property.Loc)
+				p.key("argument")
+				p.binding(property.Value)
+				p.js = append(p.js, '}')
+				continue
+			}
+			p.node("Property", property.Loc)
+			p.key("key")
+			p.propertyKey(property.Key, property.IsComputed, property.PreferQuotedKey)
+			p.key("value")
+			p.bindingWithDefault(property.Value, property.DefaultValueOrNil)
+			p.key("kind")
+			p.string("init")
+			p.key("method")
+			p.bool(false)
+			p.key("shorthand")
+			p.bool(isShorthandPropertyBinding(property, p.tree.Symbols))
+			p.key("computed")
+			p.bool(property.IsComputed)
+			p.js = append(p.js, '}')
+		}
+		p.js = append(p.js, "]}"...)
+
+	default:
+		p.null()
+	}
+}
+
+// Property bindings don't remember whether they were written in shorthand
+// form, so this treats "{ a: a }" the same as "{ a }"
+func isShorthandPropertyBinding(property js_ast.PropertyBinding, symbols []js_ast.Symbol) bool {
+	if property.IsComputed {
+		return false
+	}
+	key, ok := property.Key.Data.(*js_ast.EString)
+	if !ok {
+		return false
+	}
+	id, ok := property.Value.Data.(*js_ast.BIdentifier)
+	return ok && int(id.Ref.InnerIndex) < len(symbols) && helpers.UTF16EqualsString(key.Value, symbols[id.Ref.InnerIndex].OriginalName)
+}
+
+// JSX elements are only present when the "jsx" setting is "preserve"
+func (p *astJSONPrinter) jsxElement(loc logger.Loc, e *js_ast.EJSXElement) {
+	isFragment := e.TagOrNil.Data == nil
+	isSelfClosing := !isFragment && len(e.Children) == 0
+
+	if isFragment {
+		p.node("JSXFragment", loc)
+		p.key("openingFragment")
+		p.node("JSXOpeningFragment", loc)
+		p.js = append(p.js, '}')
+	} else {
+		p.node("JSXElement", loc)
+		p.key("openingElement")
+		p.node("JSXOpeningElement", loc)
+		p.key("name")
+		p.jsxName(e.TagOrNil)
+		p.key("attributes")
+		p.js = append(p.js, '[')
+		for i, property := range e.Properties {
+			if i > 0 {
+				p.js = append(p.js, ',')
+			}
+			p.jsxAttribute(property)
+		}
+		p.js = append(p.js, ']')
+		p.key("selfClosing")
+		p.bool(isSelfClosing)
+		p.js = append(p.js, '}')
+	}
+
+	p.key("children")
+	p.js = append(p.js, '[')
+	for i, child := range e.Children {
+		if i > 0 {
+			p.js = append(p.js, ',')
+		}
+		switch c := child.Data.(type) {
+		case *js_ast.EString:
+			p.node("JSXText", child.Loc)
+			p.key("value")
+			p.string(helpers.UTF16ToString(c.Value))
+			p.js = append(p.js, '}')
+
+		case *js_ast.EJSXElement:
+			p.jsxElement(child.Loc, c)
+
+		case *js_ast.ESpread:
+			p.node("JSXSpreadChild", child.Loc)
+			p.key("expression")
+			p.expr(c.Value)
+			p.js = append(p.js, '}')
+
+		default:
+			p.jsxExpressionContainer(child)
+		}
+	}
+	p.js = append(p.js, ']')
+
+	if isFragment {
+		p.key("closingFragment")
+		p.node("JSXClosingFragment", e.CloseLoc)
+		p.js = append(p.js, '}')
+	} else {
+		p.key("closingElement")
+		if isSelfClosing {
+			p.null()
+		} else {
+			p.node("JSXClosingElement", e.CloseLoc)
+			p.key("name")
+			p.jsxName(e.TagOrNil)
+			p.js = append(p.js, '}')
+		}
+	}
+
+	p.js = append(p.js, '}')
+}
+
+func (p *astJSONPrinter) jsxName(tag js_ast.Expr) {
+	switch t := tag.Data.(type) {
+	case *js_ast.EString:
+		name := helpers.UTF16ToString(t.Value)
+		if colon := strings.IndexByte(name, ':'); colon != -1 {
+			p.node("JSXNamespacedName", tag.Loc)
+			p.key("namespace")
+			p.jsxIdentifier(tag.Loc, name[:colon])
+			p.key("name")
+			p.jsxIdentifier(tag.Loc, name[colon+1:])
+			p.js = append(p.js, '}')
+		} else {
+			p.jsxIdentifier(tag.Loc, name)
+		}
+
+	case *js_ast.EDot:
+		p.node("JSXMemberExpression", tag.Loc)
+		p.key("object")
+		p.jsxName(t.Target)
+		p.key("property")
+		p.jsxIdentifier(t.NameLoc, t.Name)
+		p.js = append(p.js, '}')
+
+	case *js_ast.EIdentifier:
+		p.jsxIdentifier(tag.Loc, p.name(t.Ref))
+
+	case *js_ast.EImportIdentifier:
+		p.jsxIdentifier(tag.Loc, p.name(t.Ref))
+
+	case *js_ast.EThis:
+		p.jsxIdentifier(tag.Loc, "this")
+
+	default:
+		p.expr(tag)
+	}
+}
+
+func (p *astJSONPrinter) jsxIdentifier(loc logger.Loc, name string) {
+	p.node("JSXIdentifier", loc)
+	p.key("name")
+	p.string(name)
+	p.js = append(p.js, '}')
+}
+
+func (p *astJSONPrinter) jsxAttribute(property js_ast.Property) {
+	if property.Kind == js_ast.PropertySpread {
+		p.node("JSXSpreadAttribute", property.Loc)
+		p.key("argument")
+		p.expr(property.ValueOrNil)
+		p.js = append(p.js, '}')
+		return
+	}
+
+	p.node("JSXAttribute", property.Loc)
+	p.key("name")
+	p.jsxName(property.Key)
+	p.key("value")
+	if property.Flags.Has(js_ast.PropertyWasShorthand) {
+		// "<div disabled />"
+		p.null()
+	} else if str, ok := property.ValueOrNil.Data.(*js_ast.EString); ok {
+		p.stringLiteral(property.ValueOrNil.Loc, helpers.UTF16ToString(str.Value))
+	} else {
+		p.jsxExpressionContainer(property.ValueOrNil)
+	}
+	p.js = append(p.js, '}')
+}
+
+func (p *astJSONPrinter) jsxExpressionContainer(expr js_ast.Expr) {
+	p.node("JSXExpressionContainer", expr.Loc)
+	p.key("expression")
+	if expr.Data == nil {
+		p.node("JSXEmptyExpression", expr.Loc)
+		p.js = append(p.js, '}')
+	} else {
+		p.expr(expr)
+	}
+	p.js = append(p.js, '}')
+}
@@ -334,6 +334,8 @@ type printer struct {
 	renamer                renamer.Renamer
 	importRecords          []ast.ImportRecord
 	callTarget             js_ast.E
+	systemExportTarget     js_ast.E
+	systemPatternDefaults  map[js_ast.E]bool
 	exprComments           map[logger.Loc][]string
 	printedExprComments    map[logger.Loc]bool
 	hasLegalComment        map[string]struct{}
@@ -352,6 +354,7 @@ type printer struct {
 	prevNumEnd           int
 	prevRegExpEnd        int
 	noLeadingNewlineHere int
+	withNesting          int
 	intToBytesBuffer     [64]byte
 	needsSemicolon       bool
 	prevOp               js_ast.OpCode
@@ -390,8 +393,12 @@ func (p *printer) addSourceMappingForName(loc logger.Loc, name string, ref js_as
 
 func (p *printer) printIndent() {
 	if !p.options.MinifyWhitespace {
+		indent := p.options.IndentUnit
+		if indent == "" {
+			indent = "  "
+		}
 		for i := 0; i < p.options.Indent; i++ {
-			p.print("  ")
+			p.print(indent)
 		}
 	}
 }
@@ -663,7 +670,7 @@ func (p *printer) printBinding(binding js_ast.Binding) {
 							p.options.Indent++
 							p.printIndent()
 						}
-						p.printExpr(property.Key, js_ast.LComma, 0)
+						p.printExpr(property.Key, js_ast.LComma, isPropertyKey)
 						if isMultiLine {
 							p.printNewline()
 							p.printExprCommentsAfterCloseTokenAtLoc(property.CloseBracketLoc)
@@ -728,7 +735,7 @@ func (p *printer) printBinding(binding js_ast.Binding) {
 							p.printQuotedUTF8(name, false /* allowBacktick */)
 						}
 					} else {
-						p.printExpr(property.Key, js_ast.LLowest, 0)
+						p.printExpr(property.Key, js_ast.LLowest, isPropertyKey)
 					}
 
 					p.print(":")
@@ -849,6 +856,8 @@ func (p *printer) printFnArgs(args []js_ast.Arg, opts fnArgsOpts) {
 		if opts.hasRestArg && i+1 == len(args) {
 			p.print("...")
 		}
+		p.noLeadingNewlineHere = len(p.js)
+		p.printExprCommentsAtLoc(arg.Binding.Loc)
 		p.printBinding(arg.Binding)
 
 		if arg.DefaultOrNil.Data != nil {
@@ -870,6 +879,46 @@ func (p *printer) printFn(fn js_ast.Fn) {
 	p.printBlock(fn.Body.Loc, fn.Body.Block)
 }
 
+func (p *printer) printDecorators(decorators []js_ast.Expr) {
+	for _, decorator := range decorators {
+		// Decorators must be wrapped in parentheses unless they are a chain of
+		// property accesses optionally followed by a single call
+		wrap := false
+		value := decorator
+		if call, ok := value.Data.(*js_ast.ECall); ok && call.OptionalChain == js_ast.OptionalChainNone {
+			value = call.Target
+		}
+	loop:
+		for {
+			switch e := value.Data.(type) {
+			case *js_ast.EIdentifier:
+				break loop
+			case *js_ast.EDot:
+				if e.OptionalChain != js_ast.OptionalChainNone {
+					wrap = true
+					break loop
+				}
+				value = e.Target
+			default:
+				wrap = true
+				break loop
+			}
+		}
+
+		p.printSpaceBeforeIdentifier()
+		p.addSourceMapping(decorator.Loc)
+		p.print("@")
+		if wrap {
+			p.print("(")
+		}
+		p.printExpr(decorator, js_ast.LLowest, 0)
+		if wrap {
+			p.print(")")
+		}
+		p.print(" ")
+	}
+}
+
 func (p *printer) printClass(class js_ast.Class) {
 	if class.ExtendsOrNil.Data != nil {
 		p.print(" extends")
@@ -884,6 +933,10 @@ func (p *printer) printClass(class js_ast.Class) {
 	p.options.Indent++
 
 	for _, item := range class.Properties {
+		if p.isUnusedMangledMethod(item) {
+			continue
+		}
+
 		p.printSemicolonIfNeeded()
 		p.printIndent()
 
@@ -896,6 +949,7 @@ func (p *printer) printClass(class js_ast.Class) {
 			continue
 		}
 
+		p.printDecorators(item.Decorators)
 		p.printProperty(item)
 
 		// Need semicolons after class fields
@@ -916,6 +970,17 @@ func (p *printer) printClass(class js_ast.Class) {
 	p.print("}")
 }
 
+func (p *printer) isUnusedMangledMethod(property js_ast.Property) bool {
+	if p.options.UnusedMangledMethods == nil || len(property.Decorators) > 0 ||
+		(!property.Flags.Has(js_ast.PropertyIsMethod) && property.Kind != js_ast.PropertyGet && property.Kind != js_ast.PropertySet) {
+		return false
+	}
+	if mangled, ok := property.Key.Data.(*js_ast.EMangledProp); ok {
+		return p.options.UnusedMangledMethods[js_ast.FollowSymbols(p.symbols, mangled.Ref)]
+	}
+	return false
+}
+
 func (p *printer) printProperty(property js_ast.Property) {
 	p.printExprCommentsAtLoc(property.Loc)
 
@@ -933,6 +998,12 @@ func (p *printer) printProperty(property js_ast.Property) {
 	}
 
 	switch property.Kind {
+	case js_ast.PropertyAutoAccessor:
+		p.printSpaceBeforeIdentifier()
+		p.addSourceMapping(property.Loc)
+		p.print("accessor")
+		p.printSpace()
+
 	case js_ast.PropertyGet:
 		p.printSpaceBeforeIdentifier()
 		p.addSourceMapping(property.Loc)
@@ -968,7 +1039,7 @@ func (p *printer) printProperty(property js_ast.Property) {
 			p.options.Indent++
 			p.printIndent()
 		}
-		p.printExpr(property.Key, js_ast.LComma, 0)
+		p.printExpr(property.Key, js_ast.LComma, isPropertyKey)
 		if isMultiLine {
 			p.printNewline()
 			p.printExprCommentsAfterCloseTokenAtLoc(property.CloseBracketLoc)
@@ -1096,7 +1167,7 @@ func (p *printer) printProperty(property js_ast.Property) {
 		}
 
 	default:
-		p.printExpr(property.Key, js_ast.LLowest, 0)
+		p.printExpr(property.Key, js_ast.LLowest, isPropertyKey)
 	}
 
 	if property.Kind != js_ast.PropertyNormal {
@@ -1158,7 +1229,7 @@ func (p *printer) printQuotedUTF16(data []uint16, allowBacktick bool) {
 	}
 
 	c := "\""
-	if doubleCost > singleCost {
+	if doubleCost > singleCost || (doubleCost == singleCost && p.options.PreferSingleQuotes) {
 		c = "'"
 		if singleCost > backtickCost && allowBacktick {
 			c = "`"
@@ -1273,7 +1344,7 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 			p.willPrintExprCommentsAtLoc(closeParenLoc) ||
 			(record.Assertions != nil &&
 				!p.options.UnsupportedFeatures.Has(compat.DynamicImport) &&
-				!p.options.UnsupportedFeatures.Has(compat.ImportAssertions) &&
+				p.canPrintImportAssertions(record.Assertions) &&
 				p.willPrintExprCommentsAtLoc(record.Assertions.OuterOpenBraceLoc))
 		if isMultiLine {
 			p.printNewline()
@@ -1461,8 +1532,11 @@ func (p *printer) simplifyUnusedExpr(expr js_ast.Expr) js_ast.Expr {
 			symbolFlags = p.symbols.Get(ref).Flags
 		}
 
-		// Replace non-mutated empty functions with their arguments at print time
-		if (symbolFlags & (js_ast.IsEmptyFunction | js_ast.CouldPotentiallyBeMutated)) == js_ast.IsEmptyFunction {
+		// Replace non-mutated empty functions with their arguments at print time.
+		// The same applies to functions that return a constant since the result
+		// isn't used.
+		if (symbolFlags&(js_ast.IsEmptyFunction|js_ast.CouldPotentiallyBeMutated)) == js_ast.IsEmptyFunction ||
+			(symbolFlags&(js_ast.IsConstReturnFunction|js_ast.CouldPotentiallyBeMutated)) == js_ast.IsConstReturnFunction {
 			var replacement js_ast.Expr
 			for _, arg := range e.Args {
 				if _, ok := arg.Data.(*js_ast.ESpread); ok {
@@ -1669,29 +1743,16 @@ func (p *printer) restoreExprStartFlags(flags exprStartFlags) {
 
 // Print any stored comments that are associated with this location
 func (p *printer) printExprCommentsAtLoc(loc logger.Loc) {
-	if p.options.MinifyWhitespace {
-		return
-	}
 	if comments := p.exprComments[loc]; comments != nil && !p.printedExprComments[loc] {
 		flags := p.saveExprStartFlags()
 
 		// We must never generate a newline before certain expressions. For example,
 		// generating a newline before the expression in a "return" statement will
 		// cause a semicolon to be inserted, which would change the code's behavior.
-		if p.noLeadingNewlineHere == len(p.js) {
-			for _, comment := range comments {
-				if strings.HasPrefix(comment, "//") {
-					p.print("/*")
-					p.print(comment[2:])
-					if strings.HasPrefix(comment, "// ") {
-						p.print(" ")
-					}
-					p.print("*/")
-				} else {
-					p.print(strings.Join(strings.Split(comment, "\n"), ""))
-				}
-				p.printSpace()
-			}
+		// Comments are also kept on the same line when minifying whitespace, which
+		// only happens for comments that match the comments filter.
+		if p.noLeadingNewlineHere == len(p.js) || p.options.MinifyWhitespace {
+			p.printInlineExprComments(comments)
 		} else {
 			for _, comment := range comments {
 				p.printIndentedComment(comment)
@@ -1710,9 +1771,13 @@ func (p *printer) printExprCommentsAfterCloseTokenAtLoc(loc logger.Loc) {
 	if comments := p.exprComments[loc]; comments != nil && !p.printedExprComments[loc] {
 		flags := p.saveExprStartFlags()
 
-		for _, comment := range comments {
-			p.printIndent()
-			p.printIndentedComment(comment)
+		if p.options.MinifyWhitespace {
+			p.printInlineExprComments(comments)
+		} else {
+			for _, comment := range comments {
+				p.printIndent()
+				p.printIndentedComment(comment)
+			}
 		}
 
 		// Mark these comments as printed so we don't print them again
@@ -1722,6 +1787,28 @@ func (p *printer) printExprCommentsAfterCloseTokenAtLoc(loc logger.Loc) {
 	}
 }
 
+// This prints comments without any newlines by converting single-line comments
+// into multi-line comments and by removing newlines from multi-line comments
+func (p *printer) printInlineExprComments(comments []string) {
+	for _, comment := range comments {
+		// Avoid accidentally turning a "/" operator into a single-line comment
+		if len(p.js) > 0 && p.js[len(p.js)-1] == '/' {
+			p.print(" ")
+		}
+		if strings.HasPrefix(comment, "//") {
+			p.print("/*")
+			p.print(comment[2:])
+			if strings.HasPrefix(comment, "// ") {
+				p.print(" ")
+			}
+			p.print("*/")
+		} else {
+			p.print(strings.Join(strings.Split(comment, "\n"), ""))
+		}
+		p.printSpace()
+	}
+}
+
 func (p *printer) printExprWithoutLeadingNewline(expr js_ast.Expr, level js_ast.L, flags printExprFlags) {
 	if !p.options.MinifyWhitespace && p.willPrintExprCommentsAtLoc(expr.Loc) {
 		p.print("(")
@@ -1753,8 +1840,212 @@ const (
 	isDeleteTarget
 	isCallTargetOrTemplateTag
 	parentWasUnaryOrBinary
+	isPropertyKey
 )
 
+// This returns the export names of the variable that this expression assigns
+// to if it's an exported variable in a SystemJS module, or nil otherwise
+func (p *printer) systemExportAliasesForUpdate(expr js_ast.Expr) []string {
+	var target js_ast.Expr
+	switch e := expr.Data.(type) {
+	case *js_ast.EBinary:
+		if e.Op.BinaryAssignTarget() == js_ast.AssignTargetNone {
+			return nil
+		}
+		target = e.Left
+	case *js_ast.EUnary:
+		if e.Op.UnaryAssignTarget() == js_ast.AssignTargetNone {
+			return nil
+		}
+		target = e.Value
+	default:
+		return nil
+	}
+	if id, ok := target.Data.(*js_ast.EIdentifier); ok {
+		return p.options.SystemExportAliases[js_ast.FollowSymbols(p.symbols, id.Ref)]
+	}
+	return nil
+}
+
+type systemExport struct {
+	ref     js_ast.Ref
+	loc     logger.Loc
+	aliases []string
+}
+
+// This returns the exported variables in a destructuring assignment target
+// such as "[x, { y }]". Default values in the pattern such as "x = 1" in
+// "[x = 1] = z" are also assignments, but they must not be wrapped in a call
+// to "_export" since that would no longer be a valid pattern. They are
+// remembered here so they are printed as-is.
+func (p *printer) systemExportsInPattern(target js_ast.Expr, exports []systemExport) []systemExport {
+	switch e := target.Data.(type) {
+	case *js_ast.EIdentifier:
+		if aliases := p.options.SystemExportAliases[js_ast.FollowSymbols(p.symbols, e.Ref)]; aliases != nil {
+			exports = append(exports, systemExport{ref: e.Ref, loc: target.Loc, aliases: aliases})
+		}
+
+	case *js_ast.EBinary:
+		if e.Op == js_ast.BinOpAssign {
+			if p.systemPatternDefaults == nil {
+				p.systemPatternDefaults = make(map[js_ast.E]bool)
+			}
+			p.systemPatternDefaults[e] = true
+			exports = p.systemExportsInPattern(e.Left, exports)
+		}
+
+	case *js_ast.ESpread:
+		exports = p.systemExportsInPattern(e.Value, exports)
+
+	case *js_ast.EArray:
+		for _, item := range e.Items {
+			exports = p.systemExportsInPattern(item, exports)
+		}
+
+	case *js_ast.EObject:
+		for _, property := range e.Properties {
+			exports = p.systemExportsInPattern(property.ValueOrNil, exports)
+		}
+	}
+	return exports
+}
+
+// This is like "systemExportsInPattern" but for bindings, which are used by
+// "var" declarations in "for-in" and "for-of" loops
+func (p *printer) systemExportsInBinding(binding js_ast.Binding, exports []systemExport) []systemExport {
+	switch b := binding.Data.(type) {
+	case *js_ast.BIdentifier:
+		if aliases := p.options.SystemExportAliases[js_ast.FollowSymbols(p.symbols, b.Ref)]; aliases != nil {
+			exports = append(exports, systemExport{ref: b.Ref, loc: binding.Loc, aliases: aliases})
+		}
+
+	case *js_ast.BArray:
+		for _, item := range b.Items {
+			exports = p.systemExportsInBinding(item.Binding, exports)
+		}
+
+	case *js_ast.BObject:
+		for _, property := range b.Properties {
+			exports = p.systemExportsInBinding(property.Value, exports)
+		}
+	}
+	return exports
+}
+
+func (p *printer) systemExportCalls(exports []systemExport) (calls []js_ast.Expr) {
+	for _, export := range exports {
+		for _, alias := range export.aliases {
+			calls = append(calls, js_ast.Expr{Loc: export.loc, Data: &js_ast.ECall{
+				Target: js_ast.Expr{Loc: export.loc, Data: &js_ast.EIdentifier{Ref: p.options.SystemExportRef}},
+				Args: []js_ast.Expr{
+					{Loc: export.loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(alias)}},
+					{Loc: export.loc, Data: &js_ast.EIdentifier{Ref: export.ref}},
+				},
+			}})
+		}
+	}
+	return
+}
+
+// This prints "[x, y] = z" as "[[x, y] = z, _export("x", x), _export("y", y)][0]",
+// or as a comma expression without the array if the result is unused
+func (p *printer) printSystemExportDestructuring(expr js_ast.Expr, exports []systemExport, level js_ast.L, flags printExprFlags) {
+	items := append([]js_ast.Expr{expr}, p.systemExportCalls(exports)...)
+	var result js_ast.Expr
+	if (flags & exprResultIsUnused) != 0 {
+		result = js_ast.JoinAllWithComma(items)
+	} else {
+		result = js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIndex{
+			Target: js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EArray{Items: items, IsSingleLine: true}},
+			Index:  js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ENumber{Value: 0}},
+		}}
+	}
+
+	old := p.systemExportTarget
+	p.systemExportTarget = expr.Data
+	p.printExpr(result, level, flags)
+	p.systemExportTarget = old
+}
+
+// Loops such as "for (x of y)" assign to "x" each iteration, so the body is
+// printed with calls to "_export" for the loop variables at the start
+func (p *printer) systemExportLoopBody(init js_ast.Stmt, body js_ast.Stmt) js_ast.Stmt {
+	var exports []systemExport
+	switch s := init.Data.(type) {
+	case *js_ast.SExpr:
+		exports = p.systemExportsInPattern(s.Value, nil)
+	case *js_ast.SLocal:
+		for _, decl := range s.Decls {
+			exports = p.systemExportsInBinding(decl.Binding, exports)
+		}
+	}
+	if exports == nil {
+		return body
+	}
+
+	stmts := []js_ast.Stmt{{Loc: body.Loc, Data: &js_ast.SExpr{Value: js_ast.JoinAllWithComma(p.systemExportCalls(exports))}}}
+	if block, ok := body.Data.(*js_ast.SBlock); ok {
+		stmts = append(stmts, block.Stmts...)
+	} else if _, ok := body.Data.(*js_ast.SEmpty); !ok {
+		stmts = append(stmts, body)
+	}
+	return js_ast.Stmt{Loc: body.Loc, Data: &js_ast.SBlock{Stmts: stmts}}
+}
+
+// This prints "x = y" as "_export("x", x = y)". Postfix updates such as "x++"
+// must evaluate to the old value, so they are printed as "[x++, _export("x", x)][0]"
+// unless the result is unused.
+func (p *printer) printSystemExportUpdate(expr js_ast.Expr, aliases []string, level js_ast.L, flags printExprFlags) {
+	isPostfix := false
+	if e, ok := expr.Data.(*js_ast.EUnary); ok && (e.Op == js_ast.UnOpPostDec || e.Op == js_ast.UnOpPostInc) {
+		if (flags & exprResultIsUnused) != 0 {
+			op := js_ast.UnOpPreInc
+			if e.Op == js_ast.UnOpPostDec {
+				op = js_ast.UnOpPreDec
+			}
+			expr = js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EUnary{Op: op, Value: e.Value}}
+		} else {
+			isPostfix = true
+		}
+	}
+
+	old := p.systemExportTarget
+	p.systemExportTarget = expr.Data
+	wrap := level >= js_ast.LNew
+	if wrap {
+		p.print("(")
+	}
+	if isPostfix {
+		p.print("[")
+		p.printExpr(expr, js_ast.LComma, 0)
+		p.print(",")
+		p.printSpace()
+	}
+	for _, alias := range aliases {
+		p.printSpaceBeforeIdentifier()
+		p.printIdentifier(p.renamer.NameForSymbol(p.options.SystemExportRef))
+		p.print("(")
+		p.printQuotedUTF8(alias, true)
+		p.print(",")
+		p.printSpace()
+	}
+	if isPostfix {
+		p.printExpr(expr.Data.(*js_ast.EUnary).Value, js_ast.LComma, 0)
+	} else {
+		p.printExpr(expr, js_ast.LComma, 0)
+	}
+	p.systemExportTarget = old
+	for range aliases {
+		p.print(")")
+	}
+	if isPostfix {
+		p.print("][0]")
+	}
+	if wrap {
+		p.print(")")
+	}
+}
+
 func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFlags) {
 	// If syntax compression is enabled, do a pre-pass over unary and binary
 	// operators to inline bitwise operations of cross-module inlined constants.
@@ -1773,6 +2064,22 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		}
 	}
 
+	if p.options.SystemExportAliases != nil && expr.Data != p.systemExportTarget && !p.systemPatternDefaults[expr.Data] {
+		if aliases := p.systemExportAliasesForUpdate(expr); aliases != nil {
+			p.printSystemExportUpdate(expr, aliases, level, flags)
+			return
+		}
+		if e, ok := expr.Data.(*js_ast.EBinary); ok && e.Op == js_ast.BinOpAssign {
+			switch e.Left.Data.(type) {
+			case *js_ast.EArray, *js_ast.EObject:
+				if exports := p.systemExportsInPattern(e.Left, nil); exports != nil {
+					p.printSystemExportDestructuring(expr, exports, level, flags)
+					return
+				}
+			}
+		}
+	}
+
 	p.printExprCommentsAtLoc(expr.Loc)
 
 	switch e := expr.Data.(type) {
@@ -1868,7 +2175,7 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 				p.print("{...{")
 				p.printSpace()
 				p.print("[")
-				p.printExpr(property.Key, js_ast.LComma, 0)
+				p.printExpr(property.Key, js_ast.LComma, isPropertyKey)
 				p.print("]:")
 				p.printSpace()
 				p.printExpr(property.ValueOrNil, js_ast.LComma, 0)
@@ -2056,12 +2363,14 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 	case *js_ast.ECall:
 		if p.options.MinifySyntax {
 			var symbolFlags js_ast.SymbolFlags
+			var targetRef js_ast.Ref
 			switch target := e.Target.Data.(type) {
 			case *js_ast.EIdentifier:
+				targetRef = target.Ref
 				symbolFlags = p.symbols.Get(target.Ref).Flags
 			case *js_ast.EImportIdentifier:
-				ref := js_ast.FollowSymbols(p.symbols, target.Ref)
-				symbolFlags = p.symbols.Get(ref).Flags
+				targetRef = js_ast.FollowSymbols(p.symbols, target.Ref)
+				symbolFlags = p.symbols.Get(targetRef).Flags
 			}
 
 			// Replace non-mutated empty functions with their arguments at print time
@@ -2080,6 +2389,25 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 				break
 			}
 
+			// Replace non-mutated functions that return a constant with their
+			// arguments followed by the constant at print time
+			if (symbolFlags & (js_ast.IsConstReturnFunction | js_ast.CouldPotentiallyBeMutated)) == js_ast.IsConstReturnFunction {
+				if value, ok := p.options.ConstReturnValues[targetRef]; ok {
+					var replacement js_ast.Expr
+					for _, arg := range e.Args {
+						if _, ok := arg.Data.(*js_ast.ESpread); ok {
+							arg.Data = &js_ast.EArray{Items: []js_ast.Expr{arg}, IsSingleLine: true}
+						}
+						replacement = js_ast.JoinWithComma(replacement, js_ast.SimplifyUnusedExpr(arg, p.options.UnsupportedFeatures, p.isUnbound))
+					}
+					if replacement.Data == nil || (flags&exprResultIsUnused) == 0 {
+						replacement = js_ast.JoinWithComma(replacement, js_ast.ConstValueToExpr(expr.Loc, value))
+					}
+					p.printExpr(p.guardAgainstBehaviorChangeDueToSubstitution(replacement, flags), level, flags)
+					break
+				}
+			}
+
 			// Inline non-mutated identity functions at print time
 			if (symbolFlags&(js_ast.IsIdentityFunction|js_ast.CouldPotentiallyBeMutated)) == js_ast.IsIdentityFunction && len(e.Args) == 1 {
 				arg := e.Args[0]
@@ -2207,9 +2535,13 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		p.addSourceMapping(expr.Loc)
 		p.printRequireOrImportExpr(e.ImportRecordIndex, level, flags, e.CloseParenLoc)
 
+	case *js_ast.EImportPath:
+		p.printPath(e.ImportRecordIndex, p.importRecords[e.ImportRecordIndex].Kind)
+
 	case *js_ast.EImportCall:
 		// Just omit import assertions if they aren't supported
-		printImportAssertions := e.OptionsOrNil.Data != nil && !p.options.UnsupportedFeatures.Has(compat.ImportAssertions)
+		printImportAssertions := e.OptionsOrNil.Data != nil && (!p.options.UnsupportedFeatures.Has(compat.ImportAssertions) ||
+			!p.options.UnsupportedFeatures.Has(compat.ImportAttributes))
 		isMultiLine := !p.options.MinifyWhitespace &&
 			(p.willPrintExprCommentsAtLoc(e.Expr.Loc) ||
 				(printImportAssertions && p.willPrintExprCommentsAtLoc(e.OptionsOrNil.Loc)) ||
@@ -2504,6 +2836,7 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		if wrap {
 			p.print("(")
 		}
+		p.printDecorators(e.Class.Decorators)
 		p.printSpaceBeforeIdentifier()
 		p.addSourceMapping(expr.Loc)
 		p.print("class")
@@ -2637,6 +2970,22 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		}
 
 	case *js_ast.EString:
+		// Substitute repeated string literals that were hoisted into a shared
+		// variable. This is avoided inside "with" statements since the name of
+		// the variable could also refer to a property on the target object, and
+		// for property keys since they aren't counted as uses by the parser.
+		if p.options.HoistedStrings != nil && p.withNesting == 0 && (flags&isPropertyKey) == 0 {
+			if text, _, ok := helpers.UTF16ToStringWithValidation(e.Value); ok {
+				if ref, ok := p.options.HoistedStrings[text]; ok {
+					name := p.renamer.NameForSymbol(ref)
+					p.printSpaceBeforeIdentifier()
+					p.addSourceMappingForName(expr.Loc, name, ref)
+					p.printIdentifier(name)
+					return
+				}
+			}
+		}
+
 		p.addSourceMapping(expr.Loc)
 
 		// If this was originally a template literal, print it as one as long as we're not minifying
@@ -3218,6 +3567,10 @@ func (p *printer) printForLoopInit(init js_ast.Stmt, flags printExprFlags) {
 			p.printDecls("let", s.Decls, flags)
 		case js_ast.LocalConst:
 			p.printDecls("const", s.Decls, flags)
+		case js_ast.LocalUsing:
+			p.printDecls("using", s.Decls, flags)
+		case js_ast.LocalAwaitUsing:
+			p.printDecls("await using", s.Decls, flags)
 		}
 	default:
 		panic("Internal error")
@@ -3427,7 +3780,8 @@ func (p *printer) printPath(importRecordIndex uint32, importKind ast.ImportKind)
 	p.addSourceMapping(record.Range.Loc)
 	p.printQuotedUTF8(record.Path.Text, false /* allowBacktick */)
 
-	if p.options.NeedsMetafile {
+	// Inline workers are embedded directly and don't reference another file
+	if p.options.NeedsMetafile && !record.Flags.Has(ast.InlineWorker) {
 		external := ""
 		if (record.Flags & ast.ShouldNotBeExternalInMetafile) == 0 {
 			external = ",\n          \"external\": true"
@@ -3438,27 +3792,47 @@ func (p *printer) printPath(importRecordIndex uint32, importKind ast.ImportKind)
 			external))
 	}
 
-	// Just omit import assertions if they aren't supported
-	if p.options.UnsupportedFeatures.Has(compat.ImportAssertions) {
-		return
+	if record.Assertions != nil && importKind == ast.ImportStmt {
+		// Just omit import assertions if they aren't supported
+		if keyword, ok := p.importAssertionsKeyword(record.Assertions); ok {
+			p.printSpace()
+			p.addSourceMapping(record.Assertions.AssertLoc)
+			p.print(keyword.String())
+			p.printSpace()
+			p.printImportAssertionsClause(*record.Assertions)
+		}
 	}
+}
 
-	if record.Assertions != nil && importKind == ast.ImportStmt {
-		p.printSpace()
-		p.addSourceMapping(record.Assertions.AssertLoc)
-		p.print("assert")
-		p.printSpace()
-		p.printImportAssertionsClause(*record.Assertions)
+// Import attributes (i.e. "with") are the successor to import assertions (i.e.
+// "assert"). If the target only supports one of them, print that one instead.
+func (p *printer) importAssertionsKeyword(assertions *ast.ImportAssertions) (ast.ImportAssertionsKeyword, bool) {
+	assertIsSupported := !p.options.UnsupportedFeatures.Has(compat.ImportAssertions)
+	withIsSupported := !p.options.UnsupportedFeatures.Has(compat.ImportAttributes)
+	switch {
+	case assertions.Keyword == ast.WithKeyword && withIsSupported, assertions.Keyword == ast.AssertKeyword && assertIsSupported:
+		return assertions.Keyword, true
+	case withIsSupported:
+		return ast.WithKeyword, true
+	case assertIsSupported:
+		return ast.AssertKeyword, true
 	}
+	return 0, false
+}
+
+func (p *printer) canPrintImportAssertions(assertions *ast.ImportAssertions) bool {
+	_, ok := p.importAssertionsKeyword(assertions)
+	return ok
 }
 
 func (p *printer) printImportCallAssertions(assertions *ast.ImportAssertions, outerIsMultiLine bool) {
-	// Just omit import assertions if they aren't supported
-	if p.options.UnsupportedFeatures.Has(compat.ImportAssertions) {
+	if assertions == nil {
 		return
 	}
 
-	if assertions == nil {
+	// Just omit import assertions if they aren't supported
+	keyword, ok := p.importAssertionsKeyword(assertions)
+	if !ok {
 		return
 	}
 
@@ -3487,7 +3861,8 @@ func (p *printer) printImportCallAssertions(assertions *ast.ImportAssertions, ou
 
 	p.printExprCommentsAtLoc(assertions.AssertLoc)
 	p.addSourceMapping(assertions.AssertLoc)
-	p.print("assert:")
+	p.print(keyword.String())
+	p.print(":")
 
 	if p.willPrintExprCommentsAtLoc(assertions.InnerOpenBraceLoc) {
 		p.printNewline()
@@ -3593,22 +3968,26 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 		text := s.Text
 
 		if s.IsLegalComment {
+			isExtracted := false
 			switch p.options.LegalComments {
-			case config.LegalCommentsNone:
-				return
-
 			case config.LegalCommentsEndOfFile,
 				config.LegalCommentsLinkedWithComment,
 				config.LegalCommentsExternalWithoutComment:
+				isExtracted = true
+			}
 
-				// Don't record the same legal comment more than once per file
+			// Don't record the same legal comment more than once per file
+			if isExtracted || p.options.RecordLegalComments {
 				if p.hasLegalComment == nil {
 					p.hasLegalComment = make(map[string]struct{})
-				} else if _, ok := p.hasLegalComment[text]; ok {
-					return
 				}
-				p.hasLegalComment[text] = struct{}{}
-				p.extractedLegalComments = append(p.extractedLegalComments, text)
+				if _, ok := p.hasLegalComment[text]; !ok {
+					p.hasLegalComment[text] = struct{}{}
+					p.extractedLegalComments = append(p.extractedLegalComments, text)
+				}
+			}
+
+			if isExtracted || p.options.LegalComments == config.LegalCommentsNone {
 				return
 			}
 		}
@@ -3642,6 +4021,7 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 	case *js_ast.SClass:
 		p.addSourceMapping(stmt.Loc)
 		p.printIndent()
+		p.printDecorators(s.Class.Decorators)
 		p.printSpaceBeforeIdentifier()
 		if s.IsExport {
 			p.print("export ")
@@ -3662,6 +4042,9 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 	case *js_ast.SExportDefault:
 		p.addSourceMapping(stmt.Loc)
 		p.printIndent()
+		if s2, ok := s.Value.Data.(*js_ast.SClass); ok {
+			p.printDecorators(s2.Class.Decorators)
+		}
 		p.printSpaceBeforeIdentifier()
 		p.print("export default")
 		p.printSpace()
@@ -3834,6 +4217,10 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 			p.printDeclStmt(s.IsExport, "let", s.Decls)
 		case js_ast.LocalVar:
 			p.printDeclStmt(s.IsExport, "var", s.Decls)
+		case js_ast.LocalUsing:
+			p.printDeclStmt(s.IsExport, "using", s.Decls)
+		case js_ast.LocalAwaitUsing:
+			p.printDeclStmt(s.IsExport, "await using", s.Decls)
 		}
 
 	case *js_ast.SIf:
@@ -3876,6 +4263,10 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 		p.printSemicolonAfterStatement()
 
 	case *js_ast.SForIn:
+		body := s.Body
+		if p.options.SystemExportAliases != nil {
+			body = p.systemExportLoopBody(s.Init, s.Body)
+		}
 		p.addSourceMapping(stmt.Loc)
 		p.printIndent()
 		p.printSpaceBeforeIdentifier()
@@ -3906,9 +4297,13 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 			p.printIndent()
 		}
 		p.print(")")
-		p.printBody(s.Body)
+		p.printBody(body)
 
 	case *js_ast.SForOf:
+		body := s.Body
+		if p.options.SystemExportAliases != nil {
+			body = p.systemExportLoopBody(s.Init, s.Body)
+		}
 		p.addSourceMapping(stmt.Loc)
 		p.printIndent()
 		p.printSpaceBeforeIdentifier()
@@ -3947,7 +4342,7 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 			p.printIndent()
 		}
 		p.print(")")
-		p.printBody(s.Body)
+		p.printBody(body)
 
 	case *js_ast.SWhile:
 		p.addSourceMapping(stmt.Loc)
@@ -3989,7 +4384,9 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 			p.printExpr(s.Value, js_ast.LLowest, 0)
 		}
 		p.print(")")
+		p.withNesting++
 		p.printBody(s.Body)
+		p.withNesting--
 
 	case *js_ast.SLabel:
 		// Avoid printing a source mapping that masks the one from the label
@@ -4365,9 +4762,25 @@ type Options struct {
 	// Cross-module inlining of detected inlinable constants is also done during printing
 	ConstValues map[js_ast.Ref]js_ast.ConstValue
 
+	// Calls to functions that return a constant are also inlined during printing
+	ConstReturnValues map[js_ast.Ref]js_ast.ConstValue
+
+	// Repeated string literals that were hoisted into shared variables are
+	// replaced with references to those variables during printing
+	HoistedStrings map[string]js_ast.Ref
+
 	// Property mangling results go here
 	MangledProps map[js_ast.Ref]string
 
+	// Class methods with these mangled property names are never referenced
+	// anywhere in the bundle and are omitted from the output
+	UnusedMangledMethods map[js_ast.Ref]bool
+
+	// This maps the exported variables of a SystemJS module to their export
+	// names. Assignments to these variables are passed through a call to the
+	// "_export" function so that importers see the new value.
+	SystemExportAliases map[js_ast.Ref][]string
+
 	// This will be present if the input file had a source map. In that case we
 	// want to map all the way back to the original input file(s).
 	InputSourceMap *sourcemap.SourceMap
@@ -4379,8 +4792,10 @@ type Options struct {
 	ToCommonJSRef       js_ast.Ref
 	ToESMRef            js_ast.Ref
 	RuntimeRequireRef   js_ast.Ref
+	SystemExportRef     js_ast.Ref
 	UnsupportedFeatures compat.JSFeature
 	Indent              int
+	IndentUnit          string
 	OutputFormat        config.Format
 	MinifyWhitespace    bool
 	MinifyIdentifiers   bool
@@ -4389,7 +4804,13 @@ type Options struct {
 	LegalComments       config.LegalComments
 	SourceMap           config.SourceMap
 	AddSourceMappings   bool
+	SourceMapLinesOnly  bool
 	NeedsMetafile       bool
+	PreferSingleQuotes  bool
+
+	// If true, legal comments are also returned in "ExtractedLegalComments"
+	// when they are printed inline or omitted from the output entirely
+	RecordLegalComments bool
 }
 
 type RequireOrImportMeta struct {
@@ -4430,7 +4851,7 @@ func Print(tree js_ast.AST, symbols js_ast.SymbolMap, r renamer.Renamer, options
 		prevNumEnd:           -1,
 		prevRegExpEnd:        -1,
 		noLeadingNewlineHere: -1,
-		builder:              sourcemap.MakeChunkBuilder(options.InputSourceMap, options.LineOffsetTables, options.ASCIIOnly),
+		builder:              sourcemap.MakeChunkBuilder(options.InputSourceMap, options.LineOffsetTables, options.ASCIIOnly, options.SourceMapLinesOnly),
 	}
 
 	if p.exprComments != nil {
@@ -4442,10 +4863,10 @@ func Print(tree js_ast.AST, symbols js_ast.SymbolMap, r renamer.Renamer, options
 		return symbols.Get(ref).Kind == js_ast.SymbolUnbound
 	}
 
-	// Add the top-level directive if present
-	if tree.Directive != "" {
+	// Add the top-level directives if present
+	for _, directive := range tree.Directives {
 		p.printIndent()
-		p.printQuotedUTF8(tree.Directive, options.ASCIIOnly)
+		p.printQuotedUTF8(directive, options.ASCIIOnly)
 		p.print(";")
 		p.printNewline()
 	}
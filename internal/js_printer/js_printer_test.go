@@ -42,6 +42,8 @@ func expectPrintedCommon(t *testing.T, name string, contents string, expected st
 			MinifySyntax:        options.MinifySyntax,
 			MinifyWhitespace:    options.MinifyWhitespace,
 			UnsupportedFeatures: options.UnsupportedJSFeatures,
+			IndentUnit:          options.IndentUnit,
+			PreferSingleQuotes:  options.PreferSingleQuotes,
 		}).JS
 		test.AssertEqualWithDiff(t, string(js), expected)
 	})
@@ -1072,3 +1074,75 @@ func TestInfinity(t *testing.T) {
 	expectPrintedMangleMinify(t, "x = y / Infinity", "x=y/(1/0);")
 	expectPrintedMangleMinify(t, "throw Infinity", "throw 1/0;")
 }
+
+func expectPrintedASTJSON(t *testing.T, contents string, expected string) {
+	t.Helper()
+	t.Run(contents, func(t *testing.T) {
+		t.Helper()
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		tree, ok := js_parser.Parse(log, test.SourceForTest(contents), js_parser.OptionsFromConfig(&config.Options{}))
+		if !ok {
+			t.Fatal("Parse error")
+		}
+		test.AssertEqualWithDiff(t, string(PrintASTJSON(tree, false)), expected)
+	})
+}
+
+func TestASTJSON(t *testing.T) {
+	expectPrintedASTJSON(t, "", `{"type":"Program","start":0,"sourceType":"script","body":[],"comments":[]}`)
+	expectPrintedASTJSON(t, "#!/usr/bin/env node\n'use strict'",
+		`{"type":"Program","start":0,"sourceType":"script","hashbang":"#!/usr/bin/env node","body":[{"type":"ExpressionStatement",`+
+			`"expression":{"type":"Literal","value":"use strict"},"directive":"use strict"}],"comments":[]}`)
+	expectPrintedASTJSON(t, "//! legal\na + 1",
+		`{"type":"Program","start":0,"sourceType":"script","body":[{"type":"ExpressionStatement","start":10,"expression":{"type":"BinaryExpression","start":10,`+
+			`"operator":"+","left":{"type":"Identifier","start":10,"name":"a"},"right":{"type":"Literal","start":14,"value":1}}}],`+
+			`"comments":[{"type":"Line","start":0,"value":"! legal"}]}`)
+	expectPrintedASTJSON(t, "let x = a?.b",
+		`{"type":"Program","start":0,"sourceType":"script","body":[{"type":"VariableDeclaration","start":0,"kind":"let","declarations":[{"type":"VariableDeclarator","start":4,`+
+			`"id":{"type":"Identifier","start":4,"name":"x"},"init":{"type":"ChainExpression","start":8,"expression":{"type":"MemberExpression","start":8,`+
+			`"object":{"type":"Identifier","start":8,"name":"a"},"property":{"type":"Identifier","start":11,"name":"b"},"computed":false,"optional":true}}}]}],"comments":[]}`)
+	expectPrintedASTJSON(t, "import x from 'y'",
+		`{"type":"Program","start":0,"sourceType":"module","body":[{"type":"ImportDeclaration","start":0,"specifiers":[{"type":"ImportDefaultSpecifier","start":7,`+
+			`"local":{"type":"Identifier","start":7,"name":"x"}}],"source":{"type":"Literal","start":14,"value":"y"}}],"comments":[]}`)
+	expectPrintedASTJSON(t, "({ get [a]() {} })",
+		`{"type":"Program","start":0,"sourceType":"script","body":[{"type":"ExpressionStatement","start":0,"expression":{"type":"ObjectExpression","start":1,`+
+			`"properties":[{"type":"Property","start":3,"key":{"type":"Identifier","start":8,"name":"a"},"value":{"type":"FunctionExpression","start":10,"id":null,`+
+			`"params":[],"body":{"type":"BlockStatement","start":13,"body":[]},"async":false,"generator":false},"kind":"get","method":false,"shorthand":false,"computed":true}]}}],"comments":[]}`)
+	expectPrintedASTJSON(t, "[a, , ...b] = c",
+		`{"type":"Program","start":0,"sourceType":"script","body":[{"type":"ExpressionStatement","start":0,"expression":{"type":"AssignmentExpression","start":0,"operator":"=",`+
+			`"left":{"type":"ArrayPattern","start":0,"elements":[{"type":"Identifier","start":1,"name":"a"},null,{"type":"RestElement","start":6,`+
+			`"argument":{"type":"Identifier","start":9,"name":"b"}}]},"right":{"type":"Identifier","start":14,"name":"c"}}}],"comments":[]}`)
+	expectPrintedASTJSON(t, "x = -1, `a${b}`",
+		`{"type":"Program","start":0,"sourceType":"script","body":[{"type":"ExpressionStatement","start":0,"expression":{"type":"SequenceExpression","start":0,"expressions":[`+
+			`{"type":"AssignmentExpression","start":0,"operator":"=","left":{"type":"Identifier","start":0,"name":"x"},"right":{"type":"UnaryExpression","start":4,`+
+			`"operator":"-","prefix":true,"argument":{"type":"Literal","start":4,"value":1}}},{"type":"TemplateLiteral","start":8,"quasis":[`+
+			`{"type":"TemplateElement","start":8,"value":{"raw":"a","cooked":"a"},"tail":false},{"type":"TemplateElement","start":13,"value":{"raw":"","cooked":""},"tail":true}],`+
+			`"expressions":[{"type":"Identifier","start":12,"name":"b"}]}]}}],"comments":[]}`)
+	expectPrintedASTJSON(t, "class A { static #x = 1 }",
+		`{"type":"Program","start":0,"sourceType":"script","body":[{"type":"ClassDeclaration","start":0,"id":{"type":"Identifier","start":6,"name":"A"},"superClass":null,`+
+			`"body":{"type":"ClassBody","start":8,"body":[{"type":"PropertyDefinition","start":10,"key":{"type":"PrivateIdentifier","start":17,"name":"x"},`+
+			`"value":{"type":"Literal","start":22,"value":1},"computed":false,"static":true}]}}],"comments":[]}`)
+}
+
+func TestIndentUnit(t *testing.T) {
+	tabs := config.Options{IndentUnit: "\t"}
+	fourSpaces := config.Options{IndentUnit: "    "}
+
+	expectPrintedCommon(t, "tabs", "if (a) { b(); for (;;) c() }", "if (a) {\n\tb();\n\tfor (; ; )\n\t\tc();\n}\n", tabs)
+	expectPrintedCommon(t, "four spaces", "if (a) { b(); for (;;) c() }", "if (a) {\n    b();\n    for (; ; )\n        c();\n}\n", fourSpaces)
+	expectPrintedCommon(t, "object", "x = { a: 1, b: { c: 2 } }", "x = { a: 1, b: { c: 2 } };\n", tabs)
+	expectPrintedCommon(t, "class", "class A { b() { c() } }", "class A {\n\tb() {\n\t\tc();\n\t}\n}\n", tabs)
+	expectPrintedCommon(t, "minified", "if (a) { b(); c() }", "if(a){b();c()}", config.Options{IndentUnit: "\t", MinifyWhitespace: true})
+}
+
+func TestPreferSingleQuotes(t *testing.T) {
+	single := config.Options{PreferSingleQuotes: true}
+
+	expectPrintedCommon(t, "'abc' [single]", "x = 'abc'", "x = 'abc';\n", single)
+	expectPrintedCommon(t, "\"abc\" [single]", "x = \"abc\"", "x = 'abc';\n", single)
+	expectPrintedCommon(t, "\"a'b\" [single]", "x = \"a'b\"", "x = \"a'b\";\n", single)
+	expectPrintedCommon(t, "'a\"b' [single]", "x = 'a\"b'", "x = 'a\"b';\n", single)
+	expectPrintedCommon(t, "import [single]", "import a from \"a\"", "import a from 'a';\n", single)
+	expectPrintedCommon(t, "directive [single]", "\"use strict\"", "'use strict';\n", single)
+	expectPrintedCommon(t, "property [single]", "x = { \"a-b\": 1 }", "x = { 'a-b': 1 };\n", single)
+}
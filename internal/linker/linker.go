@@ -15,6 +15,7 @@ import (
 	"hash"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -63,9 +64,15 @@ type linkerContext struct {
 	// Property mangling results go here
 	mangledProps map[js_ast.Ref]string
 
+	// Class methods whose mangled property names are never referenced
+	unusedMangledMethods map[js_ast.Ref]bool
+
 	// We may need to refer to the CommonJS "module" symbol for exports
 	unboundModuleRef js_ast.Ref
 
+	// This is the "_export" function passed to the SystemJS wrapper
+	systemExportRef js_ast.Ref
+
 	// We may need to refer to the "__esm" and/or "__commonJS" runtime symbols
 	cjsRuntimeRef js_ast.Ref
 	esmRuntimeRef js_ast.Ref
@@ -102,6 +109,10 @@ type chunkInfo struct {
 	// If non-empty, this chunk needs to generate an external legal comments file.
 	externalLegalComments []byte
 
+	// The legal comments from each file in this chunk. This is only populated
+	// when third-party licenses are being extracted.
+	legalCommentsForLicenses []legalCommentEntry
+
 	// This contains the hash for just this chunk without including information
 	// from the hashes of other chunks. Later on in the linking process, the
 	// final hash for this chunk will be constructed by merging the isolated
@@ -123,6 +134,10 @@ type chunkInfo struct {
 	sourceIndex   uint32 // An index into "c.sources"
 	isEntryPoint  bool
 
+	// This is true for the chunk that only contains the runtime, which is only
+	// generated when the "RuntimeChunk" option is enabled
+	isRuntimeChunk bool
+
 	isExecutable bool
 }
 
@@ -183,6 +198,26 @@ type chunkReprJS struct {
 
 	cssChunkIndex uint32
 	hasCSSChunk   bool
+
+	// These CommonJS globals are used by code in this chunk but don't exist
+	// in node's ES module implementation, so they must be defined manually
+	nodeESMGlobals nodeESMGlobals
+
+	// Repeated string literals in this chunk are hoisted into variables that
+	// are declared at the top of the chunk when minifying
+	hoistedStrings    map[string]js_ast.Ref
+	hoistedStringUses []hoistedStringUse
+}
+
+type hoistedStringUse struct {
+	ref   js_ast.Ref
+	count uint32
+}
+
+type nodeESMGlobals struct {
+	require  bool
+	filename bool
+	dirname  bool
 }
 
 type chunkReprCSS struct {
@@ -286,6 +321,7 @@ func Link(
 			// when the global name is present, since that's the only way the exports
 			// can actually be observed externally.
 			if repr.AST.ExportKeyword.Len > 0 && (options.OutputFormat == config.FormatCommonJS ||
+				options.OutputFormat == config.FormatUMD || options.OutputFormat == config.FormatSystem ||
 				(options.OutputFormat == config.FormatIIFE && len(options.GlobalName) > 0)) {
 				repr.AST.UsesExportsRef = true
 				repr.Meta.ForceIncludeExportsForEntryPoint = true
@@ -307,6 +343,13 @@ func Link(
 		c.unboundModuleRef = js_ast.InvalidRef
 	}
 
+	// Allocate a new unbound symbol called "_export" for the SystemJS format
+	if c.options.OutputFormat == config.FormatSystem {
+		c.systemExportRef = c.graph.GenerateNewSymbol(runtime.SourceIndex, js_ast.SymbolUnbound, "_export")
+	} else {
+		c.systemExportRef = js_ast.InvalidRef
+	}
+
 	c.scanImportsAndExports()
 
 	// Stop now if there were errors
@@ -314,7 +357,9 @@ func Link(
 		return []graph.OutputFile{}
 	}
 
+	c.mergeTSScriptNamespaces()
 	c.treeShakingAndCodeSplitting()
+	c.findUsedLazyDefaultExportProperties()
 
 	if c.options.Mode == config.ModePassThrough {
 		for _, entryPoint := range c.graph.EntryPoints() {
@@ -324,6 +369,7 @@ func Link(
 
 	c.computeChunks()
 	c.computeCrossChunkDependencies()
+	c.hoistRepeatedStrings()
 
 	// Merge mangled properties before chunks are generated since the names must
 	// be consistent across all chunks, or the generated code will break
@@ -367,6 +413,7 @@ func (c *linkerContext) mangleProps(mangleCache map[string]interface{}) {
 	// Merge all mangled property symbols together
 	freq := js_ast.CharFreq{}
 	mergedProps := make(map[string]js_ast.Ref)
+	methodCounts := make(map[string]uint32)
 	for _, sourceIndex := range c.graph.ReachableFiles {
 		// Don't mangle anything in the runtime code
 		if sourceIndex == runtime.SourceIndex {
@@ -387,6 +434,7 @@ func (c *linkerContext) mangleProps(mangleCache map[string]interface{}) {
 				} else {
 					mergedProps[name] = ref
 				}
+				methodCounts[name] += repr.AST.MangledPropMethodCounts[ref]
 			}
 
 			// Include this file's frequency histogram, which affects the mangled names
@@ -415,10 +463,30 @@ func (c *linkerContext) mangleProps(mangleCache map[string]interface{}) {
 		symbol := c.graph.Symbols.Get(symbolCount.Ref)
 
 		// Don't change existing mappings
-		if existing, ok := mangleCache[symbol.OriginalName]; ok {
-			if existing != false {
-				mangledProps[symbolCount.Ref] = existing.(string)
+		existing, hasExisting := mangleCache[symbol.OriginalName]
+		if hasExisting && existing == false {
+			continue
+		}
+
+		// When bundling, a class method can be removed if the only uses of its
+		// name are method definitions. This can't be done without bundling since
+		// other files that aren't part of this build may reference the method.
+		if c.options.Mode == config.ModeBundle {
+			if count := methodCounts[symbol.OriginalName]; count > 0 && count == symbol.UseCountEstimate {
+				if c.unusedMangledMethods == nil {
+					c.unusedMangledMethods = make(map[js_ast.Ref]bool)
+				}
+				c.unusedMangledMethods[symbolCount.Ref] = true
+
+				// Don't waste a short name on a property that won't be printed
+				if !hasExisting {
+					continue
+				}
 			}
+		}
+
+		if hasExisting {
+			mangledProps[symbolCount.Ref] = existing.(string)
 			continue
 		}
 
@@ -585,11 +653,38 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 				})
 			}
 
+			// Generate the optional license file for this chunk
+			if c.options.ExtractLicenses {
+				if licenses := c.generateLicenseFile(chunk); licenses != nil {
+					outputFiles = append(outputFiles, graph.OutputFile{
+						AbsPath:  c.fs.Join(c.options.AbsOutputDir, chunk.finalRelPath+".LICENSES.txt"),
+						Contents: licenses,
+						JSONMetadataChunk: fmt.Sprintf(
+							"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {},\n      \"bytes\": %d\n    }", len(licenses)),
+					})
+				}
+			}
+
 			// Generate the optional source map for this chunk
 			if c.options.SourceMap != config.SourceMapNone && chunk.outputSourceMap.HasContent() {
 				outputSourceMap := chunk.outputSourceMap.Finalize(outputSourceMapShifts)
 				finalRelPathForSourceMap := chunk.finalRelPath + ".map"
 
+				// Potentially write a debug ID to both the output file and the source map
+				if c.options.SourceMapDebugID {
+					outputContents := outputContentsJoiner.Done()
+					debugID := debugIDForOutput(outputContents, outputSourceMap)
+					outputContentsJoiner = helpers.Joiner{}
+					outputContentsJoiner.AddBytes(outputContents)
+					outputContentsJoiner.EnsureNewlineAtEnd()
+					outputContentsJoiner.AddString(commentPrefix)
+					outputContentsJoiner.AddString("# debugId=")
+					outputContentsJoiner.AddString(debugID)
+					outputContentsJoiner.AddString(commentSuffix)
+					outputContentsJoiner.AddString("\n")
+					outputSourceMap = addDebugIDToSourceMap(outputSourceMap, debugID)
+				}
+
 				// Potentially write a trailing source map comment
 				switch c.options.SourceMap {
 				case config.SourceMapLinkedWithComment:
@@ -637,11 +732,16 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 			}
 
 			// Generate the output file for this chunk
+			var entryPointSourceIndex ast.Index32
+			if chunk.isEntryPoint {
+				entryPointSourceIndex = ast.MakeIndex32(chunk.sourceIndex)
+			}
 			outputFiles = append(outputFiles, graph.OutputFile{
-				AbsPath:           c.fs.Join(c.options.AbsOutputDir, chunk.finalRelPath),
-				Contents:          outputContents,
-				JSONMetadataChunk: jsonMetadataChunk,
-				IsExecutable:      chunk.isExecutable,
+				AbsPath:               c.fs.Join(c.options.AbsOutputDir, chunk.finalRelPath),
+				Contents:              outputContents,
+				JSONMetadataChunk:     jsonMetadataChunk,
+				IsExecutable:          chunk.isExecutable,
+				EntryPointSourceIndex: entryPointSourceIndex,
 			})
 
 			results[chunkIndex] = outputFiles
@@ -664,6 +764,32 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 	return outputFiles
 }
 
+// This generates a UUID from the contents of an output file and its source
+// map. Error-tracking services use it to match a file with its source map
+// without relying on file paths. It's derived from the contents instead of
+// being random so that builds remain deterministic.
+func debugIDForOutput(outputContents []byte, outputSourceMap []byte) string {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], xxhash.Sum64(outputContents))
+	binary.BigEndian.PutUint64(id[8:], xxhash.Sum64(outputSourceMap))
+	id[6] = (id[6] & 0x0F) | 0x40 // Version 4
+	id[8] = (id[8] & 0x3F) | 0x80 // Variant 1
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+// Source maps always end with a "}" character followed by a newline, so the
+// "debugId" field is added as the last property before that character
+func addDebugIDToSourceMap(outputSourceMap []byte, debugID string) []byte {
+	end := bytes.TrimRight(outputSourceMap, "\n")
+	end = bytes.TrimRight(end[:len(end)-1], "\n")
+	result := make([]byte, 0, len(outputSourceMap)+len(debugID)+20)
+	result = append(result, end...)
+	result = append(result, ",\n  \"debugId\": \""...)
+	result = append(result, debugID...)
+	result = append(result, "\"\n}\n"...)
+	return result
+}
+
 // Given a set of output pieces (i.e. a buffer already divided into the spans
 // between import paths), substitute the final import paths in and then join
 // everything into a single byte buffer.
@@ -692,7 +818,7 @@ func (c *linkerContext) substituteFinalPaths(
 		switch piece.kind {
 		case outputPieceAssetIndex:
 			file := c.graph.Files[piece.index]
-			if len(file.InputFile.AdditionalFiles) != 1 {
+			if len(file.InputFile.AdditionalFiles) == 0 {
 				panic("Internal error")
 			}
 			relPath, _ := c.fs.Rel(c.options.AbsOutputDir, file.InputFile.AdditionalFiles[0].AbsPath)
@@ -729,7 +855,7 @@ func (c *linkerContext) accurateFinalByteCount(output intermediateOutput, chunkF
 		switch piece.kind {
 		case outputPieceAssetIndex:
 			file := c.graph.Files[piece.index]
-			if len(file.InputFile.AdditionalFiles) != 1 {
+			if len(file.InputFile.AdditionalFiles) == 0 {
 				panic("Internal error")
 			}
 			relPath, _ := c.fs.Rel(c.options.AbsOutputDir, file.InputFile.AdditionalFiles[0].AbsPath)
@@ -1069,6 +1195,114 @@ func (c *linkerContext) computeCrossChunkDependencies() {
 	}
 }
 
+// When minifying, string literals that are repeated many times within a
+// chunk are replaced with references to a variable declared at the top of
+// the chunk. The string use counts come from the parser and are estimates,
+// so this only hoists strings where the savings are expected to be large.
+func (c *linkerContext) hoistRepeatedStrings() {
+	if !c.options.MinifySyntax || !c.options.MinifyIdentifiers || c.options.OutputFormat == config.FormatPreserve {
+		return
+	}
+
+	c.timer.Begin("Hoist repeated strings")
+	defer c.timer.End("Hoist repeated strings")
+
+	// Use "var" instead of "const" for the same reasons that the parser does
+	kind := js_ast.LocalConst
+	if c.options.Mode == config.ModeBundle || c.options.UnsupportedJSFeatures.Has(compat.ConstAndLet) {
+		kind = js_ast.LocalVar
+	}
+
+	for chunkIndex := range c.chunks {
+		chunkRepr, ok := c.chunks[chunkIndex].chunkRepr.(*chunkReprJS)
+		if !ok {
+			continue
+		}
+
+		// ES module imports are evaluated before the variables at the top of the
+		// chunk are initialized. If an import cycle calls back into this chunk
+		// while the imported module is being evaluated, the hoisted strings
+		// wouldn't be initialized yet. So don't hoist strings in that case.
+		hasESMImports := c.options.OutputFormat == config.FormatESModule && len(c.chunks[chunkIndex].crossChunkImports) > 0
+
+		// Count the uses of each string literal in the parts in this chunk
+		counts := make(map[string]uint32)
+		for _, partRange := range chunkRepr.partsInChunkInOrder {
+			// Skip the runtime in test output
+			if partRange.sourceIndex == runtime.SourceIndex && c.options.OmitRuntimeForTests {
+				continue
+			}
+			repr := c.graph.Files[partRange.sourceIndex].InputFile.Repr.(*graph.JSRepr)
+			for partIndex := partRange.partIndexBegin; partIndex < partRange.partIndexEnd; partIndex++ {
+				if part := &repr.AST.Parts[partIndex]; part.IsLive {
+					for text, count := range part.StringLiteralUses {
+						counts[text] += count
+					}
+					if c.options.OutputFormat == config.FormatESModule {
+						for _, importRecordIndex := range part.ImportRecordIndices {
+							record := &repr.AST.ImportRecords[importRecordIndex]
+							if record.Kind == ast.ImportStmt && !record.SourceIndex.IsValid() && !record.Flags.Has(ast.IsUnused) {
+								hasESMImports = true
+							}
+						}
+					}
+				}
+			}
+		}
+		if hasESMImports {
+			continue
+		}
+
+		// Only hoist long strings that are used many times. Short strings don't
+		// save much space after replacing them with an identifier, and repeated
+		// strings already compress well with gzip so it's not worth it unless
+		// there are enough of them.
+		type candidate struct {
+			text  string
+			count uint32
+		}
+		var candidates []candidate
+		for text, count := range counts {
+			if count >= minHoistedStringCount && len(text) >= minHoistedStringLength {
+				candidates = append(candidates, candidate{text: text, count: count})
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		sort.Slice(candidates, func(i int, j int) bool {
+			a, b := candidates[i], candidates[j]
+			if a.count != b.count {
+				return a.count > b.count
+			}
+			return a.text < b.text
+		})
+
+		// Declare the variables at the top of the chunk. The printer options used
+		// for the cross-chunk prefix don't include the hoisted strings, so these
+		// initializers will be printed as string literals.
+		decls := make([]js_ast.Decl, len(candidates))
+		chunkRepr.hoistedStrings = make(map[string]js_ast.Ref, len(candidates))
+		chunkRepr.hoistedStringUses = make([]hoistedStringUse, len(candidates))
+		for i, it := range candidates {
+			ref := c.graph.GenerateNewSymbol(runtime.SourceIndex, js_ast.SymbolOther, "str")
+			decls[i] = js_ast.Decl{
+				Binding:    js_ast.Binding{Data: &js_ast.BIdentifier{Ref: ref}},
+				ValueOrNil: js_ast.Expr{Data: &js_ast.EString{Value: helpers.StringToUTF16(it.text)}},
+			}
+			chunkRepr.hoistedStrings[it.text] = ref
+			chunkRepr.hoistedStringUses[i] = hoistedStringUse{ref: ref, count: it.count}
+		}
+		chunkRepr.crossChunkPrefixStmts = append(chunkRepr.crossChunkPrefixStmts,
+			js_ast.Stmt{Data: &js_ast.SLocal{Kind: kind, Decls: decls}})
+	}
+}
+
+const (
+	minHoistedStringCount  = 3
+	minHoistedStringLength = 8
+)
+
 type crossChunkImport struct {
 	sortedImportItems crossChunkImportItemArray
 	chunkIndex        uint32
@@ -1222,6 +1456,22 @@ func (c *linkerContext) scanImportsAndExports() {
 				otherFile := &c.graph.Files[record.SourceIndex.GetIndex()]
 				otherRepr := otherFile.InputFile.Repr.(*graph.JSRepr)
 
+				// Inline URLs for non-JS files referenced using "new URL()"
+				if record.Kind == ast.ImportNewURL {
+					record.Path.Text = otherRepr.AST.URLForCSS
+					record.Path.Namespace = ""
+					record.SourceIndex = ast.Index32{}
+					if otherFile.InputFile.Loader == config.LoaderEmpty {
+						record.Flags |= ast.WasLoadedWithEmptyLoader
+					} else {
+						record.Flags |= ast.ShouldNotBeExternalInMetafile
+					}
+
+					// Copy the additional files to the output directory
+					additionalFiles = append(additionalFiles, otherFile.InputFile.AdditionalFiles...)
+					continue
+				}
+
 				switch record.Kind {
 				case ast.ImportStmt:
 					// Importing using ES6 syntax from a file without any ES6 syntax
@@ -1279,7 +1529,7 @@ func (c *linkerContext) scanImportsAndExports() {
 			// resulting wrapper won't be invoked by other files. An exception is made
 			// for entry point files in CommonJS format (or when in pass-through mode).
 			if repr.AST.ExportsKind == js_ast.ExportsCommonJS && (!file.IsEntryPoint() ||
-				c.options.OutputFormat == config.FormatIIFE || c.options.OutputFormat == config.FormatESModule) {
+				c.options.OutputFormat.IsWrappedInFunction() || c.options.OutputFormat == config.FormatESModule) {
 				repr.Meta.Wrap = graph.WrapCJS
 			}
 		}
@@ -1526,6 +1776,9 @@ func (c *linkerContext) scanImportsAndExports() {
 					if (flags & (js_ast.IsEmptyFunction | js_ast.CouldPotentiallyBeMutated)) == js_ast.IsEmptyFunction {
 						// Every call will be inlined
 						continue
+					} else if (flags & (js_ast.IsConstReturnFunction | js_ast.CouldPotentiallyBeMutated)) == js_ast.IsConstReturnFunction {
+						// Every call will be inlined
+						continue
 					} else if (flags & (js_ast.IsIdentityFunction | js_ast.CouldPotentiallyBeMutated)) == js_ast.IsIdentityFunction {
 						// Every single-argument call will be inlined as long as it's not a spread
 						callUse.CallCountEstimate -= callUse.SingleArgNonSpreadCallCountEstimate
@@ -1623,7 +1876,22 @@ func (c *linkerContext) scanImportsAndExports() {
 			c.graph.GenerateSymbolImportAndUse(sourceIndex, js_ast.NSExportPartIndex, exportRef, 1, runtime.SourceIndex)
 		}
 
-		for importRef, importData := range repr.Meta.ImportsToBind {
+		// Sort imports for determinism. Otherwise the order of the dependencies
+		// of each part would depend on map iteration order. The keys aren't all
+		// from this file since symbols generated by the linker (e.g. from the
+		// runtime) are also bound here.
+		sortedImportRefs := make(stableRefArray, 0, len(repr.Meta.ImportsToBind))
+		for importRef := range repr.Meta.ImportsToBind {
+			sortedImportRefs = append(sortedImportRefs, stableRef{
+				StableSourceIndex: c.graph.StableSourceIndices[importRef.SourceIndex],
+				Ref:               importRef,
+			})
+		}
+		sort.Sort(sortedImportRefs)
+
+		for _, stable := range sortedImportRefs {
+			importRef := stable.Ref
+			importData := repr.Meta.ImportsToBind[importRef]
 			resolvedRepr := c.graph.Files[importData.SourceIndex].InputFile.Repr.(*graph.JSRepr)
 			partsDeclaringSymbol := resolvedRepr.TopLevelSymbolToParts(importData.Ref)
 
@@ -1713,6 +1981,12 @@ func (c *linkerContext) scanImportsAndExports() {
 			for _, importRecordIndex := range part.ImportRecordIndices {
 				record := &repr.AST.ImportRecords[importRecordIndex]
 
+				// Web workers are referenced by path and are never imported, so
+				// they don't need any of the runtime helpers for external imports
+				if record.Kind == ast.ImportWorker {
+					continue
+				}
+
 				// Don't follow external imports (this includes import() expressions)
 				if !record.SourceIndex.IsValid() || c.isExternalDynamicImport(record, sourceIndex) {
 					// This is an external import. Check if it will be a "require()" call.
@@ -1737,7 +2011,10 @@ func (c *linkerContext) scanImportsAndExports() {
 						// - The ES module namespace object must not be captured
 						// - The "default" and "__esModule" exports must not be accessed
 						//
-						if record.Kind != ast.ImportRequire &&
+						// This is also never needed with SystemJS because it already passes
+						// us the ES module namespace object for each external module.
+						//
+						if record.Kind != ast.ImportRequire && c.options.OutputFormat != config.FormatSystem &&
 							(record.Kind != ast.ImportStmt ||
 								record.Flags.Has(ast.ContainsImportStar) ||
 								record.Flags.Has(ast.ContainsDefaultAlias) ||
@@ -2722,6 +2999,174 @@ func (c *linkerContext) advanceImportTracker(tracker importTracker) (importTrack
 	return importTracker{sourceIndex: otherSourceIndex}, importNoMatch, nil
 }
 
+// TypeScript considers files without any import or export syntax to be
+// global scripts. A top-level namespace in one of these files is merged with
+// all top-level namespaces of the same name in other script files, and a
+// reference to that name from another script file refers to the merged
+// namespace. Bind these symbols together so that code which spreads a single
+// namespace across many files still works when those files are bundled.
+func (c *linkerContext) mergeTSScriptNamespaces() {
+	// This isn't done with code splitting because the merged symbol would then
+	// be declared in more than one chunk
+	if c.options.CodeSplitting {
+		return
+	}
+
+	type scriptRef struct {
+		sourceIndex uint32
+		ref         js_ast.Ref
+	}
+
+	// Find all top-level namespaces declared in unwrapped script files
+	declarations := make(map[string][]scriptRef)
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		if !ok || !repr.AST.IsTypeScriptScript || repr.Meta.Wrap != graph.WrapNone {
+			continue
+		}
+		for name, member := range repr.AST.ModuleScope.Members {
+			if c.graph.Symbols.Get(member.Ref).Kind == js_ast.SymbolTSNamespace {
+				declarations[name] = append(declarations[name], scriptRef{sourceIndex: sourceIndex, ref: member.Ref})
+			}
+		}
+	}
+
+	// Also find references to those names that aren't bound to anything
+	references := make(map[string][]scriptRef)
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		if !ok || !repr.AST.IsTypeScriptScript || repr.Meta.Wrap != graph.WrapNone {
+			continue
+		}
+		for name, member := range repr.AST.ModuleScope.Members {
+			if c.graph.Symbols.Get(member.Ref).Kind == js_ast.SymbolUnbound && len(declarations[name]) > 0 {
+				references[name] = append(references[name], scriptRef{sourceIndex: sourceIndex, ref: member.Ref})
+			}
+		}
+	}
+
+	// Sort the names so that the output is deterministic
+	sortedNames := make([]string, 0, len(declarations))
+	for name, refs := range declarations {
+		if len(refs) > 1 || len(references[name]) > 0 {
+			sortedNames = append(sortedNames, name)
+		}
+	}
+	sort.Strings(sortedNames)
+
+	for _, name := range sortedNames {
+		decls := declarations[name]
+		group := append(append([]scriptRef{}, decls...), references[name]...)
+
+		// Every part that uses this namespace must depend on the parts in other
+		// files that declare it, or tree shaking could remove some of them
+		var declaringParts []js_ast.Dependency
+		for _, decl := range decls {
+			repr := c.graph.Files[decl.sourceIndex].InputFile.Repr.(*graph.JSRepr)
+			for _, partIndex := range repr.TopLevelSymbolToParts(decl.ref) {
+				declaringParts = append(declaringParts, js_ast.Dependency{SourceIndex: decl.sourceIndex, PartIndex: partIndex})
+			}
+		}
+		for _, item := range group {
+			repr := c.graph.Files[item.sourceIndex].InputFile.Repr.(*graph.JSRepr)
+			for partIndex := range repr.AST.Parts {
+				part := &repr.AST.Parts[partIndex]
+				if _, ok := part.SymbolUses[item.ref]; !ok {
+					continue
+				}
+				for _, dep := range declaringParts {
+					if dep.SourceIndex != item.sourceIndex {
+						part.Dependencies = append(part.Dependencies, dep)
+					}
+				}
+			}
+		}
+
+		// Merge these symbols so they will share the same name
+		for _, item := range group[1:] {
+			js_ast.MergeSymbols(c.graph.Symbols, item.ref, decls[0].ref)
+		}
+	}
+}
+
+// When a JSON file is imported with a default import that is only used to
+// read properties with static names (e.g. "data.foo"), the properties that
+// are never read don't need to be included in the default export object.
+func (c *linkerContext) findUsedLazyDefaultExportProperties() {
+	used := make(map[uint32]map[string]bool)
+	isUnsafe := make(map[uint32]bool)
+
+	// Find all imports that were bound to the default export of a lazy file
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		if !ok {
+			continue
+		}
+		for importRef, importData := range repr.Meta.ImportsToBind {
+			otherRepr := c.graph.Files[importData.SourceIndex].InputFile.Repr.(*graph.JSRepr)
+			if !otherRepr.AST.HasLazyExport {
+				continue
+			}
+			if export, ok := otherRepr.Meta.ResolvedExports["default"]; !ok || export.Ref != importData.Ref {
+				continue
+			}
+
+			// Any use other than a static property read may observe every property
+			namedImport, ok := repr.AST.NamedImports[importRef]
+			if !ok || namedImport.PropertyReadsOrNil == nil || namedImport.IsExported {
+				isUnsafe[importData.SourceIndex] = true
+				continue
+			}
+			names := used[importData.SourceIndex]
+			if names == nil {
+				names = make(map[string]bool)
+				used[importData.SourceIndex] = names
+			}
+			for name := range namedImport.PropertyReadsOrNil {
+				names[name] = true
+			}
+		}
+	}
+
+	for sourceIndex, names := range used {
+		file := &c.graph.Files[sourceIndex]
+		repr := file.InputFile.Repr.(*graph.JSRepr)
+
+		// The default export can also be observed through the export namespace
+		// object (e.g. "import * as ns" or "import()") or by being an entry point
+		if isUnsafe[sourceIndex] || file.IsEntryPoint() || repr.AST.Parts[js_ast.NSExportPartIndex].IsLive {
+			continue
+		}
+
+		// Be careful: the top-level value in a JSON file is not necessarily an object
+		defaultExport := repr.Meta.ResolvedExports["default"]
+		stmt := repr.AST.Parts[repr.TopLevelSymbolToParts(defaultExport.Ref)[0]].Stmts[0]
+		object, ok := stmt.Data.(*js_ast.SExportDefault).Value.Data.(*js_ast.SExpr).Value.Data.(*js_ast.EObject)
+		if !ok {
+			continue
+		}
+
+		// Only do this if every property read is of a property that exists. Reading
+		// something else (e.g. "data.hasOwnProperty") may depend on the others.
+		keys := make(map[string]bool)
+		for _, property := range object.Properties {
+			if str, ok := property.Key.Data.(*js_ast.EString); ok {
+				keys[helpers.UTF16ToString(str.Value)] = true
+			}
+		}
+		isMissing := false
+		for name := range names {
+			if !keys[name] {
+				isMissing = true
+				break
+			}
+		}
+		if !isMissing {
+			repr.Meta.UsedLazyDefaultExportPropertiesOrNil = names
+		}
+	}
+}
+
 func (c *linkerContext) treeShakingAndCodeSplitting() {
 	// Tree shaking: Each entry point marks all files reachable from itself
 	c.timer.Begin("Tree shaking")
@@ -2741,6 +3186,15 @@ func (c *linkerContext) treeShakingAndCodeSplitting() {
 	c.timer.End("Code splitting")
 }
 
+func hasLiveRuntimeParts(repr *graph.JSRepr) bool {
+	for partIndex, part := range repr.AST.Parts {
+		if part.IsLive && uint32(partIndex) != js_ast.NSExportPartIndex {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *linkerContext) markFileReachableForCodeSplitting(sourceIndex uint32, entryPointBit uint, distanceFromEntryPoint uint32) {
 	file := &c.graph.Files[sourceIndex]
 	if !file.IsLive {
@@ -3123,11 +3577,21 @@ func (c *linkerContext) computeChunks() {
 	// Figure out which JS files are in which chunk
 	for _, sourceIndex := range c.graph.ReachableFiles {
 		if file := &c.graph.Files[sourceIndex]; file.IsLive {
-			if _, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
+			if repr, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
 				key := file.EntryBits.String()
+
+				// Optionally give the runtime its own chunk instead of letting it share
+				// a chunk with other code. This key can't collide with the others since
+				// those are always made of the digits of a bit set.
+				isRuntimeChunk := sourceIndex == runtime.SourceIndex && c.options.RuntimeChunk && hasLiveRuntimeParts(repr)
+				if isRuntimeChunk {
+					key = "runtime"
+				}
+
 				chunk, ok := jsChunks[key]
 				if !ok {
 					chunk.entryBits = file.EntryBits
+					chunk.isRuntimeChunk = isRuntimeChunk
 					chunk.filesWithPartsInChunk = make(map[uint32]bool)
 					chunk.chunkRepr = &chunkReprJS{}
 					jsChunks[key] = chunk
@@ -3252,6 +3716,9 @@ func (c *linkerContext) computeChunks() {
 		} else {
 			dir = "/"
 			base = "chunk"
+			if chunk.isRuntimeChunk {
+				base = "runtime"
+			}
 			ext = stdExt
 			template = c.options.ChunkPathTemplate
 		}
@@ -3350,7 +3817,7 @@ func (c *linkerContext) findImportedPartsInJSOrder(chunk *chunkInfo) (js []uint3
 		file := &c.graph.Files[sourceIndex]
 
 		if repr, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
-			isFileInThisChunk := chunk.entryBits.Equals(file.EntryBits)
+			isFileInThisChunk := chunk.filesWithPartsInChunk[sourceIndex]
 
 			// Wrapped files can't be split because they are all inside the wrapper
 			canFileBeSplit := repr.Meta.Wrap == graph.WrapNone
@@ -3816,6 +4283,8 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 	toCommonJSRef js_ast.Ref,
 	toESMRef js_ast.Ref,
 	runtimeRequireRef js_ast.Ref,
+	systemExportAliases map[js_ast.Ref][]string,
+	hoistedStrings map[string]js_ast.Ref,
 	result *compileResultJS,
 	dataForSourceMaps []bundler.DataForSourceMap,
 ) {
@@ -3827,12 +4296,14 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 	needsWrapper := false
 	stmtList := stmtList{}
 
-	// The top-level directive must come first (the non-wrapped case is handled
-	// by the chunk generation code, although only for the entry point)
-	if repr.AST.Directive != "" && repr.Meta.Wrap != graph.WrapNone && !file.IsEntryPoint() {
-		stmtList.insideWrapperPrefix = append(stmtList.insideWrapperPrefix, js_ast.Stmt{
-			Data: &js_ast.SDirective{Value: helpers.StringToUTF16(repr.AST.Directive)},
-		})
+	// The top-level directives must come first (the non-wrapped case is handled
+	// by the chunk generation code)
+	if repr.Meta.Wrap != graph.WrapNone && !file.IsEntryPoint() {
+		for _, directive := range repr.AST.Directives {
+			stmtList.insideWrapperPrefix = append(stmtList.insideWrapperPrefix, js_ast.Stmt{
+				Data: &js_ast.SDirective{Value: helpers.StringToUTF16(directive)},
+			})
+		}
 	}
 
 	// Make sure the generated call to "__export(exports, ...)" comes first
@@ -3913,21 +4384,29 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 			// Be careful: the top-level value in a JSON file is not necessarily an object
 			if object, ok := defaultExpr.Value.Data.(*js_ast.EObject); ok {
 				objectClone := *object
-				objectClone.Properties = append([]js_ast.Property{}, objectClone.Properties...)
+				objectClone.Properties = make([]js_ast.Property, 0, len(object.Properties))
 
 				// If any top-level properties ended up being imported directly, change
 				// the property to just reference the corresponding variable instead
-				for i, property := range object.Properties {
+				for _, property := range object.Properties {
 					if str, ok := property.Key.Data.(*js_ast.EString); ok {
-						if name := helpers.UTF16ToString(str.Value); name != "default" {
+						name := helpers.UTF16ToString(str.Value)
+
+						// Omit properties that are never read off of the default export
+						if used := repr.Meta.UsedLazyDefaultExportPropertiesOrNil; used != nil && !used[name] {
+							continue
+						}
+
+						if name != "default" {
 							if export, ok := repr.Meta.ResolvedExports[name]; ok {
 								if part := repr.AST.Parts[repr.TopLevelSymbolToParts(export.Ref)[0]]; part.IsLive {
 									ref := part.Stmts[0].Data.(*js_ast.SLocal).Decls[0].Binding.Data.(*js_ast.BIdentifier).Ref
-									objectClone.Properties[i].ValueOrNil = js_ast.Expr{Loc: property.Key.Loc, Data: &js_ast.EIdentifier{Ref: ref}}
+									property.ValueOrNil = js_ast.Expr{Loc: property.Key.Loc, Data: &js_ast.EIdentifier{Ref: ref}}
 								}
 							}
 						}
 					}
+					objectClone.Properties = append(objectClone.Properties, property)
 				}
 
 				// Avoid mutating the original AST
@@ -4095,14 +4574,13 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 	}
 
 	// Indent the file if everything is wrapped in an IIFE
-	indent := 0
-	if c.options.OutputFormat == config.FormatIIFE {
-		indent++
-	}
+	indent := c.wrapperIndent()
 
 	// Convert the AST to JavaScript code
 	printOptions := js_printer.Options{
 		Indent:                       indent,
+		IndentUnit:                   c.options.IndentUnit,
+		PreferSingleQuotes:           c.options.PreferSingleQuotes,
 		OutputFormat:                 c.options.OutputFormat,
 		MinifyIdentifiers:            c.options.MinifyIdentifiers,
 		MinifyWhitespace:             c.options.MinifyWhitespace,
@@ -4113,18 +4591,25 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		RuntimeRequireRef:            runtimeRequireRef,
 		TSEnums:                      c.graph.TSEnums,
 		ConstValues:                  c.graph.ConstValues,
+		ConstReturnValues:            c.graph.ConstReturnValues,
 		LegalComments:                c.options.LegalComments,
+		RecordLegalComments:          c.options.ExtractLicenses,
 		UnsupportedFeatures:          c.options.UnsupportedJSFeatures,
 		SourceMap:                    c.options.SourceMap,
 		AddSourceMappings:            addSourceMappings,
+		SourceMapLinesOnly:           c.options.SourceMapLinesOnly,
 		InputSourceMap:               inputSourceMap,
 		LineOffsetTables:             lineOffsetTables,
 		RequireOrImportMetaForSource: c.requireOrImportMetaForSource,
 		MangledProps:                 c.mangledProps,
+		UnusedMangledMethods:         c.unusedMangledMethods,
 		NeedsMetafile:                c.options.NeedsMetafile,
+		SystemExportAliases:          systemExportAliases,
+		HoistedStrings:               hoistedStrings,
+		SystemExportRef:              c.systemExportRef,
 	}
 	tree := repr.AST
-	tree.Directive = "" // This is handled elsewhere
+	tree.Directives = nil // This is handled elsewhere
 	tree.Parts = []js_ast.Part{{Stmts: stmts}}
 	*result = compileResultJS{
 		PrintResult: js_printer.Print(tree, c.graph.Symbols, r, printOptions),
@@ -4139,6 +4624,43 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 	waitGroup.Done()
 }
 
+// Directives only have an effect at the top of the file. Entry point chunks
+// use the directives from the entry point. Other chunks can contain code from
+// many files, so they only use the directives that all of those files share.
+func (c *linkerContext) directivesForChunk(chunk *chunkInfo, chunkRepr *chunkReprJS) []string {
+	if chunk.isEntryPoint {
+		return c.graph.Files[chunk.sourceIndex].InputFile.Repr.(*graph.JSRepr).AST.Directives
+	}
+
+	var directives []string
+	isFirstFile := true
+	for _, sourceIndex := range chunkRepr.filesInChunkInOrder {
+		if sourceIndex == runtime.SourceIndex {
+			continue
+		}
+		repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		if !ok {
+			continue
+		}
+		if isFirstFile {
+			directives = repr.AST.Directives
+			isFirstFile = false
+			continue
+		}
+		var shared []string
+		for _, directive := range directives {
+			for _, other := range repr.AST.Directives {
+				if directive == other {
+					shared = append(shared, directive)
+					break
+				}
+			}
+		}
+		directives = shared
+	}
+	return directives
+}
+
 func (c *linkerContext) generateEntryPointTailJS(
 	r renamer.Renamer,
 	toCommonJSRef js_ast.Ref,
@@ -4159,9 +4681,9 @@ func (c *linkerContext) generateEntryPointTailJS(
 			}}}})
 		}
 
-	case config.FormatIIFE:
+	case config.FormatIIFE, config.FormatUMD:
 		if repr.Meta.Wrap == graph.WrapCJS {
-			if len(c.options.GlobalName) > 0 {
+			if len(c.options.GlobalName) > 0 || c.options.OutputFormat == config.FormatUMD {
 				// "return require_foo();"
 				stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Data: &js_ast.ECall{
 					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
@@ -4191,6 +4713,33 @@ func (c *linkerContext) generateEntryPointTailJS(
 			}
 		}
 
+	case config.FormatSystem:
+		if repr.Meta.Wrap == graph.WrapCJS {
+			// "_export("default", require_foo());"
+			stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SExpr{Value: js_ast.Expr{Data: &js_ast.ECall{
+				Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: c.systemExportRef}},
+				Args: []js_ast.Expr{
+					{Data: &js_ast.EString{Value: helpers.StringToUTF16("default")}},
+					{Data: &js_ast.ECall{Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}}}},
+				},
+			}}}})
+		} else {
+			if repr.Meta.Wrap == graph.WrapESM {
+				// "init_foo();"
+				stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SExpr{Value: js_ast.Expr{Data: &js_ast.ECall{
+					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
+				}}}})
+			}
+
+			if repr.Meta.ForceIncludeExportsForEntryPoint {
+				// "_export(exports);"
+				stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SExpr{Value: js_ast.Expr{Data: &js_ast.ECall{
+					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: c.systemExportRef}},
+					Args:   []js_ast.Expr{{Data: &js_ast.EIdentifier{Ref: repr.AST.ExportsRef}}},
+				}}}})
+			}
+		}
+
 	case config.FormatCommonJS:
 		if repr.Meta.Wrap == graph.WrapCJS {
 			// "module.exports = require_foo();"
@@ -4420,18 +4969,17 @@ func (c *linkerContext) generateEntryPointTailJS(
 	}
 
 	tree := repr.AST
-	tree.Directive = ""
+	tree.Directives = nil
 	tree.Parts = []js_ast.Part{{Stmts: stmts}}
 
 	// Indent the file if everything is wrapped in an IIFE
-	indent := 0
-	if c.options.OutputFormat == config.FormatIIFE {
-		indent++
-	}
+	indent := c.wrapperIndent()
 
 	// Convert the AST to JavaScript code
 	printOptions := js_printer.Options{
 		Indent:                       indent,
+		IndentUnit:                   c.options.IndentUnit,
+		PreferSingleQuotes:           c.options.PreferSingleQuotes,
 		OutputFormat:                 c.options.OutputFormat,
 		MinifyIdentifiers:            c.options.MinifyIdentifiers,
 		MinifyWhitespace:             c.options.MinifyWhitespace,
@@ -4443,6 +4991,7 @@ func (c *linkerContext) generateEntryPointTailJS(
 		UnsupportedFeatures:          c.options.UnsupportedJSFeatures,
 		RequireOrImportMetaForSource: c.requireOrImportMetaForSource,
 		MangledProps:                 c.mangledProps,
+		UnusedMangledMethods:         c.unusedMangledMethods,
 	}
 	result.PrintResult = js_printer.Print(tree, c.graph.Symbols, r, printOptions)
 	return
@@ -4470,6 +5019,30 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 		reservedNames["require"] = 1
 		reservedNames["Promise"] = 1
 	}
+
+	// The SystemJS wrapper passes this to the code inside it
+	if c.options.OutputFormat == config.FormatSystem {
+		reservedNames["_export"] = 1
+	}
+
+	// These may be used by code outside of the bundle
+	for _, name := range c.options.ReserveNames {
+		reservedNames[name] = 1
+	}
+
+	// These are imported by the code that defines missing CommonJS globals
+	if chunkRepr, ok := chunk.chunkRepr.(*chunkReprJS); ok {
+		globals := chunkRepr.nodeESMGlobals
+		if globals.require {
+			reservedNames["createRequire"] = 1
+		}
+		if globals.filename || globals.dirname {
+			reservedNames["fileURLToPath"] = 1
+		}
+		if globals.dirname {
+			reservedNames["dirname"] = 1
+		}
+	}
 	timer.End("Compute reserved names")
 
 	// Make sure imports get a chance to be renamed too
@@ -4549,6 +5122,9 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 		for _, stable := range sortedImportsFromOtherChunks {
 			r.AccumulateSymbolCount(&topLevelSymbols, stable.Ref, 1, stableSourceIndices)
 		}
+		for _, use := range chunk.chunkRepr.(*chunkReprJS).hoistedStringUses {
+			r.AccumulateSymbolCount(&topLevelSymbols, use.ref, use.count+1, stableSourceIndices)
+		}
 		for _, array := range allTopLevelSymbols {
 			topLevelSymbols = append(topLevelSymbols, array...)
 		}
@@ -4702,6 +5278,7 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	toCommonJSRef := js_ast.FollowSymbols(c.graph.Symbols, runtimeMembers["__toCommonJS"].Ref)
 	toESMRef := js_ast.FollowSymbols(c.graph.Symbols, runtimeMembers["__toESM"].Ref)
 	runtimeRequireRef := js_ast.FollowSymbols(c.graph.Symbols, runtimeMembers["__require"].Ref)
+	chunkRepr.nodeESMGlobals = c.findNodeESMGlobalsInChunk(chunkRepr)
 	r := c.renameSymbolsInChunk(chunk, chunkRepr.filesInChunkInOrder, timer)
 	dataForSourceMaps := c.dataForSourceMaps()
 
@@ -4714,6 +5291,23 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	// never change the "../" count.
 	chunkAbsDir := c.fs.Dir(c.fs.Join(c.options.AbsOutputDir, config.TemplateToString(chunk.finalTemplate)))
 
+	// With SystemJS, assignments to exported variables must also update the
+	// exports of the module so that other modules see the new value
+	var systemExportAliases map[js_ast.Ref][]string
+	if c.options.OutputFormat == config.FormatSystem && chunk.isEntryPoint {
+		if repr := c.graph.Files[chunk.sourceIndex].InputFile.Repr.(*graph.JSRepr); repr.Meta.Wrap != graph.WrapCJS {
+			systemExportAliases = make(map[js_ast.Ref][]string)
+			for _, alias := range repr.Meta.SortedAndFilteredExportAliases {
+				export := repr.Meta.ResolvedExports[alias]
+				if importData, ok := c.graph.Files[export.SourceIndex].InputFile.Repr.(*graph.JSRepr).Meta.ImportsToBind[export.Ref]; ok {
+					export.Ref = importData.Ref
+				}
+				ref := js_ast.FollowSymbols(c.graph.Symbols, export.Ref)
+				systemExportAliases[ref] = append(systemExportAliases[ref], alias)
+			}
+		}
+	}
+
 	// Generate JavaScript for each file in parallel
 	timer.Begin("Print JavaScript files")
 	waitGroup := sync.WaitGroup{}
@@ -4736,6 +5330,8 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 			toCommonJSRef,
 			toESMRef,
 			runtimeRequireRef,
+			systemExportAliases,
+			chunkRepr.hoistedStrings,
 			compileResult,
 			dataForSourceMaps,
 		)
@@ -4747,17 +5343,18 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	var jsonMetadataImports []string
 	{
 		// Indent the file if everything is wrapped in an IIFE
-		indent := 0
-		if c.options.OutputFormat == config.FormatIIFE {
-			indent++
-		}
+		indent := c.wrapperIndent()
 		printOptions := js_printer.Options{
 			Indent:            indent,
+			IndentUnit:        c.options.IndentUnit,
 			OutputFormat:      c.options.OutputFormat,
 			MinifyIdentifiers: c.options.MinifyIdentifiers,
 			MinifyWhitespace:  c.options.MinifyWhitespace,
 			MinifySyntax:      c.options.MinifySyntax,
+			ASCIIOnly:         c.options.ASCIIOnly,
 			NeedsMetafile:     c.options.NeedsMetafile,
+
+			PreferSingleQuotes: c.options.PreferSingleQuotes,
 		}
 		crossChunkImportRecords := make([]ast.ImportRecord, len(chunk.crossChunkImports))
 		for i, chunkImport := range chunk.crossChunkImports {
@@ -4806,6 +5403,7 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	}
 	newlineBeforeComment := false
 	isExecutable := false
+	directives := c.directivesForChunk(chunk, chunkRepr)
 
 	// Start with the hashbang if there is one. This must be done before the
 	// banner because it only works if it's literally the first character.
@@ -4828,14 +5426,16 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 		j.AddString(c.options.JSBanner)
 		j.AddString("\n")
 		newlineBeforeComment = true
+		if chunk.isEntryPoint && strings.HasPrefix(c.options.JSBanner, "#!") {
+			isExecutable = true
+		}
 	}
 
-	// Add the top-level directive if present (but omit "use strict" in ES
+	// Add the top-level directives if present (but omit "use strict" in ES
 	// modules because all ES modules are automatically in strict mode)
-	if chunk.isEntryPoint {
-		if repr := c.graph.Files[chunk.sourceIndex].InputFile.Repr.(*graph.JSRepr); repr.AST.Directive != "" &&
-			(repr.AST.Directive != "use strict" || c.options.OutputFormat != config.FormatESModule) {
-			quoted := string(helpers.QuoteForJSON(repr.AST.Directive, c.options.ASCIIOnly)) + ";" + newline
+	for _, directive := range directives {
+		if directive != "use strict" || c.options.OutputFormat != config.FormatESModule {
+			quoted := c.quoteString(directive) + ";" + newline
 			prevOffset.AdvanceString(quoted)
 			j.AddString(quoted)
 			newlineBeforeComment = true
@@ -4845,7 +5445,7 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	// Optionally wrap with an IIFE
 	if c.options.OutputFormat == config.FormatIIFE {
 		var text string
-		indent = "  "
+		indent = c.indentUnit()
 		if len(c.options.GlobalName) > 0 {
 			text = c.generateGlobalNamePrefix()
 		}
@@ -4857,6 +5457,25 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 		prevOffset.AdvanceString(text)
 		j.AddString(text)
 		newlineBeforeComment = false
+	} else if c.options.OutputFormat == config.FormatUMD {
+		text := c.generateUMDPrefix(c.findExternalImportPathsInChunk(chunkRepr))
+		indent = c.indentUnit()
+		prevOffset.AdvanceString(text)
+		j.AddString(text)
+		newlineBeforeComment = false
+	} else if c.options.OutputFormat == config.FormatSystem {
+		text := c.generateSystemPrefix(c.findExternalImportPathsInChunk(chunkRepr))
+		indent = strings.Repeat(c.indentUnit(), 3)
+		prevOffset.AdvanceString(text)
+		j.AddString(text)
+		newlineBeforeComment = false
+	}
+
+	// Define any CommonJS globals that node doesn't provide to ES modules
+	if text := c.generateNodeESMGlobals(chunkRepr.nodeESMGlobals); text != "" {
+		prevOffset.AdvanceString(text)
+		j.AddString(text)
+		newlineBeforeComment = true
 	}
 
 	// Put the cross-chunk prefix inside the IIFE
@@ -4937,6 +5556,9 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 		if chunkRepr.hasCSSChunk {
 			jMeta.AddString(fmt.Sprintf("      \"cssBundle\": %s,\n", helpers.QuoteForJSON(c.chunks[chunkRepr.cssChunkIndex].uniqueKey, c.options.ASCIIOnly)))
 		}
+		if len(directives) > 0 {
+			jMeta.AddString(fmt.Sprintf("      \"directives\": %s,\n", helpers.QuoteStringArrayForJSON(directives, c.options.ASCIIOnly)))
+		}
 		jMeta.AddString("      \"inputs\": {")
 	}
 
@@ -5041,6 +5663,15 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	// Optionally wrap with an IIFE
 	if c.options.OutputFormat == config.FormatIIFE {
 		j.AddString("})();" + newline)
+	} else if c.options.OutputFormat == config.FormatUMD {
+		j.AddString("});" + newline)
+	} else if c.options.OutputFormat == config.FormatSystem {
+		if c.options.MinifyWhitespace {
+			j.AddString("}}});")
+		} else {
+			unit := c.indentUnit()
+			j.AddString(unit + unit + "}\n" + unit + "};\n});\n")
+		}
 	}
 
 	// Make sure the file ends with a newline
@@ -5050,6 +5681,9 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 		slashTag = ""
 	}
 	c.maybeAppendLegalComments(c.options.LegalComments, legalCommentList, chunk, &j, slashTag)
+	if c.options.ExtractLicenses {
+		chunk.legalCommentsForLicenses = legalCommentList
+	}
 
 	if len(c.options.JSFooter) > 0 {
 		j.AddString(c.options.JSFooter)
@@ -5169,6 +5803,261 @@ func (c *linkerContext) generateGlobalNamePrefix() string {
 	return text
 }
 
+// Node doesn't provide "require", "__filename", or "__dirname" to ES modules,
+// but code that was originally written as CommonJS often uses them. So when
+// bundling ES module output for node, we check which of these are used by
+// live code in the chunk so that they can be defined at the top of the chunk.
+func (c *linkerContext) findNodeESMGlobalsInChunk(chunkRepr *chunkReprJS) (globals nodeESMGlobals) {
+	if c.options.Mode != config.ModeBundle || c.options.OutputFormat != config.FormatESModule || c.options.Platform != config.PlatformNode {
+		return
+	}
+
+	for _, sourceIndex := range chunkRepr.filesInChunkInOrder {
+		repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		if !ok {
+			continue
+		}
+		for _, name := range []string{"require", "__filename", "__dirname"} {
+			member, ok := repr.AST.ModuleScope.Members[name]
+			if !ok || c.graph.Symbols.Get(member.Ref).Kind != js_ast.SymbolUnbound {
+				continue
+			}
+			isUsed := false
+			for _, part := range repr.AST.Parts {
+				if _, ok := part.SymbolUses[member.Ref]; ok && part.IsLive {
+					isUsed = true
+					break
+				}
+			}
+			if isUsed {
+				switch name {
+				case "require":
+					globals.require = true
+				case "__filename":
+					globals.filename = true
+				case "__dirname":
+					globals.dirname = true
+				}
+			}
+		}
+	}
+	return
+}
+
+func (c *linkerContext) generateNodeESMGlobals(globals nodeESMGlobals) string {
+	space := " "
+	newline := "\n"
+	if c.options.MinifyWhitespace {
+		space = ""
+		newline = ""
+	}
+
+	var text string
+	if globals.require {
+		text += fmt.Sprintf("import%s{%screateRequire%s}%sfrom%s\"module\";%s", space, space, space, space, space, newline)
+	}
+	if globals.filename || globals.dirname {
+		text += fmt.Sprintf("import%s{%sfileURLToPath%s}%sfrom%s\"url\";%s", space, space, space, space, space, newline)
+	}
+	if globals.dirname {
+		text += fmt.Sprintf("import%s{%sdirname%s}%sfrom%s\"path\";%s", space, space, space, space, space, newline)
+	}
+	if globals.require {
+		text += fmt.Sprintf("const require%s=%screateRequire(import.meta.url);%s", space, space, newline)
+	}
+	if globals.filename {
+		text += fmt.Sprintf("const __filename%s=%sfileURLToPath(import.meta.url);%s", space, space, newline)
+	}
+	if globals.dirname {
+		if globals.filename {
+			text += fmt.Sprintf("const __dirname%s=%sdirname(__filename);%s", space, space, newline)
+		} else {
+			text += fmt.Sprintf("const __dirname%s=%sdirname(fileURLToPath(import.meta.url));%s", space, space, newline)
+		}
+	}
+	return text
+}
+
+// This is the text for one level of indentation in non-minified output
+func (c *linkerContext) indentUnit() string {
+	if c.options.IndentUnit != "" {
+		return c.options.IndentUnit
+	}
+	return "  "
+}
+
+// This quotes a string in code generated by the linker. Single quotes are
+// used if requested and if that doesn't require any additional escapes.
+func (c *linkerContext) quoteString(text string) string {
+	quoted := string(helpers.QuoteForJSON(text, c.options.ASCIIOnly))
+	if c.options.PreferSingleQuotes && !strings.ContainsAny(text, "'\"\\") {
+		quoted = "'" + quoted[1:len(quoted)-1] + "'"
+	}
+	return quoted
+}
+
+// This is how deeply the bundled code is nested inside the functions that wrap
+// everything in formats that need a wrapper
+func (c *linkerContext) wrapperIndent() int {
+	switch c.options.OutputFormat {
+	case config.FormatIIFE, config.FormatUMD:
+		return 1
+	case config.FormatSystem:
+		return 3
+	}
+	return 0
+}
+
+// This returns the paths of all external modules that are imported by live
+// code in this chunk, in the order they first appear. These must be listed as
+// dependencies in the "define()" call of the UMD wrapper and in the
+// "System.register()" call of the SystemJS wrapper.
+func (c *linkerContext) findExternalImportPathsInChunk(chunkRepr *chunkReprJS) (paths []string) {
+	seen := make(map[string]bool)
+	for _, sourceIndex := range chunkRepr.filesInChunkInOrder {
+		repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		if !ok {
+			continue
+		}
+		for _, part := range repr.AST.Parts {
+			if !part.IsLive {
+				continue
+			}
+			for _, importRecordIndex := range part.ImportRecordIndices {
+				record := &repr.AST.ImportRecords[importRecordIndex]
+				if record.SourceIndex.IsValid() || record.Flags.Has(ast.IsUnused) ||
+					(record.Kind != ast.ImportStmt && record.Kind != ast.ImportRequire) {
+					continue
+				}
+				if !seen[record.Path.Text] {
+					seen[record.Path.Text] = true
+					paths = append(paths, record.Path.Text)
+				}
+			}
+		}
+	}
+	return
+}
+
+func (c *linkerContext) generateUMDPrefix(externalPaths []string) string {
+	space := " "
+	newline := "\n"
+	indent := c.indentUnit()
+	root := "root"
+	factory := "factory"
+	if c.options.MinifyWhitespace {
+		space = ""
+		newline = ""
+		indent = ""
+	}
+	if c.options.MinifyIdentifiers {
+		root = "r"
+		factory = "f"
+	}
+
+	propertyAccess := func(name string) string {
+		if js_printer.CanEscapeIdentifier(name, c.options.UnsupportedJSFeatures, c.options.ASCIIOnly) {
+			if c.options.ASCIIOnly {
+				name = string(js_printer.QuoteIdentifier(nil, name, c.options.UnsupportedJSFeatures))
+			}
+			return "." + name
+		}
+		return fmt.Sprintf("[%s]", c.quoteString(name))
+	}
+
+	// AMD: "define(["require", "dep"], factory)"
+	amdDeps := c.quoteString("require")
+	for _, path := range externalPaths {
+		amdDeps += fmt.Sprintf(",%s%s", space, c.quoteString(path))
+	}
+
+	// Script tags: external modules are read from global variables instead
+	globalRequire := ""
+	globalItems := ""
+	for _, path := range externalPaths {
+		if globalName, ok := c.options.UMDGlobals[path]; ok {
+			value := root
+			for _, name := range globalName {
+				value += propertyAccess(name)
+			}
+			if globalItems != "" {
+				globalItems += ","
+			}
+			globalItems += fmt.Sprintf("%s%s:%s%s", space, c.quoteString(path), space, value)
+		}
+	}
+	if globalItems != "" {
+		globalRequire = fmt.Sprintf("function(id)%s{%sreturn%s{%s%s}[id];%s}", space, space, space, globalItems, space, space)
+	}
+
+	// Script tags: the exports are assigned to the global name, if there is one
+	globalTarget := ""
+	if len(c.options.GlobalName) > 0 {
+		plain := root
+		target := root
+		for i, name := range c.options.GlobalName {
+			access := propertyAccess(name)
+			plain += access
+			target += access
+			if i+1 < len(c.options.GlobalName) {
+				target = fmt.Sprintf("(%s%s=%s%s%s||%s{})", target, space, space, plain, space, space)
+			}
+		}
+		globalTarget = target + space + "=" + space
+	}
+
+	quotedFunction := c.quoteString("function")
+	quotedObject := c.quoteString("object")
+	quotedUndefined := c.quoteString("undefined")
+	return fmt.Sprintf("(function(%s,%s%s)%s{%s", root, space, factory, space, newline) +
+		fmt.Sprintf("%sif%s(typeof define%s===%s%s%s&&%sdefine.amd)%sdefine([%s],%s%s);%s",
+			indent, space, space, space, quotedFunction, space, space, space, amdDeps, space, factory, newline) +
+		fmt.Sprintf("%selse if%s(typeof module%s===%s%s%s&&%smodule.exports)%smodule.exports%s=%s%s(require);%s",
+			indent, space, space, space, quotedObject, space, space, space, space, space, factory, newline) +
+		fmt.Sprintf("%selse %s%s(%s);%s", indent, globalTarget, factory, globalRequire, newline) +
+		fmt.Sprintf("})(typeof globalThis%s!==%s%s%s?%sglobalThis%s:%stypeof self%s!==%s%s%s?%sself%s:%sthis,%sfunction(require)%s{%s",
+			space, space, quotedUndefined, space, space, space, space, space, space, quotedUndefined, space, space, space, space, space, space, newline)
+}
+
+func (c *linkerContext) generateSystemPrefix(externalPaths []string) string {
+	space := " "
+	newline := "\n"
+	indent := c.indentUnit()
+	if c.options.MinifyWhitespace {
+		space = ""
+		newline = ""
+		indent = ""
+	}
+
+	// Each external module is passed to a setter function as its module
+	// namespace object. The code inside the wrapper gets them from "require()".
+	deps := ""
+	setters := ""
+	for i, path := range externalPaths {
+		quoted := c.quoteString(path)
+		if i > 0 {
+			deps += "," + space
+			setters += ","
+		}
+		deps += quoted
+		setters += fmt.Sprintf("%s%s%s%sfunction(m)%s{%smodules[%s]%s=%sm;%s}",
+			newline, indent, indent, indent, space, space, quoted, space, space, space)
+	}
+	if setters != "" {
+		setters += newline + indent + indent
+	}
+
+	text := fmt.Sprintf("System.register([%s],%sfunction(_export)%s{%s", deps, space, space, newline)
+	if len(externalPaths) > 0 {
+		text += fmt.Sprintf("%svar modules%s=%s{};%s", indent, space, space, newline)
+		text += fmt.Sprintf("%svar require%s=%sfunction(id)%s{%sreturn modules[id];%s};%s", indent, space, space, space, space, space, newline)
+	}
+	text += fmt.Sprintf("%sreturn%s{%s", indent, space, newline)
+	text += fmt.Sprintf("%s%ssetters:%s[%s],%s", indent, indent, space, setters, newline)
+	text += fmt.Sprintf("%s%sexecute:%sfunction()%s{%s", indent, indent, space, space, newline)
+	return text
+}
+
 type compileResultCSS struct {
 	css_printer.PrintResult
 
@@ -5267,12 +6156,16 @@ func (c *linkerContext) generateChunkCSS(chunkIndex int, chunkWaitGroup *sync.Wa
 				MinifyWhitespace:    c.options.MinifyWhitespace,
 				ASCIIOnly:           c.options.ASCIIOnly,
 				LegalComments:       c.options.LegalComments,
+				RecordLegalComments: c.options.ExtractLicenses,
 				SourceMap:           c.options.SourceMap,
 				UnsupportedFeatures: c.options.UnsupportedCSSFeatures,
 				AddSourceMappings:   addSourceMappings,
+				SourceMapLinesOnly:  c.options.SourceMapLinesOnly,
 				InputSourceMap:      inputSourceMap,
 				LineOffsetTables:    lineOffsetTables,
 				NeedsMetafile:       c.options.NeedsMetafile,
+				PreferSingleQuotes:  c.options.PreferSingleQuotes,
+				IndentUnit:          c.options.IndentUnit,
 			}
 			compileResult.PrintResult = css_printer.Print(asts[i], cssOptions)
 			compileResult.sourceIndex = sourceIndex
@@ -5325,9 +6218,11 @@ func (c *linkerContext) generateChunkCSS(chunkIndex int, chunkWaitGroup *sync.Wa
 
 		if len(tree.Rules) > 0 {
 			result := css_printer.Print(tree, css_printer.Options{
-				MinifyWhitespace: c.options.MinifyWhitespace,
-				ASCIIOnly:        c.options.ASCIIOnly,
-				NeedsMetafile:    c.options.NeedsMetafile,
+				MinifyWhitespace:   c.options.MinifyWhitespace,
+				ASCIIOnly:          c.options.ASCIIOnly,
+				NeedsMetafile:      c.options.NeedsMetafile,
+				PreferSingleQuotes: c.options.PreferSingleQuotes,
+				IndentUnit:         c.options.IndentUnit,
 			})
 			jsonMetadataImports = result.JSONMetadataImports
 			if len(result.CSS) > 0 {
@@ -5433,6 +6328,9 @@ func (c *linkerContext) generateChunkCSS(chunkIndex int, chunkWaitGroup *sync.Wa
 		slashTag = ""
 	}
 	c.maybeAppendLegalComments(c.options.LegalComments, legalCommentList, chunk, &j, slashTag)
+	if c.options.ExtractLicenses {
+		chunk.legalCommentsForLicenses = legalCommentList
+	}
 
 	if len(c.options.CSSFooter) > 0 {
 		j.AddString(c.options.CSSFooter)
@@ -5609,6 +6507,109 @@ func (c *linkerContext) maybeAppendLegalComments(
 	}
 }
 
+// This lists every third-party package with code in this chunk along with
+// its license information. Packages are identified by name and version
+// instead of by path for the same reasons as third-party legal comments.
+func (c *linkerContext) generateLicenseFile(chunk chunkInfo) []byte {
+	type packageEntry struct {
+		pkg      *resolver.ThirdPartyPackage
+		comments []string
+	}
+
+	var filesInChunk []uint32
+	switch chunkRepr := chunk.chunkRepr.(type) {
+	case *chunkReprJS:
+		filesInChunk = chunkRepr.filesInChunkInOrder
+	case *chunkReprCSS:
+		filesInChunk = chunkRepr.filesInChunkInOrder
+	}
+
+	var packages []*packageEntry
+	packagesByDir := make(map[string]*packageEntry)
+	hasComment := make(map[string]map[string]struct{})
+
+	for _, sourceIndex := range filesInChunk {
+		file := &c.graph.Files[sourceIndex]
+		if file.InputFile.Source.KeyPath.Namespace != "file" {
+			continue
+		}
+		pkg := c.res.ThirdPartyPackageForPath(file.InputFile.Source.KeyPath.Text)
+		if pkg == nil {
+			continue
+		}
+		entry := packagesByDir[pkg.AbsDir]
+		if entry == nil {
+			entry = &packageEntry{pkg: pkg}
+			packagesByDir[pkg.AbsDir] = entry
+			hasComment[pkg.AbsDir] = make(map[string]struct{})
+			packages = append(packages, entry)
+		}
+	}
+
+	// Also include any legal comments from files in each package
+	for _, entry := range chunk.legalCommentsForLicenses {
+		path := c.graph.Files[entry.sourceIndex].InputFile.Source.KeyPath
+		if path.Namespace != "file" {
+			continue
+		}
+		pkg := c.res.ThirdPartyPackageForPath(path.Text)
+		if pkg == nil {
+			continue
+		}
+		if packageEntry := packagesByDir[pkg.AbsDir]; packageEntry != nil {
+			for _, comment := range entry.comments {
+				if _, ok := hasComment[pkg.AbsDir][comment]; !ok {
+					hasComment[pkg.AbsDir][comment] = struct{}{}
+					packageEntry.comments = append(packageEntry.comments, comment)
+				}
+			}
+		}
+	}
+
+	if len(packages) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(packages, func(i int, j int) bool {
+		a, b := packages[i].pkg, packages[j].pkg
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Version < b.Version
+	})
+
+	var j helpers.Joiner
+	j.AddString(fmt.Sprintf("Third-party packages included in %s:\n", helpers.QuoteForJSON(c.fs.Base(chunk.finalRelPath), false)))
+
+	for _, entry := range packages {
+		pkg := entry.pkg
+		name := pkg.Name
+		if pkg.Version != "" {
+			name += "@" + pkg.Version
+		}
+		j.AddString("\n--------------------------------------------------------------------------------\n\n")
+		j.AddString(name)
+		j.AddString("\n")
+		if pkg.License != "" {
+			j.AddString(fmt.Sprintf("License: %s\n", pkg.License))
+		}
+
+		for _, comment := range entry.comments {
+			j.AddString("\n")
+			j.AddString(comment)
+			j.AddString("\n")
+		}
+
+		for _, file := range pkg.LicenseFiles {
+			j.AddString(fmt.Sprintf("\n%s:\n\n", file.Name))
+			j.AddString(strings.TrimLeft(strings.TrimRight(file.Contents, " \t\r\n"), "\r\n"))
+			j.AddString("\n")
+		}
+	}
+
+	return j.Done()
+}
+
 func (c *linkerContext) appendIsolatedHashesForImportedChunks(
 	hash hash.Hash,
 	chunkIndex uint32,
@@ -5634,7 +6635,7 @@ func (c *linkerContext) appendIsolatedHashesForImportedChunks(
 	for _, piece := range chunk.intermediateOutput.pieces {
 		if piece.kind == outputPieceAssetIndex {
 			file := c.graph.Files[piece.index]
-			if len(file.InputFile.AdditionalFiles) != 1 {
+			if len(file.InputFile.AdditionalFiles) == 0 {
 				panic("Internal error")
 			}
 			relPath, _ := c.fs.Rel(c.options.AbsOutputDir, file.InputFile.AdditionalFiles[0].AbsPath)
@@ -5939,6 +6940,22 @@ type compileResultForSourceMap struct {
 	sourceIndex     uint32
 }
 
+// This replaces the longest matching prefix of a path in the "sources" field
+func replaceSourceMapPathPrefix(prefixes map[string]string, path string) string {
+	longest := -1
+	replacement := ""
+	for prefix, value := range prefixes {
+		if len(prefix) > longest && strings.HasPrefix(path, prefix) {
+			longest = len(prefix)
+			replacement = value
+		}
+	}
+	if longest == -1 {
+		return path
+	}
+	return replacement + path[longest:]
+}
+
 func (c *linkerContext) generateSourceMapForChunk(
 	results []compileResultForSourceMap,
 	chunkAbsDir string,
@@ -6009,6 +7026,7 @@ func (c *linkerContext) generateSourceMapForChunk(
 	}
 
 	// Write the sources
+	var ignoreList []int
 	j.AddString(",\n  \"sources\": [")
 	for i, item := range items {
 		if i != 0 {
@@ -6024,10 +7042,31 @@ func (c *linkerContext) generateSourceMapForChunk(
 			}
 		}
 
+		// Apply any user-specified path prefix replacements
+		if len(c.options.SourceMapPathPrefix) > 0 {
+			item.prettyPath = replaceSourceMapPathPrefix(c.options.SourceMapPathPrefix, item.prettyPath)
+		}
+
+		if c.options.SourceMapIgnoreList != nil && c.options.SourceMapIgnoreList.MatchString(item.prettyPath) {
+			ignoreList = append(ignoreList, i)
+		}
+
 		j.AddBytes(helpers.QuoteForJSON(item.prettyPath, c.options.ASCIIOnly))
 	}
 	j.AddString("]")
 
+	// Write the indices of the sources that debuggers should hide
+	if len(ignoreList) > 0 {
+		j.AddString(",\n  \"x_google_ignoreList\": [")
+		for i, index := range ignoreList {
+			if i != 0 {
+				j.AddString(", ")
+			}
+			j.AddString(strconv.Itoa(index))
+		}
+		j.AddString("]")
+	}
+
 	if c.options.SourceRoot != "" {
 		j.AddString(",\n  \"sourceRoot\": ")
 		j.AddBytes(helpers.QuoteForJSON(c.options.SourceRoot, c.options.ASCIIOnly))
@@ -241,6 +241,11 @@ type PathFlags uint8
 const (
 	// This corresponds to a value of "false' in the "browser" package.json field
 	PathDisabled PathFlags = 1 << iota
+
+	// This is set when a file that wouldn't otherwise be loaded as JSON is
+	// imported using "with { type: 'json' }". The file is then loaded with the
+	// JSON loader, and is a separate module from imports without this flag.
+	PathWithTypeJSON
 )
 
 func (p Path) IsDisabled() bool {
@@ -0,0 +1,199 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/cache"
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This implements a subset of import maps, which are described here:
+// https://html.spec.whatwg.org/multipage/webappapis.html#import-maps. Deno's
+// "deno.json" configuration file uses the same "imports" and "scopes" fields,
+// so it can be used as an import map too.
+//
+// Keys and scope prefixes that look like paths are resolved relative to the
+// directory containing the import map and are matched against absolute paths.
+// All other keys (i.e. bare specifiers and URLs) are matched against the
+// import path as-is.
+
+type importMap struct {
+	source  logger.Source
+	absDir  string
+	imports []importMapEntry
+
+	// These are sorted from most specific to least specific
+	scopes []importMapScope
+}
+
+type importMapScope struct {
+	prefix  string
+	imports []importMapEntry
+}
+
+type importMapEntry struct {
+	key      string
+	value    string
+	keyRange logger.Range
+}
+
+type importMapResult struct {
+	path  string
+	entry importMapEntry
+}
+
+func parseImportMap(fs fs.FS, log logger.Log, caches *cache.CacheSet, absPath string) *importMap {
+	keyPath := logger.Path{Text: absPath, Namespace: "file"}
+	contents, err, _ := caches.FSCache.ReadFile(fs, absPath)
+	if err != nil {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot read import map %q: %s", PrettyPath(fs, keyPath), err.Error()))
+		return nil
+	}
+
+	source := logger.Source{
+		KeyPath:    keyPath,
+		PrettyPath: PrettyPath(fs, keyPath),
+		Contents:   contents,
+	}
+	tracker := logger.MakeLineColumnTracker(&source)
+
+	// Allow comments and trailing commas since "deno.jsonc" files have them
+	json, ok := caches.JSONCache.Parse(log, source, js_parser.JSONOptions{Flavor: js_lexer.TSConfigJSON})
+	if !ok {
+		return nil
+	}
+
+	result := &importMap{
+		source: source,
+		absDir: fs.Dir(absPath),
+	}
+
+	if importsJSON, _, ok := getProperty(json, "imports"); ok {
+		result.imports = result.parseEntries(fs, log, &tracker, importsJSON, "imports")
+	}
+
+	if scopesJSON, _, ok := getProperty(json, "scopes"); ok {
+		if scopes, ok := scopesJSON.Data.(*js_ast.EObject); ok {
+			for _, prop := range scopes.Properties {
+				if prefix, ok := getString(prop.Key); ok {
+					result.scopes = append(result.scopes, importMapScope{
+						prefix:  result.normalizeKey(fs, prefix),
+						imports: result.parseEntries(fs, log, &tracker, prop.ValueOrNil, "scopes"),
+					})
+				}
+			}
+			sort.SliceStable(result.scopes, func(i int, j int) bool {
+				return len(result.scopes[i].prefix) > len(result.scopes[j].prefix)
+			})
+		} else {
+			log.AddID(logger.MsgID_None, logger.Warning, &tracker, logger.Range{Loc: scopesJSON.Loc},
+				"The \"scopes\" field in an import map must be an object")
+		}
+	}
+
+	return result
+}
+
+func (m *importMap) parseEntries(fs fs.FS, log logger.Log, tracker *logger.LineColumnTracker, json js_ast.Expr, field string) (entries []importMapEntry) {
+	obj, ok := json.Data.(*js_ast.EObject)
+	if !ok {
+		log.AddID(logger.MsgID_None, logger.Warning, tracker, logger.Range{Loc: json.Loc},
+			fmt.Sprintf("Each mapping in the %q field of an import map must be an object", field))
+		return
+	}
+
+	for _, prop := range obj.Properties {
+		if key, ok := getString(prop.Key); ok && prop.ValueOrNil.Data != nil {
+			value, ok := getString(prop.ValueOrNil)
+			if !ok {
+				log.AddID(logger.MsgID_None, logger.Warning, tracker, logger.Range{Loc: prop.ValueOrNil.Loc},
+					"Each value in an import map must be a string")
+				continue
+			}
+			if strings.HasSuffix(key, "/") && !strings.HasSuffix(value, "/") {
+				log.AddID(logger.MsgID_None, logger.Warning, tracker, m.source.RangeOfString(prop.ValueOrNil.Loc),
+					fmt.Sprintf("The value for %q must end in \"/\" because the key does", key))
+				continue
+			}
+			entries = append(entries, importMapEntry{
+				key:      m.normalizeKey(fs, key),
+				value:    value,
+				keyRange: m.source.RangeOfString(prop.Key.Loc),
+			})
+		}
+	}
+
+	// Check longer keys first so that the most specific prefix wins
+	sort.SliceStable(entries, func(i int, j int) bool {
+		return len(entries[i].key) > len(entries[j].key)
+	})
+	return
+}
+
+func isImportMapPath(text string) bool {
+	return strings.HasPrefix(text, "./") || strings.HasPrefix(text, "../") || strings.HasPrefix(text, "/")
+}
+
+// Paths are compared as absolute paths with forward slashes so that keys and
+// import paths are compared the same way regardless of the operating system
+func (m *importMap) normalizeKey(fs fs.FS, key string) string {
+	if !isImportMapPath(key) {
+		return key
+	}
+	absPath := strings.ReplaceAll(fs.Join(m.absDir, key), "\\", "/")
+	if strings.HasSuffix(key, "/") && !strings.HasSuffix(absPath, "/") {
+		absPath += "/"
+	}
+	return absPath
+}
+
+func (m *importMap) resolve(fs fs.FS, sourceDir string, importPath string) (importMapResult, bool) {
+	specifier := importPath
+	if isImportMapPath(importPath) {
+		specifier = strings.ReplaceAll(fs.Join(sourceDir, importPath), "\\", "/")
+	}
+
+	// Scopes apply to importers inside of them and take precedence over the
+	// top-level "imports" field
+	importerDir := strings.ReplaceAll(sourceDir, "\\", "/")
+	if !strings.HasSuffix(importerDir, "/") {
+		importerDir += "/"
+	}
+	for _, scope := range m.scopes {
+		if strings.HasPrefix(importerDir, scope.prefix) {
+			if result, ok := m.resolveWithEntries(fs, scope.imports, specifier); ok {
+				return result, true
+			}
+		}
+	}
+
+	return m.resolveWithEntries(fs, m.imports, specifier)
+}
+
+func (m *importMap) resolveWithEntries(fs fs.FS, entries []importMapEntry, specifier string) (importMapResult, bool) {
+	for _, entry := range entries {
+		var rest string
+		if specifier == entry.key {
+			rest = ""
+		} else if strings.HasSuffix(entry.key, "/") && strings.HasPrefix(specifier, entry.key) {
+			rest = specifier[len(entry.key):]
+		} else {
+			continue
+		}
+
+		// Relative values are relative to the import map. Everything else (i.e.
+		// URLs, absolute paths, and bare specifiers) is used as-is.
+		path := entry.value + rest
+		if strings.HasPrefix(entry.value, "./") || strings.HasPrefix(entry.value, "../") {
+			path = fs.Join(m.absDir, path)
+		}
+		return importMapResult{path: path, entry: entry}, true
+	}
+	return importMapResult{}, false
+}
@@ -0,0 +1,121 @@
+package resolver
+
+import (
+	"strings"
+
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/js_ast"
+)
+
+// This describes a package in a "node_modules" directory. It's used to list
+// the licenses of the third-party code in each output file.
+type ThirdPartyPackage struct {
+	Name    string
+	Version string
+
+	// This is the "license" field from "package.json", if present
+	License string
+
+	AbsDir       string
+	LicenseFiles []LicenseFile
+}
+
+type LicenseFile struct {
+	Name     string
+	Contents string
+}
+
+// This returns the third-party package containing the file at the given path,
+// or nil if the file isn't in a package in a "node_modules" directory. The
+// package is the nearest enclosing directory with a "package.json" file that
+// has a "name" field. Nested "package.json" files without a name are often
+// used to set the module type of a subdirectory, so they are skipped.
+func (res *Resolver) ThirdPartyPackageForPath(absPath string) *ThirdPartyPackage {
+	r := resolverQuery{Resolver: res}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	info := r.dirInfoCached(r.fs.Dir(absPath))
+	for info != nil && !info.isNodeModules {
+		if info.packageJSON != nil && info.packageJSON.name != "" {
+			break
+		}
+		info = info.parent
+	}
+	if info == nil || info.isNodeModules {
+		return nil
+	}
+
+	// The package must be somewhere inside a "node_modules" directory
+	isThirdParty := false
+	for parent := info.parent; parent != nil; parent = parent.parent {
+		if parent.isNodeModules {
+			isThirdParty = true
+			break
+		}
+	}
+	if !isThirdParty {
+		return nil
+	}
+
+	pkg := &ThirdPartyPackage{
+		Name:    info.packageJSON.name,
+		Version: info.packageJSON.version,
+		License: info.packageJSON.license,
+		AbsDir:  info.absPath,
+	}
+
+	// Include all files that look like license files such as "LICENSE",
+	// "LICENSE.md", "license-mit.txt", or "COPYING"
+	for _, name := range info.entries.SortedKeys() {
+		lower := strings.ToLower(name)
+		if !strings.HasPrefix(lower, "license") && !strings.HasPrefix(lower, "licence") && !strings.HasPrefix(lower, "copying") {
+			continue
+		}
+		if entry, _ := info.entries.Get(name); entry == nil || entry.Kind(r.fs) != fs.FileEntry {
+			continue
+		}
+		if contents, err, _ := r.caches.FSCache.ReadFile(r.fs, r.fs.Join(info.absPath, name)); err == nil {
+			pkg.LicenseFiles = append(pkg.LicenseFiles, LicenseFile{Name: name, Contents: contents})
+		}
+	}
+
+	return pkg
+}
+
+// The "license" field is usually an SPDX expression. Some older packages use
+// an object with a "type" field instead, or a "licenses" array of them.
+func parseLicenseField(json js_ast.Expr) string {
+	if licenseJSON, _, ok := getProperty(json, "license"); ok {
+		if value, ok := getString(licenseJSON); ok {
+			return value
+		}
+		if typeJSON, _, ok := getProperty(licenseJSON, "type"); ok {
+			if value, ok := getString(typeJSON); ok {
+				return value
+			}
+		}
+	}
+
+	if licensesJSON, _, ok := getProperty(json, "licenses"); ok {
+		if array, ok := licensesJSON.Data.(*js_ast.EArray); ok {
+			var types []string
+			for _, item := range array.Items {
+				if value, ok := getString(item); ok {
+					types = append(types, value)
+				} else if typeJSON, _, ok := getProperty(item, "type"); ok {
+					if value, ok := getString(typeJSON); ok {
+						types = append(types, value)
+					}
+				}
+			}
+			if len(types) > 1 {
+				return "(" + strings.Join(types, " OR ") + ")"
+			}
+			return strings.Join(types, "")
+		}
+	}
+
+	return ""
+}
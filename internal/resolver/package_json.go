@@ -18,6 +18,8 @@ import (
 
 type packageJSON struct {
 	name           string
+	version        string
+	license        string
 	mainFields     map[string]mainField
 	moduleTypeData js_ast.ModuleTypeData
 
@@ -281,6 +283,15 @@ func (r resolverQuery) parsePackageJSON(inputPath string) *packageJSON {
 		}
 	}
 
+	// Read the "version" and "license" fields. These are only used to describe
+	// third-party packages in the license file.
+	if versionJSON, _, ok := getProperty(json, "version"); ok {
+		if versionValue, ok := getString(versionJSON); ok {
+			packageJSON.version = versionValue
+		}
+	}
+	packageJSON.license = parseLicenseField(json)
+
 	// Read the "type" field
 	if typeJSON, typeKeyLoc, ok := getProperty(json, "type"); ok {
 		if typeValue, ok := getString(typeJSON); ok {
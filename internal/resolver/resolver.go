@@ -47,6 +47,9 @@ var defaultMainFields = map[config.Platform][]string{
 	// that some packages may break if you do this.
 	config.PlatformNode: {"main", "module"},
 
+	// Deno only runs ECMAScript modules natively, so prefer the "module" field
+	config.PlatformDeno: {"module", "main"},
+
 	// The neutral platform is for people that don't want esbuild to try to
 	// pick good defaults for their platform. In that case, the list of main
 	// fields is empty by default. You must explicitly configure it yourself.
@@ -124,7 +127,11 @@ type ResolveResult struct {
 	// If true, the class field transform should use Object.defineProperty().
 	UseDefineForClassFieldsTS config.MaybeBool
 
-	// This is the "importsNotUsedAsValues" and "preserveValueImports" fields from "package.json"
+	// If true, TypeScript decorators should be passed type information
+	EmitDecoratorMetadataTS bool
+
+	// This is the "importsNotUsedAsValues", "preserveValueImports", and
+	// "verbatimModuleSyntax" fields from "tsconfig.json"
 	UnusedImportFlagsTS config.UnusedImportFlagsTS
 }
 
@@ -211,6 +218,9 @@ type Resolver struct {
 	pnpManifestWasChecked bool
 	pnpManifest           *pnpData
 
+	// This is the parsed import map if one was configured
+	importMap *importMap
+
 	options config.Options
 
 	// This mutex serves two purposes. First of all, it guards access to "dirCache"
@@ -254,6 +264,10 @@ func NewResolver(fs fs.FS, log logger.Log, caches *cache.CacheSet, options confi
 		esmConditionsDefault["browser"] = true
 	case config.PlatformNode:
 		esmConditionsDefault["node"] = true
+	case config.PlatformDeno:
+		// Deno uses these conditions when it loads npm packages
+		esmConditionsDefault["deno"] = true
+		esmConditionsDefault["node"] = true
 	}
 	for key := range esmConditionsDefault {
 		esmConditionsImport[key] = true
@@ -262,7 +276,13 @@ func NewResolver(fs fs.FS, log logger.Log, caches *cache.CacheSet, options confi
 
 	fs.Cwd()
 
+	var importMap *importMap
+	if options.AbsImportMapPath != "" {
+		importMap = parseImportMap(fs, log, caches, options.AbsImportMapPath)
+	}
+
 	return &Resolver{
+		importMap:              importMap,
 		fs:                     fs,
 		log:                    log,
 		options:                options,
@@ -288,6 +308,24 @@ func (res *Resolver) Resolve(sourceDir string, importPath string, kind ast.Impor
 			importPath, sourceDir, kind.StringForMetafile())}
 	}
 
+	// Apply the import map first since it's meant to replace the import path
+	// before any other resolution happens
+	if r.importMap != nil {
+		if result, ok := r.importMap.resolve(r.fs, sourceDir, importPath); ok {
+			if r.debugLogs != nil {
+				r.debugLogs.addNote(fmt.Sprintf("Remapped %q to %q using the import map %q", importPath, result.path, r.importMap.source.PrettyPath))
+			}
+			prettyPath := result.path
+			if r.fs.IsAbs(prettyPath) {
+				prettyPath = PrettyPath(r.fs, logger.Path{Text: prettyPath, Namespace: "file"})
+			}
+			tracker := logger.MakeLineColumnTracker(&r.importMap.source)
+			debugMeta.notes = append(debugMeta.notes, tracker.MsgData(result.entry.keyRange,
+				fmt.Sprintf("The import path %q was remapped to %q by the import map here:", importPath, prettyPath)))
+			importPath = result.path
+		}
+	}
+
 	// Apply package alias substitutions first
 	if r.options.PackageAliases != nil && IsPackagePath(importPath) {
 		if r.debugLogs != nil {
@@ -333,6 +371,22 @@ func (res *Resolver) Resolve(sourceDir string, importPath string, kind ast.Impor
 		}
 	}
 
+	// Substitute browser polyfills for node built-ins if requested. This is done
+	// after aliases so that an explicit alias can still override the polyfill.
+	if r.options.NodePolyfills && r.options.Platform != config.PlatformNode && r.options.Platform != config.PlatformDeno {
+		if polyfill, ok := BrowserPolyfillsForNodeModules[strings.TrimPrefix(importPath, "node:")]; ok {
+			// Resolve the polyfill from the current working directory for the same
+			// reasons as package aliases (see above)
+			sourceDir = r.fs.Cwd()
+			debugMeta.ModifiedImportPath = polyfill
+			if r.debugLogs != nil {
+				r.debugLogs.addNote(fmt.Sprintf("Substituting the browser polyfill %q for the node built-in %q", polyfill, importPath))
+				r.debugLogs.addNote(fmt.Sprintf("  Changed resolve directory to %q", sourceDir))
+			}
+			importPath = polyfill
+		}
+	}
+
 	// Certain types of URLs default to being external for convenience
 	if isExplicitlyExternal := r.isExternal(r.options.ExternalSettings.PreResolve, importPath, kind); isExplicitlyExternal ||
 
@@ -363,6 +417,44 @@ func (res *Resolver) Resolve(sourceDir string, importPath string, kind ast.Impor
 		}, debugMeta
 	}
 
+	// "import lodash from 'npm:lodash'"
+	// "import { assert } from 'jsr:@std/assert'"
+	// "import fs from 'node:fs'"
+	if r.options.Platform == config.PlatformDeno && (strings.HasPrefix(importPath, "npm:") ||
+		strings.HasPrefix(importPath, "jsr:") || strings.HasPrefix(importPath, "node:")) {
+		if r.debugLogs != nil {
+			r.debugLogs.addNote("Marking this path as implicitly external because Deno loads it")
+		}
+
+		// If this is a known node built-in module, mark it with "sideEffects: false"
+		var sideEffects *SideEffectsData
+		if BuiltInNodeModules[strings.TrimPrefix(importPath, "node:")] {
+			sideEffects = &SideEffectsData{}
+		}
+
+		r.flushDebugLogs(flushDueToSuccess)
+		return &ResolveResult{
+			PathPair:               PathPair{Primary: logger.Path{Text: importPath}},
+			IsExternal:             true,
+			PrimarySideEffectsData: sideEffects,
+		}, debugMeta
+	}
+
+	// "import fs from 'fs'"
+	if r.options.Platform == config.PlatformDeno && BuiltInNodeModules[importPath] {
+		if r.debugLogs != nil {
+			r.debugLogs.addNote("Marking this path as implicitly external due to it being a node built-in")
+			r.debugLogs.addNote("  Adding the \"node:\" prefix because Deno requires it")
+		}
+
+		r.flushDebugLogs(flushDueToSuccess)
+		return &ResolveResult{
+			PathPair:               PathPair{Primary: logger.Path{Text: "node:" + importPath}},
+			IsExternal:             true,
+			PrimarySideEffectsData: &SideEffectsData{}, // Mark this with "sideEffects: false"
+		}, debugMeta
+	}
+
 	// "import 'pkg'" when all packages are external (vs. "import './pkg'")
 	if r.options.ExternalPackages && IsPackagePath(importPath) && !r.fs.IsAbs(importPath) && !strings.HasPrefix(importPath, "#") {
 		if r.debugLogs != nil {
@@ -731,9 +823,11 @@ func (r resolverQuery) finalizeResolve(result *ResolveResult) {
 						result.JSX = dirInfo.enclosingTSConfigJSON.JSX
 						result.JSXImportSource = dirInfo.enclosingTSConfigJSON.JSXImportSource
 						result.UseDefineForClassFieldsTS = dirInfo.enclosingTSConfigJSON.UseDefineForClassFields
+						result.EmitDecoratorMetadataTS = dirInfo.enclosingTSConfigJSON.ShouldEmitDecoratorMetadata()
 						result.UnusedImportFlagsTS = config.UnusedImportFlagsFromTsconfigValues(
 							dirInfo.enclosingTSConfigJSON.PreserveImportsNotUsedAsValues,
 							dirInfo.enclosingTSConfigJSON.PreserveValueImports,
+							dirInfo.enclosingTSConfigJSON.VerbatimModuleSyntax,
 						)
 						result.TSTarget = dirInfo.enclosingTSConfigJSON.TSTarget
 						result.TSAlwaysStrict = dirInfo.enclosingTSConfigJSON.TSAlwaysStrictOrStrict()
@@ -907,7 +1001,7 @@ func (r resolverQuery) resolveWithoutRemapping(sourceDirInfo *dirInfo, importPat
 }
 
 func PrettyPath(fs fs.FS, path logger.Path) string {
-	if path.Namespace == "file" {
+	if path.Namespace == "file" || path.Namespace == "require-context" || path.Namespace == "import-glob" {
 		if rel, ok := fs.Rel(fs.Cwd(), path.Text); ok {
 			path.Text = rel
 		}
@@ -918,6 +1012,12 @@ func PrettyPath(fs fs.FS, path logger.Path) string {
 		// operating system it was run. Replace Windows backward slashes with standard
 		// forward slashes.
 		path.Text = strings.ReplaceAll(path.Text, "\\", "/")
+
+		// The modules generated for "require.context()" and "import.meta.glob()"
+		// calls use a file system directory as the path too
+		if path.Namespace != "file" {
+			path.Text = fmt.Sprintf("%s:%s", path.Namespace, path.Text)
+		}
 	} else if path.Namespace != "" {
 		path.Text = fmt.Sprintf("%s:%s", path.Namespace, path.Text)
 	}
@@ -2469,6 +2569,36 @@ func IsPackagePath(path string) bool {
 		!strings.HasPrefix(path, "../") && path != "." && path != ".."
 }
 
+// These are the packages that are substituted for node built-ins when node
+// polyfills are enabled. They are the same packages that were traditionally
+// used by Browserify and Webpack 4. Built-ins without a reasonable browser
+// equivalent (e.g. "fs" and "child_process") are deliberately left out.
+var BrowserPolyfillsForNodeModules = map[string]string{
+	"assert":         "assert",
+	"buffer":         "buffer",
+	"console":        "console-browserify",
+	"constants":      "constants-browserify",
+	"crypto":         "crypto-browserify",
+	"domain":         "domain-browser",
+	"events":         "events",
+	"http":           "stream-http",
+	"https":          "https-browserify",
+	"os":             "os-browserify/browser",
+	"path":           "path-browserify",
+	"process":        "process/browser",
+	"punycode":       "punycode",
+	"querystring":    "querystring-es3",
+	"stream":         "stream-browserify",
+	"string_decoder": "string_decoder",
+	"sys":            "util",
+	"timers":         "timers-browserify",
+	"tty":            "tty-browserify",
+	"url":            "url",
+	"util":           "util",
+	"vm":             "vm-browserify",
+	"zlib":           "browserify-zlib",
+}
+
 // This list can be obtained with the following command:
 //
 //	node --experimental-wasi-unstable-preview1 -p "[...require('module').builtinModules].join('\n')"
@@ -46,6 +46,15 @@ type TSConfigJSON struct {
 	UseDefineForClassFields        config.MaybeBool
 	PreserveImportsNotUsedAsValues bool
 	PreserveValueImports           bool
+	VerbatimModuleSyntax           bool
+	ExperimentalDecorators         bool
+	EmitDecoratorMetadata          bool
+}
+
+// The TypeScript compiler only emits decorator metadata for its experimental
+// decorators, so "emitDecoratorMetadata" does nothing without them
+func (config *TSConfigJSON) ShouldEmitDecoratorMetadata() bool {
+	return config.ExperimentalDecorators && config.EmitDecoratorMetadata
 }
 
 func (config *TSConfigJSON) TSAlwaysStrictOrStrict() *config.TSAlwaysStrict {
@@ -280,6 +289,27 @@ func ParseTSConfigJSON(
 			}
 		}
 
+		// Parse "verbatimModuleSyntax"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "verbatimModuleSyntax"); ok {
+			if value, ok := getBool(valueJSON); ok {
+				result.VerbatimModuleSyntax = value
+			}
+		}
+
+		// Parse "experimentalDecorators"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "experimentalDecorators"); ok {
+			if value, ok := getBool(valueJSON); ok {
+				result.ExperimentalDecorators = value
+			}
+		}
+
+		// Parse "emitDecoratorMetadata"
+		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "emitDecoratorMetadata"); ok {
+			if value, ok := getBool(valueJSON); ok {
+				result.EmitDecoratorMetadata = value
+			}
+		}
+
 		// Parse "paths"
 		if valueJSON, _, ok := getProperty(compilerOptionsJSON, "paths"); ok {
 			if paths, ok := valueJSON.Data.(*js_ast.EObject); ok {
@@ -57,13 +57,10 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 	//   __spreadArray
 	//   __spreadArrays
 	//   __values
-	//
-	// Note: The "__objRest" function has a for-of loop which requires ES6, but
-	// transforming destructuring to ES5 isn't even supported so it's ok.
 	text := `
 		var __create = Object.create
 		var __freeze = Object.freeze
-		var __defProp = Object.defineProperty
+		export var __defProp = Object.defineProperty
 		var __defProps = Object.defineProperties
 		var __getOwnPropDesc = Object.getOwnPropertyDescriptor // Note: can return "undefined" due to a Safari bug
 		var __getOwnPropDescs = Object.getOwnPropertyDescriptors
@@ -72,8 +69,6 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 		var __getProtoOf = Object.getPrototypeOf
 		var __hasOwnProp = Object.prototype.hasOwnProperty
 		var __propIsEnum = Object.prototype.propertyIsEnumerable
-		var __reflectGet = Reflect.get
-		var __reflectSet = Reflect.set
 
 		export var __pow = Math.pow
 
@@ -106,7 +101,24 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 				}
 			return a
 		}
-		export var __spreadProps = (a, b) => __defProps(a, __getOwnPropDescs(b))
+	`
+
+	// Avoid "Object.getOwnPropertyDescriptors" when not using ES6
+	if !unsupportedJSFeatures.Has(compat.ObjectExtensions) {
+		text += `
+			export var __spreadProps = (a, b) => __defProps(a, __getOwnPropDescs(b))
+		`
+	} else {
+		text += `
+			export var __spreadProps = (a, b) => {
+				for (var keys = __getOwnPropNames(b), i = 0, n = keys.length; i < n; i++)
+					__defProp(a, keys[i], __getOwnPropDesc(b, keys[i]))
+				return a
+			}
+		`
+	}
+
+	text += `
 
 		// Update the "name" property on the function or class for "--keep-names"
 		export var __name = (target, value) => __defProp(target, 'name', { value, configurable: true })
@@ -157,6 +169,27 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 			return target
 		}
 
+		// For lowering iteration (destructuring, spread, and for-of loops) to ES5.
+		// Array-like objects are iterated by index if "Symbol.iterator" is missing.
+		var __iterSymbol = typeof Symbol === 'function' && Symbol.iterator
+		export var __iterator = value => {
+			if (value == null) throw TypeError(value + ' is not iterable')
+			var method = __iterSymbol && value[__iterSymbol], i = 0
+			return method ? method.call(value) : { next: () => ({ done: i >= value.length, value: value[i++] }) }
+		}
+		export var __toArray = (value, n) => {
+			if (Array.isArray(value)) return value
+			for (var it = __iterator(value), result = [], step; (n === void 0 || result.length < n) && !(step = it.next()).done; )
+				result.push(step.value)
+			if ((!step || !step.done) && it.return) it.return()
+			return result
+		}
+		export var __requireObject = value => {
+			if (value == null) throw TypeError('Cannot destructure ' + value)
+			return value
+		}
+		export var __construct = (target, args) => new (Function.prototype.bind.apply(target, [null].concat(args)))
+
 		// This is for lazily-initialized ESM code. This has two implementations, a
 		// compact one for minified code and a verbose one that generates friendly
 		// names in V8's profiler and in stack traces.
@@ -250,6 +283,81 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 			return result
 		}
 		export var __decorateParam = (index, decorator) => (target, key) => decorator(target, key, index)
+		export var __decorateMetadata = (key, value) => typeof Reflect == 'object' && typeof Reflect.metadata == 'function' && Reflect.metadata(key, value)
+
+		// For JavaScript decorators. The "array" argument holds the state for a
+		// single class and is laid out like this:
+		//
+		//   array[0]: class extra initializers
+		//   array[1]: static method extra initializers
+		//   array[2]: instance method extra initializers
+		//   array[3]: metadata object
+		//   array[4 + 2*n]: initializers for the "n"th decorated field or accessor
+		//   array[5 + 2*n]: extra initializers for the "n"th decorated field or accessor
+		//
+		// The "flags" argument of "__decorateElement" is the element kind (0 for
+		// class, 1 for method, 2 for getter, 3 for setter, 4 for accessor, and 5
+		// for field) plus 8 if the element is static and 16 if it's private.
+		var __knownSymbol = (name, symbol) => (symbol = Symbol[name]) ? symbol : Symbol.for('Symbol.' + name)
+		var __typeError = msg => { throw TypeError(msg) }
+		var __expectFn = fn => fn !== void 0 && typeof fn !== 'function' ? __typeError('Function expected') : fn
+		var __decoratorStrings = ['class', 'method', 'getter', 'setter', 'accessor', 'field']
+		var __decoratorContext = (kind, name, done, metadata, fns) => ({
+			kind: __decoratorStrings[kind],
+			name,
+			metadata,
+			addInitializer: fn => done._ ? __typeError('Already initialized') : fns.push(__expectFn(fn || null)),
+		})
+		export var __decoratorStart = target => {
+			var base = __getProtoOf(target)
+			return [, , , __create(base && base[__knownSymbol('metadata')] || null)]
+		}
+		export var __decoratorMetadata = (array, target) => __defNormalProp(target, __knownSymbol('metadata'), array[3])
+		export var __runInitializers = (array, flags, self, value) => {
+			for (var i = 0, fns = array[flags >> 1], n = fns && fns.length; i < n; i++)
+				flags & 1 ? fns[i].call(self) : value = fns[i].call(self, value)
+			return value
+		}
+		export var __decorateElement = (array, flags, name, decorators, target, extra, extra2) => {
+			var kind = flags & 7, isStatic = !!(flags & 8), isPrivate = !!(flags & 16), fn, it, done, ctx, access
+			var index = kind > 3 ? array.length + 1 : kind ? isStatic ? 1 : 2 : 0
+			var initializers = kind > 3 && (array[index - 1] = [])
+			var extraInitializers = array[index] || (array[index] = [])
+			var key = [, 'value', 'get', 'set'][kind]
+			var obj = kind && !isPrivate && (isStatic ? target : target.prototype)
+			var desc = kind && kind < 5 && (isPrivate ? kind > 3 ? { get: extra, set: extra2 } : { [key]: extra } : __getOwnPropDesc(obj, name))
+
+			if (kind) {
+				access = { has: isPrivate ? x => __privateIn(target, x) : x => name in x }
+				if (kind ^ 3) access.get = isPrivate
+					? kind ^ 1 ? x => __privateGet(x, target, desc && desc.get) : x => __privateMethod(x, target, desc.value)
+					: x => x[name]
+				if (kind > 2) access.set = isPrivate
+					? (x, y) => __privateSet(x, target, y, desc && desc.set)
+					: (x, y) => { x[name] = y }
+			}
+
+			for (var i = decorators.length - 1; i >= 0; i--) {
+				ctx = __decoratorContext(kind, name, done = {}, array[3], extraInitializers)
+				if (kind) ctx.static = isStatic, ctx.private = isPrivate, ctx.access = access
+				it = (0, decorators[i])(kind ? kind < 4 ? desc[key] : kind < 5 ? { get: desc.get, set: desc.set } : void 0 : target, ctx)
+				done._ = 1
+
+				if (kind ^ 4 || it === void 0) {
+					if (__expectFn(it)) kind > 4 ? initializers.unshift(it) : kind ? desc[key] = it : target = it
+				} else if (typeof it !== 'object' || it === null) {
+					__typeError('Object expected')
+				} else {
+					if (__expectFn(fn = it.get)) desc.get = fn
+					if (__expectFn(fn = it.set)) desc.set = fn
+					if (__expectFn(fn = it.init)) initializers.unshift(fn)
+				}
+			}
+
+			if (!kind) __decoratorMetadata(array, target)
+			else if (desc && !isPrivate) __defProp(obj, name, desc)
+			return isPrivate && desc ? kind > 3 ? desc : desc[key] : target
+		}
 
 		// For class members
 		export var __publicField = (obj, key, value) => {
@@ -299,12 +407,59 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 			__accessCheck(obj, member, 'access private method')
 			return method
 		}
-
-		// For "super" property accesses
-		export var __superGet = (cls, obj, key) => __reflectGet(__getProtoOf(cls), key, obj)
-		export var __superSet = (cls, obj, key, val) => (__reflectSet(__getProtoOf(cls), key, val, obj), val)
 	`
 
+	// Avoid "Reflect" when not using ES6
+	if !unsupportedJSFeatures.Has(compat.Class) {
+		text += `
+			var __reflectGet = Reflect.get
+			var __reflectSet = Reflect.set
+
+			// For "super" property accesses
+			export var __superGet = (cls, obj, key) => __reflectGet(__getProtoOf(cls), key, obj)
+			export var __superSet = (cls, obj, key, val) => (__reflectSet(__getProtoOf(cls), key, val, obj), val)
+		`
+	} else {
+		text += `
+			var __superDesc = (cls, key) => {
+				for (var proto = __getProtoOf(cls), desc; proto; proto = __getProtoOf(proto))
+					if (desc = __getOwnPropDesc(proto, key)) return desc
+			}
+
+			// For "super" property accesses
+			export var __superGet = (cls, obj, key) => {
+				var desc = __superDesc(cls, key)
+				return desc ? desc.get ? desc.get.call(obj) : desc.value : void 0
+			}
+			export var __superSet = (cls, obj, key, val) => {
+				var desc = __superDesc(cls, key)
+				desc && desc.set ? desc.set.call(obj, val) : obj[key] = val
+				return val
+			}
+
+			// For lowering classes that extend other classes
+			var __setProtoOf = Object.setPrototypeOf || ((obj, proto) => {
+				if ({ __proto__: [] } instanceof Array) obj.__proto__ = proto
+				else for (var key in proto) if (__hasOwnProp.call(proto, key)) obj[key] = proto[key]
+			})
+			export var __inherit = (cls, base) => {
+				if (typeof base !== 'function' && base !== null)
+					throw TypeError('Class extends value ' + base + ' is not a constructor or null')
+				if (base) __setProtoOf(cls, base)
+				cls.prototype = __create(base && base.prototype, {
+					constructor: { value: cls, writable: true, configurable: true },
+				})
+			}
+
+			// Lowered class constructors are normal functions, so they must check
+			// that they were called using "new" like real class constructors do
+			export var __classCheck = (obj, cls) => {
+				if (!(obj instanceof cls))
+					throw TypeError("Class constructor " + cls.name + " cannot be invoked without 'new'")
+			}
+		`
+	}
+
 	if !unsupportedJSFeatures.Has(compat.ObjectAccessors) {
 		text += `
 			export var __superWrapper = (cls, obj, key) => ({
@@ -347,6 +502,102 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 			})
 		}
 
+		// This helps for lowering generator functions. The body is a state machine
+		// that is re-entered at "state.label" and returns an instruction:
+		//
+		//   [2, value]  return (after running any pending "finally" blocks)
+		//   [3, label]  jump to a label (also running "finally" blocks on the way)
+		//   [4, value]  yield
+		//   [5, value]  yield*
+		//   [7]         end of a "finally" block
+		//
+		// Resuming with "next", "throw", or "return" is represented as [0, value],
+		// [1, value], or [2, value] and an exception thrown by the body is [6, error].
+		// Each active "try" statement is an entry in "state.trys" with the labels
+		// [try, catch, finally, end], where "catch" and "finally" may be missing.
+		export var __makeGenerator = (__this, body) => {
+			var running, delegate, sent, result
+			var state = {
+				label: 0,
+				trys: [],
+				ops: [],
+				sent: () => {
+					if (sent[0] & 1) throw sent[1]
+					return sent[1]
+				},
+			}
+			var step = op => {
+				if (running) throw TypeError('Generator is already running')
+				if (!sent && op[0]) state = 0
+				while (state) {
+					running = 1
+					try {
+						if (delegate) {
+							var method = op[0] ? op[0] & 2 ? delegate.return : delegate.throw : delegate.next
+							if (method) {
+								result = method.call(delegate, op[1])
+								if (!result.done) return result
+								op = [op[0] & 2, result.value]
+							}
+							delegate = 0
+						}
+						switch (op[0]) {
+							case 0:
+							case 1:
+								sent = op
+								break
+							case 4:
+								state.label++
+								return { value: op[1], done: false }
+							case 5:
+								state.label++
+								delegate = __iterator(op[1])
+								op = [0]
+								continue
+							case 7:
+								op = state.ops.pop()
+								state.trys.pop()
+								continue
+							default:
+								var t = state.trys[state.trys.length - 1]
+								if (!t && op[0] != 3) {
+									state = 0
+									continue
+								}
+								if (op[0] == 3 && (!t || op[1] > t[0] && op[1] < t[3])) {
+									state.label = op[1]
+									break
+								}
+								if (op[0] == 6 && state.label < t[1]) {
+									state.label = t[1]
+									sent = op
+									break
+								}
+								if (state.label < t[2]) {
+									state.label = t[2]
+									state.ops.push(op)
+									break
+								}
+								if (t[2]) state.ops.pop()
+								state.trys.pop()
+								continue
+						}
+						op = body.call(__this, state)
+					} catch (e) {
+						op = [6, e]
+						delegate = 0
+					} finally {
+						running = 0
+					}
+				}
+				if (op[0] & 5) throw op[1]
+				return { value: op[0] ? op[1] : void 0, done: true }
+			}
+			var it = { next: value => step([0, value]), throw: value => step([1, value]), return: value => step([2, value]) }
+			if (__iterSymbol) it[__iterSymbol] = () => it
+			return it
+		}
+
 		// This helps for lowering for-await loops
 		export var __forAwait = (obj, it, method) => {
 			it = obj[Symbol.asyncIterator]
@@ -367,6 +618,43 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 					it)
 		}
 
+		// This helps for lowering "using" declarations. Each entry on the stack is
+		// "[isAsync, dispose, value]" and the entries are disposed in reverse order.
+		export var __using = (stack, value, async) => {
+			if (value != null) {
+				if (typeof value !== 'object' && typeof value !== 'function') __typeError('Object expected')
+				var dispose, inner
+				if (async) dispose = value[__knownSymbol('asyncDispose')]
+				if (dispose === void 0) {
+					dispose = value[__knownSymbol('dispose')]
+					if (async) inner = dispose
+				}
+				if (typeof dispose !== 'function') __typeError('Object not disposable')
+				if (inner) dispose = function () { try { inner.call(this) } catch (e) { return Promise.reject(e) } }
+				stack.push([async, dispose, value])
+			} else if (async) {
+				stack.push([async])
+			}
+			return value
+		}
+		export var __callDispose = (stack, error, hasError) => {
+			var E = typeof SuppressedError === 'function' ? SuppressedError :
+				function (e, s, m, _) { return _ = Error(m), _.name = 'SuppressedError', _.error = e, _.suppressed = s, _ }
+			var fail = e => error = hasError ? new E(e, error, 'An error was suppressed during disposal') : (hasError = true, e)
+			var next = it => {
+				while (it = stack.pop()) {
+					try {
+						var result = it[1] && it[1].call(it[2])
+						if (it[0]) return Promise.resolve(result).then(next, e => (fail(e), next()))
+					} catch (e) {
+						fail(e)
+					}
+				}
+				if (hasError) throw error
+			}
+			return next()
+		}
+
 		// This is for the "binary" loader (custom code is ~2x faster than "atob")
 		export var __toBinaryNode = base64 => new Uint8Array(Buffer.from(base64, 'base64'))
 		export var __toBinary = /* @__PURE__ */ (() => {
@@ -384,6 +672,16 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 				return bytes
 			}
 		})()
+
+		// This is for inline web workers
+		export var __toBlobURL = code => URL.createObjectURL(new Blob([code], { type: 'text/javascript' }))
+
+		// This is for "import.meta.url" in non-ESM browser code. It's computed when
+		// the runtime code is evaluated since "document.currentScript" is only
+		// available while the script is first being run.
+		export var __importMetaURL = /* @__PURE__ */ (() =>
+			typeof document !== 'undefined' && document.currentScript && document.currentScript.src ||
+			typeof location !== 'undefined' && location.href)()
 	`
 
 	return logger.Source{
@@ -611,6 +611,7 @@ type ChunkBuilder struct {
 	firstNameOffset     ast.Index32
 	hasPrevState        bool
 	asciiOnly           bool
+	linesOnly           bool
 
 	// This is a workaround for a bug in the popular "source-map" library:
 	// https://github.com/mozilla/source-map/issues/261. The library will
@@ -624,12 +625,13 @@ type ChunkBuilder struct {
 	coverLinesWithoutMappings bool
 }
 
-func MakeChunkBuilder(inputSourceMap *SourceMap, lineOffsetTables []LineOffsetTable, asciiOnly bool) ChunkBuilder {
+func MakeChunkBuilder(inputSourceMap *SourceMap, lineOffsetTables []LineOffsetTable, asciiOnly bool, linesOnly bool) ChunkBuilder {
 	return ChunkBuilder{
 		inputSourceMap:   inputSourceMap,
 		prevOriginalLoc:  logger.Loc{Start: -1},
 		lineOffsetTables: lineOffsetTables,
 		asciiOnly:        asciiOnly,
+		linesOnly:        linesOnly,
 		namesMap:         make(map[string]uint32),
 
 		// We automatically repeat the previous source mapping if we ever generate
@@ -682,6 +684,21 @@ func (b *ChunkBuilder) AddSourceMapping(originalLoc logger.Loc, originalName str
 
 	b.updateGeneratedLineAndColumn(output)
 
+	// When only lines are mapped, each line gets a single mapping at its start
+	// that points to the first thing printed on that line
+	if b.linesOnly {
+		if !b.lineStartsWithMapping {
+			oldLen := len(b.sourceMap)
+			b.appendMapping("", SourceMapState{
+				GeneratedLine:  b.prevState.GeneratedLine,
+				OriginalLine:   originalLine,
+				OriginalColumn: originalColumn,
+			})
+			b.lineStartsWithMapping = len(b.sourceMap) != oldLen
+		}
+		return
+	}
+
 	// If this line doesn't start with a mapping and we're about to add a mapping
 	// that's not at the start, insert a mapping first so the line starts with one.
 	if b.coverLinesWithoutMappings && !b.lineStartsWithMapping && b.generatedColumn > 0 && b.hasPrevState {
@@ -799,7 +816,7 @@ func (b *ChunkBuilder) appendMapping(originalName string, currentState SourceMap
 	}
 
 	// Optionally reference the original name
-	if originalName != "" {
+	if originalName != "" && !b.linesOnly {
 		i, ok := b.namesMap[originalName]
 		if !ok {
 			i = uint32(len(b.quotedNames))
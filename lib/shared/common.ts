@@ -61,6 +61,9 @@ let mustBeStringOrUint8Array = (value: string | Uint8Array | undefined): string
 let mustBeStringOrURL = (value: string | URL | undefined): string | null =>
   typeof value === 'string' || value instanceof URL ? null : 'a string or a URL'
 
+let mustBeIntegerOrString = (value: number | string | undefined): string | null =>
+  typeof value === 'string' || typeof value === 'number' && value === (value | 0) ? null : 'an integer or a string'
+
 type OptionKeys = { [key: string]: boolean }
 
 function getFlag<T, K extends (keyof T & string)>(object: T, keys: OptionKeys, key: K, mustBeFn: (value: T[K]) => string | null): T[K] | undefined {
@@ -133,20 +136,31 @@ function validateStringValue(value: unknown, what: string, key?: string): string
 
 function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKeys): void {
   let legalComments = getFlag(options, keys, 'legalComments', mustBeString)
+  let comments = getFlag(options, keys, 'comments', mustBeString)
+  let commentsFilter = getFlag(options, keys, 'commentsFilter', mustBeRegExp)
+  let sourcemapDebugId = getFlag(options, keys, 'sourcemapDebugId', mustBeBoolean)
+  let sourcemapLinesOnly = getFlag(options, keys, 'sourcemapLinesOnly', mustBeBoolean)
+  let sourcemapIgnoreList = getFlag(options, keys, 'sourcemapIgnoreList', mustBeRegExp)
+  let sourcemapPathPrefix = getFlag(options, keys, 'sourcemapPathPrefix', mustBeObject)
   let sourceRoot = getFlag(options, keys, 'sourceRoot', mustBeString)
   let sourcesContent = getFlag(options, keys, 'sourcesContent', mustBeBoolean)
   let target = getFlag(options, keys, 'target', mustBeStringOrArray)
   let format = getFlag(options, keys, 'format', mustBeString)
   let globalName = getFlag(options, keys, 'globalName', mustBeString)
+  let importMetaShim = getFlag(options, keys, 'importMetaShim', mustBeString)
   let mangleProps = getFlag(options, keys, 'mangleProps', mustBeRegExp)
   let reserveProps = getFlag(options, keys, 'reserveProps', mustBeRegExp)
   let mangleQuoted = getFlag(options, keys, 'mangleQuoted', mustBeBoolean)
+  let reserveNames = getFlag(options, keys, 'reserveNames', mustBeArray)
   let minify = getFlag(options, keys, 'minify', mustBeBoolean)
   let minifySyntax = getFlag(options, keys, 'minifySyntax', mustBeBoolean)
   let minifyWhitespace = getFlag(options, keys, 'minifyWhitespace', mustBeBoolean)
   let minifyIdentifiers = getFlag(options, keys, 'minifyIdentifiers', mustBeBoolean)
   let drop = getFlag(options, keys, 'drop', mustBeArray)
+  let dropLabels = getFlag(options, keys, 'dropLabels', mustBeArray)
   let charset = getFlag(options, keys, 'charset', mustBeString)
+  let indent = getFlag(options, keys, 'indent', mustBeIntegerOrString)
+  let quoteStyle = getFlag(options, keys, 'quoteStyle', mustBeString)
   let treeShaking = getFlag(options, keys, 'treeShaking', mustBeBoolean)
   let ignoreAnnotations = getFlag(options, keys, 'ignoreAnnotations', mustBeBoolean)
   let jsx = getFlag(options, keys, 'jsx', mustBeString)
@@ -158,19 +172,33 @@ function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKe
   let define = getFlag(options, keys, 'define', mustBeObject)
   let logOverride = getFlag(options, keys, 'logOverride', mustBeObject)
   let supported = getFlag(options, keys, 'supported', mustBeObject)
+  let browserslist = getFlag(options, keys, 'browserslist', mustBeString)
   let pure = getFlag(options, keys, 'pure', mustBeArray)
   let keepNames = getFlag(options, keys, 'keepNames', mustBeBoolean)
   let platform = getFlag(options, keys, 'platform', mustBeString)
 
   if (legalComments) flags.push(`--legal-comments=${legalComments}`)
+  if (comments) flags.push(`--comments=${comments}`)
+  if (commentsFilter) flags.push(`--comments-filter=${commentsFilter.source}`)
+  if (sourcemapDebugId) flags.push(`--sourcemap-debug-id`)
+  if (sourcemapLinesOnly) flags.push(`--sourcemap-lines-only`)
+  if (sourcemapIgnoreList) flags.push(`--sourcemap-ignore-list=${sourcemapIgnoreList.source}`)
+  if (sourcemapPathPrefix) {
+    for (let old in sourcemapPathPrefix) {
+      if (old.indexOf('=') >= 0) throw new Error(`Invalid prefix in sourcemap path prefix: ${old}`)
+      flags.push(`--sourcemap-path-prefix:${old}=${validateStringValue(sourcemapPathPrefix[old], 'sourcemap path prefix', old)}`)
+    }
+  }
   if (sourceRoot !== void 0) flags.push(`--source-root=${sourceRoot}`)
   if (sourcesContent !== void 0) flags.push(`--sources-content=${sourcesContent}`)
   if (target) {
     if (Array.isArray(target)) flags.push(`--target=${Array.from(target).map(validateTarget).join(',')}`)
     else flags.push(`--target=${validateTarget(target)}`)
   }
+  if (browserslist) flags.push(`--browserslist=${browserslist}`)
   if (format) flags.push(`--format=${format}`)
   if (globalName) flags.push(`--global-name=${globalName}`)
+  if (importMetaShim) flags.push(`--import-meta-shim=${importMetaShim}`)
   if (platform) flags.push(`--platform=${platform}`)
 
   if (minify) flags.push('--minify')
@@ -178,11 +206,31 @@ function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKe
   if (minifyWhitespace) flags.push('--minify-whitespace')
   if (minifyIdentifiers) flags.push('--minify-identifiers')
   if (charset) flags.push(`--charset=${charset}`)
+  if (indent !== void 0) flags.push(`--indent=${indent}`)
+  if (quoteStyle) flags.push(`--quote-style=${quoteStyle}`)
   if (treeShaking !== void 0) flags.push(`--tree-shaking=${treeShaking}`)
   if (ignoreAnnotations) flags.push(`--ignore-annotations`)
   if (drop) for (let what of drop) flags.push(`--drop:${validateStringValue(what, 'drop')}`)
+  if (dropLabels) {
+    let values: string[] = []
+    for (let value of dropLabels) {
+      validateStringValue(value, 'drop label')
+      if (value.indexOf(',') >= 0) throw new Error(`Invalid drop label: ${value}`)
+      values.push(value)
+    }
+    flags.push(`--drop-labels=${values.join(',')}`)
+  }
   if (mangleProps) flags.push(`--mangle-props=${mangleProps.source}`)
   if (reserveProps) flags.push(`--reserve-props=${reserveProps.source}`)
+  if (reserveNames) {
+    let values: string[] = []
+    for (let value of reserveNames) {
+      validateStringValue(value, 'reserved name')
+      if (value.indexOf(',') >= 0) throw new Error(`Invalid reserved name: ${value}`)
+      values.push(value)
+    }
+    flags.push(`--reserve-names=${values.join(',')}`)
+  }
   if (mangleQuoted !== void 0) flags.push(`--mangle-quoted=${mangleQuoted}`)
 
   if (jsx) flags.push(`--jsx=${jsx}`)
@@ -243,8 +291,12 @@ function flagsForBuildOptions(
   let sourcemap = getFlag(options, keys, 'sourcemap', mustBeStringOrBoolean)
   let bundle = getFlag(options, keys, 'bundle', mustBeBoolean)
   let splitting = getFlag(options, keys, 'splitting', mustBeBoolean)
+  let runtimeChunk = getFlag(options, keys, 'runtimeChunk', mustBeBoolean)
+  let inlineWorkers = getFlag(options, keys, 'inlineWorkers', mustBeBoolean)
+  let htmlInlineLimit = getFlag(options, keys, 'htmlInlineLimit', mustBeInteger)
   let preserveSymlinks = getFlag(options, keys, 'preserveSymlinks', mustBeBoolean)
   let metafile = getFlag(options, keys, 'metafile', mustBeBoolean)
+  let extractLicenses = getFlag(options, keys, 'extractLicenses', mustBeBoolean)
   let outfile = getFlag(options, keys, 'outfile', mustBeString)
   let outdir = getFlag(options, keys, 'outdir', mustBeString)
   let outbase = getFlag(options, keys, 'outbase', mustBeString)
@@ -255,7 +307,14 @@ function flagsForBuildOptions(
   let conditions = getFlag(options, keys, 'conditions', mustBeArray)
   let external = getFlag(options, keys, 'external', mustBeArray)
   let packages = getFlag(options, keys, 'packages', mustBeString)
+  let nodePolyfills = getFlag(options, keys, 'nodePolyfills', mustBeBoolean)
+  let nodeGlobals = getFlag(options, keys, 'nodeGlobals', mustBeArray)
+  let dirname = getFlag(options, keys, 'dirname', mustBeString)
+  let importMap = getFlag(options, keys, 'importMap', mustBeString)
   let alias = getFlag(options, keys, 'alias', mustBeObject)
+  let exposes = getFlag(options, keys, 'exposes', mustBeObject)
+  let remotes = getFlag(options, keys, 'remotes', mustBeObject)
+  let globals = getFlag(options, keys, 'globals', mustBeObject)
   let loader = getFlag(options, keys, 'loader', mustBeObject)
   let outExtension = getFlag(options, keys, 'outExtension', mustBeObject)
   let publicPath = getFlag(options, keys, 'publicPath', mustBeString)
@@ -263,6 +322,9 @@ function flagsForBuildOptions(
   let chunkNames = getFlag(options, keys, 'chunkNames', mustBeString)
   let assetNames = getFlag(options, keys, 'assetNames', mustBeString)
   let inject = getFlag(options, keys, 'inject', mustBeArray)
+  let envFiles = getFlag(options, keys, 'envFiles', mustBeArray)
+  let envPrefixes = getFlag(options, keys, 'envPrefixes', mustBeArray)
+  let processEnv = getFlag(options, keys, 'processEnv', mustBeObject)
   let banner = getFlag(options, keys, 'banner', mustBeObject)
   let footer = getFlag(options, keys, 'footer', mustBeObject)
   let entryPoints = getFlag(options, keys, 'entryPoints', mustBeEntryPoints)
@@ -278,13 +340,20 @@ function flagsForBuildOptions(
   if (bundle) flags.push('--bundle')
   if (allowOverwrite) flags.push('--allow-overwrite')
   if (splitting) flags.push('--splitting')
+  if (runtimeChunk) flags.push('--runtime-chunk')
+  if (inlineWorkers) flags.push('--inline-workers')
+  if (htmlInlineLimit) flags.push(`--html-inline-limit=${htmlInlineLimit}`)
   if (preserveSymlinks) flags.push('--preserve-symlinks')
   if (metafile) flags.push(`--metafile`)
+  if (extractLicenses) flags.push(`--extract-licenses`)
   if (outfile) flags.push(`--outfile=${outfile}`)
   if (outdir) flags.push(`--outdir=${outdir}`)
   if (outbase) flags.push(`--outbase=${outbase}`)
   if (tsconfig) flags.push(`--tsconfig=${tsconfig}`)
   if (packages) flags.push(`--packages=${packages}`)
+  if (nodePolyfills) flags.push('--node-polyfills')
+  if (dirname) flags.push(`--dirname=${dirname}`)
+  if (importMap) flags.push(`--import-map=${importMap}`)
   if (resolveExtensions) {
     let values: string[] = []
     for (let value of resolveExtensions) {
@@ -307,6 +376,15 @@ function flagsForBuildOptions(
     }
     flags.push(`--main-fields=${values.join(',')}`)
   }
+  if (nodeGlobals) {
+    let values: string[] = []
+    for (let value of nodeGlobals) {
+      validateStringValue(value, 'node global')
+      if (value.indexOf(',') >= 0) throw new Error(`Invalid node global: ${value}`)
+      values.push(value)
+    }
+    flags.push(`--node-globals=${values.join(',')}`)
+  }
   if (conditions) {
     let values: string[] = []
     for (let value of conditions) {
@@ -323,6 +401,24 @@ function flagsForBuildOptions(
       flags.push(`--alias:${old}=${validateStringValue(alias[old], 'alias', old)}`)
     }
   }
+  if (exposes) {
+    for (let name in exposes) {
+      if (name.indexOf('=') >= 0) throw new Error(`Invalid exposed name: ${name}`)
+      flags.push(`--expose:${name}=${validateStringValue(exposes[name], 'exposes', name)}`)
+    }
+  }
+  if (remotes) {
+    for (let name in remotes) {
+      if (name.indexOf('=') >= 0) throw new Error(`Invalid remote name: ${name}`)
+      flags.push(`--remote:${name}=${validateStringValue(remotes[name], 'remotes', name)}`)
+    }
+  }
+  if (globals) {
+    for (let path in globals) {
+      if (path.indexOf('=') >= 0) throw new Error(`Invalid global import path: ${path}`)
+      flags.push(`--global:${path}=${validateStringValue(globals[path], 'globals', path)}`)
+    }
+  }
   if (banner) {
     for (let type in banner) {
       if (type.indexOf('=') >= 0) throw new Error(`Invalid banner file type: ${type}`)
@@ -336,6 +432,15 @@ function flagsForBuildOptions(
     }
   }
   if (inject) for (let path of inject) flags.push(`--inject:${validateStringValue(path, 'inject')}`)
+  if (envFiles) for (let path of envFiles) flags.push(`--env-file:${validateStringValue(path, 'env file')}`)
+  if (envPrefixes) for (let prefix of envPrefixes) flags.push(`--env-prefix:${validateStringValue(prefix, 'env prefix')}`)
+  if (processEnv) {
+    for (let key in processEnv) {
+      if (key.indexOf('=') >= 0) throw new Error(`Invalid process env key: ${key}`)
+      let value = processEnv[key]
+      if (value !== undefined) flags.push(`--process-env:${key}=${validateStringValue(value, 'processEnv', key)}`)
+    }
+  }
   if (loader) {
     for (let ext in loader) {
       if (ext.indexOf('=') >= 0) throw new Error(`Invalid loader extension: ${ext}`)
@@ -415,6 +520,7 @@ function flagsForTransformOptions(
 ): {
   flags: string[],
   mangleCache: MangleCache | undefined,
+  ast: boolean | undefined,
 } {
   let flags: string[] = []
   let keys: OptionKeys = Object.create(null)
@@ -424,15 +530,18 @@ function flagsForTransformOptions(
   let sourcemap = getFlag(options, keys, 'sourcemap', mustBeStringOrBoolean)
   let tsconfigRaw = getFlag(options, keys, 'tsconfigRaw', mustBeStringOrObject)
   let sourcefile = getFlag(options, keys, 'sourcefile', mustBeString)
+  let inputSourcemap = getFlag(options, keys, 'inputSourcemap', mustBeStringOrObject)
   let loader = getFlag(options, keys, 'loader', mustBeString)
   let banner = getFlag(options, keys, 'banner', mustBeString)
   let footer = getFlag(options, keys, 'footer', mustBeString)
   let mangleCache = getFlag(options, keys, 'mangleCache', mustBeObject)
+  let ast = getFlag(options, keys, 'ast', mustBeBoolean)
   checkForInvalidFlags(options, keys, `in ${callName}() call`)
 
   if (sourcemap) flags.push(`--sourcemap=${sourcemap === true ? 'external' : sourcemap}`)
   if (tsconfigRaw) flags.push(`--tsconfig-raw=${typeof tsconfigRaw === 'string' ? tsconfigRaw : JSON.stringify(tsconfigRaw)}`)
   if (sourcefile) flags.push(`--sourcefile=${sourcefile}`)
+  if (inputSourcemap) flags.push(`--input-sourcemap=${typeof inputSourcemap === 'string' ? inputSourcemap : JSON.stringify(inputSourcemap)}`)
   if (loader) flags.push(`--loader=${loader}`)
   if (banner) flags.push(`--banner=${banner}`)
   if (footer) flags.push(`--footer=${footer}`)
@@ -440,6 +549,7 @@ function flagsForTransformOptions(
   return {
     flags,
     mangleCache: validateMangleCache(mangleCache),
+    ast,
   }
 }
 
@@ -706,6 +816,7 @@ export function createChannel(streamIn: StreamIn): StreamOut {
         let {
           flags,
           mangleCache,
+          ast,
         } = flagsForTransformOptions(callName, options, isTTY, transformLogLevelDefault)
         let request: protocol.TransformRequest = {
           command: 'transform',
@@ -716,6 +827,7 @@ export function createChannel(streamIn: StreamIn): StreamOut {
               : input,
         }
         if (mangleCache) request.mangleCache = mangleCache
+        if (ast) request.ast = ast
         sendRequest<protocol.TransformRequest, protocol.TransformResponse>(refs, request, (error, response) => {
           if (error) return callback(new Error(error), null)
           let errors = replaceDetailsInMessages(response!.errors, details)
@@ -729,9 +841,11 @@ export function createChannel(streamIn: StreamIn): StreamOut {
                 map: response!.map,
                 mangleCache: undefined,
                 legalComments: undefined,
+                ast: undefined,
               }
               if ('legalComments' in response!) result.legalComments = response?.legalComments
               if (response!.mangleCache) result.mangleCache = response?.mangleCache
+              if (response!.ast) result.ast = JSON.parse(response!.ast)
               callback(null, result)
             }
           }
@@ -109,6 +109,7 @@ export interface TransformRequest {
   input: Uint8Array
   inputFS: boolean
   mangleCache?: Record<string, string | false>
+  ast?: boolean
 }
 
 export interface TransformResponse {
@@ -123,6 +124,7 @@ export interface TransformResponse {
 
   legalComments?: string
   mangleCache?: Record<string, string | false>
+  ast?: string
 }
 
 export interface FormatMsgsRequest {
@@ -1,6 +1,6 @@
-export type Platform = 'browser' | 'node' | 'neutral'
-export type Format = 'iife' | 'cjs' | 'esm'
-export type Loader = 'base64' | 'binary' | 'copy' | 'css' | 'dataurl' | 'default' | 'empty' | 'file' | 'js' | 'json' | 'jsx' | 'text' | 'ts' | 'tsx'
+export type Platform = 'browser' | 'node' | 'neutral' | 'deno'
+export type Format = 'iife' | 'cjs' | 'esm' | 'umd' | 'system'
+export type Loader = 'base64' | 'binary' | 'copy' | 'css' | 'dataurl' | 'default' | 'empty' | 'file' | 'flow' | 'html' | 'js' | 'json' | 'jsx' | 'text' | 'ts' | 'tsx'
 export type LogLevel = 'verbose' | 'debug' | 'info' | 'warning' | 'error' | 'silent'
 export type Charset = 'ascii' | 'utf8'
 export type Drop = 'console' | 'debugger'
@@ -10,6 +10,18 @@ interface CommonOptions {
   sourcemap?: boolean | 'linked' | 'inline' | 'external' | 'both'
   /** Documentation: https://esbuild.github.io/api/#legal-comments */
   legalComments?: 'none' | 'inline' | 'eof' | 'linked' | 'external'
+  /** Documentation: https://esbuild.github.io/api/#comments */
+  comments?: 'none' | 'legal' | 'all'
+  /** Documentation: https://esbuild.github.io/api/#comments */
+  commentsFilter?: RegExp
+  /** Documentation: https://esbuild.github.io/api/#sourcemap-debug-id */
+  sourcemapDebugId?: boolean
+  /** Documentation: https://esbuild.github.io/api/#sourcemap-ignore-list */
+  sourcemapIgnoreList?: RegExp
+  /** Documentation: https://esbuild.github.io/api/#sourcemap-lines-only */
+  sourcemapLinesOnly?: boolean
+  /** Documentation: https://esbuild.github.io/api/#sourcemap-path-prefix */
+  sourcemapPathPrefix?: Record<string, string>
   /** Documentation: https://esbuild.github.io/api/#source-root */
   sourceRoot?: string
   /** Documentation: https://esbuild.github.io/api/#sources-content */
@@ -19,10 +31,14 @@ interface CommonOptions {
   format?: Format
   /** Documentation: https://esbuild.github.io/api/#global-name */
   globalName?: string
+  /** Documentation: https://esbuild.github.io/api/#import-meta-shim */
+  importMetaShim?: string
   /** Documentation: https://esbuild.github.io/api/#target */
   target?: string | string[]
   /** Documentation: https://esbuild.github.io/api/#supported */
   supported?: Record<string, boolean>
+  /** Documentation: https://esbuild.github.io/api/#browserslist */
+  browserslist?: string
   /** Documentation: https://esbuild.github.io/api/#platform */
   platform?: Platform
 
@@ -34,8 +50,12 @@ interface CommonOptions {
   mangleQuoted?: boolean
   /** Documentation: https://esbuild.github.io/api/#mangle-props */
   mangleCache?: Record<string, string | false>
+  /** Documentation: https://esbuild.github.io/api/#reserve-names */
+  reserveNames?: string[]
   /** Documentation: https://esbuild.github.io/api/#drop */
   drop?: Drop[]
+  /** Documentation: https://esbuild.github.io/api/#drop-labels */
+  dropLabels?: string[]
   /** Documentation: https://esbuild.github.io/api/#minify */
   minify?: boolean
   /** Documentation: https://esbuild.github.io/api/#minify */
@@ -46,6 +66,10 @@ interface CommonOptions {
   minifySyntax?: boolean
   /** Documentation: https://esbuild.github.io/api/#charset */
   charset?: Charset
+  /** Documentation: https://esbuild.github.io/api/#indent */
+  indent?: number | 'tab'
+  /** Documentation: https://esbuild.github.io/api/#quote-style */
+  quoteStyle?: 'double' | 'single'
   /** Documentation: https://esbuild.github.io/api/#tree-shaking */
   treeShaking?: boolean
   /** Documentation: https://esbuild.github.io/api/#ignore-annotations */
@@ -86,12 +110,20 @@ export interface BuildOptions extends CommonOptions {
   bundle?: boolean
   /** Documentation: https://esbuild.github.io/api/#splitting */
   splitting?: boolean
+  /** Documentation: https://esbuild.github.io/api/#runtime-chunk */
+  runtimeChunk?: boolean
+  /** Documentation: https://esbuild.github.io/api/#inline-workers */
+  inlineWorkers?: boolean
+  /** Documentation: https://esbuild.github.io/api/#html-inline-limit */
+  htmlInlineLimit?: number
   /** Documentation: https://esbuild.github.io/api/#preserve-symlinks */
   preserveSymlinks?: boolean
   /** Documentation: https://esbuild.github.io/api/#outfile */
   outfile?: string
   /** Documentation: https://esbuild.github.io/api/#metafile */
   metafile?: boolean
+  /** Documentation: https://esbuild.github.io/api/#extract-licenses */
+  extractLicenses?: boolean
   /** Documentation: https://esbuild.github.io/api/#outdir */
   outdir?: string
   /** Documentation: https://esbuild.github.io/api/#outbase */
@@ -100,8 +132,22 @@ export interface BuildOptions extends CommonOptions {
   external?: string[]
   /** Documentation: https://esbuild.github.io/api/#packages */
   packages?: 'external'
+  /** Documentation: https://esbuild.github.io/api/#node-polyfills */
+  nodePolyfills?: boolean
+  /** Documentation: https://esbuild.github.io/api/#node-globals */
+  nodeGlobals?: ('Buffer' | 'process')[]
+  /** Documentation: https://esbuild.github.io/api/#dirname */
+  dirname?: 'source' | 'runtime' | 'error'
+  /** Documentation: https://esbuild.github.io/api/#import-map */
+  importMap?: string
   /** Documentation: https://esbuild.github.io/api/#alias */
   alias?: Record<string, string>
+  /** Documentation: https://esbuild.github.io/api/#exposes */
+  exposes?: Record<string, string>
+  /** Documentation: https://esbuild.github.io/api/#remotes */
+  remotes?: Record<string, string>
+  /** Documentation: https://esbuild.github.io/api/#globals */
+  globals?: Record<string, string>
   /** Documentation: https://esbuild.github.io/api/#loader */
   loader?: { [ext: string]: Loader }
   /** Documentation: https://esbuild.github.io/api/#resolve-extensions */
@@ -128,6 +174,12 @@ export interface BuildOptions extends CommonOptions {
   assetNames?: string
   /** Documentation: https://esbuild.github.io/api/#inject */
   inject?: string[]
+  /** Documentation: https://esbuild.github.io/api/#env-files */
+  envFiles?: string[]
+  /** Documentation: https://esbuild.github.io/api/#env-prefixes */
+  envPrefixes?: string[]
+  /** Documentation: https://esbuild.github.io/api/#process-env */
+  processEnv?: Record<string, string>
   /** Documentation: https://esbuild.github.io/api/#banner */
   banner?: { [type: string]: string }
   /** Documentation: https://esbuild.github.io/api/#footer */
@@ -248,9 +300,13 @@ export interface TransformOptions extends CommonOptions {
   }
 
   sourcefile?: string
+  /** Documentation: https://esbuild.github.io/api/#input-sourcemap */
+  inputSourcemap?: string | object
   loader?: Loader
   banner?: string
   footer?: string
+  /** Documentation: https://esbuild.github.io/api/#ast */
+  ast?: boolean
 }
 
 export interface TransformResult<SpecificOptions extends TransformOptions = TransformOptions> {
@@ -261,6 +317,39 @@ export interface TransformResult<SpecificOptions extends TransformOptions = Tran
   mangleCache: Record<string, string | false> | (SpecificOptions['mangleCache'] extends Object ? never : undefined)
   /** Only when "legalComments" is "external" */
   legalComments: string | (SpecificOptions['legalComments'] extends 'external' ? never : undefined)
+  /** Only when "ast" is true */
+  ast: TransformAST | (SpecificOptions['ast'] extends true ? never : undefined)
+}
+
+/**
+ * The root of the AST. Nodes follow the ESTree specification
+ * (https://github.com/estree/estree) including the JSX extension, except
+ * that each node has a "start" byte offset instead of "loc" and "range".
+ *
+ * Documentation: https://esbuild.github.io/api/#ast
+ */
+export interface TransformAST extends ASTNode {
+  type: 'Program'
+  sourceType: 'script' | 'module'
+  hashbang?: string
+  body: ASTNode[]
+  /** Only the comments that esbuild keeps in the output */
+  comments: ASTComment[]
+}
+
+export interface ASTNode {
+  /** The ESTree node type (e.g. "ReturnStatement" or "BinaryExpression") */
+  type: string
+  /** The byte offset of the node in the input (missing for top-level directives) */
+  start?: number
+  [key: string]: any
+}
+
+export interface ASTComment {
+  type: 'Line' | 'Block'
+  /** The comment text without the "//" or "/* */" delimiters */
+  value: string
+  start: number
 }
 
 export interface TransformFailure extends Error {
@@ -372,6 +461,8 @@ export type ImportKind =
   | 'require-call'
   | 'dynamic-import'
   | 'require-resolve'
+  | 'worker'
+  | 'new-url'
 
   // CSS
   | 'import-rule'
@@ -450,6 +541,7 @@ export interface Metafile {
         external?: boolean
         original?: string
       }[]
+      directives?: string[]
       format?: 'cjs' | 'esm'
     }
   }
@@ -469,6 +561,7 @@ export interface Metafile {
       exports: string[]
       entryPoint?: string
       cssBundle?: string
+      directives?: string[]
     }
   }
 }
@@ -110,6 +110,15 @@ const (
 	LegalCommentsExternal
 )
 
+type Comments uint8
+
+const (
+	CommentsDefault Comments = iota
+	CommentsNone
+	CommentsLegal
+	CommentsAll
+)
+
 type JSX uint8
 
 const (
@@ -146,6 +155,8 @@ const (
 	LoaderDefault
 	LoaderEmpty
 	LoaderFile
+	LoaderFlow
+	LoaderHTML
 	LoaderJS
 	LoaderJSON
 	LoaderJSX
@@ -161,6 +172,7 @@ const (
 	PlatformBrowser
 	PlatformNode
 	PlatformNeutral
+	PlatformDeno
 )
 
 type Format uint8
@@ -170,6 +182,8 @@ const (
 	FormatIIFE
 	FormatCommonJS
 	FormatESModule
+	FormatUMD
+	FormatSystem
 )
 
 type Packages uint8
@@ -179,6 +193,15 @@ const (
 	PackagesExternal
 )
 
+type Dirname uint8
+
+const (
+	DirnameDefault Dirname = iota
+	DirnameSource
+	DirnameRuntime
+	DirnameError
+)
+
 type Engine struct {
 	Name    EngineName
 	Version string
@@ -238,6 +261,14 @@ const (
 	CharsetUTF8
 )
 
+type QuoteStyle uint8
+
+const (
+	QuoteStyleDefault QuoteStyle = iota
+	QuoteStyleDouble
+	QuoteStyleSingle
+)
+
 type TreeShaking uint8
 
 const (
@@ -269,26 +300,38 @@ type BuildOptions struct {
 	LogLimit    int                 // Documentation: https://esbuild.github.io/api/#log-limit
 	LogOverride map[string]LogLevel // Documentation: https://esbuild.github.io/api/#log-override
 
-	Sourcemap      SourceMap      // Documentation: https://esbuild.github.io/api/#sourcemap
-	SourceRoot     string         // Documentation: https://esbuild.github.io/api/#source-root
-	SourcesContent SourcesContent // Documentation: https://esbuild.github.io/api/#sources-content
+	Sourcemap           SourceMap         // Documentation: https://esbuild.github.io/api/#sourcemap
+	SourcemapDebugID    bool              // Documentation: https://esbuild.github.io/api/#sourcemap-debug-id
+	SourcemapIgnoreList string            // Documentation: https://esbuild.github.io/api/#sourcemap-ignore-list
+	SourcemapPathPrefix map[string]string // Documentation: https://esbuild.github.io/api/#sourcemap-path-prefix
+	SourcemapLinesOnly  bool              // Documentation: https://esbuild.github.io/api/#sourcemap-lines-only
+	SourceRoot          string            // Documentation: https://esbuild.github.io/api/#source-root
+	SourcesContent      SourcesContent    // Documentation: https://esbuild.github.io/api/#sources-content
 
 	Target    Target          // Documentation: https://esbuild.github.io/api/#target
 	Engines   []Engine        // Documentation: https://esbuild.github.io/api/#target
 	Supported map[string]bool // Documentation: https://esbuild.github.io/api/#supported
 
+	Browserslist string // Documentation: https://esbuild.github.io/api/#browserslist
+
 	MangleProps       string                 // Documentation: https://esbuild.github.io/api/#mangle-props
 	ReserveProps      string                 // Documentation: https://esbuild.github.io/api/#mangle-props
+	ReserveNames      []string               // Documentation: https://esbuild.github.io/api/#reserve-names
 	MangleQuoted      MangleQuoted           // Documentation: https://esbuild.github.io/api/#mangle-props
 	MangleCache       map[string]interface{} // Documentation: https://esbuild.github.io/api/#mangle-props
 	Drop              Drop                   // Documentation: https://esbuild.github.io/api/#drop
+	DropLabels        []string               // Documentation: https://esbuild.github.io/api/#drop-labels
 	MinifyWhitespace  bool                   // Documentation: https://esbuild.github.io/api/#minify
 	MinifyIdentifiers bool                   // Documentation: https://esbuild.github.io/api/#minify
 	MinifySyntax      bool                   // Documentation: https://esbuild.github.io/api/#minify
 	Charset           Charset                // Documentation: https://esbuild.github.io/api/#charset
+	Indent            string                 // Documentation: https://esbuild.github.io/api/#indent
+	QuoteStyle        QuoteStyle             // Documentation: https://esbuild.github.io/api/#quote-style
 	TreeShaking       TreeShaking            // Documentation: https://esbuild.github.io/api/#tree-shaking
 	IgnoreAnnotations bool                   // Documentation: https://esbuild.github.io/api/#ignore-annotations
 	LegalComments     LegalComments          // Documentation: https://esbuild.github.io/api/#legal-comments
+	Comments          Comments               // Documentation: https://esbuild.github.io/api/#comments
+	CommentsFilter    string                 // Documentation: https://esbuild.github.io/api/#comments
 
 	JSX             JSX    // Documentation: https://esbuild.github.io/api/#jsx-mode
 	JSXFactory      string // Documentation: https://esbuild.github.io/api/#jsx-factory
@@ -302,11 +345,16 @@ type BuildOptions struct {
 	KeepNames bool              // Documentation: https://esbuild.github.io/api/#keep-names
 
 	GlobalName        string            // Documentation: https://esbuild.github.io/api/#global-name
+	ImportMetaShim    string            // Documentation: https://esbuild.github.io/api/#import-meta-shim
 	Bundle            bool              // Documentation: https://esbuild.github.io/api/#bundle
 	PreserveSymlinks  bool              // Documentation: https://esbuild.github.io/api/#preserve-symlinks
 	Splitting         bool              // Documentation: https://esbuild.github.io/api/#splitting
+	RuntimeChunk      bool              // Documentation: https://esbuild.github.io/api/#runtime-chunk
+	InlineWorkers     bool              // Documentation: https://esbuild.github.io/api/#inline-workers
+	HTMLInlineLimit   int               // Documentation: https://esbuild.github.io/api/#html-inline-limit
 	Outfile           string            // Documentation: https://esbuild.github.io/api/#outfile
 	Metafile          bool              // Documentation: https://esbuild.github.io/api/#metafile
+	ExtractLicenses   bool              // Documentation: https://esbuild.github.io/api/#extract-licenses
 	Outdir            string            // Documentation: https://esbuild.github.io/api/#outdir
 	Outbase           string            // Documentation: https://esbuild.github.io/api/#outbase
 	AbsWorkingDir     string            // Documentation: https://esbuild.github.io/api/#working-directory
@@ -314,7 +362,14 @@ type BuildOptions struct {
 	Format            Format            // Documentation: https://esbuild.github.io/api/#format
 	External          []string          // Documentation: https://esbuild.github.io/api/#external
 	Packages          Packages          // Documentation: https://esbuild.github.io/api/#packages
+	NodePolyfills     bool              // Documentation: https://esbuild.github.io/api/#node-polyfills
+	NodeGlobals       []string          // Documentation: https://esbuild.github.io/api/#node-globals
+	Dirname           Dirname           // Documentation: https://esbuild.github.io/api/#dirname
+	ImportMap         string            // Documentation: https://esbuild.github.io/api/#import-map
 	Alias             map[string]string // Documentation: https://esbuild.github.io/api/#alias
+	Exposes           map[string]string // Documentation: https://esbuild.github.io/api/#exposes
+	Remotes           map[string]string // Documentation: https://esbuild.github.io/api/#remotes
+	Globals           map[string]string // Documentation: https://esbuild.github.io/api/#globals
 	MainFields        []string          // Documentation: https://esbuild.github.io/api/#main-fields
 	Conditions        []string          // Documentation: https://esbuild.github.io/api/#conditions
 	Loader            map[string]Loader // Documentation: https://esbuild.github.io/api/#loader
@@ -323,6 +378,9 @@ type BuildOptions struct {
 	OutExtension      map[string]string // Documentation: https://esbuild.github.io/api/#out-extension
 	PublicPath        string            // Documentation: https://esbuild.github.io/api/#public-path
 	Inject            []string          // Documentation: https://esbuild.github.io/api/#inject
+	EnvFiles          []string          // Documentation: https://esbuild.github.io/api/#env-files
+	EnvPrefixes       []string          // Documentation: https://esbuild.github.io/api/#env-prefixes
+	ProcessEnv        map[string]string // Documentation: https://esbuild.github.io/api/#process-env
 	Banner            map[string]string // Documentation: https://esbuild.github.io/api/#banner
 	Footer            map[string]string // Documentation: https://esbuild.github.io/api/#footer
 	NodePaths         []string          // Documentation: https://esbuild.github.io/api/#node-paths
@@ -396,30 +454,43 @@ type TransformOptions struct {
 	LogLimit    int                 // Documentation: https://esbuild.github.io/api/#log-limit
 	LogOverride map[string]LogLevel // Documentation: https://esbuild.github.io/api/#log-override
 
-	Sourcemap      SourceMap      // Documentation: https://esbuild.github.io/api/#sourcemap
-	SourceRoot     string         // Documentation: https://esbuild.github.io/api/#source-root
-	SourcesContent SourcesContent // Documentation: https://esbuild.github.io/api/#sources-content
+	Sourcemap           SourceMap         // Documentation: https://esbuild.github.io/api/#sourcemap
+	SourcemapDebugID    bool              // Documentation: https://esbuild.github.io/api/#sourcemap-debug-id
+	SourcemapIgnoreList string            // Documentation: https://esbuild.github.io/api/#sourcemap-ignore-list
+	SourcemapPathPrefix map[string]string // Documentation: https://esbuild.github.io/api/#sourcemap-path-prefix
+	SourcemapLinesOnly  bool              // Documentation: https://esbuild.github.io/api/#sourcemap-lines-only
+	SourceRoot          string            // Documentation: https://esbuild.github.io/api/#source-root
+	SourcesContent      SourcesContent    // Documentation: https://esbuild.github.io/api/#sources-content
 
 	Target    Target          // Documentation: https://esbuild.github.io/api/#target
 	Engines   []Engine        // Documentation: https://esbuild.github.io/api/#target
 	Supported map[string]bool // Documentation: https://esbuild.github.io/api/#supported
 
-	Platform   Platform // Documentation: https://esbuild.github.io/api/#platform
-	Format     Format   // Documentation: https://esbuild.github.io/api/#format
-	GlobalName string   // Documentation: https://esbuild.github.io/api/#global-name
+	Browserslist string // Documentation: https://esbuild.github.io/api/#browserslist
+
+	Platform       Platform // Documentation: https://esbuild.github.io/api/#platform
+	Format         Format   // Documentation: https://esbuild.github.io/api/#format
+	GlobalName     string   // Documentation: https://esbuild.github.io/api/#global-name
+	ImportMetaShim string   // Documentation: https://esbuild.github.io/api/#import-meta-shim
 
 	MangleProps       string                 // Documentation: https://esbuild.github.io/api/#mangle-props
 	ReserveProps      string                 // Documentation: https://esbuild.github.io/api/#mangle-props
+	ReserveNames      []string               // Documentation: https://esbuild.github.io/api/#reserve-names
 	MangleQuoted      MangleQuoted           // Documentation: https://esbuild.github.io/api/#mangle-props
 	MangleCache       map[string]interface{} // Documentation: https://esbuild.github.io/api/#mangle-props
 	Drop              Drop                   // Documentation: https://esbuild.github.io/api/#drop
+	DropLabels        []string               // Documentation: https://esbuild.github.io/api/#drop-labels
 	MinifyWhitespace  bool                   // Documentation: https://esbuild.github.io/api/#minify
 	MinifyIdentifiers bool                   // Documentation: https://esbuild.github.io/api/#minify
 	MinifySyntax      bool                   // Documentation: https://esbuild.github.io/api/#minify
 	Charset           Charset                // Documentation: https://esbuild.github.io/api/#charset
+	Indent            string                 // Documentation: https://esbuild.github.io/api/#indent
+	QuoteStyle        QuoteStyle             // Documentation: https://esbuild.github.io/api/#quote-style
 	TreeShaking       TreeShaking            // Documentation: https://esbuild.github.io/api/#tree-shaking
 	IgnoreAnnotations bool                   // Documentation: https://esbuild.github.io/api/#ignore-annotations
 	LegalComments     LegalComments          // Documentation: https://esbuild.github.io/api/#legal-comments
+	Comments          Comments               // Documentation: https://esbuild.github.io/api/#comments
+	CommentsFilter    string                 // Documentation: https://esbuild.github.io/api/#comments
 
 	JSX             JSX    // Documentation: https://esbuild.github.io/api/#jsx
 	JSXFactory      string // Documentation: https://esbuild.github.io/api/#jsx-factory
@@ -436,8 +507,10 @@ type TransformOptions struct {
 	Pure      []string          // Documentation: https://esbuild.github.io/api/#pure
 	KeepNames bool              // Documentation: https://esbuild.github.io/api/#keep-names
 
-	Sourcefile string // Documentation: https://esbuild.github.io/api/#sourcefile
-	Loader     Loader // Documentation: https://esbuild.github.io/api/#loader
+	Sourcefile     string // Documentation: https://esbuild.github.io/api/#sourcefile
+	InputSourcemap string // Documentation: https://esbuild.github.io/api/#input-sourcemap
+	Loader         Loader // Documentation: https://esbuild.github.io/api/#loader
+	AST            bool   // Documentation: https://esbuild.github.io/api/#ast
 }
 
 type TransformResult struct {
@@ -447,6 +520,7 @@ type TransformResult struct {
 	Code          []byte
 	Map           []byte
 	LegalComments []byte
+	AST           []byte // Only when "AST" is true (ESTree-style JSON)
 
 	MangleCache map[string]interface{}
 }
@@ -666,6 +740,8 @@ const (
 	ResolveJSRequireResolve
 	ResolveCSSImportRule
 	ResolveCSSURLToken
+	ResolveJSWorker
+	ResolveJSNewURL
 )
 
 ////////////////////////////////////////////////////////////////////////////////
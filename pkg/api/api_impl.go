@@ -30,6 +30,7 @@ import (
 	"github.com/evanw/esbuild/internal/helpers"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/js_printer"
 	"github.com/evanw/esbuild/internal/linker"
 	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/resolver"
@@ -110,6 +111,8 @@ func validatePlatform(value Platform) config.Platform {
 		return config.PlatformNode
 	case PlatformNeutral:
 		return config.PlatformNeutral
+	case PlatformDeno:
+		return config.PlatformDeno
 	default:
 		panic("Invalid platform")
 	}
@@ -125,6 +128,10 @@ func validateFormat(value Format) config.Format {
 		return config.FormatCommonJS
 	case FormatESModule:
 		return config.FormatESModule
+	case FormatUMD:
+		return config.FormatUMD
+	case FormatSystem:
+		return config.FormatSystem
 	default:
 		panic("Invalid format")
 	}
@@ -170,6 +177,30 @@ func validateLegalComments(value LegalComments, bundle bool) config.LegalComment
 	}
 }
 
+func validateComments(log logger.Log, value Comments, legalComments LegalComments, filter string) config.CommentsMode {
+	var mode config.CommentsMode
+	switch value {
+	case CommentsDefault:
+		mode = config.CommentsDefault
+	case CommentsNone:
+		mode = config.CommentsNone
+	case CommentsLegal:
+		mode = config.CommentsLegalOnly
+	case CommentsAll:
+		mode = config.CommentsAll
+	default:
+		panic("Invalid comments")
+	}
+
+	if mode == config.CommentsNone && legalComments != LegalCommentsDefault && legalComments != LegalCommentsNone {
+		log.AddError(nil, logger.Range{}, "Cannot use \"legalComments\" when \"comments\" is set to \"none\"")
+	}
+	if filter != "" && (mode == config.CommentsNone || mode == config.CommentsLegalOnly) {
+		log.AddError(nil, logger.Range{}, "Cannot use \"commentsFilter\" when only legal comments are kept")
+	}
+	return mode
+}
+
 func validateColor(value StderrColor) logger.UseColor {
 	switch value {
 	case ColorIfTerminal:
@@ -213,16 +244,35 @@ func validateASCIIOnly(value Charset) bool {
 	}
 }
 
+func validatePreferSingleQuotes(value QuoteStyle) bool {
+	switch value {
+	case QuoteStyleDefault, QuoteStyleDouble:
+		return false
+	case QuoteStyleSingle:
+		return true
+	default:
+		panic("Invalid quote style")
+	}
+}
+
+func validateIndent(log logger.Log, indent string) string {
+	if indent != "" && strings.Trim(indent, " ") != "" && strings.Trim(indent, "\t") != "" {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid indent %q: Must contain only spaces or only tabs", indent))
+		return ""
+	}
+	return indent
+}
+
 func validateTreeShaking(value TreeShaking, bundle bool, format Format) bool {
 	switch value {
 	case TreeShakingDefault:
-		// If we're in an IIFE then there's no way to concatenate additional code
+		// If we're in an IIFE (or UMD or SystemJS) then there's no way to concatenate additional code
 		// to the end of our output so we assume tree shaking is safe. And when
 		// bundling we assume that tree shaking is safe because if you want to add
 		// code to the bundle, you should be doing that by including it in the
 		// bundle instead of concatenating it afterward, so we also assume tree
 		// shaking is safe then. Otherwise we assume tree shaking is not safe.
-		return bundle || format == FormatIIFE
+		return bundle || format == FormatIIFE || format == FormatUMD || format == FormatSystem
 	case TreeShakingFalse:
 		return false
 	case TreeShakingTrue:
@@ -250,6 +300,10 @@ func validateLoader(value Loader) config.Loader {
 		return config.LoaderEmpty
 	case LoaderFile:
 		return config.LoaderFile
+	case LoaderFlow:
+		return config.LoaderFlow
+	case LoaderHTML:
+		return config.LoaderHTML
 	case LoaderJS:
 		return config.LoaderJS
 	case LoaderJSON:
@@ -291,8 +345,8 @@ func validateEngine(value EngineName) compat.Engine {
 var versionRegex = regexp.MustCompile(`^([0-9]+)(?:\.([0-9]+))?(?:\.([0-9]+))?$`)
 var preReleaseVersionRegex = regexp.MustCompile(`^([0-9]+)(?:\.([0-9]+))?(?:\.([0-9]+))?-`)
 
-func validateFeatures(log logger.Log, target Target, engines []Engine) (config.TargetFromAPI, compat.JSFeature, compat.CSSFeature, string) {
-	if target == DefaultTarget && len(engines) == 0 {
+func validateFeatures(log logger.Log, target Target, engines []Engine, browserslist map[compat.Engine][]int) (config.TargetFromAPI, compat.JSFeature, compat.CSSFeature, string) {
+	if target == DefaultTarget && len(engines) == 0 && browserslist == nil {
 		return config.TargetWasUnconfigured, 0, 0, ""
 	}
 
@@ -354,6 +408,14 @@ func validateFeatures(log logger.Log, target Target, engines []Engine) (config.T
 			[]logger.MsgData{{Text: text}})
 	}
 
+	// A browserslist query can only lower the version of each engine, since
+	// the code must run in every browser that the query includes
+	for engine, version := range browserslist {
+		if existing, ok := constraints[engine]; !ok || compat.CompareVersionSlices(version, existing) < 0 {
+			constraints[engine] = version
+		}
+	}
+
 	for engine, version := range constraints {
 		var text string
 		switch len(version) {
@@ -397,6 +459,108 @@ func validateSupported(log logger.Log, supported map[string]bool) (
 	return
 }
 
+func validateBrowserslist(log logger.Log, realFS fs.FS, value string) map[compat.Engine][]int {
+	if value == "" {
+		return nil
+	}
+
+	source := logger.Source{
+		KeyPath:    logger.Path{Text: "(browserslist)"},
+		PrettyPath: "(browserslist)",
+		Contents:   value,
+	}
+
+	// Load the query from a configuration file if the value is a path to one
+	if base := path.Base(strings.ReplaceAll(value, "\\", "/")); base == ".browserslistrc" || base == "package.json" {
+		if realFS == nil {
+			// The transform API doesn't have a file system, so use the current directory
+			realFS, _ = fs.RealFS(fs.RealFSOptions{})
+		}
+		absPath := validatePath(log, realFS, value, "browserslist config path")
+		contents, err, _ := realFS.ReadFile(absPath)
+		if err != nil {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot read browserslist config %q: %s", value, err.Error()))
+			return nil
+		}
+		keyPath := logger.Path{Text: absPath, Namespace: "file"}
+		source = logger.Source{
+			KeyPath:    keyPath,
+			PrettyPath: resolver.PrettyPath(realFS, keyPath),
+			Contents:   contents,
+		}
+
+		if base == "package.json" {
+			json, ok := js_parser.ParseJSON(log, source, js_parser.JSONOptions{})
+			if !ok {
+				return nil
+			}
+			queries := getObjectProperty(json, "browserslist")
+			if _, ok := queries.Data.(*js_ast.EObject); ok {
+				// Like browserslist, use the "production" environment by default
+				queries = getObjectProperty(queries, "production")
+			}
+			array, ok := queries.Data.(*js_ast.EArray)
+			if !ok {
+				log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot find a \"browserslist\" array in %q", source.PrettyPath))
+				return nil
+			}
+			var lines []string
+			for _, item := range array.Items {
+				if str, ok := item.Data.(*js_ast.EString); ok {
+					lines = append(lines, helpers.UTF16ToString(str.Value))
+				}
+			}
+			source = logger.Source{
+				KeyPath:    logger.Path{Text: "(browserslist)"},
+				PrettyPath: fmt.Sprintf("(browserslist in %s)", source.PrettyPath),
+				Contents:   strings.Join(lines, "\n"),
+			}
+		} else {
+			source.Contents = selectBrowserslistEnvironment(source.Contents)
+		}
+	}
+
+	versions, _ := compat.ParseBrowserslist(log, source)
+	return versions
+}
+
+var browserslistSectionRegex = regexp.MustCompile(`^\s*\[([^\]]*)\]\s*$`)
+
+// Configuration files can have sections for different environments such as
+// "[production]". Like browserslist, we use the "production" section if there
+// is one and the queries before the first section otherwise. Lines that aren't
+// used are replaced with spaces so that the locations in error messages are
+// still correct.
+func selectBrowserslistEnvironment(contents string) string {
+	lines := strings.Split(contents, "\n")
+	hasProduction := false
+	for _, line := range lines {
+		if match := browserslistSectionRegex.FindStringSubmatch(line); match != nil {
+			for _, name := range strings.Fields(match[1]) {
+				if name == "production" {
+					hasProduction = true
+				}
+			}
+		}
+	}
+
+	isIncluded := !hasProduction
+	for i, line := range lines {
+		if match := browserslistSectionRegex.FindStringSubmatch(line); match != nil {
+			isIncluded = false
+			for _, name := range strings.Fields(match[1]) {
+				if name == "production" {
+					isIncluded = true
+				}
+			}
+			lines[i] = strings.Repeat(" ", len(line))
+		} else if !isIncluded {
+			lines[i] = strings.Repeat(" ", len(line))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 func validateGlobalName(log logger.Log, text string) []string {
 	if text != "" {
 		source := logger.Source{
@@ -413,6 +577,24 @@ func validateGlobalName(log logger.Log, text string) []string {
 	return nil
 }
 
+func validateDropLabels(log logger.Log, labels []string) []string {
+	for _, label := range labels {
+		if !js_ast.IsIdentifier(label) {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid drop label: %q", label))
+		}
+	}
+	return labels
+}
+
+func validateReserveNames(log logger.Log, names []string) []string {
+	for _, name := range names {
+		if !js_ast.IsIdentifier(name) {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid reserved name: %q", name))
+		}
+	}
+	return names
+}
+
 func validateRegex(log logger.Log, what string, value string) *regexp.Regexp {
 	if value == "" {
 		return nil
@@ -529,6 +711,111 @@ func validateAlias(log logger.Log, fs fs.FS, alias map[string]string) map[string
 	return valid
 }
 
+func validateExposes(log logger.Log, exposes map[string]string) []config.ExposedModule {
+	valid := make([]config.ExposedModule, 0, len(exposes))
+
+	for name, inputPath := range exposes {
+		// Exposed names are file paths relative to the output directory, so they
+		// must not escape it. A leading "./" is allowed and ignored.
+		name = strings.TrimPrefix(name, "./")
+		if name == "" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "/") || strings.Contains(name, "\\") ||
+			path.Clean(name) != name || name == config.FederationContainerName {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid exposed name: %q", name))
+			continue
+		}
+		if inputPath == "" {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid path for exposed name %q: %q", name, inputPath))
+			continue
+		}
+		valid = append(valid, config.ExposedModule{Name: name, InputPath: inputPath})
+	}
+
+	// Sort for determinism since map iteration order is random
+	sort.Slice(valid, func(i, j int) bool {
+		return valid[i].Name < valid[j].Name
+	})
+	return valid
+}
+
+func validateRemotes(log logger.Log, remotes map[string]string) map[string]string {
+	valid := make(map[string]string, len(remotes))
+
+	for name, url := range remotes {
+		// Remote names are package names, so they follow the same rules as aliases
+		if name == "" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") ||
+			path.Clean(strings.ReplaceAll(name, "\\", "/")) != name {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid remote name: %q", name))
+			continue
+		}
+		if url == "" {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid URL for remote %q: %q", name, url))
+			continue
+		}
+		valid[name] = strings.TrimSuffix(url, "/")
+	}
+
+	return valid
+}
+
+func validateGlobals(log logger.Log, globals map[string]string) map[string][]string {
+	if len(globals) == 0 {
+		return nil
+	}
+	valid := make(map[string][]string, len(globals))
+
+	for path, text := range globals {
+		if path == "" {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid global import path: %q", path))
+			continue
+		}
+		if text == "" {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid global name for %q: %q", path, text))
+			continue
+		}
+		if name := validateGlobalName(log, text); name != nil {
+			valid[path] = name
+		}
+	}
+
+	return valid
+}
+
+func validateDirname(value Dirname) config.DirnameMode {
+	switch value {
+	case DirnameDefault:
+		return config.DirnameDefault
+	case DirnameSource:
+		return config.DirnameSource
+	case DirnameRuntime:
+		return config.DirnameRuntime
+	case DirnameError:
+		return config.DirnameError
+	default:
+		panic("Invalid dirname")
+	}
+}
+
+func validateNodeGlobals(log logger.Log, names []string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+	valid := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+
+	for _, name := range names {
+		if !bundler.IsNodeGlobal(name) {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid node global: %q (valid: Buffer, process)", name))
+			continue
+		}
+		if !seen[name] {
+			seen[name] = true
+			valid = append(valid, name)
+		}
+	}
+
+	return valid
+}
+
 func isValidExtension(ext string) bool {
 	return len(ext) >= 2 && ext[0] == '.' && ext[len(ext)-1] != '.'
 }
@@ -568,6 +855,111 @@ func validateJSXExpr(log logger.Log, text string, name string) config.DefineExpr
 	return config.DefineExpr{}
 }
 
+func validateImportMetaShim(log logger.Log, text string) string {
+	if text != "" {
+		if expr, value := js_parser.ParseDefineExprOrJSON(text); len(expr.Parts) > 0 {
+			return text
+		} else if _, ok := value.(*js_ast.EObject); ok {
+			return text
+		}
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid import meta shim (must be an entity name or a JSON object): %q", text))
+	}
+	return ""
+}
+
+// This loads variables from ".env" files and adds them to the defines as both
+// "process.env.X" and "import.meta.env.X". Only variables that start with one
+// of the prefixes are included since these files often contain secrets that
+// must not end up in the bundle. Variables from later files override earlier
+// ones, and explicit defines override all of them.
+func validateEnvFiles(log logger.Log, realFS fs.FS, files []string, prefixes []string, defines map[string]string) map[string]string {
+	if len(files) == 0 {
+		return defines
+	}
+	if len(prefixes) == 0 {
+		log.AddErrorWithNotes(nil, logger.Range{}, "Cannot use env files without an env prefix", []logger.MsgData{{
+			Text: "Env files often contain secrets, so only variables with names that start with an env prefix are included in the bundle."}})
+		return defines
+	}
+
+	var vars []config.EnvVar
+	for _, file := range files {
+		absPath := validatePath(log, realFS, file, "env file path")
+		contents, err, _ := realFS.ReadFile(absPath)
+		if err != nil {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot read env file %q: %s", file, err.Error()))
+			continue
+		}
+		keyPath := logger.Path{Text: absPath, Namespace: "file"}
+		source := logger.Source{
+			KeyPath:    keyPath,
+			PrettyPath: resolver.PrettyPath(realFS, keyPath),
+			Contents:   contents,
+		}
+		fileVars, _ := config.ParseEnvFile(log, source)
+		vars = append(vars, fileVars...)
+	}
+
+	result := make(map[string]string, len(defines)+2*len(vars))
+	for key, value := range defines {
+		result[key] = value
+	}
+
+	for _, v := range vars {
+		// Names that aren't identifiers can't be referenced with a property access
+		if !js_ast.IsIdentifier(v.Key) {
+			continue
+		}
+		hasPrefix := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(v.Key, prefix) {
+				hasPrefix = true
+				break
+			}
+		}
+		if !hasPrefix {
+			continue
+		}
+		value := string(helpers.QuoteForJSON(v.Value, false))
+		for _, key := range [2]string{"process.env." + v.Key, "import.meta.env." + v.Key} {
+			if _, ok := defines[key]; !ok {
+				result[key] = value
+			}
+		}
+	}
+
+	return result
+}
+
+// This replaces "process.env.X" with the value of "X" for all variables in
+// the map. Explicit defines take precedence. This is only allowed for node
+// since it's meant for baking configuration into server-side bundles.
+func validateProcessEnv(log logger.Log, platform config.Platform, processEnv map[string]string, userDefines map[string]string, defines map[string]string) map[string]string {
+	if len(processEnv) == 0 {
+		return defines
+	}
+	if platform != config.PlatformNode {
+		log.AddError(nil, logger.Range{}, "Cannot use \"processEnv\" without the \"node\" platform")
+		return defines
+	}
+
+	result := make(map[string]string, len(defines)+len(processEnv))
+	for key, value := range defines {
+		result[key] = value
+	}
+	for name, value := range processEnv {
+		// Names that aren't identifiers can't be referenced with a property access
+		if !js_ast.IsIdentifier(name) {
+			continue
+		}
+		key := "process.env." + name
+		if _, ok := userDefines[key]; !ok {
+			result[key] = string(helpers.QuoteForJSON(value, false))
+		}
+	}
+	return result
+}
+
 func validateDefines(
 	log logger.Log,
 	defines map[string]string,
@@ -1185,14 +1577,16 @@ func validateBuildOptions(
 	options config.Options,
 	entryPoints []bundler.EntryPoint,
 ) {
-	targetFromAPI, jsFeatures, cssFeatures, targetEnv := validateFeatures(log, buildOpts.Target, buildOpts.Engines)
+	targetFromAPI, jsFeatures, cssFeatures, targetEnv := validateFeatures(log, buildOpts.Target, buildOpts.Engines, validateBrowserslist(log, realFS, buildOpts.Browserslist))
 	jsOverrides, jsMask, cssOverrides, cssMask := validateSupported(log, buildOpts.Supported)
 	outJS, outCSS := validateOutputExtensions(log, buildOpts.OutExtension)
 	bannerJS, bannerCSS := validateBannerOrFooter(log, "banner", buildOpts.Banner)
 	footerJS, footerCSS := validateBannerOrFooter(log, "footer", buildOpts.Footer)
 	minify := buildOpts.MinifyWhitespace && buildOpts.MinifyIdentifiers && buildOpts.MinifySyntax
 	platform := validatePlatform(buildOpts.Platform)
-	defines, injectedDefines := validateDefines(log, buildOpts.Define, buildOpts.Pure, platform, true /* isBuildAPI */, minify, buildOpts.Drop)
+	define := validateEnvFiles(log, realFS, buildOpts.EnvFiles, buildOpts.EnvPrefixes, buildOpts.Define)
+	define = validateProcessEnv(log, platform, buildOpts.ProcessEnv, buildOpts.Define, define)
+	defines, injectedDefines := validateDefines(log, define, buildOpts.Pure, platform, true /* isBuildAPI */, minify, buildOpts.Drop)
 	options = config.Options{
 		TargetFromAPI:                      targetFromAPI,
 		UnsupportedJSFeatures:              jsFeatures.ApplyOverrides(jsOverrides, jsMask),
@@ -1215,7 +1609,14 @@ func validateBuildOptions(
 		InjectedDefines:       injectedDefines,
 		Platform:              platform,
 		SourceMap:             validateSourceMap(buildOpts.Sourcemap),
+		SourceMapDebugID:      buildOpts.SourcemapDebugID,
+		SourceMapIgnoreList:   validateRegex(log, "sourcemap ignore list", buildOpts.SourcemapIgnoreList),
+		SourceMapPathPrefix:   buildOpts.SourcemapPathPrefix,
+		SourceMapLinesOnly:    buildOpts.SourcemapLinesOnly,
 		LegalComments:         validateLegalComments(buildOpts.LegalComments, buildOpts.Bundle),
+		Comments:              validateComments(log, buildOpts.Comments, buildOpts.LegalComments, buildOpts.CommentsFilter),
+		CommentsFilter:        validateRegex(log, "comments filter", buildOpts.CommentsFilter),
+		ExtractLicenses:       buildOpts.ExtractLicenses,
 		SourceRoot:            buildOpts.SourceRoot,
 		ExcludeSourcesContent: buildOpts.SourcesContent == SourcesContentExclude,
 		MinifySyntax:          buildOpts.MinifySyntax,
@@ -1225,12 +1626,21 @@ func validateBuildOptions(
 		ReserveProps:          validateRegex(log, "reserve props", buildOpts.ReserveProps),
 		MangleQuoted:          buildOpts.MangleQuoted == MangleQuotedTrue,
 		DropDebugger:          (buildOpts.Drop & DropDebugger) != 0,
+		DropLabels:            validateDropLabels(log, buildOpts.DropLabels),
+		ReserveNames:          validateReserveNames(log, buildOpts.ReserveNames),
 		AllowOverwrite:        buildOpts.AllowOverwrite,
+		InlineWorkers:         buildOpts.InlineWorkers,
+		HTMLInlineLimit:       buildOpts.HTMLInlineLimit,
 		ASCIIOnly:             validateASCIIOnly(buildOpts.Charset),
+		IndentUnit:            validateIndent(log, buildOpts.Indent),
+		PreferSingleQuotes:    validatePreferSingleQuotes(buildOpts.QuoteStyle),
 		IgnoreDCEAnnotations:  buildOpts.IgnoreAnnotations,
 		TreeShaking:           validateTreeShaking(buildOpts.TreeShaking, buildOpts.Bundle, buildOpts.Format),
 		GlobalName:            validateGlobalName(log, buildOpts.GlobalName),
+		ImportMetaShim:        validateImportMetaShim(log, buildOpts.ImportMetaShim),
+		UMDGlobals:            validateGlobals(log, buildOpts.Globals),
 		CodeSplitting:         buildOpts.Splitting,
+		RuntimeChunk:          buildOpts.RuntimeChunk,
 		OutputFormat:          validateFormat(buildOpts.Format),
 		AbsOutputFile:         validatePath(log, realFS, buildOpts.Outfile, "outfile path"),
 		AbsOutputDir:          validatePath(log, realFS, buildOpts.Outdir, "outdir path"),
@@ -1246,6 +1656,12 @@ func validateBuildOptions(
 		ExternalSettings:      validateExternals(log, realFS, buildOpts.External),
 		ExternalPackages:      buildOpts.Packages == PackagesExternal,
 		PackageAliases:        validateAlias(log, realFS, buildOpts.Alias),
+		NodePolyfills:         buildOpts.NodePolyfills,
+		NodeGlobals:           validateNodeGlobals(log, buildOpts.NodeGlobals),
+		Dirname:               validateDirname(buildOpts.Dirname),
+		AbsImportMapPath:      validatePath(log, realFS, buildOpts.ImportMap, "import map path"),
+		ExposedModules:        validateExposes(log, buildOpts.Exposes),
+		RemoteModules:         validateRemotes(log, buildOpts.Remotes),
 		TsConfigOverride:      validatePath(log, realFS, buildOpts.Tsconfig, "tsconfig path"),
 		MainFields:            buildOpts.MainFields,
 		PublicPath:            buildOpts.PublicPath,
@@ -1258,6 +1674,9 @@ func validateBuildOptions(
 		CSSFooter:             footerCSS,
 		PreserveSymlinks:      buildOpts.PreserveSymlinks,
 	}
+	if options.Comments == config.CommentsNone {
+		options.LegalComments = config.LegalCommentsNone
+	}
 	if buildOpts.Conditions != nil {
 		options.Conditions = append([]string{}, buildOpts.Conditions...)
 	}
@@ -1274,6 +1693,9 @@ func validateBuildOptions(
 	for _, ep := range buildOpts.EntryPointsAdvanced {
 		entryPoints = append(entryPoints, bundler.EntryPoint{InputPath: ep.InputPath, OutputPath: ep.OutputPath})
 	}
+	for _, exposed := range options.ExposedModules {
+		entryPoints = append(entryPoints, bundler.EntryPoint{InputPath: exposed.InputPath, OutputPath: exposed.Name})
+	}
 	entryPointCount := len(entryPoints)
 	if buildOpts.Stdin != nil {
 		entryPointCount++
@@ -1306,6 +1728,9 @@ func validateBuildOptions(
 		if options.LegalComments.HasExternalFile() {
 			log.AddError(nil, logger.Range{}, "Cannot use linked or external legal comments without an output path")
 		}
+		if options.ExtractLicenses {
+			log.AddError(nil, logger.Range{}, "Cannot extract licenses without an output path")
+		}
 		for _, loader := range options.ExtensionToLoader {
 			if loader == config.LoaderFile {
 				log.AddError(nil, logger.Range{}, "Cannot use the \"file\" loader without an output path")
@@ -1330,6 +1755,18 @@ func validateBuildOptions(
 		if len(options.PackageAliases) > 0 {
 			log.AddError(nil, logger.Range{}, "Cannot use \"alias\" without \"bundle\"")
 		}
+		if options.NodePolyfills {
+			log.AddError(nil, logger.Range{}, "Cannot use \"nodePolyfills\" without \"bundle\"")
+		}
+		if len(options.NodeGlobals) > 0 {
+			log.AddError(nil, logger.Range{}, "Cannot use \"nodeGlobals\" without \"bundle\"")
+		}
+		if options.Dirname != config.DirnameDefault {
+			log.AddError(nil, logger.Range{}, "Cannot use \"dirname\" without \"bundle\"")
+		}
+		if options.AbsImportMapPath != "" {
+			log.AddError(nil, logger.Range{}, "Cannot use \"importMap\" without \"bundle\"")
+		}
 	} else if options.OutputFormat == config.FormatPreserve {
 		// If the format isn't specified, set the default format using the platform
 		switch options.Platform {
@@ -1337,7 +1774,7 @@ func validateBuildOptions(
 			options.OutputFormat = config.FormatIIFE
 		case config.PlatformNode:
 			options.OutputFormat = config.FormatCommonJS
-		case config.PlatformNeutral:
+		case config.PlatformNeutral, config.PlatformDeno:
 			options.OutputFormat = config.FormatESModule
 		}
 	}
@@ -1358,6 +1795,30 @@ func validateBuildOptions(
 	if options.CodeSplitting && options.OutputFormat != config.FormatESModule {
 		log.AddError(nil, logger.Range{}, "Splitting currently only works with the \"esm\" format")
 	}
+	if options.RuntimeChunk && !options.CodeSplitting {
+		log.AddError(nil, logger.Range{}, "Cannot use \"runtimeChunk\" without \"splitting\"")
+	}
+	if len(options.UMDGlobals) > 0 && options.OutputFormat != config.FormatUMD {
+		log.AddError(nil, logger.Range{}, "Cannot use \"globals\" without the \"umd\" format")
+	}
+
+	// Exposed and remote modules are loaded using ES6 module syntax at run time
+	if len(options.ExposedModules) > 0 || len(options.RemoteModules) > 0 {
+		if !buildOpts.Bundle {
+			log.AddError(nil, logger.Range{}, "Cannot use \"exposes\" or \"remotes\" without \"bundle\"")
+		} else if options.OutputFormat != config.FormatESModule {
+			log.AddError(nil, logger.Range{}, "Module federation currently only works with the \"esm\" format")
+		}
+	}
+	if len(options.ExposedModules) > 0 {
+		if options.WriteToStdout || options.AbsOutputFile != "" {
+			log.AddError(nil, logger.Range{}, "Must use \"outdir\" when there are exposed modules")
+		}
+		if config.HasPlaceholder(options.EntryPathTemplate, config.HashPlaceholder) {
+			log.AddError(nil, logger.Range{}, "Cannot use \"[hash]\" in \"entryNames\" when there are exposed modules "+
+				"because other builds need to be able to import exposed modules by name")
+		}
+	}
 
 	// If we aren't writing the output to the file system, then we can allow the
 	// output paths to be the same as the input paths. This helps when serving.
@@ -1498,6 +1959,13 @@ func rebuildImpl(args rebuildArgs, oldSummary buildSummary) rebuildState {
 								if err := ioutil.WriteFile(result.AbsPath, result.Contents, mode); err != nil {
 									log.AddError(nil, logger.Range{}, fmt.Sprintf(
 										"Failed to write to output file: %s", err.Error()))
+								} else if result.IsExecutable {
+									// "WriteFile" only uses the mode when it creates the file, so
+									// an existing output file must be made executable separately
+									if err := os.Chmod(result.AbsPath, mode); err != nil {
+										log.AddError(nil, logger.Range{}, fmt.Sprintf(
+											"Failed to make output file executable: %s", err.Error()))
+									}
 								}
 							}
 						}(result)
@@ -1609,6 +2077,7 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 	// Settings from the user come first
 	var unusedImportFlagsTS config.UnusedImportFlagsTS
 	useDefineForClassFieldsTS := config.Unspecified
+	emitDecoratorMetadataTS := false
 	jsx := config.JSXOptions{
 		Preserve:         transformOpts.JSX == JSXPreserve,
 		AutomaticRuntime: transformOpts.JSX == JSXAutomatic,
@@ -1645,9 +2114,11 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 			if result.UseDefineForClassFields != config.Unspecified {
 				useDefineForClassFieldsTS = result.UseDefineForClassFields
 			}
+			emitDecoratorMetadataTS = result.ShouldEmitDecoratorMetadata()
 			unusedImportFlagsTS = config.UnusedImportFlagsFromTsconfigValues(
 				result.PreserveImportsNotUsedAsValues,
 				result.PreserveValueImports,
+				result.VerbatimModuleSyntax,
 			)
 			tsTarget = result.TSTarget
 			tsAlwaysStrict = result.TSAlwaysStrictOrStrict()
@@ -1663,7 +2134,7 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 	}
 
 	// Convert and validate the transformOpts
-	targetFromAPI, jsFeatures, cssFeatures, targetEnv := validateFeatures(log, transformOpts.Target, transformOpts.Engines)
+	targetFromAPI, jsFeatures, cssFeatures, targetEnv := validateFeatures(log, transformOpts.Target, transformOpts.Engines, validateBrowserslist(log, nil, transformOpts.Browserslist))
 	jsOverrides, jsMask, cssOverrides, cssMask := validateSupported(log, transformOpts.Supported)
 	platform := validatePlatform(transformOpts.Platform)
 	defines, injectedDefines := validateDefines(log, transformOpts.Define, transformOpts.Pure, platform, false /* isBuildAPI */, false /* minify */, transformOpts.Drop)
@@ -1684,11 +2155,18 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		InjectedDefines:                    injectedDefines,
 		Platform:                           platform,
 		SourceMap:                          validateSourceMap(transformOpts.Sourcemap),
+		SourceMapDebugID:                   transformOpts.SourcemapDebugID,
+		SourceMapIgnoreList:                validateRegex(log, "sourcemap ignore list", transformOpts.SourcemapIgnoreList),
+		SourceMapPathPrefix:                transformOpts.SourcemapPathPrefix,
+		SourceMapLinesOnly:                 transformOpts.SourcemapLinesOnly,
 		LegalComments:                      validateLegalComments(transformOpts.LegalComments, false /* bundle */),
+		Comments:                           validateComments(log, transformOpts.Comments, transformOpts.LegalComments, transformOpts.CommentsFilter),
+		CommentsFilter:                     validateRegex(log, "comments filter", transformOpts.CommentsFilter),
 		SourceRoot:                         transformOpts.SourceRoot,
 		ExcludeSourcesContent:              transformOpts.SourcesContent == SourcesContentExclude,
 		OutputFormat:                       validateFormat(transformOpts.Format),
 		GlobalName:                         validateGlobalName(log, transformOpts.GlobalName),
+		ImportMetaShim:                     validateImportMetaShim(log, transformOpts.ImportMetaShim),
 		MinifySyntax:                       transformOpts.MinifySyntax,
 		MinifyWhitespace:                   transformOpts.MinifyWhitespace,
 		MinifyIdentifiers:                  transformOpts.MinifyIdentifiers,
@@ -1696,19 +2174,28 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		ReserveProps:                       validateRegex(log, "reserve props", transformOpts.ReserveProps),
 		MangleQuoted:                       transformOpts.MangleQuoted == MangleQuotedTrue,
 		DropDebugger:                       (transformOpts.Drop & DropDebugger) != 0,
+		DropLabels:                         validateDropLabels(log, transformOpts.DropLabels),
+		ReserveNames:                       validateReserveNames(log, transformOpts.ReserveNames),
 		ASCIIOnly:                          validateASCIIOnly(transformOpts.Charset),
+		IndentUnit:                         validateIndent(log, transformOpts.Indent),
+		PreferSingleQuotes:                 validatePreferSingleQuotes(transformOpts.QuoteStyle),
 		IgnoreDCEAnnotations:               transformOpts.IgnoreAnnotations,
 		TreeShaking:                        validateTreeShaking(transformOpts.TreeShaking, false /* bundle */, transformOpts.Format),
 		AbsOutputFile:                      transformOpts.Sourcefile + "-out",
 		KeepNames:                          transformOpts.KeepNames,
 		UseDefineForClassFields:            useDefineForClassFieldsTS,
+		EmitDecoratorMetadata:              emitDecoratorMetadataTS,
 		UnusedImportFlagsTS:                unusedImportFlagsTS,
 		Stdin: &config.StdinInfo{
-			Loader:     validateLoader(transformOpts.Loader),
-			Contents:   input,
-			SourceFile: transformOpts.Sourcefile,
+			Loader:         validateLoader(transformOpts.Loader),
+			Contents:       input,
+			SourceFile:     transformOpts.Sourcefile,
+			InputSourceMap: transformOpts.InputSourcemap,
 		},
 	}
+	if options.Comments == config.CommentsNone {
+		options.LegalComments = config.LegalCommentsNone
+	}
 	if options.Stdin.Loader == config.LoaderCSS {
 		options.CSSBanner = transformOpts.Banner
 		options.CSSFooter = transformOpts.Footer
@@ -1738,6 +2225,7 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 	}
 
 	var results []graph.OutputFile
+	var astJSON []byte
 
 	// Stop now if there were errors
 	if !log.HasErrors() {
@@ -1750,6 +2238,16 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		mockFS := fs.MockFS(make(map[string]string), fs.MockUnix, "/")
 		bundle := bundler.ScanBundle(log, mockFS, caches, nil, options, timer)
 
+		// The AST must be serialized before linking since linking may modify it
+		if transformOpts.AST && !log.HasErrors() {
+			if tree, ok := bundle.EntryPointJSAST(); ok {
+				astJSON = js_printer.PrintASTJSON(tree, options.ASCIIOnly)
+			} else {
+				log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot use \"ast\" with the %q loader",
+					config.LoaderToString[options.Stdin.Loader]))
+			}
+		}
+
 		// Stop now if there were errors
 		if !log.HasErrors() {
 			// Compile the bundle
@@ -1783,9 +2281,10 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		}
 	}
 
-	// Only return the mangle cache for a successful build
+	// Only return the mangle cache and the AST for a successful build
 	if log.HasErrors() {
 		mangleCache = nil
+		astJSON = nil
 	}
 
 	msgs := log.Done()
@@ -1795,6 +2294,7 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		Code:          code,
 		Map:           sourceMap,
 		LegalComments: legalComments,
+		AST:           astJSON,
 		MangleCache:   mangleCache,
 	}
 }
@@ -1842,6 +2342,10 @@ func importKindToResolveKind(kind ast.ImportKind) ResolveKind {
 		return ResolveCSSImportRule
 	case ast.ImportURL:
 		return ResolveCSSURLToken
+	case ast.ImportWorker:
+		return ResolveJSWorker
+	case ast.ImportNewURL:
+		return ResolveJSNewURL
 	default:
 		panic("Internal error")
 	}
@@ -1863,6 +2367,10 @@ func resolveKindToImportKind(kind ResolveKind) ast.ImportKind {
 		return ast.ImportAt
 	case ResolveCSSURLToken:
 		return ast.ImportURL
+	case ResolveJSWorker:
+		return ast.ImportWorker
+	case ResolveJSNewURL:
+		return ast.ImportNewURL
 	default:
 		panic("Internal error")
 	}
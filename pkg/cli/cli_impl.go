@@ -113,6 +113,34 @@ func parseOptionsImpl(
 				buildOpts.Splitting = value
 			}
 
+		case isBoolFlag(arg, "--runtime-chunk") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.RuntimeChunk = value
+			}
+
+		case isBoolFlag(arg, "--node-polyfills") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.NodePolyfills = value
+			}
+
+		case isBoolFlag(arg, "--extract-licenses") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.ExtractLicenses = value
+			}
+
+		case isBoolFlag(arg, "--inline-workers") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.InlineWorkers = value
+			}
+
 		case isBoolFlag(arg, "--allow-overwrite") && buildOpts != nil:
 			if value, err := parseBoolFlag(arg, true); err != nil {
 				return parseOptionsExtras{}, err
@@ -200,6 +228,13 @@ func parseOptionsImpl(
 				transformOpts.ReserveProps = value
 			}
 
+		case strings.HasPrefix(arg, "--reserve-names="):
+			if buildOpts != nil {
+				buildOpts.ReserveNames = splitWithEmptyCheck(arg[len("--reserve-names="):], ",")
+			} else {
+				transformOpts.ReserveNames = splitWithEmptyCheck(arg[len("--reserve-names="):], ",")
+			}
+
 		case strings.HasPrefix(arg, "--mangle-cache=") && buildOpts != nil && kind == kindInternal:
 			value := arg[len("--mangle-cache="):]
 			extras.mangleCache = &value
@@ -226,6 +261,13 @@ func parseOptionsImpl(
 				)
 			}
 
+		case strings.HasPrefix(arg, "--drop-labels="):
+			if buildOpts != nil {
+				buildOpts.DropLabels = splitWithEmptyCheck(arg[len("--drop-labels="):], ",")
+			} else {
+				transformOpts.DropLabels = splitWithEmptyCheck(arg[len("--drop-labels="):], ",")
+			}
+
 		case strings.HasPrefix(arg, "--legal-comments="):
 			value := arg[len("--legal-comments="):]
 			var legalComments api.LegalComments
@@ -252,6 +294,36 @@ func parseOptionsImpl(
 				transformOpts.LegalComments = legalComments
 			}
 
+		case strings.HasPrefix(arg, "--comments="):
+			value := arg[len("--comments="):]
+			var comments api.Comments
+			switch value {
+			case "none":
+				comments = api.CommentsNone
+			case "legal":
+				comments = api.CommentsLegal
+			case "all":
+				comments = api.CommentsAll
+			default:
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"Valid values are \"none\", \"legal\", or \"all\".",
+				)
+			}
+			if buildOpts != nil {
+				buildOpts.Comments = comments
+			} else {
+				transformOpts.Comments = comments
+			}
+
+		case strings.HasPrefix(arg, "--comments-filter="):
+			value := arg[len("--comments-filter="):]
+			if buildOpts != nil {
+				buildOpts.CommentsFilter = value
+			} else {
+				transformOpts.CommentsFilter = value
+			}
+
 		case strings.HasPrefix(arg, "--charset="):
 			var value *api.Charset
 			if buildOpts != nil {
@@ -272,6 +344,45 @@ func parseOptionsImpl(
 				)
 			}
 
+		case strings.HasPrefix(arg, "--indent="):
+			value := arg[len("--indent="):]
+			var indent string
+			if value == "tab" {
+				indent = "\t"
+			} else if n, err := strconv.Atoi(value); err == nil && n > 0 {
+				indent = strings.Repeat(" ", n)
+			} else {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"Valid values are \"tab\" or a positive number of spaces.",
+				)
+			}
+			if buildOpts != nil {
+				buildOpts.Indent = indent
+			} else {
+				transformOpts.Indent = indent
+			}
+
+		case strings.HasPrefix(arg, "--quote-style="):
+			value := arg[len("--quote-style="):]
+			var quoteStyle api.QuoteStyle
+			switch value {
+			case "double":
+				quoteStyle = api.QuoteStyleDouble
+			case "single":
+				quoteStyle = api.QuoteStyleSingle
+			default:
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"Valid values are \"double\" or \"single\".",
+				)
+			}
+			if buildOpts != nil {
+				buildOpts.QuoteStyle = quoteStyle
+			} else {
+				transformOpts.QuoteStyle = quoteStyle
+			}
+
 		case isBoolFlag(arg, "--tree-shaking"):
 			if value, err := parseBoolFlag(arg, true); err != nil {
 				return parseOptionsExtras{}, err
@@ -340,6 +451,53 @@ func parseOptionsImpl(
 			}
 			hasBareSourceMapFlag = false
 
+		case isBoolFlag(arg, "--sourcemap-debug-id"):
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else if buildOpts != nil {
+				buildOpts.SourcemapDebugID = value
+			} else {
+				transformOpts.SourcemapDebugID = value
+			}
+
+		case isBoolFlag(arg, "--sourcemap-lines-only"):
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else if buildOpts != nil {
+				buildOpts.SourcemapLinesOnly = value
+			} else {
+				transformOpts.SourcemapLinesOnly = value
+			}
+
+		case strings.HasPrefix(arg, "--sourcemap-ignore-list="):
+			value := arg[len("--sourcemap-ignore-list="):]
+			if buildOpts != nil {
+				buildOpts.SourcemapIgnoreList = value
+			} else {
+				transformOpts.SourcemapIgnoreList = value
+			}
+
+		case strings.HasPrefix(arg, "--sourcemap-path-prefix:"):
+			value := arg[len("--sourcemap-path-prefix:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"=\" to specify both the original prefix and the replacement prefix. "+
+						"For example, \"--sourcemap-path-prefix:../src/=webpack:///./src/\" replaces the prefix \"../src/\" with \"webpack:///./src/\".",
+				)
+			}
+			var prefixes *map[string]string
+			if buildOpts != nil {
+				prefixes = &buildOpts.SourcemapPathPrefix
+			} else {
+				prefixes = &transformOpts.SourcemapPathPrefix
+			}
+			if *prefixes == nil {
+				*prefixes = make(map[string]string)
+			}
+			(*prefixes)[value[:equals]] = value[equals+1:]
+
 		case strings.HasPrefix(arg, "--source-root="):
 			sourceRoot := arg[len("--source-root="):]
 			if buildOpts != nil {
@@ -381,6 +539,9 @@ func parseOptionsImpl(
 		case strings.HasPrefix(arg, "--main-fields=") && buildOpts != nil:
 			buildOpts.MainFields = splitWithEmptyCheck(arg[len("--main-fields="):], ",")
 
+		case strings.HasPrefix(arg, "--node-globals=") && buildOpts != nil:
+			buildOpts.NodeGlobals = splitWithEmptyCheck(arg[len("--node-globals="):], ",")
+
 		case strings.HasPrefix(arg, "--conditions=") && buildOpts != nil:
 			buildOpts.Conditions = splitWithEmptyCheck(arg[len("--conditions="):], ",")
 
@@ -394,6 +555,13 @@ func parseOptionsImpl(
 				transformOpts.GlobalName = arg[len("--global-name="):]
 			}
 
+		case strings.HasPrefix(arg, "--import-meta-shim="):
+			if buildOpts != nil {
+				buildOpts.ImportMetaShim = arg[len("--import-meta-shim="):]
+			} else {
+				transformOpts.ImportMetaShim = arg[len("--import-meta-shim="):]
+			}
+
 		case arg == "--metafile" && buildOpts != nil && kind == kindExternal:
 			buildOpts.Metafile = true
 
@@ -411,9 +579,15 @@ func parseOptionsImpl(
 		case strings.HasPrefix(arg, "--outbase=") && buildOpts != nil:
 			buildOpts.Outbase = arg[len("--outbase="):]
 
+		case strings.HasPrefix(arg, "--import-map=") && buildOpts != nil:
+			buildOpts.ImportMap = arg[len("--import-map="):]
+
 		case strings.HasPrefix(arg, "--tsconfig=") && buildOpts != nil:
 			buildOpts.Tsconfig = arg[len("--tsconfig="):]
 
+		case strings.HasPrefix(arg, "--input-sourcemap=") && transformOpts != nil:
+			transformOpts.InputSourcemap = arg[len("--input-sourcemap="):]
+
 		case strings.HasPrefix(arg, "--tsconfig-raw=") && transformOpts != nil:
 			transformOpts.TsconfigRaw = arg[len("--tsconfig-raw="):]
 
@@ -540,6 +714,14 @@ func parseOptionsImpl(
 				transformOpts.Engines = engines
 			}
 
+		case strings.HasPrefix(arg, "--browserslist="):
+			value := arg[len("--browserslist="):]
+			if buildOpts != nil {
+				buildOpts.Browserslist = value
+			} else {
+				transformOpts.Browserslist = value
+			}
+
 		case strings.HasPrefix(arg, "--out-extension:") && buildOpts != nil:
 			value := arg[len("--out-extension:"):]
 			equals := strings.IndexByte(value, '=')
@@ -565,10 +747,12 @@ func parseOptionsImpl(
 				platform = api.PlatformNode
 			case "neutral":
 				platform = api.PlatformNeutral
+			case "deno":
+				platform = api.PlatformDeno
 			default:
 				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
 					fmt.Sprintf("Invalid value %q in %q", value, arg),
-					"Valid values are \"browser\", \"node\", or \"neutral\".",
+					"Valid values are \"browser\", \"node\", \"neutral\", or \"deno\".",
 				)
 			}
 			if buildOpts != nil {
@@ -587,10 +771,14 @@ func parseOptionsImpl(
 				format = api.FormatCommonJS
 			case "esm":
 				format = api.FormatESModule
+			case "umd":
+				format = api.FormatUMD
+			case "system":
+				format = api.FormatSystem
 			default:
 				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
 					fmt.Sprintf("Invalid value %q in %q", value, arg),
-					"Valid values are \"iife\", \"cjs\", or \"esm\".",
+					"Valid values are \"iife\", \"cjs\", \"esm\", \"umd\", or \"system\".",
 				)
 			}
 			if buildOpts != nil {
@@ -612,12 +800,51 @@ func parseOptionsImpl(
 			}
 			buildOpts.Packages = packages
 
+		case strings.HasPrefix(arg, "--dirname=") && buildOpts != nil:
+			value := arg[len("--dirname="):]
+			var dirname api.Dirname
+			switch value {
+			case "source":
+				dirname = api.DirnameSource
+			case "runtime":
+				dirname = api.DirnameRuntime
+			case "error":
+				dirname = api.DirnameError
+			default:
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"Valid values are \"source\", \"runtime\", or \"error\".",
+				)
+			}
+			buildOpts.Dirname = dirname
+
 		case strings.HasPrefix(arg, "--external:") && buildOpts != nil:
 			buildOpts.External = append(buildOpts.External, arg[len("--external:"):])
 
 		case strings.HasPrefix(arg, "--inject:") && buildOpts != nil:
 			buildOpts.Inject = append(buildOpts.Inject, arg[len("--inject:"):])
 
+		case strings.HasPrefix(arg, "--env-file:") && buildOpts != nil:
+			buildOpts.EnvFiles = append(buildOpts.EnvFiles, arg[len("--env-file:"):])
+
+		case strings.HasPrefix(arg, "--env-prefix:") && buildOpts != nil:
+			buildOpts.EnvPrefixes = append(buildOpts.EnvPrefixes, arg[len("--env-prefix:"):])
+
+		case strings.HasPrefix(arg, "--process-env:") && buildOpts != nil:
+			value := arg[len("--process-env:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"=\" to specify both the environment variable and its value. "+
+						"For example, \"--process-env:STAGE=prod\" replaces \"process.env.STAGE\" with \"prod\".",
+				)
+			}
+			if buildOpts.ProcessEnv == nil {
+				buildOpts.ProcessEnv = make(map[string]string)
+			}
+			buildOpts.ProcessEnv[value[:equals]] = value[equals+1:]
+
 		case strings.HasPrefix(arg, "--alias:") && buildOpts != nil:
 			value := arg[len("--alias:"):]
 			equals := strings.IndexByte(value, '=')
@@ -633,6 +860,51 @@ func parseOptionsImpl(
 			}
 			buildOpts.Alias[value[:equals]] = value[equals+1:]
 
+		case strings.HasPrefix(arg, "--expose:") && buildOpts != nil:
+			value := arg[len("--expose:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"=\" to specify both the exposed name and the path of the module. "+
+						"For example, \"--expose:Button=./src/button.js\" exposes the file \"./src/button.js\" as \"Button\".",
+				)
+			}
+			if buildOpts.Exposes == nil {
+				buildOpts.Exposes = make(map[string]string)
+			}
+			buildOpts.Exposes[value[:equals]] = value[equals+1:]
+
+		case strings.HasPrefix(arg, "--remote:") && buildOpts != nil:
+			value := arg[len("--remote:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"=\" to specify both the remote name and the URL of the remote. "+
+						"For example, \"--remote:app=https://example.com/app\" imports \"app/Button\" from \"https://example.com/app/Button.js\".",
+				)
+			}
+			if buildOpts.Remotes == nil {
+				buildOpts.Remotes = make(map[string]string)
+			}
+			buildOpts.Remotes[value[:equals]] = value[equals+1:]
+
+		case strings.HasPrefix(arg, "--global:") && buildOpts != nil:
+			value := arg[len("--global:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"=\" to specify both the import path and the global name. "+
+						"For example, \"--global:react=React\" uses the global variable \"React\" for imports of \"react\".",
+				)
+			}
+			if buildOpts.Globals == nil {
+				buildOpts.Globals = make(map[string]string)
+			}
+			buildOpts.Globals[value[:equals]] = value[equals+1:]
+
 		case strings.HasPrefix(arg, "--jsx="):
 			value := arg[len("--jsx="):]
 			var mode api.JSX
@@ -725,6 +997,17 @@ func parseOptionsImpl(
 			}
 			buildOpts.Footer[value[:equals]] = value[equals+1:]
 
+		case strings.HasPrefix(arg, "--html-inline-limit=") && buildOpts != nil:
+			value := arg[len("--html-inline-limit="):]
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"The HTML inline limit must be a non-negative integer.",
+				)
+			}
+			buildOpts.HTMLInlineLimit = limit
+
 		case strings.HasPrefix(arg, "--log-limit="):
 			value := arg[len("--log-limit="):]
 			limit, err := strconv.Atoi(value)
@@ -790,92 +1073,125 @@ func parseOptionsImpl(
 
 		default:
 			bare := map[string]bool{
-				"allow-overwrite":    true,
-				"bundle":             true,
-				"ignore-annotations": true,
-				"jsx-dev":            true,
-				"jsx-side-effects":   true,
-				"keep-names":         true,
-				"minify-identifiers": true,
-				"minify-syntax":      true,
-				"minify-whitespace":  true,
-				"minify":             true,
-				"preserve-symlinks":  true,
-				"sourcemap":          true,
-				"splitting":          true,
-				"watch":              true,
+				"allow-overwrite":      true,
+				"bundle":               true,
+				"extract-licenses":     true,
+				"ignore-annotations":   true,
+				"inline-workers":       true,
+				"jsx-dev":              true,
+				"jsx-side-effects":     true,
+				"keep-names":           true,
+				"minify-identifiers":   true,
+				"minify-syntax":        true,
+				"minify-whitespace":    true,
+				"minify":               true,
+				"node-polyfills":       true,
+				"preserve-symlinks":    true,
+				"runtime-chunk":        true,
+				"sourcemap":            true,
+				"sourcemap-debug-id":   true,
+				"sourcemap-lines-only": true,
+				"splitting":            true,
+				"watch":                true,
 			}
 
 			equals := map[string]bool{
-				"allow-overwrite":    true,
-				"asset-names":        true,
-				"banner":             true,
-				"bundle":             true,
-				"certfile":           true,
-				"charset":            true,
-				"chunk-names":        true,
-				"color":              true,
-				"conditions":         true,
-				"entry-names":        true,
-				"footer":             true,
-				"format":             true,
-				"global-name":        true,
-				"ignore-annotations": true,
-				"jsx-factory":        true,
-				"jsx-fragment":       true,
-				"jsx-import-source":  true,
-				"jsx":                true,
-				"keep-names":         true,
-				"keyfile":            true,
-				"legal-comments":     true,
-				"loader":             true,
-				"log-level":          true,
-				"log-limit":          true,
-				"main-fields":        true,
-				"mangle-cache":       true,
-				"mangle-props":       true,
-				"mangle-quoted":      true,
-				"metafile":           true,
-				"minify-identifiers": true,
-				"minify-syntax":      true,
-				"minify-whitespace":  true,
-				"minify":             true,
-				"outbase":            true,
-				"outdir":             true,
-				"outfile":            true,
-				"packages":           true,
-				"platform":           true,
-				"preserve-symlinks":  true,
-				"public-path":        true,
-				"reserve-props":      true,
-				"resolve-extensions": true,
-				"serve":              true,
-				"servedir":           true,
-				"source-root":        true,
-				"sourcefile":         true,
-				"sourcemap":          true,
-				"sources-content":    true,
-				"splitting":          true,
-				"target":             true,
-				"tree-shaking":       true,
-				"tsconfig-raw":       true,
-				"tsconfig":           true,
-				"watch":              true,
+				"allow-overwrite":       true,
+				"asset-names":           true,
+				"browserslist":          true,
+				"banner":                true,
+				"bundle":                true,
+				"certfile":              true,
+				"charset":               true,
+				"chunk-names":           true,
+				"comments":              true,
+				"comments-filter":       true,
+				"color":                 true,
+				"conditions":            true,
+				"dirname":               true,
+				"drop-labels":           true,
+				"entry-names":           true,
+				"extract-licenses":      true,
+				"footer":                true,
+				"format":                true,
+				"global-name":           true,
+				"html-inline-limit":     true,
+				"ignore-annotations":    true,
+				"import-map":            true,
+				"import-meta-shim":      true,
+				"indent":                true,
+				"inline-workers":        true,
+				"input-sourcemap":       true,
+				"jsx-factory":           true,
+				"jsx-fragment":          true,
+				"jsx-import-source":     true,
+				"jsx":                   true,
+				"keep-names":            true,
+				"keyfile":               true,
+				"legal-comments":        true,
+				"loader":                true,
+				"log-level":             true,
+				"log-limit":             true,
+				"main-fields":           true,
+				"mangle-cache":          true,
+				"mangle-props":          true,
+				"mangle-quoted":         true,
+				"metafile":              true,
+				"minify-identifiers":    true,
+				"minify-syntax":         true,
+				"minify-whitespace":     true,
+				"minify":                true,
+				"node-globals":          true,
+				"node-polyfills":        true,
+				"outbase":               true,
+				"outdir":                true,
+				"outfile":               true,
+				"packages":              true,
+				"platform":              true,
+				"preserve-symlinks":     true,
+				"public-path":           true,
+				"quote-style":           true,
+				"reserve-names":         true,
+				"reserve-props":         true,
+				"resolve-extensions":    true,
+				"runtime-chunk":         true,
+				"serve":                 true,
+				"servedir":              true,
+				"source-root":           true,
+				"sourcefile":            true,
+				"sourcemap":             true,
+				"sourcemap-debug-id":    true,
+				"sourcemap-lines-only":  true,
+				"sourcemap-ignore-list": true,
+				"sources-content":       true,
+				"splitting":             true,
+				"target":                true,
+				"tree-shaking":          true,
+				"tsconfig-raw":          true,
+				"tsconfig":              true,
+				"watch":                 true,
 			}
 
 			colon := map[string]bool{
-				"alias":         true,
-				"banner":        true,
-				"define":        true,
-				"drop":          true,
-				"external":      true,
-				"footer":        true,
-				"inject":        true,
-				"loader":        true,
-				"log-override":  true,
-				"out-extension": true,
-				"pure":          true,
-				"supported":     true,
+				"alias":                 true,
+				"banner":                true,
+				"define":                true,
+				"drop":                  true,
+				"env-file":              true,
+				"env-prefix":            true,
+				"expose":                true,
+				"external":              true,
+				"footer":                true,
+				"global":                true,
+				"inject":                true,
+				"loader":                true,
+				"log-override":          true,
+				"out-extension":         true,
+				"process-env":           true,
+				"pure":                  true,
+				"remote":                true,
+				"sourcemap-path-prefix": true,
+				"supported":             true,
 			}
 
 			note := ""
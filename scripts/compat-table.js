@@ -206,6 +206,9 @@ mergeVersions('ArbitraryModuleNamespaceNames', { es2022: true })
 mergeVersions('RegexpMatchIndices', { es2022: true })
 mergeVersions('RegexpSetNotation', {})
 mergeVersions('ImportAssertions', {})
+mergeVersions('ImportAttributes', {})
+mergeVersions('Decorators', {})
+mergeVersions('Using', {})
 
 // Manually copied from https://caniuse.com/?search=export%20*%20as
 mergeVersions('ExportStarAs', {
@@ -317,6 +320,19 @@ mergeVersions('ImportAssertions', {
   // Not yet in Firefox: https://bugzilla.mozilla.org/show_bug.cgi?id=1736059
 })
 
+mergeVersions('ImportAttributes', {
+  // From https://chromestatus.com/feature/5205869105250304
+  chrome123: true,
+
+  // From https://github.com/denoland/deno/releases/tag/v1.37.0
+  deno1_37: true,
+
+  // From https://github.com/nodejs/node/blob/main/doc/changelogs/CHANGELOG_V18.md#18.20.0
+  // and https://github.com/nodejs/node/blob/main/doc/changelogs/CHANGELOG_V20.md#20.10.0
+  node18_20: true,
+  node20_10: true,
+})
+
 // Manually copied from https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Classes/Class_static_initialization_blocks
 mergeVersions('ClassStaticBlocks', {
   chrome91: true, // From https://www.chromestatus.com/feature/6482797915013120
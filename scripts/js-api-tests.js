@@ -640,6 +640,67 @@ let buildTests = {
     })
   },
 
+  async envFiles({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js');
+    const env = path.join(testDir, '.env')
+    const envLocal = path.join(testDir, '.env.local')
+    const output = path.join(testDir, 'out.js')
+    await writeFileAsync(input, 'export default [process.env.PUBLIC_A, process.env.PUBLIC_B, process.env.PUBLIC_C, import.meta.env.PUBLIC_A, typeof process.env.SECRET]')
+    await writeFileAsync(env, 'PUBLIC_A=a\nPUBLIC_B="b # not a comment"\nPUBLIC_C=c\nSECRET=1')
+    await writeFileAsync(envLocal, 'PUBLIC_A=override')
+    await esbuild.build({
+      entryPoints: [input],
+      outfile: output,
+      format: 'cjs',
+      bundle: true,
+      platform: 'node',
+      envFiles: [env, envLocal],
+      envPrefixes: ['PUBLIC_'],
+      define: { 'process.env.PUBLIC_C': '"define"' },
+    })
+    assert.deepStrictEqual(require(output).default, ['override', 'b # not a comment', 'define', 'override', 'undefined'])
+  },
+
+  async envFilesWithoutPrefix({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js');
+    const env = path.join(testDir, '.env')
+    await writeFileAsync(input, 'console.log(process.env.A)')
+    await writeFileAsync(env, 'A=1')
+    try {
+      await esbuild.build({ entryPoints: [input], write: false, logLevel: 'silent', envFiles: [env] })
+      throw new Error('Expected build failure');
+    } catch (e) {
+      if (!e.errors || !e.errors[0] || e.errors[0].text !== 'Cannot use env files without an env prefix') throw e
+    }
+  },
+
+  async processEnv({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js');
+    const output = path.join(testDir, 'out.js')
+    await writeFileAsync(input, 'export default [process.env.STAGE, process.env["REGION"], process.env.OTHER, process.env.DEFINED]')
+    await esbuild.build({
+      entryPoints: [input],
+      outfile: output,
+      format: 'cjs',
+      bundle: true,
+      platform: 'node',
+      processEnv: { STAGE: 'prod', REGION: 'us-east-1', DEFINED: 'env', 'NOT-AN-IDENTIFIER': 'x' },
+      define: { 'process.env.DEFINED': '"define"' },
+    })
+    assert.deepStrictEqual(require(output).default, ['prod', 'us-east-1', process.env.OTHER, 'define'])
+  },
+
+  async processEnvNotNode({ esbuild, testDir }) {
+    const input = path.join(testDir, 'in.js');
+    await writeFileAsync(input, 'console.log(process.env.STAGE)')
+    try {
+      await esbuild.build({ entryPoints: [input], write: false, logLevel: 'silent', processEnv: { STAGE: 'prod' } })
+      throw new Error('Expected build failure');
+    } catch (e) {
+      if (!e.errors || !e.errors[0] || e.errors[0].text !== 'Cannot use "processEnv" without the "node" platform') throw e
+    }
+  },
+
   async inject({ esbuild, testDir }) {
     const input = path.join(testDir, 'in.js');
     const inject = path.join(testDir, 'inject.js')
@@ -5766,6 +5827,37 @@ let transformTests = {
     assert.strictEqual(code, `if (x)\n  ;\n`)
   },
 
+  async dropLabels({ esbuild }) {
+    const { code } = await esbuild.transform(`a:1; b:2; c:3`, { dropLabels: ['a', 'c'] })
+    assert.strictEqual(code, `b:\n  2;\n`)
+  },
+
+  async dropLabelsInvalid({ esbuild }) {
+    try {
+      await esbuild.transform(``, { dropLabels: ['a,b'] })
+      throw new Error('Expected an error to be thrown')
+    } catch (e) {
+      assert.strictEqual(e.errors[0].text, 'Invalid drop label: a,b')
+    }
+  },
+
+  async reserveNames({ esbuild }) {
+    const { code } = await esbuild.transform(`(() => { let $ = 1, jQuery = 2, x = 3; return $ + jQuery + x })()`, {
+      minifyIdentifiers: true,
+      reserveNames: ['$', 'jQuery'],
+    })
+    assert.strictEqual(code, `(() => {\n  let $ = 1, jQuery = 2, e = 3;\n  return $ + jQuery + e;\n})();\n`)
+  },
+
+  async reserveNamesInvalid({ esbuild }) {
+    try {
+      await esbuild.transform(``, { reserveNames: ['a-b'] })
+      throw new Error('Expected an error to be thrown')
+    } catch (e) {
+      assert.strictEqual(e.errors[0].text, 'Invalid reserved name: "a-b"')
+    }
+  },
+
   async define({ esbuild }) {
     const define = { 'process.env.NODE_ENV': '"something"' }
 
@@ -5885,6 +5977,50 @@ let transformTests = {
     await assertSourceMap(map, 'afile.js')
   },
 
+  async sourceMapDebugId({ esbuild }) {
+    const { code, map } = await esbuild.transform(`let       x`, { sourcemap: 'external', sourcefile: 'afile.js', sourcemapDebugId: true })
+    const match = /^let x;\n\/\/# debugId=([0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12})\n$/.exec(code)
+    assert(match, code)
+    assert.strictEqual(JSON.parse(map).debugId, match[1])
+    await assertSourceMap(map, 'afile.js')
+
+    // The debug ID should be deterministic
+    const again = await esbuild.transform(`let       x`, { sourcemap: 'external', sourcefile: 'afile.js', sourcemapDebugId: true })
+    assert.strictEqual(again.code, code)
+  },
+
+  async sourceMapIgnoreList({ esbuild }) {
+    const { map } = await esbuild.transform(`let       x`, { sourcemap: 'external', sourcefile: 'node_modules/afile.js', sourcemapIgnoreList: /node_modules/ })
+    assert.deepStrictEqual(JSON.parse(map).x_google_ignoreList, [0])
+    await assertSourceMap(map, 'node_modules/afile.js')
+
+    const { map: map2 } = await esbuild.transform(`let       x`, { sourcemap: 'external', sourcefile: 'afile.js', sourcemapIgnoreList: /node_modules/ })
+    assert.strictEqual(JSON.parse(map2).x_google_ignoreList, undefined)
+  },
+
+  async sourceMapPathPrefix({ esbuild }) {
+    const { map } = await esbuild.transform(`let       x`, { sourcemap: 'external', sourcefile: '/repo/src/afile.js', sourcemapPathPrefix: { '/repo/': 'webpack:///./' } })
+    await assertSourceMap(map, 'webpack:///./src/afile.js')
+
+    const { map: map2 } = await esbuild.transform(`let       x`, { sourcemap: 'external', sourcefile: 'afile.js', sourcemapPathPrefix: { '/repo/': 'webpack:///./' } })
+    await assertSourceMap(map2, 'afile.js')
+  },
+
+  async sourceMapInput({ esbuild }) {
+    const { code, map } = await esbuild.transform(`let       x`, { sourcemap: 'external', sourcefile: 'afile.js' })
+    const { map: map2 } = await esbuild.transform(code, { sourcemap: 'external', sourcefile: 'bfile.js', inputSourcemap: map })
+    await assertSourceMap(map2, 'afile.js')
+
+    const { map: map3 } = await esbuild.transform(code, { sourcemap: 'external', sourcefile: 'bfile.js', inputSourcemap: JSON.parse(map) })
+    await assertSourceMap(map3, 'afile.js')
+  },
+
+  async sourceMapLinesOnly({ esbuild }) {
+    const { code, map } = await esbuild.transform(`let       x = a(b)\nlet y = c(d)`, { sourcemap: 'external', sourcefile: 'afile.js', sourcemapLinesOnly: true })
+    assert.strictEqual(code, `let x = a(b);\nlet y = c(d);\n`)
+    assert.strictEqual(JSON.parse(map).mappings, 'AAAA;AACA;')
+  },
+
   async sourceMapInlineWithName({ esbuild }) {
     const { code, map } = await esbuild.transform(`let       x`, { sourcemap: 'inline', sourcefile: 'afile.js' })
     assert(code.startsWith(`let x;\n//# sourceMappingURL=`))
@@ -6093,6 +6229,88 @@ let transformTests = {
     }
   },
 
+  async transformComments({ esbuild }) {
+    const input = `//!x\n// y\nz(/* w */ 1)`
+    assert.strictEqual((await esbuild.transform(input)).code, `//!x\nz(\n  /* w */\n  1\n);\n`)
+    assert.strictEqual((await esbuild.transform(input, { comments: 'none' })).code, `z(1);\n`)
+    assert.strictEqual((await esbuild.transform(input, { comments: 'legal' })).code, `//!x\nz(1);\n`)
+    assert.strictEqual((await esbuild.transform(input, { comments: 'all' })).code, `//!x\n// y\nz(\n  /* w */\n  1\n);\n`)
+    assert.strictEqual((await esbuild.transform(input, { comments: 'all', commentsFilter: /^\/\/ / })).code, `//!x\n// y\nz(1);\n`)
+    assert.strictEqual((await esbuild.transform(input, { minify: true })).code, `//!x\nz(1);\n`)
+    assert.strictEqual((await esbuild.transform(input, { minify: true, commentsFilter: /w/ })).code, `//!x\nz(/* w */1);\n`)
+    assert.strictEqual((await esbuild.transform(input, { minify: true, comments: 'all' })).code, `//!x\n// y\nz(/* w */1);\n`)
+
+    try {
+      await esbuild.transform(``, { comments: 'none', legalComments: 'eof' })
+      throw new Error('Expected a transform failure')
+    } catch (e) {
+      if (!e || !e.errors || !e.errors[0] || e.errors[0].text !== 'Cannot use "legalComments" when "comments" is set to "none"')
+        throw e
+    }
+  },
+
+  async transformAST({ esbuild }) {
+    const { code, ast } = await esbuild.transform(`'use strict'; x = a?.b`, { ast: true })
+    assert.strictEqual(code, `"use strict";\nx = a?.b;\n`)
+    assert.deepStrictEqual(ast, {
+      type: 'Program',
+      start: 0,
+      sourceType: 'script',
+      body: [{
+        type: 'ExpressionStatement',
+        expression: { type: 'Literal', value: 'use strict' },
+        directive: 'use strict',
+      }, {
+        type: 'ExpressionStatement',
+        start: 14,
+        expression: {
+          type: 'AssignmentExpression',
+          start: 14,
+          operator: '=',
+          left: { type: 'Identifier', start: 14, name: 'x' },
+          right: {
+            type: 'ChainExpression',
+            start: 18,
+            expression: {
+              type: 'MemberExpression',
+              start: 18,
+              object: { type: 'Identifier', start: 18, name: 'a' },
+              property: { type: 'Identifier', start: 21, name: 'b' },
+              computed: false,
+              optional: true,
+            },
+          },
+        },
+      }],
+      comments: [],
+    })
+    assert.strictEqual((await esbuild.transform(`x`)).ast, undefined)
+
+    try {
+      await esbuild.transform(`a {}`, { ast: true, loader: 'css' })
+      throw new Error('Expected a transform failure')
+    } catch (e) {
+      if (!e || !e.errors || !e.errors[0] || e.errors[0].text !== 'Cannot use "ast" with the "css" loader')
+        throw e
+    }
+  },
+
+  async transformIndentAndQuoteStyle({ esbuild }) {
+    const input = `if (x) { y("z") }`
+    assert.strictEqual((await esbuild.transform(input, { indent: 'tab' })).code, `if (x) {\n\ty("z");\n}\n`)
+    assert.strictEqual((await esbuild.transform(input, { indent: 4 })).code, `if (x) {\n    y("z");\n}\n`)
+    assert.strictEqual((await esbuild.transform(input, { quoteStyle: 'single' })).code, `if (x) {\n  y('z');\n}\n`)
+    assert.strictEqual((await esbuild.transform(`a { b: "c" }`, { loader: 'css', indent: 'tab', quoteStyle: 'single' })).code, `a {\n\tb: 'c';\n}\n`)
+
+    try {
+      await esbuild.transform(``, { indent: 0 })
+      throw new Error('Expected a transform failure')
+    } catch (e) {
+      if (!e || !e.message || !e.message.includes('Invalid value "0" in "--indent=0"'))
+        throw e
+    }
+  },
+
   async tsDecorators({ esbuild }) {
     const { code } = await esbuild.transform(`
       let observed = [];